@@ -80,6 +80,14 @@ func main() {
 		lg.Fatal("failed to initialize use cases", zap.Error(err))
 	}
 
+	// Start background jobs
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	go usecases.StockAlert.Run(bgCtx, time.Minute)
+	go usecases.CostHistory.Run(bgCtx)
+	go usecases.Repricing.Run(bgCtx, time.Minute)
+	go usecases.Upload.Run(bgCtx, time.Hour)
+
 	// Initialize handlers
 	router := handlers.NewRouter(usecases, cfg, lg)
 
@@ -106,6 +114,7 @@ func main() {
 	<-quit
 
 	lg.Info("Shutting down server...")
+	stopBackground()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -51,6 +51,8 @@ type StorageConfig struct {
 	UseSSL          bool
 	BucketName      string
 	PublicURL       string // Публичный URL для доступа к файлам
+	CWebPPath       string // Путь к cwebp для WebP-вариантов изображений (обязателен: без утилиты сервер не запускается)
+	DWebPPath       string // Путь к dwebp для уменьшения загруженных WebP (обязателен: без утилиты сервер не запускается)
 }
 
 func Load() (*Config, error) {
@@ -80,6 +82,8 @@ func Load() (*Config, error) {
 	viper.SetDefault("MINIO_USE_SSL", false)
 	viper.SetDefault("MINIO_BUCKET_NAME", "arc-images")
 	viper.SetDefault("MINIO_PUBLIC_URL", "http://localhost:9000")
+	viper.SetDefault("IMAGE_CWEBP_PATH", "cwebp")
+	viper.SetDefault("IMAGE_DWEBP_PATH", "dwebp")
 
 	// Read from environment variables
 	viper.AutomaticEnv()
@@ -145,6 +149,8 @@ func Load() (*Config, error) {
 			UseSSL:          viper.GetBool("MINIO_USE_SSL"),
 			BucketName:      getEnv("MINIO_BUCKET_NAME", viper.GetString("MINIO_BUCKET_NAME")),
 			PublicURL:       getEnv("MINIO_PUBLIC_URL", viper.GetString("MINIO_PUBLIC_URL")),
+			CWebPPath:       getEnv("IMAGE_CWEBP_PATH", viper.GetString("IMAGE_CWEBP_PATH")),
+			DWebPPath:       getEnv("IMAGE_DWEBP_PATH", viper.GetString("IMAGE_DWEBP_PATH")),
 		},
 	}
 
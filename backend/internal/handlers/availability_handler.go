@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/usecases"
+)
+
+type AvailabilityHandler struct {
+	usecase *usecases.AvailabilityUseCase
+	logger  *zap.Logger
+}
+
+func NewAvailabilityHandler(usecase *usecases.AvailabilityUseCase, logger *zap.Logger) *AvailabilityHandler {
+	return &AvailabilityHandler{
+		usecase: usecase,
+		logger:  logger,
+	}
+}
+
+// ——— Requests ———
+
+// AvailabilityRuleRequest интервал доступности
+type AvailabilityRuleRequest struct {
+	Weekdays  string `json:"weekdays" binding:"required" example:"1,2,3,4,5"` // 1 — понедельник … 7 — воскресенье
+	StartTime string `json:"start_time" binding:"required" example:"12:00"`   // ЧЧ:ММ
+	EndTime   string `json:"end_time" binding:"required" example:"16:00"`     // ЧЧ:ММ; не позже start_time — интервал через полночь
+}
+
+// AvailabilityScheduleRequest расписание доступности позиций меню
+type AvailabilityScheduleRequest struct {
+	Name   string                    `json:"name" binding:"required" example:"Бизнес-ланч"`
+	Active *bool                     `json:"active,omitempty"`
+	Rules  []AvailabilityRuleRequest `json:"rules" binding:"required,min=1,dive"`
+}
+
+func (r *AvailabilityScheduleRequest) toModel() *models.AvailabilitySchedule {
+	schedule := &models.AvailabilitySchedule{
+		Name:   r.Name,
+		Active: true,
+		Rules:  make([]models.AvailabilityRule, 0, len(r.Rules)),
+	}
+	if r.Active != nil {
+		schedule.Active = *r.Active
+	}
+	for _, rule := range r.Rules {
+		schedule.Rules = append(schedule.Rules, models.AvailabilityRule{
+			Weekdays:  rule.Weekdays,
+			StartTime: rule.StartTime,
+			EndTime:   rule.EndTime,
+		})
+	}
+	return schedule
+}
+
+// AvailabilityAssignRequest назначение расписания: ровно одно из category_id, product_id, tech_card_id
+type AvailabilityAssignRequest struct {
+	CategoryID *string `json:"category_id,omitempty" binding:"omitempty,uuid"`
+	ProductID  *string `json:"product_id,omitempty" binding:"omitempty,uuid"`
+	TechCardID *string `json:"tech_card_id,omitempty" binding:"omitempty,uuid"`
+	ScheduleID *string `json:"schedule_id,omitempty" binding:"omitempty,uuid"` // Пусто — снять расписание
+}
+
+// ——— Schedules ———
+
+// ListSchedules возвращает расписания доступности заведения
+// @Summary Расписания доступности меню
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu/availability/schedules [get]
+func (h *AvailabilityHandler) ListSchedules(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	list, err := h.usecase.ListSchedules(c.Request.Context(), estID)
+	if err != nil {
+		h.logger.Error("Failed to list availability schedules", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list availability schedules"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// CreateSchedule создает расписание доступности
+// @Summary Создать расписание доступности
+// @Description Расписание задает дни недели и интервалы времени (в часовом поясе заведения), когда позиции можно продавать. Назначается категории, товару или тех-карте
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body AvailabilityScheduleRequest true "Расписание"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/availability/schedules [post]
+func (h *AvailabilityHandler) CreateSchedule(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	var req AvailabilityScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	schedule := req.toModel()
+	if err := h.usecase.CreateSchedule(c.Request.Context(), schedule, estID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": schedule})
+}
+
+// UpdateSchedule обновляет расписание доступности
+// @Summary Обновить расписание доступности
+// @Description Правила расписания заменяются целиком
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID расписания"
+// @Param request body AvailabilityScheduleRequest true "Расписание"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/availability/schedules/{id} [put]
+func (h *AvailabilityHandler) UpdateSchedule(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req AvailabilityScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	schedule := req.toModel()
+	schedule.ID = id
+	if err := h.usecase.UpdateSchedule(c.Request.Context(), schedule, estID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": schedule})
+}
+
+// DeleteSchedule удаляет расписание доступности
+// @Summary Удалить расписание доступности
+// @Description Расписание снимается со всех категорий и позиций
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID расписания"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/availability/schedules/{id} [delete]
+func (h *AvailabilityHandler) DeleteSchedule(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.usecase.DeleteSchedule(c.Request.Context(), id, estID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "availability schedule deleted"})
+}
+
+// Assign назначает расписание категории, товару или тех-карте
+// @Summary Назначить расписание доступности
+// @Description Расписание товара или тех-карты важнее расписания категории. Без schedule_id расписание снимается
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body AvailabilityAssignRequest true "Назначение"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/availability/assign [put]
+func (h *AvailabilityHandler) Assign(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	var req AvailabilityAssignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	target := usecases.AvailabilityTarget{
+		CategoryID: parseOptionalUUID(req.CategoryID),
+		ProductID:  parseOptionalUUID(req.ProductID),
+		TechCardID: parseOptionalUUID(req.TechCardID),
+	}
+	if err := h.usecase.Assign(c.Request.Context(), estID, target, parseOptionalUUID(req.ScheduleID)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "availability schedule assigned"})
+}
@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+	"github.com/yourusername/arc/backend/internal/usecases"
+)
+
+type BarcodeHandler struct {
+	usecase *usecases.BarcodeUseCase
+	logger  *zap.Logger
+}
+
+func NewBarcodeHandler(usecase *usecases.BarcodeUseCase, logger *zap.Logger) *BarcodeHandler {
+	return &BarcodeHandler{
+		usecase: usecase,
+		logger:  logger,
+	}
+}
+
+// ——— Requests ———
+
+type CreateBarcodeRequest struct {
+	Code         string  `json:"code" binding:"required"`
+	IngredientID *string `json:"ingredient_id,omitempty" binding:"omitempty,uuid"`
+	ProductID    *string `json:"product_id,omitempty" binding:"omitempty,uuid"`
+	TechCardID   *string `json:"tech_card_id,omitempty" binding:"omitempty,uuid"`
+	Multiplier   float64 `json:"multiplier" binding:"gte=0"` // Количество позиции в одном сканировании (по умолчанию 1)
+	Unit         string  `json:"unit"`                       // Единица множителя (по умолчанию — основная единица позиции)
+	Weighted     bool    `json:"weighted"`                   // Весовой EAN-13: code — первые 7 цифр (префикс 20–29 и код товара)
+	Comment      string  `json:"comment"`
+}
+
+type UpdateBarcodeRequest struct {
+	Code       string  `json:"code" binding:"required"`
+	Multiplier float64 `json:"multiplier" binding:"gte=0"`
+	Unit       string  `json:"unit"`
+	Weighted   bool    `json:"weighted"`
+	Comment    string  `json:"comment"`
+}
+
+type ScanSupplyRequest struct {
+	Code         string   `json:"code" binding:"required"`
+	Quantity     float64  `json:"quantity" binding:"gte=0"` // Количество сканирований (по умолчанию 1)
+	PricePerUnit *float64 `json:"price_per_unit" binding:"omitempty,gte=0"`
+	Complete     bool     `json:"complete"` // Провести поставку после сканирования (приходовать остатки)
+}
+
+type ScanInventoryRequest struct {
+	Code     string  `json:"code" binding:"required"`
+	Quantity float64 `json:"quantity" binding:"gte=0"` // Количество сканирований (по умолчанию 1)
+	Zone     string  `json:"zone"`
+}
+
+type ScanOrderRequest struct {
+	Code        string `json:"code" binding:"required"`
+	Quantity    int    `json:"quantity" binding:"gte=0"` // Количество сканирований (по умолчанию 1)
+	GuestNumber *int   `json:"guest_number,omitempty"`
+}
+
+// barcodeErrorStatus возвращает 404 для ненайденного штрихкода, иначе 400
+func barcodeErrorStatus(err error) int {
+	if errors.Is(err, usecases.ErrBarcodeNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// ——— Barcodes ———
+
+// ListBarcodes возвращает штрихкоды позиций
+// @Summary Получить штрихкоды
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param ingredient_id query string false "ID ингредиента"
+// @Param product_id query string false "ID товара"
+// @Param tech_card_id query string false "ID техкарты"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/barcodes [get]
+func (h *BarcodeHandler) ListBarcodes(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := &repositories.BarcodeFilter{}
+	if s := c.Query("ingredient_id"); s != "" {
+		if id, e := uuid.Parse(s); e == nil {
+			filter.IngredientID = &id
+		}
+	}
+	if s := c.Query("product_id"); s != "" {
+		if id, e := uuid.Parse(s); e == nil {
+			filter.ProductID = &id
+		}
+	}
+	if s := c.Query("tech_card_id"); s != "" {
+		if id, e := uuid.Parse(s); e == nil {
+			filter.TechCardID = &id
+		}
+	}
+
+	list, err := h.usecase.List(c.Request.Context(), estID, filter)
+	if err != nil {
+		h.logger.Error("Failed to list barcodes", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list barcodes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// CreateBarcode привязывает штрихкод к позиции
+// @Summary Добавить штрихкод
+// @Description Привязывает штрихкод к ингредиенту, товару или техкарте. У позиции может быть несколько штрихкодов с разными множителями (штука, упаковка, коробка). Весовой штрихкод задается первыми 7 цифрами EAN-13.
+// @Tags warehouse
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body CreateBarcodeRequest true "Штрихкод"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /warehouse/barcodes [post]
+func (h *BarcodeHandler) CreateBarcode(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req CreateBarcodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	barcode := &models.ItemBarcode{
+		Code:         req.Code,
+		IngredientID: parseOptionalUUID(req.IngredientID),
+		ProductID:    parseOptionalUUID(req.ProductID),
+		TechCardID:   parseOptionalUUID(req.TechCardID),
+		Multiplier:   req.Multiplier,
+		Unit:         req.Unit,
+		Weighted:     req.Weighted,
+		Comment:      req.Comment,
+	}
+	if err := h.usecase.Create(c.Request.Context(), barcode, estID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": barcode})
+}
+
+// UpdateBarcode изменяет штрихкод
+// @Summary Обновить штрихкод
+// @Tags warehouse
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID штрихкода"
+// @Param request body UpdateBarcodeRequest true "Штрихкод"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /warehouse/barcodes/{id} [put]
+func (h *BarcodeHandler) UpdateBarcode(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req UpdateBarcodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	barcode := &models.ItemBarcode{
+		ID:         id,
+		Code:       req.Code,
+		Multiplier: req.Multiplier,
+		Unit:       req.Unit,
+		Weighted:   req.Weighted,
+		Comment:    req.Comment,
+	}
+	if err := h.usecase.Update(c.Request.Context(), barcode, estID); err != nil {
+		c.JSON(barcodeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": barcode})
+}
+
+// DeleteBarcode удаляет штрихкод
+// @Summary Удалить штрихкод
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID штрихкода"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /warehouse/barcodes/{id} [delete]
+func (h *BarcodeHandler) DeleteBarcode(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.usecase.Delete(c.Request.Context(), id, estID); err != nil {
+		c.JSON(barcodeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "barcode deleted"})
+}
+
+// LookupBarcode находит позицию по штрихкоду
+// @Summary Поиск по штрихкоду
+// @Description Возвращает позицию и количество одного сканирования: множитель упаковки или вес из весового EAN-13. Ищет также по штрихкоду в карточке ингредиента или товара.
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param code query string true "Отсканированный код"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /warehouse/barcodes/lookup [get]
+func (h *BarcodeHandler) LookupBarcode(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	match, err := h.usecase.Lookup(c.Request.Context(), estID, c.Query("code"))
+	if err != nil {
+		c.JSON(barcodeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": match})
+}
+
+// ——— Scanning ———
+
+// ScanSupply добавляет позицию в поставку по штрихкоду
+// @Summary Сканирование в поставку
+// @Description Добавляет отсканированную позицию в непроведенную поставку (pending) или увеличивает количество существующей строки. Остатки, журнал движений и партии приходуются при проведении: с complete=true сразу после сканирования или при переводе поставки в completed
+// @Tags warehouse
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID поставки"
+// @Param request body ScanSupplyRequest true "Отсканированный код"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /warehouse/supplies/{id}/scan [post]
+func (h *BarcodeHandler) ScanSupply(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req ScanSupplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	supply, match, err := h.usecase.ScanSupply(c.Request.Context(), id, estID, req.Code, req.Quantity, req.PricePerUnit, req.Complete)
+	if err != nil {
+		c.JSON(barcodeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": supply, "match": match})
+}
+
+// ScanInventory прибавляет позицию к подсчету инвентаризации по штрихкоду
+// @Summary Сканирование в инвентаризацию
+// @Description Прибавляет отсканированное количество к позиции инвентаризации (в режиме multi — к подсчету текущего пользователя в зоне). Позиция, которой нет в инвентаризации, добавляется.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID инвентаризации"
+// @Param request body ScanInventoryRequest true "Отсканированный код"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /inventory/{id}/scan [post]
+func (h *BarcodeHandler) ScanInventory(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not identified"})
+		return
+	}
+
+	var req ScanInventoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, match, err := h.usecase.ScanInventory(c.Request.Context(), id, estID, userID, req.Code, req.Quantity, req.Zone)
+	if err != nil {
+		c.JSON(barcodeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": item, "match": match})
+}
+
+// ScanOrder добавляет товар в заказ по штрихкоду
+// @Summary Сканирование в заказ
+// @Description Добавляет в заказ товар или техкарту по штрихкоду с учетом множителя упаковки
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param order_id path string true "ID заказа"
+// @Param request body ScanOrderRequest true "Отсканированный код"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /orders/{order_id}/scan [post]
+func (h *BarcodeHandler) ScanOrder(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	orderID, err := uuid.Parse(c.Param("order_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req ScanOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, match, err := h.usecase.ScanOrder(c.Request.Context(), orderID, estID, req.Code, req.Quantity, req.GuestNumber)
+	if err != nil {
+		if insufficientStockResponse(c, err) {
+			return
+		}
+		c.JSON(barcodeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": order, "match": match})
+}
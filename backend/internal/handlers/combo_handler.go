@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+	"github.com/yourusername/arc/backend/internal/usecases"
+)
+
+type ComboHandler struct {
+	usecase *usecases.ComboUseCase
+	logger  *zap.Logger
+}
+
+func NewComboHandler(usecase *usecases.ComboUseCase, logger *zap.Logger) *ComboHandler {
+	return &ComboHandler{
+		usecase: usecase,
+		logger:  logger,
+	}
+}
+
+// ——— Requests ———
+
+// ComboOptionRequest позиция группы комбо: ровно одно из product_id, tech_card_id
+type ComboOptionRequest struct {
+	ProductID  *uuid.UUID `json:"product_id,omitempty"`
+	TechCardID *uuid.UUID `json:"tech_card_id,omitempty"`
+	Surcharge  float64    `json:"surcharge" example:"50"` // Доплата за выбор позиции
+	IsDefault  bool       `json:"is_default"`
+}
+
+// ComboGroupRequest группа компонентов комбо
+type ComboGroupRequest struct {
+	Name      string               `json:"name" binding:"required" example:"Напиток"`
+	Required  *bool                `json:"required,omitempty"` // По умолчанию true
+	SortOrder int                  `json:"sort_order"`
+	Options   []ComboOptionRequest `json:"options" binding:"required,min=1,dive"`
+}
+
+// ComboRequest комбо или сет-меню
+type ComboRequest struct {
+	CategoryID             uuid.UUID           `json:"category_id" binding:"required"`
+	Name                   string              `json:"name" binding:"required" example:"Бизнес-ланч"`
+	Description            string              `json:"description"`
+	CoverImage             string              `json:"cover_image"`
+	Price                  float64             `json:"price" binding:"min=0" example:"450"` // Базовая цена без доплат
+	AvailabilityScheduleID *uuid.UUID          `json:"availability_schedule_id,omitempty"`
+	Active                 *bool               `json:"active,omitempty"`
+	Groups                 []ComboGroupRequest `json:"groups" binding:"required,min=1,dive"`
+}
+
+func (r *ComboRequest) toModel() *models.Combo {
+	combo := &models.Combo{
+		CategoryID:             r.CategoryID,
+		Name:                   r.Name,
+		Description:            r.Description,
+		CoverImage:             r.CoverImage,
+		Price:                  r.Price,
+		AvailabilityScheduleID: r.AvailabilityScheduleID,
+		Active:                 true,
+		Groups:                 make([]models.ComboGroup, 0, len(r.Groups)),
+	}
+	if r.Active != nil {
+		combo.Active = *r.Active
+	}
+	for _, g := range r.Groups {
+		group := models.ComboGroup{
+			Name:      g.Name,
+			Required:  true,
+			SortOrder: g.SortOrder,
+			Options:   make([]models.ComboOption, 0, len(g.Options)),
+		}
+		if g.Required != nil {
+			group.Required = *g.Required
+		}
+		for _, o := range g.Options {
+			group.Options = append(group.Options, models.ComboOption{
+				ProductID:  o.ProductID,
+				TechCardID: o.TechCardID,
+				Surcharge:  o.Surcharge,
+				IsDefault:  o.IsDefault,
+			})
+		}
+		combo.Groups = append(combo.Groups, group)
+	}
+	return combo
+}
+
+// ——— Combos ———
+
+// List возвращает комбо заведения
+// @Summary Список комбо
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Param category_id query string false "ID категории"
+// @Param search query string false "Поиск по названию"
+// @Param active query bool false "Только активные"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu/combos [get]
+func (h *ComboHandler) List(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := &repositories.ComboFilter{EstablishmentID: &estID}
+	if categoryID := c.Query("category_id"); categoryID != "" {
+		if id, err := uuid.Parse(categoryID); err == nil {
+			filter.CategoryID = &id
+		}
+	}
+	if search := c.Query("search"); search != "" {
+		filter.Search = &search
+	}
+	if active := c.Query("active"); active != "" {
+		activeBool := active == "true"
+		filter.Active = &activeBool
+	}
+
+	combos, err := h.usecase.List(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.Error("Failed to list combos", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list combos"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": combos})
+}
+
+// Get возвращает комбо с группами и позициями
+// @Summary Получить комбо
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID комбо"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /menu/combos/{id} [get]
+func (h *ComboHandler) Get(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	combo, err := h.usecase.Get(c.Request.Context(), id, estID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": combo})
+}
+
+// Create создает комбо
+// @Summary Создать комбо
+// @Description Комбо продается за базовую цену; гость выбирает по одной позиции из каждой группы, выбор может стоить доплату. Необязательную группу можно пропустить, для обязательной без выбора берется позиция по умолчанию
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body ComboRequest true "Комбо"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/combos [post]
+func (h *ComboHandler) Create(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	var req ComboRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	combo := req.toModel()
+	if err := h.usecase.Create(c.Request.Context(), combo, estID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": combo})
+}
+
+// Update обновляет комбо
+// @Summary Обновить комбо
+// @Description Группы и позиции заменяются целиком; проданные комбо сохраняют компоненты на момент заказа
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID комбо"
+// @Param request body ComboRequest true "Комбо"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/combos/{id} [put]
+func (h *ComboHandler) Update(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req ComboRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	combo := req.toModel()
+	combo.ID = id
+	if err := h.usecase.Update(c.Request.Context(), combo, estID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": combo})
+}
+
+// Delete удаляет комбо
+// @Summary Удалить комбо
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID комбо"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/combos/{id} [delete]
+func (h *ComboHandler) Delete(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.usecase.Delete(c.Request.Context(), id, estID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "combo deleted"})
+}
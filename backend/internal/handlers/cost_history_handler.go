@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+	"github.com/yourusername/arc/backend/internal/usecases"
+)
+
+type CostHistoryHandler struct {
+	usecase *usecases.CostHistoryUseCase
+	logger  *zap.Logger
+}
+
+func NewCostHistoryHandler(usecase *usecases.CostHistoryUseCase, logger *zap.Logger) *CostHistoryHandler {
+	return &CostHistoryHandler{
+		usecase: usecase,
+		logger:  logger,
+	}
+}
+
+// ——— Requests ———
+
+// FoodCostThresholdRequest порог фудкоста заведения
+type FoodCostThresholdRequest struct {
+	// FoodCostThreshold доля себестоимости в цене, %, выше которой создается уведомление (0 — не уведомлять)
+	FoodCostThreshold float64 `json:"food_cost_threshold" binding:"gte=0,lte=100" example:"35"`
+}
+
+type RecalculateCostsRequest struct {
+	WarehouseID *string `json:"warehouse_id,omitempty" binding:"omitempty,uuid"` // Склад, по ценам которого считать (по умолчанию — первый активный)
+}
+
+// ——— Cost history ———
+
+// GetTechCardCostHistory возвращает историю себестоимости тех-карты
+// @Summary История себестоимости тех-карты
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID тех-карты"
+// @Param start_date query string false "Начало периода (RFC3339)"
+// @Param end_date query string false "Конец периода (RFC3339)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/tech-cards/{id}/cost-history [get]
+func (h *CostHistoryHandler) GetTechCardCostHistory(c *gin.Context) {
+	h.getCostHistory(c, usecases.MenuItemTypeTechCard)
+}
+
+// GetProductCostHistory возвращает историю себестоимости товара
+// @Summary История себестоимости товара
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID товара"
+// @Param start_date query string false "Начало периода (RFC3339)"
+// @Param end_date query string false "Конец периода (RFC3339)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/products/{id}/cost-history [get]
+func (h *CostHistoryHandler) GetProductCostHistory(c *gin.Context) {
+	h.getCostHistory(c, usecases.MenuItemTypeProduct)
+}
+
+func (h *CostHistoryHandler) getCostHistory(c *gin.Context, itemType string) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var start, end *time.Time
+	if s := c.Query("start_date"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format, expected RFC3339"})
+			return
+		}
+		start = &t
+	}
+	if s := c.Query("end_date"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date format, expected RFC3339"})
+			return
+		}
+		end = &t
+	}
+
+	var techCardID, productID *uuid.UUID
+	if itemType == usecases.MenuItemTypeTechCard {
+		techCardID = &id
+	} else {
+		productID = &id
+	}
+	list, err := h.usecase.GetCostHistory(c.Request.Context(), estID, techCardID, productID, start, end)
+	if err != nil {
+		h.logger.Error("Failed to get cost history", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get cost history"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// Recalculate пересчитывает себестоимость всего активного меню
+// @Summary Пересчитать себестоимость меню
+// @Description Пересчитывает себестоимость тех-карт и товаров по текущим ценам склада, записывает изменения в историю и обновляет уведомления о фудкосте. Цены продажи не меняются
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body RecalculateCostsRequest false "Склад"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/food-cost/recalculate [post]
+func (h *CostHistoryHandler) Recalculate(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	var req RecalculateCostsRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	changed, err := h.usecase.RecalculateAll(c.Request.Context(), estID, parseOptionalUUID(req.WarehouseID))
+	if err != nil {
+		h.logger.Error("Failed to recalculate menu costs", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": changed})
+}
+
+// GetMarginDropReport возвращает позиции, маржа которых снизилась сильнее всего
+// @Summary Падение маржи
+// @Description Сравнивает маржу позиций на начало и конец периода (по умолчанию — текущий месяц) и возвращает позиции с падением маржи, начиная с наибольшего
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Param start_date query string false "Начало периода (RFC3339, по умолчанию — начало месяца)"
+// @Param end_date query string false "Конец периода (RFC3339, по умолчанию — сейчас)"
+// @Param limit query int false "Количество позиций (по умолчанию все)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu/food-cost/margin-drop [get]
+func (h *CostHistoryHandler) GetMarginDropReport(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	end := now
+	if s := c.Query("start_date"); s != "" {
+		if start, err = time.Parse(time.RFC3339, s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format, expected RFC3339"})
+			return
+		}
+	}
+	if s := c.Query("end_date"); s != "" {
+		if end, err = time.Parse(time.RFC3339, s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date format, expected RFC3339"})
+			return
+		}
+	}
+	if !end.After(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must be after start_date"})
+		return
+	}
+	limit := 0
+	if s := c.Query("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+
+	rows, err := h.usecase.GetMarginDropReport(c.Request.Context(), estID, start, end, limit)
+	if err != nil {
+		h.logger.Error("Failed to get margin drop report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get margin drop report"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rows, "start_date": start, "end_date": end})
+}
+
+// ——— Food cost alerts ———
+
+// GetThreshold возвращает порог фудкоста заведения
+// @Summary Получить порог фудкоста
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /menu/food-cost/threshold [get]
+func (h *CostHistoryHandler) GetThreshold(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	threshold, err := h.usecase.GetFoodCostThreshold(c.Request.Context(), estID)
+	if err != nil {
+		h.logger.Error("Failed to get food cost threshold", zap.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{"error": "establishment not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"food_cost_threshold": threshold}})
+}
+
+// UpdateThreshold меняет порог фудкоста заведения
+// @Summary Изменить порог фудкоста
+// @Description Сохраняет порог и сразу пересматривает уведомления по последней известной себестоимости позиций
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body FoodCostThresholdRequest true "Порог"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu/food-cost/threshold [put]
+func (h *CostHistoryHandler) UpdateThreshold(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	var req FoodCostThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.usecase.SetFoodCostThreshold(c.Request.Context(), estID, req.FoodCostThreshold); err != nil {
+		h.logger.Error("Failed to update food cost threshold", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update food cost threshold"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"food_cost_threshold": req.FoodCostThreshold}})
+}
+
+// ListAlerts возвращает уведомления о превышении фудкоста
+// @Summary Уведомления о фудкосте
+// @Description Возвращает уведомления о позициях с фудкостом выше порога. По умолчанию только активные (open, acknowledged)
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Param status query string false "Статус (open, acknowledged, resolved)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu/food-cost/alerts [get]
+func (h *CostHistoryHandler) ListAlerts(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := &repositories.FoodCostAlertFilter{}
+	if s := c.Query("status"); s != "" {
+		status := models.StockAlertStatus(s)
+		filter.Status = &status
+	} else {
+		filter.OnlyActive = true
+	}
+
+	list, err := h.usecase.ListAlerts(c.Request.Context(), estID, filter)
+	if err != nil {
+		h.logger.Error("Failed to list food cost alerts", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list food cost alerts"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// AcknowledgeAlert отмечает уведомление о фудкосте как просмотренное
+// @Summary Подтвердить уведомление о фудкосте
+// @Description Переводит уведомление в статус acknowledged. Уведомление закрывается автоматически, когда фудкост опустится до порога
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID уведомления"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/food-cost/alerts/{id}/acknowledge [put]
+func (h *CostHistoryHandler) AcknowledgeAlert(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var userID *uuid.UUID
+	if uid, ok := currentUserID(c); ok {
+		userID = &uid
+	}
+
+	alert, err := h.usecase.AcknowledgeAlert(c.Request.Context(), id, estID, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": alert})
+}
@@ -140,6 +140,134 @@ func (h *EstablishmentHandler) GetEstablishmentSettings(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// StockPolicyRequest политика продажи при нехватке остатков
+type StockPolicyRequest struct {
+	// NegativeStockPolicy allow — продавать в минус, warn — продавать с предупреждением, block — не продавать
+	NegativeStockPolicy string `json:"negative_stock_policy" binding:"required,oneof=allow warn block" example:"warn"`
+}
+
+// GetStockPolicy возвращает политику продажи при нехватке остатков
+// @Summary Получить политику нехватки остатков
+// @Description Возвращает политику продажи позиций, на которые не хватает остатков: allow — остаток уходит в минус, warn — заказ принимается с предупреждением, block — заказ отклоняется
+// @Tags establishments
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /establishments/me/stock-policy [get]
+func (h *EstablishmentHandler) GetStockPolicy(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.usecase.GetStockPolicy(c.Request.Context(), estID)
+	if err != nil {
+		h.logger.Error("Failed to get stock policy", zap.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{"error": "establishment not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"negative_stock_policy": policy}})
+}
+
+// UpdateStockPolicy меняет политику продажи при нехватке остатков
+// @Summary Изменить политику нехватки остатков
+// @Tags establishments
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body StockPolicyRequest true "Политика"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /establishments/me/stock-policy [put]
+func (h *EstablishmentHandler) UpdateStockPolicy(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req StockPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.usecase.SetStockPolicy(c.Request.Context(), estID, req.NegativeStockPolicy); err != nil {
+		h.logger.Error("Failed to update stock policy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update stock policy"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"negative_stock_policy": req.NegativeStockPolicy}})
+}
+
+// TimezoneRequest часовой пояс заведения
+type TimezoneRequest struct {
+	// Timezone часовой пояс IANA; пусто — время сервера
+	Timezone string `json:"timezone" example:"Europe/Moscow"`
+}
+
+// GetTimezone возвращает часовой пояс заведения
+// @Summary Получить часовой пояс заведения
+// @Description Часовой пояс, в котором действуют расписания доступности меню
+// @Tags establishments
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /establishments/me/timezone [get]
+func (h *EstablishmentHandler) GetTimezone(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	timezone, err := h.usecase.GetTimezone(c.Request.Context(), estID)
+	if err != nil {
+		h.logger.Error("Failed to get timezone", zap.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{"error": "establishment not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"timezone": timezone}})
+}
+
+// UpdateTimezone меняет часовой пояс заведения
+// @Summary Изменить часовой пояс заведения
+// @Tags establishments
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body TimezoneRequest true "Часовой пояс"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /establishments/me/timezone [put]
+func (h *EstablishmentHandler) UpdateTimezone(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req TimezoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.usecase.SetTimezone(c.Request.Context(), estID, req.Timezone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"timezone": req.Timezone}})
+}
+
 // List возвращает заведение пользователя (создаётся при onboarding). 0 или 1 элемент.
 // @Summary Получить список заведений
 // @Description Возвращает заведения пользователя (обычно 0 или 1 элемент). Возвращает массив заведений с полями: id, owner_id, name, address, phone, email, has_seating_places, table_count, type, tables, active, created_at, updated_at
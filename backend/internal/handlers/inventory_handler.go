@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
@@ -32,6 +33,9 @@ type CreateInventoryRequest struct {
 	Type          models.InventoryType          `json:"type" binding:"required,oneof=full partial"`
 	ScheduledDate *string                       `json:"scheduled_date"` // RFC3339 format
 	Comment       string                        `json:"comment"`
+	WriteOffReasonID *string                    `json:"write_off_reason_id" binding:"omitempty,uuid"` // Причина списания недостачи (блок ОПиУ)
+	CountMode     models.InventoryCountMode     `json:"count_mode" binding:"omitempty,oneof=single multi"` // multi — подсчеты нескольких счетчиков по зонам
+	Blind         bool                          `json:"blind"`                                             // Слепой пересчет
 	Items         []CreateInventoryItemRequest  `json:"items"`
 }
 
@@ -51,12 +55,67 @@ type UpdateInventoryItemRequest struct {
 }
 
 type UpdateInventoryStatusRequest struct {
-	Status models.InventoryStatus `json:"status" binding:"required,oneof=draft in_progress completed cancelled"`
+	Status           models.InventoryStatus `json:"status" binding:"required,oneof=draft in_progress completed cancelled"`
+	WriteOffReasonID *string                `json:"write_off_reason_id" binding:"omitempty,uuid"` // Причина списания недостачи при завершении
 }
 
 type UpdateInventoryRequest struct {
-	ScheduledDate *string `json:"scheduled_date"`
-	Comment       string  `json:"comment"`
+	ScheduledDate    *string `json:"scheduled_date"`
+	Comment          string  `json:"comment"`
+	WriteOffReasonID *string `json:"write_off_reason_id" binding:"omitempty,uuid"`
+	CountMode        models.InventoryCountMode `json:"count_mode" binding:"omitempty,oneof=single multi"`
+	Blind            *bool   `json:"blind"`
+}
+
+type InventoryCountRequest struct {
+	ItemID   string  `json:"item_id" binding:"required,uuid"`
+	Zone     string  `json:"zone"`     // Зона или полка
+	Quantity float64 `json:"quantity" binding:"gte=0"`
+	Unit     string  `json:"unit"`     // Единица подсчета (по умолчанию — единица позиции)
+	Comment  string  `json:"comment"`
+}
+
+type SubmitInventoryCountsRequest struct {
+	Counts []InventoryCountRequest `json:"counts" binding:"required,min=1,dive"`
+}
+
+func (r InventoryCountRequest) toInput() usecases.InventoryCountInput {
+	itemID, _ := uuid.Parse(r.ItemID)
+	return usecases.InventoryCountInput{
+		ItemID:   itemID,
+		Zone:     r.Zone,
+		Quantity: r.Quantity,
+		Unit:     r.Unit,
+		Comment:  r.Comment,
+	}
+}
+
+// currentUserID возвращает ID пользователя из контекста
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	v, exists := c.Get("user_id")
+	if !exists {
+		return uuid.Nil, false
+	}
+	switch id := v.(type) {
+	case uuid.UUID:
+		return id, true
+	case string:
+		parsed, err := uuid.Parse(id)
+		return parsed, err == nil
+	}
+	return uuid.Nil, false
+}
+
+// parseOptionalUUID разбирает необязательный UUID из запроса
+func parseOptionalUUID(s *string) *uuid.UUID {
+	if s == nil || *s == "" {
+		return nil
+	}
+	id, err := uuid.Parse(*s)
+	if err != nil {
+		return nil
+	}
+	return &id
 }
 
 // ——— Handlers ———
@@ -183,8 +242,11 @@ func (h *InventoryHandler) Update(c *gin.Context) {
 	}
 
 	updateReq := &usecases.UpdateInventoryRequest{
-		ScheduledDate: scheduledDate,
-		Comment:       req.Comment,
+		ScheduledDate:    scheduledDate,
+		Comment:          req.Comment,
+		WriteOffReasonID: parseOptionalUUID(req.WriteOffReasonID),
+		CountMode:        req.CountMode,
+		Blind:            req.Blind,
 	}
 
 	inventory, err := h.usecase.Update(c.Request.Context(), id, updateReq, estID)
@@ -278,6 +340,9 @@ func (h *InventoryHandler) Create(c *gin.Context) {
 		Type:          req.Type,
 		ScheduledDate: scheduledDate,
 		Comment:       req.Comment,
+		WriteOffReasonID: parseOptionalUUID(req.WriteOffReasonID),
+		CountMode:     req.CountMode,
+		Blind:         req.Blind,
 		Items:         items,
 	}
 
@@ -398,7 +463,7 @@ func (h *InventoryHandler) DeleteItem(c *gin.Context) {
 
 // UpdateStatus обновляет статус инвентаризации
 // @Summary Обновить статус инвентаризации
-// @Description Обновляет статус инвентаризации (draft -> in_progress -> completed)
+// @Description Обновляет статус инвентаризации (draft -> in_progress -> completed). При завершении остатки приводятся к фактическим: разница проводится по журналу остатков, недостача за вычетом излишков относится в ОПиУ по причине списания (write_off_reason_id; без причины — в себестоимость). Для инвентаризации задним числом учитываются движения после scheduled_date.
 // @Tags inventory
 // @Accept json
 // @Produce json
@@ -440,7 +505,7 @@ func (h *InventoryHandler) UpdateStatus(c *gin.Context) {
 		}
 	}
 
-	if err := h.usecase.UpdateStatus(c.Request.Context(), id, estID, req.Status, completedBy); err != nil {
+	if err := h.usecase.UpdateStatus(c.Request.Context(), id, estID, req.Status, completedBy, parseOptionalUUID(req.WriteOffReasonID)); err != nil {
 		h.logger.Error("Failed to update inventory status", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -535,3 +600,317 @@ func (h *InventoryHandler) GetStockSnapshot(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"data": stock})
 }
+
+// GetVarianceReport возвращает отчет о расхождениях теоретического и фактического расхода
+// @Summary Отчет о расхождениях расхода
+// @Description По каждой позиции за период: остаток на начало, поставки, теоретический расход по продажам, списания, ожидаемый и подсчитанный остаток, расхождение в количестве и деньгах
+// @Tags inventory
+// @Produce json
+// @Security Bearer
+// @Param start_date query string false "Начало периода в формате RFC3339 (по умолчанию — начало текущего месяца)"
+// @Param end_date query string false "Конец периода в формате RFC3339 (по умолчанию — текущий момент)"
+// @Param warehouse_id query string false "ID склада"
+// @Param item_type query string false "ingredient (по умолчанию) или product"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /inventory/variance [get]
+func (h *InventoryHandler) GetVarianceReport(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	end := now
+	if s := c.Query("start_date"); s != "" {
+		if start, err = time.Parse(time.RFC3339, s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format, expected RFC3339"})
+			return
+		}
+	}
+	if s := c.Query("end_date"); s != "" {
+		if end, err = time.Parse(time.RFC3339, s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date format, expected RFC3339"})
+			return
+		}
+	}
+
+	var warehouseID *uuid.UUID
+	if s := c.Query("warehouse_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+			return
+		}
+		warehouseID = &id
+	}
+
+	itemType := c.DefaultQuery("item_type", usecases.VarianceItemIngredient)
+	if itemType != usecases.VarianceItemIngredient && itemType != usecases.VarianceItemProduct {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "item_type must be ingredient or product"})
+		return
+	}
+	if !end.After(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must be after start_date"})
+		return
+	}
+
+	report, err := h.usecase.GetVarianceReport(c.Request.Context(), estID, warehouseID, itemType, start, end)
+	if err != nil {
+		h.logger.Error("Failed to get variance report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// ——— Counts ———
+
+// ListCounts возвращает подсчеты инвентаризации
+// @Summary Получить подсчеты инвентаризации
+// @Description Возвращает все подсчеты счетчиков по позициям и зонам
+// @Tags inventory
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID инвентаризации"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /inventory/{id}/counts [get]
+func (h *InventoryHandler) ListCounts(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	counts, err := h.usecase.ListCounts(c.Request.Context(), id, estID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": counts})
+}
+
+// SubmitCounts сохраняет подсчеты текущего пользователя
+// @Summary Внести подсчеты
+// @Description Сохраняет подсчеты текущего пользователя по позициям и зонам (режим multi, статус in_progress). Повторный подсчет позиции в той же зоне заменяет предыдущий. Возвращает сверку.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID инвентаризации"
+// @Param request body SubmitInventoryCountsRequest true "Подсчеты"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /inventory/{id}/counts [post]
+func (h *InventoryHandler) SubmitCounts(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not identified"})
+		return
+	}
+
+	var req SubmitInventoryCountsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	inputs := make([]usecases.InventoryCountInput, 0, len(req.Counts))
+	for _, cnt := range req.Counts {
+		inputs = append(inputs, cnt.toInput())
+	}
+
+	rec, err := h.usecase.SubmitCounts(c.Request.Context(), id, estID, userID, inputs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rec})
+}
+
+// GetReconciliation возвращает сверку подсчетов
+// @Summary Сверка подсчетов
+// @Description По каждой позиции и зоне: подсчеты счетчиков, принятое количество и расхождения. При слепом пересчете ожидаемое количество не возвращается до завершения.
+// @Tags inventory
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID инвентаризации"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /inventory/{id}/reconciliation [get]
+func (h *InventoryHandler) GetReconciliation(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	rec, err := h.usecase.GetReconciliation(c.Request.Context(), id, estID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rec})
+}
+
+// ResolveCount принимает количество позиции в зоне при расхождении
+// @Summary Сверить расхождение
+// @Description Фиксирует принятое количество позиции в зоне, когда подсчеты счетчиков разошлись
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID инвентаризации"
+// @Param request body InventoryCountRequest true "Принятое количество"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /inventory/{id}/reconcile [post]
+func (h *InventoryHandler) ResolveCount(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not identified"})
+		return
+	}
+
+	var req InventoryCountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rec, err := h.usecase.ResolveCount(c.Request.Context(), id, estID, userID, req.toInput())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rec})
+}
+
+// ExportCountSheet выгружает лист подсчета
+// @Summary Лист подсчета
+// @Description Выгружает лист подсчета для печати (PDF) или заполнения (CSV). При слепом пересчете учетное количество не выводится. Заполненный CSV загружается через /inventory/{id}/counts/import.
+// @Tags inventory
+// @Produce octet-stream
+// @Security Bearer
+// @Param id path string true "ID инвентаризации"
+// @Param format query string false "Формат: csv (по умолчанию) или pdf"
+// @Param zone query string false "Зона, для которой печатается лист"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /inventory/{id}/count-sheet [get]
+func (h *InventoryHandler) ExportCountSheet(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	data, contentType, filename, err := h.usecase.ExportCountSheet(c.Request.Context(), id, estID, c.Query("zone"), c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ImportCounts загружает подсчеты из CSV
+// @Summary Импорт подсчетов из CSV
+// @Description Загружает заполненный лист подсчета (колонки item_id, zone, counted_quantity) как подсчеты текущего пользователя
+// @Tags inventory
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID инвентаризации"
+// @Param file formData file true "CSV файл"
+// @Param zone formData string false "Зона для строк без зоны"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /inventory/{id}/counts/import [post]
+func (h *InventoryHandler) ImportCounts(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not identified"})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to open file"})
+		return
+	}
+	defer src.Close()
+
+	result, err := h.usecase.ImportCounts(c.Request.Context(), id, estID, userID, c.PostForm("zone"), src)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
@@ -47,6 +47,7 @@ type CreateProductRequest struct {
 	ExcludeFromDiscounts bool `json:"exclude_from_discounts"`
 	HasModifications  bool    `json:"has_modifications"`
 	Barcode           string  `json:"barcode"`
+	ExternalCode      string  `json:"external_code"` // Код во внешней системе
 	CostPrice         float64 `json:"cost_price"`
 	Markup            float64 `json:"markup"`
 	Price             float64 `json:"price,omitempty"` // Можно задать напрямую, иначе вычисляется
@@ -63,12 +64,24 @@ type UpdateProductRequest struct {
 	ExcludeFromDiscounts bool `json:"exclude_from_discounts"`
 	HasModifications  bool    `json:"has_modifications"`
 	Barcode           string  `json:"barcode"`
+	ExternalCode      *string `json:"external_code,omitempty"`
 	CostPrice         float64 `json:"cost_price"`
 	Markup            float64 `json:"markup"`
 	Price             float64 `json:"price,omitempty"`
 	Active            *bool   `json:"active,omitempty"`
 }
 
+// CreateProductVariantRequest модификация товара (например, 0,33 л или красный цвет)
+type CreateProductVariantRequest struct {
+	Name        string  `json:"name" binding:"required" example:"Кола 0,5 л"`
+	Barcode     string  `json:"barcode"`
+	CoverImage  string  `json:"cover_image"` // По умолчанию — обложка родительского товара
+	CostPrice   float64 `json:"cost_price"`
+	Markup      float64 `json:"markup"`
+	Price       float64 `json:"price,omitempty"` // Можно задать напрямую, иначе вычисляется
+	WarehouseID string  `json:"warehouse_id" binding:"required,uuid"` // UUID склада для создания остатков
+}
+
 // GetProducts возвращает список товаров с фильтрацией
 // @Summary Получить список товаров
 // @Description Возвращает список товаров с возможностью фильтрации по категории, цеху, поиску и активности
@@ -79,6 +92,8 @@ type UpdateProductRequest struct {
 // @Param workshop_id query string false "ID цеха"
 // @Param search query string false "Поиск по названию"
 // @Param active query bool false "Фильтр по активности"
+// @Param available_now query bool false "Только доступные сейчас по расписанию"
+// @Param flat query bool false "Модификации отдельными позициями списка (по умолчанию вложены в родительский товар)"
 // @Success 200 {object} map[string]interface{}
 // @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
@@ -108,6 +123,8 @@ func (h *MenuHandler) GetProducts(c *gin.Context) {
 		activeBool := active == "true"
 		filter.Active = &activeBool
 	}
+	filter.AvailableNow = c.Query("available_now") == "true"
+	filter.OnlyParents = c.Query("flat") != "true"
 
 	products, err := h.usecase.GetProducts(c.Request.Context(), filter)
 	if err != nil {
@@ -144,7 +161,7 @@ func (h *MenuHandler) ListProductsByCategory(c *gin.Context) {
 		return
 	}
 
-	filter := &repositories.ProductFilter{EstablishmentID: &estID, CategoryID: &categoryID}
+	filter := &repositories.ProductFilter{EstablishmentID: &estID, CategoryID: &categoryID, OnlyParents: true}
 	products, err := h.usecase.GetProducts(c.Request.Context(), filter)
 	if err != nil {
 		h.logger.Error("Failed to get products by category", zap.Error(err))
@@ -248,6 +265,7 @@ func (h *MenuHandler) CreateProduct(c *gin.Context) {
 		ExcludeFromDiscounts: req.ExcludeFromDiscounts,
 		HasModifications:    req.HasModifications,
 		Barcode:             req.Barcode,
+		ExternalCode:        req.ExternalCode,
 		CostPrice:           req.CostPrice,
 		Markup:              req.Markup,
 		Active:              true,
@@ -335,6 +353,9 @@ func (h *MenuHandler) UpdateProduct(c *gin.Context) {
 	product.ExcludeFromDiscounts = req.ExcludeFromDiscounts
 	product.HasModifications = req.HasModifications
 	product.Barcode = req.Barcode
+	if req.ExternalCode != nil {
+		product.ExternalCode = *req.ExternalCode
+	}
 	product.CostPrice = req.CostPrice
 	product.Markup = req.Markup
 	if req.Price > 0 {
@@ -387,11 +408,98 @@ func (h *MenuHandler) DeleteProduct(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "product deleted"})
 }
 
+// ——— Product variants ———
+
+// GetProductVariants возвращает модификации товара
+// @Summary Модификации товара
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID товара"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /menu/products/{id}/variants [get]
+func (h *MenuHandler) GetProductVariants(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	variants, err := h.usecase.GetProductVariants(c.Request.Context(), id, estID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": variants})
+}
+
+// CreateProductVariant создает модификацию товара
+// @Summary Создать модификацию товара
+// @Description Модификация — отдельная позиция продажи со своими названием, ценой, себестоимостью, штрихкодом и остатком. Категория, цех, склад и опции наследуются от товара, товар помечается has_modifications и продается только через модификации. Изменение и удаление модификации — через PUT/DELETE /menu/products/{id}
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID родительского товара"
+// @Param variant body CreateProductVariantRequest true "Модификация"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/products/{id}/variants [post]
+func (h *MenuHandler) CreateProductVariant(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req CreateProductVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	warehouseID, err := uuid.Parse(req.WarehouseID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+
+	variant := &models.Product{
+		Name:       req.Name,
+		Barcode:    req.Barcode,
+		CoverImage: req.CoverImage,
+		CostPrice:  req.CostPrice,
+		Markup:     req.Markup,
+		Active:     true,
+	}
+	if req.Price > 0 {
+		variant.Price = req.Price
+	}
+
+	if err := h.usecase.CreateProductVariant(c.Request.Context(), id, variant, warehouseID, estID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": variant})
+}
+
 type CreateTechCardRequest struct {
 	Name              string                        `json:"name" binding:"required"`
 	CategoryID        string                        `json:"category_id" binding:"required,uuid"`
 	WorkshopID        *string                       `json:"workshop_id,omitempty" binding:"omitempty,uuid"`
 	Description       string                        `json:"description"`
+	ExternalCode      string                        `json:"external_code"` // Код во внешней системе
 	CoverImage        string                        `json:"cover_image"`
 	IsWeighted        bool                          `json:"is_weighted"`
 	ExcludeFromDiscounts bool                       `json:"exclude_from_discounts"`
@@ -404,10 +512,39 @@ type CreateTechCardRequest struct {
 	ModifierSets      []ModifierSetRequest          `json:"modifier_sets,omitempty"`
 }
 
+// TechCardIngredientRequest позиция тех-карты: ингредиент или полуфабрикат (указывается ровно одно из полей)
 type TechCardIngredientRequest struct {
-	IngredientID string  `json:"ingredient_id" binding:"required,uuid"`
-	Quantity     float64 `json:"quantity" binding:"required"`
-	Unit         string  `json:"unit" binding:"required"` // кг, л, шт и т.д.
+	IngredientID   string  `json:"ingredient_id,omitempty" binding:"omitempty,uuid"`
+	SemiFinishedID string  `json:"semi_finished_id,omitempty" binding:"omitempty,uuid"`
+	Quantity       float64 `json:"quantity" binding:"required"` // Нетто
+	Unit           string  `json:"unit" binding:"required"` // кг, г, л, мл, шт; должна приводиться к единице ингредиента
+	// Способы приготовления через запятую (cleaning, boiling, frying, stewing, baking); потери применяются последовательно
+	PreparationMethod *string `json:"preparation_method,omitempty"`
+}
+
+func (r TechCardIngredientRequest) toModel() (models.TechCardIngredient, error) {
+	ing := models.TechCardIngredient{
+		Quantity:          r.Quantity,
+		Unit:              r.Unit,
+		PreparationMethod: r.PreparationMethod,
+	}
+	if (r.IngredientID == "") == (r.SemiFinishedID == "") {
+		return ing, errors.New("exactly one of ingredient_id or semi_finished_id must be specified")
+	}
+	if r.IngredientID != "" {
+		id, err := uuid.Parse(r.IngredientID)
+		if err != nil {
+			return ing, errors.New("invalid ingredient_id")
+		}
+		ing.IngredientID = &id
+	} else {
+		id, err := uuid.Parse(r.SemiFinishedID)
+		if err != nil {
+			return ing, errors.New("invalid semi_finished_id")
+		}
+		ing.SemiFinishedID = &id
+	}
+	return ing, nil
 }
 
 type ModifierSetRequest struct {
@@ -428,6 +565,7 @@ type UpdateTechCardRequest struct {
 	CategoryID        *string                       `json:"category_id,omitempty" binding:"omitempty,uuid"`
 	WorkshopID        *string                       `json:"workshop_id,omitempty" binding:"omitempty,uuid"`
 	Description       string                        `json:"description"`
+	ExternalCode      *string                       `json:"external_code,omitempty"`
 	CoverImage        *string                       `json:"cover_image,omitempty"`
 	IsWeighted        bool                          `json:"is_weighted"`
 	ExcludeFromDiscounts bool                       `json:"exclude_from_discounts"`
@@ -451,6 +589,8 @@ type UpdateTechCardRequest struct {
 // @Param workshop_id query string false "ID цеха"
 // @Param search query string false "Поиск по названию"
 // @Param active query bool false "Фильтр по активности"
+// @Param exclude_allergens query string false "Исключить тех-карты с аллергенами (через запятую: gluten,milk)"
+// @Param available_now query bool false "Только доступные сейчас по расписанию"
 // @Success 200 {object} map[string]interface{}
 // @Router /menu/tech-cards [get]
 func (h *MenuHandler) GetTechCards(c *gin.Context) {
@@ -478,6 +618,15 @@ func (h *MenuHandler) GetTechCards(c *gin.Context) {
 		activeBool := active == "true"
 		filter.Active = &activeBool
 	}
+	filter.AvailableNow = c.Query("available_now") == "true"
+	if exclude := c.Query("exclude_allergens"); exclude != "" {
+		allergens, err := models.ParseAllergens([]string{exclude})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter.ExcludeAllergens = allergens
+	}
 
 	techCards, err := h.usecase.GetTechCards(c.Request.Context(), filter)
 	if err != nil {
@@ -488,6 +637,43 @@ func (h *MenuHandler) GetTechCards(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": techCards})
 }
 
+// GetPublicMenu возвращает меню заведения для гостей
+// @Summary Публичное меню заведения
+// @Description Возвращает активные тех-карты и товары по категориям с пищевой ценностью порции и аллергенами. Авторизация не требуется
+// @Tags menu
+// @Produce json
+// @Param id path string true "ID заведения"
+// @Param exclude_allergens query string false "Исключить тех-карты с аллергенами (через запятую: gluten,milk)"
+// @Param available_now query bool false "Только доступные сейчас по расписанию"
+// @Success 200 {object} models.PublicMenu
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /public/establishments/{id}/menu [get]
+func (h *MenuHandler) GetPublicMenu(c *gin.Context) {
+	estID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid establishment id"})
+		return
+	}
+
+	var excludeAllergens []string
+	if exclude := c.Query("exclude_allergens"); exclude != "" {
+		excludeAllergens, err = models.ParseAllergens([]string{exclude})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	menu, err := h.usecase.GetPublicMenu(c.Request.Context(), estID, excludeAllergens, c.Query("available_now") == "true")
+	if err != nil {
+		h.logger.Error("Failed to get public menu", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get menu"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": menu})
+}
+
 // ListTechCardsByCategory возвращает список тех-карт по заданной категории
 // @Summary Получить список тех-карт по категории
 // @Description Возвращает список тех-карт, принадлежащих указанной категории.
@@ -607,6 +793,7 @@ func (h *MenuHandler) CreateTechCard(c *gin.Context) {
 		CategoryID:          categoryID,
 		WorkshopID:          workshopID,
 		Description:         req.Description,
+		ExternalCode:        req.ExternalCode,
 		CoverImage:          req.CoverImage,
 		IsWeighted:          req.IsWeighted,
 		ExcludeFromDiscounts: req.ExcludeFromDiscounts,
@@ -622,16 +809,12 @@ func (h *MenuHandler) CreateTechCard(c *gin.Context) {
 
 	// Добавляем ингредиенты
 	for _, ingReq := range req.Ingredients {
-		ingredientID, err := uuid.Parse(ingReq.IngredientID)
+		ing, err := ingReq.toModel()
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ingredient_id"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		techCard.Ingredients = append(techCard.Ingredients, models.TechCardIngredient{
-			IngredientID: ingredientID,
-			Quantity:     ingReq.Quantity,
-			Unit:         ingReq.Unit,
-		})
+		techCard.Ingredients = append(techCard.Ingredients, ing)
 	}
 
 	// Добавляем наборы модификаторов
@@ -732,6 +915,9 @@ func (h *MenuHandler) UpdateTechCard(c *gin.Context) {
 		techCard.WorkshopID = &workshopID
 	}
 	techCard.Description = req.Description
+	if req.ExternalCode != nil {
+		techCard.ExternalCode = *req.ExternalCode
+	}
 	if req.CoverImage != nil {
 		techCard.CoverImage = *req.CoverImage
 	}
@@ -750,16 +936,12 @@ func (h *MenuHandler) UpdateTechCard(c *gin.Context) {
 	if req.Ingredients != nil {
 		techCard.Ingredients = []models.TechCardIngredient{}
 		for _, ingReq := range req.Ingredients {
-			ingredientID, err := uuid.Parse(ingReq.IngredientID)
+			ing, err := ingReq.toModel()
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ingredient_id"})
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			techCard.Ingredients = append(techCard.Ingredients, models.TechCardIngredient{
-				IngredientID: ingredientID,
-				Quantity:     ingReq.Quantity,
-				Unit:         ingReq.Unit,
-			})
+			techCard.Ingredients = append(techCard.Ingredients, ing)
 		}
 	}
 
@@ -840,31 +1022,70 @@ func (h *MenuHandler) DeleteTechCard(c *gin.Context) {
 type CreateIngredientRequest struct {
 	Name          string  `json:"name" binding:"required"`
 	CategoryID    string  `json:"category_id" binding:"required,uuid"`
-	Unit          string  `json:"unit" binding:"required,oneof=шт л кг"`
+	Unit          string  `json:"unit" binding:"required,oneof=шт кг г л мл"`
 	Barcode       string  `json:"barcode"`
+	ExternalCode  string  `json:"external_code"` // Код во внешней системе
+	ShelfLifeDays int     `json:"shelf_life_days" binding:"gte=0"` // Срок годности по умолчанию, дней (0 — не отслеживается)
 	LossCleaning  float64 `json:"loss_cleaning"`
 	LossBoiling   float64 `json:"loss_boiling"`
 	LossFrying    float64 `json:"loss_frying"`
 	LossStewing   float64 `json:"loss_stewing"`
 	LossBaking    float64 `json:"loss_baking"`
+	// Аллергены (gluten, milk, eggs, ...) и пищевая ценность на 100 г
+	Allergens     []string `json:"allergens,omitempty"`
+	Calories      float64  `json:"calories" binding:"gte=0"`
+	Proteins      float64  `json:"proteins" binding:"gte=0"`
+	Fats          float64  `json:"fats" binding:"gte=0"`
+	Carbohydrates float64  `json:"carbohydrates" binding:"gte=0"`
 	// Складской учет (опционально)
 	WarehouseID   *string `json:"warehouse_id,omitempty" binding:"omitempty,uuid"`
 	SupplierID    *string `json:"supplier_id,omitempty" binding:"omitempty,uuid"` // Поставщик для автоматического создания поставки
 	Quantity      float64 `json:"quantity"` // Количество в наличии
 	PricePerUnit  float64 `json:"price_per_unit"` // Цена за единицу измерения
+	// Пересчеты единиц (например, 1 шт = 0.05 кг)
+	UnitConversions []IngredientUnitConversionRequest `json:"unit_conversions,omitempty"`
+}
+
+// IngredientUnitConversionRequest пересчет единиц ингредиента: 1 unit = factor target_unit
+type IngredientUnitConversionRequest struct {
+	Unit       string  `json:"unit" binding:"required,oneof=шт кг г л мл"`
+	Factor     float64 `json:"factor" binding:"required,gt=0"`
+	TargetUnit string  `json:"target_unit" binding:"required,oneof=шт кг г л мл"`
+}
+
+func toIngredientUnitConversions(reqs []IngredientUnitConversionRequest) []models.IngredientUnitConversion {
+	conversions := make([]models.IngredientUnitConversion, 0, len(reqs))
+	for _, r := range reqs {
+		conversions = append(conversions, models.IngredientUnitConversion{
+			Unit:       r.Unit,
+			Factor:     r.Factor,
+			TargetUnit: r.TargetUnit,
+		})
+	}
+	return conversions
 }
 
 type UpdateIngredientRequest struct {
 	Name          string  `json:"name"`
 	CategoryID    *string `json:"category_id,omitempty" binding:"omitempty,uuid"`
-	Unit          string  `json:"unit" binding:"omitempty,oneof=шт л кг"`
+	Unit          string  `json:"unit" binding:"omitempty,oneof=шт кг г л мл"`
 	Barcode       string  `json:"barcode"`
+	ExternalCode  *string `json:"external_code,omitempty"`
+	ShelfLifeDays int     `json:"shelf_life_days" binding:"gte=0"` // Срок годности по умолчанию, дней (0 — не отслеживается)
 	LossCleaning  float64 `json:"loss_cleaning"`
 	LossBoiling   float64 `json:"loss_boiling"`
 	LossFrying    float64 `json:"loss_frying"`
 	LossStewing   float64 `json:"loss_stewing"`
 	LossBaking    float64 `json:"loss_baking"`
 	Active        *bool   `json:"active,omitempty"`
+	// Если передано, аллергены заменяются целиком
+	Allergens     *[]string `json:"allergens,omitempty"`
+	Calories      *float64  `json:"calories,omitempty" binding:"omitempty,gte=0"`
+	Proteins      *float64  `json:"proteins,omitempty" binding:"omitempty,gte=0"`
+	Fats          *float64  `json:"fats,omitempty" binding:"omitempty,gte=0"`
+	Carbohydrates *float64  `json:"carbohydrates,omitempty" binding:"omitempty,gte=0"`
+	// Если передано, пересчеты единиц заменяются целиком
+	UnitConversions *[]IngredientUnitConversionRequest `json:"unit_conversions,omitempty"`
 }
 
 func (h *MenuHandler) GetIngredients(c *gin.Context) {
@@ -967,18 +1188,31 @@ func (h *MenuHandler) CreateIngredient(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category_id"})
 		return
 	}
+	allergens, err := models.ParseAllergens(req.Allergens)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	ingredient := &models.Ingredient{
 		Name:         req.Name,
 		CategoryID:   categoryID,
 		Unit:         req.Unit,
 		Barcode:      req.Barcode,
+		ExternalCode: req.ExternalCode,
+		ShelfLifeDays: req.ShelfLifeDays,
 		LossCleaning: req.LossCleaning,
 		LossBoiling:  req.LossBoiling,
 		LossFrying:   req.LossFrying,
 		LossStewing:  req.LossStewing,
 		LossBaking:   req.LossBaking,
+		Allergens:     allergens,
+		Calories:      req.Calories,
+		Proteins:      req.Proteins,
+		Fats:          req.Fats,
+		Carbohydrates: req.Carbohydrates,
 		Active:       true,
+		UnitConversions: toIngredientUnitConversions(req.UnitConversions),
 	}
 
 	var warehouseID uuid.UUID
@@ -1063,6 +1297,10 @@ func (h *MenuHandler) UpdateIngredient(c *gin.Context) {
 		ingredient.Unit = req.Unit
 	}
 	ingredient.Barcode = req.Barcode
+	if req.ExternalCode != nil {
+		ingredient.ExternalCode = *req.ExternalCode
+	}
+	ingredient.ShelfLifeDays = req.ShelfLifeDays
 	ingredient.LossCleaning = req.LossCleaning
 	ingredient.LossBoiling = req.LossBoiling
 	ingredient.LossFrying = req.LossFrying
@@ -1071,6 +1309,29 @@ func (h *MenuHandler) UpdateIngredient(c *gin.Context) {
 	if req.Active != nil {
 		ingredient.Active = *req.Active
 	}
+	if req.Allergens != nil {
+		allergens, err := models.ParseAllergens(*req.Allergens)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ingredient.Allergens = allergens
+	}
+	if req.Calories != nil {
+		ingredient.Calories = *req.Calories
+	}
+	if req.Proteins != nil {
+		ingredient.Proteins = *req.Proteins
+	}
+	if req.Fats != nil {
+		ingredient.Fats = *req.Fats
+	}
+	if req.Carbohydrates != nil {
+		ingredient.Carbohydrates = *req.Carbohydrates
+	}
+	if req.UnitConversions != nil {
+		ingredient.UnitConversions = toIngredientUnitConversions(*req.UnitConversions)
+	}
 
 	if err := h.usecase.UpdateIngredient(c.Request.Context(), ingredient); err != nil {
 		h.logger.Error("Failed to update ingredient", zap.Error(err))
@@ -1530,6 +1791,7 @@ type CreateSemiFinishedRequest struct {
 	WorkshopID      *string                        `json:"workshop_id,omitempty" binding:"omitempty,uuid"`
 	Description     string                         `json:"description"`
 	CookingProcess  string                         `json:"cooking_process"`
+	ExternalCode    string                         `json:"external_code"` // Код во внешней системе
 	CoverImage      string                         `json:"cover_image"`
 	Unit            string                         `json:"unit" binding:"required"` // kg, gram, liter, ml, piece
 	Quantity        float64                        `json:"quantity"`
@@ -1538,8 +1800,8 @@ type CreateSemiFinishedRequest struct {
 
 type CreateSemiFinishedIngredient struct {
 	IngredientID      string  `json:"ingredient_id" binding:"required,uuid"`
-	PreparationMethod *string `json:"preparation_method"`
-	Gross             float64 `json:"gross" binding:"required"`
+	PreparationMethod *string `json:"preparation_method"` // Несколько способов через запятую применяются последовательно
+	Gross             float64 `json:"gross"` // При указанных способах приготовления рассчитывается по нетто
 	Net               float64 `json:"net" binding:"required"`
 	Unit              string  `json:"unit" binding:"required"` // г, мл, шт
 }
@@ -1591,6 +1853,7 @@ func (h *MenuHandler) CreateSemiFinished(c *gin.Context) {
 		WorkshopID:      workshopID,
 		Description:     req.Description,
 		CookingProcess:  req.CookingProcess,
+		ExternalCode:    req.ExternalCode,
 		CoverImage:      req.CoverImage,
 		Unit:            unit,
 		Quantity:        req.Quantity,
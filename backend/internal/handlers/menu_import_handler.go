@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/yourusername/arc/backend/internal/usecases"
+)
+
+// maxMenuImportFileSize предельный размер импортируемого файла меню
+const maxMenuImportFileSize = 10 << 20
+
+type MenuImportHandler struct {
+	usecase *usecases.MenuImportUseCase
+	logger  *zap.Logger
+}
+
+func NewMenuImportHandler(usecase *usecases.MenuImportUseCase, logger *zap.Logger) *MenuImportHandler {
+	return &MenuImportHandler{
+		usecase: usecase,
+		logger:  logger,
+	}
+}
+
+// ——— Import / Export ———
+
+// Import загружает меню из файла
+// @Summary Импорт меню
+// @Description Создает и обновляет категории, ингредиенты, полуфабрикаты, товары и тех-карты с рецептурами из CSV, XLSX или JSON. Позиции сопоставляются по external_code, затем по названию; недостающие категории создаются. Начальные остатки заводятся на склад warehouse_id. С dry_run=true возвращает отчет без сохранения. Если в файле есть ошибки, ничего не сохраняется и возвращается 422 с отчетом по строкам
+// @Tags menu
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param file formData file true "Файл меню (.csv, .xlsx, .json)"
+// @Param warehouse_id formData string true "ID склада для начальных остатков и расчета себестоимости"
+// @Param format formData string false "csv, xlsx или json (по умолчанию — по файлу)"
+// @Param dry_run formData bool false "Только проверить файл"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
+// @Router /menu/import [post]
+func (h *MenuImportHandler) Import(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	warehouseID, err := uuid.Parse(c.PostForm("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+	format := c.PostForm("format")
+	switch format {
+	case "", usecases.MenuExchangeFormatCSV, usecases.MenuExchangeFormatXLSX, usecases.MenuExchangeFormatJSON:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv, xlsx or json"})
+		return
+	}
+	dryRun := c.PostForm("dry_run") == "true"
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if file.Size > maxMenuImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is too large"})
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to open file"})
+		return
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, maxMenuImportFileSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+
+	report, err := h.usecase.Import(c.Request.Context(), estID, warehouseID, format, file.Filename, data, dryRun)
+	if err != nil {
+		if errors.Is(err, usecases.ErrMenuImportInvalid) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "data": report})
+			return
+		}
+		h.logger.Error("Failed to import menu", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// Export выгружает меню в файл
+// @Summary Выгрузка меню
+// @Description Выгружает категории, ингредиенты, полуфабрикаты, товары и тех-карты с рецептурами в формате импорта. С warehouse_id в файл попадают остатки склада как начальные остатки
+// @Tags menu
+// @Produce octet-stream
+// @Security Bearer
+// @Param format query string false "Формат: csv (по умолчанию), xlsx или json"
+// @Param warehouse_id query string false "ID склада для выгрузки остатков"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/export [get]
+func (h *MenuImportHandler) Export(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	var warehouseID *uuid.UUID
+	if raw := c.Query("warehouse_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+			return
+		}
+		warehouseID = &id
+	}
+
+	data, contentType, filename, err := h.usecase.Export(c.Request.Context(), estID, warehouseID, c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, data)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -23,17 +24,45 @@ type CreateOrderRequest struct {
 }
 
 type OrderItemRequest struct {
-	ProductID   *uuid.UUID `json:"product_id,omitempty"`
-	TechCardID  *uuid.UUID `json:"tech_card_id,omitempty"`
-	Quantity    int        `json:"quantity" binding:"required,min=1"`
-	GuestNumber *int       `json:"guest_number,omitempty"`
+	ProductID   *uuid.UUID              `json:"product_id,omitempty"`
+	TechCardID  *uuid.UUID              `json:"tech_card_id,omitempty"`
+	ComboID     *uuid.UUID              `json:"combo_id,omitempty"`
+	Components  []ComboComponentRequest `json:"components,omitempty" binding:"dive"` // Выбор компонентов комбо
+	Quantity    int                     `json:"quantity" binding:"required,min=1"`
+	GuestNumber *int                    `json:"guest_number,omitempty"`
 }
 
 type AddOrderItemRequest struct {
-	ProductID   *uuid.UUID `json:"product_id,omitempty"`
-	TechCardID  *uuid.UUID `json:"tech_card_id,omitempty"`
-	Quantity    int        `json:"quantity" binding:"required,min=1"`
-	GuestNumber *int       `json:"guest_number,omitempty"`
+	ProductID   *uuid.UUID              `json:"product_id,omitempty"`
+	TechCardID  *uuid.UUID              `json:"tech_card_id,omitempty"`
+	ComboID     *uuid.UUID              `json:"combo_id,omitempty"`
+	Components  []ComboComponentRequest `json:"components,omitempty" binding:"dive"` // Выбор компонентов комбо
+	Quantity    int                     `json:"quantity" binding:"required,min=1"`
+	GuestNumber *int                    `json:"guest_number,omitempty"`
+}
+
+// ComboComponentRequest выбор позиции в группе комбо: товар или тех-карта из позиций группы.
+// Для обязательной группы без выбора берется позиция по умолчанию
+type ComboComponentRequest struct {
+	GroupID    uuid.UUID  `json:"group_id" binding:"required"`
+	ProductID  *uuid.UUID `json:"product_id,omitempty"`
+	TechCardID *uuid.UUID `json:"tech_card_id,omitempty"`
+}
+
+// comboComponents переводит выбор компонентов комбо в компоненты позиции заказа
+func comboComponents(reqs []ComboComponentRequest) []models.OrderItemComponent {
+	if len(reqs) == 0 {
+		return nil
+	}
+	components := make([]models.OrderItemComponent, 0, len(reqs))
+	for _, r := range reqs {
+		components = append(components, models.OrderItemComponent{
+			GroupID:    r.GroupID,
+			ProductID:  r.ProductID,
+			TechCardID: r.TechCardID,
+		})
+	}
+	return components
 }
 
 type UpdateOrderItemQuantityRequest struct {
@@ -157,9 +186,39 @@ func (h *OrderHandler) Get(c *gin.Context) {
 	c.JSON(http.StatusOK, order)
 }
 
+// insufficientStockResponse отвечает 409 со списком нехватки, если заказ отклонен политикой block
+func insufficientStockResponse(c *gin.Context, err error) bool {
+	var stockErr *usecases.InsufficientStockError
+	if !errors.As(err, &stockErr) {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": "Недостаточно остатков", "shortages": stockErr.Shortages})
+	return true
+}
+
+// itemUnavailableResponse отвечает 409 со списком позиций, недоступных по расписанию
+func itemUnavailableResponse(c *gin.Context, err error) bool {
+	var availabilityErr *usecases.ItemUnavailableError
+	if !errors.As(err, &availabilityErr) {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": "Позиция недоступна в это время", "unavailable_items": availabilityErr.Items})
+	return true
+}
+
+// invalidOrderItemResponse отвечает 400, если в заказе указан товар с модификациями вместо модификации
+// или выбор компонентов комбо не соответствует его группам
+func invalidOrderItemResponse(c *gin.Context, err error) bool {
+	if !errors.Is(err, usecases.ErrProductHasVariants) && !errors.Is(err, usecases.ErrInvalidComboSelection) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	return true
+}
+
 // Create создает новый заказ
 // @Summary Создать заказ
-// @Description Создает новый заказ
+// @Description Создает новый заказ. Остатки проверяются по политике заведения: при warn нехватка возвращается в stock_warnings, при block заказ отклоняется с кодом 409. Позиции вне расписания доступности также отклоняются с кодом 409
 // @Tags orders
 // @Accept json
 // @Produce json
@@ -167,6 +226,7 @@ func (h *OrderHandler) Get(c *gin.Context) {
 // @Param request body object true "Данные заказа"
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]string
 // @Router /orders [post]
 func (h *OrderHandler) Create(c *gin.Context) {
@@ -187,6 +247,8 @@ func (h *OrderHandler) Create(c *gin.Context) {
 		orderItems[i] = models.OrderItem{
 			ProductID:   itemReq.ProductID,
 			TechCardID:  itemReq.TechCardID,
+			ComboID:     itemReq.ComboID,
+			Components:  comboComponents(itemReq.Components),
 			Quantity:    itemReq.Quantity,
 			GuestNumber: itemReq.GuestNumber,
 		}
@@ -199,6 +261,9 @@ func (h *OrderHandler) Create(c *gin.Context) {
 		order, err = h.usecase.CreateOrder(c.Request.Context(), estID, req.TableID, orderItems)
 	}
 	if err != nil {
+		if insufficientStockResponse(c, err) || itemUnavailableResponse(c, err) || invalidOrderItemResponse(c, err) {
+			return
+		}
 		h.logger.Error("Failed to create order", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось создать заказ"})
 		return
@@ -247,7 +312,7 @@ func (h *OrderHandler) Update(c *gin.Context) {
 
 // AddOrderItem добавляет позицию в существующий заказ
 // @Summary Добавить позицию в заказ
-// @Description Добавляет новую позицию в существующий заказ
+// @Description Добавляет новую позицию в существующий заказ. Остатки на весь заказ проверяются по политике заведения (warn — stock_warnings, block — 409)
 // @Tags orders
 // @Accept json
 // @Produce json
@@ -257,6 +322,7 @@ func (h *OrderHandler) Update(c *gin.Context) {
 // @Success 200 {object} models.Order
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]string
 // @Router /orders/{id}/items [post]
 func (h *OrderHandler) AddOrderItem(c *gin.Context) {
@@ -276,12 +342,17 @@ func (h *OrderHandler) AddOrderItem(c *gin.Context) {
 	orderItem := models.OrderItem{
 		ProductID:   req.ProductID,
 		TechCardID:  req.TechCardID,
+		ComboID:     req.ComboID,
+		Components:  comboComponents(req.Components),
 		Quantity:    req.Quantity,
 		GuestNumber: req.GuestNumber,
 	}
 
 	order, err := h.usecase.AddOrderItem(c.Request.Context(), orderID, orderItem)
 	if err != nil {
+		if insufficientStockResponse(c, err) || itemUnavailableResponse(c, err) || invalidOrderItemResponse(c, err) {
+			return
+		}
 		h.logger.Error("Failed to add order item", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось добавить позицию в заказ"})
 		return
@@ -303,6 +374,7 @@ func (h *OrderHandler) AddOrderItem(c *gin.Context) {
 // @Success 200 {object} models.Order
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]string
 // @Router /orders/{order_id}/items/{item_id} [put]
 func (h *OrderHandler) UpdateOrderItemQuantity(c *gin.Context) {
@@ -328,6 +400,9 @@ func (h *OrderHandler) UpdateOrderItemQuantity(c *gin.Context) {
 
 	order, err := h.usecase.UpdateOrderItemQuantity(c.Request.Context(), orderID, itemID, req.Quantity)
 	if err != nil {
+		if insufficientStockResponse(c, err) {
+			return
+		}
 		h.logger.Error("Failed to update order item quantity", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось обновить количество позиции в заказе"})
 		return
@@ -411,3 +486,30 @@ func (h *OrderHandler) CloseOrderWithoutPayment(c *gin.Context) {
 
 	c.JSON(http.StatusOK, order)
 }
+
+// GetStopList возвращает стоп-лист
+// @Summary Стоп-лист
+// @Description Возвращает активные товары, тех-карты и комбо, на одну порцию которых не хватает остатков на складах заведения (с учетом рецептуры и полуфабрикатов), с перечнем недостающих позиций. Позиция, расход которой не удалось посчитать, возвращается с полем error
+// @Tags orders
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /orders/stop-list [get]
+func (h *OrderHandler) GetStopList(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	stopList, err := h.usecase.GetStopList(c.Request.Context(), estID)
+	if err != nil {
+		h.logger.Error("Failed to get stop list", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить стоп-лист"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": stopList})
+}
@@ -0,0 +1,485 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+	"github.com/yourusername/arc/backend/internal/usecases"
+)
+
+type PurchaseOrderHandler struct {
+	usecase *usecases.PurchaseOrderUseCase
+	logger  *zap.Logger
+}
+
+func NewPurchaseOrderHandler(usecase *usecases.PurchaseOrderUseCase, logger *zap.Logger) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{
+		usecase: usecase,
+		logger:  logger,
+	}
+}
+
+// ——— Requests ———
+
+type PurchaseOrderItemRequest struct {
+	IngredientID *string `json:"ingredient_id,omitempty" binding:"omitempty,uuid"`
+	ProductID    *string `json:"product_id,omitempty" binding:"omitempty,uuid"`
+	Quantity     float64 `json:"quantity" binding:"required,gt=0"` // Заказываемое количество
+	Unit         string  `json:"unit" binding:"required"`
+	PricePerUnit float64 `json:"price_per_unit" binding:"gte=0"` // Ожидаемая цена за единицу
+}
+
+type PurchaseOrderRequest struct {
+	SupplierID   string                     `json:"supplier_id" binding:"required,uuid"`
+	WarehouseID  string                     `json:"warehouse_id" binding:"required,uuid"`
+	ExpectedDate *string                    `json:"expected_date,omitempty"` // Ожидаемая дата поставки (RFC3339)
+	Comment      string                     `json:"comment"`
+	Items        []PurchaseOrderItemRequest `json:"items" binding:"required,min=1"`
+}
+
+type UpdatePurchaseOrderStatusRequest struct {
+	Status models.PurchaseOrderStatus `json:"status" binding:"required,oneof=sent closed"`
+}
+
+type ReceivePurchaseOrderItemRequest struct {
+	PurchaseOrderItemID string   `json:"purchase_order_item_id" binding:"required,uuid"`
+	Quantity            float64  `json:"quantity" binding:"required,gt=0"`                   // Фактически поступило (в единице позиции заказа)
+	PricePerUnit        *float64 `json:"price_per_unit,omitempty" binding:"omitempty,gte=0"` // Фактическая цена; по умолчанию — из заказа
+}
+
+type ReceivePurchaseOrderRequest struct {
+	DeliveryDateTime string                            `json:"delivery_date_time"` // RFC3339, по умолчанию — текущее время
+	InvoiceNumber    string                            `json:"invoice_number"`
+	InvoiceDate      string                            `json:"invoice_date"` // RFC3339
+	Comment          string                            `json:"comment"`
+	Items            []ReceivePurchaseOrderItemRequest `json:"items" binding:"required,min=1"`
+}
+
+func (r *PurchaseOrderRequest) toModel() (*models.PurchaseOrder, error) {
+	supplierID, err := uuid.Parse(r.SupplierID)
+	if err != nil {
+		return nil, errors.New("invalid supplier_id")
+	}
+	warehouseID, err := uuid.Parse(r.WarehouseID)
+	if err != nil {
+		return nil, errors.New("invalid warehouse_id")
+	}
+	order := &models.PurchaseOrder{
+		SupplierID:  supplierID,
+		WarehouseID: warehouseID,
+		Comment:     r.Comment,
+	}
+	if r.ExpectedDate != nil && *r.ExpectedDate != "" {
+		t, err := time.Parse(time.RFC3339, *r.ExpectedDate)
+		if err != nil {
+			return nil, errors.New("invalid expected_date format, use RFC3339")
+		}
+		order.ExpectedDate = &t
+	}
+	for _, it := range r.Items {
+		item := models.PurchaseOrderItem{
+			Quantity:     it.Quantity,
+			Unit:         it.Unit,
+			PricePerUnit: it.PricePerUnit,
+		}
+		if it.IngredientID != nil && *it.IngredientID != "" {
+			id, _ := uuid.Parse(*it.IngredientID)
+			item.IngredientID = &id
+		}
+		if it.ProductID != nil && *it.ProductID != "" {
+			id, _ := uuid.Parse(*it.ProductID)
+			item.ProductID = &id
+		}
+		order.Items = append(order.Items, item)
+	}
+	return order, nil
+}
+
+// ——— Handlers ———
+
+// ListPurchaseOrders возвращает список заказов поставщикам
+// @Summary Получить заказы поставщикам
+// @Description Возвращает заказы поставщикам с фильтрацией по поставщику, складу и статусу
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param supplier_id query string false "ID поставщика"
+// @Param warehouse_id query string false "ID склада"
+// @Param status query string false "Статус (draft, sent, partially_received, received, closed)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/purchase-orders [get]
+func (h *PurchaseOrderHandler) ListPurchaseOrders(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := &repositories.PurchaseOrderFilter{}
+	if s := c.Query("supplier_id"); s != "" {
+		if id, e := uuid.Parse(s); e == nil {
+			filter.SupplierID = &id
+		}
+	}
+	if s := c.Query("warehouse_id"); s != "" {
+		if id, e := uuid.Parse(s); e == nil {
+			filter.WarehouseID = &id
+		}
+	}
+	if s := c.Query("status"); s != "" {
+		status := models.PurchaseOrderStatus(s)
+		filter.Status = &status
+	}
+
+	list, err := h.usecase.ListPurchaseOrders(c.Request.Context(), estID, filter)
+	if err != nil {
+		h.logger.Error("Failed to list purchase orders", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list purchase orders"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// GetPurchaseOrder возвращает заказ поставщику по ID
+// @Summary Получить заказ поставщику
+// @Description Возвращает заказ с позициями (заказано / получено) и поставками, оформленными по нему
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID заказа"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /warehouse/purchase-orders/{id} [get]
+func (h *PurchaseOrderHandler) GetPurchaseOrder(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	order, err := h.usecase.GetPurchaseOrder(c.Request.Context(), id, estID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": order})
+}
+
+// CreatePurchaseOrder создает заказ поставщику
+// @Summary Создать заказ поставщику
+// @Description Создает заказ поставщику в статусе draft с ожидаемыми количествами и ценами
+// @Tags warehouse
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body PurchaseOrderRequest true "Данные заказа"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/purchase-orders [post]
+func (h *PurchaseOrderHandler) CreatePurchaseOrder(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req PurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	order, err := req.toModel()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.usecase.CreatePurchaseOrder(c.Request.Context(), order, estID); err != nil {
+		h.logger.Error("Failed to create purchase order", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": order})
+}
+
+// UpdatePurchaseOrder обновляет черновик заказа поставщику
+// @Summary Обновить заказ поставщику
+// @Description Обновляет заказ в статусе draft; позиции заменяются целиком
+// @Tags warehouse
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID заказа"
+// @Param request body PurchaseOrderRequest true "Данные заказа"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/purchase-orders/{id} [put]
+func (h *PurchaseOrderHandler) UpdatePurchaseOrder(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req PurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	order, err := req.toModel()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	order.ID = id
+
+	if err := h.usecase.UpdatePurchaseOrder(c.Request.Context(), order, estID); err != nil {
+		h.logger.Error("Failed to update purchase order", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	updated, err := h.usecase.GetPurchaseOrder(c.Request.Context(), id, estID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": updated})
+}
+
+// UpdatePurchaseOrderStatus меняет статус заказа поставщику
+// @Summary Изменить статус заказа поставщику
+// @Description draft → sent (отправлен поставщику), любой незакрытый → closed. Статусы partially_received и received выставляются приемкой.
+// @Tags warehouse
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID заказа"
+// @Param request body UpdatePurchaseOrderStatusRequest true "Новый статус"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/purchase-orders/{id}/status [put]
+func (h *PurchaseOrderHandler) UpdatePurchaseOrderStatus(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req UpdatePurchaseOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := h.usecase.UpdatePurchaseOrderStatus(c.Request.Context(), id, req.Status, estID)
+	if err != nil {
+		h.logger.Error("Failed to update purchase order status", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": order})
+}
+
+// ReceivePurchaseOrder оформляет поступление по заказу поставщику
+// @Summary Принять поставку по заказу
+// @Description Создает поставку (Supply) по фактически поступившим количествам и ценам, обновляет полученное по заказу и возвращает расхождения с заказом по количеству и цене
+// @Tags warehouse
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID заказа"
+// @Param request body ReceivePurchaseOrderRequest true "Данные приемки"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/purchase-orders/{id}/receive [post]
+func (h *PurchaseOrderHandler) ReceivePurchaseOrder(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req ReceivePurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	receiveReq := &usecases.ReceivePurchaseOrderRequest{
+		InvoiceNumber: req.InvoiceNumber,
+		Comment:       req.Comment,
+	}
+	if req.DeliveryDateTime != "" {
+		t, err := time.Parse(time.RFC3339, req.DeliveryDateTime)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delivery_date_time format, use RFC3339"})
+			return
+		}
+		receiveReq.DeliveryDateTime = t
+	}
+	if req.InvoiceDate != "" {
+		t, err := time.Parse(time.RFC3339, req.InvoiceDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invoice_date format, use RFC3339"})
+			return
+		}
+		receiveReq.InvoiceDate = &t
+	}
+	for _, it := range req.Items {
+		itemID, _ := uuid.Parse(it.PurchaseOrderItemID)
+		receiveReq.Items = append(receiveReq.Items, usecases.ReceivePurchaseOrderItem{
+			PurchaseOrderItemID: itemID,
+			Quantity:            it.Quantity,
+			PricePerUnit:        it.PricePerUnit,
+		})
+	}
+
+	receipt, err := h.usecase.ReceivePurchaseOrder(c.Request.Context(), id, receiveReq, estID)
+	if err != nil {
+		h.logger.Error("Failed to receive purchase order", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": receipt})
+}
+
+// DeletePurchaseOrder удаляет черновик заказа поставщику
+// @Summary Удалить заказ поставщику
+// @Description Удаляет заказ в статусе draft
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID заказа"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/purchase-orders/{id} [delete]
+func (h *PurchaseOrderHandler) DeletePurchaseOrder(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.usecase.DeletePurchaseOrder(c.Request.Context(), id, estID); err != nil {
+		h.logger.Error("Failed to delete purchase order", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "purchase order deleted"})
+}
+
+// GetOpenPurchaseOrdersReport возвращает отчет по открытым заказам поставщикам
+// @Summary Отчет по открытым заказам поставщикам
+// @Description Заказы в статусах sent и partially_received, сгруппированные по поставщикам: заказано, получено, недопоставлено, просроченные
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param supplier_id query string false "ID поставщика"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/purchase-orders/open-report [get]
+func (h *PurchaseOrderHandler) GetOpenPurchaseOrdersReport(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	var supplierID *uuid.UUID
+	if s := c.Query("supplier_id"); s != "" {
+		if id, e := uuid.Parse(s); e == nil {
+			supplierID = &id
+		}
+	}
+
+	report, err := h.usecase.GetOpenPurchaseOrdersReport(c.Request.Context(), estID, supplierID)
+	if err != nil {
+		h.logger.Error("Failed to get open purchase orders report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// ExportPurchaseOrder выгружает заказ поставщику в CSV или PDF
+// @Summary Выгрузить заказ поставщику
+// @Description Возвращает файл заказа для отправки поставщику
+// @Tags warehouse
+// @Produce octet-stream
+// @Security Bearer
+// @Param id path string true "ID заказа"
+// @Param format query string false "Формат: csv (по умолчанию) или pdf"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/purchase-orders/{id}/export [get]
+func (h *PurchaseOrderHandler) ExportPurchaseOrder(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	data, contentType, filename, err := h.usecase.ExportPurchaseOrder(c.Request.Context(), id, c.Query("format"), estID)
+	if err != nil {
+		h.logger.Error("Failed to export purchase order", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, data)
+}
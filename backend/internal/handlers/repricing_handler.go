@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/usecases"
+)
+
+type RepricingHandler struct {
+	usecase *usecases.RepricingUseCase
+	logger  *zap.Logger
+}
+
+func NewRepricingHandler(usecase *usecases.RepricingUseCase, logger *zap.Logger) *RepricingHandler {
+	return &RepricingHandler{
+		usecase: usecase,
+		logger:  logger,
+	}
+}
+
+// ——— Requests ———
+
+// RepricingRuleRequest правило переоценки. Область действия — одно из category_id, tech_card_id, product_id или все меню
+type RepricingRuleRequest struct {
+	Name        string   `json:"name"`
+	CategoryID  *string  `json:"category_id,omitempty" binding:"omitempty,uuid"`
+	TechCardID  *string  `json:"tech_card_id,omitempty" binding:"omitempty,uuid"`
+	ProductID   *string  `json:"product_id,omitempty" binding:"omitempty,uuid"`
+	Strategy    string   `json:"strategy" binding:"required,oneof=markup food_cost" example:"food_cost"`
+	TargetValue float64  `json:"target_value" binding:"required,gt=0" example:"30"`            // Наценка или фудкост, %
+	Rounding    string   `json:"rounding" binding:"omitempty,oneof=none 5 10 90" example:"10"` // none, 5, 10 или 90 (окончание .90)
+	MinPrice    *float64 `json:"min_price,omitempty" binding:"omitempty,gte=0"`
+	MaxPrice    *float64 `json:"max_price,omitempty" binding:"omitempty,gte=0"`
+	Active      *bool    `json:"active,omitempty"`
+}
+
+func (r *RepricingRuleRequest) toModel() *models.RepricingRule {
+	rule := &models.RepricingRule{
+		Name:        r.Name,
+		CategoryID:  parseOptionalUUID(r.CategoryID),
+		TechCardID:  parseOptionalUUID(r.TechCardID),
+		ProductID:   parseOptionalUUID(r.ProductID),
+		Strategy:    r.Strategy,
+		TargetValue: r.TargetValue,
+		Rounding:    r.Rounding,
+		MinPrice:    r.MinPrice,
+		MaxPrice:    r.MaxPrice,
+		Active:      true,
+	}
+	if r.Active != nil {
+		rule.Active = *r.Active
+	}
+	return rule
+}
+
+// PriceChangeDecisionRequest решение по предложениям об изменении цены
+type PriceChangeDecisionRequest struct {
+	IDs     []string `json:"ids" binding:"omitempty,dive,uuid"` // Пусто — все ожидающие предложения
+	ApplyAt *string  `json:"apply_at,omitempty"`                // RFC3339; пусто — применить сразу (только для approve)
+}
+
+func (r *PriceChangeDecisionRequest) parseIDs() []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(r.IDs))
+	for _, s := range r.IDs {
+		if id, err := uuid.Parse(s); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ——— Rules ———
+
+// ListRules возвращает правила переоценки
+// @Summary Правила переоценки
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu/repricing/rules [get]
+func (h *RepricingHandler) ListRules(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	list, err := h.usecase.ListRules(c.Request.Context(), estID)
+	if err != nil {
+		h.logger.Error("Failed to list repricing rules", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list repricing rules"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// CreateRule создает правило переоценки
+// @Summary Создать правило переоценки
+// @Description Правило задает целевую наценку или фудкост, округление новой цены вверх и границы цены. Для позиции применяется самое точное правило: для самой позиции, для ее категории, для всего меню
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body RepricingRuleRequest true "Правило"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/repricing/rules [post]
+func (h *RepricingHandler) CreateRule(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	var req RepricingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rule := req.toModel()
+	if err := h.usecase.CreateRule(c.Request.Context(), rule, estID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": rule})
+}
+
+// UpdateRule обновляет правило переоценки
+// @Summary Обновить правило переоценки
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID правила"
+// @Param request body RepricingRuleRequest true "Правило"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/repricing/rules/{id} [put]
+func (h *RepricingHandler) UpdateRule(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req RepricingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rule := req.toModel()
+	rule.ID = id
+	if err := h.usecase.UpdateRule(c.Request.Context(), rule, estID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": rule})
+}
+
+// DeleteRule удаляет правило переоценки
+// @Summary Удалить правило переоценки
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID правила"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/repricing/rules/{id} [delete]
+func (h *RepricingHandler) DeleteRule(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.usecase.DeleteRule(c.Request.Context(), id, estID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "repricing rule deleted"})
+}
+
+// ——— Preview and price changes ———
+
+// Preview показывает старые и новые цены по правилам переоценки
+// @Summary Предпросмотр переоценки
+// @Description Рассчитывает новые цены активного меню по текущей себестоимости и правилам переоценки. Цены не меняются
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu/repricing/preview [get]
+func (h *RepricingHandler) Preview(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.usecase.Preview(c.Request.Context(), estID)
+	if err != nil {
+		h.logger.Error("Failed to preview repricing", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to preview repricing"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": items})
+}
+
+// GenerateProposals создает предложения об изменении цены по предпросмотру
+// @Summary Сформировать предложения по переоценке
+// @Description Создает ожидающие одобрения предложения. Предложения также формируются автоматически после изменения себестоимости
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu/repricing/proposals [post]
+func (h *RepricingHandler) GenerateProposals(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	created, err := h.usecase.GenerateProposals(c.Request.Context(), estID)
+	if err != nil {
+		h.logger.Error("Failed to generate price change proposals", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate price change proposals"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": created})
+}
+
+// ListProposals возвращает предложения об изменении цены
+// @Summary Предложения по переоценке
+// @Tags menu
+// @Produce json
+// @Security Bearer
+// @Param status query string false "Статус (pending, approved, applied, rejected, superseded, stale); по умолчанию pending"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /menu/repricing/proposals [get]
+func (h *RepricingHandler) ListProposals(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	status := c.DefaultQuery("status", models.PriceChangeStatusPending)
+	list, err := h.usecase.ListPriceChanges(c.Request.Context(), estID, &status)
+	if err != nil {
+		h.logger.Error("Failed to list price changes", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list price changes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// ApproveProposals одобряет предложения об изменении цены
+// @Summary Одобрить переоценку
+// @Description Одобряет выбранные (или все ожидающие) предложения. Без apply_at цены меняются сразу, иначе — в указанное время. Если цена позиции изменилась вручную, предложение получает статус stale
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body PriceChangeDecisionRequest true "Предложения"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/repricing/proposals/approve [post]
+func (h *RepricingHandler) ApproveProposals(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	var req PriceChangeDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var applyAt *time.Time
+	if req.ApplyAt != nil && *req.ApplyAt != "" {
+		t, err := time.Parse(time.RFC3339, *req.ApplyAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid apply_at format, expected RFC3339"})
+			return
+		}
+		applyAt = &t
+	}
+
+	var userID *uuid.UUID
+	if uid, ok := currentUserID(c); ok {
+		userID = &uid
+	}
+	changes, err := h.usecase.ApprovePriceChanges(c.Request.Context(), estID, req.parseIDs(), applyAt, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": changes})
+}
+
+// RejectProposals отклоняет предложения об изменении цены
+// @Summary Отклонить переоценку
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body PriceChangeDecisionRequest true "Предложения"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /menu/repricing/proposals/reject [post]
+func (h *RepricingHandler) RejectProposals(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	var req PriceChangeDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var userID *uuid.UUID
+	if uid, ok := currentUserID(c); ok {
+		userID = &uid
+	}
+	changes, err := h.usecase.RejectPriceChanges(c.Request.Context(), estID, req.parseIDs(), userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": changes})
+}
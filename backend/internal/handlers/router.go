@@ -62,12 +62,19 @@ func NewRouter(usecases *usecases.UseCases, cfg *config.Config, logger *zap.Logg
 			}
 		}
 
+		// Public routes (без авторизации)
+		publicMenuHandler := NewMenuHandler(usecases.Menu, logger)
+		public := v1.Group("/public")
+		{
+			public.GET("/establishments/:id/menu", publicMenuHandler.GetPublicMenu)
+		}
+
 		// Protected routes
 		protected := v1.Group("")
 		protected.Use(middleware.Auth(cfg.JWT.Secret, usecases.Auth.GetTokenRepo()))
 		{
 			// Upload routes (для загрузки изображений)
-			uploadHandler := NewUploadHandler(usecases.Storage, logger)
+			uploadHandler := NewUploadHandler(usecases.Upload, logger)
 			shiftHandler := NewShiftHandler(usecases.Shift, logger)
 			userHandler := NewUserHandler(usecases.User, usecases.EmployeeStatistics, logger)
 			roleHandler := NewRoleHandler(usecases.Role, logger)
@@ -98,6 +105,8 @@ func NewRouter(usecases *usecases.UseCases, cfg *config.Config, logger *zap.Logg
 				statistics.GET("/categories", statisticsHandler.GetCategories)
 				statistics.GET("/products", statisticsHandler.GetProducts)
 				statistics.GET("/abc", statisticsHandler.GetABCAnalysis)
+				statistics.GET("/menu-engineering", statisticsHandler.GetMenuEngineering)
+				statistics.GET("/menu-engineering/compare", statisticsHandler.CompareMenuEngineering)
 				statistics.GET("/checks", statisticsHandler.GetChecks)
 				statistics.GET("/reviews", statisticsHandler.GetReviews)
 				statistics.GET("/payments", statisticsHandler.GetPayments)
@@ -167,6 +176,10 @@ func NewRouter(usecases *usecases.UseCases, cfg *config.Config, logger *zap.Logg
 				establishments.PUT("/:id", establishmentHandler.Update)
 				establishments.DELETE("/:id", establishmentHandler.Delete)
 				establishments.GET("/me/settings", establishmentHandler.GetEstablishmentSettings)
+				establishments.GET("/me/stock-policy", establishmentHandler.GetStockPolicy)
+				establishments.PUT("/me/stock-policy", establishmentHandler.UpdateStockPolicy)
+				establishments.GET("/me/timezone", establishmentHandler.GetTimezone)
+				establishments.PUT("/me/timezone", establishmentHandler.UpdateTimezone)
 
 				// Tables через rooms
 				rooms := protected.Group("/rooms")
@@ -191,6 +204,11 @@ func NewRouter(usecases *usecases.UseCases, cfg *config.Config, logger *zap.Logg
 
 			// Menu / Products (требуется заведение — onboarding завершён)
 			menuHandler := NewMenuHandler(usecases.Menu, logger)
+			costHistoryHandler := NewCostHistoryHandler(usecases.CostHistory, logger)
+			repricingHandler := NewRepricingHandler(usecases.Repricing, logger)
+			availabilityHandler := NewAvailabilityHandler(usecases.Availability, logger)
+			comboHandler := NewComboHandler(usecases.Combo, logger)
+			menuImportHandler := NewMenuImportHandler(usecases.MenuImport, logger)
 			menu := protected.Group("/menu")
 			menu.Use(middleware.RequireEstablishment(usecases.Auth))
 			{
@@ -202,6 +220,9 @@ func NewRouter(usecases *usecases.UseCases, cfg *config.Config, logger *zap.Logg
 					products.POST("", menuHandler.CreateProduct)
 					products.PUT("/:id", menuHandler.UpdateProduct)
 					products.DELETE("/:id", menuHandler.DeleteProduct)
+					products.GET("/:id/cost-history", costHistoryHandler.GetProductCostHistory) // ?start_date, ?end_date
+					products.GET("/:id/variants", menuHandler.GetProductVariants)
+					products.POST("/:id/variants", menuHandler.CreateProductVariant)
 				}
 				// Categories (для товаров и тех-карт)
 				categories := menu.Group("/categories")
@@ -230,6 +251,48 @@ func NewRouter(usecases *usecases.UseCases, cfg *config.Config, logger *zap.Logg
 					techCards.POST("", menuHandler.CreateTechCard)
 					techCards.PUT("/:id", menuHandler.UpdateTechCard)
 					techCards.DELETE("/:id", menuHandler.DeleteTechCard)
+					techCards.GET("/:id/cost-history", costHistoryHandler.GetTechCardCostHistory) // ?start_date, ?end_date
+				}
+				// Food cost: порог, уведомления, пересчет себестоимости и падение маржи
+				foodCost := menu.Group("/food-cost")
+				{
+					foodCost.GET("/threshold", costHistoryHandler.GetThreshold)
+					foodCost.PUT("/threshold", costHistoryHandler.UpdateThreshold)
+					foodCost.GET("/alerts", costHistoryHandler.ListAlerts) // ?status
+					foodCost.PUT("/alerts/:id/acknowledge", costHistoryHandler.AcknowledgeAlert)
+					foodCost.POST("/recalculate", costHistoryHandler.Recalculate)
+					foodCost.GET("/margin-drop", costHistoryHandler.GetMarginDropReport) // ?start_date, ?end_date, ?limit
+				}
+				// Repricing: правила переоценки, предпросмотр и одобрение новых цен
+				repricing := menu.Group("/repricing")
+				{
+					repricing.GET("/rules", repricingHandler.ListRules)
+					repricing.POST("/rules", repricingHandler.CreateRule)
+					repricing.PUT("/rules/:id", repricingHandler.UpdateRule)
+					repricing.DELETE("/rules/:id", repricingHandler.DeleteRule)
+					repricing.GET("/preview", repricingHandler.Preview)
+					repricing.GET("/proposals", repricingHandler.ListProposals) // ?status
+					repricing.POST("/proposals", repricingHandler.GenerateProposals)
+					repricing.POST("/proposals/approve", repricingHandler.ApproveProposals)
+					repricing.POST("/proposals/reject", repricingHandler.RejectProposals)
+				}
+				// Availability: расписания доступности категорий и позиций по дням недели и времени
+				availability := menu.Group("/availability")
+				{
+					availability.GET("/schedules", availabilityHandler.ListSchedules)
+					availability.POST("/schedules", availabilityHandler.CreateSchedule)
+					availability.PUT("/schedules/:id", availabilityHandler.UpdateSchedule)
+					availability.DELETE("/schedules/:id", availabilityHandler.DeleteSchedule)
+					availability.PUT("/assign", availabilityHandler.Assign)
+				}
+				// Combos: комбо и сет-меню с группами компонентов и доплатами
+				combos := menu.Group("/combos")
+				{
+					combos.GET("", comboHandler.List) // ?category_id, search, active
+					combos.POST("", comboHandler.Create)
+					combos.GET("/:id", comboHandler.Get)
+					combos.PUT("/:id", comboHandler.Update)
+					combos.DELETE("/:id", comboHandler.Delete)
 				}
 				// Ingredients
 				ingredients := menu.Group("/ingredients")
@@ -252,10 +315,18 @@ func NewRouter(usecases *usecases.UseCases, cfg *config.Config, logger *zap.Logg
 				// Semi-finished (полуфабрикаты)
 				menu.GET("/semi-finished", menuHandler.GetSemiFinished)
 				menu.POST("/semi-finished", menuHandler.CreateSemiFinished)
+				// Import / Export: массовая загрузка и выгрузка меню
+				menu.POST("/import", menuImportHandler.Import) // multipart: file (.csv, .xlsx, .json), warehouse_id, format, dry_run
+				menu.GET("/export", menuImportHandler.Export)  // ?format=csv|xlsx|json, warehouse_id
 			}
 
 			// Warehouses (склады) + Stock, Supply, WriteOff, Suppliers
 			warehouseHandler := NewWarehouseHandler(usecases.Warehouse, logger)
+			stockAlertHandler := NewStockAlertHandler(usecases.StockAlert, logger)
+			barcodeHandler := NewBarcodeHandler(usecases.Barcode, logger)
+			supplyImportHandler := NewSupplyImportHandler(usecases.SupplyImport, logger)
+			purchaseOrderHandler := NewPurchaseOrderHandler(usecases.PurchaseOrder, logger)
+			supplierPaymentHandler := NewSupplierPaymentHandler(usecases.SupplierPayment, logger)
 			warehouses := protected.Group("/warehouses")
 			warehouses.Use(middleware.RequireEstablishment(usecases.Auth))
 			{
@@ -281,12 +352,30 @@ func NewRouter(usecases *usecases.UseCases, cfg *config.Config, logger *zap.Logg
 			warehouse.Use(middleware.RequireEstablishment(usecases.Auth))
 			{
 				warehouse.GET("/stock", warehouseHandler.GetStock)
+				warehouse.GET("/stock/as-of", warehouseHandler.GetStockAsOf) // ?as_of, ?warehouse_id
 				warehouse.PUT("/stock/:id/limit", warehouseHandler.UpdateStockLimit)
+				warehouse.GET("/alerts", stockAlertHandler.ListAlerts) // Уведомления о низком остатке, ?warehouse_id, ?status
+				warehouse.POST("/alerts/evaluate", stockAlertHandler.EvaluateAlerts)
+				warehouse.PUT("/alerts/:id/acknowledge", stockAlertHandler.AcknowledgeAlert)
+				warehouse.GET("/reorder-suggestions", stockAlertHandler.GetReorderSuggestions) // ?warehouse_id, ?window_days, ?cover_days
+				warehouse.POST("/reorder-suggestions/purchase-order", stockAlertHandler.CreatePurchaseOrderFromSuggestion)
+				warehouse.GET("/expiry", warehouseHandler.GetExpiryReport) // Истекающие и просроченные партии, ?warehouse_id, ?days
+				warehouse.GET("/expiry/write-off", warehouseHandler.SuggestExpiredWriteOffs)
+				warehouse.POST("/expiry/write-off", warehouseHandler.WriteOffExpired)
+				warehouse.GET("/lots", warehouseHandler.GetStockLots) // Партии FIFO, ?warehouse_id, ?ingredient_id, ?product_id, ?open=true
 				warehouse.GET("/supplies", warehouseHandler.ListSupplies) // Список всех поставок, опционально ?warehouse_id=xxx
 				warehouse.GET("/supplies/:id", warehouseHandler.GetSupply) // Получить поставку по ID
 				warehouse.GET("/supplies/by-item", warehouseHandler.GetSuppliesByItem) // ?ingredient_id=xxx или ?product_id=xxx
 				warehouse.POST("/supplies", warehouseHandler.CreateSupply)
 				warehouse.PUT("/supplies/:id", warehouseHandler.UpdateSupply) // Обновить поставку
+				warehouse.POST("/supplies/:id/scan", barcodeHandler.ScanSupply) // Добавить позицию по штрихкоду
+				warehouse.POST("/supplies/import/preview", supplyImportHandler.Preview) // multipart: file (.csv, .xlsx, UBL .xml), supplier_id, warehouse_id
+				warehouse.POST("/supplies/import", supplyImportHandler.Import)
+				warehouse.GET("/barcodes", barcodeHandler.ListBarcodes) // ?ingredient_id, ?product_id, ?tech_card_id
+				warehouse.POST("/barcodes", barcodeHandler.CreateBarcode)
+				warehouse.GET("/barcodes/lookup", barcodeHandler.LookupBarcode) // ?code
+				warehouse.PUT("/barcodes/:id", barcodeHandler.UpdateBarcode)
+				warehouse.DELETE("/barcodes/:id", barcodeHandler.DeleteBarcode)
 				warehouse.GET("/write-offs", warehouseHandler.ListWriteOffs)
 				warehouse.GET("/write-offs/:id", warehouseHandler.GetWriteOff)
 				warehouse.POST("/write-offs", warehouseHandler.CreateWriteOff)
@@ -295,12 +384,38 @@ func NewRouter(usecases *usecases.UseCases, cfg *config.Config, logger *zap.Logg
 				warehouse.POST("/write-off-reasons", warehouseHandler.CreateWriteOffReason)
 				warehouse.PUT("/write-off-reasons/:id", warehouseHandler.UpdateWriteOffReason)
 				warehouse.DELETE("/write-off-reasons/:id", warehouseHandler.DeleteWriteOffReason)
-				warehouse.GET("/movements", warehouseHandler.GetMovements)
+				warehouse.GET("/transfers", warehouseHandler.ListTransfers) // ?warehouse_id=xxx — склад-отправитель или получатель
+				warehouse.GET("/transfers/:id", warehouseHandler.GetTransfer)
+				warehouse.POST("/transfers", warehouseHandler.CreateTransfer)
+				warehouse.PUT("/transfers/:id/status", warehouseHandler.UpdateTransferStatus)
+				warehouse.GET("/productions", warehouseHandler.ListProductions) // ?warehouse_id=xxx
+				warehouse.GET("/productions/:id", warehouseHandler.GetProduction)
+				warehouse.POST("/productions", warehouseHandler.CreateProduction)
+				warehouse.GET("/movements", warehouseHandler.GetMovements) // ?warehouse_id, ?ingredient_id, ?product_id, ?semi_finished_id, ?movement_type, ?start_date, ?end_date
+				warehouse.GET("/movements/report", warehouseHandler.GetMovementReport)
+				warehouse.GET("/purchase-orders", purchaseOrderHandler.ListPurchaseOrders) // ?supplier_id, ?warehouse_id, ?status
+				warehouse.GET("/purchase-orders/open-report", purchaseOrderHandler.GetOpenPurchaseOrdersReport) // ?supplier_id
+				warehouse.GET("/purchase-orders/:id", purchaseOrderHandler.GetPurchaseOrder)
+				warehouse.GET("/purchase-orders/:id/export", purchaseOrderHandler.ExportPurchaseOrder) // ?format=csv|pdf
+				warehouse.POST("/purchase-orders", purchaseOrderHandler.CreatePurchaseOrder)
+				warehouse.PUT("/purchase-orders/:id", purchaseOrderHandler.UpdatePurchaseOrder)
+				warehouse.PUT("/purchase-orders/:id/status", purchaseOrderHandler.UpdatePurchaseOrderStatus)
+				warehouse.POST("/purchase-orders/:id/receive", purchaseOrderHandler.ReceivePurchaseOrder)
+				warehouse.DELETE("/purchase-orders/:id", purchaseOrderHandler.DeletePurchaseOrder)
 				warehouse.GET("/suppliers", warehouseHandler.ListSuppliers)
 				warehouse.POST("/suppliers", warehouseHandler.CreateSupplier)
 				warehouse.GET("/suppliers/:id", warehouseHandler.GetSupplier)
 				warehouse.PUT("/suppliers/:id", warehouseHandler.UpdateSupplier)
 				warehouse.DELETE("/suppliers/:id", warehouseHandler.DeleteSupplier)
+				warehouse.GET("/suppliers/:id/balance", supplierPaymentHandler.GetSupplierBalance)
+				warehouse.GET("/suppliers/:id/item-mappings", supplyImportHandler.ListItemMappings)
+				warehouse.PUT("/suppliers/:id/item-mappings", supplyImportHandler.SaveItemMapping)
+				warehouse.DELETE("/suppliers/:id/item-mappings/:mapping_id", supplyImportHandler.DeleteItemMapping)
+				warehouse.GET("/supplier-payments", supplierPaymentHandler.ListSupplierPayments) // ?supplier_id, ?start_date, ?end_date
+				warehouse.GET("/supplier-payments/:id", supplierPaymentHandler.GetSupplierPayment)
+				warehouse.POST("/supplier-payments", supplierPaymentHandler.CreateSupplierPayment)
+				warehouse.DELETE("/supplier-payments/:id", supplierPaymentHandler.DeleteSupplierPayment)
+				warehouse.GET("/payables/aging", supplierPaymentHandler.GetPayablesAging) // ?as_of
 			}
 
 			// Inventory (инвентаризация)
@@ -313,10 +428,18 @@ func NewRouter(usecases *usecases.UseCases, cfg *config.Config, logger *zap.Logg
 				inventory.POST("", inventoryHandler.Create)
 				inventory.PUT("/:id", inventoryHandler.Update)
 				inventory.PUT("/:id/status", inventoryHandler.UpdateStatus)
+				inventory.POST("/:id/scan", barcodeHandler.ScanInventory)
 				inventory.DELETE("/:id", inventoryHandler.Delete)
 				inventory.GET("/stock-snapshot", inventoryHandler.GetStockSnapshot)
+				inventory.GET("/variance", inventoryHandler.GetVarianceReport) // ?start_date=&end_date=&warehouse_id=&item_type=ingredient|product
 				inventory.PUT("/:id/items/:item_id", inventoryHandler.UpdateItem)
 				inventory.DELETE("/:id/items/:item_id", inventoryHandler.DeleteItem)
+				inventory.GET("/:id/counts", inventoryHandler.ListCounts)
+				inventory.POST("/:id/counts", inventoryHandler.SubmitCounts)
+				inventory.POST("/:id/counts/import", inventoryHandler.ImportCounts)
+				inventory.GET("/:id/reconciliation", inventoryHandler.GetReconciliation)
+				inventory.POST("/:id/reconcile", inventoryHandler.ResolveCount)
+				inventory.GET("/:id/count-sheet", inventoryHandler.ExportCountSheet) // ?format=csv|pdf&zone=
 			}
 
 			// Finance
@@ -363,9 +486,11 @@ func NewRouter(usecases *usecases.UseCases, cfg *config.Config, logger *zap.Logg
 			{
 				orders.GET("", orderHandler.List)
 				orders.GET("/active", orderHandler.ListActiveOrdersByEstablishment)
+				orders.GET("/stop-list", orderHandler.GetStopList) // Блюда и товары, которые нельзя приготовить из остатков
 				orders.GET("/:order_id", orderHandler.Get)
 				orders.POST("", orderHandler.Create)
 				orders.POST("/:order_id/items", orderHandler.AddOrderItem)
+				orders.POST("/:order_id/scan", barcodeHandler.ScanOrder)
 				orders.PUT("/:order_id/items/:item_id", orderHandler.UpdateOrderItemQuantity)
 				orders.PUT("/:order_id", orderHandler.Update)
 				orders.POST("/:order_id/pay", orderHandler.ProcessOrderPayment)
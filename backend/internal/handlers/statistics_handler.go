@@ -206,6 +206,7 @@ func (h *StatisticsHandler) GetCategories(c *gin.Context) {
 // @Param establishment_id query string false "ID заведения"
 // @Param start_date query string false "Начальная дата (format: 2006-01-02), опционально"
 // @Param end_date query string false "Конечная дата (format: 2006-01-02), опционально"
+// @Param rollup_variants query bool false "Свернуть модификации в родительский товар"
 // @Success 200 {object} models.ProductStatistics
 // @Router /statistics/products [get]
 func (h *StatisticsHandler) GetProducts(c *gin.Context) {
@@ -215,7 +216,7 @@ func (h *StatisticsHandler) GetProducts(c *gin.Context) {
 		return
 	}
 
-	stats, err := h.usecase.GetProductStatistics(c.Request.Context(), establishmentID, startDate, endDate)
+	stats, err := h.usecase.GetProductStatistics(c.Request.Context(), establishmentID, startDate, endDate, c.Query("rollup_variants") == "true")
 	if err != nil {
 		h.logger.Error("Failed to get product statistics", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get product statistics"})
@@ -234,6 +235,7 @@ func (h *StatisticsHandler) GetProducts(c *gin.Context) {
 // @Param establishment_id query string false "ID заведения"
 // @Param start_date query string false "Начальная дата (format: 2006-01-02), опционально"
 // @Param end_date query string false "Конечная дата (format: 2006-01-02), опционально"
+// @Param rollup_variants query bool false "Свернуть модификации в родительский товар"
 // @Success 200 {object} models.ABCAnalysisData
 // @Router /statistics/abc [get]
 func (h *StatisticsHandler) GetABCAnalysis(c *gin.Context) {
@@ -243,7 +245,7 @@ func (h *StatisticsHandler) GetABCAnalysis(c *gin.Context) {
 		return
 	}
 
-	stats, err := h.usecase.GetABCAnalysis(c.Request.Context(), establishmentID, startDate, endDate)
+	stats, err := h.usecase.GetABCAnalysis(c.Request.Context(), establishmentID, startDate, endDate, c.Query("rollup_variants") == "true")
 	if err != nil {
 		h.logger.Error("Failed to get ABC analysis", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get ABC analysis"})
@@ -365,6 +367,112 @@ func (h *StatisticsHandler) GetTaxes(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": stats})
 }
 
+// GetMenuEngineering возвращает отчет меню-инжиниринга
+// @Summary Меню-инжиниринг
+// @Description Классифицирует проданные тех-карты и товары каждой категории на Star, Plowhorse, Puzzle и Dog по доле продаж и маржинальному доходу и дает рекомендации
+// @Tags statistics
+// @Produce json
+// @Security Bearer
+// @Param establishment_id query string false "ID заведения"
+// @Param start_date query string false "Начальная дата (format: 2006-01-02), опционально"
+// @Param end_date query string false "Конечная дата (format: 2006-01-02), опционально"
+// @Param category_id query string false "ID категории"
+// @Success 200 {object} models.MenuEngineeringReport
+// @Router /statistics/menu-engineering [get]
+func (h *StatisticsHandler) GetMenuEngineering(c *gin.Context) {
+	establishmentID, startDate, endDate, err := h.parseStatisticsParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	categoryID, err := parseCategoryIDQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category_id"})
+		return
+	}
+
+	report, err := h.usecase.GetMenuEngineering(c.Request.Context(), establishmentID, startDate, endDate, categoryID)
+	if err != nil {
+		h.logger.Error("Failed to get menu engineering report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get menu engineering report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// CompareMenuEngineering сравнивает отчеты меню-инжиниринга за два периода
+// @Summary Сравнение меню-инжиниринга за два периода
+// @Description Показывает изменение класса, продаж и маржинальности позиций. Если период сравнения не указан, берется предыдущий период той же длины
+// @Tags statistics
+// @Produce json
+// @Security Bearer
+// @Param establishment_id query string false "ID заведения"
+// @Param start_date query string false "Начальная дата (format: 2006-01-02), опционально"
+// @Param end_date query string false "Конечная дата (format: 2006-01-02), опционально"
+// @Param compare_start_date query string false "Начальная дата периода сравнения (format: 2006-01-02)"
+// @Param compare_end_date query string false "Конечная дата периода сравнения (format: 2006-01-02)"
+// @Param category_id query string false "ID категории"
+// @Success 200 {object} models.MenuEngineeringComparison
+// @Router /statistics/menu-engineering/compare [get]
+func (h *StatisticsHandler) CompareMenuEngineering(c *gin.Context) {
+	establishmentID, startDate, endDate, err := h.parseStatisticsParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	categoryID, err := parseCategoryIDQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category_id"})
+		return
+	}
+
+	var prevStartDate, prevEndDate time.Time
+	compareStartStr := c.Query("compare_start_date")
+	compareEndStr := c.Query("compare_end_date")
+	if compareStartStr == "" || compareEndStr == "" {
+		// Предыдущий период той же длины
+		days := int(endDate.Sub(startDate).Hours()/24) + 1
+		prevStartDate = startDate.AddDate(0, 0, -days)
+		prevEndDate = startDate.Add(-time.Second)
+	} else {
+		prevStartDate, err = time.Parse("2006-01-02", compareStartStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid compare_start_date"})
+			return
+		}
+		prevEndDate, err = time.Parse("2006-01-02", compareEndStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid compare_end_date"})
+			return
+		}
+		prevStartDate = time.Date(prevStartDate.Year(), prevStartDate.Month(), prevStartDate.Day(), 0, 0, 0, 0, time.Local)
+		prevEndDate = time.Date(prevEndDate.Year(), prevEndDate.Month(), prevEndDate.Day(), 23, 59, 59, 0, time.Local)
+	}
+
+	comparison, err := h.usecase.CompareMenuEngineering(c.Request.Context(), establishmentID, startDate, endDate, prevStartDate, prevEndDate, categoryID)
+	if err != nil {
+		h.logger.Error("Failed to compare menu engineering reports", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare menu engineering reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": comparison})
+}
+
+// parseCategoryIDQuery парсит необязательный параметр category_id
+func parseCategoryIDQuery(c *gin.Context) (*uuid.UUID, error) {
+	categoryIDStr := c.Query("category_id")
+	if categoryIDStr == "" {
+		return nil, nil
+	}
+	categoryID, err := uuid.Parse(categoryIDStr)
+	if err != nil {
+		return nil, err
+	}
+	return &categoryID, nil
+}
+
 // parseStatisticsParams парсит параметры для запросов статистики
 func (h *StatisticsHandler) parseStatisticsParams(c *gin.Context) (uuid.UUID, time.Time, time.Time, error) {
 	var establishmentID uuid.UUID
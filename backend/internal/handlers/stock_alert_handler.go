@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+	"github.com/yourusername/arc/backend/internal/usecases"
+)
+
+type StockAlertHandler struct {
+	usecase *usecases.StockAlertUseCase
+	logger  *zap.Logger
+}
+
+func NewStockAlertHandler(usecase *usecases.StockAlertUseCase, logger *zap.Logger) *StockAlertHandler {
+	return &StockAlertHandler{
+		usecase: usecase,
+		logger:  logger,
+	}
+}
+
+// ——— Requests ———
+
+type CreatePurchaseOrderFromSuggestionRequest struct {
+	SupplierID  string `json:"supplier_id" binding:"required,uuid"`
+	WarehouseID string `json:"warehouse_id" binding:"required,uuid"`
+	WindowDays  int    `json:"window_days" binding:"omitempty,gte=1"` // Период расчета расхода (по умолчанию 14 дней)
+	CoverDays   int    `json:"cover_days" binding:"omitempty,gte=1"`  // Запас после поставки (по умолчанию 7 дней)
+}
+
+// ——— Handlers ———
+
+// ListAlerts возвращает уведомления о низком остатке
+// @Summary Получить уведомления о низком остатке
+// @Description Возвращает уведомления об остатках ниже лимита. По умолчанию только активные (open, acknowledged).
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param warehouse_id query string false "ID склада"
+// @Param status query string false "Статус (open, acknowledged, resolved)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/alerts [get]
+func (h *StockAlertHandler) ListAlerts(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := &repositories.StockAlertFilter{}
+	if s := c.Query("warehouse_id"); s != "" {
+		if id, e := uuid.Parse(s); e == nil {
+			filter.WarehouseID = &id
+		}
+	}
+	if s := c.Query("status"); s != "" {
+		status := models.StockAlertStatus(s)
+		filter.Status = &status
+	} else {
+		filter.OnlyActive = true
+	}
+
+	list, err := h.usecase.ListAlerts(c.Request.Context(), estID, filter)
+	if err != nil {
+		h.logger.Error("Failed to list stock alerts", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list stock alerts"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// AcknowledgeAlert отмечает уведомление о низком остатке как просмотренное
+// @Summary Подтвердить уведомление о низком остатке
+// @Description Переводит уведомление в статус acknowledged. Уведомление закрывается автоматически, когда остаток восстановится.
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID уведомления"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/alerts/{id}/acknowledge [put]
+func (h *StockAlertHandler) AcknowledgeAlert(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var userID *uuid.UUID
+	if v, exists := c.Get("user_id"); exists {
+		if uid, ok := v.(uuid.UUID); ok {
+			userID = &uid
+		}
+	}
+
+	alert, err := h.usecase.AcknowledgeAlert(c.Request.Context(), id, estID, userID)
+	if err != nil {
+		h.logger.Error("Failed to acknowledge stock alert", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": alert})
+}
+
+// EvaluateAlerts запускает проверку остатков заведения немедленно
+// @Summary Проверить остатки
+// @Description Сравнивает остатки с лимитами и обновляет уведомления, не дожидаясь фоновой проверки
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/alerts/evaluate [post]
+func (h *StockAlertHandler) EvaluateAlerts(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.usecase.EvaluateStockAlerts(c.Request.Context(), &estID); err != nil {
+		h.logger.Error("Failed to evaluate stock alerts", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.usecase.ListAlerts(c.Request.Context(), estID, &repositories.StockAlertFilter{OnlyActive: true})
+	if err != nil {
+		h.logger.Error("Failed to list stock alerts", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list stock alerts"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// GetReorderSuggestions возвращает рекомендации по дозаказу
+// @Summary Рекомендации по дозаказу
+// @Description Группирует по поставщикам позиции, остаток которых опустится ниже лимита до поступления заказа. Учитывает средний расход, срок поставки поставщика и цену последней поставки.
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param warehouse_id query string false "ID склада"
+// @Param window_days query int false "Период расчета среднего расхода в днях (по умолчанию 14)"
+// @Param cover_days query int false "На сколько дней после поставки должно хватить запаса (по умолчанию 7)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/reorder-suggestions [get]
+func (h *StockAlertHandler) GetReorderSuggestions(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := usecases.ReorderOptions{}
+	if s := c.Query("warehouse_id"); s != "" {
+		if id, e := uuid.Parse(s); e == nil {
+			opts.WarehouseID = &id
+		}
+	}
+	if s := c.Query("window_days"); s != "" {
+		if v, e := strconv.Atoi(s); e == nil {
+			opts.WindowDays = v
+		}
+	}
+	if s := c.Query("cover_days"); s != "" {
+		if v, e := strconv.Atoi(s); e == nil {
+			opts.CoverDays = v
+		}
+	}
+
+	list, err := h.usecase.GetReorderSuggestions(c.Request.Context(), estID, opts)
+	if err != nil {
+		h.logger.Error("Failed to get reorder suggestions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// CreatePurchaseOrderFromSuggestion создает заказ поставщику из рекомендации по дозаказу
+// @Summary Создать заказ поставщику из рекомендации
+// @Description Создает черновик заказа поставщику с рекомендованными позициями, количеством и ценами
+// @Tags warehouse
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body CreatePurchaseOrderFromSuggestionRequest true "Поставщик и склад рекомендации"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/reorder-suggestions/purchase-order [post]
+func (h *StockAlertHandler) CreatePurchaseOrderFromSuggestion(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req CreatePurchaseOrderFromSuggestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	supplierID, _ := uuid.Parse(req.SupplierID)
+	warehouseID, _ := uuid.Parse(req.WarehouseID)
+
+	order, err := h.usecase.CreatePurchaseOrderFromSuggestion(c.Request.Context(), estID, supplierID, warehouseID, usecases.ReorderOptions{
+		WindowDays: req.WindowDays,
+		CoverDays:  req.CoverDays,
+	})
+	if err != nil {
+		h.logger.Error("Failed to create purchase order from suggestion", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": order})
+}
@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+	"github.com/yourusername/arc/backend/internal/usecases"
+)
+
+type SupplierPaymentHandler struct {
+	usecase *usecases.SupplierPaymentUseCase
+	logger  *zap.Logger
+}
+
+func NewSupplierPaymentHandler(usecase *usecases.SupplierPaymentUseCase, logger *zap.Logger) *SupplierPaymentHandler {
+	return &SupplierPaymentHandler{
+		usecase: usecase,
+		logger:  logger,
+	}
+}
+
+// ——— Requests ———
+
+type SupplierPaymentAllocationRequest struct {
+	SupplyID string  `json:"supply_id" binding:"required,uuid"`
+	Amount   float64 `json:"amount" binding:"required,gt=0"`
+}
+
+type CreateSupplierPaymentRequest struct {
+	SupplierID  string                             `json:"supplier_id" binding:"required,uuid"`
+	AccountID   string                             `json:"account_id" binding:"required,uuid"` // Счет, с которого производится оплата
+	Amount      float64                            `json:"amount" binding:"required,gt=0"`
+	PaymentDate string                             `json:"payment_date"` // RFC3339, по умолчанию — текущее время
+	Comment     string                             `json:"comment"`
+	Allocations []SupplierPaymentAllocationRequest `json:"allocations"` // Распределение по поставкам; если пусто — по сроку оплаты
+}
+
+// ——— Handlers ———
+
+// ListSupplierPayments возвращает платежи поставщикам
+// @Summary Получить платежи поставщикам
+// @Description Возвращает платежи поставщикам с распределением по поставкам
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param supplier_id query string false "ID поставщика"
+// @Param start_date query string false "Начало периода (RFC3339)"
+// @Param end_date query string false "Конец периода (RFC3339)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/supplier-payments [get]
+func (h *SupplierPaymentHandler) ListSupplierPayments(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := &repositories.SupplierPaymentFilter{}
+	if s := c.Query("supplier_id"); s != "" {
+		if id, e := uuid.Parse(s); e == nil {
+			filter.SupplierID = &id
+		}
+	}
+	if s := c.Query("start_date"); s != "" {
+		if t, e := time.Parse(time.RFC3339, s); e == nil {
+			filter.StartDate = &t
+		}
+	}
+	if s := c.Query("end_date"); s != "" {
+		if t, e := time.Parse(time.RFC3339, s); e == nil {
+			filter.EndDate = &t
+		}
+	}
+
+	list, err := h.usecase.ListPayments(c.Request.Context(), estID, filter)
+	if err != nil {
+		h.logger.Error("Failed to list supplier payments", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list supplier payments"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// GetSupplierPayment возвращает платеж поставщику по ID
+// @Summary Получить платеж поставщику
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID платежа"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /warehouse/supplier-payments/{id} [get]
+func (h *SupplierPaymentHandler) GetSupplierPayment(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	payment, err := h.usecase.GetPayment(c.Request.Context(), id, estID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": payment})
+}
+
+// CreateSupplierPayment проводит платеж поставщику
+// @Summary Оплатить поставщику
+// @Description Создает платеж поставщику и расходную транзакцию по счету. Платеж может закрывать несколько поставок или часть одной; без распределения сумма закрывает поставки по сроку оплаты, остаток становится авансом.
+// @Tags warehouse
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body CreateSupplierPaymentRequest true "Данные платежа"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/supplier-payments [post]
+func (h *SupplierPaymentHandler) CreateSupplierPayment(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req CreateSupplierPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	supplierID, _ := uuid.Parse(req.SupplierID)
+	accountID, _ := uuid.Parse(req.AccountID)
+
+	payment := &models.SupplierPayment{
+		SupplierID: supplierID,
+		AccountID:  accountID,
+		Amount:     req.Amount,
+		Comment:    req.Comment,
+	}
+	if req.PaymentDate != "" {
+		t, err := time.Parse(time.RFC3339, req.PaymentDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment_date format, use RFC3339"})
+			return
+		}
+		payment.PaymentDate = t
+	}
+	for _, a := range req.Allocations {
+		supplyID, _ := uuid.Parse(a.SupplyID)
+		payment.Allocations = append(payment.Allocations, models.SupplierPaymentAllocation{
+			SupplyID: supplyID,
+			Amount:   a.Amount,
+		})
+	}
+	if v, exists := c.Get("user_id"); exists {
+		if uid, ok := v.(uuid.UUID); ok {
+			payment.CreatedBy = &uid
+		}
+	}
+
+	if err := h.usecase.CreatePayment(c.Request.Context(), payment, estID); err != nil {
+		h.logger.Error("Failed to create supplier payment", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": payment})
+}
+
+// DeleteSupplierPayment отменяет платеж поставщику
+// @Summary Отменить платеж поставщику
+// @Description Удаляет платеж, возвращает сумму на счет и уменьшает оплаченные суммы поставок
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID платежа"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/supplier-payments/{id} [delete]
+func (h *SupplierPaymentHandler) DeleteSupplierPayment(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.usecase.DeletePayment(c.Request.Context(), id, estID); err != nil {
+		h.logger.Error("Failed to delete supplier payment", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "supplier payment deleted"})
+}
+
+// GetSupplierBalance возвращает задолженность перед поставщиком
+// @Summary Баланс расчетов с поставщиком
+// @Description Сумма поставок, оплачено, неоплаченный остаток, просрочено, аванс и список неоплаченных поставок со сроками оплаты
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID поставщика"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /warehouse/suppliers/{id}/balance [get]
+func (h *SupplierPaymentHandler) GetSupplierBalance(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	balance, err := h.usecase.GetSupplierBalance(c.Request.Context(), id, estID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": balance})
+}
+
+// GetPayablesAging возвращает отчет по старению задолженности перед поставщиками
+// @Summary Старение кредиторской задолженности
+// @Description Неоплаченные суммы по поставщикам в разрезе просрочки: не наступил срок, 0–30, 31–60 и более 60 дней
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param as_of query string false "Дата отчета (RFC3339), по умолчанию — текущая"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/payables/aging [get]
+func (h *SupplierPaymentHandler) GetPayablesAging(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	var asOf time.Time
+	if s := c.Query("as_of"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid as_of format, use RFC3339"})
+			return
+		}
+		asOf = t
+	}
+
+	report, err := h.usecase.GetAgingReport(c.Request.Context(), estID, asOf)
+	if err != nil {
+		h.logger.Error("Failed to get payables aging report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/usecases"
+)
+
+// maxSupplyImportFileSize предельный размер импортируемого документа поставщика
+const maxSupplyImportFileSize = 10 << 20
+
+type SupplyImportHandler struct {
+	usecase *usecases.SupplyImportUseCase
+	logger  *zap.Logger
+}
+
+func NewSupplyImportHandler(usecase *usecases.SupplyImportUseCase, logger *zap.Logger) *SupplyImportHandler {
+	return &SupplyImportHandler{
+		usecase: usecase,
+		logger:  logger,
+	}
+}
+
+// ——— Requests ———
+
+type SupplierItemMappingRequest struct {
+	SupplierCode string  `json:"supplier_code"` // Артикул поставщика
+	SupplierName string  `json:"supplier_name"` // Название в документах поставщика (если нет артикула)
+	IngredientID *string `json:"ingredient_id,omitempty" binding:"omitempty,uuid"`
+	ProductID    *string `json:"product_id,omitempty" binding:"omitempty,uuid"`
+	SupplierUnit string  `json:"supplier_unit"`               // Единица поставщика, например «кор»
+	UnitFactor   float64 `json:"unit_factor" binding:"gte=0"` // Количество unit в одной единице поставщика
+	Unit         string  `json:"unit"`                        // Единица unit_factor (по умолчанию — единица учета позиции)
+}
+
+// ——— Import ———
+
+// Preview разбирает документ поставщика без создания поставки
+// @Summary Предпросмотр импорта поставки
+// @Description Разбирает CSV, XLSX или UBL-счет поставщика, сопоставляет строки с ингредиентами и товарами, пересчитывает единицы и проверяет строки
+// @Tags warehouse
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param file formData file true "Документ поставщика (.csv, .xlsx, .xml)"
+// @Param supplier_id formData string true "ID поставщика"
+// @Param warehouse_id formData string true "ID склада"
+// @Param format formData string false "csv, xlsx или ubl (по умолчанию — по расширению файла)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /warehouse/supplies/import/preview [post]
+func (h *SupplyImportHandler) Preview(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	supplierID, err := uuid.Parse(c.PostForm("supplier_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid supplier_id"})
+		return
+	}
+	warehouseID, err := uuid.Parse(c.PostForm("warehouse_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid warehouse_id"})
+		return
+	}
+	format := c.PostForm("format")
+	switch format {
+	case "", usecases.SupplyImportFormatCSV, usecases.SupplyImportFormatXLSX, usecases.SupplyImportFormatUBL:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv, xlsx or ubl"})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if file.Size > maxSupplyImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is too large"})
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to open file"})
+		return
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, maxSupplyImportFileSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+
+	preview, err := h.usecase.Preview(c.Request.Context(), estID, supplierID, warehouseID, format, file.Filename, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": preview})
+}
+
+// Import создает поставку из строк предпросмотра
+// @Summary Импорт поставки
+// @Description Создает поставку из подтвержденных строк предпросмотра. Если в строках остались ошибки, возвращает 422 и предпросмотр с ошибками
+// @Tags warehouse
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body usecases.SupplyImportRequest true "Строки поставки"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
+// @Router /warehouse/supplies/import [post]
+func (h *SupplyImportHandler) Import(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	var req usecases.SupplyImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	supply, preview, err := h.usecase.Import(c.Request.Context(), estID, &req)
+	if err != nil {
+		if errors.Is(err, usecases.ErrSupplyImportInvalid) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "data": preview})
+			return
+		}
+		h.logger.Error("Failed to import supply", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": supply, "preview": preview})
+}
+
+// ——— Supplier item mappings ———
+
+// ListItemMappings возвращает запомненные сопоставления позиций поставщика
+// @Summary Сопоставления позиций поставщика
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID поставщика"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /warehouse/suppliers/{id}/item-mappings [get]
+func (h *SupplyImportHandler) ListItemMappings(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	supplierID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	list, err := h.usecase.ListItemMappings(c.Request.Context(), estID, supplierID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// SaveItemMapping создает или обновляет сопоставление позиции поставщика
+// @Summary Сохранить сопоставление позиции поставщика
+// @Description Ключ сопоставления — артикул поставщика, а если его нет — название. Существующее сопоставление с тем же ключом обновляется
+// @Tags warehouse
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID поставщика"
+// @Param request body SupplierItemMappingRequest true "Сопоставление"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /warehouse/suppliers/{id}/item-mappings [put]
+func (h *SupplyImportHandler) SaveItemMapping(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	supplierID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req SupplierItemMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	m := &models.SupplierItemMapping{
+		SupplierID:   supplierID,
+		SupplierCode: req.SupplierCode,
+		SupplierName: req.SupplierName,
+		IngredientID: parseOptionalUUID(req.IngredientID),
+		ProductID:    parseOptionalUUID(req.ProductID),
+		SupplierUnit: req.SupplierUnit,
+		UnitFactor:   req.UnitFactor,
+		Unit:         req.Unit,
+	}
+	if err := h.usecase.SaveItemMapping(c.Request.Context(), estID, m); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": m})
+}
+
+// DeleteItemMapping удаляет сопоставление позиции поставщика
+// @Summary Удалить сопоставление позиции поставщика
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID поставщика"
+// @Param mapping_id path string true "ID сопоставления"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /warehouse/suppliers/{id}/item-mappings/{mapping_id} [delete]
+func (h *SupplyImportHandler) DeleteItemMapping(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	supplierID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	mappingID, err := uuid.Parse(c.Param("mapping_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid mapping_id"})
+		return
+	}
+	if err := h.usecase.DeleteItemMapping(c.Request.Context(), estID, supplierID, mappingID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "item mapping deleted"})
+}
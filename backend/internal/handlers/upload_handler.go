@@ -1,38 +1,44 @@
 package handlers
 
 import (
-	"bytes"
 	"encoding/base64"
-	"fmt"
+	"errors"
+	"io"
 	"net/http"
-	"path/filepath"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 
-	"github.com/yourusername/arc/backend/pkg/storage"
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/usecases"
 )
 
+// maxImageUploadSize предельный размер загружаемого изображения
+const maxImageUploadSize = 10 << 20
+
 type UploadHandler struct {
-	storage *storage.MinIOClient
+	usecase *usecases.UploadUseCase
 	logger  *zap.Logger
 }
 
-func NewUploadHandler(storageClient *storage.MinIOClient, logger *zap.Logger) *UploadHandler {
+func NewUploadHandler(usecase *usecases.UploadUseCase, logger *zap.Logger) *UploadHandler {
 	return &UploadHandler{
-		storage: storageClient,
+		usecase: usecase,
 		logger:  logger,
 	}
 }
 
 // UploadImage загружает изображение в MinIO
 // @Summary Загрузить изображение
-// @Description Загружает изображение в хранилище и возвращает публичный URL
+// @Description Проверяет тип по содержимому файла (JPEG, PNG, GIF, WebP), удаляет EXIF и другие метаданные, уменьшает оригинал до 2048 px и сохраняет варианты medium (1024 px), thumb (320 px) и WebP. Изображение, на которое сутки не ссылается ни один cover_image, удаляется автоматически
 // @Tags upload
 // @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "Файл изображения"
+// @Param entity_type formData string false "Владелец: product, tech_card, semi_finished или combo"
+// @Param entity_id formData string false "ID владельца"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
@@ -45,32 +51,15 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
 		return
 	}
-
-	// Проверяем тип файла
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	allowedExts := []string{".jpg", ".jpeg", ".png", ".gif", ".webp"}
-	allowed := false
-	for _, allowedExt := range allowedExts {
-		if ext == allowedExt {
-			allowed = true
-			break
-		}
-	}
-
-	if !allowed {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("file type not allowed. Allowed types: %v", allowedExts),
-		})
+	if file.Size > maxImageUploadSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file size exceeds 10MB limit"})
 		return
 	}
-
-	// Проверяем размер файла (максимум 10MB)
-	if file.Size > 10*1024*1024 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "file size exceeds 10MB limit"})
+	owner, ok := uploadOwner(c, c.PostForm("entity_type"), c.PostForm("entity_id"))
+	if !ok {
 		return
 	}
 
-	// Открываем файл
 	src, err := file.Open()
 	if err != nil {
 		h.logger.Error("Failed to open uploaded file", zap.Error(err))
@@ -78,40 +67,18 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 		return
 	}
 	defer src.Close()
-
-	// Определяем Content-Type
-	contentType := "image/jpeg"
-	switch ext {
-	case ".png":
-		contentType = "image/png"
-	case ".gif":
-		contentType = "image/gif"
-	case ".webp":
-		contentType = "image/webp"
-	}
-
-	// Загружаем в MinIO
-	url, err := h.storage.UploadImage(c.Request.Context(), src, file.Filename, contentType)
+	data, err := io.ReadAll(io.LimitReader(src, maxImageUploadSize))
 	if err != nil {
-		h.logger.Error("Failed to upload image", 
-			zap.Error(err),
-			zap.String("filename", file.Filename),
-			zap.String("content_type", contentType),
-			zap.Int64("size", file.Size))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to upload image"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"url":      url,
-		"filename": file.Filename,
-		"size":     file.Size,
-	})
+	h.upload(c, data, owner, file.Filename)
 }
 
 // UploadImageFromBase64 загружает изображение из base64 строки
 // @Summary Загрузить изображение из base64
-// @Description Загружает изображение из base64 строки в хранилище
+// @Description Загружает изображение из base64 строки; обработка та же, что у /upload/image. Тип определяется по содержимому, а не по заголовку data URL
 // @Tags upload
 // @Accept json
 // @Produce json
@@ -123,13 +90,19 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 // @Security Bearer
 func (h *UploadHandler) UploadImageFromBase64(c *gin.Context) {
 	var req struct {
-		Data string `json:"data" binding:"required"` // data:image/jpeg;base64,/9j/4AAQSkZJRg...
+		Data       string `json:"data" binding:"required"` // data:image/jpeg;base64,/9j/4AAQSkZJRg...
+		EntityType string `json:"entity_type"`
+		EntityID   string `json:"entity_id"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	owner, ok := uploadOwner(c, req.EntityType, req.EntityID)
+	if !ok {
+		return
+	}
 
 	// Парсим data URL
 	parts := strings.Split(req.Data, ",")
@@ -137,50 +110,73 @@ func (h *UploadHandler) UploadImageFromBase64(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid base64 data format"})
 		return
 	}
-
-	header := parts[0] // data:image/jpeg;base64
-	data := parts[1]  // base64 encoded data
-
-	// Извлекаем тип из header
-	contentType := "image/jpeg"
-	if strings.Contains(header, "image/png") {
-		contentType = "image/png"
-	} else if strings.Contains(header, "image/gif") {
-		contentType = "image/gif"
-	} else if strings.Contains(header, "image/webp") {
-		contentType = "image/webp"
+	if base64.StdEncoding.DecodedLen(len(parts[1])) > maxImageUploadSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file size exceeds 10MB limit"})
+		return
 	}
 
 	// Декодируем base64
-	decoded, err := base64.StdEncoding.DecodeString(data)
+	decoded, err := base64.StdEncoding.DecodeString(parts[1])
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to decode base64"})
 		return
 	}
 
-	// Определяем расширение файла
-	ext := ".jpg"
-	switch contentType {
-	case "image/png":
-		ext = ".png"
-	case "image/gif":
-		ext = ".gif"
-	case "image/webp":
-		ext = ".webp"
+	h.upload(c, decoded, owner, "")
+}
+
+// uploadOwner разбирает необязательного владельца изображения; при ошибке отвечает 400
+func uploadOwner(c *gin.Context, entityType, entityID string) (usecases.UploadOwner, bool) {
+	owner := usecases.UploadOwner{EntityType: entityType}
+	if entityID != "" {
+		id, err := uuid.Parse(entityID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entity_id"})
+			return owner, false
+		}
+		owner.EntityID = &id
 	}
+	return owner, true
+}
 
-	// Загружаем в MinIO (decoded уже []byte, преобразуем в Reader)
-	url, err := h.storage.UploadImage(c.Request.Context(), bytes.NewReader(decoded), "image"+ext, contentType)
+func (h *UploadHandler) upload(c *gin.Context, data []byte, owner usecases.UploadOwner, filename string) {
+	var uploadedBy *uuid.UUID
+	if userID, ok := currentUserID(c); ok {
+		uploadedBy = &userID
+	}
+
+	upload, err := h.usecase.UploadImage(c.Request.Context(), data, owner, uploadedBy)
 	if err != nil {
-		h.logger.Error("Failed to upload image from base64", 
+		if errors.Is(err, usecases.ErrInvalidImage) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to upload image",
 			zap.Error(err),
-			zap.String("content_type", contentType),
-			zap.Int("size", len(decoded)))
+			zap.String("filename", filename),
+			zap.Int("size", len(data)))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to upload image"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"url": url,
-	})
+	c.JSON(http.StatusOK, uploadResponse(upload, filename))
+}
+
+// uploadResponse сохраняет прежние поля ответа (url, filename, size) и добавляет варианты
+func uploadResponse(upload *models.Upload, filename string) gin.H {
+	resp := gin.H{
+		"id":           upload.ID,
+		"url":          upload.URL,
+		"medium_url":   upload.MediumURL,
+		"thumb_url":    upload.ThumbURL,
+		"webp_url":     upload.WebPURL,
+		"content_type": upload.ContentType,
+		"size":         upload.Size,
+		"width":        upload.Width,
+		"height":       upload.Height,
+	}
+	if filename != "" {
+		resp["filename"] = filename
+	}
+	return resp
 }
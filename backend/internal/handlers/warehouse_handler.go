@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -221,7 +224,7 @@ func (h *WarehouseHandler) DeleteWarehouse(c *gin.Context) {
 // @Security Bearer
 // @Param warehouse_id query string false "ID склада"
 // @Param search query string false "Поиск"
-// @Param type query string false "Тип (ingredient или product)"
+// @Param type query string false "Тип (ingredient, product или semi_finished)"
 // @Param category_id query string false "ID категории"
 // @Success 200 {object} map[string]interface{}
 // @Failure 403 {object} map[string]string
@@ -244,7 +247,7 @@ func (h *WarehouseHandler) GetStock(c *gin.Context) {
 		filter.Search = &search
 	}
 	if itemType := c.Query("type"); itemType != "" {
-		filter.Type = &itemType // "ingredient" или "product"
+		filter.Type = &itemType // "ingredient", "product" или "semi_finished"
 	}
 	if categoryID := c.Query("category_id"); categoryID != "" {
 		if id, e := uuid.Parse(categoryID); e == nil {
@@ -305,6 +308,193 @@ func (h *WarehouseHandler) UpdateStockLimit(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "stock limit updated"})
 }
 
+// GetStockLots возвращает партии (слои себестоимости FEFO/FIFO)
+// @Summary Получить партии на складе
+// @Description Возвращает партии ингредиентов/товаров/полуфабрикатов с количеством, остатком, себестоимостью единицы, датой поступления и сроком годности. Партии расходуются по FEFO: сначала с ближайшим сроком годности, партии без срока — по FIFO.
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param warehouse_id query string false "ID склада"
+// @Param ingredient_id query string false "ID ингредиента"
+// @Param product_id query string false "ID товара"
+// @Param semi_finished_id query string false "ID полуфабриката"
+// @Param open query bool false "Только партии с нерасходованным остатком"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/lots [get]
+func (h *WarehouseHandler) GetStockLots(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := &repositories.StockLotFilter{}
+	if s := c.Query("warehouse_id"); s != "" {
+		if id, e := uuid.Parse(s); e == nil {
+			filter.WarehouseID = &id
+		}
+	}
+	if s := c.Query("ingredient_id"); s != "" {
+		if id, e := uuid.Parse(s); e == nil {
+			filter.IngredientID = &id
+		}
+	}
+	if s := c.Query("product_id"); s != "" {
+		if id, e := uuid.Parse(s); e == nil {
+			filter.ProductID = &id
+		}
+	}
+	if s := c.Query("semi_finished_id"); s != "" {
+		if id, e := uuid.Parse(s); e == nil {
+			filter.SemiFinishedID = &id
+		}
+	}
+	filter.OnlyOpen = c.Query("open") == "true"
+
+	lots, err := h.usecase.GetStockLots(c.Request.Context(), estID, filter)
+	if err != nil {
+		h.logger.Error("Failed to get stock lots", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get stock lots"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": lots})
+}
+
+// ——— Expiry ———
+
+// parseExpiryWarehouseID разбирает необязательный ?warehouse_id
+func parseExpiryWarehouseID(c *gin.Context) (*uuid.UUID, error) {
+	s := c.Query("warehouse_id")
+	if s == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return nil, errors.New("invalid warehouse_id")
+	}
+	return &id, nil
+}
+
+// GetExpiryReport возвращает партии с истекающим и истекшим сроком годности
+// @Summary Отчет по срокам годности
+// @Description Возвращает открытые партии, срок годности которых истек или истекает в ближайшие days дней (по умолчанию 3), с остатком, себестоимостью и количеством дней до окончания срока
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param warehouse_id query string false "ID склада"
+// @Param days query int false "Горизонт, дней (по умолчанию 3)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/expiry [get]
+func (h *WarehouseHandler) GetExpiryReport(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	warehouseID, err := parseExpiryWarehouseID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	days := 3
+	if s := c.Query("days"); s != "" {
+		days, err = strconv.Atoi(s)
+		if err != nil || days < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid days"})
+			return
+		}
+	}
+
+	report, err := h.usecase.GetExpiryReport(c.Request.Context(), estID, warehouseID, days, time.Now())
+	if err != nil {
+		h.logger.Error("Failed to get expiry report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// SuggestExpiredWriteOffs предлагает списание просроченных остатков
+// @Summary Предложение списания просроченного
+// @Description Формирует без проведения списания просроченных остатков — по одному на склад, с перечнем просроченных партий в деталях позиции
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param warehouse_id query string false "ID склада"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/expiry/write-off [get]
+func (h *WarehouseHandler) SuggestExpiredWriteOffs(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	warehouseID, err := parseExpiryWarehouseID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	writeOffs, err := h.usecase.SuggestExpiredWriteOffs(c.Request.Context(), estID, warehouseID, time.Now())
+	if err != nil {
+		h.logger.Error("Failed to suggest expired write-offs", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": writeOffs})
+}
+
+// WriteOffExpired проводит списание просроченных остатков
+// @Summary Списать просроченное
+// @Description Проводит предложенные списания просроченных остатков. Партии расходуются по FEFO, поэтому списываются именно просроченные партии.
+// @Tags warehouse
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param warehouse_id query string false "ID склада"
+// @Param request body object false "comment — комментарий к списанию"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /warehouse/expiry/write-off [post]
+func (h *WarehouseHandler) WriteOffExpired(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	warehouseID, err := parseExpiryWarehouseID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var req struct {
+		Comment string `json:"comment"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	writeOffs, err := h.usecase.WriteOffExpired(c.Request.Context(), estID, warehouseID, req.Comment, time.Now())
+	if err != nil {
+		h.logger.Error("Failed to write off expired stock", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": writeOffs})
+}
+
 // ——— Supply ———
 
 type SupplyItemRequest struct {
@@ -314,6 +504,32 @@ type SupplyItemRequest struct {
 	Unit         string   `json:"unit" binding:"required"`
 	PricePerUnit float64  `json:"price_per_unit"` // Цена за единицу измерения
 	TotalAmount  float64  `json:"total_amount"`   // Общая сумма позиции
+	ProductionDate *string `json:"production_date,omitempty"` // Дата производства (YYYY-MM-DD или RFC3339)
+	ExpiryDate     *string `json:"expiry_date,omitempty"`     // Годен до (YYYY-MM-DD или RFC3339)
+}
+
+// parseSupplyItemDates разбирает даты производства и срока годности позиции поставки
+func parseSupplyItemDates(it SupplyItemRequest) (production, expiry *time.Time, err error) {
+	parse := func(s *string, field string) (*time.Time, error) {
+		if s == nil || *s == "" {
+			return nil, nil
+		}
+		if t, err := time.Parse(time.RFC3339, *s); err == nil {
+			return &t, nil
+		}
+		t, err := time.Parse("2006-01-02", *s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s format, expected YYYY-MM-DD or RFC3339", field)
+		}
+		return &t, nil
+	}
+	if production, err = parse(it.ProductionDate, "production_date"); err != nil {
+		return nil, nil, err
+	}
+	if expiry, err = parse(it.ExpiryDate, "expiry_date"); err != nil {
+		return nil, nil, err
+	}
+	return production, expiry, nil
 }
 
 type CreateSupplyRequest struct {
@@ -344,15 +560,15 @@ type UpdateSupplyRequest struct {
 	InvoiceNumber   *string  `json:"invoice_number,omitempty"`
 	InvoiceDate     *string  `json:"invoice_date,omitempty"`
 	TotalAmount     *float64 `json:"total_amount,omitempty"`
-	PaymentStatus   *string  `json:"payment_status,omitempty"`
-	PaymentDate     *string  `json:"payment_date,omitempty"`
-	PaymentAmount   *float64 `json:"payment_amount,omitempty"`
+	PaymentStatus   *string  `json:"payment_status,omitempty"` // Только для чтения: оплата вносится платежом поставщику
+	PaymentDate     *string  `json:"payment_date,omitempty"`   // Только для чтения
+	PaymentAmount   *float64 `json:"payment_amount,omitempty"` // Только для чтения
 	AccountID       *string  `json:"account_id,omitempty" binding:"omitempty,uuid"`
 }
 
 // CreateSupply создает новую поставку
 // @Summary Создать поставку
-// @Description Создает новую поставку на склад
+// @Description Создает новую поставку на склад. Остатки, журнал движений и партии меняются только у проведенной поставки (status=completed, по умолчанию); поставка pending приходуется при переводе в completed
 // @Tags warehouse
 // @Accept json
 // @Produce json
@@ -414,6 +630,11 @@ func (h *WarehouseHandler) CreateSupply(c *gin.Context) {
 		} else if totalAmount == 0 && pricePerUnit > 0 && it.Quantity > 0 {
 			totalAmount = pricePerUnit * it.Quantity
 		}
+		productionDate, expiryDate, err := parseSupplyItemDates(it)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
 		item := models.SupplyItem{
 			ID:            uuid.New(), // Явно генерируем UUID
@@ -423,6 +644,8 @@ func (h *WarehouseHandler) CreateSupply(c *gin.Context) {
 			Unit:        it.Unit,
 			PricePerUnit: pricePerUnit,
 			TotalAmount:  totalAmount,
+			ProductionDate: productionDate,
+			ExpiryDate:     expiryDate,
 		}
 		items = append(items, item)
 	}
@@ -477,7 +700,7 @@ func (h *WarehouseHandler) CreateSupply(c *gin.Context) {
 
 // UpdateSupply обновляет поставку
 // @Summary Обновить поставку
-// @Description Обновляет данные поставки
+// @Description Обновляет данные поставки. Перевод pending → completed приходует остатки; у проведенной поставки нельзя менять статус, склад и позиции. Поля оплаты только для чтения (оплата — через /warehouse/supplier-payments); срок оплаты пересчитывается при изменении даты счета
 // @Tags warehouse
 // @Accept json
 // @Produce json
@@ -515,6 +738,11 @@ func (h *WarehouseHandler) UpdateSupply(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	// Оплата поставки меняется только платежами поставщику, иначе баланс разойдется с распределениями
+	if req.PaymentStatus != nil || req.PaymentAmount != nil || req.PaymentDate != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payment_status, payment_amount and payment_date are read-only; register a supplier payment instead"})
+		return
+	}
 
 	// Обновляем поля поставки
 	if req.WarehouseID != nil {
@@ -548,17 +776,6 @@ func (h *WarehouseHandler) UpdateSupply(c *gin.Context) {
 	if req.TotalAmount != nil {
 		existingSupply.TotalAmount = *req.TotalAmount
 	}
-	if req.PaymentStatus != nil {
-		existingSupply.PaymentStatus = *req.PaymentStatus
-	}
-	if req.PaymentDate != nil {
-		if t, err := time.Parse(time.RFC3339, *req.PaymentDate); err == nil {
-			existingSupply.PaymentDate = &t
-		}
-	}
-	if req.PaymentAmount != nil {
-		existingSupply.PaymentAmount = *req.PaymentAmount
-	}
 	if req.AccountID != nil && *req.AccountID != "" {
 		if id, err := uuid.Parse(*req.AccountID); err == nil {
 			existingSupply.AccountID = &id
@@ -592,6 +809,11 @@ func (h *WarehouseHandler) UpdateSupply(c *gin.Context) {
 			} else if totalAmount == 0 && pricePerUnit > 0 && it.Quantity > 0 {
 				totalAmount = pricePerUnit * it.Quantity
 			}
+			productionDate, expiryDate, err := parseSupplyItemDates(it)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
 
 			item := models.SupplyItem{
 				ID:            uuid.New(),
@@ -601,6 +823,8 @@ func (h *WarehouseHandler) UpdateSupply(c *gin.Context) {
 				Unit:        it.Unit,
 				PricePerUnit: pricePerUnit,
 				TotalAmount:  totalAmount,
+				ProductionDate: productionDate,
+				ExpiryDate:     expiryDate,
 			}
 			items = append(items, item)
 		}
@@ -894,16 +1118,61 @@ func (h *WarehouseHandler) GetSuppliesByItem(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": supplies})
 }
 
+// parseStockLedgerFilter разбирает фильтр журнала остатков из query-параметров
+func parseStockLedgerFilter(c *gin.Context) (*repositories.StockLedgerFilter, error) {
+	filter := &repositories.StockLedgerFilter{}
+	ids := map[string]**uuid.UUID{
+		"warehouse_id":     &filter.WarehouseID,
+		"ingredient_id":    &filter.IngredientID,
+		"product_id":       &filter.ProductID,
+		"semi_finished_id": &filter.SemiFinishedID,
+		"source_id":        &filter.SourceID,
+	}
+	for param, target := range ids {
+		if s := c.Query(param); s != "" {
+			id, err := uuid.Parse(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s", param)
+			}
+			*target = &id
+		}
+	}
+	if s := c.Query("movement_type"); s != "" {
+		filter.MovementType = &s
+	}
+	if s := c.Query("start_date"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, errors.New("invalid start_date format, use RFC3339")
+		}
+		filter.StartDate = &t
+	}
+	if s := c.Query("end_date"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, errors.New("invalid end_date format, use RFC3339")
+		}
+		filter.EndDate = &t
+	}
+	return filter, nil
+}
+
 // GetMovements возвращает движения по складу
 // @Summary Получить движения по складу
-// @Description Возвращает историю движений по складу
+// @Description Возвращает записи журнала остатков: поставки, продажи, списания, перемещения, производство и корректировки инвентаризации. Каждая запись содержит документ-источник, изменение количества, себестоимость и остаток после движения.
 // @Tags warehouse
 // @Produce json
 // @Security Bearer
 // @Param warehouse_id query string false "ID склада"
-// @Param start_date query string false "Начальная дата"
-// @Param end_date query string false "Конечная дата"
+// @Param ingredient_id query string false "ID ингредиента"
+// @Param product_id query string false "ID товара"
+// @Param semi_finished_id query string false "ID полуфабриката"
+// @Param source_id query string false "ID документа-источника"
+// @Param movement_type query string false "Тип движения (opening, supply, sale, write_off, transfer_out, transfer_in, production_out, production_in, inventory)"
+// @Param start_date query string false "Начальная дата (RFC3339)"
+// @Param end_date query string false "Конечная дата (RFC3339)"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /warehouse/movements [get]
@@ -913,21 +1182,460 @@ func (h *WarehouseHandler) GetMovements(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
+	filter, err := parseStockLedgerFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	list, err := h.usecase.GetMovements(c.Request.Context(), estID, filter)
+	if err != nil {
+		h.logger.Error("Failed to get movements", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get movements"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// GetMovementReport возвращает отчет по движению позиций за период
+// @Summary Отчет по движению остатков
+// @Description По каждой позиции склада: остаток на начало периода, приход, расход с разбивкой по типам движений и остаток на конец. Строится по журналу остатков.
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param warehouse_id query string false "ID склада"
+// @Param ingredient_id query string false "ID ингредиента"
+// @Param product_id query string false "ID товара"
+// @Param semi_finished_id query string false "ID полуфабриката"
+// @Param start_date query string false "Начало периода (RFC3339)"
+// @Param end_date query string false "Конец периода (RFC3339), по умолчанию — текущий момент"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/movements/report [get]
+func (h *WarehouseHandler) GetMovementReport(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	filter, err := parseStockLedgerFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Остаток на конец считается по всем движениям, поэтому фильтр по типу и документу не применяется
+	filter.MovementType = nil
+	filter.SourceID = nil
+
+	report, err := h.usecase.GetStockMovementReport(c.Request.Context(), estID, *filter)
+	if err != nil {
+		h.logger.Error("Failed to get movement report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// GetStockAsOf возвращает остатки на указанный момент
+// @Summary Остатки на момент времени
+// @Description Восстанавливает остатки складов на указанный момент по журналу остатков
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param warehouse_id query string false "ID склада"
+// @Param as_of query string true "Момент времени (RFC3339)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/stock/as-of [get]
+func (h *WarehouseHandler) GetStockAsOf(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	asOf, err := time.Parse(time.RFC3339, c.Query("as_of"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "as_of is required in RFC3339 format"})
+		return
+	}
 	var warehouseID *uuid.UUID
 	if s := c.Query("warehouse_id"); s != "" {
 		if id, e := uuid.Parse(s); e == nil {
 			warehouseID = &id
 		}
 	}
-	list, err := h.usecase.GetMovements(c.Request.Context(), estID, warehouseID)
+
+	list, err := h.usecase.GetStockAsOf(c.Request.Context(), estID, warehouseID, asOf)
 	if err != nil {
-		h.logger.Error("Failed to get movements", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get movements"})
+		h.logger.Error("Failed to get stock as of date", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": list})
 }
 
+// ——— Transfer ———
+
+// TransferItemRequest представляет позицию перемещения
+type TransferItemRequest struct {
+	IngredientID *string `json:"ingredient_id,omitempty" binding:"omitempty,uuid"` // ID ингредиента (обязательно, если не указан product_id)
+	ProductID    *string `json:"product_id,omitempty" binding:"omitempty,uuid"`    // ID товара (обязательно, если не указан ingredient_id)
+	Quantity     float64 `json:"quantity" binding:"required,gt=0"`                 // Количество для перемещения
+	Unit         string  `json:"unit" binding:"required"`                          // Единица измерения
+}
+
+// CreateTransferRequest представляет запрос на создание перемещения
+type CreateTransferRequest struct {
+	SourceWarehouseID string                `json:"source_warehouse_id" binding:"required,uuid"` // Склад-отправитель
+	TargetWarehouseID string                `json:"target_warehouse_id" binding:"required,uuid"` // Склад-получатель
+	TransferDateTime  string                `json:"transfer_date_time" binding:"required"`       // Дата и время перемещения (RFC3339)
+	Status            string                `json:"status" binding:"omitempty,oneof=draft sent received"`
+	Comment           string                `json:"comment"`
+	Items             []TransferItemRequest `json:"items" binding:"required,min=1"`
+}
+
+// UpdateTransferStatusRequest представляет запрос на смену статуса перемещения
+type UpdateTransferStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=sent received"`
+}
+
+// CreateTransfer создает перемещение между складами
+// @Summary Создать перемещение
+// @Description Создает перемещение товаров/ингредиентов между складами. По умолчанию документ создается в статусе draft. При статусе sent остатки списываются со склада-отправителя, при received — приходуются на склад-получатель по себестоимости склада-отправителя.
+// @Tags warehouse
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body CreateTransferRequest true "Данные перемещения"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/transfers [post]
+func (h *WarehouseHandler) CreateTransfer(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	var req CreateTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sourceID, _ := uuid.Parse(req.SourceWarehouseID)
+	targetID, _ := uuid.Parse(req.TargetWarehouseID)
+
+	transferDateTime, err := time.Parse(time.RFC3339, req.TransferDateTime)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transfer_date_time format, expected RFC3339"})
+		return
+	}
+
+	items := make([]models.TransferItem, 0, len(req.Items))
+	for _, it := range req.Items {
+		var ingID, prodID *uuid.UUID
+		if it.IngredientID != nil && *it.IngredientID != "" {
+			if id, e := uuid.Parse(*it.IngredientID); e == nil {
+				ingID = &id
+			}
+		}
+		if it.ProductID != nil && *it.ProductID != "" {
+			if id, e := uuid.Parse(*it.ProductID); e == nil {
+				prodID = &id
+			}
+		}
+		if ingID == nil && prodID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "each item must have ingredient_id or product_id"})
+			return
+		}
+		items = append(items, models.TransferItem{
+			ID:           uuid.New(), // Явно генерируем UUID
+			IngredientID: ingID,
+			ProductID:    prodID,
+			Quantity:     it.Quantity,
+			Unit:         it.Unit,
+		})
+	}
+
+	transfer := &models.Transfer{
+		SourceWarehouseID: sourceID,
+		TargetWarehouseID: targetID,
+		TransferDateTime:  transferDateTime,
+		Status:            models.TransferStatus(req.Status),
+		Comment:           req.Comment,
+		Items:             items,
+	}
+	if err := h.usecase.CreateTransfer(c.Request.Context(), transfer, estID); err != nil {
+		h.logger.Error("Failed to create transfer", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := h.usecase.GetTransfer(c.Request.Context(), transfer.ID, estID)
+	if err != nil || created == nil {
+		c.JSON(http.StatusCreated, gin.H{"data": transfer})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": created})
+}
+
+// ListTransfers возвращает список перемещений
+// @Summary Получить список перемещений
+// @Description Возвращает список перемещений; при указании склада — те, где он отправитель или получатель
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param warehouse_id query string false "ID склада"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/transfers [get]
+func (h *WarehouseHandler) ListTransfers(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	var warehouseID *uuid.UUID
+	if s := c.Query("warehouse_id"); s != "" {
+		if id, e := uuid.Parse(s); e == nil {
+			warehouseID = &id
+		}
+	}
+
+	list, err := h.usecase.GetTransfers(c.Request.Context(), estID, warehouseID)
+	if err != nil {
+		h.logger.Error("Failed to list transfers", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list transfers"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// GetTransfer возвращает перемещение по ID
+// @Summary Получить перемещение по ID
+// @Description Возвращает перемещение по ID
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID перемещения"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /warehouse/transfers/{id} [get]
+func (h *WarehouseHandler) GetTransfer(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	transfer, err := h.usecase.GetTransfer(c.Request.Context(), id, estID)
+	if err != nil {
+		h.logger.Error("Failed to get transfer", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get transfer"})
+		return
+	}
+	if transfer == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "transfer not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": transfer})
+}
+
+// UpdateTransferStatus меняет статус перемещения
+// @Summary Изменить статус перемещения
+// @Description Переводит перемещение в статус sent (списание со склада-отправителя) или received (оприходование на складе-получателе)
+// @Tags warehouse
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID перемещения"
+// @Param request body UpdateTransferStatusRequest true "Новый статус"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/transfers/{id}/status [put]
+func (h *WarehouseHandler) UpdateTransferStatus(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req UpdateTransferStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.usecase.UpdateTransferStatus(c.Request.Context(), id, models.TransferStatus(req.Status), estID); err != nil {
+		h.logger.Error("Failed to update transfer status", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	transfer, err := h.usecase.GetTransfer(c.Request.Context(), id, estID)
+	if err != nil {
+		h.logger.Error("Failed to get transfer", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get transfer"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": transfer})
+}
+
+// ——— Production ———
+
+// CreateProductionRequest представляет запрос на производство полуфабриката
+type CreateProductionRequest struct {
+	WarehouseID        string  `json:"warehouse_id" binding:"required,uuid"`     // Склад, с которого списываются ингредиенты и на который приходуется выход
+	SemiFinishedID     string  `json:"semi_finished_id" binding:"required,uuid"` // Полуфабрикат
+	Quantity           float64 `json:"quantity" binding:"required,gt=0"`         // Выход
+	Unit               string  `json:"unit"`                                     // Единица выхода (по умолчанию — единица полуфабриката)
+	ProductionDateTime string  `json:"production_date_time"`                     // Дата и время производства (RFC3339), по умолчанию — сейчас
+	Comment            string  `json:"comment"`
+}
+
+// CreateProduction проводит производство полуфабриката
+// @Summary Произвести полуфабрикат
+// @Description Списывает ингредиенты по рецептуре полуфабриката пропорционально выходу и приходует полуфабрикат на склад по фактической себестоимости ингредиентов
+// @Tags warehouse
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body CreateProductionRequest true "Данные производства"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/productions [post]
+func (h *WarehouseHandler) CreateProduction(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	var req CreateProductionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	warehouseID, _ := uuid.Parse(req.WarehouseID)
+	semiFinishedID, _ := uuid.Parse(req.SemiFinishedID)
+
+	production := &models.Production{
+		WarehouseID:    warehouseID,
+		SemiFinishedID: semiFinishedID,
+		Quantity:       req.Quantity,
+		Unit:           req.Unit,
+		Comment:        req.Comment,
+	}
+	if req.ProductionDateTime != "" {
+		productionDateTime, err := time.Parse(time.RFC3339, req.ProductionDateTime)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid production_date_time format, expected RFC3339"})
+			return
+		}
+		production.ProductionDateTime = productionDateTime
+	}
+
+	if err := h.usecase.CreateProduction(c.Request.Context(), production, estID); err != nil {
+		h.logger.Error("Failed to create production", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := h.usecase.GetProduction(c.Request.Context(), production.ID, estID)
+	if err != nil || created == nil {
+		c.JSON(http.StatusCreated, gin.H{"data": production})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": created})
+}
+
+// ListProductions возвращает список производств
+// @Summary Получить список производств полуфабрикатов
+// @Description Возвращает список производств полуфабрикатов, опционально по складу
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param warehouse_id query string false "ID склада"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /warehouse/productions [get]
+func (h *WarehouseHandler) ListProductions(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	var warehouseID *uuid.UUID
+	if s := c.Query("warehouse_id"); s != "" {
+		if id, e := uuid.Parse(s); e == nil {
+			warehouseID = &id
+		}
+	}
+
+	list, err := h.usecase.GetProductions(c.Request.Context(), estID, warehouseID)
+	if err != nil {
+		h.logger.Error("Failed to list productions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list productions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// GetProduction возвращает производство по ID
+// @Summary Получить производство по ID
+// @Description Возвращает производство полуфабриката с израсходованными ингредиентами
+// @Tags warehouse
+// @Produce json
+// @Security Bearer
+// @Param id path string true "ID производства"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /warehouse/productions/{id} [get]
+func (h *WarehouseHandler) GetProduction(c *gin.Context) {
+	estID, err := getEstablishmentID(c)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	production, err := h.usecase.GetProduction(c.Request.Context(), id, estID)
+	if err != nil {
+		h.logger.Error("Failed to get production", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get production"})
+		return
+	}
+	if production == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "production not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": production})
+}
+
 // ——— Suppliers ———
 
 type CreateSupplierRequest struct {
@@ -938,6 +1646,8 @@ type CreateSupplierRequest struct {
 	Comment        string `json:"comment"`                           // Комментарий
 	Contact        string `json:"contact"`                           // Контактное лицо (опционально)
 	Email          string `json:"email"`                             // Email (опционально)
+	LeadTimeDays   *int   `json:"lead_time_days,omitempty" binding:"omitempty,gte=0"` // Срок поставки в днях (по умолчанию 1)
+	PaymentTermDays *int  `json:"payment_term_days,omitempty" binding:"omitempty,gte=0"` // Отсрочка платежа в днях (по умолчанию 0)
 }
 
 type UpdateSupplierRequest struct {
@@ -948,6 +1658,8 @@ type UpdateSupplierRequest struct {
 	Comment        *string `json:"comment,omitempty"`
 	Contact        *string `json:"contact,omitempty"`
 	Email          *string `json:"email,omitempty"`
+	LeadTimeDays   *int    `json:"lead_time_days,omitempty" binding:"omitempty,gte=0"`
+	PaymentTermDays *int   `json:"payment_term_days,omitempty" binding:"omitempty,gte=0"`
 	Active         *bool   `json:"active,omitempty"`
 }
 
@@ -1047,8 +1759,15 @@ func (h *WarehouseHandler) CreateSupplier(c *gin.Context) {
 		Comment:        req.Comment,
 		Contact:        req.Contact,
 		Email:          req.Email,
+		LeadTimeDays:   1,
 		Active:         true,
 	}
+	if req.LeadTimeDays != nil {
+		s.LeadTimeDays = *req.LeadTimeDays
+	}
+	if req.PaymentTermDays != nil {
+		s.PaymentTermDays = *req.PaymentTermDays
+	}
 	if err := h.usecase.CreateSupplier(c.Request.Context(), s, estID); err != nil {
 		h.logger.Error("Failed to create supplier", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create supplier"})
@@ -1114,6 +1833,12 @@ func (h *WarehouseHandler) UpdateSupplier(c *gin.Context) {
 	if req.Email != nil {
 		s.Email = *req.Email
 	}
+	if req.LeadTimeDays != nil {
+		s.LeadTimeDays = *req.LeadTimeDays
+	}
+	if req.PaymentTermDays != nil {
+		s.PaymentTermDays = *req.PaymentTermDays
+	}
 	if req.Active != nil {
 		s.Active = *req.Active
 	}
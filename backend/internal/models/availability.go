@@ -0,0 +1,196 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AvailabilitySchedule расписание доступности позиций меню (например, "Завтраки" или "Бизнес-ланч").
+// Назначается категории, товару или тех-карте; расписание позиции важнее расписания ее категории.
+// Время задается в часовом поясе заведения (Establishment.Timezone).
+type AvailabilitySchedule struct {
+	ID              uuid.UUID          `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EstablishmentID uuid.UUID          `json:"establishment_id" gorm:"type:uuid;not null;index"`
+	Name            string             `json:"name" gorm:"not null"`
+	Active          bool               `json:"active" gorm:"default:true"` // Неактивное расписание не ограничивает продажу
+	Rules           []AvailabilityRule `json:"rules" gorm:"foreignKey:ScheduleID;constraint:OnDelete:CASCADE"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt     `json:"-" gorm:"index"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID
+func (s *AvailabilitySchedule) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// AvailabilityRule интервал доступности: дни недели и время с StartTime до EndTime.
+// Если EndTime не позже StartTime, интервал переходит через полночь и относится ко дню начала.
+type AvailabilityRule struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ScheduleID uuid.UUID `json:"schedule_id" gorm:"type:uuid;not null;index"`
+	Weekdays   string    `json:"weekdays" gorm:"type:varchar(20);not null"`  // Дни недели через запятую: 1 — понедельник … 7 — воскресенье
+	StartTime  string    `json:"start_time" gorm:"type:varchar(5);not null"` // ЧЧ:ММ
+	EndTime    string    `json:"end_time" gorm:"type:varchar(5);not null"`   // ЧЧ:ММ, 24:00 — до конца дня
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID
+func (r *AvailabilityRule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// ParseWeekdays разбирает дни недели через запятую (1 — понедельник … 7 — воскресенье)
+// и возвращает их отсортированными без повторов
+func ParseWeekdays(value string) ([]int, error) {
+	seen := make(map[int]bool)
+	days := make([]int, 0, 7)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		day, err := strconv.Atoi(part)
+		if err != nil || day < 1 || day > 7 {
+			return nil, fmt.Errorf("invalid weekday %q, expected 1 (Monday) to 7 (Sunday)", part)
+		}
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	if len(days) == 0 {
+		return nil, fmt.Errorf("at least one weekday is required")
+	}
+	sort.Ints(days)
+	return days, nil
+}
+
+// parseClock переводит ЧЧ:ММ в минуты от начала суток (допускается 24:00)
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err == nil {
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+	return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+}
+
+// Normalize проверяет правило и приводит дни недели к каноническому виду
+func (r *AvailabilityRule) Normalize() error {
+	days, err := ParseWeekdays(r.Weekdays)
+	if err != nil {
+		return err
+	}
+	parts := make([]string, len(days))
+	for i, d := range days {
+		parts[i] = strconv.Itoa(d)
+	}
+	r.Weekdays = strings.Join(parts, ",")
+
+	start, err := parseClock(r.StartTime)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(r.EndTime)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return fmt.Errorf("start time and end time must differ")
+	}
+	if start == 24*60 {
+		return fmt.Errorf("start time must be before 24:00")
+	}
+	return nil
+}
+
+// isoWeekday возвращает день недели: 1 — понедельник … 7 — воскресенье
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+// hasWeekday проверяет, входит ли день недели в правило
+func (r *AvailabilityRule) hasWeekday(day int) bool {
+	days, err := ParseWeekdays(r.Weekdays)
+	if err != nil {
+		return false
+	}
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains проверяет, попадает ли момент t (в часовом поясе заведения) в интервал правила
+func (r *AvailabilityRule) Contains(t time.Time) bool {
+	start, err := parseClock(r.StartTime)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(r.EndTime)
+	if err != nil {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	day := isoWeekday(t)
+
+	if start < end {
+		return r.hasWeekday(day) && minute >= start && minute < end
+	}
+	// Интервал через полночь: вечер дня начала или утро следующего дня
+	if minute >= start && r.hasWeekday(day) {
+		return true
+	}
+	previous := day - 1
+	if previous == 0 {
+		previous = 7
+	}
+	return minute < end && r.hasWeekday(previous)
+}
+
+// IsAvailableAt проверяет, разрешает ли расписание продажу в момент t (в часовом поясе заведения).
+// Неактивное расписание и расписание без правил не ограничивают продажу.
+func (s *AvailabilitySchedule) IsAvailableAt(t time.Time) bool {
+	if !s.Active || len(s.Rules) == 0 {
+		return true
+	}
+	for i := range s.Rules {
+		if s.Rules[i].Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadEstablishmentLocation возвращает часовой пояс заведения; пустой или неизвестный пояс — локальное время сервера
+func LoadEstablishmentLocation(timezone string) *time.Location {
+	if timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
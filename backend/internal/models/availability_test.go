@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvailabilityRuleNormalize(t *testing.T) {
+	rule := &AvailabilityRule{Weekdays: "5, 1,3,1", StartTime: "12:00", EndTime: "16:00"}
+	require.NoError(t, rule.Normalize())
+	assert.Equal(t, "1,3,5", rule.Weekdays)
+
+	assert.Error(t, (&AvailabilityRule{Weekdays: "0", StartTime: "12:00", EndTime: "16:00"}).Normalize())
+	assert.Error(t, (&AvailabilityRule{Weekdays: "1", StartTime: "12:00", EndTime: "12:00"}).Normalize())
+	assert.Error(t, (&AvailabilityRule{Weekdays: "1", StartTime: "25:00", EndTime: "12:00"}).Normalize())
+	assert.NoError(t, (&AvailabilityRule{Weekdays: "1", StartTime: "08:00", EndTime: "24:00"}).Normalize())
+}
+
+func TestAvailabilityScheduleIsAvailableAt(t *testing.T) {
+	// 2024-01-01 — понедельник
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	lunch := &AvailabilitySchedule{Active: true, Rules: []AvailabilityRule{
+		{Weekdays: "1,2,3,4,5", StartTime: "12:00", EndTime: "16:00"},
+	}}
+	assert.True(t, lunch.IsAvailableAt(at(1, 12, 0)))
+	assert.True(t, lunch.IsAvailableAt(at(5, 15, 59)))
+	assert.False(t, lunch.IsAvailableAt(at(1, 16, 0)))
+	assert.False(t, lunch.IsAvailableAt(at(6, 13, 0))) // суббота
+
+	// Ночное меню пятницы и субботы: 22:00–02:00
+	night := &AvailabilitySchedule{Active: true, Rules: []AvailabilityRule{
+		{Weekdays: "5,6", StartTime: "22:00", EndTime: "02:00"},
+	}}
+	assert.True(t, night.IsAvailableAt(at(5, 23, 0)))
+	assert.True(t, night.IsAvailableAt(at(6, 1, 30)))  // ночь с пятницы на субботу
+	assert.True(t, night.IsAvailableAt(at(7, 1, 30)))  // ночь с субботы на воскресенье
+	assert.False(t, night.IsAvailableAt(at(8, 1, 30))) // ночь с воскресенья на понедельник
+	assert.False(t, night.IsAvailableAt(at(5, 21, 0)))
+
+	night.Active = false
+	assert.True(t, night.IsAvailableAt(at(5, 21, 0)))
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ItemBarcode штрихкод позиции. У позиции может быть несколько штрихкодов — например,
+// штучный и на упаковку; Multiplier задает, сколько единиц позиции содержит одно сканирование.
+//
+// Весовой штрихкод (Weighted) — это EAN-13 с префиксом 20–29: Code хранит первые 7 цифр
+// (префикс и код товара), следующие 5 цифр кодируют вес в граммах.
+type ItemBarcode struct {
+	ID              uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EstablishmentID uuid.UUID   `json:"establishment_id" gorm:"type:uuid;not null;uniqueIndex:idx_item_barcode_code"`
+	Code            string      `json:"code" gorm:"type:varchar(32);not null;uniqueIndex:idx_item_barcode_code"`
+	IngredientID    *uuid.UUID  `json:"ingredient_id,omitempty" gorm:"type:uuid;index"`
+	Ingredient      *Ingredient `json:"ingredient,omitempty" gorm:"foreignKey:IngredientID"`
+	ProductID       *uuid.UUID  `json:"product_id,omitempty" gorm:"type:uuid;index"`
+	Product         *Product    `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	TechCardID      *uuid.UUID  `json:"tech_card_id,omitempty" gorm:"type:uuid;index"`
+	TechCard        *TechCard   `json:"tech_card,omitempty" gorm:"foreignKey:TechCardID"`
+	Multiplier      float64     `json:"multiplier" gorm:"not null;default:1"` // Количество позиции в одном сканировании
+	Unit            string      `json:"unit"`                                 // Единица Multiplier (пусто — основная единица позиции)
+	Weighted        bool        `json:"weighted" gorm:"default:false"`        // Весовой EAN-13
+	Comment         string      `json:"comment"`                              // Например, «Коробка 12 шт»
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID
+func (b *ItemBarcode) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	if b.Multiplier <= 0 {
+		b.Multiplier = 1
+	}
+	return nil
+}
+
+// WeightedBarcodeCodeLength длина кода весового товара (префикс и код) в весовом EAN-13
+const WeightedBarcodeCodeLength = 7
+
+// EAN13CheckDigit вычисляет контрольную цифру по первым 12 цифрам EAN-13
+func EAN13CheckDigit(digits string) (int, bool) {
+	if len(digits) != 12 || !isDigits(digits) {
+		return 0, false
+	}
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(digits[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10, true
+}
+
+// ValidEAN13 проверяет длину и контрольную цифру EAN-13
+func ValidEAN13(code string) bool {
+	if len(code) != 13 {
+		return false
+	}
+	check, ok := EAN13CheckDigit(code[:12])
+	return ok && int(code[12]-'0') == check
+}
+
+// IsWeightedBarcodePrefix сообщает, что код начинается с префикса внутреннего (весового) штрихкода 20–29
+func IsWeightedBarcodePrefix(code string) bool {
+	return len(code) >= 2 && code[0] == '2' && code[1] >= '0' && code[1] <= '9'
+}
+
+// ParseWeightedEAN13 разбирает весовой EAN-13 на код товара (первые 7 цифр) и вес в граммах
+func ParseWeightedEAN13(code string) (itemCode string, grams int, ok bool) {
+	if !ValidEAN13(code) || !IsWeightedBarcodePrefix(code) {
+		return "", 0, false
+	}
+	grams, err := strconv.Atoi(code[WeightedBarcodeCodeLength:12])
+	if err != nil {
+		return "", 0, false
+	}
+	return code[:WeightedBarcodeCodeLength], grams, true
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidEAN13(t *testing.T) {
+	assert.True(t, ValidEAN13("4006381333931"))
+	assert.False(t, ValidEAN13("4006381333932"))
+	assert.False(t, ValidEAN13("400638133393"))
+	assert.False(t, ValidEAN13("40063813339a1"))
+}
+
+func TestParseWeightedEAN13(t *testing.T) {
+	// 21 — весовой префикс, 00123 — код товара, 01250 — 1250 г
+	code, grams, ok := ParseWeightedEAN13("2100123012503")
+	assert.True(t, ok)
+	assert.Equal(t, "2100123", code)
+	assert.Equal(t, 1250, grams)
+
+	_, _, ok = ParseWeightedEAN13("4006381333931")
+	assert.False(t, ok, "not a weighted prefix")
+
+	_, _, ok = ParseWeightedEAN13("2100123012504")
+	assert.False(t, ok, "wrong check digit")
+}
@@ -14,6 +14,9 @@ type Category struct {
 	Establishment   *Establishment `json:"establishment,omitempty" gorm:"foreignKey:EstablishmentID"`
 	Name            string         `json:"name" gorm:"not null"`
 	Type            string         `json:"type" gorm:"not null"` // product, tech_card, semi_finished
+	// Расписание доступности позиций категории (например, завтраки до 12:00)
+	AvailabilityScheduleID *uuid.UUID            `json:"availability_schedule_id,omitempty" gorm:"type:uuid;index"`
+	AvailabilitySchedule   *AvailabilitySchedule `json:"availability_schedule,omitempty" gorm:"foreignKey:AvailabilityScheduleID"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
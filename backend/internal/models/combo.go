@@ -0,0 +1,175 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Combo комбо или сет-меню: набор групп компонентов за фиксированную цену (например, бургер + гарнир + напиток).
+// Гость выбирает по одной позиции из каждой группы; выбор некоторых позиций может стоить доплату
+type Combo struct {
+	ID              uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EstablishmentID uuid.UUID    `json:"establishment_id" gorm:"type:uuid;not null;index"`
+	CategoryID      uuid.UUID    `json:"category_id" gorm:"type:uuid;not null;index"`
+	Category        *Category    `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+	Name            string       `json:"name" gorm:"not null"`
+	Description     string       `json:"description"`
+	CoverImage      string       `json:"cover_image"`
+	Price           float64      `json:"price" gorm:"not null"` // Базовая цена комбо без доплат
+	Groups          []ComboGroup `json:"groups" gorm:"foreignKey:ComboID;constraint:OnDelete:CASCADE"`
+
+	// Расписание доступности (если не задано — действует расписание категории)
+	AvailabilityScheduleID *uuid.UUID `json:"availability_schedule_id,omitempty" gorm:"type:uuid;index"`
+
+	Active    bool           `json:"active" gorm:"default:true;index"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID и округления цены
+func (c *Combo) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	c.Price = RoundTo2(c.Price)
+	return nil
+}
+
+// BeforeUpdate hook для округления цены
+func (c *Combo) BeforeUpdate(tx *gorm.DB) error {
+	c.Price = RoundTo2(c.Price)
+	return nil
+}
+
+// ComboGroup группа компонентов комбо (например, "Гарнир" или "Напиток"), из которой выбирается одна позиция
+type ComboGroup struct {
+	ID        uuid.UUID     `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ComboID   uuid.UUID     `json:"combo_id" gorm:"type:uuid;not null;index"`
+	Name      string        `json:"name" gorm:"not null"`
+	Required  bool          `json:"required" gorm:"default:true"` // Необязательную группу гость может пропустить
+	SortOrder int           `json:"sort_order" gorm:"default:0"`
+	Options   []ComboOption `json:"options" gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID
+func (g *ComboGroup) BeforeCreate(tx *gorm.DB) error {
+	if g.ID == uuid.Nil {
+		g.ID = uuid.New()
+	}
+	return nil
+}
+
+// DefaultOption возвращает позицию группы по умолчанию или nil
+func (g *ComboGroup) DefaultOption() *ComboOption {
+	for i := range g.Options {
+		if g.Options[i].IsDefault {
+			return &g.Options[i]
+		}
+	}
+	return nil
+}
+
+// FindOption возвращает позицию группы с указанным товаром или тех-картой или nil
+func (g *ComboGroup) FindOption(productID, techCardID *uuid.UUID) *ComboOption {
+	for i := range g.Options {
+		o := &g.Options[i]
+		if productID != nil && o.ProductID != nil && *o.ProductID == *productID {
+			return o
+		}
+		if techCardID != nil && o.TechCardID != nil && *o.TechCardID == *techCardID {
+			return o
+		}
+	}
+	return nil
+}
+
+// ComboOption позиция, доступная для выбора в группе комбо: товар или тех-карта с необязательной доплатой
+type ComboOption struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	GroupID    uuid.UUID  `json:"group_id" gorm:"type:uuid;not null;index"`
+	ProductID  *uuid.UUID `json:"product_id,omitempty" gorm:"type:uuid;index"`
+	Product    *Product   `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	TechCardID *uuid.UUID `json:"tech_card_id,omitempty" gorm:"type:uuid;index"`
+	TechCard   *TechCard  `json:"tech_card,omitempty" gorm:"foreignKey:TechCardID"`
+	Surcharge  float64    `json:"surcharge" gorm:"default:0"` // Доплата за выбор позиции
+	IsDefault  bool       `json:"is_default" gorm:"default:false"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID и округления доплаты
+func (o *ComboOption) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	o.Surcharge = RoundTo2(o.Surcharge)
+	return nil
+}
+
+// OrderItemComponent компонент комбо в позиции заказа: выбранный товар или тех-карта.
+// AllocatedPrice — доля цены одного комбо, отнесенная на компонент (для статистики по категориям)
+type OrderItemComponent struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	OrderItemID    uuid.UUID  `json:"order_item_id" gorm:"type:uuid;not null;index"`
+	GroupID        uuid.UUID  `json:"group_id" gorm:"type:uuid;not null"`
+	GroupName      string     `json:"group_name"`
+	ProductID      *uuid.UUID `json:"product_id,omitempty" gorm:"type:uuid;index"`
+	Product        *Product   `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	TechCardID     *uuid.UUID `json:"tech_card_id,omitempty" gorm:"type:uuid;index"`
+	TechCard       *TechCard  `json:"tech_card,omitempty" gorm:"foreignKey:TechCardID"`
+	ListPrice      float64    `json:"list_price"`      // Цена компонента в меню на момент заказа
+	Surcharge      float64    `json:"surcharge"`       // Доплата за выбор компонента
+	AllocatedPrice float64    `json:"allocated_price"` // Доля цены одного комбо
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID и округления значений
+func (c *OrderItemComponent) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	c.ListPrice = RoundTo2(c.ListPrice)
+	c.Surcharge = RoundTo2(c.Surcharge)
+	c.AllocatedPrice = RoundTo2(c.AllocatedPrice)
+	return nil
+}
+
+// CategoryID возвращает категорию компонента (товар и тех-карта должны быть загружены)
+func (c *OrderItemComponent) CategoryID() (uuid.UUID, bool) {
+	switch {
+	case c.Product != nil:
+		return c.Product.CategoryID, true
+	case c.TechCard != nil:
+		return c.TechCard.CategoryID, true
+	}
+	return uuid.Nil, false
+}
+
+// AllocateComboPrice распределяет базовую цену комбо по компонентам пропорционально их ценам в меню
+// и добавляет каждому компоненту его доплату. Если цены в меню не заданы, база делится поровну.
+// Остаток от округления относится на последний компонент, так что сумма долей равна цене комбо
+func AllocateComboPrice(basePrice float64, components []OrderItemComponent) {
+	if len(components) == 0 {
+		return
+	}
+	totalList := 0.0
+	for _, c := range components {
+		totalList += c.ListPrice
+	}
+	allocated := 0.0
+	for i := range components {
+		var share float64
+		if i == len(components)-1 {
+			share = RoundTo2(basePrice - allocated)
+		} else if totalList > 0 {
+			share = RoundTo2(basePrice * components[i].ListPrice / totalList)
+		} else {
+			share = RoundTo2(basePrice / float64(len(components)))
+		}
+		allocated += share
+		components[i].AllocatedPrice = RoundTo2(share + components[i].Surcharge)
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocateComboPrice(t *testing.T) {
+	components := []OrderItemComponent{
+		{ListPrice: 300},                // Бургер
+		{ListPrice: 150},                // Картофель
+		{ListPrice: 150, Surcharge: 50}, // Большой напиток
+	}
+	AllocateComboPrice(450, components)
+
+	assert.Equal(t, 225.0, components[0].AllocatedPrice)
+	assert.Equal(t, 112.5, components[1].AllocatedPrice)
+	assert.Equal(t, 162.5, components[2].AllocatedPrice)
+
+	total := 0.0
+	for _, c := range components {
+		total += c.AllocatedPrice
+	}
+	assert.InDelta(t, 500.0, total, 0.001)
+}
+
+func TestAllocateComboPriceRounding(t *testing.T) {
+	components := []OrderItemComponent{{}, {}, {}}
+	AllocateComboPrice(100, components)
+
+	assert.Equal(t, 33.33, components[0].AllocatedPrice)
+	assert.Equal(t, 33.33, components[1].AllocatedPrice)
+	assert.Equal(t, 33.34, components[2].AllocatedPrice)
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Причины записи себестоимости в историю
+const (
+	CostChangeReasonManual        = "manual"        // Тех-карта или товар изменены вручную
+	CostChangeReasonSupply        = "supply"        // Поставка изменила закупочные цены ингредиентов
+	CostChangeReasonRecalculation = "recalculation" // Ручной пересчет себестоимости всего меню
+	CostChangeReasonRepricing     = "repricing"     // Цена изменена по правилу переоценки
+)
+
+// DefaultFoodCostThreshold порог фудкоста по умолчанию, %
+const DefaultFoodCostThreshold = 35
+
+// MenuItemCost запись истории себестоимости тех-карты или товара.
+// Новая запись добавляется только при изменении себестоимости или цены позиции.
+type MenuItemCost struct {
+	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EstablishmentID uuid.UUID  `json:"establishment_id" gorm:"type:uuid;not null;index"`
+	TechCardID      *uuid.UUID `json:"tech_card_id,omitempty" gorm:"type:uuid;index"`
+	TechCard        *TechCard  `json:"tech_card,omitempty" gorm:"foreignKey:TechCardID"`
+	ProductID       *uuid.UUID `json:"product_id,omitempty" gorm:"type:uuid;index"`
+	Product         *Product   `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	CostPrice       float64    `json:"cost_price"`
+	Price           float64    `json:"price"`
+	FoodCostPercent float64    `json:"food_cost_percent"` // Себестоимость в процентах от цены
+	Margin          float64    `json:"margin"`            // Цена минус себестоимость
+	Reason          string     `json:"reason" gorm:"type:varchar(20);not null"`
+	SourceID        *uuid.UUID `json:"source_id,omitempty" gorm:"type:uuid"` // Поставка, после которой пересчитана себестоимость
+	RecordedAt      time.Time  `json:"recorded_at" gorm:"not null;index"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID и расчета показателей
+func (c *MenuItemCost) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	if c.RecordedAt.IsZero() {
+		c.RecordedAt = time.Now()
+	}
+	c.CostPrice = RoundTo2(c.CostPrice)
+	c.Price = RoundTo2(c.Price)
+	c.FoodCostPercent = FoodCostPercent(c.CostPrice, c.Price)
+	c.Margin = RoundTo2(c.Price - c.CostPrice)
+	return nil
+}
+
+// FoodCostPercent возвращает долю себестоимости в цене, % (0, если цена не задана)
+func FoodCostPercent(costPrice, price float64) float64 {
+	if price <= 0 {
+		return 0
+	}
+	return RoundTo2(costPrice / price * 100)
+}
+
+// FoodCostAlert уведомление о том, что фудкост позиции превысил порог заведения (Establishment.FoodCostThreshold).
+// Статусы те же, что у уведомлений о низком остатке; для позиции существует не более одного активного уведомления.
+type FoodCostAlert struct {
+	ID              uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EstablishmentID uuid.UUID        `json:"establishment_id" gorm:"type:uuid;not null;index"`
+	TechCardID      *uuid.UUID       `json:"tech_card_id,omitempty" gorm:"type:uuid;index"`
+	TechCard        *TechCard        `json:"tech_card,omitempty" gorm:"foreignKey:TechCardID"`
+	ProductID       *uuid.UUID       `json:"product_id,omitempty" gorm:"type:uuid;index"`
+	Product         *Product         `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	CostPrice       float64          `json:"cost_price"`
+	Price           float64          `json:"price"`
+	FoodCostPercent float64          `json:"food_cost_percent"` // Фудкост на момент последней проверки
+	Threshold       float64          `json:"threshold"`         // Порог на момент срабатывания
+	Status          StockAlertStatus `json:"status" gorm:"type:varchar(20);not null;default:'open';index"`
+	AcknowledgedAt  *time.Time       `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy  *uuid.UUID       `json:"acknowledged_by,omitempty" gorm:"type:uuid"`
+	ResolvedAt      *time.Time       `json:"resolved_at,omitempty"`
+	CreatedAt       time.Time        `json:"created_at" gorm:"index"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID и округления значений
+func (a *FoodCostAlert) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	if a.Status == "" {
+		a.Status = StockAlertStatusOpen
+	}
+	a.CostPrice = RoundTo2(a.CostPrice)
+	a.Price = RoundTo2(a.Price)
+	a.FoodCostPercent = RoundTo2(a.FoodCostPercent)
+	a.Threshold = RoundTo2(a.Threshold)
+	return nil
+}
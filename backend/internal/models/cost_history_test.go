@@ -0,0 +1,22 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFoodCostPercent(t *testing.T) {
+	assert.Equal(t, 35.0, FoodCostPercent(105, 300))
+	assert.Equal(t, 33.33, FoodCostPercent(100, 300))
+	assert.Equal(t, 0.0, FoodCostPercent(100, 0), "price not set")
+}
+
+func TestMenuItemCostBeforeCreate(t *testing.T) {
+	c := &MenuItemCost{CostPrice: 120.456, Price: 400}
+	assert.NoError(t, c.BeforeCreate(nil))
+	assert.Equal(t, 120.46, c.CostPrice)
+	assert.Equal(t, 30.12, c.FoodCostPercent)
+	assert.Equal(t, 279.54, c.Margin)
+	assert.False(t, c.RecordedAt.IsZero())
+}
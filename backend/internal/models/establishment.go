@@ -24,6 +24,11 @@ type Establishment struct {
 	HasDelivery      bool          `json:"has_delivery" gorm:"default:false"`         // Есть ли доставка
 	HasTakeaway      bool          `json:"has_takeaway" gorm:"default:false"`         // Есть ли на вынос
 	HasReservations  bool          `json:"has_reservations" gorm:"default:false"`     // Принимаются ли бронирования
+	Timezone         string        `json:"timezone" gorm:"type:varchar(64)"`          // Часовой пояс IANA (Europe/Moscow); пусто — время сервера
+
+	// Складской учет
+	NegativeStockPolicy string     `json:"negative_stock_policy" gorm:"type:varchar(10);default:'allow'"` // allow, warn, block
+	FoodCostThreshold   float64    `json:"food_cost_threshold" gorm:"default:35"`                         // Порог фудкоста для уведомлений, % (0 — не уведомлять)
 
 	// Связи
 	Rooms           []Room         `json:"rooms,omitempty" gorm:"foreignKey:EstablishmentID;constraint:OnDelete:CASCADE"`
@@ -39,9 +44,36 @@ func (e *Establishment) BeforeCreate(tx *gorm.DB) error {
 	if e.ID == uuid.Nil {
 		e.ID = uuid.New()
 	}
+	if e.NegativeStockPolicy == "" {
+		e.NegativeStockPolicy = NegativeStockPolicyAllow
+	}
 	return nil
 }
 
+// Политика продажи при нехватке остатков
+const (
+	NegativeStockPolicyAllow = "allow" // Продавать, остаток уходит в минус
+	NegativeStockPolicyWarn  = "warn"  // Продавать, но предупреждать о нехватке
+	NegativeStockPolicyBlock = "block" // Не продавать позиции, на которые не хватает остатков
+)
+
+// IsValidNegativeStockPolicy проверяет значение политики продажи при нехватке остатков
+func IsValidNegativeStockPolicy(policy string) bool {
+	switch policy {
+	case NegativeStockPolicyAllow, NegativeStockPolicyWarn, NegativeStockPolicyBlock:
+		return true
+	}
+	return false
+}
+
+// StockPolicy возвращает политику продажи при нехватке остатков (по умолчанию allow)
+func (e *Establishment) StockPolicy() string {
+	if IsValidNegativeStockPolicy(e.NegativeStockPolicy) {
+		return e.NegativeStockPolicy
+	}
+	return NegativeStockPolicyAllow
+}
+
 // Room представляет зал в заведении
 type Room struct {
 	ID              uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
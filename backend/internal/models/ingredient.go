@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,8 +17,12 @@ type Ingredient struct {
 	CategoryID      uuid.UUID           `json:"category_id" gorm:"type:uuid;not null;index"`
 	Category    *IngredientCategory `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
 	Name        string         `json:"name" gorm:"not null;index"`
-	Unit        string         `json:"unit" gorm:"not null"` // единица измерения: шт, л, кг
+	Unit        string         `json:"unit" gorm:"not null"` // единица измерения: шт, кг, г, л, мл
 	Barcode     string         `json:"barcode"` // Штрихкод
+	ExternalCode string        `json:"external_code" gorm:"index"` // Код во внешней системе (для импорта и выгрузки меню)
+	ShelfLifeDays int          `json:"shelf_life_days" gorm:"default:0"` // Срок годности по умолчанию, дней (0 — не отслеживается)
+	// Пользовательские пересчеты единиц (например, 1 шт = 0.05 кг)
+	UnitConversions []IngredientUnitConversion `json:"unit_conversions,omitempty" gorm:"foreignKey:IngredientID"`
 	
 	// Проценты потерь при разных способах приготовления
 	LossCleaning   float64     `json:"loss_cleaning" gorm:"default:0"`   // % потерь при очистке
@@ -25,6 +31,13 @@ type Ingredient struct {
 	LossStewing    float64     `json:"loss_stewing" gorm:"default:0"`     // % потерь при тушении
 	LossBaking     float64     `json:"loss_baking" gorm:"default:0"`      // % потерь при запекании
 	
+	// Аллергены и пищевая ценность на 100 г (для жидкостей — на 100 мл)
+	Allergens     AllergenList `json:"allergens" gorm:"type:varchar(255);default:''"`
+	Calories      float64      `json:"calories" gorm:"default:0"`      // ккал
+	Proteins      float64      `json:"proteins" gorm:"default:0"`      // Белки, г
+	Fats          float64      `json:"fats" gorm:"default:0"`          // Жиры, г
+	Carbohydrates float64      `json:"carbohydrates" gorm:"default:0"` // Углеводы, г
+	
 	Active      bool           `json:"active" gorm:"default:true;index"`
 	CreatedAt   time.Time      `json:"created_at" gorm:"index"`
 	UpdatedAt   time.Time      `json:"updated_at"`
@@ -33,18 +46,108 @@ type Ingredient struct {
 
 // ValidUnits возвращает список допустимых единиц измерения
 func ValidIngredientUnits() []string {
-	return []string{"шт", "л", "кг"}
+	return ValidUnits()
 }
 
 // ValidateUnit проверяет, является ли единица измерения допустимой
 func (i *Ingredient) ValidateUnit() bool {
-	validUnits := ValidIngredientUnits()
-	for _, unit := range validUnits {
-		if i.Unit == unit {
-			return true
+	return IsValidUnit(i.Unit)
+}
+
+// ConvertQuantity переводит количество ингредиента из одной единицы в другую
+// с учетом его пользовательских пересчетов
+func (i *Ingredient) ConvertQuantity(quantity float64, from, to string) (float64, error) {
+	return ConvertIngredientUnit(quantity, from, to, i.UnitConversions)
+}
+
+// Способы приготовления, для которых у ингредиента задан процент потерь
+const (
+	PreparationCleaning = "cleaning" // Очистка
+	PreparationBoiling  = "boiling"  // Варка
+	PreparationFrying   = "frying"   // Жарка
+	PreparationStewing  = "stewing"  // Тушение
+	PreparationBaking   = "baking"   // Запекание
+)
+
+// ValidPreparationMethods возвращает список допустимых способов приготовления
+func ValidPreparationMethods() []string {
+	return []string{PreparationCleaning, PreparationBoiling, PreparationFrying, PreparationStewing, PreparationBaking}
+}
+
+// ParsePreparationMethods разбирает последовательность способов приготовления,
+// записанную через запятую (например, "cleaning,boiling"), и проверяет каждый способ
+func ParsePreparationMethods(value *string) ([]string, error) {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return nil, nil
+	}
+	valid := ValidPreparationMethods()
+	methods := make([]string, 0)
+	for _, part := range strings.Split(*value, ",") {
+		method := strings.ToLower(strings.TrimSpace(part))
+		if method == "" {
+			continue
 		}
+		known := false
+		for _, v := range valid {
+			if v == method {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("invalid preparation method %q, must be one of: %s", method, strings.Join(valid, ", "))
+		}
+		methods = append(methods, method)
 	}
-	return false
+	return methods, nil
+}
+
+// LossPercent возвращает процент потерь ингредиента при способе приготовления
+func (i *Ingredient) LossPercent(method string) float64 {
+	switch method {
+	case PreparationCleaning:
+		return i.LossCleaning
+	case PreparationBoiling:
+		return i.LossBoiling
+	case PreparationFrying:
+		return i.LossFrying
+	case PreparationStewing:
+		return i.LossStewing
+	case PreparationBaking:
+		return i.LossBaking
+	}
+	return 0
+}
+
+// YieldRatio возвращает долю выхода (нетто / брутто) после последовательного применения способов приготовления.
+// Потери складываются последовательно: очистка 10% и варка 20% дают выход 0.9 * 0.8 = 0.72.
+func (i *Ingredient) YieldRatio(methods []string) float64 {
+	ratio := 1.0
+	for _, method := range methods {
+		loss := i.LossPercent(method)
+		if loss <= 0 {
+			continue
+		}
+		if loss >= 100 {
+			return 0
+		}
+		ratio *= 1 - loss/100
+	}
+	return ratio
+}
+
+// GrossFromNet рассчитывает брутто по нетто с учетом потерь способов приготовления
+func (i *Ingredient) GrossFromNet(net float64, methods []string) float64 {
+	ratio := i.YieldRatio(methods)
+	if ratio <= 0 {
+		return net
+	}
+	return net / ratio
+}
+
+// NetFromGross рассчитывает нетто по брутто с учетом потерь способов приготовления
+func (i *Ingredient) NetFromGross(gross float64, methods []string) float64 {
+	return gross * i.YieldRatio(methods)
 }
 
 // BeforeCreate hook для автоматической генерации UUID
@@ -25,6 +25,14 @@ const (
 	InventoryStatusCancelled InventoryStatus = "cancelled" // Отменена
 )
 
+// InventoryCountMode режим подсчета
+type InventoryCountMode string
+
+const (
+	InventoryCountModeSingle InventoryCountMode = "single" // Фактическое количество вносится в позицию напрямую
+	InventoryCountModeMulti  InventoryCountMode = "multi"  // Несколько счетчиков вносят подсчеты по зонам
+)
+
 // Inventory представляет инвентаризацию
 type Inventory struct {
 	ID               uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
@@ -37,6 +45,13 @@ type Inventory struct {
 	ScheduledDate    *time.Time       `json:"scheduled_date"`                                          // Запланированная дата проведения (для задним числом)
 	ActualDate       *time.Time       `json:"actual_date"`                                             // Фактическая дата проведения
 	Comment          string           `json:"comment"`                                                 // Комментарий
+	CountMode        InventoryCountMode `json:"count_mode" gorm:"default:'single'"`                    // single, multi
+	Blind            bool             `json:"blind" gorm:"default:false"`                              // Слепой пересчет: ожидаемое количество скрыто до завершения
+	WriteOffReasonID *uuid.UUID       `json:"write_off_reason_id,omitempty" gorm:"type:uuid;index"`   // Причина списания недостачи (блок ОПиУ); без причины — себестоимость
+	WriteOffReason   *WriteOffReason  `json:"write_off_reason,omitempty" gorm:"foreignKey:WriteOffReasonID"`
+	CountDate        *time.Time       `json:"count_date,omitempty" gorm:"index"`                       // Дата, на которую зафиксированы фактические остатки
+	ShortageAmount   float64          `json:"shortage_amount" gorm:"default:0"`                        // Себестоимость недостачи
+	SurplusAmount    float64          `json:"surplus_amount" gorm:"default:0"`                         // Стоимость излишков
 	Items            []InventoryItem  `json:"items,omitempty" gorm:"foreignKey:InventoryID;constraint:OnDelete:CASCADE"`
 	CreatedBy        *uuid.UUID       `json:"created_by,omitempty" gorm:"type:uuid"`
 	CompletedBy      *uuid.UUID       `json:"completed_by,omitempty" gorm:"type:uuid"`
@@ -45,6 +60,28 @@ type Inventory struct {
 	DeletedAt        gorm.DeletedAt   `json:"-" gorm:"index"`
 }
 
+// HidesExpected сообщает, что ожидаемые количества нужно скрывать (слепой пересчет до завершения)
+func (i *Inventory) HidesExpected() bool {
+	return i.Blind && i.Status != InventoryStatusCompleted
+}
+
+// HideExpected скрывает ожидаемые количества и расхождения позиций при слепом пересчете
+func (i *Inventory) HideExpected() {
+	if !i.HidesExpected() {
+		return
+	}
+	for j := range i.Items {
+		i.Items[j].ExpectedQuantity = 0
+		i.Items[j].Difference = 0
+		i.Items[j].DifferenceValue = 0
+	}
+}
+
+// LossAmount возвращает итог инвентаризации для ОПиУ: недостача за вычетом излишков
+func (i *Inventory) LossAmount() float64 {
+	return RoundTo2(i.ShortageAmount - i.SurplusAmount)
+}
+
 // BeforeCreate hook для автоматической генерации UUID
 func (i *Inventory) BeforeCreate(tx *gorm.DB) error {
 	if i.ID == uuid.Nil {
@@ -95,3 +132,29 @@ func (ii *InventoryItem) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// InventoryCount подсчет позиции инвентаризации одним счетчиком в одной зоне (полке, помещении).
+// Фактическое количество позиции — сумма количеств по зонам; в зоне с расходящимися
+// подсчетами количество определяет итоговая запись (IsFinal), внесенная при сверке.
+type InventoryCount struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	InventoryID uuid.UUID      `json:"inventory_id" gorm:"type:uuid;not null;index"`
+	ItemID      uuid.UUID      `json:"item_id" gorm:"type:uuid;not null;index"`
+	Item        *InventoryItem `json:"item,omitempty" gorm:"foreignKey:ItemID;constraint:OnDelete:CASCADE"`
+	Zone        string         `json:"zone" gorm:"index"`                  // Зона или полка; пусто — весь склад
+	CounterID   uuid.UUID      `json:"counter_id" gorm:"type:uuid;index"`   // Кто считал (или сверил)
+	Quantity    float64        `json:"quantity" gorm:"not null"`           // В единице позиции инвентаризации
+	IsFinal     bool           `json:"is_final" gorm:"default:false"`      // Количество, принятое при сверке расхождений
+	Comment     string         `json:"comment"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID
+func (c *InventoryCount) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	c.Quantity = RoundTo2(c.Quantity)
+	return nil
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MenuEngineeringClass класс позиции в матрице меню-инжиниринга (популярность × маржинальность)
+type MenuEngineeringClass string
+
+const (
+	MenuEngineeringStar      MenuEngineeringClass = "star"      // Популярная и маржинальная
+	MenuEngineeringPlowhorse MenuEngineeringClass = "plowhorse" // Популярная, но с низкой маржой
+	MenuEngineeringPuzzle    MenuEngineeringClass = "puzzle"    // Маржинальная, но продается плохо
+	MenuEngineeringDog       MenuEngineeringClass = "dog"       // Непопулярная и низкомаржинальная
+)
+
+// MenuEngineeringPopularityFactor доля от равной доли продаж (1/N), начиная с которой позиция считается популярной
+const MenuEngineeringPopularityFactor = 0.7
+
+// ClassifyMenuItem определяет класс позиции по популярности и маржинальности
+func ClassifyMenuItem(popular, profitable bool) MenuEngineeringClass {
+	switch {
+	case popular && profitable:
+		return MenuEngineeringStar
+	case popular:
+		return MenuEngineeringPlowhorse
+	case profitable:
+		return MenuEngineeringPuzzle
+	default:
+		return MenuEngineeringDog
+	}
+}
+
+// Recommendation возвращает рекомендуемое действие для позиций класса
+func (c MenuEngineeringClass) Recommendation() string {
+	switch c {
+	case MenuEngineeringStar:
+		return "Сохранить рецептуру и подачу, держать на видном месте в меню, цену повышать осторожно"
+	case MenuEngineeringPlowhorse:
+		return "Поднять цену или снизить себестоимость (выход, ингредиенты), предлагать в паре с маржинальными позициями"
+	case MenuEngineeringPuzzle:
+		return "Продвигать: переместить на видное место в меню, переименовать, рекомендовать гостям, пересмотреть цену вниз"
+	case MenuEngineeringDog:
+		return "Убрать из меню или переработать рецептуру"
+	default:
+		return ""
+	}
+}
+
+// MenuEngineeringItem позиция меню (тех-карта или товар) в отчете меню-инжиниринга
+type MenuEngineeringItem struct {
+	ItemID          uuid.UUID            `json:"item_id"`
+	ItemType        string               `json:"item_type"` // tech_card, product
+	Name            string               `json:"name"`
+	CategoryID      uuid.UUID            `json:"category_id"`
+	CategoryName    string               `json:"category_name"`
+	QuantitySold    int                  `json:"quantity_sold"`
+	Revenue         float64              `json:"revenue"`
+	AveragePrice    float64              `json:"average_price"`     // Средняя фактическая цена продажи
+	CostPrice       float64              `json:"cost_price"`        // Себестоимость единицы на конец периода
+	UnitMargin      float64              `json:"unit_margin"`       // Маржинальный доход с единицы: цена минус себестоимость
+	TotalMargin     float64              `json:"total_margin"`      // Маржинальный доход за период
+	FoodCostPercent float64              `json:"food_cost_percent"` // Себестоимость в процентах от средней цены
+	SalesMixPercent float64              `json:"sales_mix_percent"` // Доля в продажах категории, %
+	Class           MenuEngineeringClass `json:"class"`
+	Recommendation  string               `json:"recommendation"`
+}
+
+// MenuEngineeringCategory итоги и пороги классификации по категории
+type MenuEngineeringCategory struct {
+	CategoryID          uuid.UUID `json:"category_id"`
+	CategoryName        string    `json:"category_name"`
+	ItemsCount          int       `json:"items_count"`
+	QuantitySold        int       `json:"quantity_sold"`
+	Revenue             float64   `json:"revenue"`
+	TotalMargin         float64   `json:"total_margin"`
+	PopularityThreshold float64   `json:"popularity_threshold"` // Порог доли продаж, %
+	MarginThreshold     float64   `json:"margin_threshold"`     // Средневзвешенный маржинальный доход с единицы
+	Stars               int       `json:"stars"`
+	Plowhorses          int       `json:"plowhorses"`
+	Puzzles             int       `json:"puzzles"`
+	Dogs                int       `json:"dogs"`
+}
+
+// MenuEngineeringReport отчет меню-инжиниринга за период
+type MenuEngineeringReport struct {
+	StartDate  time.Time                 `json:"start_date"`
+	EndDate    time.Time                 `json:"end_date"`
+	Categories []MenuEngineeringCategory `json:"categories"`
+	Items      []MenuEngineeringItem     `json:"items"`
+}
+
+// MenuEngineeringChange изменение позиции между двумя периодами
+type MenuEngineeringChange struct {
+	ItemID           uuid.UUID            `json:"item_id"`
+	ItemType         string               `json:"item_type"`
+	Name             string               `json:"name"`
+	CategoryName     string               `json:"category_name"`
+	PreviousClass    MenuEngineeringClass `json:"previous_class,omitempty"` // Пусто, если позиция не продавалась в прошлом периоде
+	CurrentClass     MenuEngineeringClass `json:"current_class,omitempty"`  // Пусто, если позиция не продавалась в текущем периоде
+	ClassChanged     bool                 `json:"class_changed"`
+	QuantityDelta    int                  `json:"quantity_delta"`
+	UnitMarginDelta  float64              `json:"unit_margin_delta"`
+	SalesMixDelta    float64              `json:"sales_mix_delta"`
+	TotalMarginDelta float64              `json:"total_margin_delta"`
+	Recommendation   string               `json:"recommendation,omitempty"` // Рекомендация для текущего класса
+}
+
+// MenuEngineeringComparison сравнение отчетов меню-инжиниринга за два периода
+type MenuEngineeringComparison struct {
+	Current  *MenuEngineeringReport  `json:"current"`
+	Previous *MenuEngineeringReport  `json:"previous"`
+	Changes  []MenuEngineeringChange `json:"changes"`
+}
+
+// ClassifyMenuEngineering рассчитывает долю продаж, пороги и класс позиций по каждой категории отдельно.
+// Позиция популярна, если ее доля продаж не ниже 70% от равной доли (1/N), и маржинальна,
+// если ее маржинальный доход с единицы не ниже средневзвешенного по категории.
+func ClassifyMenuEngineering(items []MenuEngineeringItem) []MenuEngineeringCategory {
+	var order []uuid.UUID
+	byCategory := make(map[uuid.UUID][]int)
+	for i := range items {
+		catID := items[i].CategoryID
+		if _, ok := byCategory[catID]; !ok {
+			order = append(order, catID)
+		}
+		byCategory[catID] = append(byCategory[catID], i)
+	}
+
+	categories := make([]MenuEngineeringCategory, 0, len(order))
+	for _, catID := range order {
+		idx := byCategory[catID]
+		cat := MenuEngineeringCategory{
+			CategoryID:   catID,
+			CategoryName: items[idx[0]].CategoryName,
+			ItemsCount:   len(idx),
+		}
+		for _, i := range idx {
+			cat.QuantitySold += items[i].QuantitySold
+			cat.Revenue += items[i].Revenue
+			cat.TotalMargin += items[i].TotalMargin
+		}
+
+		popularity := 100 / float64(len(idx)) * MenuEngineeringPopularityFactor
+		var marginThreshold float64
+		if cat.QuantitySold > 0 {
+			marginThreshold = cat.TotalMargin / float64(cat.QuantitySold)
+		}
+
+		for _, i := range idx {
+			item := &items[i]
+			if cat.QuantitySold > 0 {
+				item.SalesMixPercent = RoundTo2(float64(item.QuantitySold) / float64(cat.QuantitySold) * 100)
+			}
+			popular := float64(item.QuantitySold)/float64(max(cat.QuantitySold, 1))*100 >= popularity
+			profitable := item.UnitMargin >= RoundTo2(marginThreshold)
+			item.Class = ClassifyMenuItem(popular, profitable)
+			item.Recommendation = item.Class.Recommendation()
+
+			switch item.Class {
+			case MenuEngineeringStar:
+				cat.Stars++
+			case MenuEngineeringPlowhorse:
+				cat.Plowhorses++
+			case MenuEngineeringPuzzle:
+				cat.Puzzles++
+			case MenuEngineeringDog:
+				cat.Dogs++
+			}
+		}
+
+		cat.Revenue = RoundTo2(cat.Revenue)
+		cat.TotalMargin = RoundTo2(cat.TotalMargin)
+		cat.PopularityThreshold = RoundTo2(popularity)
+		cat.MarginThreshold = RoundTo2(marginThreshold)
+		categories = append(categories, cat)
+	}
+	return categories
+}
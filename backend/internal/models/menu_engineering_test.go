@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyMenuEngineering(t *testing.T) {
+	hot, drinks := uuid.New(), uuid.New()
+	item := func(cat uuid.UUID, qty int, unitMargin float64) MenuEngineeringItem {
+		return MenuEngineeringItem{
+			ItemID:       uuid.New(),
+			CategoryID:   cat,
+			QuantitySold: qty,
+			UnitMargin:   unitMargin,
+			TotalMargin:  unitMargin * float64(qty),
+		}
+	}
+	items := []MenuEngineeringItem{
+		item(hot, 50, 300),
+		item(hot, 40, 100),
+		item(hot, 5, 400),
+		item(hot, 5, 50),
+		item(drinks, 10, 80),
+	}
+
+	categories := ClassifyMenuEngineering(items)
+	assert.Len(t, categories, 2)
+
+	// Порог популярности 100% / 4 * 0.7 = 17.5%, порог маржи 21250 / 100 = 212.5
+	assert.Equal(t, 17.5, categories[0].PopularityThreshold)
+	assert.Equal(t, 212.5, categories[0].MarginThreshold)
+	assert.Equal(t, MenuEngineeringStar, items[0].Class)
+	assert.Equal(t, MenuEngineeringPlowhorse, items[1].Class)
+	assert.Equal(t, MenuEngineeringPuzzle, items[2].Class)
+	assert.Equal(t, MenuEngineeringDog, items[3].Class)
+	assert.Equal(t, 50.0, items[0].SalesMixPercent)
+	assert.Equal(t, 1, categories[0].Stars)
+	assert.Equal(t, 1, categories[0].Dogs)
+	assert.NotEmpty(t, items[3].Recommendation)
+
+	// Единственная позиция категории всегда Star
+	assert.Equal(t, MenuEngineeringStar, items[4].Class)
+	assert.Equal(t, 100.0, items[4].SalesMixPercent)
+}
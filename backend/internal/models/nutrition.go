@@ -0,0 +1,242 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Аллергены (14 основных аллергенов по классификации ЕС)
+const (
+	AllergenGluten      = "gluten"      // Злаки, содержащие глютен
+	AllergenCrustaceans = "crustaceans" // Ракообразные
+	AllergenEggs        = "eggs"        // Яйца
+	AllergenFish        = "fish"        // Рыба
+	AllergenPeanuts     = "peanuts"     // Арахис
+	AllergenSoy         = "soy"         // Соя
+	AllergenMilk        = "milk"        // Молоко и лактоза
+	AllergenNuts        = "nuts"        // Орехи
+	AllergenCelery      = "celery"      // Сельдерей
+	AllergenMustard     = "mustard"     // Горчица
+	AllergenSesame      = "sesame"      // Кунжут
+	AllergenSulphites   = "sulphites"   // Диоксид серы и сульфиты
+	AllergenLupin       = "lupin"       // Люпин
+	AllergenMolluscs    = "molluscs"    // Моллюски
+)
+
+// ValidAllergens возвращает список поддерживаемых аллергенов
+func ValidAllergens() []string {
+	return []string{
+		AllergenGluten, AllergenCrustaceans, AllergenEggs, AllergenFish, AllergenPeanuts, AllergenSoy, AllergenMilk,
+		AllergenNuts, AllergenCelery, AllergenMustard, AllergenSesame, AllergenSulphites, AllergenLupin, AllergenMolluscs,
+	}
+}
+
+// AllergenList набор аллергенов; в БД хранится строкой через запятую
+type AllergenList []string
+
+// ParseAllergens приводит аллергены к нижнему регистру, убирает повторы, сортирует и проверяет каждый
+func ParseAllergens(values []string) (AllergenList, error) {
+	valid := make(map[string]bool)
+	for _, a := range ValidAllergens() {
+		valid[a] = true
+	}
+	list := make(AllergenList, 0, len(values))
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			allergen := strings.ToLower(strings.TrimSpace(part))
+			if allergen == "" {
+				continue
+			}
+			if !valid[allergen] {
+				return nil, fmt.Errorf("invalid allergen %q, must be one of: %s", allergen, strings.Join(ValidAllergens(), ", "))
+			}
+			list = append(list, allergen)
+		}
+	}
+	return list.normalize(), nil
+}
+
+// normalize убирает повторы и сортирует список
+func (l AllergenList) normalize() AllergenList {
+	seen := make(map[string]bool, len(l))
+	result := make(AllergenList, 0, len(l))
+	for _, a := range l {
+		if !seen[a] {
+			seen[a] = true
+			result = append(result, a)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Merge возвращает объединение двух наборов аллергенов
+func (l AllergenList) Merge(other AllergenList) AllergenList {
+	return append(append(AllergenList{}, l...), other...).normalize()
+}
+
+// ContainsAny проверяет, содержит ли набор хотя бы один из аллергенов
+func (l AllergenList) ContainsAny(allergens []string) bool {
+	for _, a := range l {
+		for _, b := range allergens {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Value реализует driver.Valuer
+func (l AllergenList) Value() (driver.Value, error) {
+	return strings.Join(l.normalize(), ","), nil
+}
+
+// Scan реализует sql.Scanner
+func (l *AllergenList) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		*l = AllergenList{}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into AllergenList", value)
+	}
+	list := AllergenList{}
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	*l = list
+	return nil
+}
+
+// NutritionFacts пищевая ценность: вес нетто (г), калорийность (ккал) и БЖУ (г)
+type NutritionFacts struct {
+	Weight        float64 `json:"weight"`
+	Calories      float64 `json:"calories"`
+	Proteins      float64 `json:"proteins"`
+	Fats          float64 `json:"fats"`
+	Carbohydrates float64 `json:"carbohydrates"`
+}
+
+// add прибавляет пищевую ценность, умноженную на factor
+func (n *NutritionFacts) add(other NutritionFacts, factor float64) {
+	n.Weight += other.Weight * factor
+	n.Calories += other.Calories * factor
+	n.Proteins += other.Proteins * factor
+	n.Fats += other.Fats * factor
+	n.Carbohydrates += other.Carbohydrates * factor
+}
+
+// rounded возвращает значения, округленные до 2 знаков
+func (n NutritionFacts) rounded() NutritionFacts {
+	return NutritionFacts{
+		Weight:        RoundTo2(n.Weight),
+		Calories:      RoundTo2(n.Calories),
+		Proteins:      RoundTo2(n.Proteins),
+		Fats:          RoundTo2(n.Fats),
+		Carbohydrates: RoundTo2(n.Carbohydrates),
+	}
+}
+
+// recipeGrams переводит количество в граммы с учетом пересчетов ингредиента.
+// Для объемных единиц без пересчета в массу плотность принимается равной 1 (1 мл = 1 г).
+func recipeGrams(quantity float64, unit string, conversions []IngredientUnitConversion) (float64, bool) {
+	if unit == "" {
+		return 0, false
+	}
+	if grams, err := ConvertIngredientUnit(quantity, unit, UnitGram, conversions); err == nil {
+		return grams, true
+	}
+	if ml, err := ConvertUnit(quantity, unit, UnitMilliliter); err == nil {
+		return ml, true
+	}
+	return 0, false
+}
+
+// NutritionFor возвращает пищевую ценность количества ингредиента нетто.
+// Второе значение false, если количество нельзя перевести в граммы (штуки без пересчета).
+func (i *Ingredient) NutritionFor(quantity float64, unit string) (NutritionFacts, bool) {
+	if unit == "" {
+		unit = i.Unit
+	}
+	grams, ok := recipeGrams(quantity, unit, i.UnitConversions)
+	if !ok {
+		return NutritionFacts{}, false
+	}
+	factor := grams / 100
+	return NutritionFacts{
+		Weight:        grams,
+		Calories:      i.Calories * factor,
+		Proteins:      i.Proteins * factor,
+		Fats:          i.Fats * factor,
+		Carbohydrates: i.Carbohydrates * factor,
+	}, true
+}
+
+// Composition возвращает пищевую ценность и аллергены всего выхода полуфабриката по весу нетто ингредиентов.
+// Ингредиенты должны быть загружены вместе с Ingredient.
+func (sfp *SemiFinishedProduct) Composition() (NutritionFacts, AllergenList) {
+	var total NutritionFacts
+	allergens := AllergenList{}
+	for _, ing := range sfp.Ingredients {
+		if ing.Ingredient == nil {
+			continue
+		}
+		allergens = allergens.Merge(ing.Ingredient.Allergens)
+		if facts, ok := ing.Ingredient.NutritionFor(ing.Net, ing.Unit); ok {
+			total.add(facts, 1)
+		}
+	}
+	// Вес берется по выходу, если он задан в единицах массы или объема (уварка, ужарка)
+	if grams, ok := recipeGrams(sfp.Quantity, sfp.Unit, nil); ok && grams > 0 {
+		total.Weight = grams
+	}
+	return total, allergens
+}
+
+// semiFinishedShare возвращает долю выхода полуфабриката, приходящуюся на количество в рецептуре
+func (sfp *SemiFinishedProduct) semiFinishedShare(quantity float64, unit string, batchWeight float64) (float64, bool) {
+	if sfp.Quantity > 0 {
+		if q, err := ConvertUnit(quantity, unit, sfp.Unit); err == nil {
+			return q / sfp.Quantity, true
+		}
+	}
+	if grams, ok := recipeGrams(quantity, unit, nil); ok && batchWeight > 0 {
+		return grams / batchWeight, true
+	}
+	return 0, false
+}
+
+// CalculateComposition рассчитывает пищевую ценность порции и аллергены тех-карты по весу нетто ингредиентов
+// и полуфабрикатов. Ингредиенты и ингредиенты полуфабрикатов должны быть загружены.
+func (tc *TechCard) CalculateComposition() {
+	var total NutritionFacts
+	allergens := AllergenList{}
+	for _, ing := range tc.Ingredients {
+		switch {
+		case ing.Ingredient != nil:
+			allergens = allergens.Merge(ing.Ingredient.Allergens)
+			if facts, ok := ing.Ingredient.NutritionFor(ing.Quantity, ing.Unit); ok {
+				total.add(facts, 1)
+			}
+		case ing.SemiFinished != nil:
+			batch, sfAllergens := ing.SemiFinished.Composition()
+			allergens = allergens.Merge(sfAllergens)
+			if share, ok := ing.SemiFinished.semiFinishedShare(ing.Quantity, ing.Unit, batch.Weight); ok {
+				total.add(batch, share)
+			}
+		}
+	}
+	facts := total.rounded()
+	tc.Nutrition = &facts
+	tc.Allergens = allergens
+}
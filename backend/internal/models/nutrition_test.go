@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAllergens(t *testing.T) {
+	list, err := ParseAllergens([]string{"Milk, gluten", "milk"})
+	require.NoError(t, err)
+	assert.Equal(t, AllergenList{"gluten", "milk"}, list)
+
+	_, err = ParseAllergens([]string{"chocolate"})
+	assert.Error(t, err)
+}
+
+func TestTechCardCalculateComposition(t *testing.T) {
+	flour := &Ingredient{Unit: UnitKilogram, Allergens: AllergenList{AllergenGluten}, Calories: 340, Proteins: 10, Fats: 1, Carbohydrates: 70}
+	egg := &Ingredient{
+		Unit:            UnitPiece,
+		Allergens:       AllergenList{AllergenEggs},
+		Calories:        150,
+		Proteins:        12,
+		Fats:            10,
+		UnitConversions: []IngredientUnitConversion{{Unit: UnitPiece, Factor: 0.05, TargetUnit: UnitKilogram}},
+	}
+	milk := &Ingredient{Unit: UnitLiter, Allergens: AllergenList{AllergenMilk}, Calories: 60, Proteins: 3, Fats: 3, Carbohydrates: 5}
+
+	// Соус: 400 мл молока уваривается до 200 г выхода
+	sauce := &SemiFinishedProduct{
+		Unit:     UnitGram,
+		Quantity: 200,
+		Ingredients: []SemiFinishedIngredient{
+			{Ingredient: milk, Net: 400, Unit: UnitMilliliter},
+		},
+	}
+
+	tc := &TechCard{
+		Ingredients: []TechCardIngredient{
+			{Ingredient: flour, Quantity: 0.1, Unit: UnitKilogram}, // 100 г
+			{Ingredient: egg, Quantity: 2, Unit: UnitPiece},        // 100 г
+			{SemiFinished: sauce, Quantity: 50, Unit: UnitGram},    // четверть выхода соуса
+		},
+	}
+	tc.CalculateComposition()
+
+	require.NotNil(t, tc.Nutrition)
+	assert.Equal(t, 250.0, tc.Nutrition.Weight)
+	assert.Equal(t, 340.0+150+60, tc.Nutrition.Calories) // 400 мл молока × 1/4 = 100 мл
+	assert.Equal(t, 10.0+12+3, tc.Nutrition.Proteins)
+	assert.Equal(t, 70.0+5, tc.Nutrition.Carbohydrates)
+	assert.Equal(t, AllergenList{"eggs", "gluten", "milk"}, tc.Allergens)
+	assert.True(t, tc.Allergens.ContainsAny([]string{AllergenMilk, AllergenNuts}))
+	assert.False(t, tc.Allergens.ContainsAny([]string{AllergenNuts}))
+}
@@ -25,6 +25,7 @@ type Order struct {
 	ReasonForNoPayment *string   `json:"reason_for_no_payment,omitempty"` // Причина закрытия без оплаты
 	TotalAmount   float64        `json:"total_amount"`
 	Items         []OrderItem    `json:"items,omitempty" gorm:"foreignKey:OrderID"`
+	StockWarnings []StockShortage `json:"stock_warnings,omitempty" gorm:"-"` // Нехватка остатков при политике warn
 	CreatedAt     time.Time      `json:"created_at" gorm:"index"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
@@ -69,6 +70,9 @@ type OrderItem struct {
 	Product     *Product    `json:"product,omitempty" gorm:"foreignKey:ProductID"`
 	TechCardID  *uuid.UUID  `json:"tech_card_id,omitempty" gorm:"type:uuid;index"`
 	TechCard    *TechCard   `json:"tech_card,omitempty" gorm:"foreignKey:TechCardID"`
+	ComboID     *uuid.UUID  `json:"combo_id,omitempty" gorm:"type:uuid;index"`
+	Combo       *Combo      `json:"combo,omitempty" gorm:"foreignKey:ComboID"`
+	Components  []OrderItemComponent `json:"components,omitempty" gorm:"foreignKey:OrderItemID;constraint:OnDelete:CASCADE"` // Выбранные компоненты комбо
 	Quantity    int        `json:"quantity" gorm:"not null"`
 	GuestNumber *int       `json:"guest_number,omitempty"` // Номер гостя в рамках заказа
 	Price       float64    `json:"price" gorm:"not null"`
@@ -93,4 +97,13 @@ func (oi *OrderItem) BeforeUpdate(tx *gorm.DB) error {
 	oi.Price = RoundTo2(oi.Price)
 	oi.TotalPrice = RoundTo2(oi.TotalPrice)
 	return nil
-}
\ No newline at end of file
+}
+// StockShortage нехватка позиции склада для заказа или блюда
+type StockShortage struct {
+	ItemID    uuid.UUID `json:"item_id"`
+	ItemType  string    `json:"item_type"` // ingredient, product
+	Name      string    `json:"name"`
+	Required  float64   `json:"required"`
+	Available float64   `json:"available"`
+	Unit      string    `json:"unit"`
+}
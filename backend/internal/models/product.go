@@ -29,14 +29,24 @@ type Product struct {
 	ExcludeFromDiscounts bool `json:"exclude_from_discounts" gorm:"default:false"` // Не участвует в скидках
 	HasModifications    bool   `json:"has_modifications" gorm:"default:false"`      // С модификациями (несколько видов товара)
 	
+	// Модификации: у модификации задан ParentID, у родительского товара — список Variants.
+	// Продается, поступает на склад и инвентаризуется модификация, а не родительский товар
+	ParentID    *uuid.UUID     `json:"parent_id,omitempty" gorm:"type:uuid;index"`
+	Variants    []Product      `json:"variants,omitempty" gorm:"foreignKey:ParentID"`
+	
 	// Штрихкод
 	Barcode     string         `json:"barcode"`                                      // Штрихкод товара
+	ExternalCode string        `json:"external_code" gorm:"index"`                   // Код во внешней системе (для импорта и выгрузки меню)
 	
 	// Ценообразование
 	CostPrice   float64        `json:"cost_price" gorm:"default:0"`                  // Себестоимость без НДС
 	Markup      float64        `json:"markup" gorm:"default:0"`                      // Наценка (%)
 	Price       float64        `json:"price" gorm:"not null"`                        // Итоговая цена (себестоимость + наценка)
 	
+	// Расписание доступности (если не задано — действует расписание категории)
+	AvailabilityScheduleID *uuid.UUID            `json:"availability_schedule_id,omitempty" gorm:"type:uuid;index"`
+	AvailabilitySchedule   *AvailabilitySchedule `json:"availability_schedule,omitempty" gorm:"foreignKey:AvailabilityScheduleID"`
+	
 	Active      bool           `json:"active" gorm:"default:true;index"`
 	CreatedAt   time.Time      `json:"created_at" gorm:"index"`
 	UpdatedAt   time.Time      `json:"updated_at"`
@@ -74,4 +84,22 @@ func (p *Product) CalculatePrice() {
 			p.Price = RoundTo2(p.CostPrice)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// IsVariant проверяет, является ли товар модификацией другого товара
+func (p *Product) IsVariant() bool {
+	return p.ParentID != nil
+}
+
+// HasVariants проверяет, продается ли товар только через модификации
+func (p *Product) HasVariants() bool {
+	return p.HasModifications && p.ParentID == nil
+}
+
+// ReportID возвращает ID товара для отчетов: при свертке модификаций — ID родительского товара
+func (p *Product) ReportID(rollupVariants bool) uuid.UUID {
+	if rollupVariants && p.ParentID != nil {
+		return *p.ParentID
+	}
+	return p.ID
+}
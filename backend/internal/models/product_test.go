@@ -0,0 +1,24 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductVariants(t *testing.T) {
+	parent := &Product{ID: uuid.New(), HasModifications: true}
+	variant := &Product{ID: uuid.New(), ParentID: &parent.ID}
+	plain := &Product{ID: uuid.New()}
+
+	assert.True(t, parent.HasVariants())
+	assert.False(t, parent.IsVariant())
+	assert.True(t, variant.IsVariant())
+	assert.False(t, variant.HasVariants())
+	assert.False(t, plain.HasVariants())
+
+	assert.Equal(t, variant.ID, variant.ReportID(false))
+	assert.Equal(t, parent.ID, variant.ReportID(true))
+	assert.Equal(t, plain.ID, plain.ReportID(true))
+}
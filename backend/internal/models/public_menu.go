@@ -0,0 +1,37 @@
+package models
+
+import "github.com/google/uuid"
+
+// PublicMenu меню заведения для гостей: активные тех-карты и товары по категориям
+type PublicMenu struct {
+	EstablishmentID uuid.UUID            `json:"establishment_id"`
+	Categories      []PublicMenuCategory `json:"categories"`
+}
+
+// PublicMenuCategory категория публичного меню
+type PublicMenuCategory struct {
+	ID    uuid.UUID        `json:"id"`
+	Name  string           `json:"name"`
+	Items []PublicMenuItem `json:"items"`
+}
+
+// PublicMenuItem позиция публичного меню без себестоимости и рецептуры
+type PublicMenuItem struct {
+	ID          uuid.UUID           `json:"id"`
+	Type        string              `json:"type"` // tech_card, product
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	CoverImage  string              `json:"cover_image"`
+	Price       float64             `json:"price"`
+	IsWeighted  bool                `json:"is_weighted"`
+	Nutrition   *NutritionFacts     `json:"nutrition,omitempty"` // Пищевая ценность порции (только для тех-карт)
+	Allergens   AllergenList        `json:"allergens"`           // Для товаров не рассчитываются
+	Variants    []PublicMenuVariant `json:"variants,omitempty"`  // Модификации товара; заказывается модификация
+}
+
+// PublicMenuVariant модификация товара в публичном меню
+type PublicMenuVariant struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Price float64   `json:"price"`
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PurchaseOrderStatus статус заказа поставщику
+type PurchaseOrderStatus string
+
+const (
+	PurchaseOrderStatusDraft             PurchaseOrderStatus = "draft"              // Черновик
+	PurchaseOrderStatusSent              PurchaseOrderStatus = "sent"               // Отправлен поставщику
+	PurchaseOrderStatusPartiallyReceived PurchaseOrderStatus = "partially_received" // Получен частично
+	PurchaseOrderStatusReceived          PurchaseOrderStatus = "received"           // Получен полностью
+	PurchaseOrderStatusClosed            PurchaseOrderStatus = "closed"             // Закрыт (недопоставка больше не ожидается)
+)
+
+// IsOpen возвращает true, если по заказу еще ожидается поставка
+func (s PurchaseOrderStatus) IsOpen() bool {
+	return s == PurchaseOrderStatusSent || s == PurchaseOrderStatusPartiallyReceived
+}
+
+// PurchaseOrder заказ поставщику (что заказано, в отличие от Supply — что фактически поступило)
+type PurchaseOrder struct {
+	ID              uuid.UUID           `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EstablishmentID uuid.UUID           `json:"establishment_id" gorm:"type:uuid;not null;index"`
+	SupplierID      uuid.UUID           `json:"supplier_id" gorm:"type:uuid;not null;index"`
+	Supplier        *Supplier           `json:"supplier,omitempty" gorm:"foreignKey:SupplierID"`
+	WarehouseID     uuid.UUID           `json:"warehouse_id" gorm:"type:uuid;not null;index"`
+	Warehouse       *Warehouse          `json:"warehouse,omitempty" gorm:"foreignKey:WarehouseID"`
+	Number          string              `json:"number" gorm:"index"` // Номер заказа для поставщика
+	Status          PurchaseOrderStatus `json:"status" gorm:"type:varchar(30);not null;default:'draft';index"`
+	ExpectedDate    *time.Time          `json:"expected_date,omitempty"` // Ожидаемая дата поставки
+	Comment         string              `json:"comment"`
+	TotalAmount     float64             `json:"total_amount" gorm:"default:0"`    // Ожидаемая сумма заказа
+	ReceivedAmount  float64             `json:"received_amount" gorm:"default:0"` // Сумма фактически полученного
+	SentAt          *time.Time          `json:"sent_at,omitempty"`
+	ClosedAt        *time.Time          `json:"closed_at,omitempty"`
+	Items           []PurchaseOrderItem `json:"items,omitempty" gorm:"foreignKey:PurchaseOrderID"`
+	Supplies        []Supply            `json:"supplies,omitempty" gorm:"foreignKey:PurchaseOrderID"` // Поставки, оформленные по заказу
+	CreatedAt       time.Time           `json:"created_at" gorm:"index"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID
+func (po *PurchaseOrder) BeforeCreate(tx *gorm.DB) error {
+	if po.ID == uuid.Nil {
+		po.ID = uuid.New()
+	}
+	if po.Status == "" {
+		po.Status = PurchaseOrderStatusDraft
+	}
+	if po.Number == "" {
+		po.Number = "PO-" + time.Now().Format("20060102") + "-" + po.ID.String()[:6]
+	}
+	po.TotalAmount = RoundTo2(po.TotalAmount)
+	po.ReceivedAmount = RoundTo2(po.ReceivedAmount)
+	return nil
+}
+
+// RecalculateTotals пересчитывает ожидаемую и полученную суммы по позициям
+func (po *PurchaseOrder) RecalculateTotals() {
+	po.TotalAmount = 0
+	po.ReceivedAmount = 0
+	for i := range po.Items {
+		po.Items[i].TotalAmount = RoundTo2(po.Items[i].Quantity * po.Items[i].PricePerUnit)
+		po.TotalAmount += po.Items[i].TotalAmount
+		po.ReceivedAmount += po.Items[i].ReceivedAmount
+	}
+	po.TotalAmount = RoundTo2(po.TotalAmount)
+	po.ReceivedAmount = RoundTo2(po.ReceivedAmount)
+}
+
+// FullyReceived возвращает true, если по всем позициям получено не меньше заказанного
+func (po *PurchaseOrder) FullyReceived() bool {
+	for _, item := range po.Items {
+		if item.OutstandingQuantity() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// PurchaseOrderItem позиция заказа поставщику: ожидаемое количество и цена
+type PurchaseOrderItem struct {
+	ID               uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey"`
+	PurchaseOrderID  uuid.UUID   `json:"purchase_order_id" gorm:"type:uuid;not null;index"`
+	IngredientID     *uuid.UUID  `json:"ingredient_id,omitempty" gorm:"type:uuid;index"`
+	Ingredient       *Ingredient `json:"ingredient,omitempty" gorm:"foreignKey:IngredientID"`
+	ProductID        *uuid.UUID  `json:"product_id,omitempty" gorm:"type:uuid;index"`
+	Product          *Product    `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	Quantity         float64     `json:"quantity" gorm:"not null"` // Заказанное количество
+	Unit             string      `json:"unit" gorm:"not null"`
+	PricePerUnit     float64     `json:"price_per_unit" gorm:"default:0"` // Ожидаемая цена за единицу
+	TotalAmount      float64     `json:"total_amount" gorm:"default:0"`
+	ReceivedQuantity float64     `json:"received_quantity" gorm:"default:0"` // Получено по поставкам (в единице заказа)
+	ReceivedAmount   float64     `json:"received_amount" gorm:"default:0"`   // Сумма полученного по фактическим ценам
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+}
+
+// OutstandingQuantity возвращает количество, которое еще не поставлено
+func (poi *PurchaseOrderItem) OutstandingQuantity() float64 {
+	outstanding := RoundTo2(poi.Quantity - poi.ReceivedQuantity)
+	if outstanding < 0 {
+		return 0
+	}
+	return outstanding
+}
+
+// BeforeCreate hook для автоматической генерации UUID и округления значений
+func (poi *PurchaseOrderItem) BeforeCreate(tx *gorm.DB) error {
+	if poi.ID == uuid.Nil {
+		poi.ID = uuid.New()
+	}
+	poi.Quantity = RoundTo2(poi.Quantity)
+	poi.PricePerUnit = RoundTo2(poi.PricePerUnit)
+	poi.TotalAmount = RoundTo2(poi.TotalAmount)
+	poi.ReceivedQuantity = RoundTo2(poi.ReceivedQuantity)
+	poi.ReceivedAmount = RoundTo2(poi.ReceivedAmount)
+	return nil
+}
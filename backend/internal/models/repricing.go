@@ -0,0 +1,161 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Стратегии правила переоценки
+const (
+	RepricingStrategyMarkup   = "markup"    // Целевая наценка на себестоимость, %
+	RepricingStrategyFoodCost = "food_cost" // Целевой фудкост (доля себестоимости в цене), %
+)
+
+// Правила округления новой цены (всегда вверх, чтобы не уменьшать маржу)
+const (
+	RepricingRoundingNone = "none" // До копеек
+	RepricingRounding5    = "5"    // До 5 ₽
+	RepricingRounding10   = "10"   // До 10 ₽
+	RepricingRounding90   = "90"   // До окончания .90
+)
+
+// IsValidRepricingStrategy проверяет стратегию правила переоценки
+func IsValidRepricingStrategy(s string) bool {
+	return s == RepricingStrategyMarkup || s == RepricingStrategyFoodCost
+}
+
+// IsValidRepricingRounding проверяет правило округления
+func IsValidRepricingRounding(s string) bool {
+	switch s {
+	case RepricingRoundingNone, RepricingRounding5, RepricingRounding10, RepricingRounding90:
+		return true
+	}
+	return false
+}
+
+// RepricingRule правило переоценки позиций меню.
+// Область действия — конкретная тех-карта или товар, категория или все меню (если ничего не указано);
+// для позиции применяется самое точное подходящее правило.
+type RepricingRule struct {
+	ID              uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EstablishmentID uuid.UUID      `json:"establishment_id" gorm:"type:uuid;not null;index"`
+	Name            string         `json:"name"`
+	CategoryID      *uuid.UUID     `json:"category_id,omitempty" gorm:"type:uuid;index"`
+	Category        *Category      `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+	TechCardID      *uuid.UUID     `json:"tech_card_id,omitempty" gorm:"type:uuid;index"`
+	ProductID       *uuid.UUID     `json:"product_id,omitempty" gorm:"type:uuid;index"`
+	Strategy        string         `json:"strategy" gorm:"type:varchar(20);not null"` // markup, food_cost
+	TargetValue     float64        `json:"target_value" gorm:"not null"`              // Наценка или фудкост, %
+	Rounding        string         `json:"rounding" gorm:"type:varchar(10);default:'none'"`
+	MinPrice        *float64       `json:"min_price,omitempty"`
+	MaxPrice        *float64       `json:"max_price,omitempty"`
+	Active          bool           `json:"active" gorm:"default:true;index"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID
+func (r *RepricingRule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	if r.Rounding == "" {
+		r.Rounding = RepricingRoundingNone
+	}
+	return nil
+}
+
+// TargetPrice рассчитывает цену по себестоимости: целевая наценка или фудкост, затем округление и границы.
+// Возвращает 0, если цену по правилу рассчитать нельзя (нет себестоимости или цели).
+func (r *RepricingRule) TargetPrice(costPrice float64) float64 {
+	if costPrice <= 0 || r.TargetValue <= 0 {
+		return 0
+	}
+	var price float64
+	switch r.Strategy {
+	case RepricingStrategyMarkup:
+		price = costPrice * (1 + r.TargetValue/100)
+	case RepricingStrategyFoodCost:
+		price = costPrice / (r.TargetValue / 100)
+	default:
+		return 0
+	}
+	price = RoundPrice(price, r.Rounding)
+	if r.MinPrice != nil && price < *r.MinPrice {
+		price = *r.MinPrice
+	}
+	if r.MaxPrice != nil && *r.MaxPrice > 0 && price > *r.MaxPrice {
+		price = *r.MaxPrice
+	}
+	return RoundTo2(price)
+}
+
+// RoundPrice округляет цену вверх по правилу округления
+func RoundPrice(price float64, rounding string) float64 {
+	// Копейки убираем до округления, чтобы погрешность float не поднимала цену на шаг
+	price = RoundTo2(price)
+	switch rounding {
+	case RepricingRounding5:
+		return math.Ceil(price/5) * 5
+	case RepricingRounding10:
+		return math.Ceil(price/10) * 10
+	case RepricingRounding90:
+		return RoundTo2(math.Ceil(price-0.9) + 0.9)
+	}
+	return price
+}
+
+// Статусы предложения об изменении цены
+const (
+	PriceChangeStatusPending    = "pending"    // Ожидает решения владельца
+	PriceChangeStatusApproved   = "approved"   // Одобрено, применится в apply_at
+	PriceChangeStatusApplied    = "applied"    // Цена изменена
+	PriceChangeStatusRejected   = "rejected"   // Отклонено владельцем
+	PriceChangeStatusSuperseded = "superseded" // Заменено более новым предложением
+	PriceChangeStatusStale      = "stale"      // Цена позиции изменилась вручную, предложение не применено
+)
+
+// PriceChange предложение изменить цену позиции по правилу переоценки.
+// Цена меняется только после одобрения владельцем.
+type PriceChange struct {
+	ID                 uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EstablishmentID    uuid.UUID      `json:"establishment_id" gorm:"type:uuid;not null;index"`
+	RuleID             *uuid.UUID     `json:"rule_id,omitempty" gorm:"type:uuid;index"`
+	Rule               *RepricingRule `json:"rule,omitempty" gorm:"foreignKey:RuleID"`
+	TechCardID         *uuid.UUID     `json:"tech_card_id,omitempty" gorm:"type:uuid;index"`
+	TechCard           *TechCard      `json:"tech_card,omitempty" gorm:"foreignKey:TechCardID"`
+	ProductID          *uuid.UUID     `json:"product_id,omitempty" gorm:"type:uuid;index"`
+	Product            *Product       `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	CostPrice          float64        `json:"cost_price"`
+	OldPrice           float64        `json:"old_price"`
+	NewPrice           float64        `json:"new_price"`
+	OldFoodCostPercent float64        `json:"old_food_cost_percent"`
+	NewFoodCostPercent float64        `json:"new_food_cost_percent"`
+	Status             string         `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	ApplyAt            *time.Time     `json:"apply_at,omitempty" gorm:"index"` // Когда применить одобренное изменение
+	DecidedAt          *time.Time     `json:"decided_at,omitempty"`
+	DecidedBy          *uuid.UUID     `json:"decided_by,omitempty" gorm:"type:uuid"`
+	AppliedAt          *time.Time     `json:"applied_at,omitempty"`
+	CreatedAt          time.Time      `json:"created_at" gorm:"index"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID и расчета фудкоста
+func (p *PriceChange) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	if p.Status == "" {
+		p.Status = PriceChangeStatusPending
+	}
+	p.CostPrice = RoundTo2(p.CostPrice)
+	p.OldPrice = RoundTo2(p.OldPrice)
+	p.NewPrice = RoundTo2(p.NewPrice)
+	p.OldFoodCostPercent = FoodCostPercent(p.CostPrice, p.OldPrice)
+	p.NewFoodCostPercent = FoodCostPercent(p.CostPrice, p.NewPrice)
+	return nil
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundPrice(t *testing.T) {
+	assert.Equal(t, 123.46, RoundPrice(123.456, RepricingRoundingNone))
+	assert.Equal(t, 125.0, RoundPrice(121, RepricingRounding5))
+	assert.Equal(t, 120.0, RoundPrice(120, RepricingRounding5))
+	assert.Equal(t, 130.0, RoundPrice(121, RepricingRounding10))
+	assert.Equal(t, 121.9, RoundPrice(121.2, RepricingRounding90))
+	assert.Equal(t, 121.9, RoundPrice(121.9, RepricingRounding90))
+	assert.Equal(t, 122.9, RoundPrice(121.95, RepricingRounding90))
+}
+
+func TestRepricingRuleTargetPrice(t *testing.T) {
+	markup := &RepricingRule{Strategy: RepricingStrategyMarkup, TargetValue: 200, Rounding: RepricingRounding10}
+	assert.Equal(t, 310.0, markup.TargetPrice(101)) // 303 → 310
+
+	foodCost := &RepricingRule{Strategy: RepricingStrategyFoodCost, TargetValue: 30, Rounding: RepricingRounding90}
+	assert.Equal(t, 333.9, foodCost.TargetPrice(100)) // 333.33 → 333.90
+
+	minPrice, maxPrice := 150.0, 300.0
+	bounded := &RepricingRule{Strategy: RepricingStrategyMarkup, TargetValue: 100, MinPrice: &minPrice, MaxPrice: &maxPrice}
+	assert.Equal(t, 150.0, bounded.TargetPrice(50))
+	assert.Equal(t, 300.0, bounded.TargetPrice(200))
+
+	assert.Equal(t, 0.0, markup.TargetPrice(0), "no cost price")
+}
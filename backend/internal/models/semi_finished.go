@@ -19,6 +19,7 @@ type SemiFinishedProduct struct {
 	Name            string                   `json:"name" gorm:"not null"`
 	Description     string                   `json:"description"`
 	CookingProcess  string                   `json:"cooking_process"`
+	ExternalCode    string                   `json:"external_code" gorm:"index"` // Код во внешней системе (для импорта и выгрузки меню)
 
 	// Изображение
 	CoverImage      string                   `json:"cover_image"`
@@ -52,10 +53,11 @@ type SemiFinishedIngredient struct {
 	SemiFinished      *SemiFinishedProduct `json:"semi_finished,omitempty" gorm:"foreignKey:SemiFinishedID"`
 	IngredientID      uuid.UUID    `json:"ingredient_id" gorm:"type:uuid;not null"`
 	Ingredient        *Ingredient  `json:"ingredient,omitempty" gorm:"foreignKey:IngredientID"`
-	PreparationMethod *string     `json:"preparation_method"` // cleaning, boiling, frying, stewing, baking
+	PreparationMethod *string     `json:"preparation_method"` // cleaning, boiling, frying, stewing, baking; несколько через запятую применяются последовательно
 	Gross             float64      `json:"gross" gorm:"not null"` // Брутто
 	Net               float64      `json:"net" gorm:"not null"` // Нетто
 	Unit              string       `json:"unit" gorm:"not null"` // г, мл, шт
+	LossPercent       float64      `json:"loss_percent" gorm:"-"` // Суммарный процент потерь (рассчитывается)
 	CreatedAt         time.Time    `json:"created_at"`
 	UpdatedAt         time.Time    `json:"updated_at"`
 }
@@ -67,3 +69,42 @@ func (sfi *SemiFinishedIngredient) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// ApplyLosses пересчитывает брутто по нетто с учетом потерь способов приготовления ингредиента.
+// Если нетто не задано, оно рассчитывается из брутто. Ингредиент должен быть загружен.
+func (sfi *SemiFinishedIngredient) ApplyLosses() error {
+	methods, err := ParsePreparationMethods(sfi.PreparationMethod)
+	if err != nil {
+		return err
+	}
+	if sfi.Ingredient == nil {
+		if sfi.Gross == 0 {
+			sfi.Gross = sfi.Net
+		}
+		return nil
+	}
+	ratio := sfi.Ingredient.YieldRatio(methods)
+	sfi.LossPercent = RoundTo2((1 - ratio) * 100)
+	switch {
+	case sfi.Net > 0 && len(methods) > 0:
+		sfi.Gross = sfi.Ingredient.GrossFromNet(sfi.Net, methods)
+	case sfi.Net == 0 && sfi.Gross > 0:
+		sfi.Net = sfi.Ingredient.NetFromGross(sfi.Gross, methods)
+	case sfi.Gross == 0:
+		sfi.Gross = sfi.Net
+	}
+	return nil
+}
+
+// GrossQuantity возвращает количество брутто, которое списывается со склада
+func (sfi *SemiFinishedIngredient) GrossQuantity() float64 {
+	if sfi.Ingredient != nil && sfi.Net > 0 {
+		if methods, err := ParsePreparationMethods(sfi.PreparationMethod); err == nil && len(methods) > 0 {
+			return sfi.Ingredient.GrossFromNet(sfi.Net, methods)
+		}
+	}
+	if sfi.Gross > 0 {
+		return sfi.Gross
+	}
+	return sfi.Net
+}
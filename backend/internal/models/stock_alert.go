@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StockAlertStatus статус уведомления о низком остатке
+type StockAlertStatus string
+
+const (
+	StockAlertStatusOpen         StockAlertStatus = "open"         // Остаток ниже лимита, уведомление не просмотрено
+	StockAlertStatusAcknowledged StockAlertStatus = "acknowledged" // Уведомление просмотрено менеджером
+	StockAlertStatusResolved     StockAlertStatus = "resolved"     // Остаток восстановлен до лимита
+)
+
+// StockAlert уведомление о том, что остаток опустился ниже лимита (Stock.Limit).
+// Для одного остатка одновременно существует не более одного активного (open/acknowledged) уведомления.
+type StockAlert struct {
+	ID              uuid.UUID            `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EstablishmentID uuid.UUID            `json:"establishment_id" gorm:"type:uuid;not null;index"`
+	WarehouseID     uuid.UUID            `json:"warehouse_id" gorm:"type:uuid;not null;index"`
+	Warehouse       *Warehouse           `json:"warehouse,omitempty" gorm:"foreignKey:WarehouseID"`
+	StockID         uuid.UUID            `json:"stock_id" gorm:"type:uuid;not null;index"`
+	IngredientID    *uuid.UUID           `json:"ingredient_id,omitempty" gorm:"type:uuid;index"`
+	Ingredient      *Ingredient          `json:"ingredient,omitempty" gorm:"foreignKey:IngredientID"`
+	ProductID       *uuid.UUID           `json:"product_id,omitempty" gorm:"type:uuid;index"`
+	Product         *Product             `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	SemiFinishedID  *uuid.UUID           `json:"semi_finished_id,omitempty" gorm:"type:uuid;index"`
+	SemiFinished    *SemiFinishedProduct `json:"semi_finished,omitempty" gorm:"foreignKey:SemiFinishedID"`
+	Quantity        float64              `json:"quantity"` // Остаток на момент срабатывания
+	Limit           float64              `json:"limit"`    // Лимит на момент срабатывания
+	Unit            string               `json:"unit"`
+	Status          StockAlertStatus     `json:"status" gorm:"type:varchar(20);not null;default:'open';index"`
+	AcknowledgedAt  *time.Time           `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy  *uuid.UUID           `json:"acknowledged_by,omitempty" gorm:"type:uuid"`
+	ResolvedAt      *time.Time           `json:"resolved_at,omitempty"`
+	CreatedAt       time.Time            `json:"created_at" gorm:"index"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID и округления значений
+func (a *StockAlert) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	if a.Status == "" {
+		a.Status = StockAlertStatusOpen
+	}
+	a.Quantity = RoundTo2(a.Quantity)
+	a.Limit = RoundTo2(a.Limit)
+	return nil
+}
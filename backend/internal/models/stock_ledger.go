@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Типы движений журнала остатков
+const (
+	StockLedgerOpening       = "opening"        // Начальный остаток (заведен до ведения журнала или при создании позиции)
+	StockLedgerSupply        = "supply"         // Поставка
+	StockLedgerSale          = "sale"           // Продажа (списание по заказу)
+	StockLedgerWriteOff      = "write_off"      // Списание
+	StockLedgerTransferOut   = "transfer_out"   // Перемещение: расход со склада-отправителя
+	StockLedgerTransferIn    = "transfer_in"    // Перемещение: приход на склад-получатель
+	StockLedgerProductionOut = "production_out" // Производство: расход ингредиентов
+	StockLedgerProductionIn  = "production_in"  // Производство: выход полуфабриката
+	StockLedgerInventory     = "inventory"      // Корректировка по инвентаризации
+)
+
+// ErrStockLedgerImmutable возвращается при попытке изменить или удалить запись журнала остатков
+var ErrStockLedgerImmutable = errors.New("stock ledger entries are append-only")
+
+// StockLedgerEntry запись журнала движения остатков. Журнал только дополняется:
+// исправления оформляются новыми записями, а остаток на любой момент равен сумме
+// Quantity по записям позиции склада с OccurredAt не позже этого момента.
+type StockLedgerEntry struct {
+	ID             uuid.UUID            `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	WarehouseID    uuid.UUID            `json:"warehouse_id" gorm:"type:uuid;not null;index"`
+	Warehouse      *Warehouse           `json:"warehouse,omitempty" gorm:"foreignKey:WarehouseID"`
+	StockID        uuid.UUID            `json:"stock_id" gorm:"type:uuid;not null;index"`
+	IngredientID   *uuid.UUID           `json:"ingredient_id,omitempty" gorm:"type:uuid;index"`
+	Ingredient     *Ingredient          `json:"ingredient,omitempty" gorm:"foreignKey:IngredientID"`
+	ProductID      *uuid.UUID           `json:"product_id,omitempty" gorm:"type:uuid;index"`
+	Product        *Product             `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	SemiFinishedID *uuid.UUID           `json:"semi_finished_id,omitempty" gorm:"type:uuid;index"`
+	SemiFinished   *SemiFinishedProduct `json:"semi_finished,omitempty" gorm:"foreignKey:SemiFinishedID"`
+	MovementType   string               `json:"movement_type" gorm:"type:varchar(20);not null;index"`
+	SourceID       *uuid.UUID           `json:"source_id,omitempty" gorm:"type:uuid;index"` // Документ-источник (поставка, заказ, списание, перемещение, производство, инвентаризация)
+	Quantity       float64              `json:"quantity" gorm:"not null"`                   // Изменение остатка со знаком, в единице остатка
+	Unit           string               `json:"unit" gorm:"not null"`
+	UnitCost       float64              `json:"unit_cost"`                         // Себестоимость единицы движения
+	Amount         float64              `json:"amount"`                            // Стоимость движения со знаком
+	BalanceAfter   float64              `json:"balance_after" gorm:"not null"`     // Остаток позиции на складе после записи (при чтении журнала пересчитывается)
+	OccurredAt     time.Time            `json:"occurred_at" gorm:"not null;index"` // Момент движения (дата документа)
+	CreatedAt      time.Time            `json:"created_at" gorm:"index"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID и округления значений
+func (e *StockLedgerEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	e.Quantity = RoundTo2(e.Quantity)
+	e.UnitCost = RoundTo2(e.UnitCost)
+	e.Amount = RoundTo2(e.Amount)
+	e.BalanceAfter = RoundTo2(e.BalanceAfter)
+	return nil
+}
+
+// BeforeUpdate запрещает изменение записей журнала
+func (e *StockLedgerEntry) BeforeUpdate(tx *gorm.DB) error {
+	return ErrStockLedgerImmutable
+}
+
+// BeforeDelete запрещает удаление записей журнала
+func (e *StockLedgerEntry) BeforeDelete(tx *gorm.DB) error {
+	return ErrStockLedgerImmutable
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Источники поступления партии
+const (
+	StockLotSourceSupply     = "supply"     // Поставка
+	StockLotSourceTransfer   = "transfer"   // Перемещение с другого склада
+	StockLotSourceProduction = "production" // Производство полуфабриката
+	StockLotSourceInventory  = "inventory"  // Излишки по инвентаризации
+)
+
+// Документы, расходующие партии
+const (
+	StockConsumptionSale       = "sale"       // Продажа (списание по заказу)
+	StockConsumptionWriteOff   = "write_off"  // Списание
+	StockConsumptionTransfer   = "transfer"   // Перемещение на другой склад
+	StockConsumptionProduction = "production" // Расход ингредиентов на производство полуфабриката
+	StockConsumptionInventory  = "inventory"  // Недостача по инвентаризации
+)
+
+// StockLot представляет партию (слой себестоимости) ингредиента, товара или полуфабриката на складе.
+// Партии расходуются по FEFO — сначала с ближайшим сроком годности, затем (и без срока) по FIFO —
+// самые ранние по дате поступления.
+type StockLot struct {
+	ID                uuid.UUID            `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	WarehouseID       uuid.UUID            `json:"warehouse_id" gorm:"type:uuid;not null;index"`
+	Warehouse         *Warehouse           `json:"warehouse,omitempty" gorm:"foreignKey:WarehouseID"`
+	IngredientID      *uuid.UUID           `json:"ingredient_id,omitempty" gorm:"type:uuid;index"`
+	Ingredient        *Ingredient          `json:"ingredient,omitempty" gorm:"foreignKey:IngredientID"`
+	ProductID         *uuid.UUID           `json:"product_id,omitempty" gorm:"type:uuid;index"`
+	Product           *Product             `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	SemiFinishedID    *uuid.UUID           `json:"semi_finished_id,omitempty" gorm:"type:uuid;index"`
+	SemiFinished      *SemiFinishedProduct `json:"semi_finished,omitempty" gorm:"foreignKey:SemiFinishedID"`
+	SourceType        string               `json:"source_type" gorm:"type:varchar(20);not null"` // supply, transfer, production
+	SourceID          *uuid.UUID           `json:"source_id,omitempty" gorm:"type:uuid;index"`   // ID документа-источника
+	SupplyItemID      *uuid.UUID           `json:"supply_item_id,omitempty" gorm:"type:uuid;index"`
+	Quantity          float64              `json:"quantity" gorm:"not null"`           // Поступившее количество
+	RemainingQuantity float64              `json:"remaining_quantity" gorm:"not null"` // Нерасходованный остаток партии
+	Unit              string               `json:"unit" gorm:"not null"`
+	UnitCost          float64              `json:"unit_cost" gorm:"default:0"` // Себестоимость единицы
+	ReceivedAt        time.Time            `json:"received_at" gorm:"not null;index"`
+	ExpiresAt         *time.Time           `json:"expires_at,omitempty" gorm:"index"` // Годен до (пусто — срок не отслеживается)
+	CreatedAt         time.Time            `json:"created_at"`
+	UpdatedAt         time.Time            `json:"updated_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID и округления значений
+func (l *StockLot) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	l.Quantity = RoundTo2(l.Quantity)
+	l.RemainingQuantity = RoundTo2(l.RemainingQuantity)
+	l.UnitCost = RoundTo2(l.UnitCost)
+	return nil
+}
+
+// BeforeUpdate hook для округления значений перед обновлением
+func (l *StockLot) BeforeUpdate(tx *gorm.DB) error {
+	l.Quantity = RoundTo2(l.Quantity)
+	l.RemainingQuantity = RoundTo2(l.RemainingQuantity)
+	l.UnitCost = RoundTo2(l.UnitCost)
+	return nil
+}
+
+// IsExpired сообщает, что срок годности партии истек к моменту at
+func (l *StockLot) IsExpired(at time.Time) bool {
+	return l.ExpiresAt != nil && !l.ExpiresAt.After(at)
+}
+
+// StockLotExpiry определяет срок годности партии: явная дата «годен до», иначе
+// дата производства (или поступления) плюс срок годности в днях. Возвращает nil, если срок не отслеживается.
+func StockLotExpiry(expiryDate, productionDate *time.Time, receivedAt time.Time, shelfLifeDays int) *time.Time {
+	if expiryDate != nil {
+		t := *expiryDate
+		return &t
+	}
+	if shelfLifeDays <= 0 {
+		return nil
+	}
+	base := receivedAt
+	if productionDate != nil {
+		base = *productionDate
+	}
+	t := base.AddDate(0, 0, shelfLifeDays)
+	return &t
+}
+
+// StockLotConsumption фиксирует расход партии документом (продажа, списание, перемещение, производство)
+// и фактическую себестоимость израсходованного количества.
+// LotID пуст, если остаток не был покрыт партиями (например, ушел в минус) и оценен по цене остатка.
+type StockLotConsumption struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	LotID          *uuid.UUID `json:"lot_id,omitempty" gorm:"type:uuid;index"`
+	Lot            *StockLot  `json:"lot,omitempty" gorm:"foreignKey:LotID"`
+	WarehouseID    uuid.UUID  `json:"warehouse_id" gorm:"type:uuid;not null;index"`
+	IngredientID   *uuid.UUID `json:"ingredient_id,omitempty" gorm:"type:uuid;index"`
+	ProductID      *uuid.UUID `json:"product_id,omitempty" gorm:"type:uuid;index"`
+	SemiFinishedID *uuid.UUID `json:"semi_finished_id,omitempty" gorm:"type:uuid;index"`
+	DocumentType   string     `json:"document_type" gorm:"type:varchar(20);not null;index"` // sale, write_off, transfer, production
+	DocumentID     uuid.UUID  `json:"document_id" gorm:"type:uuid;not null;index"`
+	Quantity       float64    `json:"quantity" gorm:"not null"`
+	UnitCost       float64    `json:"unit_cost" gorm:"default:0"`
+	TotalCost      float64    `json:"total_cost" gorm:"default:0"`
+	ConsumedAt     time.Time  `json:"consumed_at" gorm:"not null;index"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID и округления значений
+func (c *StockLotConsumption) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	c.Quantity = RoundTo2(c.Quantity)
+	c.UnitCost = RoundTo2(c.UnitCost)
+	c.TotalCost = RoundTo2(c.TotalCost)
+	return nil
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStockLotExpiry(t *testing.T) {
+	received := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	produced := time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)
+	explicit := time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)
+
+	assert.Nil(t, StockLotExpiry(nil, nil, received, 0), "shelf life not tracked")
+	assert.Equal(t, explicit, *StockLotExpiry(&explicit, &produced, received, 5), "explicit date wins")
+	assert.Equal(t, produced.AddDate(0, 0, 5), *StockLotExpiry(nil, &produced, received, 5))
+	assert.Equal(t, received.AddDate(0, 0, 5), *StockLotExpiry(nil, nil, received, 5))
+}
+
+func TestStockLotIsExpired(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	assert.False(t, (&StockLot{}).IsExpired(now))
+	assert.True(t, (&StockLot{ExpiresAt: &past}).IsExpired(now))
+	assert.False(t, (&StockLot{ExpiresAt: &future}).IsExpired(now))
+}
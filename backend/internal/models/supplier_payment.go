@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Статусы оплаты поставки (Supply.PaymentStatus)
+const (
+	SupplyPaymentStatusNone    = "none"    // Оплата не требуется / не указана
+	SupplyPaymentStatusPending = "pending" // Ожидает оплаты
+	SupplyPaymentStatusPartial = "partial" // Оплачена частично
+	SupplyPaymentStatusPaid    = "paid"    // Оплачена полностью
+)
+
+// SupplierPayment платеж поставщику. Один платеж может закрывать несколько поставок,
+// а одна поставка может оплачиваться несколькими платежами (SupplierPaymentAllocation).
+// Часть суммы, не распределенная по поставкам, считается авансом поставщику.
+type SupplierPayment struct {
+	ID              uuid.UUID                   `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EstablishmentID uuid.UUID                   `json:"establishment_id" gorm:"type:uuid;not null;index"`
+	SupplierID      uuid.UUID                   `json:"supplier_id" gorm:"type:uuid;not null;index"`
+	Supplier        *Supplier                   `json:"supplier,omitempty" gorm:"foreignKey:SupplierID"`
+	AccountID       uuid.UUID                   `json:"account_id" gorm:"type:uuid;not null;index"` // Счет, с которого оплачено
+	Account         *Account                    `json:"account,omitempty" gorm:"foreignKey:AccountID"`
+	TransactionID   *uuid.UUID                  `json:"transaction_id,omitempty" gorm:"type:uuid;index"` // Расходная транзакция по счету
+	Amount          float64                     `json:"amount" gorm:"not null"`
+	PaymentDate     time.Time                   `json:"payment_date" gorm:"not null;index"`
+	Comment         string                      `json:"comment"`
+	CreatedBy       *uuid.UUID                  `json:"created_by,omitempty" gorm:"type:uuid"`
+	Allocations     []SupplierPaymentAllocation `json:"allocations,omitempty" gorm:"foreignKey:PaymentID"`
+	CreatedAt       time.Time                   `json:"created_at" gorm:"index"`
+	UpdatedAt       time.Time                   `json:"updated_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID и округления значений
+func (p *SupplierPayment) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	p.Amount = RoundTo2(p.Amount)
+	return nil
+}
+
+// AllocatedAmount сумма, распределенная по поставкам
+func (p *SupplierPayment) AllocatedAmount() float64 {
+	total := 0.0
+	for _, a := range p.Allocations {
+		total += a.Amount
+	}
+	return RoundTo2(total)
+}
+
+// UnallocatedAmount нераспределенный остаток платежа (аванс поставщику)
+func (p *SupplierPayment) UnallocatedAmount() float64 {
+	return RoundTo2(p.Amount - p.AllocatedAmount())
+}
+
+// SupplierPaymentAllocation часть платежа, отнесенная на конкретную поставку
+type SupplierPaymentAllocation struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	PaymentID uuid.UUID `json:"payment_id" gorm:"type:uuid;not null;index"`
+	SupplyID  uuid.UUID `json:"supply_id" gorm:"type:uuid;not null;index"`
+	Supply    *Supply   `json:"supply,omitempty" gorm:"foreignKey:SupplyID"`
+	Amount    float64   `json:"amount" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID и округления значений
+func (a *SupplierPaymentAllocation) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	a.Amount = RoundTo2(a.Amount)
+	return nil
+}
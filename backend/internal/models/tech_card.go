@@ -18,6 +18,7 @@ type TechCard struct {
 	Workshop      *Workshop          `json:"workshop,omitempty" gorm:"foreignKey:WorkshopID"`
 	Name          string             `json:"name" gorm:"not null"`
 	Description   string             `json:"description"`
+	ExternalCode  string             `json:"external_code" gorm:"index"` // Код во внешней системе (для импорта и выгрузки меню)
 	
 	// Изображение
 	CoverImage    string             `json:"cover_image"` // URL к обложке тех-карты
@@ -31,6 +32,14 @@ type TechCard struct {
 	Markup      float64        `json:"markup" gorm:"default:0"`                      // Наценка (%)
 	Price       float64        `json:"price" gorm:"not null"`                        // Итоговая цена (себестоимость + наценка)
 	
+	// Пищевая ценность порции и аллергены по ингредиентам (рассчитываются, не хранятся)
+	Nutrition     *NutritionFacts    `json:"nutrition,omitempty" gorm:"-"`
+	Allergens     AllergenList       `json:"allergens" gorm:"-"`
+	
+	// Расписание доступности (если не задано — действует расписание категории)
+	AvailabilityScheduleID *uuid.UUID            `json:"availability_schedule_id,omitempty" gorm:"type:uuid;index"`
+	AvailabilitySchedule   *AvailabilitySchedule `json:"availability_schedule,omitempty" gorm:"foreignKey:AvailabilityScheduleID"`
+	
 	Active        bool               `json:"active" gorm:"default:true;index"`
 	Ingredients   []TechCardIngredient `json:"ingredients,omitempty" gorm:"foreignKey:TechCardID"`
 	ModifierSets  []ModifierSet      `json:"modifier_sets,omitempty" gorm:"foreignKey:TechCardID"`
@@ -60,14 +69,23 @@ func (tc *TechCard) BeforeUpdate(tx *gorm.DB) error {
 	return nil
 }
 
-// TechCardIngredient представляет связь тех-карты с ингредиентами
+// TechCardIngredient представляет связь тех-карты с ингредиентами.
+// Позиция ссылается либо на ингредиент, либо на полуфабрикат (соус, основа и т.п.)
 type TechCardIngredient struct {
-	ID           uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	TechCardID   uuid.UUID `json:"tech_card_id" gorm:"type:uuid;not null"`
-	IngredientID uuid.UUID `json:"ingredient_id" gorm:"type:uuid;not null"`
-	Ingredient   *Ingredient `json:"ingredient,omitempty" gorm:"foreignKey:IngredientID"`
-	Quantity     float64   `json:"quantity" gorm:"not null"`
+	ID             uuid.UUID            `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	TechCardID     uuid.UUID            `json:"tech_card_id" gorm:"type:uuid;not null"`
+	IngredientID   *uuid.UUID           `json:"ingredient_id,omitempty" gorm:"type:uuid"`
+	Ingredient     *Ingredient          `json:"ingredient,omitempty" gorm:"foreignKey:IngredientID"`
+	SemiFinishedID *uuid.UUID           `json:"semi_finished_id,omitempty" gorm:"type:uuid;index"`
+	SemiFinished   *SemiFinishedProduct `json:"semi_finished,omitempty" gorm:"foreignKey:SemiFinishedID"`
+	Quantity     float64   `json:"quantity" gorm:"not null"` // Количество нетто (в готовом блюде)
 	Unit         string    `json:"unit" gorm:"not null"` // кг, л, шт и т.д.
+	// Способы приготовления через запятую (cleaning, boiling, frying, stewing, baking), потери применяются последовательно
+	PreparationMethod *string `json:"preparation_method,omitempty"`
+	// Брутто/нетто с учетом потерь (рассчитываются, не хранятся)
+	Gross        float64   `json:"gross" gorm:"-"`
+	Net          float64   `json:"net" gorm:"-"`
+	LossPercent  float64   `json:"loss_percent" gorm:"-"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -89,6 +107,29 @@ func (tci *TechCardIngredient) BeforeUpdate(tx *gorm.DB) error {
 	return nil
 }
 
+// GrossQuantity возвращает количество брутто: нетто, увеличенное на потери способов приготовления.
+// Для полуфабрикатов и позиций без загруженного ингредиента брутто равно нетто.
+func (tci *TechCardIngredient) GrossQuantity() float64 {
+	if tci.Ingredient == nil {
+		return tci.Quantity
+	}
+	methods, err := ParsePreparationMethods(tci.PreparationMethod)
+	if err != nil || len(methods) == 0 {
+		return tci.Quantity
+	}
+	return tci.Ingredient.GrossFromNet(tci.Quantity, methods)
+}
+
+// FillGrossNet заполняет расчетные поля брутто/нетто и процент потерь
+func (tci *TechCardIngredient) FillGrossNet() {
+	tci.Net = tci.Quantity
+	tci.Gross = tci.GrossQuantity()
+	tci.LossPercent = 0
+	if tci.Gross > 0 {
+		tci.LossPercent = RoundTo2((1 - tci.Net/tci.Gross) * 100)
+	}
+}
+
 // CalculatePrice вычисляет цену на основе себестоимости и наценки
 func (tc *TechCard) CalculatePrice() {
 	if tc.Markup > 0 {
@@ -0,0 +1,215 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Единицы измерения
+const (
+	UnitPiece      = "шт"
+	UnitKilogram   = "кг"
+	UnitGram       = "г"
+	UnitLiter      = "л"
+	UnitMilliliter = "мл"
+)
+
+// UnitDimension измерение, к которому относится единица (масса, объем, штуки)
+type UnitDimension string
+
+const (
+	UnitDimensionMass   UnitDimension = "mass"
+	UnitDimensionVolume UnitDimension = "volume"
+	UnitDimensionCount  UnitDimension = "count"
+)
+
+var (
+	ErrUnknownUnit       = errors.New("unknown unit")
+	ErrIncompatibleUnits = errors.New("incompatible units")
+)
+
+// unitDefinition описывает единицу: измерение и множитель к базовой единице измерения
+type unitDefinition struct {
+	dimension UnitDimension
+	factor    float64
+}
+
+// Базовые единицы: кг для массы, л для объема, шт для штук
+var unitDefinitions = map[string]unitDefinition{
+	UnitKilogram:   {UnitDimensionMass, 1},
+	UnitGram:       {UnitDimensionMass, 0.001},
+	UnitLiter:      {UnitDimensionVolume, 1},
+	UnitMilliliter: {UnitDimensionVolume, 0.001},
+	UnitPiece:      {UnitDimensionCount, 1},
+}
+
+// Синонимы единиц (в т.ч. значения, которые сохраняются для полуфабрикатов: kg, gram, liter, ml, piece)
+var unitAliases = map[string]string{
+	"kg":    UnitKilogram,
+	"г.":    UnitGram,
+	"гр":    UnitGram,
+	"g":     UnitGram,
+	"gram":  UnitGram,
+	"l":     UnitLiter,
+	"liter": UnitLiter,
+	"ml":    UnitMilliliter,
+	"шт.":   UnitPiece,
+	"pcs":   UnitPiece,
+	"piece": UnitPiece,
+}
+
+// ValidUnits возвращает список поддерживаемых единиц измерения
+func ValidUnits() []string {
+	return []string{UnitPiece, UnitKilogram, UnitGram, UnitLiter, UnitMilliliter}
+}
+
+// NormalizeUnit приводит единицу к каноническому виду (kg → кг, gram → г и т.д.)
+func NormalizeUnit(unit string) string {
+	u := strings.ToLower(strings.TrimSpace(unit))
+	if canonical, ok := unitAliases[u]; ok {
+		return canonical
+	}
+	return u
+}
+
+// IsValidUnit проверяет, поддерживается ли единица измерения
+func IsValidUnit(unit string) bool {
+	_, ok := unitDefinitions[NormalizeUnit(unit)]
+	return ok
+}
+
+// GetUnitDimension возвращает измерение единицы
+func GetUnitDimension(unit string) (UnitDimension, error) {
+	def, ok := unitDefinitions[NormalizeUnit(unit)]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownUnit, unit)
+	}
+	return def.dimension, nil
+}
+
+// BaseUnit возвращает базовую единицу для измерения единицы (г → кг, мл → л)
+func BaseUnit(unit string) (string, error) {
+	dimension, err := GetUnitDimension(unit)
+	if err != nil {
+		return "", err
+	}
+	switch dimension {
+	case UnitDimensionMass:
+		return UnitKilogram, nil
+	case UnitDimensionVolume:
+		return UnitLiter, nil
+	default:
+		return UnitPiece, nil
+	}
+}
+
+// ConvertUnit переводит количество между единицами одного измерения (г ↔ кг, мл ↔ л)
+func ConvertUnit(quantity float64, from, to string) (float64, error) {
+	fromDef, ok := unitDefinitions[NormalizeUnit(from)]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownUnit, from)
+	}
+	toDef, ok := unitDefinitions[NormalizeUnit(to)]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownUnit, to)
+	}
+	if fromDef.dimension != toDef.dimension {
+		return 0, fmt.Errorf("%w: cannot convert %s to %s", ErrIncompatibleUnits, from, to)
+	}
+	return quantity * fromDef.factor / toDef.factor, nil
+}
+
+// ConvertIngredientUnit переводит количество ингредиента между единицами.
+// Единицы одного измерения конвертируются напрямую, разных — через пользовательские
+// пересчеты ингредиента (например, 1 шт яйца = 0.05 кг).
+func ConvertIngredientUnit(quantity float64, from, to string, conversions []IngredientUnitConversion) (float64, error) {
+	fromDim, err := GetUnitDimension(from)
+	if err != nil {
+		return 0, err
+	}
+	toDim, err := GetUnitDimension(to)
+	if err != nil {
+		return 0, err
+	}
+	if fromDim == toDim {
+		return ConvertUnit(quantity, from, to)
+	}
+
+	for _, c := range conversions {
+		if c.Factor <= 0 {
+			continue
+		}
+		unitDim, err := GetUnitDimension(c.Unit)
+		if err != nil {
+			continue
+		}
+		targetDim, err := GetUnitDimension(c.TargetUnit)
+		if err != nil {
+			continue
+		}
+		switch {
+		case unitDim == fromDim && targetDim == toDim:
+			// from → c.Unit → c.TargetUnit → to
+			q, err := ConvertUnit(quantity, from, c.Unit)
+			if err != nil {
+				return 0, err
+			}
+			return ConvertUnit(q*c.Factor, c.TargetUnit, to)
+		case unitDim == toDim && targetDim == fromDim:
+			// from → c.TargetUnit → c.Unit → to
+			q, err := ConvertUnit(quantity, from, c.TargetUnit)
+			if err != nil {
+				return 0, err
+			}
+			return ConvertUnit(q/c.Factor, c.Unit, to)
+		}
+	}
+
+	return 0, fmt.Errorf("%w: cannot convert %s to %s, add a unit conversion for the ingredient", ErrIncompatibleUnits, from, to)
+}
+
+// IngredientUnitConversion пользовательский пересчет единиц ингредиента:
+// 1 Unit = Factor TargetUnit (например, 1 шт = 0.05 кг для яиц)
+type IngredientUnitConversion struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	IngredientID uuid.UUID `json:"ingredient_id" gorm:"type:uuid;not null;index"`
+	Unit         string    `json:"unit" gorm:"not null"`
+	Factor       float64   `json:"factor" gorm:"not null"`
+	TargetUnit   string    `json:"target_unit" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID и нормализации единиц
+func (c *IngredientUnitConversion) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	c.Unit = NormalizeUnit(c.Unit)
+	c.TargetUnit = NormalizeUnit(c.TargetUnit)
+	return nil
+}
+
+// Validate проверяет корректность пересчета
+func (c *IngredientUnitConversion) Validate() error {
+	if c.Factor <= 0 {
+		return errors.New("conversion factor must be positive")
+	}
+	unitDim, err := GetUnitDimension(c.Unit)
+	if err != nil {
+		return err
+	}
+	targetDim, err := GetUnitDimension(c.TargetUnit)
+	if err != nil {
+		return err
+	}
+	if unitDim == targetDim {
+		return fmt.Errorf("%w: conversion between %s and %s is built in", ErrIncompatibleUnits, c.Unit, c.TargetUnit)
+	}
+	return nil
+}
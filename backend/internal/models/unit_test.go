@@ -0,0 +1,88 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertUnit(t *testing.T) {
+	tests := []struct {
+		name     string
+		quantity float64
+		from     string
+		to       string
+		want     float64
+		wantErr  error
+	}{
+		{name: "grams to kilograms", quantity: 500, from: UnitGram, to: UnitKilogram, want: 0.5},
+		{name: "kilograms to grams", quantity: 1.2, from: UnitKilogram, to: UnitGram, want: 1200},
+		{name: "milliliters to liters", quantity: 250, from: UnitMilliliter, to: UnitLiter, want: 0.25},
+		{name: "alias kg", quantity: 2, from: "kg", to: UnitGram, want: 2000},
+		{name: "same unit", quantity: 3, from: UnitPiece, to: UnitPiece, want: 3},
+		{name: "mass to volume", quantity: 1, from: UnitKilogram, to: UnitLiter, wantErr: ErrIncompatibleUnits},
+		{name: "unknown unit", quantity: 1, from: "ящик", to: UnitKilogram, wantErr: ErrUnknownUnit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertUnit(tt.quantity, tt.from, tt.to)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr))
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+func TestConvertIngredientUnit(t *testing.T) {
+	// 1 шт яйца = 0.05 кг
+	egg := &Ingredient{
+		Unit: UnitKilogram,
+		UnitConversions: []IngredientUnitConversion{
+			{Unit: UnitPiece, Factor: 0.05, TargetUnit: UnitKilogram},
+		},
+	}
+
+	got, err := egg.ConvertQuantity(4, UnitPiece, UnitKilogram)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.2, got, 1e-9)
+
+	got, err = egg.ConvertQuantity(4, UnitPiece, UnitGram)
+	assert.NoError(t, err)
+	assert.InDelta(t, 200, got, 1e-9)
+
+	got, err = egg.ConvertQuantity(100, UnitGram, UnitPiece)
+	assert.NoError(t, err)
+	assert.InDelta(t, 2, got, 1e-9)
+
+	_, err = egg.ConvertQuantity(1, UnitLiter, UnitKilogram)
+	assert.True(t, errors.Is(err, ErrIncompatibleUnits))
+}
+
+func TestIngredientStackedLosses(t *testing.T) {
+	potato := &Ingredient{LossCleaning: 20, LossBoiling: 10}
+	methods := []string{PreparationCleaning, PreparationBoiling}
+
+	assert.InDelta(t, 0.72, potato.YieldRatio(methods), 1e-9)
+	assert.InDelta(t, 100, potato.GrossFromNet(72, methods), 1e-9)
+	assert.InDelta(t, 72, potato.NetFromGross(100, methods), 1e-9)
+
+	value := "cleaning, boiling"
+	parsed, err := ParsePreparationMethods(&value)
+	assert.NoError(t, err)
+	assert.Equal(t, methods, parsed)
+
+	invalid := "grilling"
+	_, err = ParsePreparationMethods(&invalid)
+	assert.Error(t, err)
+
+	line := &TechCardIngredient{Ingredient: potato, Quantity: 0.36, PreparationMethod: &value}
+	line.FillGrossNet()
+	assert.InDelta(t, 0.5, line.Gross, 1e-9)
+	assert.InDelta(t, 0.36, line.Net, 1e-9)
+	assert.InDelta(t, 28, line.LossPercent, 1e-9)
+}
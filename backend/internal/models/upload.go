@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Типы владельцев загруженного изображения: позиции, у которых есть CoverImage
+const (
+	UploadEntityProduct      = "product"
+	UploadEntityTechCard     = "tech_card"
+	UploadEntitySemiFinished = "semi_finished"
+	UploadEntityCombo        = "combo"
+)
+
+// IsValidUploadEntity проверяет тип владельца изображения
+func IsValidUploadEntity(entityType string) bool {
+	switch entityType {
+	case UploadEntityProduct, UploadEntityTechCard, UploadEntitySemiFinished, UploadEntityCombo:
+		return true
+	}
+	return false
+}
+
+// Upload загруженное изображение: обработанный оригинал и уменьшенные варианты в хранилище.
+// Все объекты загрузки лежат под ObjectPrefix. Изображение, на которое не ссылается ни один CoverImage,
+// удаляется фоновой очисткой вместе с объектами
+type Upload struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EntityType   string     `json:"entity_type,omitempty" gorm:"index:idx_upload_entity"` // product, tech_card, semi_finished, combo
+	EntityID     *uuid.UUID `json:"entity_id,omitempty" gorm:"type:uuid;index:idx_upload_entity"`
+	UploadedBy   *uuid.UUID `json:"uploaded_by,omitempty" gorm:"type:uuid"`
+	ObjectPrefix string     `json:"-" gorm:"not null"`
+	ContentType  string     `json:"content_type"` // Тип сохраненного оригинала
+	Size         int64      `json:"size"`         // Размер сохраненного оригинала, байт
+	Width        int        `json:"width"`
+	Height       int        `json:"height"`
+	URL          string     `json:"url" gorm:"not null;uniqueIndex"` // Оригинал без метаданных
+	MediumURL    string     `json:"medium_url,omitempty"`
+	ThumbURL     string     `json:"thumb_url,omitempty"`
+	WebPURL      string     `json:"webp_url,omitempty" gorm:"column:webp_url"`
+	ReferencedAt *time.Time `json:"referenced_at,omitempty"` // Когда очистка последний раз нашла ссылку на изображение
+	CreatedAt    time.Time  `json:"created_at" gorm:"index"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID
+func (u *Upload) BeforeCreate(tx *gorm.DB) error {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return nil
+}
+
+// URLs возвращает все непустые URL загрузки: ссылка на любой из них удерживает изображение
+func (u *Upload) URLs() []string {
+	urls := make([]string, 0, 4)
+	for _, url := range []string{u.URL, u.MediumURL, u.ThumbURL, u.WebPURL} {
+		if url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// UploadReference ссылка позиции на изображение через CoverImage
+type UploadReference struct {
+	URL        string
+	EntityType string
+	EntityID   uuid.UUID
+}
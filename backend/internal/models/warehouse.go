@@ -2,6 +2,7 @@ package models
 
 import (
 	"math"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,6 +14,11 @@ func RoundTo2(value float64) float64 {
 	return math.Round(value*100) / 100
 }
 
+// RoundTo3 округляет float64 до 3 знаков после запятой (количество в кг/л с точностью до грамма)
+func RoundTo3(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
 // Warehouse представляет склад
 type Warehouse struct {
 	ID              uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
@@ -43,6 +49,8 @@ type Stock struct {
 	Ingredient   *Ingredient `json:"ingredient,omitempty" gorm:"foreignKey:IngredientID"`
 	ProductID    *uuid.UUID  `json:"product_id,omitempty" gorm:"type:uuid;index"`
 	Product      *Product    `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	SemiFinishedID *uuid.UUID         `json:"semi_finished_id,omitempty" gorm:"type:uuid;index"` // Произведенный полуфабрикат
+	SemiFinished *SemiFinishedProduct `json:"semi_finished,omitempty" gorm:"foreignKey:SemiFinishedID"`
 	Quantity     float64     `json:"quantity" gorm:"not null"`
 	Unit         string      `json:"unit" gorm:"not null"`
 	PricePerUnit float64     `json:"price_per_unit" gorm:"default:0"` // Цена за единицу измерения (себестоимость)
@@ -81,6 +89,7 @@ type Supply struct {
 	DeliveryDateTime time.Time   `json:"delivery_date_time" gorm:"not null;index"` // Дата и время поставки
 	Status          string       `json:"status" gorm:"not null;index"`              // pending, completed, cancelled
 	Comment         string       `json:"comment"`                             // Комментарий
+	PurchaseOrderID *uuid.UUID   `json:"purchase_order_id,omitempty" gorm:"type:uuid;index"` // Заказ поставщику, по которому получена поставка
 	Items           []SupplyItem  `json:"items,omitempty" gorm:"foreignKey:SupplyID"`
 	// Поля для счета и оплаты
 	InvoiceNumber   string       `json:"invoice_number"`                           // Номер счета от поставщика
@@ -91,10 +100,57 @@ type Supply struct {
 	PaymentAmount   float64      `json:"payment_amount" gorm:"default:0"`          // Сумма оплаты
 	AccountID       *uuid.UUID   `json:"account_id,omitempty" gorm:"type:uuid"`    // Счет для оплаты
 	Account         *Account     `json:"account,omitempty" gorm:"foreignKey:AccountID"`
+	DueDate         *time.Time   `json:"due_date,omitempty" gorm:"index"`          // Срок оплаты (дата счета + отсрочка поставщика)
 	CreatedAt       time.Time    `json:"created_at" gorm:"index"`
 	UpdatedAt       time.Time    `json:"updated_at"`
 }
 
+// InvoiceAmount сумма к оплате: сумма по счету, а если она не указана — сумма позиций
+func (s *Supply) InvoiceAmount() float64 {
+	if s.TotalAmount > 0 {
+		return RoundTo2(s.TotalAmount)
+	}
+	total := 0.0
+	for _, it := range s.Items {
+		if it.TotalAmount > 0 {
+			total += it.TotalAmount
+		} else {
+			total += it.Quantity * it.PricePerUnit
+		}
+	}
+	return RoundTo2(total)
+}
+
+// OutstandingAmount неоплаченный остаток по поставке
+func (s *Supply) OutstandingAmount() float64 {
+	outstanding := RoundTo2(s.InvoiceAmount() - s.PaymentAmount)
+	if outstanding < 0 {
+		return 0
+	}
+	return outstanding
+}
+
+// PaymentStatusFor возвращает статус оплаты для оплаченной суммы paid
+func (s *Supply) PaymentStatusFor(paid float64) string {
+	switch {
+	case paid <= 0:
+		return SupplyPaymentStatusPending
+	case paid < s.InvoiceAmount():
+		return SupplyPaymentStatusPartial
+	default:
+		return SupplyPaymentStatusPaid
+	}
+}
+
+// DueDateFor вычисляет срок оплаты от даты счета (или даты поставки) с отсрочкой в днях
+func (s *Supply) DueDateFor(paymentTermDays int) time.Time {
+	base := s.DeliveryDateTime
+	if s.InvoiceDate != nil {
+		base = *s.InvoiceDate
+	}
+	return base.AddDate(0, 0, paymentTermDays)
+}
+
 // BeforeCreate hook для автоматической генерации UUID
 func (s *Supply) BeforeCreate(tx *gorm.DB) error {
 	if s.ID == uuid.Nil {
@@ -115,6 +171,9 @@ type SupplyItem struct {
 	Unit         string    `json:"unit" gorm:"not null"`
 	PricePerUnit float64   `json:"price_per_unit" gorm:"default:0"` // Цена за единицу измерения
 	TotalAmount  float64   `json:"total_amount" gorm:"default:0"`   // Общая сумма (цена за единицу * количество)
+	PurchaseOrderItemID *uuid.UUID `json:"purchase_order_item_id,omitempty" gorm:"type:uuid;index"` // Позиция заказа поставщику
+	ProductionDate *time.Time `json:"production_date,omitempty"` // Дата производства
+	ExpiryDate     *time.Time `json:"expiry_date,omitempty"`     // Годен до (если не указан — дата производства или поставки + срок годности ингредиента)
 	CreatedAt    time.Time `json:"created_at"`
 }
 
@@ -147,6 +206,7 @@ type WriteOff struct {
 	WriteOffDateTime time.Time      `json:"write_off_date_time" gorm:"not null;index"` // Дата и время списания
 	Reason           string         `json:"reason"`                               // Причина списания
 	Comment          string         `json:"comment"`                              // Комментарий
+	TotalAmount      float64        `json:"total_amount" gorm:"default:0"`        // Фактическая себестоимость списания (по партиям FIFO)
 	Items            []WriteOffItem `json:"items,omitempty" gorm:"foreignKey:WriteOffID"`
 	CreatedAt        time.Time      `json:"created_at" gorm:"index"`
 	UpdatedAt        time.Time      `json:"updated_at"`
@@ -158,6 +218,7 @@ func (wo *WriteOff) BeforeCreate(tx *gorm.DB) error {
 	if wo.ID == uuid.Nil {
 		wo.ID = uuid.New()
 	}
+	wo.TotalAmount = RoundTo2(wo.TotalAmount)
 	return nil
 }
 
@@ -172,6 +233,8 @@ type WriteOffItem struct {
 	Quantity     float64     `json:"quantity" gorm:"not null"`
 	Unit         string      `json:"unit" gorm:"not null"`
 	Details      string      `json:"details"` // Детали списания
+	PricePerUnit float64     `json:"price_per_unit" gorm:"default:0"` // Фактическая себестоимость единицы (по партиям FIFO)
+	TotalAmount  float64     `json:"total_amount" gorm:"default:0"`   // Фактическая себестоимость позиции
 	CreatedAt    time.Time   `json:"created_at"`
 }
 
@@ -182,6 +245,8 @@ func (woi *WriteOffItem) BeforeCreate(tx *gorm.DB) error {
 	}
 	// Округляем значения до 2 знаков после запятой
 	woi.Quantity = RoundTo2(woi.Quantity)
+	woi.PricePerUnit = RoundTo2(woi.PricePerUnit)
+	woi.TotalAmount = RoundTo2(woi.TotalAmount)
 	return nil
 }
 
@@ -189,6 +254,8 @@ func (woi *WriteOffItem) BeforeCreate(tx *gorm.DB) error {
 func (woi *WriteOffItem) BeforeUpdate(tx *gorm.DB) error {
 	// Округляем значения до 2 знаков после запятой
 	woi.Quantity = RoundTo2(woi.Quantity)
+	woi.PricePerUnit = RoundTo2(woi.PricePerUnit)
+	woi.TotalAmount = RoundTo2(woi.TotalAmount)
 	return nil
 }
 
@@ -204,6 +271,8 @@ type Supplier struct {
 	Comment         string         `json:"comment"`         // Комментарий
 	Contact         string         `json:"contact"`
 	Email           string         `json:"email"`
+	LeadTimeDays    int            `json:"lead_time_days" gorm:"default:1"` // Срок поставки в днях (для рекомендаций по дозаказу)
+	PaymentTermDays int            `json:"payment_term_days" gorm:"default:0"` // Отсрочка платежа в днях (0 — оплата в день поставки)
 	Active          bool           `json:"active" gorm:"default:true"`
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
@@ -218,6 +287,51 @@ func (s *Supplier) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// SupplierItemMapping запомненное сопоставление позиции из документов поставщика
+// (по коду, а если кода нет — по названию) с ингредиентом или товаром заведения.
+// UnitFactor задает, сколько Unit содержит одна единица поставщика SupplierUnit
+// (например, 1 кор = 12 шт); 0 — единица поставщика переводится стандартным пересчетом.
+type SupplierItemMapping struct {
+	ID              uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EstablishmentID uuid.UUID   `json:"establishment_id" gorm:"type:uuid;not null;index"`
+	SupplierID      uuid.UUID   `json:"supplier_id" gorm:"type:uuid;not null;uniqueIndex:idx_supplier_item_mapping_key"`
+	MatchKey        string      `json:"match_key" gorm:"not null;uniqueIndex:idx_supplier_item_mapping_key"` // См. SupplierItemKey
+	SupplierCode    string      `json:"supplier_code"`                                                        // Артикул поставщика
+	SupplierName    string      `json:"supplier_name"`                                                        // Название в документах поставщика
+	IngredientID    *uuid.UUID  `json:"ingredient_id,omitempty" gorm:"type:uuid;index"`
+	Ingredient      *Ingredient `json:"ingredient,omitempty" gorm:"foreignKey:IngredientID"`
+	ProductID       *uuid.UUID  `json:"product_id,omitempty" gorm:"type:uuid;index"`
+	Product         *Product    `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	SupplierUnit    string      `json:"supplier_unit"`                   // Единица в документах поставщика
+	UnitFactor      float64     `json:"unit_factor" gorm:"default:0"`    // Количество Unit в одной единице поставщика
+	Unit            string      `json:"unit"`                            // Единица UnitFactor (пусто — единица учета позиции)
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID
+func (m *SupplierItemMapping) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	if m.MatchKey == "" {
+		m.MatchKey = SupplierItemKey(m.SupplierCode, m.SupplierName)
+	}
+	return nil
+}
+
+// SupplierItemKey ключ сопоставления позиции поставщика: артикул, а без артикула — название
+// без учета регистра и лишних пробелов. Пустая строка — позицию сопоставить нельзя.
+func SupplierItemKey(code, name string) string {
+	if code = strings.TrimSpace(code); code != "" {
+		return "code:" + strings.ToLower(code)
+	}
+	if name = strings.Join(strings.Fields(strings.ToLower(name)), " "); name != "" {
+		return "name:" + name
+	}
+	return ""
+}
+
 // WriteOffReason представляет причину списания
 type WriteOffReason struct {
 	ID              uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
@@ -237,4 +351,148 @@ func (wor *WriteOffReason) BeforeCreate(tx *gorm.DB) error {
 		wor.ID = uuid.New()
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// TransferStatus статус перемещения между складами
+type TransferStatus string
+
+const (
+	TransferStatusDraft    TransferStatus = "draft"    // Черновик
+	TransferStatusSent     TransferStatus = "sent"     // Отправлено (списано со склада-отправителя)
+	TransferStatusReceived TransferStatus = "received" // Получено (оприходовано на складе-получателе)
+)
+
+// Transfer представляет перемещение товаров между складами
+type Transfer struct {
+	ID                uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	SourceWarehouseID uuid.UUID      `json:"source_warehouse_id" gorm:"type:uuid;not null;index"`
+	SourceWarehouse   *Warehouse     `json:"source_warehouse,omitempty" gorm:"foreignKey:SourceWarehouseID"`
+	TargetWarehouseID uuid.UUID      `json:"target_warehouse_id" gorm:"type:uuid;not null;index"`
+	TargetWarehouse   *Warehouse     `json:"target_warehouse,omitempty" gorm:"foreignKey:TargetWarehouseID"`
+	TransferDateTime  time.Time      `json:"transfer_date_time" gorm:"not null;index"` // Дата и время перемещения
+	Status            TransferStatus `json:"status" gorm:"type:varchar(20);not null;default:'draft';index"`
+	Comment           string         `json:"comment"`
+	TotalAmount       float64        `json:"total_amount" gorm:"default:0"` // Себестоимость перемещения по ценам склада-отправителя
+	SentAt            *time.Time     `json:"sent_at,omitempty"`
+	ReceivedAt        *time.Time     `json:"received_at,omitempty"`
+	Items             []TransferItem `json:"items,omitempty" gorm:"foreignKey:TransferID"`
+	CreatedAt         time.Time      `json:"created_at" gorm:"index"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID
+func (t *Transfer) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	if t.Status == "" {
+		t.Status = TransferStatusDraft
+	}
+	t.TotalAmount = RoundTo2(t.TotalAmount)
+	return nil
+}
+
+// BeforeUpdate hook для округления значений перед обновлением
+func (t *Transfer) BeforeUpdate(tx *gorm.DB) error {
+	t.TotalAmount = RoundTo2(t.TotalAmount)
+	return nil
+}
+
+// TransferItem представляет позицию перемещения
+type TransferItem struct {
+	ID           uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey"` // UUID генерируется в коде, не используем default
+	TransferID   uuid.UUID   `json:"transfer_id" gorm:"type:uuid;not null;index"`
+	IngredientID *uuid.UUID  `json:"ingredient_id,omitempty" gorm:"type:uuid;index"`
+	Ingredient   *Ingredient `json:"ingredient,omitempty" gorm:"foreignKey:IngredientID"`
+	ProductID    *uuid.UUID  `json:"product_id,omitempty" gorm:"type:uuid;index"`
+	Product      *Product    `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	Quantity     float64     `json:"quantity" gorm:"not null"`
+	Unit         string      `json:"unit" gorm:"not null"`
+	PricePerUnit float64     `json:"price_per_unit" gorm:"default:0"` // Себестоимость единицы на складе-отправителе
+	TotalAmount  float64     `json:"total_amount" gorm:"default:0"`   // Себестоимость позиции (цена за единицу * количество)
+	ExpiresAt    *time.Time  `json:"expires_at,omitempty"`            // Ближайший срок годности отправленных партий
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID и округления значений
+func (ti *TransferItem) BeforeCreate(tx *gorm.DB) error {
+	if ti.ID == uuid.Nil {
+		ti.ID = uuid.New()
+	}
+	ti.Quantity = RoundTo2(ti.Quantity)
+	ti.PricePerUnit = RoundTo2(ti.PricePerUnit)
+	ti.TotalAmount = RoundTo2(ti.TotalAmount)
+	return nil
+}
+
+// BeforeUpdate hook для округления значений перед обновлением
+func (ti *TransferItem) BeforeUpdate(tx *gorm.DB) error {
+	ti.Quantity = RoundTo2(ti.Quantity)
+	ti.PricePerUnit = RoundTo2(ti.PricePerUnit)
+	ti.TotalAmount = RoundTo2(ti.TotalAmount)
+	return nil
+}
+
+// ProductionStatus статус производства полуфабриката
+type ProductionStatus string
+
+const (
+	ProductionStatusCompleted ProductionStatus = "completed" // Проведено: ингредиенты списаны, полуфабрикат оприходован
+)
+
+// Production представляет документ производства полуфабриката (заготовка соусов, основ и т.п.).
+// Ингредиенты по рецептуре полуфабриката списываются со склада, а выход приходуется
+// на тот же склад по фактической себестоимости израсходованных партий.
+type Production struct {
+	ID                 uuid.UUID            `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	WarehouseID        uuid.UUID            `json:"warehouse_id" gorm:"type:uuid;not null;index"`
+	Warehouse          *Warehouse           `json:"warehouse,omitempty" gorm:"foreignKey:WarehouseID"`
+	SemiFinishedID     uuid.UUID            `json:"semi_finished_id" gorm:"type:uuid;not null;index"`
+	SemiFinished       *SemiFinishedProduct `json:"semi_finished,omitempty" gorm:"foreignKey:SemiFinishedID"`
+	Quantity           float64              `json:"quantity" gorm:"not null"` // Выход в единице полуфабриката
+	Unit               string               `json:"unit" gorm:"not null"`
+	ProductionDateTime time.Time            `json:"production_date_time" gorm:"not null;index"`
+	Status             ProductionStatus     `json:"status" gorm:"type:varchar(20);not null;default:'completed'"`
+	Comment            string               `json:"comment"`
+	TotalCost          float64              `json:"total_cost" gorm:"default:0"` // Себестоимость израсходованных ингредиентов
+	UnitCost           float64              `json:"unit_cost" gorm:"default:0"`  // Себестоимость единицы выхода
+	Items              []ProductionItem     `json:"items,omitempty" gorm:"foreignKey:ProductionID"`
+	CreatedAt          time.Time            `json:"created_at" gorm:"index"`
+	UpdatedAt          time.Time            `json:"updated_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID и округления значений
+func (p *Production) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	if p.Status == "" {
+		p.Status = ProductionStatusCompleted
+	}
+	p.TotalCost = RoundTo2(p.TotalCost)
+	p.UnitCost = RoundTo2(p.UnitCost)
+	return nil
+}
+
+// ProductionItem представляет израсходованный на производство ингредиент
+type ProductionItem struct {
+	ID           uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey"` // UUID генерируется в коде, не используем default
+	ProductionID uuid.UUID   `json:"production_id" gorm:"type:uuid;not null;index"`
+	IngredientID uuid.UUID   `json:"ingredient_id" gorm:"type:uuid;not null;index"`
+	Ingredient   *Ingredient `json:"ingredient,omitempty" gorm:"foreignKey:IngredientID"`
+	Quantity     float64     `json:"quantity" gorm:"not null"` // Количество в единице остатка
+	Unit         string      `json:"unit" gorm:"not null"`
+	PricePerUnit float64     `json:"price_per_unit" gorm:"default:0"` // Фактическая себестоимость единицы (по партиям FIFO)
+	TotalAmount  float64     `json:"total_amount" gorm:"default:0"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// BeforeCreate hook для автоматической генерации UUID и округления значений
+func (pi *ProductionItem) BeforeCreate(tx *gorm.DB) error {
+	if pi.ID == uuid.Nil {
+		pi.ID = uuid.New()
+	}
+	pi.PricePerUnit = RoundTo2(pi.PricePerUnit)
+	pi.TotalAmount = RoundTo2(pi.TotalAmount)
+	return nil
+}
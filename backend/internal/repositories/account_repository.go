@@ -35,12 +35,12 @@ func NewAccountRepository(db *gorm.DB) AccountRepository {
 }
 
 func (r *accountRepository) Create(ctx context.Context, account *models.Account) error {
-	return r.db.WithContext(ctx).Create(account).Error
+	return dbFor(ctx, r.db).Create(account).Error
 }
 
 func (r *accountRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Account, error) {
 	var account models.Account
-	query := r.db.WithContext(ctx).Preload("Type").Preload("Establishment")
+	query := dbFor(ctx, r.db).Preload("Type").Preload("Establishment")
 	
 	if establishmentID != nil {
 		query = query.Where("establishment_id = ?", *establishmentID)
@@ -55,7 +55,7 @@ func (r *accountRepository) GetByID(ctx context.Context, id uuid.UUID, establish
 
 func (r *accountRepository) List(ctx context.Context, filter *AccountFilter) ([]*models.Account, error) {
 	var accounts []*models.Account
-	query := r.db.WithContext(ctx).Preload("Type").Preload("Establishment")
+	query := dbFor(ctx, r.db).Preload("Type").Preload("Establishment")
 	
 	if filter != nil {
 		if filter.EstablishmentID != nil {
@@ -78,15 +78,15 @@ func (r *accountRepository) List(ctx context.Context, filter *AccountFilter) ([]
 }
 
 func (r *accountRepository) Update(ctx context.Context, account *models.Account) error {
-	return r.db.WithContext(ctx).Save(account).Error
+	return dbFor(ctx, r.db).Save(account).Error
 }
 
 func (r *accountRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Account{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.Account{}, "id = ?", id).Error
 }
 
 func (r *accountRepository) UpdateBalance(ctx context.Context, id uuid.UUID, balance float64) error {
-	return r.db.WithContext(ctx).
+	return dbFor(ctx, r.db).
 		Model(&models.Account{}).
 		Where("id = ?", id).
 		Update("balance", balance).Error
@@ -110,13 +110,13 @@ func NewAccountTypeRepository(db *gorm.DB) AccountTypeRepository {
 
 func (r *accountTypeRepository) GetAll(ctx context.Context) ([]*models.AccountType, error) {
 	var types []*models.AccountType
-	err := r.db.WithContext(ctx).Find(&types).Error
+	err := dbFor(ctx, r.db).Find(&types).Error
 	return types, err
 }
 
 func (r *accountTypeRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.AccountType, error) {
 	var accountType models.AccountType
-	err := r.db.WithContext(ctx).First(&accountType, "id = ?", id).Error
+	err := dbFor(ctx, r.db).First(&accountType, "id = ?", id).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
@@ -125,7 +125,7 @@ func (r *accountTypeRepository) GetByID(ctx context.Context, id uuid.UUID) (*mod
 
 func (r *accountTypeRepository) GetByName(ctx context.Context, name string) (*models.AccountType, error) {
 	var accountType models.AccountType
-	err := r.db.WithContext(ctx).Where("name = ?", name).First(&accountType).Error
+	err := dbFor(ctx, r.db).Where("name = ?", name).First(&accountType).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
@@ -133,5 +133,5 @@ func (r *accountTypeRepository) GetByName(ctx context.Context, name string) (*mo
 }
 
 func (r *accountTypeRepository) Create(ctx context.Context, accountType *models.AccountType) error {
-	return r.db.WithContext(ctx).Create(accountType).Error
+	return dbFor(ctx, r.db).Create(accountType).Error
 }
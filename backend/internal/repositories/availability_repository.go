@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+// AvailabilityRepository интерфейс репозитория расписаний доступности позиций меню
+type AvailabilityRepository interface {
+	CreateSchedule(ctx context.Context, schedule *models.AvailabilitySchedule) error
+	// UpdateSchedule обновляет название и активность, правила заменяются целиком
+	UpdateSchedule(ctx context.Context, schedule *models.AvailabilitySchedule) error
+	// DeleteSchedule удаляет расписание и снимает его с категорий, товаров и тех-карт
+	DeleteSchedule(ctx context.Context, id uuid.UUID) error
+	GetScheduleByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.AvailabilitySchedule, error)
+	ListSchedules(ctx context.Context, establishmentID uuid.UUID) ([]*models.AvailabilitySchedule, error)
+
+	// AssignCategory, AssignProduct и AssignTechCard назначают расписание (nil — снимают).
+	// Возвращают gorm.ErrRecordNotFound, если позиции нет в заведении.
+	AssignCategory(ctx context.Context, id, establishmentID uuid.UUID, scheduleID *uuid.UUID) error
+	AssignProduct(ctx context.Context, id, establishmentID uuid.UUID, scheduleID *uuid.UUID) error
+	AssignTechCard(ctx context.Context, id, establishmentID uuid.UUID, scheduleID *uuid.UUID) error
+}
+
+type availabilityRepository struct {
+	db *gorm.DB
+}
+
+func NewAvailabilityRepository(db *gorm.DB) AvailabilityRepository {
+	return &availabilityRepository{db: db}
+}
+
+func (r *availabilityRepository) CreateSchedule(ctx context.Context, schedule *models.AvailabilitySchedule) error {
+	return dbFor(ctx, r.db).Create(schedule).Error
+}
+
+func (r *availabilityRepository) UpdateSchedule(ctx context.Context, schedule *models.AvailabilitySchedule) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.AvailabilitySchedule{}).Where("id = ?", schedule.ID).Updates(map[string]interface{}{
+			"name":   schedule.Name,
+			"active": schedule.Active,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("schedule_id = ?", schedule.ID).Delete(&models.AvailabilityRule{}).Error; err != nil {
+			return err
+		}
+		for i := range schedule.Rules {
+			schedule.Rules[i].ID = uuid.Nil
+			schedule.Rules[i].ScheduleID = schedule.ID
+			if err := tx.Create(&schedule.Rules[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *availabilityRepository) DeleteSchedule(ctx context.Context, id uuid.UUID) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.Category{}, &models.Product{}, &models.TechCard{}, &models.Combo{}} {
+			if err := tx.Model(model).Where("availability_schedule_id = ?", id).Update("availability_schedule_id", nil).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("schedule_id = ?", id).Delete(&models.AvailabilityRule{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.AvailabilitySchedule{}, "id = ?", id).Error
+	})
+}
+
+func (r *availabilityRepository) GetScheduleByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.AvailabilitySchedule, error) {
+	var schedule models.AvailabilitySchedule
+	q := dbFor(ctx, r.db).Preload("Rules")
+	if establishmentID != nil {
+		q = q.Where("establishment_id = ?", *establishmentID)
+	}
+	err := q.First(&schedule, "id = ?", id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &schedule, err
+}
+
+func (r *availabilityRepository) ListSchedules(ctx context.Context, establishmentID uuid.UUID) ([]*models.AvailabilitySchedule, error) {
+	var schedules []*models.AvailabilitySchedule
+	err := dbFor(ctx, r.db).
+		Preload("Rules").
+		Where("establishment_id = ?", establishmentID).
+		Order("name").
+		Find(&schedules).Error
+	return schedules, err
+}
+
+func (r *availabilityRepository) AssignCategory(ctx context.Context, id, establishmentID uuid.UUID, scheduleID *uuid.UUID) error {
+	return r.assign(ctx, &models.Category{}, id, establishmentID, scheduleID)
+}
+
+func (r *availabilityRepository) AssignProduct(ctx context.Context, id, establishmentID uuid.UUID, scheduleID *uuid.UUID) error {
+	return r.assign(ctx, &models.Product{}, id, establishmentID, scheduleID)
+}
+
+func (r *availabilityRepository) AssignTechCard(ctx context.Context, id, establishmentID uuid.UUID, scheduleID *uuid.UUID) error {
+	return r.assign(ctx, &models.TechCard{}, id, establishmentID, scheduleID)
+}
+
+func (r *availabilityRepository) assign(ctx context.Context, model interface{}, id, establishmentID uuid.UUID, scheduleID *uuid.UUID) error {
+	result := dbFor(ctx, r.db).
+		Model(model).
+		Where("id = ? AND establishment_id = ?", id, establishmentID).
+		UpdateColumn("availability_schedule_id", scheduleID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
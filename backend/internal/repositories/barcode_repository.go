@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+// BarcodeFilter фильтр для списка штрихкодов
+type BarcodeFilter struct {
+	IngredientID *uuid.UUID
+	ProductID    *uuid.UUID
+	TechCardID   *uuid.UUID
+}
+
+// BarcodeRepository интерфейс репозитория штрихкодов позиций
+type BarcodeRepository interface {
+	Create(ctx context.Context, barcode *models.ItemBarcode) error
+	Update(ctx context.Context, barcode *models.ItemBarcode) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	GetByID(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) (*models.ItemBarcode, error)
+	List(ctx context.Context, establishmentID uuid.UUID, filter *BarcodeFilter) ([]*models.ItemBarcode, error)
+	// GetByCode ищет штрихкод заведения по точному коду (для весовых — по первым 7 цифрам)
+	GetByCode(ctx context.Context, establishmentID uuid.UUID, code string, weighted bool) (*models.ItemBarcode, error)
+	// GetIngredientByBarcode и GetProductByBarcode ищут по полю Barcode самой позиции
+	GetIngredientByBarcode(ctx context.Context, establishmentID uuid.UUID, code string) (*models.Ingredient, error)
+	GetProductByBarcode(ctx context.Context, establishmentID uuid.UUID, code string) (*models.Product, error)
+}
+
+type barcodeRepository struct {
+	db *gorm.DB
+}
+
+func NewBarcodeRepository(db *gorm.DB) BarcodeRepository {
+	return &barcodeRepository{db: db}
+}
+
+func (r *barcodeRepository) Create(ctx context.Context, barcode *models.ItemBarcode) error {
+	return dbFor(ctx, r.db).Create(barcode).Error
+}
+
+func (r *barcodeRepository) Update(ctx context.Context, barcode *models.ItemBarcode) error {
+	return dbFor(ctx, r.db).Model(&models.ItemBarcode{}).Where("id = ?", barcode.ID).Updates(map[string]interface{}{
+		"code":       barcode.Code,
+		"multiplier": barcode.Multiplier,
+		"unit":       barcode.Unit,
+		"weighted":   barcode.Weighted,
+		"comment":    barcode.Comment,
+	}).Error
+}
+
+func (r *barcodeRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return dbFor(ctx, r.db).Delete(&models.ItemBarcode{}, "id = ?", id).Error
+}
+
+func (r *barcodeRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) (*models.ItemBarcode, error) {
+	var barcode models.ItemBarcode
+	err := dbFor(ctx, r.db).
+		Where("id = ? AND establishment_id = ?", id, establishmentID).
+		First(&barcode).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &barcode, err
+}
+
+func (r *barcodeRepository) List(ctx context.Context, establishmentID uuid.UUID, filter *BarcodeFilter) ([]*models.ItemBarcode, error) {
+	query := dbFor(ctx, r.db).
+		Preload("Ingredient").
+		Preload("Product").
+		Preload("TechCard").
+		Where("establishment_id = ?", establishmentID)
+	if filter != nil {
+		if filter.IngredientID != nil {
+			query = query.Where("ingredient_id = ?", *filter.IngredientID)
+		}
+		if filter.ProductID != nil {
+			query = query.Where("product_id = ?", *filter.ProductID)
+		}
+		if filter.TechCardID != nil {
+			query = query.Where("tech_card_id = ?", *filter.TechCardID)
+		}
+	}
+
+	var barcodes []*models.ItemBarcode
+	err := query.Order("code").Find(&barcodes).Error
+	return barcodes, err
+}
+
+func (r *barcodeRepository) GetByCode(ctx context.Context, establishmentID uuid.UUID, code string, weighted bool) (*models.ItemBarcode, error) {
+	var barcode models.ItemBarcode
+	err := dbFor(ctx, r.db).
+		Preload("Ingredient").
+		Preload("Product").
+		Preload("TechCard").
+		Where("establishment_id = ? AND code = ? AND weighted = ?", establishmentID, code, weighted).
+		First(&barcode).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &barcode, err
+}
+
+func (r *barcodeRepository) GetIngredientByBarcode(ctx context.Context, establishmentID uuid.UUID, code string) (*models.Ingredient, error) {
+	var ingredient models.Ingredient
+	err := dbFor(ctx, r.db).
+		Where("establishment_id = ? AND barcode = ?", establishmentID, code).
+		First(&ingredient).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &ingredient, err
+}
+
+func (r *barcodeRepository) GetProductByBarcode(ctx context.Context, establishmentID uuid.UUID, code string) (*models.Product, error) {
+	var product models.Product
+	err := dbFor(ctx, r.db).
+		Where("establishment_id = ? AND barcode = ?", establishmentID, code).
+		First(&product).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &product, err
+}
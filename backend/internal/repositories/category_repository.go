@@ -34,7 +34,7 @@ func NewCategoryRepository(db *gorm.DB) CategoryRepository {
 
 func (r *categoryRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Category, error) {
 	var c models.Category
-	q := r.db.WithContext(ctx)
+	q := dbFor(ctx, r.db)
 	if establishmentID != nil {
 		q = q.Where("establishment_id = ?", *establishmentID)
 	}
@@ -44,7 +44,7 @@ func (r *categoryRepository) GetByID(ctx context.Context, id uuid.UUID, establis
 
 func (r *categoryRepository) List(ctx context.Context, filter *CategoryFilter) ([]*models.Category, error) {
 	var list []*models.Category
-	query := r.db.WithContext(ctx)
+	query := dbFor(ctx, r.db)
 
 	if filter != nil {
 		if filter.EstablishmentID != nil {
@@ -64,13 +64,13 @@ func (r *categoryRepository) List(ctx context.Context, filter *CategoryFilter) (
 }
 
 func (r *categoryRepository) Create(ctx context.Context, category *models.Category) error {
-	return r.db.WithContext(ctx).Create(category).Error
+	return dbFor(ctx, r.db).Create(category).Error
 }
 
 func (r *categoryRepository) Update(ctx context.Context, category *models.Category) error {
-	return r.db.WithContext(ctx).Save(category).Error
+	return dbFor(ctx, r.db).Save(category).Error
 }
 
 func (r *categoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Category{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.Category{}, "id = ?", id).Error
 }
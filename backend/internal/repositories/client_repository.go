@@ -32,7 +32,7 @@ func NewClientRepository(db *gorm.DB) ClientRepository {
 
 func (r *clientRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Client, error) {
 	var client models.Client
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Preload("LoyaltyProgram").
 		Preload("Group").
 		First(&client, "id = ?", id).Error
@@ -47,7 +47,7 @@ func (r *clientRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.C
 
 func (r *clientRepository) GetAllByEstablishmentID(ctx context.Context, establishmentID uuid.UUID) ([]*models.Client, error) {
 	var clients []*models.Client
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Preload("LoyaltyProgram").
 		Preload("Group").
 		Where("establishment_id = ?", establishmentID).
@@ -59,26 +59,26 @@ func (r *clientRepository) GetAllByEstablishmentID(ctx context.Context, establis
 }
 
 func (r *clientRepository) Create(ctx context.Context, client *models.Client) error {
-	return r.db.WithContext(ctx).Create(client).Error
+	return dbFor(ctx, r.db).Create(client).Error
 }
 
 func (r *clientRepository) Update(ctx context.Context, client *models.Client) error {
-	return r.db.WithContext(ctx).Save(client).Error
+	return dbFor(ctx, r.db).Save(client).Error
 }
 
 func (r *clientRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Client{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.Client{}, "id = ?", id).Error
 }
 
 func (r *clientRepository) AddLoyaltyPoints(ctx context.Context, clientID uuid.UUID, points int) error {
-	return r.db.WithContext(ctx).
+	return dbFor(ctx, r.db).
 		Model(&models.Client{}).
 		Where("id = ?", clientID).
 		Update("loyalty_points", gorm.Expr("loyalty_points + ?", points)).Error
 }
 
 func (r *clientRepository) RedeemLoyaltyPoints(ctx context.Context, clientID uuid.UUID, points int) error {
-	return r.db.WithContext(ctx).
+	return dbFor(ctx, r.db).
 		Model(&models.Client{}).
 		Where("id = ? AND loyalty_points >= ?", clientID, points).
 		Update("loyalty_points", gorm.Expr("loyalty_points - ?", points)).Error
@@ -86,7 +86,7 @@ func (r *clientRepository) RedeemLoyaltyPoints(ctx context.Context, clientID uui
 
 func (r *clientRepository) GetClientLoyaltyPoints(ctx context.Context, clientID uuid.UUID) (int, error) {
 	var client models.Client
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Select("loyalty_points").
 		First(&client, "id = ?", clientID).Error
 	if err != nil {
@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+type ComboFilter struct {
+	EstablishmentID *uuid.UUID // обязательно для изоляции заведений
+	CategoryID      *uuid.UUID
+	Search          *string
+	Active          *bool
+}
+
+// ComboRepository интерфейс репозитория комбо и сет-меню
+type ComboRepository interface {
+	// GetByID возвращает комбо с группами и позициями; nil, nil — если комбо не найдено
+	GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Combo, error)
+	List(ctx context.Context, filter *ComboFilter) ([]*models.Combo, error)
+	Create(ctx context.Context, combo *models.Combo) error
+	// Update обновляет комбо, группы и позиции заменяются целиком
+	Update(ctx context.Context, combo *models.Combo) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type comboRepository struct {
+	db *gorm.DB
+}
+
+func NewComboRepository(db *gorm.DB) ComboRepository {
+	return &comboRepository{db: db}
+}
+
+// preloadComboGroups загружает группы по порядку и их позиции с товарами и тех-картами
+func preloadComboGroups(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Groups", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order, created_at") }).
+		Preload("Groups.Options.Product").
+		Preload("Groups.Options.TechCard")
+}
+
+func (r *comboRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Combo, error) {
+	var combo models.Combo
+	q := preloadComboGroups(dbFor(ctx, r.db))
+	if establishmentID != nil {
+		q = q.Where("establishment_id = ?", *establishmentID)
+	}
+	err := q.First(&combo, "id = ?", id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &combo, err
+}
+
+func (r *comboRepository) List(ctx context.Context, filter *ComboFilter) ([]*models.Combo, error) {
+	var combos []*models.Combo
+	query := preloadComboGroups(dbFor(ctx, r.db)).Preload("Category")
+
+	if filter != nil {
+		if filter.EstablishmentID != nil {
+			query = query.Where("establishment_id = ?", *filter.EstablishmentID)
+		}
+		if filter.CategoryID != nil {
+			query = query.Where("category_id = ?", *filter.CategoryID)
+		}
+		if filter.Search != nil && *filter.Search != "" {
+			search := "%" + strings.ToLower(*filter.Search) + "%"
+			query = query.Where("LOWER(name) LIKE ?", search)
+		}
+		if filter.Active != nil {
+			query = query.Where("active = ?", *filter.Active)
+		}
+	}
+
+	err := query.Order("name").Find(&combos).Error
+	return combos, err
+}
+
+func (r *comboRepository) Create(ctx context.Context, combo *models.Combo) error {
+	return dbFor(ctx, r.db).Create(combo).Error
+}
+
+func (r *comboRepository) Update(ctx context.Context, combo *models.Combo) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Combo{}).Where("id = ?", combo.ID).Updates(map[string]interface{}{
+			"category_id":              combo.CategoryID,
+			"name":                     combo.Name,
+			"description":              combo.Description,
+			"cover_image":              combo.CoverImage,
+			"price":                    models.RoundTo2(combo.Price),
+			"availability_schedule_id": combo.AvailabilityScheduleID,
+			"active":                   combo.Active,
+		}).Error; err != nil {
+			return err
+		}
+		if err := deleteComboGroups(tx, combo.ID); err != nil {
+			return err
+		}
+		for i := range combo.Groups {
+			group := &combo.Groups[i]
+			group.ID = uuid.Nil
+			group.ComboID = combo.ID
+			for j := range group.Options {
+				group.Options[j].ID = uuid.Nil
+				group.Options[j].GroupID = uuid.Nil
+			}
+			if err := tx.Create(group).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *comboRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := deleteComboGroups(tx, id); err != nil {
+			return err
+		}
+		return tx.Delete(&models.Combo{}, "id = ?", id).Error
+	})
+}
+
+// deleteComboGroups удаляет группы комбо вместе с позициями
+func deleteComboGroups(tx *gorm.DB, comboID uuid.UUID) error {
+	groupIDs := tx.Model(&models.ComboGroup{}).Select("id").Where("combo_id = ?", comboID)
+	if err := tx.Where("group_id IN (?)", groupIDs).Delete(&models.ComboOption{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("combo_id = ?", comboID).Delete(&models.ComboGroup{}).Error
+}
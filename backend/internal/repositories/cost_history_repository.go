@@ -0,0 +1,209 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+// CostHistoryFilter фильтр истории себестоимости
+type CostHistoryFilter struct {
+	EstablishmentID *uuid.UUID
+	TechCardID      *uuid.UUID
+	ProductID       *uuid.UUID
+	StartDate       *time.Time
+	EndDate         *time.Time
+}
+
+// FoodCostAlertFilter фильтр уведомлений о превышении фудкоста
+type FoodCostAlertFilter struct {
+	EstablishmentID *uuid.UUID
+	Status          *models.StockAlertStatus
+	OnlyActive      bool // Только open и acknowledged
+}
+
+// CostHistoryRepository интерфейс репозитория истории себестоимости и уведомлений о фудкосте
+type CostHistoryRepository interface {
+	CreateEntry(ctx context.Context, entry *models.MenuItemCost) error
+	ListEntries(ctx context.Context, filter *CostHistoryFilter) ([]*models.MenuItemCost, error)
+	// GetLatestEntry возвращает последнюю запись истории позиции (тех-карты или товара)
+	GetLatestEntry(ctx context.Context, techCardID, productID *uuid.UUID) (*models.MenuItemCost, error)
+	// GetLatestEntries возвращает последнюю запись по каждой позиции заведения, сделанную до before
+	GetLatestEntries(ctx context.Context, establishmentID uuid.UUID, before time.Time) ([]*models.MenuItemCost, error)
+
+	// UpdateTechCardCost, UpdateProductCost и UpdateSemiFinishedCost меняют только себестоимость позиции
+	UpdateTechCardCost(ctx context.Context, id uuid.UUID, costPrice float64) error
+	UpdateProductCost(ctx context.Context, id uuid.UUID, costPrice float64) error
+	UpdateSemiFinishedCost(ctx context.Context, id uuid.UUID, costPrice float64) error
+
+	CreateAlert(ctx context.Context, alert *models.FoodCostAlert) error
+	UpdateAlert(ctx context.Context, alert *models.FoodCostAlert) error
+	GetAlertByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.FoodCostAlert, error)
+	// GetActiveAlert возвращает активное (open/acknowledged) уведомление по позиции
+	GetActiveAlert(ctx context.Context, techCardID, productID *uuid.UUID) (*models.FoodCostAlert, error)
+	ListAlerts(ctx context.Context, filter *FoodCostAlertFilter) ([]*models.FoodCostAlert, error)
+}
+
+type costHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewCostHistoryRepository(db *gorm.DB) CostHistoryRepository {
+	return &costHistoryRepository{db: db}
+}
+
+var activeFoodCostAlertStatuses = []models.StockAlertStatus{models.StockAlertStatusOpen, models.StockAlertStatusAcknowledged}
+
+// menuItemScope ограничивает выборку позицией: тех-картой или товаром
+func menuItemScope(q *gorm.DB, techCardID, productID *uuid.UUID) *gorm.DB {
+	if techCardID != nil {
+		return q.Where("tech_card_id = ?", *techCardID)
+	}
+	if productID != nil {
+		return q.Where("product_id = ?", *productID)
+	}
+	return q.Where("1 = 0")
+}
+
+func (r *costHistoryRepository) CreateEntry(ctx context.Context, entry *models.MenuItemCost) error {
+	return dbFor(ctx, r.db).Create(entry).Error
+}
+
+func (r *costHistoryRepository) ListEntries(ctx context.Context, filter *CostHistoryFilter) ([]*models.MenuItemCost, error) {
+	query := dbFor(ctx, r.db).Model(&models.MenuItemCost{})
+	if filter != nil {
+		if filter.EstablishmentID != nil {
+			query = query.Where("establishment_id = ?", *filter.EstablishmentID)
+		}
+		if filter.TechCardID != nil {
+			query = query.Where("tech_card_id = ?", *filter.TechCardID)
+		}
+		if filter.ProductID != nil {
+			query = query.Where("product_id = ?", *filter.ProductID)
+		}
+		if filter.StartDate != nil {
+			query = query.Where("recorded_at >= ?", *filter.StartDate)
+		}
+		if filter.EndDate != nil {
+			query = query.Where("recorded_at <= ?", *filter.EndDate)
+		}
+	}
+
+	var entries []*models.MenuItemCost
+	err := query.Order("recorded_at DESC").Find(&entries).Error
+	return entries, err
+}
+
+func (r *costHistoryRepository) GetLatestEntry(ctx context.Context, techCardID, productID *uuid.UUID) (*models.MenuItemCost, error) {
+	var entry models.MenuItemCost
+	err := menuItemScope(dbFor(ctx, r.db), techCardID, productID).
+		Order("recorded_at DESC").
+		First(&entry).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &entry, err
+}
+
+func (r *costHistoryRepository) GetLatestEntries(ctx context.Context, establishmentID uuid.UUID, before time.Time) ([]*models.MenuItemCost, error) {
+	var entries []*models.MenuItemCost
+	err := dbFor(ctx, r.db).
+		Preload("TechCard").
+		Preload("Product").
+		Select("DISTINCT ON (tech_card_id, product_id) *").
+		Where("establishment_id = ? AND recorded_at < ?", establishmentID, before).
+		Order("tech_card_id, product_id, recorded_at DESC").
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *costHistoryRepository) UpdateTechCardCost(ctx context.Context, id uuid.UUID, costPrice float64) error {
+	return dbFor(ctx, r.db).
+		Model(&models.TechCard{}).
+		Where("id = ?", id).
+		Update("cost_price", models.RoundTo2(costPrice)).Error
+}
+
+func (r *costHistoryRepository) UpdateProductCost(ctx context.Context, id uuid.UUID, costPrice float64) error {
+	return dbFor(ctx, r.db).
+		Model(&models.Product{}).
+		Where("id = ?", id).
+		Update("cost_price", models.RoundTo2(costPrice)).Error
+}
+
+func (r *costHistoryRepository) UpdateSemiFinishedCost(ctx context.Context, id uuid.UUID, costPrice float64) error {
+	return dbFor(ctx, r.db).
+		Model(&models.SemiFinishedProduct{}).
+		Where("id = ?", id).
+		Update("cost_price", models.RoundTo2(costPrice)).Error
+}
+
+func (r *costHistoryRepository) CreateAlert(ctx context.Context, alert *models.FoodCostAlert) error {
+	return dbFor(ctx, r.db).Create(alert).Error
+}
+
+func (r *costHistoryRepository) UpdateAlert(ctx context.Context, alert *models.FoodCostAlert) error {
+	return dbFor(ctx, r.db).Model(&models.FoodCostAlert{}).Where("id = ?", alert.ID).Updates(map[string]interface{}{
+		"cost_price":        alert.CostPrice,
+		"price":             alert.Price,
+		"food_cost_percent": alert.FoodCostPercent,
+		"threshold":         alert.Threshold,
+		"status":            alert.Status,
+		"acknowledged_at":   alert.AcknowledgedAt,
+		"acknowledged_by":   alert.AcknowledgedBy,
+		"resolved_at":       alert.ResolvedAt,
+	}).Error
+}
+
+func (r *costHistoryRepository) GetAlertByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.FoodCostAlert, error) {
+	var alert models.FoodCostAlert
+	q := dbFor(ctx, r.db).
+		Preload("TechCard").
+		Preload("Product")
+	if establishmentID != nil {
+		q = q.Where("establishment_id = ?", *establishmentID)
+	}
+	err := q.First(&alert, "id = ?", id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &alert, err
+}
+
+func (r *costHistoryRepository) GetActiveAlert(ctx context.Context, techCardID, productID *uuid.UUID) (*models.FoodCostAlert, error) {
+	var alert models.FoodCostAlert
+	err := menuItemScope(dbFor(ctx, r.db), techCardID, productID).
+		Where("status IN ?", activeFoodCostAlertStatuses).
+		Order("created_at DESC").
+		First(&alert).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &alert, err
+}
+
+func (r *costHistoryRepository) ListAlerts(ctx context.Context, filter *FoodCostAlertFilter) ([]*models.FoodCostAlert, error) {
+	query := dbFor(ctx, r.db).
+		Preload("TechCard").
+		Preload("Product")
+
+	if filter != nil {
+		if filter.EstablishmentID != nil {
+			query = query.Where("establishment_id = ?", *filter.EstablishmentID)
+		}
+		if filter.Status != nil {
+			query = query.Where("status = ?", *filter.Status)
+		}
+		if filter.OnlyActive {
+			query = query.Where("status IN ?", activeFoodCostAlertStatuses)
+		}
+	}
+
+	var alerts []*models.FoodCostAlert
+	err := query.Order("created_at DESC").Find(&alerts).Error
+	return alerts, err
+}
@@ -15,6 +15,15 @@ type EstablishmentRepository interface {
 	Create(ctx context.Context, establishment *models.Establishment) error
 	Update(ctx context.Context, establishment *models.Establishment) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	// GetNegativeStockPolicy и UpdateNegativeStockPolicy читают и меняют только политику продажи при нехватке остатков
+	GetNegativeStockPolicy(ctx context.Context, id uuid.UUID) (string, error)
+	UpdateNegativeStockPolicy(ctx context.Context, id uuid.UUID, policy string) error
+	// GetFoodCostThreshold и UpdateFoodCostThreshold читают и меняют только порог фудкоста
+	GetFoodCostThreshold(ctx context.Context, id uuid.UUID) (float64, error)
+	UpdateFoodCostThreshold(ctx context.Context, id uuid.UUID, threshold float64) error
+	// GetTimezone и UpdateTimezone читают и меняют только часовой пояс заведения
+	GetTimezone(ctx context.Context, id uuid.UUID) (string, error)
+	UpdateTimezone(ctx context.Context, id uuid.UUID, timezone string) error
 }
 
 type establishmentRepository struct {
@@ -27,24 +36,89 @@ func NewEstablishmentRepository(db *gorm.DB) EstablishmentRepository {
 
 func (r *establishmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Establishment, error) {
 	var establishment models.Establishment
-	err := r.db.WithContext(ctx).Preload("Tables").First(&establishment, "id = ?", id).Error
+	err := dbFor(ctx, r.db).Preload("Tables").First(&establishment, "id = ?", id).Error
 	return &establishment, err
 }
 
 func (r *establishmentRepository) List(ctx context.Context) ([]*models.Establishment, error) {
 	var establishments []*models.Establishment
-	err := r.db.WithContext(ctx).Find(&establishments).Error
+	err := dbFor(ctx, r.db).Find(&establishments).Error
 	return establishments, err
 }
 
 func (r *establishmentRepository) Create(ctx context.Context, establishment *models.Establishment) error {
-	return r.db.WithContext(ctx).Create(establishment).Error
+	return dbFor(ctx, r.db).Create(establishment).Error
 }
 
 func (r *establishmentRepository) Update(ctx context.Context, establishment *models.Establishment) error {
-	return r.db.WithContext(ctx).Save(establishment).Error
+	return dbFor(ctx, r.db).Save(establishment).Error
 }
 
 func (r *establishmentRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Establishment{}, "id = ?", id).Error
-}
\ No newline at end of file
+	return dbFor(ctx, r.db).Delete(&models.Establishment{}, "id = ?", id).Error
+}
+func (r *establishmentRepository) GetNegativeStockPolicy(ctx context.Context, id uuid.UUID) (string, error) {
+	var policies []string
+	err := dbFor(ctx, r.db).
+		Model(&models.Establishment{}).
+		Where("id = ?", id).
+		Pluck("negative_stock_policy", &policies).Error
+	if err != nil {
+		return "", err
+	}
+	if len(policies) == 0 {
+		return "", gorm.ErrRecordNotFound
+	}
+	return policies[0], nil
+}
+
+func (r *establishmentRepository) UpdateNegativeStockPolicy(ctx context.Context, id uuid.UUID, policy string) error {
+	return dbFor(ctx, r.db).
+		Model(&models.Establishment{}).
+		Where("id = ?", id).
+		Update("negative_stock_policy", policy).Error
+}
+
+func (r *establishmentRepository) GetFoodCostThreshold(ctx context.Context, id uuid.UUID) (float64, error) {
+	var thresholds []float64
+	err := dbFor(ctx, r.db).
+		Model(&models.Establishment{}).
+		Where("id = ?", id).
+		Pluck("food_cost_threshold", &thresholds).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(thresholds) == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return thresholds[0], nil
+}
+
+func (r *establishmentRepository) UpdateFoodCostThreshold(ctx context.Context, id uuid.UUID, threshold float64) error {
+	return dbFor(ctx, r.db).
+		Model(&models.Establishment{}).
+		Where("id = ?", id).
+		Update("food_cost_threshold", threshold).Error
+}
+
+func (r *establishmentRepository) GetTimezone(ctx context.Context, id uuid.UUID) (string, error) {
+	var timezones []string
+	err := dbFor(ctx, r.db).
+		Model(&models.Establishment{}).
+		Where("id = ?", id).
+		Pluck("COALESCE(timezone, '')", &timezones).Error
+	if err != nil {
+		return "", err
+	}
+	if len(timezones) == 0 {
+		return "", gorm.ErrRecordNotFound
+	}
+	return timezones[0], nil
+}
+
+func (r *establishmentRepository) UpdateTimezone(ctx context.Context, id uuid.UUID, timezone string) error {
+	return dbFor(ctx, r.db).
+		Model(&models.Establishment{}).
+		Where("id = ?", id).
+		Update("timezone", timezone).Error
+}
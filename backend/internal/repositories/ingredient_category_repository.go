@@ -35,7 +35,7 @@ func NewIngredientCategoryRepository(db *gorm.DB) IngredientCategoryRepository {
 
 func (r *ingredientCategoryRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.IngredientCategory, error) {
 	var c models.IngredientCategory
-	q := r.db.WithContext(ctx)
+	q := dbFor(ctx, r.db)
 	if establishmentID != nil {
 		q = q.Where("establishment_id = ?", *establishmentID)
 	}
@@ -45,7 +45,7 @@ func (r *ingredientCategoryRepository) GetByID(ctx context.Context, id uuid.UUID
 
 func (r *ingredientCategoryRepository) List(ctx context.Context, filter *IngredientCategoryFilter) ([]*models.IngredientCategory, error) {
 	var list []*models.IngredientCategory
-	query := r.db.WithContext(ctx)
+	query := dbFor(ctx, r.db)
 
 	if filter != nil {
 		if filter.EstablishmentID != nil {
@@ -62,21 +62,21 @@ func (r *ingredientCategoryRepository) List(ctx context.Context, filter *Ingredi
 }
 
 func (r *ingredientCategoryRepository) Create(ctx context.Context, c *models.IngredientCategory) error {
-	return r.db.WithContext(ctx).Create(c).Error
+	return dbFor(ctx, r.db).Create(c).Error
 }
 
 func (r *ingredientCategoryRepository) Update(ctx context.Context, c *models.IngredientCategory) error {
-	return r.db.WithContext(ctx).Save(c).Error
+	return dbFor(ctx, r.db).Save(c).Error
 }
 
 func (r *ingredientCategoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.IngredientCategory{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.IngredientCategory{}, "id = ?", id).Error
 }
 
 // ListWithStats возвращает список категорий со статистикой ингредиентов и остатков
 func (r *ingredientCategoryRepository) ListWithStats(ctx context.Context, filter *IngredientCategoryFilter) ([]*models.IngredientCategoryWithStats, error) {
 	var categories []*models.IngredientCategory
-	query := r.db.WithContext(ctx)
+	query := dbFor(ctx, r.db)
 
 	if filter != nil {
 		if filter.EstablishmentID != nil {
@@ -115,7 +115,7 @@ func (r *ingredientCategoryRepository) ListWithStats(ctx context.Context, filter
 // GetWithStats возвращает категорию со статистикой по ID
 func (r *ingredientCategoryRepository) GetWithStats(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.IngredientCategoryWithStats, error) {
 	var cat models.IngredientCategory
-	q := r.db.WithContext(ctx)
+	q := dbFor(ctx, r.db)
 	if establishmentID != nil {
 		q = q.Where("establishment_id = ?", *establishmentID)
 	}
@@ -150,7 +150,7 @@ type categoryStats struct {
 func (r *ingredientCategoryRepository) getCategoryStats(ctx context.Context, categoryID uuid.UUID) (*categoryStats, error) {
 	// Подсчитываем количество ингредиентов в категории
 	var count int64
-	if err := r.db.WithContext(ctx).Model(&models.Ingredient{}).Where("category_id = ?", categoryID).Count(&count).Error; err != nil {
+	if err := dbFor(ctx, r.db).Model(&models.Ingredient{}).Where("category_id = ?", categoryID).Count(&count).Error; err != nil {
 		return nil, err
 	}
 
@@ -163,7 +163,7 @@ func (r *ingredientCategoryRepository) getCategoryStats(ctx context.Context, cat
 	}
 
 	var stockResults []stockResult
-	err := r.db.WithContext(ctx).Table("stocks").
+	err := dbFor(ctx, r.db).Table("stocks").
 		Select("stocks.warehouse_id, warehouses.name as warehouse_name, SUM(stocks.quantity) as total_quantity, stocks.unit").
 		Joins("LEFT JOIN warehouses ON stocks.warehouse_id = warehouses.id").
 		Joins("LEFT JOIN ingredients ON stocks.ingredient_id = ingredients.id").
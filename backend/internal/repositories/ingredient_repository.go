@@ -36,7 +36,7 @@ func NewIngredientRepository(db *gorm.DB) IngredientRepository {
 
 func (r *ingredientRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Ingredient, error) {
 	var ingredient models.Ingredient
-	q := r.db.WithContext(ctx).Preload("Category")
+	q := dbFor(ctx, r.db).Preload("Category").Preload("UnitConversions")
 	if establishmentID != nil {
 		q = q.Where("establishment_id = ?", *establishmentID)
 	}
@@ -46,7 +46,7 @@ func (r *ingredientRepository) GetByID(ctx context.Context, id uuid.UUID, establ
 
 func (r *ingredientRepository) List(ctx context.Context, filter *IngredientFilter) ([]*models.Ingredient, error) {
 	var ingredients []*models.Ingredient
-	query := r.db.WithContext(ctx).Preload("Category")
+	query := dbFor(ctx, r.db).Preload("Category").Preload("UnitConversions")
 
 	if filter != nil {
 		if filter.EstablishmentID != nil {
@@ -72,13 +72,29 @@ func (r *ingredientRepository) List(ctx context.Context, filter *IngredientFilte
 }
 
 func (r *ingredientRepository) Create(ctx context.Context, ingredient *models.Ingredient) error {
-	return r.db.WithContext(ctx).Create(ingredient).Error
+	return dbFor(ctx, r.db).Create(ingredient).Error
 }
 
 func (r *ingredientRepository) Update(ctx context.Context, ingredient *models.Ingredient) error {
-	return r.db.WithContext(ctx).Save(ingredient).Error
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("UnitConversions", "Category").Save(ingredient).Error; err != nil {
+			return err
+		}
+		// Пересчеты единиц заменяем полностью
+		if err := tx.Where("ingredient_id = ?", ingredient.ID).Delete(&models.IngredientUnitConversion{}).Error; err != nil {
+			return err
+		}
+		for i := range ingredient.UnitConversions {
+			ingredient.UnitConversions[i].ID = uuid.Nil
+			ingredient.UnitConversions[i].IngredientID = ingredient.ID
+			if err := tx.Create(&ingredient.UnitConversions[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (r *ingredientRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Ingredient{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.Ingredient{}, "id = ?", id).Error
 }
\ No newline at end of file
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,6 +19,16 @@ type InventoryFilter struct {
 	Status          *models.InventoryStatus
 }
 
+// InventoryCompletion изменения склада, проводимые при завершении инвентаризации
+type InventoryCompletion struct {
+	StockDeltas   map[uuid.UUID]float64 // Корректировка количества по ID остатка
+	NewStocks     []*models.Stock       // Остатки позиций, которых не было на складе
+	Lots          []*models.StockLot    // Партии, уменьшенные недостачей
+	NewLots       []*models.StockLot    // Партии излишков
+	Consumptions  []*models.StockLotConsumption
+	LedgerEntries []*models.StockLedgerEntry
+}
+
 // InventoryRepository интерфейс репозитория инвентаризаций
 type InventoryRepository interface {
 	// Inventory CRUD
@@ -27,6 +38,12 @@ type InventoryRepository interface {
 	Update(ctx context.Context, inventory *models.Inventory) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	UpdateStatus(ctx context.Context, id uuid.UUID, status models.InventoryStatus) error
+	// Complete в одной транзакции переводит инвентаризацию в статус completed, сохраняет
+	// пересчитанные позиции и проводит корректировки остатков, партий и журнала
+	Complete(ctx context.Context, inventory *models.Inventory, completion *InventoryCompletion) error
+	// GetLossesByPnlBlock возвращает итог завершенных инвентаризаций (недостача минус излишки)
+	// за период по блокам ОПиУ причины списания (cost, expenses)
+	GetLossesByPnlBlock(ctx context.Context, establishmentID uuid.UUID, startDate, endDate time.Time) (map[string]float64, error)
 
 	// Inventory Items
 	CreateItem(ctx context.Context, item *models.InventoryItem) error
@@ -34,6 +51,12 @@ type InventoryRepository interface {
 	DeleteItem(ctx context.Context, id uuid.UUID) error
 	GetItemsByInventoryID(ctx context.Context, inventoryID uuid.UUID) ([]*models.InventoryItem, error)
 
+	// Inventory Counts (подсчеты по зонам нескольких счетчиков)
+	// SaveCounts сохраняет подсчеты, заменяя прежние записи того же счетчика по той же позиции и зоне
+	// (для итоговых записей сверки — любую прежнюю итоговую запись позиции и зоны)
+	SaveCounts(ctx context.Context, counts []*models.InventoryCount) error
+	ListCounts(ctx context.Context, inventoryID uuid.UUID) ([]*models.InventoryCount, error)
+
 	// Stock snapshot - получение остатков на определенную дату
 	GetStockSnapshot(ctx context.Context, warehouseID uuid.UUID, date *time.Time) ([]*models.Stock, error)
 }
@@ -47,7 +70,7 @@ func NewInventoryRepository(db *gorm.DB) InventoryRepository {
 }
 
 func (r *inventoryRepository) Create(ctx context.Context, inventory *models.Inventory) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
 		items := inventory.Items
 		inventory.Items = nil
 
@@ -72,7 +95,7 @@ func (r *inventoryRepository) Create(ctx context.Context, inventory *models.Inve
 
 func (r *inventoryRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Inventory, error) {
 	var inventory models.Inventory
-	query := r.db.WithContext(ctx).
+	query := dbFor(ctx, r.db).
 		Preload("Warehouse").
 		Preload("Items.Ingredient").
 		Preload("Items.Product").
@@ -92,7 +115,7 @@ func (r *inventoryRepository) GetByID(ctx context.Context, id uuid.UUID, establi
 
 func (r *inventoryRepository) List(ctx context.Context, filter *InventoryFilter) ([]*models.Inventory, error) {
 	var inventories []*models.Inventory
-	query := r.db.WithContext(ctx).
+	query := dbFor(ctx, r.db).
 		Preload("Warehouse").
 		Preload("Items")
 
@@ -116,35 +139,130 @@ func (r *inventoryRepository) List(ctx context.Context, filter *InventoryFilter)
 }
 
 func (r *inventoryRepository) Update(ctx context.Context, inventory *models.Inventory) error {
-	return r.db.WithContext(ctx).Save(inventory).Error
+	return dbFor(ctx, r.db).Save(inventory).Error
 }
 
 func (r *inventoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Inventory{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.Inventory{}, "id = ?", id).Error
 }
 
 func (r *inventoryRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status models.InventoryStatus) error {
-	return r.db.WithContext(ctx).
+	return dbFor(ctx, r.db).
 		Model(&models.Inventory{}).
 		Where("id = ?", id).
 		Update("status", status).Error
 }
 
+func (r *inventoryRepository) Complete(ctx context.Context, inventory *models.Inventory, completion *InventoryCompletion) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		// Статус проверяем в том же запросе, чтобы инвентаризацию нельзя было провести дважды
+		res := tx.Model(&models.Inventory{}).
+			Where("id = ? AND status = ?", inventory.ID, models.InventoryStatusInProgress).
+			Updates(map[string]interface{}{
+				"status":              models.InventoryStatusCompleted,
+				"actual_date":         inventory.ActualDate,
+				"count_date":          inventory.CountDate,
+				"completed_by":        inventory.CompletedBy,
+				"write_off_reason_id": inventory.WriteOffReasonID,
+				"shortage_amount":     models.RoundTo2(inventory.ShortageAmount),
+				"surplus_amount":      models.RoundTo2(inventory.SurplusAmount),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("inventory is not in progress")
+		}
+
+		for i := range inventory.Items {
+			item := &inventory.Items[i]
+			if err := tx.Model(&models.InventoryItem{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+				"expected_quantity": models.RoundTo2(item.ExpectedQuantity),
+				"price_per_unit":    models.RoundTo2(item.PricePerUnit),
+				"difference":        models.RoundTo2(item.Difference),
+				"difference_value":  models.RoundTo2(item.DifferenceValue),
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		for stockID, delta := range completion.StockDeltas {
+			if err := tx.Model(&models.Stock{}).Where("id = ?", stockID).
+				Update("quantity", gorm.Expr("quantity + ?", models.RoundTo2(delta))).Error; err != nil {
+				return err
+			}
+		}
+		for _, st := range completion.NewStocks {
+			if err := tx.Create(st).Error; err != nil {
+				return err
+			}
+		}
+		for _, lot := range completion.Lots {
+			if err := tx.Model(&models.StockLot{}).Where("id = ?", lot.ID).
+				Update("remaining_quantity", models.RoundTo2(lot.RemainingQuantity)).Error; err != nil {
+				return err
+			}
+		}
+		for _, lot := range completion.NewLots {
+			if err := tx.Create(lot).Error; err != nil {
+				return err
+			}
+		}
+		for _, c := range completion.Consumptions {
+			if err := tx.Create(c).Error; err != nil {
+				return err
+			}
+		}
+		for _, e := range completion.LedgerEntries {
+			if err := tx.Create(e).Error; err != nil {
+				return err
+			}
+		}
+
+		inventory.Status = models.InventoryStatusCompleted
+		return nil
+	})
+}
+
+func (r *inventoryRepository) GetLossesByPnlBlock(ctx context.Context, establishmentID uuid.UUID, startDate, endDate time.Time) (map[string]float64, error) {
+	var rows []struct {
+		PnlBlock string
+		Total    float64
+	}
+	err := dbFor(ctx, r.db).
+		Model(&models.Inventory{}).
+		Select("COALESCE(write_off_reasons.pnl_block, 'cost') AS pnl_block, COALESCE(SUM(inventories.shortage_amount - inventories.surplus_amount), 0) AS total").
+		Joins("LEFT JOIN write_off_reasons ON write_off_reasons.id = inventories.write_off_reason_id").
+		Where("inventories.establishment_id = ? AND inventories.status = ?", establishmentID, models.InventoryStatusCompleted).
+		Where("inventories.count_date >= ? AND inventories.count_date <= ?", startDate, endDate).
+		Group("COALESCE(write_off_reasons.pnl_block, 'cost')").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		result[row.PnlBlock] = models.RoundTo2(row.Total)
+	}
+	return result, nil
+}
+
 func (r *inventoryRepository) CreateItem(ctx context.Context, item *models.InventoryItem) error {
-	return r.db.WithContext(ctx).Create(item).Error
+	return dbFor(ctx, r.db).Create(item).Error
 }
 
 func (r *inventoryRepository) UpdateItem(ctx context.Context, item *models.InventoryItem) error {
-	return r.db.WithContext(ctx).Save(item).Error
+	return dbFor(ctx, r.db).Save(item).Error
 }
 
 func (r *inventoryRepository) DeleteItem(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.InventoryItem{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.InventoryItem{}, "id = ?", id).Error
 }
 
 func (r *inventoryRepository) GetItemsByInventoryID(ctx context.Context, inventoryID uuid.UUID) ([]*models.InventoryItem, error) {
 	var items []*models.InventoryItem
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Preload("Ingredient").
 		Preload("Product").
 		Preload("TechCard").
@@ -154,9 +272,37 @@ func (r *inventoryRepository) GetItemsByInventoryID(ctx context.Context, invento
 	return items, err
 }
 
+func (r *inventoryRepository) SaveCounts(ctx context.Context, counts []*models.InventoryCount) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		for _, c := range counts {
+			q := tx.Where("item_id = ? AND zone = ? AND is_final = ?", c.ItemID, c.Zone, c.IsFinal)
+			if !c.IsFinal {
+				q = q.Where("counter_id = ?", c.CounterID)
+			}
+			if err := q.Delete(&models.InventoryCount{}).Error; err != nil {
+				return err
+			}
+			c.Item = nil
+			if err := tx.Create(c).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *inventoryRepository) ListCounts(ctx context.Context, inventoryID uuid.UUID) ([]*models.InventoryCount, error) {
+	var counts []*models.InventoryCount
+	err := dbFor(ctx, r.db).
+		Where("inventory_id = ?", inventoryID).
+		Order("zone, created_at").
+		Find(&counts).Error
+	return counts, err
+}
+
 func (r *inventoryRepository) GetStockSnapshot(ctx context.Context, warehouseID uuid.UUID, date *time.Time) ([]*models.Stock, error) {
 	var stock []*models.Stock
-	query := r.db.WithContext(ctx).
+	query := dbFor(ctx, r.db).
 		Preload("Ingredient").
 		Preload("Product").
 		Where("warehouse_id = ?", warehouseID)
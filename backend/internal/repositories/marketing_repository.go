@@ -30,7 +30,7 @@ func NewClientGroupRepository(db *gorm.DB) ClientGroupRepository {
 
 func (r *clientGroupRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ClientGroup, error) {
 	var group models.ClientGroup
-	err := r.db.WithContext(ctx).First(&group, "id = ?", id).Error
+	err := dbFor(ctx, r.db).First(&group, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrClientGroupNotFound
@@ -42,7 +42,7 @@ func (r *clientGroupRepository) GetByID(ctx context.Context, id uuid.UUID) (*mod
 
 func (r *clientGroupRepository) GetAllByEstablishmentID(ctx context.Context, establishmentID uuid.UUID) ([]*models.ClientGroup, error) {
 	var groups []*models.ClientGroup
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("establishment_id = ?", establishmentID).
 		Find(&groups).Error
 	if err != nil {
@@ -52,19 +52,19 @@ func (r *clientGroupRepository) GetAllByEstablishmentID(ctx context.Context, est
 }
 
 func (r *clientGroupRepository) Create(ctx context.Context, group *models.ClientGroup) error {
-	return r.db.WithContext(ctx).Create(group).Error
+	return dbFor(ctx, r.db).Create(group).Error
 }
 
 func (r *clientGroupRepository) Update(ctx context.Context, group *models.ClientGroup) error {
-	return r.db.WithContext(ctx).Save(group).Error
+	return dbFor(ctx, r.db).Save(group).Error
 }
 
 func (r *clientGroupRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.ClientGroup{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.ClientGroup{}, "id = ?", id).Error
 }
 
 func (r *clientGroupRepository) UpdateCustomersCount(ctx context.Context, groupID uuid.UUID) error {
-	return r.db.WithContext(ctx).
+	return dbFor(ctx, r.db).
 		Model(&models.ClientGroup{}).
 		Where("id = ?", groupID).
 		Update("customers_count", gorm.Expr(
@@ -93,7 +93,7 @@ func NewLoyaltyProgramRepository(db *gorm.DB) LoyaltyProgramRepository {
 
 func (r *loyaltyProgramRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.LoyaltyProgram, error) {
 	var program models.LoyaltyProgram
-	err := r.db.WithContext(ctx).First(&program, "id = ?", id).Error
+	err := dbFor(ctx, r.db).First(&program, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrLoyaltyProgramNotFound
@@ -105,7 +105,7 @@ func (r *loyaltyProgramRepository) GetByID(ctx context.Context, id uuid.UUID) (*
 
 func (r *loyaltyProgramRepository) GetAllByEstablishmentID(ctx context.Context, establishmentID uuid.UUID) ([]*models.LoyaltyProgram, error) {
 	var programs []*models.LoyaltyProgram
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("establishment_id = ?", establishmentID).
 		Find(&programs).Error
 	if err != nil {
@@ -115,19 +115,19 @@ func (r *loyaltyProgramRepository) GetAllByEstablishmentID(ctx context.Context,
 }
 
 func (r *loyaltyProgramRepository) Create(ctx context.Context, program *models.LoyaltyProgram) error {
-	return r.db.WithContext(ctx).Create(program).Error
+	return dbFor(ctx, r.db).Create(program).Error
 }
 
 func (r *loyaltyProgramRepository) Update(ctx context.Context, program *models.LoyaltyProgram) error {
-	return r.db.WithContext(ctx).Save(program).Error
+	return dbFor(ctx, r.db).Save(program).Error
 }
 
 func (r *loyaltyProgramRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.LoyaltyProgram{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.LoyaltyProgram{}, "id = ?", id).Error
 }
 
 func (r *loyaltyProgramRepository) UpdateMembersCount(ctx context.Context, programID uuid.UUID) error {
-	return r.db.WithContext(ctx).
+	return dbFor(ctx, r.db).
 		Model(&models.LoyaltyProgram{}).
 		Where("id = ?", programID).
 		Update("members_count", gorm.Expr(
@@ -157,7 +157,7 @@ func NewPromotionRepository(db *gorm.DB) PromotionRepository {
 
 func (r *promotionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Promotion, error) {
 	var promotion models.Promotion
-	err := r.db.WithContext(ctx).First(&promotion, "id = ?", id).Error
+	err := dbFor(ctx, r.db).First(&promotion, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrPromotionNotFound
@@ -169,7 +169,7 @@ func (r *promotionRepository) GetByID(ctx context.Context, id uuid.UUID) (*model
 
 func (r *promotionRepository) GetAllByEstablishmentID(ctx context.Context, establishmentID uuid.UUID) ([]*models.Promotion, error) {
 	var promotions []*models.Promotion
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("establishment_id = ?", establishmentID).
 		Find(&promotions).Error
 	if err != nil {
@@ -181,7 +181,7 @@ func (r *promotionRepository) GetAllByEstablishmentID(ctx context.Context, estab
 func (r *promotionRepository) GetActive(ctx context.Context, establishmentID uuid.UUID) ([]*models.Promotion, error) {
 	var promotions []*models.Promotion
 	now := r.db.NowFunc()
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("establishment_id = ? AND active = ? AND start_date <= ? AND end_date >= ?",
 			establishmentID, true, now, now).
 		Find(&promotions).Error
@@ -192,19 +192,19 @@ func (r *promotionRepository) GetActive(ctx context.Context, establishmentID uui
 }
 
 func (r *promotionRepository) Create(ctx context.Context, promotion *models.Promotion) error {
-	return r.db.WithContext(ctx).Create(promotion).Error
+	return dbFor(ctx, r.db).Create(promotion).Error
 }
 
 func (r *promotionRepository) Update(ctx context.Context, promotion *models.Promotion) error {
-	return r.db.WithContext(ctx).Save(promotion).Error
+	return dbFor(ctx, r.db).Save(promotion).Error
 }
 
 func (r *promotionRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Promotion{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.Promotion{}, "id = ?", id).Error
 }
 
 func (r *promotionRepository) IncrementUsageCount(ctx context.Context, promotionID uuid.UUID) error {
-	return r.db.WithContext(ctx).
+	return dbFor(ctx, r.db).
 		Model(&models.Promotion{}).
 		Where("id = ?", promotionID).
 		Update("usage_count", gorm.Expr("usage_count + 1")).Error
@@ -230,7 +230,7 @@ func NewExclusionRepository(db *gorm.DB) ExclusionRepository {
 
 func (r *exclusionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Exclusion, error) {
 	var exclusion models.Exclusion
-	err := r.db.WithContext(ctx).First(&exclusion, "id = ?", id).Error
+	err := dbFor(ctx, r.db).First(&exclusion, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrExclusionNotFound
@@ -242,7 +242,7 @@ func (r *exclusionRepository) GetByID(ctx context.Context, id uuid.UUID) (*model
 
 func (r *exclusionRepository) GetAllByEstablishmentID(ctx context.Context, establishmentID uuid.UUID) ([]*models.Exclusion, error) {
 	var exclusions []*models.Exclusion
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("establishment_id = ?", establishmentID).
 		Find(&exclusions).Error
 	if err != nil {
@@ -253,7 +253,7 @@ func (r *exclusionRepository) GetAllByEstablishmentID(ctx context.Context, estab
 
 func (r *exclusionRepository) GetActive(ctx context.Context, establishmentID uuid.UUID) ([]*models.Exclusion, error) {
 	var exclusions []*models.Exclusion
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("establishment_id = ? AND active = ?", establishmentID, true).
 		Find(&exclusions).Error
 	if err != nil {
@@ -263,13 +263,13 @@ func (r *exclusionRepository) GetActive(ctx context.Context, establishmentID uui
 }
 
 func (r *exclusionRepository) Create(ctx context.Context, exclusion *models.Exclusion) error {
-	return r.db.WithContext(ctx).Create(exclusion).Error
+	return dbFor(ctx, r.db).Create(exclusion).Error
 }
 
 func (r *exclusionRepository) Update(ctx context.Context, exclusion *models.Exclusion) error {
-	return r.db.WithContext(ctx).Save(exclusion).Error
+	return dbFor(ctx, r.db).Save(exclusion).Error
 }
 
 func (r *exclusionRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Exclusion{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.Exclusion{}, "id = ?", id).Error
 }
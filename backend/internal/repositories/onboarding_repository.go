@@ -31,7 +31,7 @@ func NewOnboardingRepository(db *gorm.DB) OnboardingRepository {
 
 func (r *onboardingRepository) GetQuestions(ctx context.Context) ([]*models.OnboardingQuestion, error) {
 	var questions []*models.OnboardingQuestion
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("active = ?", true).
 		Preload("Options").
 		Order("step ASC, \"order\" ASC").
@@ -41,7 +41,7 @@ func (r *onboardingRepository) GetQuestions(ctx context.Context) ([]*models.Onbo
 
 func (r *onboardingRepository) GetQuestionByKey(ctx context.Context, key string) (*models.OnboardingQuestion, error) {
 	var question models.OnboardingQuestion
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("key = ? AND active = ?", key, true).
 		Preload("Options").
 		First(&question).Error
@@ -50,7 +50,7 @@ func (r *onboardingRepository) GetQuestionByKey(ctx context.Context, key string)
 
 func (r *onboardingRepository) GetResponseByUserID(ctx context.Context, userID uuid.UUID) (*models.OnboardingResponse, error) {
 	var response models.OnboardingResponse
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("user_id = ?", userID).
 		Preload("Answers").
 		First(&response).Error
@@ -64,13 +64,13 @@ func (r *onboardingRepository) GetResponseByUserID(ctx context.Context, userID u
 }
 
 func (r *onboardingRepository) CreateResponse(ctx context.Context, response *models.OnboardingResponse) error {
-	return r.db.WithContext(ctx).Create(response).Error
+	return dbFor(ctx, r.db).Create(response).Error
 }
 
 func (r *onboardingRepository) UpdateResponse(ctx context.Context, response *models.OnboardingResponse) error {
-	return r.db.WithContext(ctx).Session(&gorm.Session{FullSaveAssociations: true}).Save(response).Error
+	return dbFor(ctx, r.db).Session(&gorm.Session{FullSaveAssociations: true}).Save(response).Error
 }
 
 func (r *onboardingRepository) DeleteResponse(ctx context.Context, userID uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.OnboardingResponse{}, "user_id = ?", userID).Error
+	return dbFor(ctx, r.db).Delete(&models.OnboardingResponse{}, "user_id = ?", userID).Error
 }
@@ -33,13 +33,13 @@ func NewOrderRepository(db *gorm.DB) OrderRepository {
 
 func (r *orderRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Order, error) {
 	var order models.Order
-	err := r.db.WithContext(ctx).Preload("Items.Product").Preload("Items.Product.Category").Preload("Items.TechCard").Preload("Table").First(&order, "id = ?", id).Error
+	err := dbFor(ctx, r.db).Preload("Items.Product").Preload("Items.Product.Category").Preload("Items.TechCard").Preload("Items.Combo").Preload("Items.Components.Product").Preload("Items.Components.TechCard").Preload("Table").First(&order, "id = ?", id).Error
 	return &order, err
 }
 
 func (r *orderRepository) List(ctx context.Context, establishmentID uuid.UUID, startDate, endDate time.Time, status string) ([]*models.Order, error) {
 	var orders []*models.Order
-	query := r.db.WithContext(ctx).Preload("Items.Product").Preload("Items.Product.Category").Preload("Items.TechCard").Preload("Table").Where("establishment_id = ?", establishmentID)
+	query := dbFor(ctx, r.db).Preload("Items.Product").Preload("Items.Product.Category").Preload("Items.TechCard").Preload("Items.Combo").Preload("Items.Components.Product").Preload("Items.Components.TechCard").Preload("Table").Where("establishment_id = ?", establishmentID)
 
 	if !startDate.IsZero() {
 		query = query.Where("created_at >= ?", startDate)
@@ -57,29 +57,29 @@ func (r *orderRepository) List(ctx context.Context, establishmentID uuid.UUID, s
 
 func (r *orderRepository) ListActiveByEstablishmentID(ctx context.Context, establishmentID uuid.UUID) ([]*models.Order, error) {
 	var orders []*models.Order
-	err := r.db.WithContext(ctx).Preload("Items.Product").Preload("Items.Product.Category").Preload("Items.TechCard").Preload("Table").Where("establishment_id = ? AND status IN (?, ?, ?)", establishmentID, "draft", "confirmed", "preparing").Find(&orders).Error
+	err := dbFor(ctx, r.db).Preload("Items.Product").Preload("Items.Product.Category").Preload("Items.TechCard").Preload("Items.Combo").Preload("Items.Components.Product").Preload("Items.Components.TechCard").Preload("Table").Where("establishment_id = ? AND status IN (?, ?, ?)", establishmentID, "draft", "confirmed", "preparing").Find(&orders).Error
 	return orders, err
 }
 
 func (r *orderRepository) Create(ctx context.Context, order *models.Order) error {
-	return r.db.WithContext(ctx).Create(order).Error
+	return dbFor(ctx, r.db).Create(order).Error
 }
 
 func (r *orderRepository) Update(ctx context.Context, order *models.Order) error {
-	return r.db.WithContext(ctx).Save(order).Error
+	return dbFor(ctx, r.db).Save(order).Error
 }
 
 func (r *orderRepository) CreateOrderItem(ctx context.Context, item *models.OrderItem) error {
-	return r.db.WithContext(ctx).Create(item).Error
+	return dbFor(ctx, r.db).Create(item).Error
 }
 
 func (r *orderRepository) UpdateOrderItem(ctx context.Context, item *models.OrderItem) error {
-	return r.db.WithContext(ctx).Save(item).Error
+	return dbFor(ctx, r.db).Save(item).Error
 }
 
 func (r *orderRepository) ListByShiftIDAndEstablishmentIDAndDateRange(ctx context.Context, shiftID, establishmentID uuid.UUID, startDate, endDate time.Time) ([]*models.Order, error) {
 	var orders []*models.Order
-	query := r.db.WithContext(ctx).Preload("Items.Product").Preload("Items.Product.Category").Preload("Items.TechCard").Preload("Table").Where("shift_id = ? AND establishment_id = ?", shiftID, establishmentID)
+	query := dbFor(ctx, r.db).Preload("Items.Product").Preload("Items.Product.Category").Preload("Items.TechCard").Preload("Items.Combo").Preload("Items.Components.Product").Preload("Items.Components.TechCard").Preload("Table").Where("shift_id = ? AND establishment_id = ?", shiftID, establishmentID)
 
 	if !startDate.IsZero() {
 		query = query.Where("created_at >= ?", startDate)
@@ -94,7 +94,7 @@ func (r *orderRepository) ListByShiftIDAndEstablishmentIDAndDateRange(ctx contex
 
 func (r *orderRepository) GetTotalSalesByUserIDAndDateRange(ctx context.Context, userID, establishmentID uuid.UUID, startDate, endDate time.Time) (float64, error) {
 	var total float64
-	query := r.db.WithContext(ctx).Model(&models.Order{}).
+	query := dbFor(ctx, r.db).Model(&models.Order{}).
 		Select("COALESCE(SUM(total_amount), 0)").
 		Where("establishment_id = ? AND created_by_id = ?", establishmentID, userID)
 
@@ -112,10 +112,13 @@ func (r *orderRepository) GetTotalSalesByUserIDAndDateRange(ctx context.Context,
 // ListByEstablishmentIDAndDateRange возвращает заказы по заведению и диапазону дат
 func (r *orderRepository) ListByEstablishmentIDAndDateRange(ctx context.Context, establishmentID uuid.UUID, startDate, endDate time.Time) ([]*models.Order, error) {
 	var orders []*models.Order
-	query := r.db.WithContext(ctx).
+	query := dbFor(ctx, r.db).
 		Preload("Items.Product").
 		Preload("Items.Product.Category").
 		Preload("Items.TechCard").
+		Preload("Items.Combo").
+		Preload("Items.Components.Product").
+		Preload("Items.Components.TechCard").
 		Preload("Table").
 		Where("establishment_id = ?", establishmentID)
 
@@ -16,6 +16,11 @@ type ProductFilter struct {
 	WorkshopID     *uuid.UUID
 	Search         *string
 	Active         *bool
+	ParentID       *uuid.UUID // Только модификации указанного товара
+	// Только товары верхнего уровня (без модификаций в списке); модификации загружаются в Variants
+	OnlyParents bool
+	// Только доступные сейчас по расписанию (применяется в MenuUseCase)
+	AvailableNow bool
 }
 
 type ProductRepository interface {
@@ -24,6 +29,10 @@ type ProductRepository interface {
 	Create(ctx context.Context, product *models.Product) error
 	Update(ctx context.Context, product *models.Product) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	// CountVariants возвращает число модификаций товара
+	CountVariants(ctx context.Context, parentID uuid.UUID) (int64, error)
+	// SyncVariants переносит категорию, цех и склад родительского товара на его модификации
+	SyncVariants(ctx context.Context, parent *models.Product) error
 }
 
 type productRepository struct {
@@ -36,7 +45,7 @@ func NewProductRepository(db *gorm.DB) ProductRepository {
 
 func (r *productRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Product, error) {
 	var product models.Product
-	q := r.db.WithContext(ctx).Preload("Warehouse")
+	q := dbFor(ctx, r.db).Preload("Warehouse")
 	if establishmentID != nil {
 		q = q.Where("establishment_id = ?", *establishmentID)
 	}
@@ -46,7 +55,7 @@ func (r *productRepository) GetByID(ctx context.Context, id uuid.UUID, establish
 
 func (r *productRepository) List(ctx context.Context, filter *ProductFilter) ([]*models.Product, error) {
 	var products []*models.Product
-	query := r.db.WithContext(ctx).Preload("Category").Preload("Workshop")
+	query := dbFor(ctx, r.db).Preload("Category").Preload("Workshop")
 
 	if filter != nil {
 		if filter.EstablishmentID != nil {
@@ -65,6 +74,17 @@ func (r *productRepository) List(ctx context.Context, filter *ProductFilter) ([]
 		if filter.Active != nil {
 			query = query.Where("active = ?", *filter.Active)
 		}
+		if filter.ParentID != nil {
+			query = query.Where("parent_id = ?", *filter.ParentID)
+		}
+		if filter.OnlyParents {
+			query = query.Where("parent_id IS NULL").Preload("Variants", func(db *gorm.DB) *gorm.DB {
+				if filter.Active != nil {
+					db = db.Where("active = ?", *filter.Active)
+				}
+				return db.Order("name")
+			})
+		}
 	}
 
 	err := query.Find(&products).Error
@@ -72,13 +92,30 @@ func (r *productRepository) List(ctx context.Context, filter *ProductFilter) ([]
 }
 
 func (r *productRepository) Create(ctx context.Context, product *models.Product) error {
-	return r.db.WithContext(ctx).Create(product).Error
+	return dbFor(ctx, r.db).Create(product).Error
 }
 
 func (r *productRepository) Update(ctx context.Context, product *models.Product) error {
-	return r.db.WithContext(ctx).Save(product).Error
+	return dbFor(ctx, r.db).Omit("Variants").Save(product).Error
 }
 
+// Delete удаляет товар вместе с его модификациями
 func (r *productRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Product{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.Product{}, "id = ? OR parent_id = ?", id, id).Error
+}
+
+func (r *productRepository) CountVariants(ctx context.Context, parentID uuid.UUID) (int64, error) {
+	var count int64
+	err := dbFor(ctx, r.db).Model(&models.Product{}).Where("parent_id = ?", parentID).Count(&count).Error
+	return count, err
+}
+
+func (r *productRepository) SyncVariants(ctx context.Context, parent *models.Product) error {
+	return dbFor(ctx, r.db).Model(&models.Product{}).
+		Where("parent_id = ?", parent.ID).
+		Updates(map[string]interface{}{
+			"category_id":  parent.CategoryID,
+			"workshop_id":  parent.WorkshopID,
+			"warehouse_id": parent.WarehouseID,
+		}).Error
 }
\ No newline at end of file
@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+// PurchaseOrderFilter фильтр для списка заказов поставщикам
+type PurchaseOrderFilter struct {
+	EstablishmentID *uuid.UUID
+	SupplierID      *uuid.UUID
+	WarehouseID     *uuid.UUID
+	Status          *models.PurchaseOrderStatus
+	Statuses        []models.PurchaseOrderStatus
+}
+
+// PurchaseOrderRepository интерфейс репозитория заказов поставщикам
+type PurchaseOrderRepository interface {
+	Create(ctx context.Context, order *models.PurchaseOrder) error
+	GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.PurchaseOrder, error)
+	List(ctx context.Context, filter *PurchaseOrderFilter) ([]*models.PurchaseOrder, error)
+	Update(ctx context.Context, order *models.PurchaseOrder) error
+	ReplaceItems(ctx context.Context, order *models.PurchaseOrder) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type purchaseOrderRepository struct {
+	db *gorm.DB
+}
+
+func NewPurchaseOrderRepository(db *gorm.DB) PurchaseOrderRepository {
+	return &purchaseOrderRepository{db: db}
+}
+
+func (r *purchaseOrderRepository) Create(ctx context.Context, order *models.PurchaseOrder) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		items := order.Items
+		order.Items = nil
+
+		if err := tx.Create(order).Error; err != nil {
+			return err
+		}
+
+		for i := range items {
+			items[i].PurchaseOrderID = order.ID
+			if err := tx.Create(&items[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		order.Items = items
+		return nil
+	})
+}
+
+func (r *purchaseOrderRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.PurchaseOrder, error) {
+	var order models.PurchaseOrder
+	q := dbFor(ctx, r.db).
+		Preload("Supplier").
+		Preload("Warehouse").
+		Preload("Items.Ingredient").
+		Preload("Items.Product").
+		Preload("Supplies.Items")
+	if establishmentID != nil {
+		q = q.Where("establishment_id = ?", *establishmentID)
+	}
+	err := q.First(&order, "id = ?", id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &order, err
+}
+
+func (r *purchaseOrderRepository) List(ctx context.Context, filter *PurchaseOrderFilter) ([]*models.PurchaseOrder, error) {
+	query := dbFor(ctx, r.db).
+		Preload("Supplier").
+		Preload("Warehouse").
+		Preload("Items.Ingredient").
+		Preload("Items.Product")
+
+	if filter != nil {
+		if filter.EstablishmentID != nil {
+			query = query.Where("establishment_id = ?", *filter.EstablishmentID)
+		}
+		if filter.SupplierID != nil {
+			query = query.Where("supplier_id = ?", *filter.SupplierID)
+		}
+		if filter.WarehouseID != nil {
+			query = query.Where("warehouse_id = ?", *filter.WarehouseID)
+		}
+		if filter.Status != nil {
+			query = query.Where("status = ?", *filter.Status)
+		}
+		if len(filter.Statuses) > 0 {
+			query = query.Where("status IN ?", filter.Statuses)
+		}
+	}
+
+	var orders []*models.PurchaseOrder
+	err := query.Order("created_at DESC").Find(&orders).Error
+	return orders, err
+}
+
+// Update сохраняет шапку заказа и полученные количества по позициям
+func (r *purchaseOrderRepository) Update(ctx context.Context, order *models.PurchaseOrder) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.PurchaseOrder{}).Where("id = ?", order.ID).Updates(map[string]interface{}{
+			"supplier_id":     order.SupplierID,
+			"warehouse_id":    order.WarehouseID,
+			"status":          order.Status,
+			"expected_date":   order.ExpectedDate,
+			"comment":         order.Comment,
+			"total_amount":    models.RoundTo2(order.TotalAmount),
+			"received_amount": models.RoundTo2(order.ReceivedAmount),
+			"sent_at":         order.SentAt,
+			"closed_at":       order.ClosedAt,
+		}).Error; err != nil {
+			return err
+		}
+		for _, item := range order.Items {
+			if err := tx.Model(&models.PurchaseOrderItem{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+				"received_quantity": models.RoundTo2(item.ReceivedQuantity),
+				"received_amount":   models.RoundTo2(item.ReceivedAmount),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReplaceItems заменяет позиции заказа (используется для черновиков)
+func (r *purchaseOrderRepository) ReplaceItems(ctx context.Context, order *models.PurchaseOrder) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("purchase_order_id = ?", order.ID).Delete(&models.PurchaseOrderItem{}).Error; err != nil {
+			return err
+		}
+		for i := range order.Items {
+			order.Items[i].ID = uuid.Nil
+			order.Items[i].PurchaseOrderID = order.ID
+			if err := tx.Create(&order.Items[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *purchaseOrderRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("purchase_order_id = ?", id).Delete(&models.PurchaseOrderItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.PurchaseOrder{}, "id = ?", id).Error
+	})
+}
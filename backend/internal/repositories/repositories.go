@@ -6,6 +6,9 @@ import (
 
 // Repositories содержит все репозитории приложения
 type Repositories struct {
+	// Transactor объединяет операции нескольких репозиториев в одну транзакцию
+	Transactor Transactor
+
 	User         UserRepository
 	Role         RoleRepository
 	Establishment EstablishmentRepository
@@ -30,6 +33,15 @@ type Repositories struct {
 	Account      AccountRepository
 	AccountType  AccountTypeRepository
 	Inventory    InventoryRepository
+	StockAlert         StockAlertRepository
+	PurchaseOrder      PurchaseOrderRepository
+	SupplierPayment    SupplierPaymentRepository
+	Barcode            BarcodeRepository
+	CostHistory        CostHistoryRepository
+	Repricing          RepricingRepository
+	Availability       AvailabilityRepository
+	Combo              ComboRepository
+	Upload             UploadRepository
 	// Marketing repositories
 	Client             ClientRepository
 	ClientGroup         ClientGroupRepository
@@ -41,6 +53,8 @@ type Repositories struct {
 // NewRepositories создает все репозитории
 func NewRepositories(db *gorm.DB) *Repositories {
 	return &Repositories{
+		Transactor: NewTransactor(db),
+
 		User:         NewUserRepository(db),
 		Role:         NewRoleRepository(db),
 		Establishment: NewEstablishmentRepository(db),
@@ -65,6 +79,15 @@ func NewRepositories(db *gorm.DB) *Repositories {
 		Account:      NewAccountRepository(db),
 		AccountType:  NewAccountTypeRepository(db),
 		Inventory:    NewInventoryRepository(db),
+		StockAlert:         NewStockAlertRepository(db),
+		PurchaseOrder:      NewPurchaseOrderRepository(db),
+		SupplierPayment:    NewSupplierPaymentRepository(db),
+		Barcode:            NewBarcodeRepository(db),
+		CostHistory:        NewCostHistoryRepository(db),
+		Repricing:          NewRepricingRepository(db),
+		Availability:       NewAvailabilityRepository(db),
+		Combo:              NewComboRepository(db),
+		Upload:             NewUploadRepository(db),
 		Client:             NewClientRepository(db),
 		ClientGroup:         NewClientGroupRepository(db),
 		LoyaltyProgram:      NewLoyaltyProgramRepository(db),
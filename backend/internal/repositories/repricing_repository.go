@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+// PriceChangeFilter фильтр предложений об изменении цены
+type PriceChangeFilter struct {
+	EstablishmentID *uuid.UUID
+	Status          *string
+	IDs             []uuid.UUID
+}
+
+// RepricingRepository интерфейс репозитория правил переоценки и предложений об изменении цены
+type RepricingRepository interface {
+	CreateRule(ctx context.Context, rule *models.RepricingRule) error
+	UpdateRule(ctx context.Context, rule *models.RepricingRule) error
+	DeleteRule(ctx context.Context, id uuid.UUID) error
+	GetRuleByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.RepricingRule, error)
+	ListRules(ctx context.Context, establishmentID uuid.UUID, onlyActive bool) ([]*models.RepricingRule, error)
+
+	CreatePriceChange(ctx context.Context, change *models.PriceChange) error
+	UpdatePriceChange(ctx context.Context, change *models.PriceChange) error
+	ListPriceChanges(ctx context.Context, filter *PriceChangeFilter) ([]*models.PriceChange, error)
+	// GetPendingPriceChange возвращает ожидающее решения предложение по позиции
+	GetPendingPriceChange(ctx context.Context, techCardID, productID *uuid.UUID) (*models.PriceChange, error)
+	// ListDuePriceChanges возвращает одобренные предложения всех заведений, срок применения которых наступил
+	ListDuePriceChanges(ctx context.Context, now time.Time) ([]*models.PriceChange, error)
+
+	// UpdateTechCardPrice и UpdateProductPrice меняют только цену и наценку позиции
+	UpdateTechCardPrice(ctx context.Context, id uuid.UUID, price, markup float64) error
+	UpdateProductPrice(ctx context.Context, id uuid.UUID, price, markup float64) error
+}
+
+type repricingRepository struct {
+	db *gorm.DB
+}
+
+func NewRepricingRepository(db *gorm.DB) RepricingRepository {
+	return &repricingRepository{db: db}
+}
+
+func (r *repricingRepository) CreateRule(ctx context.Context, rule *models.RepricingRule) error {
+	return dbFor(ctx, r.db).Create(rule).Error
+}
+
+func (r *repricingRepository) UpdateRule(ctx context.Context, rule *models.RepricingRule) error {
+	return dbFor(ctx, r.db).Model(&models.RepricingRule{}).Where("id = ?", rule.ID).Updates(map[string]interface{}{
+		"name":         rule.Name,
+		"category_id":  rule.CategoryID,
+		"tech_card_id": rule.TechCardID,
+		"product_id":   rule.ProductID,
+		"strategy":     rule.Strategy,
+		"target_value": rule.TargetValue,
+		"rounding":     rule.Rounding,
+		"min_price":    rule.MinPrice,
+		"max_price":    rule.MaxPrice,
+		"active":       rule.Active,
+	}).Error
+}
+
+func (r *repricingRepository) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	return dbFor(ctx, r.db).Delete(&models.RepricingRule{}, "id = ?", id).Error
+}
+
+func (r *repricingRepository) GetRuleByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.RepricingRule, error) {
+	var rule models.RepricingRule
+	q := dbFor(ctx, r.db).Preload("Category")
+	if establishmentID != nil {
+		q = q.Where("establishment_id = ?", *establishmentID)
+	}
+	err := q.First(&rule, "id = ?", id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &rule, err
+}
+
+func (r *repricingRepository) ListRules(ctx context.Context, establishmentID uuid.UUID, onlyActive bool) ([]*models.RepricingRule, error) {
+	q := dbFor(ctx, r.db).Preload("Category").Where("establishment_id = ?", establishmentID)
+	if onlyActive {
+		q = q.Where("active = ?", true)
+	}
+	var rules []*models.RepricingRule
+	err := q.Order("created_at").Find(&rules).Error
+	return rules, err
+}
+
+func (r *repricingRepository) CreatePriceChange(ctx context.Context, change *models.PriceChange) error {
+	return dbFor(ctx, r.db).Create(change).Error
+}
+
+func (r *repricingRepository) UpdatePriceChange(ctx context.Context, change *models.PriceChange) error {
+	return dbFor(ctx, r.db).Model(&models.PriceChange{}).Where("id = ?", change.ID).Updates(map[string]interface{}{
+		"status":     change.Status,
+		"apply_at":   change.ApplyAt,
+		"decided_at": change.DecidedAt,
+		"decided_by": change.DecidedBy,
+		"applied_at": change.AppliedAt,
+	}).Error
+}
+
+func (r *repricingRepository) ListPriceChanges(ctx context.Context, filter *PriceChangeFilter) ([]*models.PriceChange, error) {
+	query := dbFor(ctx, r.db).
+		Preload("Rule").
+		Preload("TechCard").
+		Preload("Product")
+
+	if filter != nil {
+		if filter.EstablishmentID != nil {
+			query = query.Where("establishment_id = ?", *filter.EstablishmentID)
+		}
+		if filter.Status != nil {
+			query = query.Where("status = ?", *filter.Status)
+		}
+		if len(filter.IDs) > 0 {
+			query = query.Where("id IN ?", filter.IDs)
+		}
+	}
+
+	var changes []*models.PriceChange
+	err := query.Order("created_at DESC").Find(&changes).Error
+	return changes, err
+}
+
+func (r *repricingRepository) GetPendingPriceChange(ctx context.Context, techCardID, productID *uuid.UUID) (*models.PriceChange, error) {
+	var change models.PriceChange
+	err := menuItemScope(dbFor(ctx, r.db), techCardID, productID).
+		Where("status = ?", models.PriceChangeStatusPending).
+		Order("created_at DESC").
+		First(&change).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &change, err
+}
+
+func (r *repricingRepository) ListDuePriceChanges(ctx context.Context, now time.Time) ([]*models.PriceChange, error) {
+	var changes []*models.PriceChange
+	err := dbFor(ctx, r.db).
+		Where("status = ? AND (apply_at IS NULL OR apply_at <= ?)", models.PriceChangeStatusApproved, now).
+		Order("apply_at").
+		Find(&changes).Error
+	return changes, err
+}
+
+func (r *repricingRepository) UpdateTechCardPrice(ctx context.Context, id uuid.UUID, price, markup float64) error {
+	return dbFor(ctx, r.db).
+		Model(&models.TechCard{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"price": models.RoundTo2(price), "markup": models.RoundTo2(markup)}).Error
+}
+
+func (r *repricingRepository) UpdateProductPrice(ctx context.Context, id uuid.UUID, price, markup float64) error {
+	return dbFor(ctx, r.db).
+		Model(&models.Product{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"price": models.RoundTo2(price), "markup": models.RoundTo2(markup)}).Error
+}
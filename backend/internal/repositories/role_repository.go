@@ -28,30 +28,30 @@ func NewRoleRepository(db *gorm.DB) RoleRepository {
 
 func (r *roleRepository) GetByName(ctx context.Context, name string) (*models.Role, error) {
 	var role models.Role
-	err := r.db.WithContext(ctx).Where("name = ?", name).First(&role).Error
+	err := dbFor(ctx, r.db).Where("name = ?", name).First(&role).Error
 	return &role, err
 }
 
 func (r *roleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Role, error) {
 	var role models.Role
-	err := r.db.WithContext(ctx).First(&role, "id = ?", id).Error
+	err := dbFor(ctx, r.db).First(&role, "id = ?", id).Error
 	return &role, err
 }
 
 func (r *roleRepository) Create(ctx context.Context, role *models.Role) error {
-	return r.db.WithContext(ctx).Create(role).Error
+	return dbFor(ctx, r.db).Create(role).Error
 }
 
 func (r *roleRepository) Update(ctx context.Context, role *models.Role) error {
-	return r.db.WithContext(ctx).Save(role).Error
+	return dbFor(ctx, r.db).Save(role).Error
 }
 
 func (r *roleRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Role{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.Role{}, "id = ?", id).Error
 }
 
 func (r *roleRepository) GetAll(ctx context.Context) ([]*models.Role, error) {
 	var roles []*models.Role
-	err := r.db.WithContext(ctx).Find(&roles).Error
+	err := dbFor(ctx, r.db).Find(&roles).Error
 	return roles, err
 }
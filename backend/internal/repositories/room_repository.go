@@ -28,7 +28,7 @@ func NewRoomRepository(db *gorm.DB) RoomRepository {
 
 func (r *roomRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) (*models.Room, error) {
 	var room models.Room
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Preload("Tables").
 		Where("establishment_id = ?", establishmentID).
 		First(&room, "id = ?", id).Error
@@ -43,7 +43,7 @@ func (r *roomRepository) GetByID(ctx context.Context, id uuid.UUID, establishmen
 
 func (r *roomRepository) ListByEstablishmentID(ctx context.Context, establishmentID uuid.UUID) ([]*models.Room, error) {
 	var rooms []*models.Room
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Preload("Tables").
 		Where("establishment_id = ?", establishmentID).
 		Find(&rooms).Error
@@ -51,13 +51,13 @@ func (r *roomRepository) ListByEstablishmentID(ctx context.Context, establishmen
 }
 
 func (r *roomRepository) Create(ctx context.Context, room *models.Room) error {
-	return r.db.WithContext(ctx).Create(room).Error
+	return dbFor(ctx, r.db).Create(room).Error
 }
 
 func (r *roomRepository) Update(ctx context.Context, room *models.Room, establishmentID uuid.UUID) error {
-	return r.db.WithContext(ctx).Where("establishment_id = ?", establishmentID).Save(room).Error
+	return dbFor(ctx, r.db).Where("establishment_id = ?", establishmentID).Save(room).Error
 }
 
 func (r *roomRepository) Delete(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) error {
-	return r.db.WithContext(ctx).Where("establishment_id = ?", establishmentID).Delete(&models.Room{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Where("establishment_id = ?", establishmentID).Delete(&models.Room{}, "id = ?", id).Error
 }
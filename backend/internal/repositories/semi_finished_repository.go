@@ -36,8 +36,8 @@ func NewSemiFinishedRepository(db *gorm.DB) SemiFinishedRepository {
 
 func (r *semiFinishedRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.SemiFinishedProduct, error) {
 	var semiFinished models.SemiFinishedProduct
-	q := r.db.WithContext(ctx).
-		Preload("Ingredients.Ingredient").
+	q := dbFor(ctx, r.db).
+		Preload("Ingredients.Ingredient.UnitConversions").
 		Preload("Category").
 		Preload("Workshop")
 	if establishmentID != nil {
@@ -49,8 +49,8 @@ func (r *semiFinishedRepository) GetByID(ctx context.Context, id uuid.UUID, esta
 
 func (r *semiFinishedRepository) List(ctx context.Context, filter *SemiFinishedFilter) ([]*models.SemiFinishedProduct, error) {
 	var semiFinishedProducts []*models.SemiFinishedProduct
-	query := r.db.WithContext(ctx).
-		Preload("Ingredients.Ingredient").
+	query := dbFor(ctx, r.db).
+		Preload("Ingredients.Ingredient.UnitConversions").
 		Preload("Category").
 		Preload("Workshop")
 
@@ -78,11 +78,11 @@ func (r *semiFinishedRepository) List(ctx context.Context, filter *SemiFinishedF
 }
 
 func (r *semiFinishedRepository) Create(ctx context.Context, semiFinished *models.SemiFinishedProduct) error {
-	return r.db.WithContext(ctx).Create(semiFinished).Error
+	return dbFor(ctx, r.db).Create(semiFinished).Error
 }
 
 func (r *semiFinishedRepository) Update(ctx context.Context, semiFinished *models.SemiFinishedProduct) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
 		// Обновляем основную информацию о полуфабрикате
 		if err := tx.Model(semiFinished).Updates(map[string]interface{}{
 			"name":            semiFinished.Name,
@@ -90,6 +90,7 @@ func (r *semiFinishedRepository) Update(ctx context.Context, semiFinished *model
 			"workshop_id":     semiFinished.WorkshopID,
 			"description":      semiFinished.Description,
 			"cooking_process": semiFinished.CookingProcess,
+			"external_code":   semiFinished.ExternalCode,
 			"cover_image":     semiFinished.CoverImage,
 			"unit":            semiFinished.Unit,
 			"quantity":        semiFinished.Quantity,
@@ -119,5 +120,5 @@ func (r *semiFinishedRepository) Update(ctx context.Context, semiFinished *model
 }
 
 func (r *semiFinishedRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.SemiFinishedProduct{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.SemiFinishedProduct{}, "id = ?", id).Error
 }
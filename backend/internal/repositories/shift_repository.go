@@ -36,7 +36,7 @@ func NewShiftRepository(db *gorm.DB) ShiftRepository {
 
 func (r *shiftRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Shift, error) {
 	var shift models.Shift
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Preload("Sessions").
 		First(&shift, "id = ?", id).Error
 	if err != nil {
@@ -49,17 +49,17 @@ func (r *shiftRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Sh
 }
 
 func (r *shiftRepository) Create(ctx context.Context, shift *models.Shift) error {
-	return r.db.WithContext(ctx).Create(shift).Error
+	return dbFor(ctx, r.db).Create(shift).Error
 }
 
 func (r *shiftRepository) Update(ctx context.Context, shift *models.Shift) error {
-	return r.db.WithContext(ctx).Save(shift).Error
+	return dbFor(ctx, r.db).Save(shift).Error
 }
 
 // GetActiveShiftByEstablishmentID находит активную смену заведения (end_time IS NULL)
 func (r *shiftRepository) GetActiveShiftByEstablishmentID(ctx context.Context, establishmentID uuid.UUID) (*models.Shift, error) {
 	var shift models.Shift
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Preload("Sessions").
 		Where("establishment_id = ? AND end_time IS NULL", establishmentID).
 		First(&shift).Error
@@ -74,7 +74,7 @@ func (r *shiftRepository) GetActiveShiftByEstablishmentID(ctx context.Context, e
 
 func (r *shiftRepository) ListByFilter(ctx context.Context, filter *ShiftFilter) ([]*models.Shift, error) {
 	var shifts []*models.Shift
-	query := r.db.WithContext(ctx).Preload("Sessions").Preload("Establishment")
+	query := dbFor(ctx, r.db).Preload("Sessions").Preload("Establishment")
 
 	if filter != nil {
 		if filter.EstablishmentID != nil {
@@ -94,7 +94,7 @@ func (r *shiftRepository) ListByFilter(ctx context.Context, filter *ShiftFilter)
 
 func (r *shiftRepository) GetByUserIDAndDateRange(ctx context.Context, userID, establishmentID uuid.UUID, startDate, endDate time.Time) ([]*models.Shift, error) {
 	var shifts []*models.Shift
-	query := r.db.WithContext(ctx).Where("user_id = ? AND establishment_id = ?", userID, establishmentID)
+	query := dbFor(ctx, r.db).Where("user_id = ? AND establishment_id = ?", userID, establishmentID)
 
 	if !startDate.IsZero() {
 		query = query.Where("start_time >= ?", startDate)
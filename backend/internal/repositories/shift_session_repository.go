@@ -33,12 +33,12 @@ func NewShiftSessionRepository(db *gorm.DB) ShiftSessionRepository {
 }
 
 func (r *shiftSessionRepository) Create(ctx context.Context, session *models.ShiftSession) error {
-	return r.db.WithContext(ctx).Create(session).Error
+	return dbFor(ctx, r.db).Create(session).Error
 }
 
 func (r *shiftSessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ShiftSession, error) {
 	var session models.ShiftSession
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Preload("Shift").
 		Preload("User").
 		First(&session, "id = ?", id).Error
@@ -54,7 +54,7 @@ func (r *shiftSessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*mo
 // GetActiveSessionByUserID находит активную сессию пользователя (без привязки к конкретной смене)
 func (r *shiftSessionRepository) GetActiveSessionByUserID(ctx context.Context, userID uuid.UUID) (*models.ShiftSession, error) {
 	var session models.ShiftSession
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Preload("Shift").
 		Preload("User").
 		Where("user_id = ? AND end_time IS NULL", userID).
@@ -71,7 +71,7 @@ func (r *shiftSessionRepository) GetActiveSessionByUserID(ctx context.Context, u
 // GetActiveSessionByUserIDAndShiftID находит активную сессию пользователя в рамках конкретной смены
 func (r *shiftSessionRepository) GetActiveSessionByUserIDAndShiftID(ctx context.Context, userID uuid.UUID, shiftID uuid.UUID) (*models.ShiftSession, error) {
 	var session models.ShiftSession
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Preload("Shift").
 		Preload("User").
 		Where("user_id = ? AND shift_id = ? AND end_time IS NULL", userID, shiftID).
@@ -87,7 +87,7 @@ func (r *shiftSessionRepository) GetActiveSessionByUserIDAndShiftID(ctx context.
 
 func (r *shiftSessionRepository) GetByShiftID(ctx context.Context, shiftID uuid.UUID) ([]models.ShiftSession, error) {
 	var sessions []models.ShiftSession
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Preload("User").
 		Where("shift_id = ?", shiftID).
 		Order("start_time ASC").
@@ -99,12 +99,12 @@ func (r *shiftSessionRepository) GetByShiftID(ctx context.Context, shiftID uuid.
 }
 
 func (r *shiftSessionRepository) Update(ctx context.Context, session *models.ShiftSession) error {
-	return r.db.WithContext(ctx).Save(session).Error
+	return dbFor(ctx, r.db).Save(session).Error
 }
 
 // EndSession завершает сессию (устанавливает end_time)
 func (r *shiftSessionRepository) EndSession(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).
+	return dbFor(ctx, r.db).
 		Model(&models.ShiftSession{}).
 		Where("id = ?", id).
 		Update("end_time", gorm.Expr("NOW()")).Error
@@ -112,7 +112,7 @@ func (r *shiftSessionRepository) EndSession(ctx context.Context, id uuid.UUID) e
 
 func (r *shiftSessionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]models.ShiftSession, error) {
 	var sessions []models.ShiftSession
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Preload("Shift").
 		Where("user_id = ?", userID).
 		Order("start_time DESC").
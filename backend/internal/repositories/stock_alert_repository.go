@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+// StockAlertFilter фильтр для списка уведомлений о низком остатке
+type StockAlertFilter struct {
+	EstablishmentID *uuid.UUID
+	WarehouseID     *uuid.UUID
+	Status          *models.StockAlertStatus
+	OnlyActive      bool // Только open и acknowledged
+}
+
+// StockAlertRepository интерфейс репозитория уведомлений о низком остатке
+type StockAlertRepository interface {
+	Create(ctx context.Context, alert *models.StockAlert) error
+	Update(ctx context.Context, alert *models.StockAlert) error
+	GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.StockAlert, error)
+	GetActiveByStockID(ctx context.Context, stockID uuid.UUID) (*models.StockAlert, error)
+	List(ctx context.Context, filter *StockAlertFilter) ([]*models.StockAlert, error)
+}
+
+type stockAlertRepository struct {
+	db *gorm.DB
+}
+
+func NewStockAlertRepository(db *gorm.DB) StockAlertRepository {
+	return &stockAlertRepository{db: db}
+}
+
+func (r *stockAlertRepository) Create(ctx context.Context, alert *models.StockAlert) error {
+	return dbFor(ctx, r.db).Create(alert).Error
+}
+
+func (r *stockAlertRepository) Update(ctx context.Context, alert *models.StockAlert) error {
+	return dbFor(ctx, r.db).Model(&models.StockAlert{}).Where("id = ?", alert.ID).Updates(map[string]interface{}{
+		"quantity":        alert.Quantity,
+		"limit":           alert.Limit,
+		"status":          alert.Status,
+		"acknowledged_at": alert.AcknowledgedAt,
+		"acknowledged_by": alert.AcknowledgedBy,
+		"resolved_at":     alert.ResolvedAt,
+	}).Error
+}
+
+func (r *stockAlertRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.StockAlert, error) {
+	var alert models.StockAlert
+	q := dbFor(ctx, r.db).
+		Preload("Warehouse").
+		Preload("Ingredient").
+		Preload("Product").
+		Preload("SemiFinished")
+	if establishmentID != nil {
+		q = q.Where("establishment_id = ?", *establishmentID)
+	}
+	err := q.First(&alert, "id = ?", id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &alert, err
+}
+
+// GetActiveByStockID возвращает активное (open/acknowledged) уведомление по остатку
+func (r *stockAlertRepository) GetActiveByStockID(ctx context.Context, stockID uuid.UUID) (*models.StockAlert, error) {
+	var alert models.StockAlert
+	err := dbFor(ctx, r.db).
+		Where("stock_id = ? AND status IN ?", stockID, []models.StockAlertStatus{models.StockAlertStatusOpen, models.StockAlertStatusAcknowledged}).
+		Order("created_at DESC").
+		First(&alert).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &alert, err
+}
+
+func (r *stockAlertRepository) List(ctx context.Context, filter *StockAlertFilter) ([]*models.StockAlert, error) {
+	query := dbFor(ctx, r.db).
+		Preload("Warehouse").
+		Preload("Ingredient").
+		Preload("Product").
+		Preload("SemiFinished")
+
+	if filter != nil {
+		if filter.EstablishmentID != nil {
+			query = query.Where("establishment_id = ?", *filter.EstablishmentID)
+		}
+		if filter.WarehouseID != nil {
+			query = query.Where("warehouse_id = ?", *filter.WarehouseID)
+		}
+		if filter.Status != nil {
+			query = query.Where("status = ?", *filter.Status)
+		}
+		if filter.OnlyActive {
+			query = query.Where("status IN ?", []models.StockAlertStatus{models.StockAlertStatusOpen, models.StockAlertStatusAcknowledged})
+		}
+	}
+
+	var alerts []*models.StockAlert
+	err := query.Order("created_at DESC").Find(&alerts).Error
+	return alerts, err
+}
@@ -27,19 +27,19 @@ func NewSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
 
 func (r *subscriptionRepository) GetPlanByName(ctx context.Context, name string) (*models.SubscriptionPlan, error) {
 	var plan models.SubscriptionPlan
-	err := r.db.WithContext(ctx).Where("name = ? AND active = ?", name, true).First(&plan).Error
+	err := dbFor(ctx, r.db).Where("name = ? AND active = ?", name, true).First(&plan).Error
 	return &plan, err
 }
 
 func (r *subscriptionRepository) GetPlanByID(ctx context.Context, id uuid.UUID) (*models.SubscriptionPlan, error) {
 	var plan models.SubscriptionPlan
-	err := r.db.WithContext(ctx).First(&plan, "id = ?", id).Error
+	err := dbFor(ctx, r.db).First(&plan, "id = ?", id).Error
 	return &plan, err
 }
 
 func (r *subscriptionRepository) GetSubscriptionByUserID(ctx context.Context, userID uuid.UUID) (*models.Subscription, error) {
 	var subscription models.Subscription
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("user_id = ?", userID).
 		Preload("Plan").
 		First(&subscription).Error
@@ -50,9 +50,9 @@ func (r *subscriptionRepository) GetSubscriptionByUserID(ctx context.Context, us
 }
 
 func (r *subscriptionRepository) CreateSubscription(ctx context.Context, subscription *models.Subscription) error {
-	return r.db.WithContext(ctx).Create(subscription).Error
+	return dbFor(ctx, r.db).Create(subscription).Error
 }
 
 func (r *subscriptionRepository) UpdateSubscription(ctx context.Context, subscription *models.Subscription) error {
-	return r.db.WithContext(ctx).Save(subscription).Error
+	return dbFor(ctx, r.db).Save(subscription).Error
 }
@@ -0,0 +1,146 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+// SupplierPaymentFilter фильтр для списка платежей поставщикам
+type SupplierPaymentFilter struct {
+	EstablishmentID *uuid.UUID
+	SupplierID      *uuid.UUID
+	StartDate       *time.Time
+	EndDate         *time.Time
+}
+
+// SupplierPaymentRepository интерфейс репозитория платежей поставщикам
+type SupplierPaymentRepository interface {
+	// Create сохраняет платеж с распределением и оплаченные суммы поставок в одной транзакции
+	Create(ctx context.Context, payment *models.SupplierPayment, supplies []*models.Supply) error
+	// Delete удаляет платеж с распределением и сохраняет пересчитанные оплаченные суммы поставок
+	Delete(ctx context.Context, id uuid.UUID, supplies []*models.Supply) error
+	GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.SupplierPayment, error)
+	List(ctx context.Context, filter *SupplierPaymentFilter) ([]*models.SupplierPayment, error)
+	// ListPayableSupplies возвращает проведенные поставки заведения (опционально — одного поставщика)
+	ListPayableSupplies(ctx context.Context, establishmentID uuid.UUID, supplierID *uuid.UUID) ([]*models.Supply, error)
+}
+
+type supplierPaymentRepository struct {
+	db *gorm.DB
+}
+
+func NewSupplierPaymentRepository(db *gorm.DB) SupplierPaymentRepository {
+	return &supplierPaymentRepository{db: db}
+}
+
+func saveSupplyPayments(tx *gorm.DB, supplies []*models.Supply) error {
+	for _, s := range supplies {
+		if err := tx.Model(&models.Supply{}).Where("id = ?", s.ID).Updates(map[string]interface{}{
+			"payment_amount": models.RoundTo2(s.PaymentAmount),
+			"payment_status": s.PaymentStatus,
+			"payment_date":   s.PaymentDate,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *supplierPaymentRepository) Create(ctx context.Context, payment *models.SupplierPayment, supplies []*models.Supply) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		// Сохраняем Allocations отдельно, чтобы GORM не создавал их вместе со связанными поставками
+		allocations := payment.Allocations
+		payment.Allocations = nil
+
+		if err := tx.Create(payment).Error; err != nil {
+			payment.Allocations = allocations
+			return err
+		}
+		for i := range allocations {
+			allocations[i].PaymentID = payment.ID
+			allocations[i].Supply = nil
+			if err := tx.Create(&allocations[i]).Error; err != nil {
+				payment.Allocations = allocations
+				return err
+			}
+		}
+		payment.Allocations = allocations
+
+		return saveSupplyPayments(tx, supplies)
+	})
+}
+
+func (r *supplierPaymentRepository) Delete(ctx context.Context, id uuid.UUID, supplies []*models.Supply) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("payment_id = ?", id).Delete(&models.SupplierPaymentAllocation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.SupplierPayment{}, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return saveSupplyPayments(tx, supplies)
+	})
+}
+
+func (r *supplierPaymentRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.SupplierPayment, error) {
+	var payment models.SupplierPayment
+	q := dbFor(ctx, r.db).
+		Preload("Supplier").
+		Preload("Account").
+		Preload("Allocations.Supply")
+	if establishmentID != nil {
+		q = q.Where("establishment_id = ?", *establishmentID)
+	}
+	err := q.First(&payment, "id = ?", id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &payment, err
+}
+
+func (r *supplierPaymentRepository) List(ctx context.Context, filter *SupplierPaymentFilter) ([]*models.SupplierPayment, error) {
+	query := dbFor(ctx, r.db).
+		Preload("Supplier").
+		Preload("Account").
+		Preload("Allocations")
+
+	if filter != nil {
+		if filter.EstablishmentID != nil {
+			query = query.Where("establishment_id = ?", *filter.EstablishmentID)
+		}
+		if filter.SupplierID != nil {
+			query = query.Where("supplier_id = ?", *filter.SupplierID)
+		}
+		if filter.StartDate != nil {
+			query = query.Where("payment_date >= ?", *filter.StartDate)
+		}
+		if filter.EndDate != nil {
+			query = query.Where("payment_date <= ?", *filter.EndDate)
+		}
+	}
+
+	var payments []*models.SupplierPayment
+	err := query.Order("payment_date DESC").Find(&payments).Error
+	return payments, err
+}
+
+func (r *supplierPaymentRepository) ListPayableSupplies(ctx context.Context, establishmentID uuid.UUID, supplierID *uuid.UUID) ([]*models.Supply, error) {
+	query := dbFor(ctx, r.db).
+		Model(&models.Supply{}).
+		Preload("Supplier").
+		Preload("Items").
+		Joins("JOIN warehouses ON supplies.warehouse_id = warehouses.id").
+		Where("warehouses.establishment_id = ? AND supplies.status = ?", establishmentID, "completed")
+	if supplierID != nil {
+		query = query.Where("supplies.supplier_id = ?", *supplierID)
+	}
+
+	var supplies []*models.Supply
+	err := query.Order("supplies.delivery_date_time ASC").Find(&supplies).Error
+	return supplies, err
+}
@@ -22,6 +22,13 @@ type SupplierRepository interface {
 	Create(ctx context.Context, s *models.Supplier) error
 	Update(ctx context.Context, s *models.Supplier) error
 	Delete(ctx context.Context, id uuid.UUID) error
+
+	// Сопоставления позиций поставщика с ингредиентами и товарами заведения
+	ListItemMappings(ctx context.Context, supplierID uuid.UUID) ([]*models.SupplierItemMapping, error)
+	GetItemMapping(ctx context.Context, id, supplierID uuid.UUID) (*models.SupplierItemMapping, error)
+	// SaveItemMapping создает сопоставление или обновляет существующее с тем же ключом
+	SaveItemMapping(ctx context.Context, m *models.SupplierItemMapping) error
+	DeleteItemMapping(ctx context.Context, id uuid.UUID) error
 }
 
 type supplierRepository struct {
@@ -34,7 +41,7 @@ func NewSupplierRepository(db *gorm.DB) SupplierRepository {
 
 func (r *supplierRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Supplier, error) {
 	var s models.Supplier
-	q := r.db.WithContext(ctx)
+	q := dbFor(ctx, r.db)
 	if establishmentID != nil {
 		q = q.Where("establishment_id = ?", *establishmentID)
 	}
@@ -44,7 +51,7 @@ func (r *supplierRepository) GetByID(ctx context.Context, id uuid.UUID, establis
 
 func (r *supplierRepository) List(ctx context.Context, filter *SupplierFilter) ([]*models.Supplier, error) {
 	var list []*models.Supplier
-	query := r.db.WithContext(ctx)
+	query := dbFor(ctx, r.db)
 	if filter != nil {
 		if filter.EstablishmentID != nil {
 			query = query.Where("establishment_id = ?", *filter.EstablishmentID)
@@ -62,13 +69,62 @@ func (r *supplierRepository) List(ctx context.Context, filter *SupplierFilter) (
 }
 
 func (r *supplierRepository) Create(ctx context.Context, s *models.Supplier) error {
-	return r.db.WithContext(ctx).Create(s).Error
+	return dbFor(ctx, r.db).Create(s).Error
 }
 
 func (r *supplierRepository) Update(ctx context.Context, s *models.Supplier) error {
-	return r.db.WithContext(ctx).Save(s).Error
+	return dbFor(ctx, r.db).Save(s).Error
 }
 
 func (r *supplierRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Supplier{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.Supplier{}, "id = ?", id).Error
+}
+
+func (r *supplierRepository) ListItemMappings(ctx context.Context, supplierID uuid.UUID) ([]*models.SupplierItemMapping, error) {
+	var list []*models.SupplierItemMapping
+	err := dbFor(ctx, r.db).
+		Preload("Ingredient").
+		Preload("Product").
+		Where("supplier_id = ?", supplierID).
+		Order("supplier_name, supplier_code").
+		Find(&list).Error
+	return list, err
+}
+
+func (r *supplierRepository) GetItemMapping(ctx context.Context, id, supplierID uuid.UUID) (*models.SupplierItemMapping, error) {
+	var m models.SupplierItemMapping
+	err := dbFor(ctx, r.db).
+		Preload("Ingredient").
+		Preload("Product").
+		Where("supplier_id = ?", supplierID).
+		First(&m, "id = ?", id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &m, err
+}
+
+func (r *supplierRepository) SaveItemMapping(ctx context.Context, m *models.SupplierItemMapping) error {
+	if m.MatchKey == "" {
+		m.MatchKey = models.SupplierItemKey(m.SupplierCode, m.SupplierName)
+	}
+	var existing models.SupplierItemMapping
+	err := dbFor(ctx, r.db).
+		Where("supplier_id = ? AND match_key = ?", m.SupplierID, m.MatchKey).
+		First(&existing).Error
+	if err == gorm.ErrRecordNotFound {
+		return dbFor(ctx, r.db).Create(m).Error
+	}
+	if err != nil {
+		return err
+	}
+	m.ID = existing.ID
+	m.CreatedAt = existing.CreatedAt
+	return dbFor(ctx, r.db).Model(&existing).Select(
+		"supplier_code", "supplier_name", "ingredient_id", "product_id", "supplier_unit", "unit_factor", "unit",
+	).Updates(m).Error
+}
+
+func (r *supplierRepository) DeleteItemMapping(ctx context.Context, id uuid.UUID) error {
+	return dbFor(ctx, r.db).Delete(&models.SupplierItemMapping{}, "id = ?", id).Error
 }
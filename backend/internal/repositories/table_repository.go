@@ -28,12 +28,12 @@ func NewTableRepository(db *gorm.DB) TableRepository {
 }
 
 func (r *tableRepository) CreateBatch(ctx context.Context, tables []*models.Table) error {
-	return r.db.WithContext(ctx).Create(&tables).Error
+	return dbFor(ctx, r.db).Create(&tables).Error
 }
 
 func (r *tableRepository) GetByID(ctx context.Context, id uuid.UUID, roomID uuid.UUID) (*models.Table, error) {
 	var table models.Table
-	err := r.db.WithContext(ctx).Where("room_id = ?", roomID).First(&table, "id = ?", id).Error
+	err := dbFor(ctx, r.db).Where("room_id = ?", roomID).First(&table, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrTableNotFound
@@ -45,18 +45,18 @@ func (r *tableRepository) GetByID(ctx context.Context, id uuid.UUID, roomID uuid
 
 func (r *tableRepository) ListByRoomID(ctx context.Context, roomID uuid.UUID) ([]*models.Table, error) {
 	var tables []*models.Table
-	err := r.db.WithContext(ctx).Where("room_id = ?", roomID).Find(&tables).Error
+	err := dbFor(ctx, r.db).Where("room_id = ?", roomID).Find(&tables).Error
 	return tables, err
 }
 
 func (r *tableRepository) Create(ctx context.Context, table *models.Table) error {
-	return r.db.WithContext(ctx).Create(table).Error
+	return dbFor(ctx, r.db).Create(table).Error
 }
 
 func (r *tableRepository) Update(ctx context.Context, table *models.Table, roomID uuid.UUID) error {
-	return r.db.WithContext(ctx).Where("room_id = ?", roomID).Save(table).Error
+	return dbFor(ctx, r.db).Where("room_id = ?", roomID).Save(table).Error
 }
 
 func (r *tableRepository) Delete(ctx context.Context, id uuid.UUID, roomID uuid.UUID) error {
-	return r.db.WithContext(ctx).Where("room_id = ?", roomID).Delete(&models.Table{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Where("room_id = ?", roomID).Delete(&models.Table{}, "id = ?", id).Error
 }
\ No newline at end of file
@@ -16,6 +16,10 @@ type TechCardFilter struct {
 	WorkshopID     *uuid.UUID
 	Search         *string
 	Active         *bool
+	// Исключить тех-карты, содержащие любой из аллергенов (применяется после расчета состава в MenuUseCase)
+	ExcludeAllergens []string
+	// Только доступные сейчас по расписанию (применяется в MenuUseCase)
+	AvailableNow bool
 }
 
 type TechCardRepository interface {
@@ -36,8 +40,9 @@ func NewTechCardRepository(db *gorm.DB) TechCardRepository {
 
 func (r *techCardRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.TechCard, error) {
 	var techCard models.TechCard
-	q := r.db.WithContext(ctx).
-		Preload("Ingredients.Ingredient").
+	q := dbFor(ctx, r.db).
+		Preload("Ingredients.Ingredient.UnitConversions").
+		Preload("Ingredients.SemiFinished.Ingredients.Ingredient.UnitConversions").
 		Preload("ModifierSets.Options").
 		Preload("Category").
 		Preload("Workshop")
@@ -50,8 +55,9 @@ func (r *techCardRepository) GetByID(ctx context.Context, id uuid.UUID, establis
 
 func (r *techCardRepository) List(ctx context.Context, filter *TechCardFilter) ([]*models.TechCard, error) {
 	var techCards []*models.TechCard
-	query := r.db.WithContext(ctx).
-		Preload("Ingredients.Ingredient").
+	query := dbFor(ctx, r.db).
+		Preload("Ingredients.Ingredient.UnitConversions").
+		Preload("Ingredients.SemiFinished.Ingredients.Ingredient.UnitConversions").
 		Preload("ModifierSets.Options").
 		Preload("Category").
 		Preload("Workshop")
@@ -80,17 +86,18 @@ func (r *techCardRepository) List(ctx context.Context, filter *TechCardFilter) (
 }
 
 func (r *techCardRepository) Create(ctx context.Context, techCard *models.TechCard) error {
-	return r.db.WithContext(ctx).Create(techCard).Error
+	return dbFor(ctx, r.db).Create(techCard).Error
 }
 
 func (r *techCardRepository) Update(ctx context.Context, techCard *models.TechCard) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
 		// Обновляем основную информацию о тех-карте
 		if err := tx.Model(techCard).Updates(map[string]interface{}{
 			"name":                   techCard.Name,
 			"category_id":            techCard.CategoryID,
 			"workshop_id":            techCard.WorkshopID,
 			"description":          techCard.Description,
+			"external_code":        techCard.ExternalCode,
 			"cover_image":          techCard.CoverImage,
 			"is_weighted":          techCard.IsWeighted,
 			"exclude_from_discounts": techCard.ExcludeFromDiscounts,
@@ -161,5 +168,5 @@ func (r *techCardRepository) Update(ctx context.Context, techCard *models.TechCa
 }
 
 func (r *techCardRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.TechCard{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.TechCard{}, "id = ?", id).Error
 }
\ No newline at end of file
@@ -38,13 +38,13 @@ func (r *tokenRepository) AddToBlacklist(ctx context.Context, tokenString string
 		UserID:    userID,
 		ExpiresAt: expiresAt,
 	}
-	return r.db.WithContext(ctx).Create(blacklistEntry).Error
+	return dbFor(ctx, r.db).Create(blacklistEntry).Error
 }
 
 func (r *tokenRepository) IsBlacklisted(ctx context.Context, tokenString string) (bool, error) {
 	tokenHash := hashToken(tokenString)
 	var count int64
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Model(&models.TokenBlacklist{}).
 		Where("token_hash = ? AND expires_at > ?", tokenHash, time.Now()).
 		Count(&count).Error
@@ -57,7 +57,7 @@ func (r *tokenRepository) IsBlacklisted(ctx context.Context, tokenString string)
 }
 
 func (r *tokenRepository) CleanupExpired(ctx context.Context) error {
-	return r.db.WithContext(ctx).
+	return dbFor(ctx, r.db).
 		Where("expires_at < ?", time.Now()).
 		Delete(&models.TokenBlacklist{}).Error
 }
@@ -40,7 +40,7 @@ func NewTransactionRepository(db *gorm.DB) TransactionRepository {
 
 func (r *transactionRepository) Create(ctx context.Context, transaction *models.Transaction) error {
 	// Используем транзакцию для атомарного обновления
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
 		// Создаем транзакцию
 		if err := tx.Create(transaction).Error; err != nil {
 			return err
@@ -70,7 +70,7 @@ func (r *transactionRepository) Create(ctx context.Context, transaction *models.
 
 func (r *transactionRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Transaction, error) {
 	var transaction models.Transaction
-	query := r.db.WithContext(ctx).
+	query := dbFor(ctx, r.db).
 		Preload("Account").
 		Preload("Account.Type").
 		Preload("Establishment")
@@ -88,7 +88,7 @@ func (r *transactionRepository) GetByID(ctx context.Context, id uuid.UUID, estab
 
 func (r *transactionRepository) List(ctx context.Context, filter *TransactionFilter) ([]*models.Transaction, error) {
 	var transactions []*models.Transaction
-	query := r.db.WithContext(ctx).
+	query := dbFor(ctx, r.db).
 		Preload("Account").
 		Preload("Account.Type").
 		Preload("Establishment").
@@ -128,9 +128,9 @@ func (r *transactionRepository) List(ctx context.Context, filter *TransactionFil
 }
 
 func (r *transactionRepository) Update(ctx context.Context, transaction *models.Transaction) error {
-	return r.db.WithContext(ctx).Save(transaction).Error
+	return dbFor(ctx, r.db).Save(transaction).Error
 }
 
 func (r *transactionRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Transaction{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.Transaction{}, "id = ?", id).Error
 }
\ No newline at end of file
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// txContextKey ключ контекста, в котором Transactor передает открытую транзакцию
+type txContextKey struct{}
+
+// txState открытая транзакция и действия, отложенные до ее фиксации
+type txState struct {
+	db          *gorm.DB
+	afterCommit []func()
+}
+
+// Transactor выполняет операции нескольких репозиториев в одной транзакции БД
+type Transactor interface {
+	// WithinTransaction вызывает fn в транзакции: репозитории, получившие ctx из fn, работают в ней.
+	// Ошибка fn откатывает все изменения. Вложенный вызов выполняется в уже открытой транзакции.
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	// AfterCommit откладывает fn до фиксации внешней транзакции ctx (при откате fn не вызывается).
+	// Вне транзакции fn вызывается сразу. Используется для уведомлений фоновых обработчиков,
+	// которые не должны видеть незафиксированные данные.
+	AfterCommit(ctx context.Context, fn func())
+}
+
+type transactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) Transactor {
+	return &transactor{db: db}
+}
+
+func (t *transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txContextKey{}).(*txState); ok {
+		return fn(ctx)
+	}
+	state := &txState{}
+	err := t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		state.db = tx
+		return fn(context.WithValue(ctx, txContextKey{}, state))
+	})
+	if err != nil {
+		return err
+	}
+	for _, hook := range state.afterCommit {
+		hook()
+	}
+	return nil
+}
+
+func (t *transactor) AfterCommit(ctx context.Context, fn func()) {
+	if state, ok := ctx.Value(txContextKey{}).(*txState); ok {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+// dbFor возвращает соединение для запроса: транзакцию из ctx, если она открыта Transactor, иначе db
+func dbFor(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if state, ok := ctx.Value(txContextKey{}).(*txState); ok {
+		return state.db.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+// UploadRepository интерфейс репозитория загруженных изображений
+type UploadRepository interface {
+	Create(ctx context.Context, upload *models.Upload) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	// UpdateOwner записывает владельца, найденного по ссылке, и время проверки ссылки
+	UpdateOwner(ctx context.Context, id uuid.UUID, entityType string, entityID uuid.UUID, referencedAt time.Time) error
+	// ListCreatedBetween возвращает загрузки, созданные в интервале (after, before), начиная с самых старых
+	ListCreatedBetween(ctx context.Context, after, before time.Time, limit int) ([]*models.Upload, error)
+	// FindReferences ищет позиции (без удаленных), у которых CoverImage совпадает с одним из urls
+	FindReferences(ctx context.Context, urls []string) ([]models.UploadReference, error)
+}
+
+type uploadRepository struct {
+	db *gorm.DB
+}
+
+func NewUploadRepository(db *gorm.DB) UploadRepository {
+	return &uploadRepository{db: db}
+}
+
+func (r *uploadRepository) Create(ctx context.Context, upload *models.Upload) error {
+	return dbFor(ctx, r.db).Create(upload).Error
+}
+
+func (r *uploadRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return dbFor(ctx, r.db).Delete(&models.Upload{}, "id = ?", id).Error
+}
+
+func (r *uploadRepository) UpdateOwner(ctx context.Context, id uuid.UUID, entityType string, entityID uuid.UUID, referencedAt time.Time) error {
+	return dbFor(ctx, r.db).Model(&models.Upload{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"entity_type":   entityType,
+		"entity_id":     entityID,
+		"referenced_at": referencedAt,
+	}).Error
+}
+
+func (r *uploadRepository) ListCreatedBetween(ctx context.Context, after, before time.Time, limit int) ([]*models.Upload, error) {
+	var uploads []*models.Upload
+	err := dbFor(ctx, r.db).
+		Where("created_at > ? AND created_at < ?", after, before).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&uploads).Error
+	return uploads, err
+}
+
+func (r *uploadRepository) FindReferences(ctx context.Context, urls []string) ([]models.UploadReference, error) {
+	refs := make([]models.UploadReference, 0)
+	if len(urls) == 0 {
+		return refs, nil
+	}
+	for _, source := range []struct {
+		entityType string
+		model      interface{}
+	}{
+		{models.UploadEntityProduct, &models.Product{}},
+		{models.UploadEntityTechCard, &models.TechCard{}},
+		{models.UploadEntitySemiFinished, &models.SemiFinishedProduct{}},
+		{models.UploadEntityCombo, &models.Combo{}},
+	} {
+		var rows []struct {
+			ID         uuid.UUID
+			CoverImage string
+		}
+		// Model учитывает мягкое удаление: изображения удаленных позиций считаются свободными
+		err := dbFor(ctx, r.db).Model(source.model).
+			Select("id, cover_image").
+			Where("cover_image IN ?", urls).
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			refs = append(refs, models.UploadReference{URL: row.CoverImage, EntityType: source.entityType, EntityID: row.ID})
+		}
+	}
+	return refs, nil
+}
@@ -30,7 +30,7 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 
 func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	var user models.User
-	err := r.db.WithContext(ctx).Preload("Role").First(&user, "id = ?", id).Error
+	err := dbFor(ctx, r.db).Preload("Role").First(&user, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrUserNotFound
@@ -42,7 +42,7 @@ func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	err := dbFor(ctx, r.db).Where("email = ?", email).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrUserNotFound
@@ -53,20 +53,20 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.
 }
 
 func (r *userRepository) Create(ctx context.Context, user *models.User) error {
-	return r.db.WithContext(ctx).Create(user).Error
+	return dbFor(ctx, r.db).Create(user).Error
 }
 
 func (r *userRepository) Update(ctx context.Context, user *models.User) error {
-	return r.db.WithContext(ctx).Save(user).Error
+	return dbFor(ctx, r.db).Save(user).Error
 }
 
 func (r *userRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.User{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.User{}, "id = ?", id).Error
 }
 
 func (r *userRepository) GetAllByEstablishmentID(ctx context.Context, establishmentID uuid.UUID) ([]*models.User, error) {
 	var users []*models.User
-	err := r.db.WithContext(ctx).Preload("Role").Where("establishment_id = ?", establishmentID).Find(&users).Error
+	err := dbFor(ctx, r.db).Preload("Role").Where("establishment_id = ?", establishmentID).Find(&users).Error
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +76,7 @@ func (r *userRepository) GetAllByEstablishmentID(ctx context.Context, establishm
 func (r *userRepository) GetByPIN(ctx context.Context, pin string, establishmentID uuid.UUID) (*models.User, error) {
 	var user models.User
 	// Сначала ищем пользователя с указанным establishment_id
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("establishment_id = ? AND pin = ?", establishmentID, pin).
 		First(&user).Error
 
@@ -84,7 +84,7 @@ func (r *userRepository) GetByPIN(ctx context.Context, pin string, establishment
 		// Если не нашли, пробуем найти по PIN без проверки establishment_id
 		// Это позволит автоматически привязать сотрудника к заведению при первом логине
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			err = r.db.WithContext(ctx).
+			err = dbFor(ctx, r.db).
 				Where("pin = ? AND (establishment_id IS NULL OR establishment_id = ?)", pin, establishmentID).
 				First(&user).Error
 			if err != nil {
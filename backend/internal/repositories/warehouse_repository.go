@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -14,10 +15,58 @@ type StockFilter struct {
 	EstablishmentID *uuid.UUID
 	WarehouseID     *uuid.UUID
 	Search          *string // Поиск по названию ингредиента/товара
-	Type            *string // "ingredient" или "product"
+	Type            *string // "ingredient", "product" или "semi_finished"
 	CategoryID      *uuid.UUID // Фильтр по категории (для ингредиентов или товаров)
 }
 
+type StockLotFilter struct {
+	WarehouseID    *uuid.UUID
+	IngredientID   *uuid.UUID
+	ProductID      *uuid.UUID
+	SemiFinishedID *uuid.UUID
+	OnlyOpen     bool // Только партии с нерасходованным остатком
+	ExpiresBefore *time.Time // Только партии со сроком годности до указанного момента (включительно)
+}
+
+// StockLedgerFilter фильтр записей журнала остатков
+type StockLedgerFilter struct {
+	WarehouseID    *uuid.UUID
+	StockID        *uuid.UUID
+	IngredientID   *uuid.UUID
+	ProductID      *uuid.UUID
+	SemiFinishedID *uuid.UUID
+	MovementType   *string
+	SourceID       *uuid.UUID
+	StartDate      *time.Time // occurred_at >= StartDate
+	EndDate        *time.Time // occurred_at <= EndDate
+}
+
+// StockLedgerBalance остаток позиции склада, собранный из журнала
+type StockLedgerBalance struct {
+	StockID        uuid.UUID
+	WarehouseID    uuid.UUID
+	IngredientID   *uuid.UUID
+	ProductID      *uuid.UUID
+	SemiFinishedID *uuid.UUID
+	Unit           string
+	Quantity       float64
+	Amount         float64
+}
+
+// ConsumedQuantity расход позиции склада за период (в единицах остатка)
+type ConsumedQuantity struct {
+	ItemID   uuid.UUID // ID ингредиента, товара или полуфабриката
+	Quantity float64
+}
+
+// LastSupplyPrice последняя закупочная цена позиции и поставщик
+type LastSupplyPrice struct {
+	SupplierID       uuid.UUID
+	PricePerUnit     float64
+	Unit             string
+	DeliveryDateTime time.Time
+}
+
 type WarehouseRepository interface {
 	// Warehouse CRUD
 	CreateWarehouse(ctx context.Context, w *models.Warehouse) error
@@ -33,14 +82,27 @@ type WarehouseRepository interface {
 	GetStockByProductID(ctx context.Context, productID uuid.UUID) ([]*models.Stock, error)
 	GetStockByIngredientAndWarehouse(ctx context.Context, ingredientID, warehouseID uuid.UUID) (*models.Stock, error)
 	GetStockByProductAndWarehouse(ctx context.Context, productID, warehouseID uuid.UUID) (*models.Stock, error)
+	GetStockBySemiFinishedID(ctx context.Context, semiFinishedID uuid.UUID) ([]*models.Stock, error)
+	GetStockBySemiFinishedAndWarehouse(ctx context.Context, semiFinishedID, warehouseID uuid.UUID) (*models.Stock, error)
 	GetStockByID(ctx context.Context, id uuid.UUID) (*models.Stock, error)
 	CreateStock(ctx context.Context, stock *models.Stock) error
 	UpdateStock(ctx context.Context, stock *models.Stock) error
 	UpdateStockLimit(ctx context.Context, id uuid.UUID, limit float64) error
+	GetStocksWithLimit(ctx context.Context, establishmentID *uuid.UUID) ([]*models.Stock, error)
+	GetConsumedQuantities(ctx context.Context, warehouseID uuid.UUID, documentTypes []string, since time.Time) ([]ConsumedQuantity, error)
+	GetLastSupplyPrice(ctx context.Context, establishmentID uuid.UUID, ingredientID, productID *uuid.UUID) (*LastSupplyPrice, error)
 
 	// Product and TechCard retrievals
 	GetProductByID(ctx context.Context, id uuid.UUID) (*models.Product, error)
+	// CountProductVariants возвращает число модификаций товара
+	CountProductVariants(ctx context.Context, parentID uuid.UUID) (int64, error)
+	GetIngredientByID(ctx context.Context, id uuid.UUID) (*models.Ingredient, error)
+	GetSemiFinishedByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.SemiFinishedProduct, error)
 	GetTechCardByID(ctx context.Context, id uuid.UUID) (*models.TechCard, error)
+	// GetActiveTechCards и GetActiveProducts возвращают активное меню заведения (тех-карты — с рецептурой).
+	// Товар с модификациями представлен своими модификациями
+	GetActiveTechCards(ctx context.Context, establishmentID uuid.UUID) ([]*models.TechCard, error)
+	GetActiveProducts(ctx context.Context, establishmentID uuid.UUID) ([]*models.Product, error)
 
 	// Supply & WriteOff
 	CreateSupply(ctx context.Context, supply *models.Supply) error
@@ -53,6 +115,32 @@ type WarehouseRepository interface {
 	GetWriteOffsByWarehouse(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID) ([]*models.WriteOff, error)
 	GetWriteOffByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.WriteOff, error)
 
+	// Transfer (перемещения между складами)
+	CreateTransfer(ctx context.Context, transfer *models.Transfer) error
+	UpdateTransfer(ctx context.Context, transfer *models.Transfer) error
+	GetTransferByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Transfer, error)
+	GetTransfersByWarehouse(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID) ([]*models.Transfer, error)
+
+	// Production (производство полуфабрикатов)
+	CreateProduction(ctx context.Context, production *models.Production) error
+	GetProductionByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Production, error)
+	GetProductionsByWarehouse(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID) ([]*models.Production, error)
+
+	// StockLot (партии FIFO и их расход)
+	CreateStockLot(ctx context.Context, lot *models.StockLot) error
+	UpdateStockLot(ctx context.Context, lot *models.StockLot) error
+	GetOpenStockLots(ctx context.Context, warehouseID uuid.UUID, ingredientID, productID, semiFinishedID *uuid.UUID) ([]*models.StockLot, error)
+	GetStockLots(ctx context.Context, establishmentID uuid.UUID, filter *StockLotFilter) ([]*models.StockLot, error)
+	CreateStockLotConsumption(ctx context.Context, consumption *models.StockLotConsumption) error
+	GetConsumedCostByDocuments(ctx context.Context, documentType string, documentIDs []uuid.UUID) (map[uuid.UUID]float64, error)
+
+	// StockLedger (журнал движения остатков, только добавление)
+	CreateStockLedgerEntry(ctx context.Context, entry *models.StockLedgerEntry) error
+	// GetStockLedger возвращает записи журнала; BalanceAfter пересчитывается по всем записям позиции
+	GetStockLedger(ctx context.Context, establishmentID uuid.UUID, filter *StockLedgerFilter) ([]*models.StockLedgerEntry, error)
+	// GetStockLedgerBalances суммирует записи журнала по позициям складов (остаток на filter.EndDate)
+	GetStockLedgerBalances(ctx context.Context, establishmentID uuid.UUID, filter *StockLedgerFilter) ([]StockLedgerBalance, error)
+
 	// WriteOffReason CRUD
 	CreateWriteOffReason(ctx context.Context, reason *models.WriteOffReason) error
 	ListWriteOffReasons(ctx context.Context, establishmentID uuid.UUID) ([]*models.WriteOffReason, error)
@@ -71,17 +159,46 @@ func NewWarehouseRepository(db *gorm.DB) WarehouseRepository {
 
 func (r *warehouseRepository) GetProductByID(ctx context.Context, id uuid.UUID) (*models.Product, error) {
 	var product models.Product
-	err := r.db.WithContext(ctx).First(&product, "id = ?", id).Error
+	err := dbFor(ctx, r.db).First(&product, "id = ?", id).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
 	return &product, err
 }
 
+func (r *warehouseRepository) CountProductVariants(ctx context.Context, parentID uuid.UUID) (int64, error) {
+	var count int64
+	err := dbFor(ctx, r.db).Model(&models.Product{}).Where("parent_id = ?", parentID).Count(&count).Error
+	return count, err
+}
+
+func (r *warehouseRepository) GetIngredientByID(ctx context.Context, id uuid.UUID) (*models.Ingredient, error) {
+	var ingredient models.Ingredient
+	err := dbFor(ctx, r.db).Preload("UnitConversions").First(&ingredient, "id = ?", id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &ingredient, err
+}
+
+func (r *warehouseRepository) GetSemiFinishedByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.SemiFinishedProduct, error) {
+	var semiFinished models.SemiFinishedProduct
+	q := dbFor(ctx, r.db).Preload("Ingredients.Ingredient.UnitConversions")
+	if establishmentID != nil {
+		q = q.Where("establishment_id = ?", *establishmentID)
+	}
+	err := q.First(&semiFinished, "id = ?", id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &semiFinished, err
+}
+
 func (r *warehouseRepository) GetTechCardByID(ctx context.Context, id uuid.UUID) (*models.TechCard, error) {
 	var techCard models.TechCard
-	err := r.db.WithContext(ctx).
-		Preload("Ingredients.Ingredient").
+	err := dbFor(ctx, r.db).
+		Preload("Ingredients.Ingredient.UnitConversions").
+		Preload("Ingredients.SemiFinished.Ingredients.Ingredient.UnitConversions").
 		Preload("ModifierSets.Options").
 		Preload("Category").
 		Preload("Workshop").
@@ -92,19 +209,40 @@ func (r *warehouseRepository) GetTechCardByID(ctx context.Context, id uuid.UUID)
 	return &techCard, err
 }
 
+func (r *warehouseRepository) GetActiveTechCards(ctx context.Context, establishmentID uuid.UUID) ([]*models.TechCard, error) {
+	var techCards []*models.TechCard
+	err := dbFor(ctx, r.db).
+		Preload("Ingredients.Ingredient.UnitConversions").
+		Preload("Ingredients.SemiFinished.Ingredients.Ingredient.UnitConversions").
+		Where("establishment_id = ? AND active = ?", establishmentID, true).
+		Order("name").
+		Find(&techCards).Error
+	return techCards, err
+}
+
+func (r *warehouseRepository) GetActiveProducts(ctx context.Context, establishmentID uuid.UUID) ([]*models.Product, error) {
+	var products []*models.Product
+	err := dbFor(ctx, r.db).
+		Where("establishment_id = ? AND active = ?", establishmentID, true).
+		Where("NOT (has_modifications AND parent_id IS NULL)").
+		Order("name").
+		Find(&products).Error
+	return products, err
+}
+
 func (r *warehouseRepository) CreateWarehouse(ctx context.Context, w *models.Warehouse) error {
-	return r.db.WithContext(ctx).Create(w).Error
+	return dbFor(ctx, r.db).Create(w).Error
 }
 
 func (r *warehouseRepository) ListWarehouses(ctx context.Context, establishmentID uuid.UUID) ([]*models.Warehouse, error) {
 	var list []*models.Warehouse
-	err := r.db.WithContext(ctx).Where("establishment_id = ?", establishmentID).Find(&list).Error
+	err := dbFor(ctx, r.db).Where("establishment_id = ?", establishmentID).Find(&list).Error
 	return list, err
 }
 
 func (r *warehouseRepository) GetWarehouseByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Warehouse, error) {
 	var w models.Warehouse
-	q := r.db.WithContext(ctx)
+	q := dbFor(ctx, r.db)
 	if establishmentID != nil {
 		q = q.Where("establishment_id = ?", *establishmentID)
 	}
@@ -113,22 +251,22 @@ func (r *warehouseRepository) GetWarehouseByID(ctx context.Context, id uuid.UUID
 }
 
 func (r *warehouseRepository) UpdateWarehouse(ctx context.Context, w *models.Warehouse) error {
-	return r.db.WithContext(ctx).Save(w).Error
+	return dbFor(ctx, r.db).Save(w).Error
 }
 
 func (r *warehouseRepository) DeleteWarehouse(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Warehouse{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.Warehouse{}, "id = ?", id).Error
 }
 
 func (r *warehouseRepository) GetStock(ctx context.Context) ([]*models.Stock, error) {
 	var stock []*models.Stock
-	err := r.db.WithContext(ctx).Preload("Ingredient").Preload("Product").Preload("Warehouse").Find(&stock).Error
+	err := dbFor(ctx, r.db).Preload("Ingredient").Preload("Product").Preload("Warehouse").Find(&stock).Error
 	return stock, err
 }
 
 func (r *warehouseRepository) GetStockByWarehouseID(ctx context.Context, warehouseID uuid.UUID) ([]*models.Stock, error) {
 	var stock []*models.Stock
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("warehouse_id = ?", warehouseID).
 		Preload("Ingredient").Preload("Product").Preload("Warehouse").
 		Find(&stock).Error
@@ -137,15 +275,17 @@ func (r *warehouseRepository) GetStockByWarehouseID(ctx context.Context, warehou
 
 func (r *warehouseRepository) GetStockForEstablishment(ctx context.Context, establishmentID uuid.UUID, filter *StockFilter) ([]*models.Stock, error) {
 	var stock []*models.Stock
-	query := r.db.WithContext(ctx).
+	query := dbFor(ctx, r.db).
 		Preload("Ingredient.Category").
 		Preload("Product.Category").
+		Preload("SemiFinished.Category").
 		Preload("Warehouse").
 		// Используем INNER JOIN вместо LEFT JOIN для лучшей производительности
 		// и фильтруем по establishment_id сразу в JOIN
 		Joins("LEFT JOIN ingredients ON stocks.ingredient_id = ingredients.id AND ingredients.establishment_id = ?", establishmentID).
 		Joins("LEFT JOIN products ON stocks.product_id = products.id AND products.establishment_id = ?", establishmentID).
-		Where("(stocks.ingredient_id IS NOT NULL AND ingredients.id IS NOT NULL) OR (stocks.product_id IS NOT NULL AND products.id IS NOT NULL)")
+		Joins("LEFT JOIN semi_finished_products ON stocks.semi_finished_id = semi_finished_products.id AND semi_finished_products.establishment_id = ?", establishmentID).
+		Where("(stocks.ingredient_id IS NOT NULL AND ingredients.id IS NOT NULL) OR (stocks.product_id IS NOT NULL AND products.id IS NOT NULL) OR (stocks.semi_finished_id IS NOT NULL AND semi_finished_products.id IS NOT NULL)")
 
 	if filter != nil {
 		if filter.WarehouseID != nil {
@@ -154,7 +294,7 @@ func (r *warehouseRepository) GetStockForEstablishment(ctx context.Context, esta
 		if filter.Search != nil && *filter.Search != "" {
 			search := "%" + strings.ToLower(*filter.Search) + "%"
 			// Оптимизация: используем COALESCE для объединения условий поиска
-			query = query.Where("COALESCE(LOWER(ingredients.name), LOWER(products.name)) LIKE ?", search)
+			query = query.Where("COALESCE(LOWER(ingredients.name), LOWER(products.name), LOWER(semi_finished_products.name)) LIKE ?", search)
 		}
 		if filter.Type != nil {
 			switch *filter.Type {
@@ -162,10 +302,12 @@ func (r *warehouseRepository) GetStockForEstablishment(ctx context.Context, esta
 				query = query.Where("stocks.ingredient_id IS NOT NULL AND ingredients.id IS NOT NULL")
 			case "product":
 				query = query.Where("stocks.product_id IS NOT NULL AND products.id IS NOT NULL")
+			case "semi_finished":
+				query = query.Where("stocks.semi_finished_id IS NOT NULL AND semi_finished_products.id IS NOT NULL")
 			}
 		}
 		if filter.CategoryID != nil {
-			query = query.Where("(ingredients.category_id = ? OR products.category_id = ?)", *filter.CategoryID, *filter.CategoryID)
+			query = query.Where("(ingredients.category_id = ? OR products.category_id = ? OR semi_finished_products.category_id = ?)", *filter.CategoryID, *filter.CategoryID, *filter.CategoryID)
 		}
 	}
 
@@ -175,7 +317,7 @@ func (r *warehouseRepository) GetStockForEstablishment(ctx context.Context, esta
 
 func (r *warehouseRepository) GetStockByIngredientID(ctx context.Context, ingredientID uuid.UUID) ([]*models.Stock, error) {
 	var stock []*models.Stock
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("ingredient_id = ?", ingredientID).
 		Preload("Ingredient").Preload("Warehouse").
 		Find(&stock).Error
@@ -184,7 +326,7 @@ func (r *warehouseRepository) GetStockByIngredientID(ctx context.Context, ingred
 
 func (r *warehouseRepository) GetStockByProductID(ctx context.Context, productID uuid.UUID) ([]*models.Stock, error) {
 	var stock []*models.Stock
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("product_id = ?", productID).
 		Preload("Product").Preload("Warehouse").
 		Find(&stock).Error
@@ -193,7 +335,7 @@ func (r *warehouseRepository) GetStockByProductID(ctx context.Context, productID
 
 func (r *warehouseRepository) GetStockByIngredientAndWarehouse(ctx context.Context, ingredientID, warehouseID uuid.UUID) (*models.Stock, error) {
 	var stock models.Stock
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("ingredient_id = ? AND warehouse_id = ?", ingredientID, warehouseID).
 		Preload("Ingredient").Preload("Warehouse").
 		First(&stock).Error
@@ -205,7 +347,7 @@ func (r *warehouseRepository) GetStockByIngredientAndWarehouse(ctx context.Conte
 
 func (r *warehouseRepository) GetStockByProductAndWarehouse(ctx context.Context, productID, warehouseID uuid.UUID) (*models.Stock, error) {
 	var stock models.Stock
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("product_id = ? AND warehouse_id = ?", productID, warehouseID).
 		Preload("Product").Preload("Warehouse").
 		First(&stock).Error
@@ -215,13 +357,34 @@ func (r *warehouseRepository) GetStockByProductAndWarehouse(ctx context.Context,
 	return &stock, err
 }
 
+func (r *warehouseRepository) GetStockBySemiFinishedID(ctx context.Context, semiFinishedID uuid.UUID) ([]*models.Stock, error) {
+	var stock []*models.Stock
+	err := dbFor(ctx, r.db).
+		Where("semi_finished_id = ?", semiFinishedID).
+		Preload("SemiFinished").Preload("Warehouse").
+		Find(&stock).Error
+	return stock, err
+}
+
+func (r *warehouseRepository) GetStockBySemiFinishedAndWarehouse(ctx context.Context, semiFinishedID, warehouseID uuid.UUID) (*models.Stock, error) {
+	var stock models.Stock
+	err := dbFor(ctx, r.db).
+		Where("semi_finished_id = ? AND warehouse_id = ?", semiFinishedID, warehouseID).
+		Preload("SemiFinished").Preload("Warehouse").
+		First(&stock).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &stock, err
+}
+
 func (r *warehouseRepository) CreateStock(ctx context.Context, stock *models.Stock) error {
-	return r.db.WithContext(ctx).Create(stock).Error
+	return dbFor(ctx, r.db).Create(stock).Error
 }
 
 func (r *warehouseRepository) GetStockByID(ctx context.Context, id uuid.UUID) (*models.Stock, error) {
 	var stock models.Stock
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Preload("Ingredient.Category").
 		Preload("Product.Category").
 		Preload("Warehouse").
@@ -233,18 +396,36 @@ func (r *warehouseRepository) GetStockByID(ctx context.Context, id uuid.UUID) (*
 }
 
 func (r *warehouseRepository) UpdateStock(ctx context.Context, stock *models.Stock) error {
-	return r.db.WithContext(ctx).Save(stock).Error
+	return dbFor(ctx, r.db).Save(stock).Error
 }
 
 func (r *warehouseRepository) UpdateStockLimit(ctx context.Context, id uuid.UUID, limit float64) error {
-	return r.db.WithContext(ctx).
+	return dbFor(ctx, r.db).
 		Model(&models.Stock{}).
 		Where("id = ?", id).
 		Update("limit", limit).Error
 }
 
+// GetStocksWithLimit возвращает остатки с заданным лимитом (для всех заведений, если establishmentID не указан)
+func (r *warehouseRepository) GetStocksWithLimit(ctx context.Context, establishmentID *uuid.UUID) ([]*models.Stock, error) {
+	query := dbFor(ctx, r.db).
+		Preload("Warehouse").
+		Preload("Ingredient").
+		Preload("Product").
+		Preload("SemiFinished").
+		Joins("JOIN warehouses ON stocks.warehouse_id = warehouses.id").
+		Where(`stocks."limit" > 0`)
+	if establishmentID != nil {
+		query = query.Where("warehouses.establishment_id = ?", *establishmentID)
+	}
+
+	var stocks []*models.Stock
+	err := query.Find(&stocks).Error
+	return stocks, err
+}
+
 func (r *warehouseRepository) CreateSupply(ctx context.Context, supply *models.Supply) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
 		// Сохраняем Items во временную переменную и очищаем supply.Items
 		// чтобы GORM не пытался создать их автоматически
 		items := supply.Items
@@ -277,7 +458,7 @@ func (r *warehouseRepository) CreateSupply(ctx context.Context, supply *models.S
 }
 
 func (r *warehouseRepository) UpdateSupply(ctx context.Context, supply *models.Supply) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
 		// Удаляем старые элементы поставки
 		if err := tx.Where("supply_id = ?", supply.ID).Delete(&models.SupplyItem{}).Error; err != nil {
 			return err
@@ -312,12 +493,12 @@ func (r *warehouseRepository) UpdateSupply(ctx context.Context, supply *models.S
 }
 
 func (r *warehouseRepository) DeleteSupply(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Supply{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.Supply{}, "id = ?", id).Error
 }
 
 func (r *warehouseRepository) GetSupplyByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Supply, error) {
 	var supply models.Supply
-	query := r.db.WithContext(ctx).
+	query := dbFor(ctx, r.db).
 		Preload("Warehouse").
 		Preload("Supplier").
 		Preload("Items.Ingredient").
@@ -339,7 +520,7 @@ func (r *warehouseRepository) GetSupplyByID(ctx context.Context, id uuid.UUID, e
 func (r *warehouseRepository) GetSuppliesByIngredientOrProduct(ctx context.Context, establishmentID uuid.UUID, ingredientID *uuid.UUID, productID *uuid.UUID) ([]*models.Supply, error) {
 	// Оптимизированный запрос с JOIN вместо вложенных подзапросов
 	var supplies []*models.Supply
-	query := r.db.WithContext(ctx).
+	query := dbFor(ctx, r.db).
 		Model(&models.Supply{}).
 		Preload("Warehouse").
 		Preload("Supplier").
@@ -365,7 +546,7 @@ func (r *warehouseRepository) GetSuppliesByIngredientOrProduct(ctx context.Conte
 }
 
 func (r *warehouseRepository) GetSuppliesByWarehouse(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID) ([]*models.Supply, error) {
-	query := r.db.WithContext(ctx).
+	query := dbFor(ctx, r.db).
 		Model(&models.Supply{}).
 		Preload("Warehouse").
 		Preload("Supplier").
@@ -384,7 +565,7 @@ func (r *warehouseRepository) GetSuppliesByWarehouse(ctx context.Context, establ
 }
 
 func (r *warehouseRepository) CreateWriteOff(ctx context.Context, writeOff *models.WriteOff) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
 		// Сохраняем Items во временную переменную и очищаем writeOff.Items
 		// чтобы GORM не пытался создать их автоматически
 		items := writeOff.Items
@@ -414,7 +595,7 @@ func (r *warehouseRepository) CreateWriteOff(ctx context.Context, writeOff *mode
 }
 
 func (r *warehouseRepository) GetWriteOffsByWarehouse(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID) ([]*models.WriteOff, error) {
-	query := r.db.WithContext(ctx).
+	query := dbFor(ctx, r.db).
 		Model(&models.WriteOff{}).
 		Preload("Warehouse").
 		Preload("Items.Ingredient").
@@ -433,7 +614,7 @@ func (r *warehouseRepository) GetWriteOffsByWarehouse(ctx context.Context, estab
 
 func (r *warehouseRepository) GetWriteOffByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.WriteOff, error) {
 	var writeOff models.WriteOff
-	query := r.db.WithContext(ctx).
+	query := dbFor(ctx, r.db).
 		Preload("Warehouse").
 		Preload("Items.Ingredient").
 		Preload("Items.Product").
@@ -451,15 +632,305 @@ func (r *warehouseRepository) GetWriteOffByID(ctx context.Context, id uuid.UUID,
 	return &writeOff, err
 }
 
+// ——— Transfer ———
+
+func (r *warehouseRepository) CreateTransfer(ctx context.Context, transfer *models.Transfer) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		// Сохраняем Items во временную переменную, чтобы GORM не создавал их автоматически
+		items := transfer.Items
+		transfer.Items = nil
+
+		if err := tx.Create(transfer).Error; err != nil {
+			return err
+		}
+
+		for i := range items {
+			items[i].TransferID = transfer.ID
+			if items[i].ID == uuid.Nil {
+				items[i].ID = uuid.New()
+			}
+			if err := tx.Create(&items[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		transfer.Items = items
+		return nil
+	})
+}
+
+func (r *warehouseRepository) UpdateTransfer(ctx context.Context, transfer *models.Transfer) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Transfer{}).Where("id = ?", transfer.ID).Updates(map[string]interface{}{
+			"status":       transfer.Status,
+			"comment":      transfer.Comment,
+			"total_amount": models.RoundTo2(transfer.TotalAmount),
+			"sent_at":      transfer.SentAt,
+			"received_at":  transfer.ReceivedAt,
+		}).Error; err != nil {
+			return err
+		}
+		// Обновляем зафиксированную себестоимость позиций
+		for i := range transfer.Items {
+			if err := tx.Model(&models.TransferItem{}).Where("id = ?", transfer.Items[i].ID).Updates(map[string]interface{}{
+				"price_per_unit": models.RoundTo2(transfer.Items[i].PricePerUnit),
+				"total_amount":   models.RoundTo2(transfer.Items[i].TotalAmount),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *warehouseRepository) GetTransferByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Transfer, error) {
+	var transfer models.Transfer
+	query := dbFor(ctx, r.db).
+		Preload("SourceWarehouse").
+		Preload("TargetWarehouse").
+		Preload("Items.Ingredient").
+		Preload("Items.Product").
+		Joins("JOIN warehouses ON transfers.source_warehouse_id = warehouses.id").
+		Where("transfers.id = ?", id)
+
+	if establishmentID != nil {
+		query = query.Where("warehouses.establishment_id = ?", *establishmentID)
+	}
+
+	err := query.First(&transfer).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &transfer, err
+}
+
+// GetTransfersByWarehouse возвращает перемещения, где склад является отправителем или получателем
+func (r *warehouseRepository) GetTransfersByWarehouse(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID) ([]*models.Transfer, error) {
+	query := dbFor(ctx, r.db).
+		Model(&models.Transfer{}).
+		Preload("SourceWarehouse").
+		Preload("TargetWarehouse").
+		Preload("Items.Ingredient").
+		Preload("Items.Product").
+		Joins("JOIN warehouses ON transfers.source_warehouse_id = warehouses.id").
+		Where("warehouses.establishment_id = ?", establishmentID)
+
+	if warehouseID != nil {
+		query = query.Where("(transfers.source_warehouse_id = ? OR transfers.target_warehouse_id = ?)", *warehouseID, *warehouseID)
+	}
+
+	var transfers []*models.Transfer
+	err := query.Order("transfers.transfer_date_time DESC").Find(&transfers).Error
+	return transfers, err
+}
+
+// ——— Production ———
+
+func (r *warehouseRepository) CreateProduction(ctx context.Context, production *models.Production) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		// Сохраняем Items во временную переменную, чтобы GORM не создавал их автоматически
+		items := production.Items
+		production.Items = nil
+
+		if err := tx.Create(production).Error; err != nil {
+			return err
+		}
+
+		for i := range items {
+			items[i].ProductionID = production.ID
+			if items[i].ID == uuid.Nil {
+				items[i].ID = uuid.New()
+			}
+			if err := tx.Create(&items[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		production.Items = items
+		return nil
+	})
+}
+
+func (r *warehouseRepository) GetProductionByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Production, error) {
+	var production models.Production
+	query := dbFor(ctx, r.db).
+		Preload("Warehouse").
+		Preload("SemiFinished").
+		Preload("Items.Ingredient").
+		Joins("JOIN warehouses ON productions.warehouse_id = warehouses.id").
+		Where("productions.id = ?", id)
+
+	if establishmentID != nil {
+		query = query.Where("warehouses.establishment_id = ?", *establishmentID)
+	}
+
+	err := query.First(&production).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &production, err
+}
+
+func (r *warehouseRepository) GetProductionsByWarehouse(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID) ([]*models.Production, error) {
+	query := dbFor(ctx, r.db).
+		Model(&models.Production{}).
+		Preload("Warehouse").
+		Preload("SemiFinished").
+		Preload("Items.Ingredient").
+		Joins("JOIN warehouses ON productions.warehouse_id = warehouses.id").
+		Where("warehouses.establishment_id = ?", establishmentID)
+
+	if warehouseID != nil {
+		query = query.Where("productions.warehouse_id = ?", *warehouseID)
+	}
+
+	var productions []*models.Production
+	err := query.Order("productions.production_date_time DESC").Find(&productions).Error
+	return productions, err
+}
+
+// ——— StockLot ———
+
+func (r *warehouseRepository) CreateStockLot(ctx context.Context, lot *models.StockLot) error {
+	return dbFor(ctx, r.db).Create(lot).Error
+}
+
+func (r *warehouseRepository) UpdateStockLot(ctx context.Context, lot *models.StockLot) error {
+	return dbFor(ctx, r.db).Model(&models.StockLot{}).Where("id = ?", lot.ID).
+		Update("remaining_quantity", models.RoundTo2(lot.RemainingQuantity)).Error
+}
+
+// GetOpenStockLots возвращает партии с остатком в порядке FEFO: сначала с ближайшим сроком годности,
+// партии без срока — в конце; при равном сроке — FIFO (сначала самые ранние)
+func (r *warehouseRepository) GetOpenStockLots(ctx context.Context, warehouseID uuid.UUID, ingredientID, productID, semiFinishedID *uuid.UUID) ([]*models.StockLot, error) {
+	query := dbFor(ctx, r.db).
+		Where("warehouse_id = ? AND remaining_quantity > 0", warehouseID)
+	if ingredientID != nil {
+		query = query.Where("ingredient_id = ?", *ingredientID)
+	} else if productID != nil {
+		query = query.Where("product_id = ?", *productID)
+	} else if semiFinishedID != nil {
+		query = query.Where("semi_finished_id = ?", *semiFinishedID)
+	}
+
+	var lots []*models.StockLot
+	err := query.Order("expires_at ASC NULLS LAST, received_at ASC, created_at ASC").Find(&lots).Error
+	return lots, err
+}
+
+func (r *warehouseRepository) GetStockLots(ctx context.Context, establishmentID uuid.UUID, filter *StockLotFilter) ([]*models.StockLot, error) {
+	query := dbFor(ctx, r.db).
+		Model(&models.StockLot{}).
+		Preload("Warehouse").
+		Preload("Ingredient").
+		Preload("Product").
+		Preload("SemiFinished").
+		Joins("JOIN warehouses ON stock_lots.warehouse_id = warehouses.id").
+		Where("warehouses.establishment_id = ?", establishmentID)
+
+	if filter != nil {
+		if filter.WarehouseID != nil {
+			query = query.Where("stock_lots.warehouse_id = ?", *filter.WarehouseID)
+		}
+		if filter.IngredientID != nil {
+			query = query.Where("stock_lots.ingredient_id = ?", *filter.IngredientID)
+		}
+		if filter.ProductID != nil {
+			query = query.Where("stock_lots.product_id = ?", *filter.ProductID)
+		}
+		if filter.SemiFinishedID != nil {
+			query = query.Where("stock_lots.semi_finished_id = ?", *filter.SemiFinishedID)
+		}
+		if filter.OnlyOpen {
+			query = query.Where("stock_lots.remaining_quantity > 0")
+		}
+		if filter.ExpiresBefore != nil {
+			query = query.Where("stock_lots.expires_at IS NOT NULL AND stock_lots.expires_at <= ?", *filter.ExpiresBefore)
+		}
+	}
+
+	var lots []*models.StockLot
+	err := query.Order("stock_lots.expires_at ASC NULLS LAST, stock_lots.received_at ASC, stock_lots.created_at ASC").Find(&lots).Error
+	return lots, err
+}
+
+func (r *warehouseRepository) CreateStockLotConsumption(ctx context.Context, consumption *models.StockLotConsumption) error {
+	return dbFor(ctx, r.db).Create(consumption).Error
+}
+
+// GetConsumedQuantities возвращает расход позиций склада документами указанных типов начиная с since
+func (r *warehouseRepository) GetConsumedQuantities(ctx context.Context, warehouseID uuid.UUID, documentTypes []string, since time.Time) ([]ConsumedQuantity, error) {
+	var rows []ConsumedQuantity
+	err := dbFor(ctx, r.db).
+		Model(&models.StockLotConsumption{}).
+		Select("COALESCE(ingredient_id, product_id, semi_finished_id) AS item_id, SUM(quantity) AS quantity").
+		Where("warehouse_id = ? AND document_type IN ? AND consumed_at >= ?", warehouseID, documentTypes, since).
+		Group("COALESCE(ingredient_id, product_id, semi_finished_id)").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// GetLastSupplyPrice возвращает цену и поставщика из последней (неотмененной) поставки позиции
+func (r *warehouseRepository) GetLastSupplyPrice(ctx context.Context, establishmentID uuid.UUID, ingredientID, productID *uuid.UUID) (*LastSupplyPrice, error) {
+	query := dbFor(ctx, r.db).
+		Table("supply_items").
+		Select("supplies.supplier_id, supply_items.price_per_unit, supply_items.unit, supplies.delivery_date_time").
+		Joins("JOIN supplies ON supply_items.supply_id = supplies.id").
+		Joins("JOIN warehouses ON supplies.warehouse_id = warehouses.id").
+		Where("warehouses.establishment_id = ? AND supplies.status <> ?", establishmentID, "cancelled")
+	if ingredientID != nil {
+		query = query.Where("supply_items.ingredient_id = ?", *ingredientID)
+	} else if productID != nil {
+		query = query.Where("supply_items.product_id = ?", *productID)
+	} else {
+		return nil, nil
+	}
+
+	var rows []LastSupplyPrice
+	if err := query.Order("supplies.delivery_date_time DESC, supply_items.created_at DESC").Limit(1).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return &rows[0], nil
+}
+
+// GetConsumedCostByDocuments возвращает фактическую себестоимость, израсходованную документами указанного типа
+func (r *warehouseRepository) GetConsumedCostByDocuments(ctx context.Context, documentType string, documentIDs []uuid.UUID) (map[uuid.UUID]float64, error) {
+	result := make(map[uuid.UUID]float64)
+	if len(documentIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		DocumentID uuid.UUID
+		Total      float64
+	}
+	err := dbFor(ctx, r.db).
+		Model(&models.StockLotConsumption{}).
+		Select("document_id, SUM(total_cost) AS total").
+		Where("document_type = ? AND document_id IN ?", documentType, documentIDs).
+		Group("document_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.DocumentID] = row.Total
+	}
+	return result, nil
+}
+
 // ——— WriteOffReason CRUD ———
 
 func (r *warehouseRepository) CreateWriteOffReason(ctx context.Context, reason *models.WriteOffReason) error {
-	return r.db.WithContext(ctx).Create(reason).Error
+	return dbFor(ctx, r.db).Create(reason).Error
 }
 
 func (r *warehouseRepository) ListWriteOffReasons(ctx context.Context, establishmentID uuid.UUID) ([]*models.WriteOffReason, error) {
 	var reasons []*models.WriteOffReason
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("establishment_id = ?", establishmentID).
 		Order("name ASC").
 		Find(&reasons).Error
@@ -468,7 +939,7 @@ func (r *warehouseRepository) ListWriteOffReasons(ctx context.Context, establish
 
 func (r *warehouseRepository) GetWriteOffReasonByID(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) (*models.WriteOffReason, error) {
 	var reason models.WriteOffReason
-	err := r.db.WithContext(ctx).
+	err := dbFor(ctx, r.db).
 		Where("id = ? AND establishment_id = ?", id, establishmentID).
 		First(&reason).Error
 	if err == gorm.ErrRecordNotFound {
@@ -478,9 +949,91 @@ func (r *warehouseRepository) GetWriteOffReasonByID(ctx context.Context, id uuid
 }
 
 func (r *warehouseRepository) UpdateWriteOffReason(ctx context.Context, reason *models.WriteOffReason) error {
-	return r.db.WithContext(ctx).Save(reason).Error
+	return dbFor(ctx, r.db).Save(reason).Error
 }
 
 func (r *warehouseRepository) DeleteWriteOffReason(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.WriteOffReason{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.WriteOffReason{}, "id = ?", id).Error
+}
+
+// ——— StockLedger ———
+
+func (r *warehouseRepository) CreateStockLedgerEntry(ctx context.Context, entry *models.StockLedgerEntry) error {
+	return dbFor(ctx, r.db).Create(entry).Error
+}
+
+func applyStockLedgerFilter(query *gorm.DB, filter *StockLedgerFilter) *gorm.DB {
+	if filter == nil {
+		return query
+	}
+	if filter.WarehouseID != nil {
+		query = query.Where("stock_ledger_entries.warehouse_id = ?", *filter.WarehouseID)
+	}
+	if filter.StockID != nil {
+		query = query.Where("stock_ledger_entries.stock_id = ?", *filter.StockID)
+	}
+	if filter.IngredientID != nil {
+		query = query.Where("stock_ledger_entries.ingredient_id = ?", *filter.IngredientID)
+	}
+	if filter.ProductID != nil {
+		query = query.Where("stock_ledger_entries.product_id = ?", *filter.ProductID)
+	}
+	if filter.SemiFinishedID != nil {
+		query = query.Where("stock_ledger_entries.semi_finished_id = ?", *filter.SemiFinishedID)
+	}
+	if filter.MovementType != nil {
+		query = query.Where("stock_ledger_entries.movement_type = ?", *filter.MovementType)
+	}
+	if filter.SourceID != nil {
+		query = query.Where("stock_ledger_entries.source_id = ?", *filter.SourceID)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("stock_ledger_entries.occurred_at >= ?", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("stock_ledger_entries.occurred_at <= ?", *filter.EndDate)
+	}
+	return query
+}
+
+// stockLedgerBalanceAfter остаток позиции после записи по всему журналу позиции, а не только по
+// отобранным записям. Считается при чтении: запись, проведенная задним числом (инвентаризация
+// на прошедшую дату), меняет остаток и во всех более поздних записях, а журнал не изменяется
+const stockLedgerBalanceAfter = `(SELECT COALESCE(SUM(prev.quantity), 0) FROM stock_ledger_entries prev
+	WHERE prev.stock_id = stock_ledger_entries.stock_id
+		AND (prev.occurred_at, prev.created_at) <= (stock_ledger_entries.occurred_at, stock_ledger_entries.created_at)) AS balance_after`
+
+func (r *warehouseRepository) GetStockLedger(ctx context.Context, establishmentID uuid.UUID, filter *StockLedgerFilter) ([]*models.StockLedgerEntry, error) {
+	query := dbFor(ctx, r.db).
+		Model(&models.StockLedgerEntry{}).
+		Select("stock_ledger_entries.*, " + stockLedgerBalanceAfter).
+		Preload("Warehouse").
+		Preload("Ingredient").
+		Preload("Product").
+		Preload("SemiFinished").
+		Joins("JOIN warehouses ON stock_ledger_entries.warehouse_id = warehouses.id").
+		Where("warehouses.establishment_id = ?", establishmentID)
+	query = applyStockLedgerFilter(query, filter)
+
+	var entries []*models.StockLedgerEntry
+	err := query.Order("stock_ledger_entries.occurred_at ASC, stock_ledger_entries.created_at ASC").Find(&entries).Error
+	return entries, err
+}
+
+func (r *warehouseRepository) GetStockLedgerBalances(ctx context.Context, establishmentID uuid.UUID, filter *StockLedgerFilter) ([]StockLedgerBalance, error) {
+	query := dbFor(ctx, r.db).
+		Model(&models.StockLedgerEntry{}).
+		Select(`stock_ledger_entries.stock_id, stock_ledger_entries.warehouse_id,
+			stock_ledger_entries.ingredient_id, stock_ledger_entries.product_id, stock_ledger_entries.semi_finished_id,
+			MAX(stock_ledger_entries.unit) AS unit,
+			SUM(stock_ledger_entries.quantity) AS quantity, SUM(stock_ledger_entries.amount) AS amount`).
+		Joins("JOIN warehouses ON stock_ledger_entries.warehouse_id = warehouses.id").
+		Where("warehouses.establishment_id = ?", establishmentID)
+	query = applyStockLedgerFilter(query, filter)
+
+	var rows []StockLedgerBalance
+	err := query.Group(`stock_ledger_entries.stock_id, stock_ledger_entries.warehouse_id,
+		stock_ledger_entries.ingredient_id, stock_ledger_entries.product_id, stock_ledger_entries.semi_finished_id`).
+		Scan(&rows).Error
+	return rows, err
 }
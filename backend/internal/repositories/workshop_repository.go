@@ -26,18 +26,18 @@ func NewWorkshopRepository(db *gorm.DB) WorkshopRepository {
 }
 
 func (r *workshopRepository) CreateWorkshop(ctx context.Context, w *models.Workshop) error {
-	return r.db.WithContext(ctx).Create(w).Error
+	return dbFor(ctx, r.db).Create(w).Error
 }
 
 func (r *workshopRepository) ListWorkshops(ctx context.Context, establishmentID uuid.UUID) ([]*models.Workshop, error) {
 	var list []*models.Workshop
-	err := r.db.WithContext(ctx).Where("establishment_id = ?", establishmentID).Find(&list).Error
+	err := dbFor(ctx, r.db).Where("establishment_id = ?", establishmentID).Find(&list).Error
 	return list, err
 }
 
 func (r *workshopRepository) GetWorkshopByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Workshop, error) {
 	var w models.Workshop
-	q := r.db.WithContext(ctx)
+	q := dbFor(ctx, r.db)
 	if establishmentID != nil {
 		q = q.Where("establishment_id = ?", *establishmentID)
 	}
@@ -46,9 +46,9 @@ func (r *workshopRepository) GetWorkshopByID(ctx context.Context, id uuid.UUID,
 }
 
 func (r *workshopRepository) UpdateWorkshop(ctx context.Context, w *models.Workshop) error {
-	return r.db.WithContext(ctx).Save(w).Error
+	return dbFor(ctx, r.db).Save(w).Error
 }
 
 func (r *workshopRepository) DeleteWorkshop(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Workshop{}, "id = ?", id).Error
+	return dbFor(ctx, r.db).Delete(&models.Workshop{}, "id = ?", id).Error
 }
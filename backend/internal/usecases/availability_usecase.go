@@ -0,0 +1,256 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// ErrItemUnavailable позиция меню недоступна по расписанию
+var ErrItemUnavailable = errors.New("item is not available at this time")
+
+// UnavailableMenuItem позиция заказа, которую нельзя продать по расписанию доступности
+type UnavailableMenuItem struct {
+	Type     string    `json:"type"` // product, tech_card
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"` // Название действующего расписания
+}
+
+// ItemUnavailableError перечисляет позиции, недоступные в момент заказа
+type ItemUnavailableError struct {
+	Items []UnavailableMenuItem
+}
+
+func (e *ItemUnavailableError) Error() string {
+	parts := make([]string, 0, len(e.Items))
+	for _, item := range e.Items {
+		parts = append(parts, fmt.Sprintf("%s (schedule %q)", item.Name, item.Schedule))
+	}
+	return "items are not available at this time: " + strings.Join(parts, "; ")
+}
+
+func (e *ItemUnavailableError) Unwrap() error {
+	return ErrItemUnavailable
+}
+
+// MenuItemRef позиция меню для проверки доступности
+type MenuItemRef struct {
+	Type       string
+	ID         uuid.UUID
+	Name       string
+	CategoryID uuid.UUID
+	ScheduleID *uuid.UUID // Собственное расписание позиции
+}
+
+// AvailabilityTarget позиция, которой назначается расписание: ровно одно из полей
+type AvailabilityTarget struct {
+	CategoryID *uuid.UUID
+	ProductID  *uuid.UUID
+	TechCardID *uuid.UUID
+}
+
+// MenuAvailability расписания заведения на момент Now (в часовом поясе заведения)
+type MenuAvailability struct {
+	Now               time.Time
+	schedules         map[uuid.UUID]*models.AvailabilitySchedule
+	categorySchedules map[uuid.UUID]uuid.UUID
+}
+
+// effectiveSchedule возвращает действующее расписание: собственное расписание позиции, иначе расписание категории
+func (a *MenuAvailability) effectiveSchedule(scheduleID *uuid.UUID, categoryID uuid.UUID) *models.AvailabilitySchedule {
+	if scheduleID != nil {
+		return a.schedules[*scheduleID]
+	}
+	if id, ok := a.categorySchedules[categoryID]; ok {
+		return a.schedules[id]
+	}
+	return nil
+}
+
+// IsAvailable проверяет, можно ли продавать позицию сейчас
+func (a *MenuAvailability) IsAvailable(scheduleID *uuid.UUID, categoryID uuid.UUID) bool {
+	if a == nil {
+		return true
+	}
+	schedule := a.effectiveSchedule(scheduleID, categoryID)
+	return schedule == nil || schedule.IsAvailableAt(a.Now)
+}
+
+type AvailabilityUseCase struct {
+	repo              repositories.AvailabilityRepository
+	categoryRepo      repositories.CategoryRepository
+	establishmentRepo repositories.EstablishmentRepository
+}
+
+func NewAvailabilityUseCase(
+	repo repositories.AvailabilityRepository,
+	categoryRepo repositories.CategoryRepository,
+	establishmentRepo repositories.EstablishmentRepository,
+) *AvailabilityUseCase {
+	return &AvailabilityUseCase{
+		repo:              repo,
+		categoryRepo:      categoryRepo,
+		establishmentRepo: establishmentRepo,
+	}
+}
+
+// ——— Расписания ———
+
+func (uc *AvailabilityUseCase) ListSchedules(ctx context.Context, establishmentID uuid.UUID) ([]*models.AvailabilitySchedule, error) {
+	return uc.repo.ListSchedules(ctx, establishmentID)
+}
+
+func (uc *AvailabilityUseCase) GetSchedule(ctx context.Context, id, establishmentID uuid.UUID) (*models.AvailabilitySchedule, error) {
+	schedule, err := uc.repo.GetScheduleByID(ctx, id, &establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return nil, errors.New("availability schedule not found")
+	}
+	return schedule, nil
+}
+
+func (uc *AvailabilityUseCase) CreateSchedule(ctx context.Context, schedule *models.AvailabilitySchedule, establishmentID uuid.UUID) error {
+	schedule.EstablishmentID = establishmentID
+	if err := validateSchedule(schedule); err != nil {
+		return err
+	}
+	return uc.repo.CreateSchedule(ctx, schedule)
+}
+
+func (uc *AvailabilityUseCase) UpdateSchedule(ctx context.Context, schedule *models.AvailabilitySchedule, establishmentID uuid.UUID) error {
+	if _, err := uc.GetSchedule(ctx, schedule.ID, establishmentID); err != nil {
+		return err
+	}
+	schedule.EstablishmentID = establishmentID
+	if err := validateSchedule(schedule); err != nil {
+		return err
+	}
+	return uc.repo.UpdateSchedule(ctx, schedule)
+}
+
+func (uc *AvailabilityUseCase) DeleteSchedule(ctx context.Context, id, establishmentID uuid.UUID) error {
+	if _, err := uc.GetSchedule(ctx, id, establishmentID); err != nil {
+		return err
+	}
+	return uc.repo.DeleteSchedule(ctx, id)
+}
+
+// validateSchedule проверяет название и правила расписания
+func validateSchedule(schedule *models.AvailabilitySchedule) error {
+	schedule.Name = strings.TrimSpace(schedule.Name)
+	if schedule.Name == "" {
+		return errors.New("schedule name is required")
+	}
+	for i := range schedule.Rules {
+		if err := schedule.Rules[i].Normalize(); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Assign назначает расписание категории, товару или тех-карте; scheduleID nil снимает расписание
+func (uc *AvailabilityUseCase) Assign(ctx context.Context, establishmentID uuid.UUID, target AvailabilityTarget, scheduleID *uuid.UUID) error {
+	if scheduleID != nil {
+		if _, err := uc.GetSchedule(ctx, *scheduleID, establishmentID); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	switch {
+	case target.CategoryID != nil && target.ProductID == nil && target.TechCardID == nil:
+		err = uc.repo.AssignCategory(ctx, *target.CategoryID, establishmentID, scheduleID)
+	case target.ProductID != nil && target.CategoryID == nil && target.TechCardID == nil:
+		err = uc.repo.AssignProduct(ctx, *target.ProductID, establishmentID, scheduleID)
+	case target.TechCardID != nil && target.CategoryID == nil && target.ProductID == nil:
+		err = uc.repo.AssignTechCard(ctx, *target.TechCardID, establishmentID, scheduleID)
+	default:
+		return errors.New("exactly one of category_id, product_id or tech_card_id is required")
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("item not found")
+	}
+	return err
+}
+
+// ——— Доступность ———
+
+// Now возвращает текущее время в часовом поясе заведения
+func (uc *AvailabilityUseCase) Now(ctx context.Context, establishmentID uuid.UUID) (time.Time, error) {
+	timezone, err := uc.establishmentRepo.GetTimezone(ctx, establishmentID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get establishment timezone: %w", err)
+	}
+	return time.Now().In(models.LoadEstablishmentLocation(timezone)), nil
+}
+
+// Snapshot загружает расписания заведения и назначения категорий на текущий момент
+func (uc *AvailabilityUseCase) Snapshot(ctx context.Context, establishmentID uuid.UUID) (*MenuAvailability, error) {
+	now, err := uc.Now(ctx, establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	schedules, err := uc.repo.ListSchedules(ctx, establishmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get availability schedules: %w", err)
+	}
+	categories, err := uc.categoryRepo.List(ctx, &repositories.CategoryFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	availability := &MenuAvailability{
+		Now:               now,
+		schedules:         make(map[uuid.UUID]*models.AvailabilitySchedule, len(schedules)),
+		categorySchedules: make(map[uuid.UUID]uuid.UUID),
+	}
+	for _, s := range schedules {
+		availability.schedules[s.ID] = s
+	}
+	for _, c := range categories {
+		if c.AvailabilityScheduleID != nil {
+			availability.categorySchedules[c.ID] = *c.AvailabilityScheduleID
+		}
+	}
+	return availability, nil
+}
+
+// CheckItems возвращает *ItemUnavailableError, если какую-либо из позиций сейчас нельзя продавать
+func (uc *AvailabilityUseCase) CheckItems(ctx context.Context, establishmentID uuid.UUID, items []MenuItemRef) error {
+	if uc == nil || len(items) == 0 {
+		return nil
+	}
+	availability, err := uc.Snapshot(ctx, establishmentID)
+	if err != nil {
+		return err
+	}
+
+	var unavailable []UnavailableMenuItem
+	for _, item := range items {
+		if availability.IsAvailable(item.ScheduleID, item.CategoryID) {
+			continue
+		}
+		unavailable = append(unavailable, UnavailableMenuItem{
+			Type:     item.Type,
+			ID:       item.ID,
+			Name:     item.Name,
+			Schedule: availability.effectiveSchedule(item.ScheduleID, item.CategoryID).Name,
+		})
+	}
+	if len(unavailable) > 0 {
+		return &ItemUnavailableError{Items: unavailable}
+	}
+	return nil
+}
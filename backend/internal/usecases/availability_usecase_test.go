@@ -0,0 +1,125 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+type fakeAvailabilityRepository struct {
+	repositories.AvailabilityRepository
+	schedules []*models.AvailabilitySchedule
+}
+
+func (r *fakeAvailabilityRepository) ListSchedules(ctx context.Context, establishmentID uuid.UUID) ([]*models.AvailabilitySchedule, error) {
+	return r.schedules, nil
+}
+
+// fakeOrderRepository хранит созданные заказы в памяти
+type fakeOrderRepository struct {
+	repositories.OrderRepository
+	orders map[uuid.UUID]*models.Order
+}
+
+func (r *fakeOrderRepository) Create(ctx context.Context, order *models.Order) error {
+	order.ID = uuid.New()
+	r.orders[order.ID] = order
+	return nil
+}
+
+func (r *fakeOrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Order, error) {
+	order, ok := r.orders[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return order, nil
+}
+
+func (r *fakeOrderRepository) Update(ctx context.Context, order *models.Order) error {
+	r.orders[order.ID] = order
+	return nil
+}
+
+func TestOrderUseCase_CreateOrder_Availability(t *testing.T) {
+	ctx := context.Background()
+	establishmentID := uuid.New()
+
+	// Расписание на весь день и расписание, которое сегодня не действует (через три дня от текущего)
+	allDay := &models.AvailabilitySchedule{ID: uuid.New(), Name: "Весь день", Active: true, Rules: []models.AvailabilityRule{
+		{Weekdays: "1,2,3,4,5,6,7", StartTime: "00:00", EndTime: "24:00"},
+	}}
+	otherDay := strconv.Itoa(int(time.Now().UTC().AddDate(0, 0, 3).Weekday()+6)%7 + 1)
+	breakfast := &models.AvailabilitySchedule{ID: uuid.New(), Name: "Завтраки", Active: true, Rules: []models.AvailabilityRule{
+		{Weekdays: otherDay, StartTime: "00:00", EndTime: "24:00"},
+	}}
+	// Неактивное расписание продажу не ограничивает
+	disabled := &models.AvailabilitySchedule{ID: uuid.New(), Name: "Отключено", Active: false, Rules: breakfast.Rules}
+
+	breakfastCategory := &models.Category{ID: uuid.New(), Name: "Завтраки", AvailabilityScheduleID: &breakfast.ID}
+	drinksCategory := &models.Category{ID: uuid.New(), Name: "Напитки"}
+
+	syrniki := &models.TechCard{ID: uuid.New(), Name: "Сырники", CategoryID: breakfastCategory.ID, Price: 250}
+	// Собственное расписание позиции важнее расписания категории
+	omelette := &models.TechCard{ID: uuid.New(), Name: "Омлет", CategoryID: breakfastCategory.ID, AvailabilityScheduleID: &allDay.ID, Price: 200}
+	cola := &models.Product{ID: uuid.New(), Name: "Кола", CategoryID: drinksCategory.ID, Price: 100}
+	lemonade := &models.Product{ID: uuid.New(), Name: "Лимонад", CategoryID: drinksCategory.ID, AvailabilityScheduleID: &breakfast.ID, Price: 150}
+	// Модификация без своего расписания продается по расписанию родителя
+	lemonadeLarge := &models.Product{ID: uuid.New(), Name: "Лимонад 0.5", CategoryID: drinksCategory.ID, ParentID: &lemonade.ID, Price: 200}
+	kvass := &models.Product{ID: uuid.New(), Name: "Квас", CategoryID: drinksCategory.ID, AvailabilityScheduleID: &disabled.ID, Price: 90}
+
+	warehouse := &stockAvailabilityRepository{
+		fakeWarehouseRepository: newFakeWarehouseRepository(),
+		techCards:               map[uuid.UUID]*models.TechCard{syrniki.ID: syrniki, omelette.ID: omelette},
+		products:                []*models.Product{cola, lemonade, lemonadeLarge, kvass},
+	}
+	establishments := &fakeEstablishmentRepository{timezone: "UTC"}
+	availability := NewAvailabilityUseCase(
+		&fakeAvailabilityRepository{schedules: []*models.AvailabilitySchedule{allDay, breakfast, disabled}},
+		&fakeCategoryRepository{items: []*models.Category{breakfastCategory, drinksCategory}},
+		establishments,
+	)
+	orders := &fakeOrderRepository{orders: make(map[uuid.UUID]*models.Order)}
+	uc := NewOrderUseCase(orders, warehouse, nil, nil, nil, establishments, availability, nil)
+
+	t.Run("unavailable items reject the order", func(t *testing.T) {
+		_, err := uc.CreateOrder(ctx, establishmentID, nil, []models.OrderItem{
+			{TechCardID: &syrniki.ID, Quantity: 1},
+			{ProductID: &cola.ID, Quantity: 2},
+			{ProductID: &lemonadeLarge.ID, Quantity: 1},
+		})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrItemUnavailable))
+		var unavailableErr *ItemUnavailableError
+		require.ErrorAs(t, err, &unavailableErr)
+		assert.Equal(t, []UnavailableMenuItem{
+			{Type: MenuItemTypeTechCard, ID: syrniki.ID, Name: "Сырники", Schedule: "Завтраки"},
+			{Type: MenuItemTypeProduct, ID: lemonadeLarge.ID, Name: "Лимонад 0.5", Schedule: "Завтраки"},
+		}, unavailableErr.Items)
+		assert.Empty(t, orders.orders)
+	})
+
+	t.Run("available items are sold", func(t *testing.T) {
+		order, err := uc.CreateOrder(ctx, establishmentID, nil, []models.OrderItem{
+			{TechCardID: &omelette.ID, Quantity: 1},
+			{ProductID: &cola.ID, Quantity: 2},
+			{ProductID: &kvass.ID, Quantity: 1},
+		})
+		require.NoError(t, err)
+		assert.InDelta(t, 200+2*100+90, order.TotalAmount, 1e-9)
+		assert.Contains(t, orders.orders, order.ID)
+
+		// Добавление позиции в существующий заказ проверяется так же
+		_, err = uc.AddOrderItem(ctx, order.ID, models.OrderItem{ProductID: &lemonade.ID, Quantity: 1})
+		assert.True(t, errors.Is(err, ErrItemUnavailable))
+		assert.Len(t, orders.orders[order.ID].Items, 3)
+	})
+}
@@ -0,0 +1,402 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// ErrBarcodeNotFound возвращается, если штрихкод не привязан ни к одной позиции заведения
+var ErrBarcodeNotFound = errors.New("barcode not found")
+
+// BarcodeUseCase штрихкоды позиций и сканирование в поставках, инвентаризациях и заказах
+type BarcodeUseCase struct {
+	repo          repositories.BarcodeRepository
+	warehouseRepo repositories.WarehouseRepository
+	warehouse     *WarehouseUseCase
+	inventory     *InventoryUseCase
+	orders        *OrderUseCase
+}
+
+func NewBarcodeUseCase(
+	repo repositories.BarcodeRepository,
+	warehouseRepo repositories.WarehouseRepository,
+	warehouse *WarehouseUseCase,
+	inventory *InventoryUseCase,
+	orders *OrderUseCase,
+) *BarcodeUseCase {
+	return &BarcodeUseCase{
+		repo:          repo,
+		warehouseRepo: warehouseRepo,
+		warehouse:     warehouse,
+		inventory:     inventory,
+		orders:        orders,
+	}
+}
+
+// BarcodeMatch позиция, найденная по штрихкоду, и количество одного сканирования
+type BarcodeMatch struct {
+	Code         string     `json:"code"`
+	BarcodeID    *uuid.UUID `json:"barcode_id,omitempty"` // nil — найдено по штрихкоду в карточке позиции
+	Type         string     `json:"type"`                 // ingredient, product, tech_card
+	IngredientID *uuid.UUID `json:"ingredient_id,omitempty"`
+	ProductID    *uuid.UUID `json:"product_id,omitempty"`
+	TechCardID   *uuid.UUID `json:"tech_card_id,omitempty"`
+	Name         string     `json:"name"`
+	Quantity     float64    `json:"quantity"` // Множитель упаковки или вес из весового штрихкода
+	Unit         string     `json:"unit"`
+	Weighted     bool       `json:"weighted"`
+}
+
+// ——— Barcodes CRUD ———
+
+func (uc *BarcodeUseCase) List(ctx context.Context, establishmentID uuid.UUID, filter *repositories.BarcodeFilter) ([]*models.ItemBarcode, error) {
+	return uc.repo.List(ctx, establishmentID, filter)
+}
+
+// Create привязывает штрихкод к ингредиенту, товару или техкарте заведения
+func (uc *BarcodeUseCase) Create(ctx context.Context, barcode *models.ItemBarcode, establishmentID uuid.UUID) error {
+	barcode.EstablishmentID = establishmentID
+	if err := uc.validate(ctx, barcode); err != nil {
+		return err
+	}
+	return uc.repo.Create(ctx, barcode)
+}
+
+// Update изменяет код, множитель и единицу штрихкода; позиция, к которой он привязан, не меняется
+func (uc *BarcodeUseCase) Update(ctx context.Context, barcode *models.ItemBarcode, establishmentID uuid.UUID) error {
+	existing, err := uc.repo.GetByID(ctx, barcode.ID, establishmentID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrBarcodeNotFound
+	}
+	barcode.EstablishmentID = establishmentID
+	barcode.IngredientID = existing.IngredientID
+	barcode.ProductID = existing.ProductID
+	barcode.TechCardID = existing.TechCardID
+	if err := uc.validate(ctx, barcode); err != nil {
+		return err
+	}
+	return uc.repo.Update(ctx, barcode)
+}
+
+func (uc *BarcodeUseCase) Delete(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) error {
+	existing, err := uc.repo.GetByID(ctx, id, establishmentID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrBarcodeNotFound
+	}
+	return uc.repo.Delete(ctx, id)
+}
+
+// validate проверяет код, позицию и единицу штрихкода
+func (uc *BarcodeUseCase) validate(ctx context.Context, barcode *models.ItemBarcode) error {
+	barcode.Code = strings.TrimSpace(barcode.Code)
+	if barcode.Code == "" || strings.ContainsAny(barcode.Code, " \t") {
+		return errors.New("barcode code is required and must not contain spaces")
+	}
+	if barcode.Weighted && (len(barcode.Code) != models.WeightedBarcodeCodeLength || !models.IsWeightedBarcodePrefix(barcode.Code) || strings.Trim(barcode.Code, "0123456789") != "") {
+		return fmt.Errorf("weighted barcode code must be %d digits starting with 20-29", models.WeightedBarcodeCodeLength)
+	}
+	if barcode.Multiplier < 0 {
+		return errors.New("multiplier must be positive")
+	}
+	if barcode.Multiplier == 0 {
+		barcode.Multiplier = 1
+	}
+
+	ids := 0
+	for _, id := range []*uuid.UUID{barcode.IngredientID, barcode.ProductID, barcode.TechCardID} {
+		if id != nil {
+			ids++
+		}
+	}
+	if ids != 1 {
+		return errors.New("exactly one of ingredient_id, product_id or tech_card_id must be specified")
+	}
+
+	baseUnit := models.UnitPiece
+	switch {
+	case barcode.IngredientID != nil:
+		ingredient, err := uc.warehouseRepo.GetIngredientByID(ctx, *barcode.IngredientID)
+		if err != nil || ingredient == nil || ingredient.EstablishmentID != barcode.EstablishmentID {
+			return errors.New("ingredient not found")
+		}
+		if barcode.Unit != "" {
+			if _, err := ingredient.ConvertQuantity(1, barcode.Unit, ingredient.Unit); err != nil {
+				return fmt.Errorf("ingredient %q: %w", ingredient.Name, err)
+			}
+		}
+		baseUnit = ingredient.Unit
+	case barcode.ProductID != nil:
+		product, err := uc.warehouseRepo.GetProductByID(ctx, *barcode.ProductID)
+		if err != nil || product == nil || product.EstablishmentID != barcode.EstablishmentID {
+			return errors.New("product not found")
+		}
+	case barcode.TechCardID != nil:
+		techCard, err := uc.warehouseRepo.GetTechCardByID(ctx, *barcode.TechCardID)
+		if err != nil || techCard == nil || techCard.EstablishmentID != barcode.EstablishmentID {
+			return errors.New("tech card not found")
+		}
+		if barcode.Weighted {
+			return errors.New("tech cards can not have weighted barcodes")
+		}
+	}
+	if barcode.Weighted && barcode.Unit != "" {
+		if _, err := models.ConvertUnit(1, models.UnitGram, barcode.Unit); err != nil {
+			return fmt.Errorf("weighted barcode unit: %w", err)
+		}
+	}
+	if barcode.Unit == "" && !barcode.Weighted {
+		barcode.Unit = baseUnit
+	}
+
+	existing, err := uc.repo.GetByCode(ctx, barcode.EstablishmentID, barcode.Code, barcode.Weighted)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != barcode.ID {
+		return errors.New("barcode is already assigned to another item")
+	}
+	return nil
+}
+
+// ——— Lookup ———
+
+// Lookup находит позицию по отсканированному коду. Порядок поиска: штрихкоды позиций,
+// весовой EAN-13 (префикс 20–29, код товара — первые 7 цифр, вес в граммах — следующие 5),
+// затем поле Barcode в карточках ингредиентов и товаров.
+func (uc *BarcodeUseCase) Lookup(ctx context.Context, establishmentID uuid.UUID, code string) (*BarcodeMatch, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, errors.New("code is required")
+	}
+
+	barcode, err := uc.repo.GetByCode(ctx, establishmentID, code, false)
+	if err != nil {
+		return nil, err
+	}
+	if barcode != nil {
+		return barcodeMatch(code, barcode, barcode.Multiplier, barcode.Unit), nil
+	}
+
+	if itemCode, grams, ok := models.ParseWeightedEAN13(code); ok {
+		barcode, err := uc.repo.GetByCode(ctx, establishmentID, itemCode, true)
+		if err != nil {
+			return nil, err
+		}
+		if barcode != nil {
+			unit := barcode.Unit
+			if unit == "" {
+				unit = models.UnitKilogram
+			}
+			quantity, err := models.ConvertUnit(float64(grams), models.UnitGram, unit)
+			if err != nil {
+				return nil, err
+			}
+			return barcodeMatch(code, barcode, quantity, unit), nil
+		}
+	}
+
+	ingredient, err := uc.repo.GetIngredientByBarcode(ctx, establishmentID, code)
+	if err != nil {
+		return nil, err
+	}
+	if ingredient != nil {
+		id := ingredient.ID
+		return &BarcodeMatch{
+			Code:         code,
+			Type:         string(models.InventoryItemTypeIngredient),
+			IngredientID: &id,
+			Name:         ingredient.Name,
+			Quantity:     1,
+			Unit:         ingredient.Unit,
+		}, nil
+	}
+
+	product, err := uc.repo.GetProductByBarcode(ctx, establishmentID, code)
+	if err != nil {
+		return nil, err
+	}
+	if product != nil {
+		id := product.ID
+		return &BarcodeMatch{
+			Code:      code,
+			Type:      string(models.InventoryItemTypeProduct),
+			ProductID: &id,
+			Name:      product.Name,
+			Quantity:  1,
+			Unit:      models.UnitPiece,
+		}, nil
+	}
+
+	return nil, ErrBarcodeNotFound
+}
+
+func barcodeMatch(code string, barcode *models.ItemBarcode, quantity float64, unit string) *BarcodeMatch {
+	id := barcode.ID
+	match := &BarcodeMatch{
+		Code:         code,
+		BarcodeID:    &id,
+		IngredientID: barcode.IngredientID,
+		ProductID:    barcode.ProductID,
+		TechCardID:   barcode.TechCardID,
+		Quantity:     models.RoundTo3(quantity), // Вес из весового штрихкода — с точностью до грамма
+		Unit:         unit,
+		Weighted:     barcode.Weighted,
+	}
+	switch {
+	case barcode.IngredientID != nil:
+		match.Type = string(models.InventoryItemTypeIngredient)
+		if barcode.Ingredient != nil {
+			match.Name = barcode.Ingredient.Name
+		}
+	case barcode.ProductID != nil:
+		match.Type = string(models.InventoryItemTypeProduct)
+		if barcode.Product != nil {
+			match.Name = barcode.Product.Name
+		}
+	case barcode.TechCardID != nil:
+		match.Type = string(models.InventoryItemTypeTechCard)
+		if barcode.TechCard != nil {
+			match.Name = barcode.TechCard.Name
+		}
+	}
+	return match
+}
+
+// ——— Scanning ———
+
+// ScanSupply добавляет отсканированную позицию в поставку, которая еще не проведена.
+// Количество строки увеличивается на quantity сканирований; если строки с этой позицией
+// и единицей нет, она добавляется. pricePerUnit, если указан, задает цену строки.
+// complete проводит поставку после сканирования: остатки, журнал движений и партии приходуются
+// тем же путем, что и при переводе поставки в completed (WarehouseUseCase.UpdateSupply).
+func (uc *BarcodeUseCase) ScanSupply(ctx context.Context, supplyID, establishmentID uuid.UUID, code string, scans float64, pricePerUnit *float64, complete bool) (*models.Supply, *BarcodeMatch, error) {
+	supply, err := uc.warehouse.GetSupply(ctx, supplyID, establishmentID)
+	if err != nil || supply == nil {
+		return nil, nil, errors.New("supply not found")
+	}
+	if supply.Status != "pending" {
+		return nil, nil, errors.New("can only scan into pending supplies")
+	}
+	match, err := uc.Lookup(ctx, establishmentID, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if match.TechCardID != nil {
+		return nil, nil, errors.New("tech cards can not be received in supplies")
+	}
+	if scans <= 0 {
+		scans = 1
+	}
+	quantity := match.Quantity * scans
+
+	var line *models.SupplyItem
+	for i := range supply.Items {
+		it := &supply.Items[i]
+		if sameItemID(it.IngredientID, match.IngredientID) && sameItemID(it.ProductID, match.ProductID) &&
+			models.NormalizeUnit(it.Unit) == models.NormalizeUnit(match.Unit) {
+			line = it
+			break
+		}
+	}
+	if line == nil {
+		supply.Items = append(supply.Items, models.SupplyItem{
+			ID:           uuid.New(),
+			SupplyID:     supply.ID,
+			IngredientID: match.IngredientID,
+			ProductID:    match.ProductID,
+			Unit:         match.Unit,
+		})
+		line = &supply.Items[len(supply.Items)-1]
+	}
+	line.Quantity = models.RoundTo3(line.Quantity + quantity)
+	if pricePerUnit != nil {
+		line.PricePerUnit = *pricePerUnit
+	}
+	line.TotalAmount = models.RoundTo2(line.Quantity * line.PricePerUnit)
+
+	// Связанные записи не сохраняем вместе с поставкой
+	supply.Warehouse = nil
+	supply.Supplier = nil
+	supply.Account = nil
+	for i := range supply.Items {
+		supply.Items[i].Ingredient = nil
+		supply.Items[i].Product = nil
+	}
+	if complete {
+		supply.Status = "completed"
+	}
+	if err := uc.warehouse.UpdateSupply(ctx, supply, establishmentID); err != nil {
+		return nil, nil, err
+	}
+	return supply, match, nil
+}
+
+// ScanInventory прибавляет отсканированную позицию к подсчету инвентаризации
+func (uc *BarcodeUseCase) ScanInventory(ctx context.Context, inventoryID, establishmentID, counterID uuid.UUID, code string, scans float64, zone string) (*models.InventoryItem, *BarcodeMatch, error) {
+	match, err := uc.Lookup(ctx, establishmentID, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if scans <= 0 {
+		scans = 1
+	}
+	item, err := uc.inventory.AddScannedQuantity(ctx, inventoryID, establishmentID, counterID, InventoryScanInput{
+		Type:         models.InventoryItemType(match.Type),
+		IngredientID: match.IngredientID,
+		ProductID:    match.ProductID,
+		TechCardID:   match.TechCardID,
+		Quantity:     match.Quantity * scans,
+		Unit:         match.Unit,
+		Zone:         zone,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return item, match, nil
+}
+
+// ScanOrder добавляет в заказ товар или техкарту по штрихкоду. Количество позиции заказа
+// целое, поэтому весовые штрихкоды и дробные множители в заказе не поддерживаются.
+func (uc *BarcodeUseCase) ScanOrder(ctx context.Context, orderID, establishmentID uuid.UUID, code string, scans int, guestNumber *int) (*models.Order, *BarcodeMatch, error) {
+	if _, err := uc.orders.GetOrder(ctx, orderID, establishmentID); err != nil {
+		return nil, nil, err
+	}
+	match, err := uc.Lookup(ctx, establishmentID, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if match.IngredientID != nil {
+		return nil, nil, errors.New("ingredients can not be added to orders")
+	}
+	if match.Weighted || match.Quantity != math.Trunc(match.Quantity) {
+		return nil, nil, errors.New("weighted and fractional barcodes can not be added to orders")
+	}
+	if scans <= 0 {
+		scans = 1
+	}
+
+	order, err := uc.orders.AddOrderItem(ctx, orderID, models.OrderItem{
+		ProductID:   match.ProductID,
+		TechCardID:  match.TechCardID,
+		Quantity:    int(match.Quantity) * scans,
+		GuestNumber: guestNumber,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return order, match, nil
+}
@@ -0,0 +1,70 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// fakeBarcodeRepository находит штрихкоды из списка по коду и признаку весового
+type fakeBarcodeRepository struct {
+	repositories.BarcodeRepository
+	barcodes []*models.ItemBarcode
+}
+
+func (r *fakeBarcodeRepository) GetByCode(ctx context.Context, establishmentID uuid.UUID, code string, weighted bool) (*models.ItemBarcode, error) {
+	for _, b := range r.barcodes {
+		if b.Code == code && b.Weighted == weighted {
+			return b, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestBarcodeUseCase_ScanSupply(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeWarehouseRepository()
+	warehouseID := repo.addWarehouse()
+	cheeseID := repo.addIngredient(models.UnitKilogram)
+	warehouse := &WarehouseUseCase{repo: repo, supplierRepo: &fakeSupplierRepository{}, transactor: &fakeTransactor{}}
+	barcodes := &fakeBarcodeRepository{barcodes: []*models.ItemBarcode{
+		{ID: uuid.New(), Code: "2100001", IngredientID: &cheeseID, Unit: models.UnitKilogram, Weighted: true},
+	}}
+	uc := NewBarcodeUseCase(barcodes, repo, warehouse, nil, nil)
+
+	supply := &models.Supply{WarehouseID: warehouseID, SupplierID: uuid.New(), DeliveryDateTime: time.Now(), Status: "pending"}
+	require.NoError(t, warehouse.CreateSupply(ctx, supply, uuid.New()))
+
+	// Весовой EAN-13: 125 г сыра — вес не округляется до сотых
+	price := 800.0
+	scanned, match, err := uc.ScanSupply(ctx, supply.ID, uuid.New(), "2100001001254", 1, &price, false)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.125, match.Quantity, 1e-9)
+	require.Len(t, scanned.Items, 1)
+	assert.InDelta(t, 0.125, scanned.Items[0].Quantity, 1e-9)
+	// Непроведенная поставка не меняет остатки
+	assert.Nil(t, repo.stock(warehouseID, cheeseID))
+	assert.Empty(t, repo.ledger)
+
+	scanned, _, err = uc.ScanSupply(ctx, supply.ID, uuid.New(), "2100001001254", 2, nil, true)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", scanned.Status)
+	assert.InDelta(t, 0.375, scanned.Items[0].Quantity, 1e-9)
+	st := repo.stock(warehouseID, cheeseID)
+	require.NotNil(t, st)
+	assert.InDelta(t, 0.375, st.Quantity, 1e-9)
+	require.Len(t, repo.ledger, 1)
+	assert.Equal(t, models.StockLedgerSupply, repo.ledger[0].MovementType)
+	require.Len(t, repo.lotsOf(warehouseID, cheeseID), 1)
+
+	// В проведенную поставку сканировать нельзя
+	_, _, err = uc.ScanSupply(ctx, supply.ID, uuid.New(), "2100001001254", 1, nil, false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,251 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// MenuItemTypeCombo тип позиции меню для комбо и сет-меню
+const MenuItemTypeCombo = "combo"
+
+// ErrInvalidComboSelection выбор компонентов комбо в заказе не соответствует группам комбо
+var ErrInvalidComboSelection = errors.New("invalid combo selection")
+
+type ComboUseCase struct {
+	repo         repositories.ComboRepository
+	productRepo  repositories.ProductRepository
+	techCardRepo repositories.TechCardRepository
+	categoryRepo repositories.CategoryRepository
+}
+
+func NewComboUseCase(
+	repo repositories.ComboRepository,
+	productRepo repositories.ProductRepository,
+	techCardRepo repositories.TechCardRepository,
+	categoryRepo repositories.CategoryRepository,
+) *ComboUseCase {
+	return &ComboUseCase{
+		repo:         repo,
+		productRepo:  productRepo,
+		techCardRepo: techCardRepo,
+		categoryRepo: categoryRepo,
+	}
+}
+
+// ——— Комбо ———
+
+func (uc *ComboUseCase) List(ctx context.Context, filter *repositories.ComboFilter) ([]*models.Combo, error) {
+	return uc.repo.List(ctx, filter)
+}
+
+func (uc *ComboUseCase) Get(ctx context.Context, id, establishmentID uuid.UUID) (*models.Combo, error) {
+	combo, err := uc.repo.GetByID(ctx, id, &establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	if combo == nil {
+		return nil, errors.New("combo not found")
+	}
+	return combo, nil
+}
+
+func (uc *ComboUseCase) Create(ctx context.Context, combo *models.Combo, establishmentID uuid.UUID) error {
+	combo.EstablishmentID = establishmentID
+	if err := uc.validate(ctx, combo); err != nil {
+		return err
+	}
+	if err := uc.repo.Create(ctx, combo); err != nil {
+		return err
+	}
+	return uc.reload(ctx, combo)
+}
+
+func (uc *ComboUseCase) Update(ctx context.Context, combo *models.Combo, establishmentID uuid.UUID) error {
+	if _, err := uc.Get(ctx, combo.ID, establishmentID); err != nil {
+		return err
+	}
+	combo.EstablishmentID = establishmentID
+	if err := uc.validate(ctx, combo); err != nil {
+		return err
+	}
+	if err := uc.repo.Update(ctx, combo); err != nil {
+		return err
+	}
+	return uc.reload(ctx, combo)
+}
+
+func (uc *ComboUseCase) Delete(ctx context.Context, id, establishmentID uuid.UUID) error {
+	if _, err := uc.Get(ctx, id, establishmentID); err != nil {
+		return err
+	}
+	return uc.repo.Delete(ctx, id)
+}
+
+// reload перечитывает комбо с позициями групп (товары и тех-карты для ответа)
+func (uc *ComboUseCase) reload(ctx context.Context, combo *models.Combo) error {
+	saved, err := uc.Get(ctx, combo.ID, combo.EstablishmentID)
+	if err != nil {
+		return err
+	}
+	*combo = *saved
+	return nil
+}
+
+// validate проверяет комбо: категорию, группы и позиции групп заведения
+func (uc *ComboUseCase) validate(ctx context.Context, combo *models.Combo) error {
+	combo.Name = strings.TrimSpace(combo.Name)
+	if combo.Name == "" {
+		return errors.New("combo name is required")
+	}
+	if combo.Price < 0 {
+		return errors.New("combo price cannot be negative")
+	}
+	if _, err := uc.categoryRepo.GetByID(ctx, combo.CategoryID, &combo.EstablishmentID); err != nil {
+		return errors.New("category not found")
+	}
+	if len(combo.Groups) == 0 {
+		return errors.New("combo must have at least one component group")
+	}
+
+	for i := range combo.Groups {
+		group := &combo.Groups[i]
+		group.Name = strings.TrimSpace(group.Name)
+		if group.Name == "" {
+			return fmt.Errorf("group %d: name is required", i+1)
+		}
+		if len(group.Options) == 0 {
+			return fmt.Errorf("group %q: at least one option is required", group.Name)
+		}
+		defaults := 0
+		seen := make(map[uuid.UUID]bool, len(group.Options))
+		for j := range group.Options {
+			option := &group.Options[j]
+			if (option.ProductID == nil) == (option.TechCardID == nil) {
+				return fmt.Errorf("group %q: each option must have exactly one of product_id or tech_card_id", group.Name)
+			}
+			if option.Surcharge < 0 {
+				return fmt.Errorf("group %q: surcharge cannot be negative", group.Name)
+			}
+			if option.IsDefault {
+				defaults++
+			}
+
+			var itemID uuid.UUID
+			if option.ProductID != nil {
+				itemID = *option.ProductID
+				product, err := uc.productRepo.GetByID(ctx, itemID, &combo.EstablishmentID)
+				if err != nil {
+					return fmt.Errorf("group %q: product %s not found", group.Name, itemID)
+				}
+				byVariants, err := soldByVariants(product, func() (int64, error) {
+					return uc.productRepo.CountVariants(ctx, product.ID)
+				})
+				if err != nil {
+					return err
+				}
+				if byVariants {
+					return fmt.Errorf("group %q: %w: %s", group.Name, ErrProductHasVariants, product.Name)
+				}
+			} else {
+				itemID = *option.TechCardID
+				if _, err := uc.techCardRepo.GetByID(ctx, itemID, &combo.EstablishmentID); err != nil {
+					return fmt.Errorf("group %q: tech card %s not found", group.Name, itemID)
+				}
+			}
+			if seen[itemID] {
+				return fmt.Errorf("group %q: duplicate option %s", group.Name, itemID)
+			}
+			seen[itemID] = true
+		}
+		if defaults > 1 {
+			return fmt.Errorf("group %q: only one default option is allowed", group.Name)
+		}
+	}
+	return nil
+}
+
+// ——— Продажа ———
+
+// PriceOrderItem проверяет выбор компонентов позиции заказа с комбо и проставляет цену: базовая цена комбо плюс доплаты.
+// В item.Components ожидается выбор по группам (group_id и product_id или tech_card_id); для обязательной группы
+// без выбора берется позиция по умолчанию. Цена комбо распределяется по компонентам для статистики категорий.
+func (uc *ComboUseCase) PriceOrderItem(ctx context.Context, item *models.OrderItem) (MenuItemRef, error) {
+	var ref MenuItemRef
+	if uc == nil {
+		return ref, errors.New("combos are not supported")
+	}
+	combo, err := uc.repo.GetByID(ctx, *item.ComboID, nil)
+	if err != nil {
+		return ref, fmt.Errorf("combo not found: %w", err)
+	}
+	if combo == nil {
+		return ref, errors.New("combo not found")
+	}
+	if !combo.Active {
+		return ref, fmt.Errorf("%w: combo %q is not active", ErrInvalidComboSelection, combo.Name)
+	}
+
+	selections := make(map[uuid.UUID]models.OrderItemComponent, len(item.Components))
+	for _, sel := range item.Components {
+		if _, dup := selections[sel.GroupID]; dup {
+			return ref, fmt.Errorf("%w: combo %q: only one choice per group is allowed", ErrInvalidComboSelection, combo.Name)
+		}
+		selections[sel.GroupID] = sel
+	}
+
+	components := make([]models.OrderItemComponent, 0, len(combo.Groups))
+	surcharges := 0.0
+	for i := range combo.Groups {
+		group := &combo.Groups[i]
+		var option *models.ComboOption
+		if sel, ok := selections[group.ID]; ok {
+			delete(selections, group.ID)
+			option = group.FindOption(sel.ProductID, sel.TechCardID)
+			if option == nil {
+				return ref, fmt.Errorf("%w: combo %q: selected item is not an option of group %q", ErrInvalidComboSelection, combo.Name, group.Name)
+			}
+		} else if group.Required {
+			option = group.DefaultOption()
+			if option == nil {
+				return ref, fmt.Errorf("%w: combo %q: group %q requires a choice", ErrInvalidComboSelection, combo.Name, group.Name)
+			}
+		}
+		if option == nil {
+			continue
+		}
+
+		component := models.OrderItemComponent{
+			GroupID:    group.ID,
+			GroupName:  group.Name,
+			ProductID:  option.ProductID,
+			TechCardID: option.TechCardID,
+			Surcharge:  option.Surcharge,
+		}
+		switch {
+		case option.Product != nil:
+			component.ListPrice = option.Product.Price
+		case option.TechCard != nil:
+			component.ListPrice = option.TechCard.Price
+		}
+		components = append(components, component)
+		surcharges += option.Surcharge
+	}
+	if len(selections) > 0 {
+		return ref, fmt.Errorf("%w: combo %q: unknown component group", ErrInvalidComboSelection, combo.Name)
+	}
+
+	models.AllocateComboPrice(combo.Price, components)
+	item.Components = components
+	item.Price = models.RoundTo2(combo.Price + surcharges)
+	item.TotalPrice = item.Price * float64(item.Quantity)
+
+	ref = MenuItemRef{Type: MenuItemTypeCombo, ID: combo.ID, Name: combo.Name, CategoryID: combo.CategoryID, ScheduleID: combo.AvailabilityScheduleID}
+	return ref, nil
+}
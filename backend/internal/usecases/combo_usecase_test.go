@@ -0,0 +1,137 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+func TestOrderUseCase_ComboOrder(t *testing.T) {
+	ctx := context.Background()
+	establishmentID := uuid.New()
+	hot := &models.Category{ID: uuid.New(), Name: "Горячее"}
+	drinks := &models.Category{ID: uuid.New(), Name: "Напитки"}
+	lunches := &models.Category{ID: uuid.New(), Name: "Комбо"}
+
+	warehouse := newFakeWarehouseRepository()
+	warehouseID := warehouse.addWarehouse()
+	beetID := warehouse.addIngredient(models.UnitKilogram)
+	meatID := warehouse.addIngredient(models.UnitKilogram)
+	beetStock := warehouse.addStock(warehouseID, beetID, 1, 100)
+	meatStock := warehouse.addStock(warehouseID, meatID, 0.5, 600)
+
+	borscht := &models.TechCard{ID: uuid.New(), Name: "Борщ", CategoryID: hot.ID, Price: 250, Ingredients: []models.TechCardIngredient{
+		{IngredientID: &beetID, Ingredient: warehouse.ingredients[beetID], Quantity: 200, Unit: models.UnitGram},
+	}}
+	solyanka := &models.TechCard{ID: uuid.New(), Name: "Солянка", CategoryID: hot.ID, Price: 300, Ingredients: []models.TechCardIngredient{
+		{IngredientID: &meatID, Ingredient: warehouse.ingredients[meatID], Quantity: 150, Unit: models.UnitGram},
+	}}
+	cheesecake := &models.TechCard{ID: uuid.New(), Name: "Чизкейк", Price: 200}
+	cola := &models.Product{ID: uuid.New(), Name: "Кола", CategoryID: drinks.ID, Price: 100}
+	colaStock := &models.Stock{ID: uuid.New(), WarehouseID: warehouseID, ProductID: &cola.ID, Quantity: 10, Unit: models.UnitPiece, PricePerUnit: 40}
+	warehouse.stocks[colaStock.ID] = colaStock
+
+	soup := models.ComboGroup{ID: uuid.New(), Name: "Суп", Required: true, Options: []models.ComboOption{
+		{TechCardID: &borscht.ID, TechCard: borscht, IsDefault: true},
+		{TechCardID: &solyanka.ID, TechCard: solyanka, Surcharge: 50},
+	}}
+	drink := models.ComboGroup{ID: uuid.New(), Name: "Напиток", Required: true, Options: []models.ComboOption{
+		{ProductID: &cola.ID, Product: cola, IsDefault: true},
+	}}
+	dessert := models.ComboGroup{ID: uuid.New(), Name: "Десерт", Options: []models.ComboOption{
+		{TechCardID: &cheesecake.ID, TechCard: cheesecake},
+	}}
+	lunch := &models.Combo{ID: uuid.New(), EstablishmentID: establishmentID, CategoryID: lunches.ID, Name: "Бизнес-ланч", Price: 400, Active: true,
+		Groups: []models.ComboGroup{soup, drink, dessert}}
+
+	repo := &stockAvailabilityRepository{
+		fakeWarehouseRepository: warehouse,
+		techCards:               map[uuid.UUID]*models.TechCard{borscht.ID: borscht, solyanka.ID: solyanka, cheesecake.ID: cheesecake},
+		products:                []*models.Product{cola},
+	}
+	establishments := &fakeEstablishmentRepository{policy: models.NegativeStockPolicyBlock}
+	orders := &fakeOrderRepository{orders: make(map[uuid.UUID]*models.Order)}
+	combos := NewComboUseCase(&fakeComboRepository{combos: []*models.Combo{lunch}}, nil, nil, nil)
+	uc := NewOrderUseCase(orders, repo, nil, nil, nil, establishments, nil, combos)
+
+	comboItem := func(quantity int, components ...models.OrderItemComponent) []models.OrderItem {
+		return []models.OrderItem{{ComboID: &lunch.ID, Quantity: quantity, Components: components}}
+	}
+
+	t.Run("invalid selection", func(t *testing.T) {
+		// Кола не входит в группу супов
+		_, err := uc.CreateOrder(ctx, establishmentID, nil, comboItem(1, models.OrderItemComponent{GroupID: soup.ID, ProductID: &cola.ID}))
+		assert.True(t, errors.Is(err, ErrInvalidComboSelection))
+		_, err = uc.CreateOrder(ctx, establishmentID, nil, comboItem(1, models.OrderItemComponent{GroupID: uuid.New(), TechCardID: &borscht.ID}))
+		assert.True(t, errors.Is(err, ErrInvalidComboSelection))
+		_, err = uc.CreateOrder(ctx, establishmentID, nil, []models.OrderItem{{ComboID: &lunch.ID, ProductID: &cola.ID, Quantity: 1}})
+		assert.Error(t, err)
+		assert.Empty(t, orders.orders)
+	})
+
+	t.Run("components are checked against stock", func(t *testing.T) {
+		// Четыре солянки — 600 г мяса при остатке 500 г
+		_, err := uc.CreateOrder(ctx, establishmentID, nil, comboItem(4, models.OrderItemComponent{GroupID: soup.ID, TechCardID: &solyanka.ID}))
+		assert.True(t, errors.Is(err, ErrInsufficientStock))
+		assert.Empty(t, orders.orders)
+	})
+
+	// Солянка с доплатой, напиток по умолчанию, десерт пропущен
+	order, err := uc.CreateOrder(ctx, establishmentID, nil, comboItem(2, models.OrderItemComponent{GroupID: soup.ID, TechCardID: &solyanka.ID}))
+	require.NoError(t, err)
+	require.Len(t, order.Items, 1)
+	item := order.Items[0]
+	assert.InDelta(t, 450, item.Price, 1e-9)
+	assert.InDelta(t, 900, item.TotalPrice, 1e-9)
+	assert.InDelta(t, 900, order.TotalAmount, 1e-9)
+
+	// Базовая цена делится пропорционально ценам меню (300 : 100), доплата достается выбранному компоненту
+	require.Len(t, item.Components, 2)
+	soupChoice, drinkChoice := item.Components[0], item.Components[1]
+	assert.Equal(t, solyanka.ID, *soupChoice.TechCardID)
+	assert.Equal(t, "Суп", soupChoice.GroupName)
+	assert.InDelta(t, 300, soupChoice.ListPrice, 1e-9)
+	assert.InDelta(t, 300+50, soupChoice.AllocatedPrice, 1e-9)
+	assert.Equal(t, cola.ID, *drinkChoice.ProductID)
+	assert.InDelta(t, 100, drinkChoice.AllocatedPrice, 1e-9)
+
+	// Оплата списывает каждый компонент по его рецептуре; сам комбо склад не расходует
+	_, err = uc.ProcessOrderPayment(ctx, order.ID, 0, 900, 0)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.2, meatStock.Quantity, 1e-9)
+	assert.InDelta(t, 1, beetStock.Quantity, 1e-9)
+	assert.InDelta(t, 8, colaStock.Quantity, 1e-9)
+	require.Len(t, warehouse.ledger, 2)
+	for _, entry := range warehouse.ledger {
+		assert.Equal(t, models.StockLedgerSale, entry.MovementType)
+		assert.Equal(t, order.ID, *entry.SourceID)
+	}
+
+	// Выручка комбо в статистике категорий делится по категориям компонентов
+	paid := orders.orders[order.ID]
+	paid.CreatedAt = time.Now()
+	paid.Items[0].Components[0].TechCard = solyanka
+	paid.Items[0].Components[1].Product = cola
+	stats := NewStatisticsUseCase(
+		&fakeStatisticsOrderRepository{orders: []*models.Order{paid}},
+		&fakeProductRepository{items: []*models.Product{cola}},
+		&fakeCategoryRepository{items: []*models.Category{hot, drinks, lunches}},
+		nil, nil, nil, nil, nil, nil, nil,
+	)
+	categoryStats, err := stats.GetCategoryStatistics(ctx, establishmentID, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	revenue := make(map[string]float64)
+	for _, data := range categoryStats.CategoryData {
+		revenue[data.CategoryName] = data.Revenue
+	}
+	assert.Equal(t, map[string]float64{"Горячее": 700, "Напитки": 200, "Комбо": 0}, revenue)
+	assert.InDelta(t, 900, categoryStats.CategoryRevenue, 1e-9)
+	assert.Equal(t, "Горячее", categoryStats.TopCategory)
+}
@@ -0,0 +1,627 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// Типы позиций меню в истории себестоимости и отчетах
+const (
+	MenuItemTypeTechCard = "tech_card"
+	MenuItemTypeProduct  = "product"
+)
+
+// costRecalculation запрос фонового пересчета себестоимости после поставки
+type costRecalculation struct {
+	establishmentID uuid.UUID
+	supplyID        uuid.UUID
+}
+
+// CostHistoryUseCase ведет историю себестоимости тех-карт и товаров, пересчитывает ее после поставок
+// и уведомляет о превышении порога фудкоста
+type CostHistoryUseCase struct {
+	repo              repositories.CostHistoryRepository
+	menu              *MenuUseCase
+	warehouseRepo     repositories.WarehouseRepository
+	establishmentRepo repositories.EstablishmentRepository
+	repricing         *RepricingUseCase // Назначается после создания: RepricingUseCase сам зависит от CostHistoryUseCase
+	logger            *zap.Logger
+	trigger           chan costRecalculation
+}
+
+func NewCostHistoryUseCase(
+	repo repositories.CostHistoryRepository,
+	menu *MenuUseCase,
+	warehouseRepo repositories.WarehouseRepository,
+	establishmentRepo repositories.EstablishmentRepository,
+	logger *zap.Logger,
+) *CostHistoryUseCase {
+	return &CostHistoryUseCase{
+		repo:              repo,
+		menu:              menu,
+		warehouseRepo:     warehouseRepo,
+		establishmentRepo: establishmentRepo,
+		logger:            logger,
+		trigger:           make(chan costRecalculation, 100),
+	}
+}
+
+// ——— Recalculation ———
+
+// NotifySupply сообщает фоновому обработчику, что поставка изменила закупочные цены.
+// Вызов не блокирует: если очередь переполнена, себестоимость обновится при ручном пересчете.
+func (uc *CostHistoryUseCase) NotifySupply(establishmentID, supplyID uuid.UUID) {
+	if uc == nil {
+		return
+	}
+	select {
+	case uc.trigger <- costRecalculation{establishmentID: establishmentID, supplyID: supplyID}:
+	default:
+	}
+}
+
+// Run обрабатывает запросы пересчета себестоимости после поставок
+func (uc *CostHistoryUseCase) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case req := <-uc.trigger:
+			if err := uc.RecalculateForSupply(ctx, req.establishmentID, req.supplyID); err != nil && uc.logger != nil {
+				uc.logger.Error("Failed to recalculate menu costs after supply",
+					zap.String("supply_id", req.supplyID.String()), zap.Error(err))
+			}
+		}
+	}
+}
+
+// RecalculateForSupply пересчитывает себестоимость полуфабрикатов, тех-карт и товаров, в которые входят позиции поставки.
+// Цена продажи не меняется: в историю пишется новая себестоимость, фудкост сравнивается с порогом заведения.
+func (uc *CostHistoryUseCase) RecalculateForSupply(ctx context.Context, establishmentID, supplyID uuid.UUID) error {
+	supply, err := uc.warehouseRepo.GetSupplyByID(ctx, supplyID, &establishmentID)
+	if err != nil {
+		return err
+	}
+	if supply == nil {
+		return errors.New("supply not found or access denied")
+	}
+
+	ingredients := make(map[uuid.UUID]bool)
+	var productIDs []uuid.UUID
+	for _, it := range supply.Items {
+		if it.PricePerUnit <= 0 {
+			continue
+		}
+		if it.IngredientID != nil {
+			ingredients[*it.IngredientID] = true
+		}
+		if it.ProductID != nil {
+			productIDs = append(productIDs, *it.ProductID)
+		}
+	}
+	if len(ingredients) == 0 && len(productIDs) == 0 {
+		return nil
+	}
+
+	threshold, err := uc.threshold(ctx, establishmentID)
+	if err != nil {
+		return err
+	}
+	source := &supply.ID
+
+	// Полуфабрикаты без собственного остатка оцениваются по сохраненной себестоимости — обновляем ее первой
+	semiFinished := make(map[uuid.UUID]bool)
+	if len(ingredients) > 0 {
+		list, err := uc.menu.GetSemiFinishedProducts(ctx, &repositories.SemiFinishedFilter{EstablishmentID: &establishmentID})
+		if err != nil {
+			return err
+		}
+		for _, sf := range list {
+			uses := false
+			for _, line := range sf.Ingredients {
+				if ingredients[line.IngredientID] {
+					uses = true
+					break
+				}
+			}
+			if !uses {
+				continue
+			}
+			semiFinished[sf.ID] = true
+			cost, err := uc.menu.CalculateSemiFinishedCost(ctx, sf, supply.WarehouseID, establishmentID)
+			if err != nil {
+				return fmt.Errorf("semi-finished product %q: %w", sf.Name, err)
+			}
+			if models.RoundTo2(cost) != models.RoundTo2(sf.CostPrice) {
+				if err := uc.repo.UpdateSemiFinishedCost(ctx, sf.ID, cost); err != nil {
+					return err
+				}
+			}
+		}
+
+		techCards, err := uc.warehouseRepo.GetActiveTechCards(ctx, establishmentID)
+		if err != nil {
+			return err
+		}
+		for _, tc := range techCards {
+			if !techCardUses(tc, ingredients, semiFinished) {
+				continue
+			}
+			if err := uc.recalculateTechCard(ctx, tc, supply.WarehouseID, threshold, models.CostChangeReasonSupply, source); err != nil {
+				return fmt.Errorf("tech card %q: %w", tc.Name, err)
+			}
+		}
+	}
+
+	for _, productID := range productIDs {
+		product, err := uc.menu.GetProductByID(ctx, productID, establishmentID)
+		if err != nil || product == nil {
+			continue
+		}
+		if err := uc.recalculateProduct(ctx, product, supply.WarehouseID, threshold, models.CostChangeReasonSupply, source); err != nil {
+			return fmt.Errorf("product %q: %w", product.Name, err)
+		}
+	}
+	// Себестоимость изменилась — пересобираем предложения по переоценке
+	uc.repricing.Notify(establishmentID)
+	return nil
+}
+
+// RecalculateAll пересчитывает себестоимость всего активного меню по текущим ценам склада
+// и возвращает позиции, у которых себестоимость или цена изменились
+func (uc *CostHistoryUseCase) RecalculateAll(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID) ([]*models.MenuItemCost, error) {
+	whID, err := uc.costWarehouse(ctx, establishmentID, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	threshold, err := uc.threshold(ctx, establishmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := time.Now()
+	techCards, err := uc.warehouseRepo.GetActiveTechCards(ctx, establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, tc := range techCards {
+		if err := uc.recalculateTechCard(ctx, tc, whID, threshold, models.CostChangeReasonRecalculation, nil); err != nil {
+			return nil, fmt.Errorf("tech card %q: %w", tc.Name, err)
+		}
+	}
+	products, err := uc.warehouseRepo.GetActiveProducts(ctx, establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range products {
+		productWarehouse := whID
+		if warehouseID == nil && p.WarehouseID != nil {
+			productWarehouse = *p.WarehouseID
+		}
+		if err := uc.recalculateProduct(ctx, p, productWarehouse, threshold, models.CostChangeReasonRecalculation, nil); err != nil {
+			return nil, fmt.Errorf("product %q: %w", p.Name, err)
+		}
+	}
+
+	uc.repricing.Notify(establishmentID)
+	return uc.repo.ListEntries(ctx, &repositories.CostHistoryFilter{EstablishmentID: &establishmentID, StartDate: &before})
+}
+
+// TrackTechCard записывает себестоимость и цену тех-карты после изменения и проверяет порог фудкоста.
+// Ошибки только логируются: история не должна мешать сохранению тех-карты.
+func (uc *CostHistoryUseCase) TrackTechCard(ctx context.Context, tc *models.TechCard, reason string) {
+	if uc == nil || tc == nil {
+		return
+	}
+	uc.track(ctx, tc.EstablishmentID, &tc.ID, nil, tc.CostPrice, tc.Price, reason)
+}
+
+// TrackProduct записывает себестоимость и цену товара после изменения и проверяет порог фудкоста
+func (uc *CostHistoryUseCase) TrackProduct(ctx context.Context, p *models.Product, reason string) {
+	if uc == nil || p == nil {
+		return
+	}
+	uc.track(ctx, p.EstablishmentID, nil, &p.ID, p.CostPrice, p.Price, reason)
+}
+
+func (uc *CostHistoryUseCase) track(ctx context.Context, establishmentID uuid.UUID, techCardID, productID *uuid.UUID, costPrice, price float64, reason string) {
+	err := func() error {
+		threshold, err := uc.threshold(ctx, establishmentID)
+		if err != nil {
+			return err
+		}
+		if _, err := uc.record(ctx, establishmentID, techCardID, productID, costPrice, price, reason, nil); err != nil {
+			return err
+		}
+		return uc.evaluateAlert(ctx, establishmentID, techCardID, productID, costPrice, price, threshold)
+	}()
+	if err != nil && uc.logger != nil {
+		uc.logger.Error("Failed to record menu item cost", zap.Error(err))
+	}
+}
+
+func (uc *CostHistoryUseCase) recalculateTechCard(ctx context.Context, tc *models.TechCard, warehouseID uuid.UUID, threshold float64, reason string, source *uuid.UUID) error {
+	costPrice := tc.CostPrice
+	if len(tc.Ingredients) > 0 {
+		cost, err := uc.menu.CalculateTechCardCost(ctx, tc, warehouseID, tc.EstablishmentID)
+		if err != nil {
+			return err
+		}
+		costPrice = models.RoundTo2(cost)
+		if costPrice != models.RoundTo2(tc.CostPrice) {
+			if err := uc.repo.UpdateTechCardCost(ctx, tc.ID, costPrice); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := uc.record(ctx, tc.EstablishmentID, &tc.ID, nil, costPrice, tc.Price, reason, source); err != nil {
+		return err
+	}
+	return uc.evaluateAlert(ctx, tc.EstablishmentID, &tc.ID, nil, costPrice, tc.Price, threshold)
+}
+
+// recalculateProduct берет себестоимость товара из закупочной цены остатка на складе
+func (uc *CostHistoryUseCase) recalculateProduct(ctx context.Context, p *models.Product, warehouseID uuid.UUID, threshold float64, reason string, source *uuid.UUID) error {
+	costPrice := p.CostPrice
+	if stock, err := uc.warehouseRepo.GetStockByProductAndWarehouse(ctx, p.ID, warehouseID); err == nil && stock != nil && stock.PricePerUnit > 0 {
+		costPrice = models.RoundTo2(stock.PricePerUnit)
+		if costPrice != models.RoundTo2(p.CostPrice) {
+			if err := uc.repo.UpdateProductCost(ctx, p.ID, costPrice); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := uc.record(ctx, p.EstablishmentID, nil, &p.ID, costPrice, p.Price, reason, source); err != nil {
+		return err
+	}
+	return uc.evaluateAlert(ctx, p.EstablishmentID, nil, &p.ID, costPrice, p.Price, threshold)
+}
+
+// record добавляет запись в историю, если себестоимость или цена изменились с последней записи
+func (uc *CostHistoryUseCase) record(ctx context.Context, establishmentID uuid.UUID, techCardID, productID *uuid.UUID, costPrice, price float64, reason string, source *uuid.UUID) (*models.MenuItemCost, error) {
+	last, err := uc.repo.GetLatestEntry(ctx, techCardID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if last != nil && last.CostPrice == models.RoundTo2(costPrice) && last.Price == models.RoundTo2(price) {
+		return nil, nil
+	}
+	entry := &models.MenuItemCost{
+		EstablishmentID: establishmentID,
+		TechCardID:      techCardID,
+		ProductID:       productID,
+		CostPrice:       costPrice,
+		Price:           price,
+		Reason:          reason,
+		SourceID:        source,
+		RecordedAt:      time.Now(),
+	}
+	if err := uc.repo.CreateEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// techCardUses проверяет, входит ли в тех-карту один из ингредиентов или полуфабрикатов
+func techCardUses(tc *models.TechCard, ingredients, semiFinished map[uuid.UUID]bool) bool {
+	for _, line := range tc.Ingredients {
+		if line.IngredientID != nil && ingredients[*line.IngredientID] {
+			return true
+		}
+		if line.SemiFinishedID != nil && semiFinished[*line.SemiFinishedID] {
+			return true
+		}
+	}
+	return false
+}
+
+// costWarehouse возвращает склад, по ценам которого считается себестоимость (по умолчанию — первый активный)
+func (uc *CostHistoryUseCase) costWarehouse(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID) (uuid.UUID, error) {
+	if warehouseID != nil {
+		w, err := uc.warehouseRepo.GetWarehouseByID(ctx, *warehouseID, &establishmentID)
+		if err != nil || w == nil {
+			return uuid.Nil, errors.New("warehouse not found or access denied")
+		}
+		return w.ID, nil
+	}
+	warehouses, err := uc.warehouseRepo.ListWarehouses(ctx, establishmentID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	for _, w := range warehouses {
+		if w.Active {
+			return w.ID, nil
+		}
+	}
+	return uuid.Nil, errors.New("no active warehouse")
+}
+
+// ——— Alerts ———
+
+// GetFoodCostThreshold возвращает порог фудкоста заведения, %
+func (uc *CostHistoryUseCase) GetFoodCostThreshold(ctx context.Context, establishmentID uuid.UUID) (float64, error) {
+	return uc.threshold(ctx, establishmentID)
+}
+
+// SetFoodCostThreshold меняет порог фудкоста и сразу пересматривает активные уведомления
+func (uc *CostHistoryUseCase) SetFoodCostThreshold(ctx context.Context, establishmentID uuid.UUID, threshold float64) error {
+	if threshold < 0 || threshold > 100 {
+		return errors.New("food cost threshold must be between 0 and 100")
+	}
+	if err := uc.establishmentRepo.UpdateFoodCostThreshold(ctx, establishmentID, threshold); err != nil {
+		return err
+	}
+	return uc.EvaluateFoodCostAlerts(ctx, establishmentID)
+}
+
+// EvaluateFoodCostAlerts сравнивает последнюю известную себестоимость позиций с порогом заведения
+func (uc *CostHistoryUseCase) EvaluateFoodCostAlerts(ctx context.Context, establishmentID uuid.UUID) error {
+	threshold, err := uc.threshold(ctx, establishmentID)
+	if err != nil {
+		return err
+	}
+	entries, err := uc.repo.GetLatestEntries(ctx, establishmentID, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := uc.evaluateAlert(ctx, establishmentID, e.TechCardID, e.ProductID, e.CostPrice, e.Price, threshold); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (uc *CostHistoryUseCase) threshold(ctx context.Context, establishmentID uuid.UUID) (float64, error) {
+	threshold, err := uc.establishmentRepo.GetFoodCostThreshold(ctx, establishmentID)
+	if err != nil {
+		return 0, err
+	}
+	if threshold < 0 {
+		return 0, nil
+	}
+	return threshold, nil
+}
+
+// evaluateAlert создает уведомление, если фудкост позиции выше порога, и закрывает активное, если снова ниже.
+// Нулевой порог отключает уведомления: активные закрываются.
+func (uc *CostHistoryUseCase) evaluateAlert(ctx context.Context, establishmentID uuid.UUID, techCardID, productID *uuid.UUID, costPrice, price, threshold float64) error {
+	active, err := uc.repo.GetActiveAlert(ctx, techCardID, productID)
+	if err != nil {
+		return err
+	}
+
+	percent := models.FoodCostPercent(costPrice, price)
+	above := threshold > 0 && price > 0 && percent > threshold
+	switch {
+	case above && active == nil:
+		return uc.repo.CreateAlert(ctx, &models.FoodCostAlert{
+			EstablishmentID: establishmentID,
+			TechCardID:      techCardID,
+			ProductID:       productID,
+			CostPrice:       costPrice,
+			Price:           price,
+			FoodCostPercent: percent,
+			Threshold:       threshold,
+			Status:          models.StockAlertStatusOpen,
+		})
+	case above && active != nil:
+		// Обновляем показатели в активном уведомлении
+		if active.FoodCostPercent != percent || active.Threshold != models.RoundTo2(threshold) {
+			active.CostPrice = models.RoundTo2(costPrice)
+			active.Price = models.RoundTo2(price)
+			active.FoodCostPercent = percent
+			active.Threshold = models.RoundTo2(threshold)
+			return uc.repo.UpdateAlert(ctx, active)
+		}
+	case !above && active != nil:
+		now := time.Now()
+		active.CostPrice = models.RoundTo2(costPrice)
+		active.Price = models.RoundTo2(price)
+		active.FoodCostPercent = percent
+		active.Status = models.StockAlertStatusResolved
+		active.ResolvedAt = &now
+		return uc.repo.UpdateAlert(ctx, active)
+	}
+	return nil
+}
+
+// ListAlerts возвращает уведомления о превышении фудкоста
+func (uc *CostHistoryUseCase) ListAlerts(ctx context.Context, establishmentID uuid.UUID, filter *repositories.FoodCostAlertFilter) ([]*models.FoodCostAlert, error) {
+	if filter == nil {
+		filter = &repositories.FoodCostAlertFilter{}
+	}
+	filter.EstablishmentID = &establishmentID
+	return uc.repo.ListAlerts(ctx, filter)
+}
+
+// AcknowledgeAlert отмечает уведомление о фудкосте как просмотренное
+func (uc *CostHistoryUseCase) AcknowledgeAlert(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID, userID *uuid.UUID) (*models.FoodCostAlert, error) {
+	alert, err := uc.repo.GetAlertByID(ctx, id, &establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	if alert == nil {
+		return nil, errors.New("food cost alert not found or access denied")
+	}
+	if alert.Status == models.StockAlertStatusResolved {
+		return nil, errors.New("food cost alert is already resolved")
+	}
+	if alert.Status == models.StockAlertStatusAcknowledged {
+		return alert, nil
+	}
+
+	now := time.Now()
+	alert.Status = models.StockAlertStatusAcknowledged
+	alert.AcknowledgedAt = &now
+	alert.AcknowledgedBy = userID
+	if err := uc.repo.UpdateAlert(ctx, alert); err != nil {
+		return nil, err
+	}
+	return alert, nil
+}
+
+// ——— History and reports ———
+
+// GetCostHistory возвращает историю себестоимости тех-карты или товара за период (новые записи первыми)
+func (uc *CostHistoryUseCase) GetCostHistory(ctx context.Context, establishmentID uuid.UUID, techCardID, productID *uuid.UUID, start, end *time.Time) ([]*models.MenuItemCost, error) {
+	if techCardID == nil && productID == nil {
+		return nil, errors.New("tech card or product is required")
+	}
+	return uc.repo.ListEntries(ctx, &repositories.CostHistoryFilter{
+		EstablishmentID: &establishmentID,
+		TechCardID:      techCardID,
+		ProductID:       productID,
+		StartDate:       start,
+		EndDate:         end,
+	})
+}
+
+// MarginDropRow изменение маржи позиции за период
+type MarginDropRow struct {
+	ItemType             string     `json:"item_type"` // tech_card или product
+	TechCardID           *uuid.UUID `json:"tech_card_id,omitempty"`
+	ProductID            *uuid.UUID `json:"product_id,omitempty"`
+	Name                 string     `json:"name"`
+	StartCostPrice       float64    `json:"start_cost_price"`
+	StartPrice           float64    `json:"start_price"`
+	StartMargin          float64    `json:"start_margin"`
+	StartFoodCostPercent float64    `json:"start_food_cost_percent"`
+	EndCostPrice         float64    `json:"end_cost_price"`
+	EndPrice             float64    `json:"end_price"`
+	EndMargin            float64    `json:"end_margin"`
+	EndFoodCostPercent   float64    `json:"end_food_cost_percent"`
+	MarginChange         float64    `json:"margin_change"`                   // Отрицательное значение — маржа упала
+	MarginChangePercent  *float64   `json:"margin_change_percent,omitempty"` // Относительно маржи на начало периода
+	FoodCostChange       float64    `json:"food_cost_change"`                // Изменение фудкоста, п.п.
+	ChangedAt            time.Time  `json:"changed_at"`                      // Последнее изменение в периоде
+}
+
+// GetMarginDropReport возвращает позиции, маржа которых за период снизилась, — по убыванию падения.
+// Маржа на начало периода берется из последней записи до start, а для новых позиций — из первой записи периода.
+func (uc *CostHistoryUseCase) GetMarginDropReport(ctx context.Context, establishmentID uuid.UUID, start, end time.Time, limit int) ([]*MarginDropRow, error) {
+	opening, err := uc.repo.GetLatestEntries(ctx, establishmentID, start)
+	if err != nil {
+		return nil, err
+	}
+	// Записи периода отсортированы от новых к старым
+	period, err := uc.repo.ListEntries(ctx, &repositories.CostHistoryFilter{EstablishmentID: &establishmentID, StartDate: &start, EndDate: &end})
+	if err != nil {
+		return nil, err
+	}
+	if len(period) == 0 {
+		return []*MarginDropRow{}, nil
+	}
+
+	key := func(e *models.MenuItemCost) uuid.UUID {
+		if e.TechCardID != nil {
+			return *e.TechCardID
+		}
+		if e.ProductID != nil {
+			return *e.ProductID
+		}
+		return uuid.Nil
+	}
+	first := make(map[uuid.UUID]*models.MenuItemCost)
+	hasOpening := make(map[uuid.UUID]bool)
+	for _, e := range opening {
+		first[key(e)] = e
+		hasOpening[key(e)] = true
+	}
+	last := make(map[uuid.UUID]*models.MenuItemCost)
+	for _, e := range period {
+		k := key(e)
+		if _, ok := last[k]; !ok {
+			last[k] = e
+		}
+		// Без записи до начала периода базой служит самая ранняя запись периода
+		if !hasOpening[k] {
+			first[k] = e
+		}
+	}
+
+	names := make(map[uuid.UUID]string)
+	for _, e := range opening {
+		if e.TechCard != nil {
+			names[key(e)] = e.TechCard.Name
+		} else if e.Product != nil {
+			names[key(e)] = e.Product.Name
+		}
+	}
+
+	rows := make([]*MarginDropRow, 0)
+	for k, to := range last {
+		from := first[k]
+		if from == nil || from.ID == to.ID {
+			continue
+		}
+		change := models.RoundTo2(to.Margin - from.Margin)
+		if change >= 0 {
+			continue
+		}
+		row := &MarginDropRow{
+			TechCardID:           to.TechCardID,
+			ProductID:            to.ProductID,
+			Name:                 names[k],
+			StartCostPrice:       from.CostPrice,
+			StartPrice:           from.Price,
+			StartMargin:          from.Margin,
+			StartFoodCostPercent: from.FoodCostPercent,
+			EndCostPrice:         to.CostPrice,
+			EndPrice:             to.Price,
+			EndMargin:            to.Margin,
+			EndFoodCostPercent:   to.FoodCostPercent,
+			MarginChange:         change,
+			FoodCostChange:       models.RoundTo2(to.FoodCostPercent - from.FoodCostPercent),
+			ChangedAt:            to.RecordedAt,
+		}
+		if to.TechCardID != nil {
+			row.ItemType = MenuItemTypeTechCard
+		} else {
+			row.ItemType = MenuItemTypeProduct
+		}
+		if from.Margin > 0 {
+			pct := models.RoundTo2(change / from.Margin * 100)
+			row.MarginChangePercent = &pct
+		}
+		if row.Name == "" {
+			row.Name = uc.menuItemName(ctx, establishmentID, row.TechCardID, row.ProductID)
+		}
+		rows = append(rows, row)
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].MarginChange != rows[j].MarginChange {
+			return rows[i].MarginChange < rows[j].MarginChange
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return rows, nil
+}
+
+// menuItemName возвращает название позиции, которой не было в истории до начала периода
+func (uc *CostHistoryUseCase) menuItemName(ctx context.Context, establishmentID uuid.UUID, techCardID, productID *uuid.UUID) string {
+	if techCardID != nil {
+		if tc, err := uc.menu.GetTechCardByID(ctx, *techCardID, establishmentID); err == nil && tc != nil {
+			return tc.Name
+		}
+	}
+	if productID != nil {
+		if p, err := uc.menu.GetProductByID(ctx, *productID, establishmentID); err == nil && p != nil {
+			return p.Name
+		}
+	}
+	return ""
+}
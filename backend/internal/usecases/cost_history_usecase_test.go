@@ -0,0 +1,307 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// fakeCostHistoryRepository хранит историю и уведомления в памяти. Фоновый обработчик пишет
+// из своей горутины, поэтому записи защищены мьютексом
+type fakeCostHistoryRepository struct {
+	repositories.CostHistoryRepository
+	mu            sync.Mutex
+	entries       []*models.MenuItemCost
+	alerts        []*models.FoodCostAlert
+	techCardCosts map[uuid.UUID]float64
+	productCosts  map[uuid.UUID]float64
+	semiFinished  map[uuid.UUID]*models.SemiFinishedProduct // UpdateSemiFinishedCost меняет себестоимость полуфабриката
+}
+
+func (r *fakeCostHistoryRepository) CreateEntry(ctx context.Context, entry *models.MenuItemCost) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry.ID = uuid.New()
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *fakeCostHistoryRepository) GetLatestEntry(ctx context.Context, techCardID, productID *uuid.UUID) (*models.MenuItemCost, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		e := r.entries[i]
+		if (techCardID != nil && e.TechCardID != nil && *e.TechCardID == *techCardID) ||
+			(productID != nil && e.ProductID != nil && *e.ProductID == *productID) {
+			return e, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetLatestEntries возвращает последнюю запись по каждой позиции, сделанную до before
+func (r *fakeCostHistoryRepository) GetLatestEntries(ctx context.Context, establishmentID uuid.UUID, before time.Time) ([]*models.MenuItemCost, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	latest := make(map[uuid.UUID]*models.MenuItemCost)
+	var keys []uuid.UUID
+	for _, e := range r.entries {
+		if e.EstablishmentID != establishmentID || !e.RecordedAt.Before(before) {
+			continue
+		}
+		key := uuid.Nil
+		switch {
+		case e.TechCardID != nil:
+			key = *e.TechCardID
+		case e.ProductID != nil:
+			key = *e.ProductID
+		}
+		if prev, ok := latest[key]; !ok {
+			keys = append(keys, key)
+		} else if prev.RecordedAt.After(e.RecordedAt) {
+			continue
+		}
+		latest[key] = e
+	}
+	entries := make([]*models.MenuItemCost, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, latest[key])
+	}
+	return entries, nil
+}
+
+func (r *fakeCostHistoryRepository) UpdateTechCardCost(ctx context.Context, id uuid.UUID, costPrice float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.techCardCosts[id] = costPrice
+	return nil
+}
+
+func (r *fakeCostHistoryRepository) UpdateProductCost(ctx context.Context, id uuid.UUID, costPrice float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.productCosts[id] = costPrice
+	return nil
+}
+
+func (r *fakeCostHistoryRepository) UpdateSemiFinishedCost(ctx context.Context, id uuid.UUID, costPrice float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.semiFinished[id].CostPrice = costPrice
+	return nil
+}
+
+func (r *fakeCostHistoryRepository) CreateAlert(ctx context.Context, alert *models.FoodCostAlert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	alert.ID = uuid.New()
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func (r *fakeCostHistoryRepository) UpdateAlert(ctx context.Context, alert *models.FoodCostAlert) error {
+	return nil
+}
+
+func (r *fakeCostHistoryRepository) GetActiveAlert(ctx context.Context, techCardID, productID *uuid.UUID) (*models.FoodCostAlert, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, a := range r.alerts {
+		if a.Status == models.StockAlertStatusResolved {
+			continue
+		}
+		if (techCardID != nil && a.TechCardID != nil && *a.TechCardID == *techCardID) ||
+			(productID != nil && a.ProductID != nil && *a.ProductID == *productID) {
+			return a, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeCostHistoryRepository) entryCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// costHistoryWarehouseRepository добавляет к складу активное меню и товары
+type costHistoryWarehouseRepository struct {
+	*fakeWarehouseRepository
+	techCards []*models.TechCard
+	products  map[uuid.UUID]*models.Product
+}
+
+func (r *costHistoryWarehouseRepository) GetActiveTechCards(ctx context.Context, establishmentID uuid.UUID) ([]*models.TechCard, error) {
+	return r.techCards, nil
+}
+
+func (r *costHistoryWarehouseRepository) GetProductByID(ctx context.Context, id uuid.UUID) (*models.Product, error) {
+	if p, ok := r.products[id]; ok {
+		return p, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *costHistoryWarehouseRepository) GetStockByProductAndWarehouse(ctx context.Context, productID, warehouseID uuid.UUID) (*models.Stock, error) {
+	for _, st := range r.stocks {
+		if st.WarehouseID == warehouseID && st.ProductID != nil && *st.ProductID == productID {
+			cp := *st
+			return &cp, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+// costHistoryIngredientRepository, costHistorySemiFinishedRepository и costHistoryProductRepository
+// отдают MenuUseCase ингредиенты склада, полуфабрикаты и товары по ID
+type costHistoryIngredientRepository struct {
+	repositories.IngredientRepository
+	warehouse *costHistoryWarehouseRepository
+}
+
+func (r *costHistoryIngredientRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Ingredient, error) {
+	if ing, ok := r.warehouse.ingredients[id]; ok {
+		return ing, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+type costHistorySemiFinishedRepository struct {
+	repositories.SemiFinishedRepository
+	items map[uuid.UUID]*models.SemiFinishedProduct
+}
+
+func (r *costHistorySemiFinishedRepository) List(ctx context.Context, filter *repositories.SemiFinishedFilter) ([]*models.SemiFinishedProduct, error) {
+	list := make([]*models.SemiFinishedProduct, 0, len(r.items))
+	for _, sf := range r.items {
+		list = append(list, sf)
+	}
+	return list, nil
+}
+
+func (r *costHistorySemiFinishedRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.SemiFinishedProduct, error) {
+	if sf, ok := r.items[id]; ok {
+		return sf, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+type costHistoryProductRepository struct {
+	repositories.ProductRepository
+	warehouse *costHistoryWarehouseRepository
+}
+
+func (r *costHistoryProductRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Product, error) {
+	return r.warehouse.GetProductByID(ctx, id)
+}
+
+func TestCostHistoryUseCase_RunAfterSupply(t *testing.T) {
+	warehouse := &costHistoryWarehouseRepository{fakeWarehouseRepository: newFakeWarehouseRepository(), products: make(map[uuid.UUID]*models.Product)}
+	warehouseID := warehouse.addWarehouse()
+	flourID := warehouse.addIngredient(models.UnitKilogram)
+	sugarID := warehouse.addIngredient(models.UnitKilogram)
+	establishmentID := uuid.New()
+
+	// Тесто: 500 г муки на 1 кг выхода, сохраненная себестоимость 25
+	dough := &models.SemiFinishedProduct{ID: uuid.New(), Name: "Тесто", Quantity: 1, Unit: models.UnitKilogram, CostPrice: 25,
+		Ingredients: []models.SemiFinishedIngredient{{IngredientID: flourID, Net: 500, Unit: models.UnitGram}}}
+	semiFinished := map[uuid.UUID]*models.SemiFinishedProduct{dough.ID: dough}
+	// Блин: 200 г муки; вареники: 100 г теста; сироп к поставке не относится
+	pancake := &models.TechCard{ID: uuid.New(), EstablishmentID: establishmentID, Name: "Блин", Price: 20, CostPrice: 10,
+		Ingredients: []models.TechCardIngredient{{IngredientID: &flourID, Quantity: 200, Unit: models.UnitGram}}}
+	dumplings := &models.TechCard{ID: uuid.New(), EstablishmentID: establishmentID, Name: "Вареники", Price: 30, CostPrice: 2.5,
+		Ingredients: []models.TechCardIngredient{{SemiFinishedID: &dough.ID, SemiFinished: dough, Quantity: 100, Unit: models.UnitGram}}}
+	syrup := &models.TechCard{ID: uuid.New(), EstablishmentID: establishmentID, Name: "Сироп", Price: 50,
+		Ingredients: []models.TechCardIngredient{{IngredientID: &sugarID, Quantity: 100, Unit: models.UnitGram}}}
+	warehouse.techCards = []*models.TechCard{pancake, dumplings, syrup}
+	cola := &models.Product{ID: uuid.New(), EstablishmentID: establishmentID, Name: "Кола", Price: 100, CostPrice: 40}
+	warehouse.products[cola.ID] = cola
+
+	costs := &fakeCostHistoryRepository{
+		techCardCosts: make(map[uuid.UUID]float64),
+		productCosts:  make(map[uuid.UUID]float64),
+		semiFinished:  semiFinished,
+	}
+	menu := NewMenuUseCase(&costHistoryProductRepository{warehouse: warehouse}, nil, &costHistorySemiFinishedRepository{items: semiFinished},
+		&costHistoryIngredientRepository{warehouse: warehouse}, nil, nil, warehouse, nil)
+	costHistory := NewCostHistoryUseCase(costs, menu, warehouse, &fakeEstablishmentRepository{threshold: 50}, nil)
+	warehouseUC := &WarehouseUseCase{repo: warehouse, supplierRepo: &fakeSupplierRepository{}, transactor: &fakeTransactor{}, costHistory: costHistory}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		costHistory.Run(ctx)
+		close(done)
+	}()
+
+	// Проведенная поставка ставит пересчет в очередь фонового обработчика
+	supply := &models.Supply{
+		WarehouseID:      warehouseID,
+		SupplierID:       uuid.New(),
+		DeliveryDateTime: time.Now(),
+		Status:           "completed",
+		Items: []models.SupplyItem{
+			{ID: uuid.New(), IngredientID: &flourID, Quantity: 10, Unit: models.UnitKilogram, PricePerUnit: 60},
+			{ID: uuid.New(), ProductID: &cola.ID, Quantity: 20, Unit: models.UnitPiece, PricePerUnit: 50},
+		},
+	}
+	require.NoError(t, warehouseUC.CreateSupply(ctx, supply, establishmentID))
+	require.Eventually(t, func() bool { return costs.entryCount() == 3 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+
+	// Тесто: 0.5 кг * 60 = 30; блин: 0.2 кг * 60 = 12; вареники: 0.1 кг теста по 30 = 3; кола — по цене поставки
+	assert.InDelta(t, 30, dough.CostPrice, 1e-9)
+	assert.InDelta(t, 12, costs.techCardCosts[pancake.ID], 1e-9)
+	assert.InDelta(t, 3, costs.techCardCosts[dumplings.ID], 1e-9)
+	assert.NotContains(t, costs.techCardCosts, syrup.ID)
+	assert.InDelta(t, 50, costs.productCosts[cola.ID], 1e-9)
+
+	byItem := make(map[uuid.UUID]*models.MenuItemCost)
+	for _, e := range costs.entries {
+		assert.Equal(t, models.CostChangeReasonSupply, e.Reason)
+		require.NotNil(t, e.SourceID)
+		assert.Equal(t, supply.ID, *e.SourceID)
+		if e.TechCardID != nil {
+			byItem[*e.TechCardID] = e
+		} else {
+			byItem[*e.ProductID] = e
+		}
+	}
+	require.Contains(t, byItem, pancake.ID)
+	assert.InDelta(t, 12, byItem[pancake.ID].CostPrice, 1e-9)
+	require.Contains(t, byItem, cola.ID)
+	assert.InDelta(t, 100, byItem[cola.ID].Price, 1e-9)
+
+	// Фудкост блина 60% выше порога 50%, у колы ровно 50% — уведомление только по блину
+	require.Len(t, costs.alerts, 1)
+	assert.Equal(t, pancake.ID, *costs.alerts[0].TechCardID)
+	assert.InDelta(t, 60, costs.alerts[0].FoodCostPercent, 1e-9)
+
+	// Повторный пересчет без изменения цен не дублирует историю и уведомления
+	require.NoError(t, costHistory.RecalculateForSupply(context.Background(), establishmentID, supply.ID))
+	assert.Len(t, costs.entries, 3)
+	assert.Len(t, costs.alerts, 1)
+}
+
+func TestCostHistoryUseCase_NotifySupply(t *testing.T) {
+	// Без фонового обработчика уведомление ничего не делает
+	var disabled *CostHistoryUseCase
+	disabled.NotifySupply(uuid.New(), uuid.New())
+
+	// Переполненная очередь не блокирует проведение поставки
+	uc := NewCostHistoryUseCase(nil, nil, nil, nil, nil)
+	for i := 0; i < cap(uc.trigger)+10; i++ {
+		uc.NotifySupply(uuid.New(), uuid.New())
+	}
+	assert.Len(t, uc.trigger, cap(uc.trigger))
+}
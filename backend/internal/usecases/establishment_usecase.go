@@ -2,6 +2,10 @@ package usecases
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/yourusername/arc/backend/internal/models"
@@ -40,6 +44,42 @@ func (uc *EstablishmentUseCase) ListByEstablishment(ctx context.Context, establi
 	return []*models.Establishment{e}, nil
 }
 
+// GetStockPolicy возвращает политику продажи при нехватке остатков (allow, warn, block)
+func (uc *EstablishmentUseCase) GetStockPolicy(ctx context.Context, establishmentID uuid.UUID) (string, error) {
+	policy, err := uc.repo.GetNegativeStockPolicy(ctx, establishmentID)
+	if err != nil {
+		return "", err
+	}
+	if !models.IsValidNegativeStockPolicy(policy) {
+		return models.NegativeStockPolicyAllow, nil
+	}
+	return policy, nil
+}
+
+// SetStockPolicy меняет политику продажи при нехватке остатков
+func (uc *EstablishmentUseCase) SetStockPolicy(ctx context.Context, establishmentID uuid.UUID, policy string) error {
+	if !models.IsValidNegativeStockPolicy(policy) {
+		return errors.New("invalid negative stock policy, expected allow, warn or block")
+	}
+	return uc.repo.UpdateNegativeStockPolicy(ctx, establishmentID, policy)
+}
+
+// GetTimezone возвращает часовой пояс заведения (пусто — время сервера)
+func (uc *EstablishmentUseCase) GetTimezone(ctx context.Context, establishmentID uuid.UUID) (string, error) {
+	return uc.repo.GetTimezone(ctx, establishmentID)
+}
+
+// SetTimezone меняет часовой пояс заведения, в котором действуют расписания доступности меню
+func (uc *EstablishmentUseCase) SetTimezone(ctx context.Context, establishmentID uuid.UUID, timezone string) error {
+	timezone = strings.TrimSpace(timezone)
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", timezone)
+		}
+	}
+	return uc.repo.UpdateTimezone(ctx, establishmentID, timezone)
+}
+
 // Update обновляет заведение
 func (uc *EstablishmentUseCase) Update(ctx context.Context, e *models.Establishment) error {
 	return uc.repo.Update(ctx, e)
@@ -22,6 +22,8 @@ type FinanceUseCase struct {
 	accountRepo     repositories.AccountRepository
 	shiftRepo       repositories.ShiftRepository
 	orderRepo       repositories.OrderRepository // Добавлен orderRepo
+	warehouseRepo   repositories.WarehouseRepository // Для фактической себестоимости продаж по партиям
+	inventoryRepo   repositories.InventoryRepository // Для недостач и излишков по инвентаризациям
 }
 
 func NewFinanceUseCase(
@@ -29,12 +31,16 @@ func NewFinanceUseCase(
 	accountRepo repositories.AccountRepository,
 	shiftRepo repositories.ShiftRepository,
 	orderRepo repositories.OrderRepository, // Добавлен orderRepo
+	warehouseRepo repositories.WarehouseRepository,
+	inventoryRepo repositories.InventoryRepository,
 ) *FinanceUseCase {
 	return &FinanceUseCase{
 		transactionRepo: transactionRepo,
 		accountRepo:     accountRepo,
 		shiftRepo:       shiftRepo,
 		orderRepo:       orderRepo,
+		warehouseRepo:   warehouseRepo,
+		inventoryRepo:   inventoryRepo,
 	}
 }
 
@@ -325,6 +331,7 @@ type PNLReport struct {
 	Salary        float64 `json:"salary"`
 	Rent          float64 `json:"rent"`
 	OtherExpenses float64 `json:"other_expenses"`
+	InventoryLoss float64 `json:"inventory_loss"` // Недостачи минус излишки по инвентаризациям (уже включены в cost_of_goods или other_expenses)
 	TotalExpenses float64 `json:"total_expenses"`
 	NetProfit     float64 `json:"net_profit"`
 }
@@ -357,11 +364,28 @@ func (uc *FinanceUseCase) GetPNL(ctx context.Context, establishmentID uuid.UUID,
 		_ = cancelledOrders
 	}
 
+	// Фактическая себестоимость продаж — по израсходованным партиям (FIFO)
+	consumedCost := make(map[uuid.UUID]float64)
+	if uc.warehouseRepo != nil {
+		orderIDs := make([]uuid.UUID, 0, len(allOrders))
+		for _, order := range allOrders {
+			orderIDs = append(orderIDs, order.ID)
+		}
+		consumedCost, err = uc.warehouseRepo.GetConsumedCostByDocuments(ctx, models.StockConsumptionSale, orderIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get consumed cost of goods: %w", err)
+		}
+	}
+
 	var revenue float64
 	var costOfGoods float64
 	for _, order := range allOrders {
 		revenue += order.TotalAmount
-		// Calculate cost of goods from order items
+		if cost, ok := consumedCost[order.ID]; ok {
+			costOfGoods += cost
+			continue
+		}
+		// Заказ еще не списан со склада — оцениваем по себестоимости позиций
 		for _, item := range order.Items {
 			var costPrice float64
 			if item.Product != nil {
@@ -418,6 +442,22 @@ func (uc *FinanceUseCase) GetPNL(ctx context.Context, establishmentID uuid.UUID,
 		}
 	}
 
+	// Итоги инвентаризаций относим в блок ОПиУ, указанный в причине списания
+	if uc.inventoryRepo != nil {
+		losses, err := uc.inventoryRepo.GetLossesByPnlBlock(ctx, establishmentID, startDate, endDate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get inventory losses: %w", err)
+		}
+		for block, amount := range losses {
+			if block == "expenses" {
+				report.OtherExpenses += amount
+			} else {
+				report.CostOfGoods += amount
+			}
+			report.InventoryLoss += amount
+		}
+	}
+
 	report.TotalExpenses = report.CostOfGoods + report.Salary + report.Rent + report.OtherExpenses
 	report.NetProfit = report.TotalIncome - report.TotalExpenses
 
@@ -0,0 +1,593 @@
+package usecases
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/pkg/pdf"
+)
+
+// InventoryCountInput подсчет одной позиции в зоне
+type InventoryCountInput struct {
+	ItemID   uuid.UUID `json:"item_id" binding:"required"`
+	Zone     string    `json:"zone"`
+	Quantity float64   `json:"quantity" binding:"gte=0"`
+	Unit     string    `json:"unit,omitempty"` // Единица подсчета (по умолчанию — единица позиции)
+	Comment  string    `json:"comment"`
+}
+
+// InventoryZoneCounts подсчеты позиции в одной зоне
+type InventoryZoneCounts struct {
+	Zone     string                   `json:"zone"`
+	Counts   []*models.InventoryCount `json:"counts"`
+	Quantity *float64                 `json:"quantity,omitempty"` // Принятое количество (nil — расхождение не сверено)
+	Final    bool                     `json:"final"`              // Количество принято при сверке
+	Conflict bool                     `json:"conflict"`           // Счетчики разошлись, итоговой записи нет
+}
+
+// InventoryItemReconciliation сверка подсчетов одной позиции
+type InventoryItemReconciliation struct {
+	ItemID           uuid.UUID              `json:"item_id"`
+	Name             string                 `json:"name"`
+	Unit             string                 `json:"unit"`
+	ExpectedQuantity *float64               `json:"expected_quantity,omitempty"` // Скрыто при слепом пересчете
+	ActualQuantity   float64                `json:"actual_quantity"`             // Сумма принятых количеств по зонам
+	Zones            []*InventoryZoneCounts `json:"zones"`
+	Conflict         bool                   `json:"conflict"`
+}
+
+// InventoryReconciliation сверка подсчетов инвентаризации
+type InventoryReconciliation struct {
+	InventoryID    uuid.UUID                      `json:"inventory_id"`
+	Blind          bool                           `json:"blind"`
+	CountersCount  int                            `json:"counters_count"`
+	ConflictsCount int                            `json:"conflicts_count"` // Зон с несверенными расхождениями
+	Items          []*InventoryItemReconciliation `json:"items"`
+}
+
+// InventoryCountImportResult итог импорта подсчетов
+type InventoryCountImportResult struct {
+	Imported int      `json:"imported"`
+	Errors   []string `json:"errors,omitempty"` // Ошибки по строкам файла
+}
+
+// getCountableInventory возвращает инвентаризацию, в которую можно вносить подсчеты
+func (uc *InventoryUseCase) getCountableInventory(ctx context.Context, id, establishmentID uuid.UUID) (*models.Inventory, error) {
+	inventory, err := uc.repo.GetByID(ctx, id, &establishmentID)
+	if err != nil || inventory == nil {
+		return nil, errors.New("inventory not found")
+	}
+	if inventory.CountMode != models.InventoryCountModeMulti {
+		return nil, errors.New("inventory is not in multi-counter mode")
+	}
+	if inventory.Status != models.InventoryStatusInProgress {
+		return nil, errors.New("counts can only be submitted for inventories in progress")
+	}
+	return inventory, nil
+}
+
+// SubmitCounts сохраняет подсчеты счетчика. Повторный подсчет той же позиции в той же зоне
+// заменяет предыдущий. Фактические количества позиций пересчитываются по всем подсчетам.
+func (uc *InventoryUseCase) SubmitCounts(ctx context.Context, inventoryID, establishmentID, counterID uuid.UUID, inputs []InventoryCountInput) (*InventoryReconciliation, error) {
+	inventory, err := uc.getCountableInventory(ctx, inventoryID, establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := uc.buildCounts(ctx, inventory, counterID, inputs, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.repo.SaveCounts(ctx, counts); err != nil {
+		return nil, err
+	}
+	return uc.reconcile(ctx, inventory)
+}
+
+// ResolveCount принимает количество позиции в зоне при расхождении подсчетов
+func (uc *InventoryUseCase) ResolveCount(ctx context.Context, inventoryID, establishmentID, userID uuid.UUID, input InventoryCountInput) (*InventoryReconciliation, error) {
+	inventory, err := uc.getCountableInventory(ctx, inventoryID, establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := uc.buildCounts(ctx, inventory, userID, []InventoryCountInput{input}, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.repo.SaveCounts(ctx, counts); err != nil {
+		return nil, err
+	}
+	return uc.reconcile(ctx, inventory)
+}
+
+// GetReconciliation возвращает сверку подсчетов по позициям и зонам
+func (uc *InventoryUseCase) GetReconciliation(ctx context.Context, inventoryID, establishmentID uuid.UUID) (*InventoryReconciliation, error) {
+	inventory, err := uc.repo.GetByID(ctx, inventoryID, &establishmentID)
+	if err != nil || inventory == nil {
+		return nil, errors.New("inventory not found")
+	}
+	return uc.reconcile(ctx, inventory)
+}
+
+// ListCounts возвращает все подсчеты инвентаризации
+func (uc *InventoryUseCase) ListCounts(ctx context.Context, inventoryID, establishmentID uuid.UUID) ([]*models.InventoryCount, error) {
+	inventory, err := uc.repo.GetByID(ctx, inventoryID, &establishmentID)
+	if err != nil || inventory == nil {
+		return nil, errors.New("inventory not found")
+	}
+	return uc.repo.ListCounts(ctx, inventory.ID)
+}
+
+// buildCounts проверяет подсчеты и переводит количества в единицу позиции
+func (uc *InventoryUseCase) buildCounts(ctx context.Context, inventory *models.Inventory, counterID uuid.UUID, inputs []InventoryCountInput, final bool) ([]*models.InventoryCount, error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("at least one count required")
+	}
+	items := make(map[uuid.UUID]*models.InventoryItem, len(inventory.Items))
+	for i := range inventory.Items {
+		items[inventory.Items[i].ID] = &inventory.Items[i]
+	}
+
+	counts := make([]*models.InventoryCount, 0, len(inputs))
+	for _, in := range inputs {
+		item, ok := items[in.ItemID]
+		if !ok {
+			return nil, fmt.Errorf("inventory item %s not found", in.ItemID)
+		}
+		if in.Quantity < 0 {
+			return nil, errors.New("quantity must not be negative")
+		}
+		quantity, err := uc.convertActualQuantity(ctx, &CreateInventoryItemRequest{
+			IngredientID:   item.IngredientID,
+			ActualQuantity: in.Quantity,
+			Unit:           in.Unit,
+		}, item.Unit)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, &models.InventoryCount{
+			InventoryID: inventory.ID,
+			ItemID:      item.ID,
+			Zone:        strings.TrimSpace(in.Zone),
+			CounterID:   counterID,
+			Quantity:    quantity,
+			IsFinal:     final,
+			Comment:     in.Comment,
+		})
+	}
+	return counts, nil
+}
+
+// reconcile сводит подсчеты по зонам: в зоне принимается итоговая запись сверки, а без нее —
+// количество, в котором сошлись все счетчики. Фактическое количество позиции — сумма принятых
+// количеств по зонам; изменившиеся позиции сохраняются.
+func (uc *InventoryUseCase) reconcile(ctx context.Context, inventory *models.Inventory) (*InventoryReconciliation, error) {
+	counts, err := uc.repo.ListCounts(ctx, inventory.ID)
+	if err != nil {
+		return nil, err
+	}
+	byItem := make(map[uuid.UUID]map[string][]*models.InventoryCount)
+	counters := make(map[uuid.UUID]bool)
+	for _, c := range counts {
+		if byItem[c.ItemID] == nil {
+			byItem[c.ItemID] = make(map[string][]*models.InventoryCount)
+		}
+		byItem[c.ItemID][c.Zone] = append(byItem[c.ItemID][c.Zone], c)
+		if !c.IsFinal {
+			counters[c.CounterID] = true
+		}
+	}
+
+	result := &InventoryReconciliation{
+		InventoryID:   inventory.ID,
+		Blind:         inventory.Blind,
+		CountersCount: len(counters),
+		Items:         make([]*InventoryItemReconciliation, 0, len(inventory.Items)),
+	}
+	for i := range inventory.Items {
+		item := &inventory.Items[i]
+		row := &InventoryItemReconciliation{
+			ItemID: item.ID,
+			Name:   inventoryItemName(item),
+			Unit:   item.Unit,
+		}
+		if !inventory.HidesExpected() {
+			expected := item.ExpectedQuantity
+			row.ExpectedQuantity = &expected
+		}
+
+		zones := byItem[item.ID]
+		zoneNames := make([]string, 0, len(zones))
+		for zone := range zones {
+			zoneNames = append(zoneNames, zone)
+		}
+		sort.Strings(zoneNames)
+
+		actual := 0.0
+		for _, zone := range zoneNames {
+			zc := reconcileZone(zone, zones[zone])
+			if zc.Conflict {
+				row.Conflict = true
+				result.ConflictsCount++
+			} else {
+				actual += *zc.Quantity
+			}
+			row.Zones = append(row.Zones, zc)
+		}
+		row.ActualQuantity = models.RoundTo2(actual)
+		result.Items = append(result.Items, row)
+
+		if len(zones) == 0 || models.RoundTo2(item.ActualQuantity) == row.ActualQuantity {
+			continue
+		}
+		item.ActualQuantity = row.ActualQuantity
+		item.Difference = item.ActualQuantity - item.ExpectedQuantity
+		item.DifferenceValue = item.Difference * item.PricePerUnit
+		if err := uc.repo.UpdateItem(ctx, item); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// reconcileZone определяет принятое количество позиции в зоне
+func reconcileZone(zone string, counts []*models.InventoryCount) *InventoryZoneCounts {
+	zc := &InventoryZoneCounts{Zone: zone, Counts: counts}
+	for _, c := range counts {
+		if c.IsFinal {
+			q := c.Quantity
+			zc.Quantity = &q
+			zc.Final = true
+			return zc
+		}
+	}
+	for _, c := range counts[1:] {
+		if models.RoundTo2(c.Quantity) != models.RoundTo2(counts[0].Quantity) {
+			zc.Conflict = true
+			return zc
+		}
+	}
+	q := counts[0].Quantity
+	zc.Quantity = &q
+	return zc
+}
+
+// checkCountsReconciled не дает завершить инвентаризацию с несверенными расхождениями
+func (uc *InventoryUseCase) checkCountsReconciled(ctx context.Context, inventory *models.Inventory) error {
+	if inventory.CountMode != models.InventoryCountModeMulti {
+		return nil
+	}
+	rec, err := uc.reconcile(ctx, inventory)
+	if err != nil {
+		return err
+	}
+	if rec.ConflictsCount > 0 {
+		return fmt.Errorf("inventory has %d unreconciled count discrepancies", rec.ConflictsCount)
+	}
+	return nil
+}
+
+func inventoryItemName(item *models.InventoryItem) string {
+	switch {
+	case item.Ingredient != nil:
+		return item.Ingredient.Name
+	case item.Product != nil:
+		return item.Product.Name
+	case item.TechCard != nil:
+		return item.TechCard.Name
+	case item.SemiFinished != nil:
+		return item.SemiFinished.Name
+	}
+	return ""
+}
+
+// ——— Count sheets ———
+
+// Колонки листа подсчета в CSV; импорт читает те же колонки
+var inventoryCountSheetColumns = []string{"item_id", "name", "unit", "zone", "expected_quantity", "counted_quantity"}
+
+// ExportCountSheet формирует лист подсчета в CSV или PDF для зоны zone.
+// При слепом пересчете ожидаемое количество в лист не попадает.
+// Возвращает содержимое файла, MIME-тип и имя файла.
+func (uc *InventoryUseCase) ExportCountSheet(ctx context.Context, inventoryID, establishmentID uuid.UUID, zone, format string) ([]byte, string, string, error) {
+	inventory, err := uc.repo.GetByID(ctx, inventoryID, &establishmentID)
+	if err != nil || inventory == nil {
+		return nil, "", "", errors.New("inventory not found")
+	}
+	zone = strings.TrimSpace(zone)
+	name := "count-sheet-" + inventory.CreatedAt.Format("2006-01-02")
+	if zone != "" {
+		name += "-" + zone
+	}
+
+	switch format {
+	case ExportFormatCSV, "":
+		data, err := inventoryCountSheetCSV(inventory, zone)
+		if err != nil {
+			return nil, "", "", err
+		}
+		return data, "text/csv; charset=utf-8", name + ".csv", nil
+	case ExportFormatPDF:
+		return inventoryCountSheetPDF(inventory, zone), "application/pdf", name + ".pdf", nil
+	default:
+		return nil, "", "", fmt.Errorf("unsupported export format %q, must be csv or pdf", format)
+	}
+}
+
+func inventoryCountSheetCSV(inventory *models.Inventory, zone string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	rows := [][]string{inventoryCountSheetColumns}
+	for i := range inventory.Items {
+		item := &inventory.Items[i]
+		expected := ""
+		if !inventory.HidesExpected() {
+			expected = formatAmount(item.ExpectedQuantity)
+		}
+		rows = append(rows, []string{item.ID.String(), inventoryItemName(item), item.Unit, zone, expected, ""})
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func inventoryCountSheetPDF(inventory *models.Inventory, zone string) []byte {
+	doc := pdf.New()
+	doc.Title("Лист подсчета")
+	if inventory.Warehouse != nil {
+		doc.Text("Склад: " + inventory.Warehouse.Name)
+	}
+	if zone != "" {
+		doc.Text("Зона: " + zone)
+	}
+	if inventory.ScheduledDate != nil {
+		doc.Text("Дата: " + inventory.ScheduledDate.Format("02.01.2006"))
+	}
+	doc.Text("Счетчик: ____________________")
+	doc.Space()
+
+	blind := inventory.HidesExpected()
+	headers := []string{"№", "Наименование", "Ед.", "Учетное кол-во", "Факт"}
+	widths := []float64{0.06, 0.5, 0.1, 0.17, 0.17}
+	if blind {
+		headers = []string{"№", "Наименование", "Ед.", "Факт"}
+		widths = []float64{0.06, 0.6, 0.12, 0.22}
+	}
+	rows := make([][]string, 0, len(inventory.Items))
+	for i := range inventory.Items {
+		item := &inventory.Items[i]
+		row := []string{strconv.Itoa(i + 1), inventoryItemName(item), item.Unit}
+		if !blind {
+			row = append(row, formatAmount(item.ExpectedQuantity))
+		}
+		rows = append(rows, append(row, ""))
+	}
+	doc.Table(headers, rows, widths)
+	return doc.Bytes()
+}
+
+// ImportCounts загружает подсчеты счетчика из CSV листа подсчета (колонки item_id, zone,
+// counted_quantity; остальные колонки игнорируются). Строки без количества пропускаются,
+// строки с ошибками не загружаются и перечисляются в результате.
+func (uc *InventoryUseCase) ImportCounts(ctx context.Context, inventoryID, establishmentID, counterID uuid.UUID, zone string, r io.Reader) (*InventoryCountImportResult, error) {
+	inventory, err := uc.getCountableInventory(ctx, inventoryID, establishmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("csv file is empty")
+	}
+
+	columns := make(map[string]int)
+	for i, h := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	itemCol, ok := columns["item_id"]
+	if !ok {
+		return nil, errors.New("csv must have item_id column")
+	}
+	qtyCol, ok := columns["counted_quantity"]
+	if !ok {
+		return nil, errors.New("csv must have counted_quantity column")
+	}
+	zoneCol, hasZone := columns["zone"]
+	unitCol, hasUnit := columns["unit"]
+
+	items := make(map[uuid.UUID]bool, len(inventory.Items))
+	for i := range inventory.Items {
+		items[inventory.Items[i].ID] = true
+	}
+
+	result := &InventoryCountImportResult{}
+	inputs := make([]InventoryCountInput, 0, len(records)-1)
+	for n, rec := range records[1:] {
+		line := n + 2
+		cell := func(col int) string {
+			if col < len(rec) {
+				return strings.TrimSpace(rec[col])
+			}
+			return ""
+		}
+		if cell(qtyCol) == "" {
+			continue
+		}
+		itemID, err := uuid.Parse(cell(itemCol))
+		if err != nil || !items[itemID] {
+			result.Errors = append(result.Errors, fmt.Sprintf("line %d: unknown item_id %q", line, cell(itemCol)))
+			continue
+		}
+		quantity, err := strconv.ParseFloat(strings.ReplaceAll(cell(qtyCol), ",", "."), 64)
+		if err != nil || quantity < 0 {
+			result.Errors = append(result.Errors, fmt.Sprintf("line %d: invalid counted_quantity %q", line, cell(qtyCol)))
+			continue
+		}
+		in := InventoryCountInput{ItemID: itemID, Zone: zone, Quantity: quantity}
+		if hasZone && cell(zoneCol) != "" {
+			in.Zone = cell(zoneCol)
+		}
+		if hasUnit {
+			in.Unit = cell(unitCol)
+		}
+		inputs = append(inputs, in)
+	}
+	if len(inputs) == 0 {
+		return result, nil
+	}
+
+	counts, err := uc.buildCounts(ctx, inventory, counterID, inputs, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.repo.SaveCounts(ctx, counts); err != nil {
+		return nil, err
+	}
+	if _, err := uc.reconcile(ctx, inventory); err != nil {
+		return nil, err
+	}
+	result.Imported = len(counts)
+	return result, nil
+}
+
+// InventoryScanInput количество позиции, считанное сканером
+type InventoryScanInput struct {
+	Type         models.InventoryItemType
+	IngredientID *uuid.UUID
+	ProductID    *uuid.UUID
+	TechCardID   *uuid.UUID
+	Quantity     float64
+	Unit         string
+	Zone         string
+}
+
+// AddScannedQuantity прибавляет отсканированное количество к позиции инвентаризации.
+// Позиция, которой еще нет в инвентаризации, добавляется с ожидаемым количеством из остатков.
+// В режиме multi количество прибавляется к подсчету счетчика counterID в зоне, иначе — к фактическому количеству.
+func (uc *InventoryUseCase) AddScannedQuantity(ctx context.Context, inventoryID, establishmentID, counterID uuid.UUID, in InventoryScanInput) (*models.InventoryItem, error) {
+	inventory, err := uc.repo.GetByID(ctx, inventoryID, &establishmentID)
+	if err != nil || inventory == nil {
+		return nil, errors.New("inventory not found")
+	}
+	multi := inventory.CountMode == models.InventoryCountModeMulti
+	if multi && inventory.Status != models.InventoryStatusInProgress {
+		return nil, errors.New("counts can only be submitted for inventories in progress")
+	}
+	if inventory.Status != models.InventoryStatusDraft && inventory.Status != models.InventoryStatusInProgress {
+		return nil, errors.New("can only update items in draft or in_progress status")
+	}
+
+	var item *models.InventoryItem
+	for i := range inventory.Items {
+		it := &inventory.Items[i]
+		if sameItemID(it.IngredientID, in.IngredientID) && sameItemID(it.ProductID, in.ProductID) && sameItemID(it.TechCardID, in.TechCardID) {
+			item = it
+			break
+		}
+	}
+	if item == nil {
+		req := &CreateInventoryItemRequest{
+			Type:         in.Type,
+			IngredientID: in.IngredientID,
+			ProductID:    in.ProductID,
+			TechCardID:   in.TechCardID,
+		}
+		if err := uc.validateInventoryItem(req); err != nil {
+			return nil, err
+		}
+		expected, unit, price, err := uc.getStockData(ctx, inventory.WarehouseID, req)
+		if err != nil {
+			return nil, err
+		}
+		item = &models.InventoryItem{
+			InventoryID:      inventory.ID,
+			Type:             in.Type,
+			IngredientID:     in.IngredientID,
+			ProductID:        in.ProductID,
+			TechCardID:       in.TechCardID,
+			ExpectedQuantity: expected,
+			Unit:             unit,
+			PricePerUnit:     price,
+			Difference:       -expected,
+			DifferenceValue:  -expected * price,
+		}
+		if err := uc.repo.CreateItem(ctx, item); err != nil {
+			return nil, err
+		}
+		inventory.Items = append(inventory.Items, *item)
+		item = &inventory.Items[len(inventory.Items)-1]
+	}
+
+	quantity, err := uc.convertActualQuantity(ctx, &CreateInventoryItemRequest{
+		IngredientID:   item.IngredientID,
+		ActualQuantity: in.Quantity,
+		Unit:           in.Unit,
+	}, item.Unit)
+	if err != nil {
+		return nil, err
+	}
+
+	if multi {
+		zone := strings.TrimSpace(in.Zone)
+		counts, err := uc.repo.ListCounts(ctx, inventory.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range counts {
+			if c.ItemID == item.ID && c.Zone == zone && c.CounterID == counterID && !c.IsFinal {
+				quantity += c.Quantity
+				break
+			}
+		}
+		if err := uc.repo.SaveCounts(ctx, []*models.InventoryCount{{
+			InventoryID: inventory.ID,
+			ItemID:      item.ID,
+			Zone:        zone,
+			CounterID:   counterID,
+			Quantity:    quantity,
+		}}); err != nil {
+			return nil, err
+		}
+		if _, err := uc.reconcile(ctx, inventory); err != nil {
+			return nil, err
+		}
+	} else {
+		item.ActualQuantity += quantity
+		item.Difference = item.ActualQuantity - item.ExpectedQuantity
+		item.DifferenceValue = item.Difference * item.PricePerUnit
+		if err := uc.repo.UpdateItem(ctx, item); err != nil {
+			return nil, err
+		}
+	}
+
+	result := *item
+	if inventory.HidesExpected() {
+		result.ExpectedQuantity = 0
+		result.Difference = 0
+		result.DifferenceValue = 0
+	}
+	return &result, nil
+}
+
+func sameItemID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
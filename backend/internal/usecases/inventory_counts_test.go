@@ -0,0 +1,86 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+func (r *fakeInventoryRepository) ListCounts(ctx context.Context, inventoryID uuid.UUID) ([]*models.InventoryCount, error) {
+	return r.counts, nil
+}
+
+func (r *fakeInventoryRepository) UpdateItem(ctx context.Context, item *models.InventoryItem) error {
+	return nil
+}
+
+func TestReconcileZone(t *testing.T) {
+	count := func(quantity float64, final bool) *models.InventoryCount {
+		return &models.InventoryCount{ID: uuid.New(), CounterID: uuid.New(), Quantity: quantity, IsFinal: final}
+	}
+	quantity := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name     string
+		counts   []*models.InventoryCount
+		quantity *float64
+		conflict bool
+		final    bool
+	}{
+		{name: "single counter", counts: []*models.InventoryCount{count(4.5, false)}, quantity: quantity(4.5)},
+		{name: "counters agree", counts: []*models.InventoryCount{count(3, false), count(3.001, false)}, quantity: quantity(3)},
+		{name: "counters disagree", counts: []*models.InventoryCount{count(3, false), count(3.5, false)}, conflict: true},
+		{name: "final count resolves conflict", counts: []*models.InventoryCount{count(3, false), count(3.5, false), count(3.2, true)}, quantity: quantity(3.2), final: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zc := reconcileZone("Холодильник", tt.counts)
+			assert.Equal(t, "Холодильник", zc.Zone)
+			assert.Equal(t, tt.conflict, zc.Conflict)
+			assert.Equal(t, tt.final, zc.Final)
+			if tt.quantity == nil {
+				assert.Nil(t, zc.Quantity)
+			} else {
+				require.NotNil(t, zc.Quantity)
+				assert.InDelta(t, *tt.quantity, *zc.Quantity, 1e-9)
+			}
+		})
+	}
+}
+
+func TestInventoryUseCase_Reconcile_SumsZones(t *testing.T) {
+	ctx := context.Background()
+	uc, repo, _ := newInventoryFixture(t, nil, 0)
+	repo.inventory.CountMode = models.InventoryCountModeMulti
+	repo.inventory.Blind = true
+	itemID := repo.inventory.Items[0].ID
+	zoneCount := func(zone string, quantity float64, final bool) *models.InventoryCount {
+		return &models.InventoryCount{ID: uuid.New(), ItemID: itemID, Zone: zone, CounterID: uuid.New(), Quantity: quantity, IsFinal: final}
+	}
+	repo.counts = []*models.InventoryCount{
+		zoneCount("Склад", 4, false),
+		zoneCount("Склад", 4, false),
+		zoneCount("Кухня", 1, false),
+		zoneCount("Кухня", 1.5, false),
+	}
+
+	rec, err := uc.reconcile(ctx, repo.inventory)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rec.ConflictsCount)
+	assert.Equal(t, 4, rec.CountersCount)
+	require.Len(t, rec.Items, 1)
+	assert.Nil(t, rec.Items[0].ExpectedQuantity, "blind count hides expected quantity")
+	// Незавершенную сверку провести нельзя
+	assert.ErrorContains(t, uc.UpdateStatus(ctx, repo.inventory.ID, uuid.New(), models.InventoryStatusCompleted, nil, nil), "unreconciled")
+
+	repo.counts = append(repo.counts, zoneCount("Кухня", 1.5, true))
+	rec, err = uc.reconcile(ctx, repo.inventory)
+	require.NoError(t, err)
+	assert.Zero(t, rec.ConflictsCount)
+	assert.InDelta(t, 5.5, rec.Items[0].ActualQuantity, 1e-9)
+	assert.InDelta(t, 5.5, repo.inventory.Items[0].ActualQuantity, 1e-9)
+}
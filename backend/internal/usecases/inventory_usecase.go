@@ -3,6 +3,7 @@ package usecases
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,12 +14,14 @@ import (
 type InventoryUseCase struct {
 	repo         repositories.InventoryRepository
 	warehouseRepo repositories.WarehouseRepository
+	stockAlerts   *StockAlertUseCase
 }
 
-func NewInventoryUseCase(repo repositories.InventoryRepository, warehouseRepo repositories.WarehouseRepository) *InventoryUseCase {
+func NewInventoryUseCase(repo repositories.InventoryRepository, warehouseRepo repositories.WarehouseRepository, stockAlerts *StockAlertUseCase) *InventoryUseCase {
 	return &InventoryUseCase{
 		repo:         repo,
 		warehouseRepo: warehouseRepo,
+		stockAlerts:   stockAlerts,
 	}
 }
 
@@ -28,6 +31,9 @@ type CreateInventoryRequest struct {
 	Type          models.InventoryType            `json:"type" binding:"required"`
 	ScheduledDate *time.Time                      `json:"scheduled_date"`
 	Comment       string                          `json:"comment"`
+	WriteOffReasonID *uuid.UUID                   `json:"write_off_reason_id"` // Причина списания недостачи
+	CountMode     models.InventoryCountMode       `json:"count_mode"`                  // single (по умолчанию) или multi
+	Blind         bool                            `json:"blind"`                       // Скрывать ожидаемое количество до завершения
 	Items         []CreateInventoryItemRequest    `json:"items"`
 }
 
@@ -39,6 +45,7 @@ type CreateInventoryItemRequest struct {
 	TechCardID       *uuid.UUID               `json:"tech_card_id,omitempty"`
 	SemiFinishedID   *uuid.UUID               `json:"semi_finished_id,omitempty"`
 	ActualQuantity   float64                  `json:"actual_quantity"`
+	Unit             string                   `json:"unit,omitempty"` // Единица фактического количества (по умолчанию — единица остатка)
 	Comment          string                   `json:"comment"`
 }
 
@@ -50,8 +57,11 @@ type UpdateInventoryItemRequest struct {
 
 // UpdateInventoryRequest запрос на обновление инвентаризации
 type UpdateInventoryRequest struct {
-	ScheduledDate *time.Time `json:"scheduled_date"`
-	Comment       string     `json:"comment"`
+	ScheduledDate    *time.Time `json:"scheduled_date"`
+	Comment          string     `json:"comment"`
+	WriteOffReasonID *uuid.UUID `json:"write_off_reason_id"`
+	CountMode        models.InventoryCountMode `json:"count_mode"`
+	Blind            *bool      `json:"blind"`
 }
 
 // List возвращает список инвентаризаций
@@ -60,7 +70,14 @@ func (uc *InventoryUseCase) List(ctx context.Context, establishmentID uuid.UUID,
 		filter = &repositories.InventoryFilter{}
 	}
 	filter.EstablishmentID = &establishmentID
-	return uc.repo.List(ctx, filter)
+	list, err := uc.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, inventory := range list {
+		inventory.HideExpected()
+	}
+	return list, nil
 }
 
 // GetByID возвращает инвентаризацию по ID
@@ -72,6 +89,7 @@ func (uc *InventoryUseCase) GetByID(ctx context.Context, id uuid.UUID, establish
 	if inventory == nil {
 		return nil, errors.New("inventory not found")
 	}
+	inventory.HideExpected()
 	return inventory, nil
 }
 
@@ -93,6 +111,18 @@ func (uc *InventoryUseCase) Create(ctx context.Context, req *CreateInventoryRequ
 		return nil, errors.New("at least one item required for partial inventory")
 	}
 
+	if err := uc.checkWriteOffReason(ctx, req.WriteOffReasonID, establishmentID); err != nil {
+		return nil, err
+	}
+
+	countMode := req.CountMode
+	if countMode == "" {
+		countMode = models.InventoryCountModeSingle
+	}
+	if countMode != models.InventoryCountModeSingle && countMode != models.InventoryCountModeMulti {
+		return nil, errors.New("invalid count mode")
+	}
+
 	inventory := &models.Inventory{
 		EstablishmentID: establishmentID,
 		WarehouseID:     req.WarehouseID,
@@ -100,6 +130,9 @@ func (uc *InventoryUseCase) Create(ctx context.Context, req *CreateInventoryRequ
 		Status:          models.InventoryStatusDraft,
 		ScheduledDate:   req.ScheduledDate,
 		Comment:         req.Comment,
+		WriteOffReasonID: req.WriteOffReasonID,
+		CountMode:       countMode,
+		Blind:           req.Blind,
 		CreatedBy:       createdBy,
 	}
 
@@ -117,6 +150,12 @@ func (uc *InventoryUseCase) Create(ctx context.Context, req *CreateInventoryRequ
 			return nil, err
 		}
 
+		// Фактическое количество приводим к единице остатка (например, 500 г → 0.5 кг)
+		actualQuantity, err := uc.convertActualQuantity(ctx, &itemReq, unit)
+		if err != nil {
+			return nil, err
+		}
+
 		item := models.InventoryItem{
 			Type:             itemReq.Type,
 			IngredientID:     itemReq.IngredientID,
@@ -124,7 +163,7 @@ func (uc *InventoryUseCase) Create(ctx context.Context, req *CreateInventoryRequ
 			TechCardID:       itemReq.TechCardID,
 			SemiFinishedID:   itemReq.SemiFinishedID,
 			ExpectedQuantity: expectedQuantity,
-			ActualQuantity:   itemReq.ActualQuantity,
+			ActualQuantity:   actualQuantity,
 			Unit:             unit,
 			PricePerUnit:     pricePerUnit,
 			Comment:          itemReq.Comment,
@@ -143,6 +182,7 @@ func (uc *InventoryUseCase) Create(ctx context.Context, req *CreateInventoryRequ
 		return nil, err
 	}
 
+	inventory.HideExpected()
 	return inventory, nil
 }
 
@@ -208,16 +248,31 @@ func (uc *InventoryUseCase) getStockData(ctx context.Context, warehouseID uuid.U
 				pricePerUnit = stock.PricePerUnit
 			}
 		}
-	case models.InventoryItemTypeProduct, models.InventoryItemTypeSemiFinished:
-		var productID *uuid.UUID
-		if req.Type == models.InventoryItemTypeProduct {
-			productID = req.ProductID
-		} else {
-			productID = req.SemiFinishedID
+	case models.InventoryItemTypeProduct:
+		if req.ProductID != nil {
+			// Товар с модификациями пересчитывается по модификациям
+			if product, prodErr := uc.warehouseRepo.GetProductByID(ctx, *req.ProductID); prodErr == nil && product != nil {
+				byVariants, err := soldByVariants(product, func() (int64, error) {
+					return uc.warehouseRepo.CountProductVariants(ctx, product.ID)
+				})
+				if err != nil {
+					return 0, "", 0, err
+				}
+				if byVariants {
+					return 0, "", 0, fmt.Errorf("%w: %s", ErrProductHasVariants, product.Name)
+				}
+			}
+			stock, stockErr := uc.warehouseRepo.GetStockByProductAndWarehouse(ctx, *req.ProductID, warehouseID)
+			if stockErr == nil && stock != nil {
+				quantity = stock.Quantity
+				unit = stock.Unit
+				pricePerUnit = stock.PricePerUnit
+			}
 		}
-
-		if productID != nil {
-			stock, stockErr := uc.warehouseRepo.GetStockByProductAndWarehouse(ctx, *productID, warehouseID)
+	case models.InventoryItemTypeSemiFinished:
+		// Остатки полуфабрикатов появляются после производства
+		if req.SemiFinishedID != nil {
+			stock, stockErr := uc.warehouseRepo.GetStockBySemiFinishedAndWarehouse(ctx, *req.SemiFinishedID, warehouseID)
 			if stockErr == nil && stock != nil {
 				quantity = stock.Quantity
 				unit = stock.Unit
@@ -232,6 +287,25 @@ func (uc *InventoryUseCase) getStockData(ctx context.Context, warehouseID uuid.U
 	return quantity, unit, pricePerUnit, nil
 }
 
+// convertActualQuantity переводит фактическое количество из единицы запроса в единицу остатка
+func (uc *InventoryUseCase) convertActualQuantity(ctx context.Context, req *CreateInventoryItemRequest, stockUnit string) (float64, error) {
+	if req.Unit == "" || models.NormalizeUnit(req.Unit) == models.NormalizeUnit(stockUnit) {
+		return req.ActualQuantity, nil
+	}
+	if req.IngredientID != nil {
+		ingredient, err := uc.warehouseRepo.GetIngredientByID(ctx, *req.IngredientID)
+		if err != nil || ingredient == nil {
+			return 0, errors.New("ingredient not found")
+		}
+		quantity, err := ingredient.ConvertQuantity(req.ActualQuantity, req.Unit, stockUnit)
+		if err != nil {
+			return 0, fmt.Errorf("ingredient %q: %w", ingredient.Name, err)
+		}
+		return quantity, nil
+	}
+	return models.ConvertUnit(req.ActualQuantity, req.Unit, stockUnit)
+}
+
 // UpdateItem обновляет элемент инвентаризации
 func (uc *InventoryUseCase) UpdateItem(ctx context.Context, inventoryID, itemID uuid.UUID, establishmentID uuid.UUID, req *UpdateInventoryItemRequest) error {
 	// Проверяем существование инвентаризации
@@ -267,8 +341,10 @@ func (uc *InventoryUseCase) UpdateItem(ctx context.Context, inventoryID, itemID
 	return uc.repo.UpdateItem(ctx, item)
 }
 
-// UpdateStatus обновляет статус инвентаризации
-func (uc *InventoryUseCase) UpdateStatus(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID, status models.InventoryStatus, completedBy *uuid.UUID) error {
+// UpdateStatus обновляет статус инвентаризации.
+// Завершение проводит корректировки остатков (см. complete); writeOffReasonID,
+// если указан, заменяет причину списания недостачи, выбранную при создании.
+func (uc *InventoryUseCase) UpdateStatus(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID, status models.InventoryStatus, completedBy *uuid.UUID, writeOffReasonID *uuid.UUID) error {
 	// Проверяем существование
 	inventory, err := uc.repo.GetByID(ctx, id, &establishmentID)
 	if err != nil || inventory == nil {
@@ -281,14 +357,188 @@ func (uc *InventoryUseCase) UpdateStatus(ctx context.Context, id uuid.UUID, esta
 		return errors.New("invalid status transition")
 	}
 
-	// При завершении вычисляем финальную дату
-	if status == models.InventoryStatusCompleted {
-		now := time.Now()
-		inventory.ActualDate = &now
-		inventory.CompletedBy = completedBy
+	if status != models.InventoryStatusCompleted {
+		return uc.repo.UpdateStatus(ctx, id, status)
+	}
+
+	if writeOffReasonID != nil {
+		if err := uc.checkWriteOffReason(ctx, writeOffReasonID, establishmentID); err != nil {
+			return err
+		}
+		inventory.WriteOffReasonID = writeOffReasonID
+	}
+	if err := uc.checkCountsReconciled(ctx, inventory); err != nil {
+		return err
+	}
+	if err := uc.complete(ctx, inventory, completedBy); err != nil {
+		return err
+	}
+	uc.stockAlerts.Notify(establishmentID)
+	return nil
+}
+
+// complete проводит инвентаризацию: остаток каждой позиции становится равным фактическому,
+// разница оформляется записями журнала (излишек — новой партией, недостача — расходом партий по FIFO),
+// а недостача за вычетом излишков попадает в ОПиУ по причине списания инвентаризации.
+//
+// Если инвентаризация проводится задним числом (ScheduledDate в прошлом), факт относится к этой дате:
+// ожидаемое количество берется из журнала на дату подсчета, а движения после нее сохраняются
+// поверх фактического количества. Все изменения записываются одной транзакцией.
+func (uc *InventoryUseCase) complete(ctx context.Context, inventory *models.Inventory, completedBy *uuid.UUID) error {
+	now := time.Now()
+	countAt := now
+	backdated := inventory.ScheduledDate != nil && inventory.ScheduledDate.Before(now)
+	if backdated {
+		countAt = *inventory.ScheduledDate
+	}
+
+	// Остатки на дату подсчета по журналу
+	var balances map[uuid.UUID]float64
+	if backdated {
+		rows, err := uc.warehouseRepo.GetStockLedgerBalances(ctx, inventory.EstablishmentID, &repositories.StockLedgerFilter{
+			WarehouseID: &inventory.WarehouseID,
+			EndDate:     &countAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get stock balances at count date: %w", err)
+		}
+		balances = make(map[uuid.UUID]float64, len(rows))
+		for _, b := range rows {
+			balances[b.StockID] = b.Quantity
+		}
+	}
+
+	completion := &repositories.InventoryCompletion{StockDeltas: make(map[uuid.UUID]float64)}
+	src := stockLedgerSource{Type: models.StockLedgerInventory, ID: inventory.ID, At: countAt}
+	doc := stockDocument{Type: models.StockConsumptionInventory, ID: inventory.ID, At: countAt}
+	var shortage, surplus float64
+
+	for i := range inventory.Items {
+		item := &inventory.Items[i]
+		if item.Type == models.InventoryItemTypeTechCard {
+			// Остатки техкарт не хранятся — корректировать нечего
+			continue
+		}
+
+		st, err := uc.findStock(ctx, inventory.WarehouseID, item)
+		if err != nil {
+			return err
+		}
+
+		expected := 0.0
+		if st != nil {
+			expected = st.Quantity
+			if backdated {
+				expected = balances[st.ID]
+			}
+			item.PricePerUnit = st.PricePerUnit
+		}
+		delta := models.RoundTo2(item.ActualQuantity - expected)
+		item.ExpectedQuantity = expected
+		item.Difference = delta
+		item.DifferenceValue = 0
+		if delta == 0 {
+			continue
+		}
+
+		if st == nil {
+			// Позиции не было на складе — заводим остаток из излишка
+			st = &models.Stock{
+				ID:             uuid.New(),
+				WarehouseID:    inventory.WarehouseID,
+				IngredientID:   item.IngredientID,
+				ProductID:      item.ProductID,
+				SemiFinishedID: item.SemiFinishedID,
+				Unit:           item.Unit,
+				PricePerUnit:   item.PricePerUnit,
+			}
+			completion.NewStocks = append(completion.NewStocks, st)
+		} else {
+			completion.StockDeltas[st.ID] += delta
+		}
+		st.Quantity = models.RoundTo2(st.Quantity + delta)
+
+		var cost float64
+		if delta < 0 {
+			lots, err := uc.warehouseRepo.GetOpenStockLots(ctx, st.WarehouseID, st.IngredientID, st.ProductID, st.SemiFinishedID)
+			if err != nil {
+				return fmt.Errorf("failed to get stock lots: %w", err)
+			}
+			plan := planStockLotConsumption(lots, st, -delta, doc)
+			completion.Lots = append(completion.Lots, plan.Lots...)
+			completion.Consumptions = append(completion.Consumptions, plan.Consumptions...)
+			cost = plan.Cost
+			item.DifferenceValue = -cost
+			shortage += cost
+		} else {
+			cost = models.RoundTo2(delta * st.PricePerUnit)
+			sourceID := inventory.ID
+			completion.NewLots = append(completion.NewLots, &models.StockLot{
+				WarehouseID:       st.WarehouseID,
+				IngredientID:      st.IngredientID,
+				ProductID:         st.ProductID,
+				SemiFinishedID:    st.SemiFinishedID,
+				SourceType:        models.StockLotSourceInventory,
+				SourceID:          &sourceID,
+				Quantity:          delta,
+				RemainingQuantity: delta,
+				Unit:              st.Unit,
+				UnitCost:          st.PricePerUnit,
+				ReceivedAt:        countAt,
+			})
+			item.DifferenceValue = cost
+			surplus += cost
+		}
+
+		if entry := newStockLedgerEntry(st, delta, cost, src); entry != nil {
+			// Остаток после записи — на момент подсчета, т.е. фактическое количество
+			entry.BalanceAfter = item.ActualQuantity
+			completion.LedgerEntries = append(completion.LedgerEntries, entry)
+		}
 	}
 
-	return uc.repo.UpdateStatus(ctx, id, status)
+	inventory.ActualDate = &now
+	inventory.CountDate = &countAt
+	inventory.CompletedBy = completedBy
+	inventory.ShortageAmount = models.RoundTo2(shortage)
+	inventory.SurplusAmount = models.RoundTo2(surplus)
+
+	if err := uc.repo.Complete(ctx, inventory, completion); err != nil {
+		return fmt.Errorf("failed to complete inventory: %w", err)
+	}
+	return nil
+}
+
+// findStock возвращает остаток позиции инвентаризации на складе (nil, если позиции на складе нет)
+func (uc *InventoryUseCase) findStock(ctx context.Context, warehouseID uuid.UUID, item *models.InventoryItem) (*models.Stock, error) {
+	var (
+		st  *models.Stock
+		err error
+	)
+	switch {
+	case item.IngredientID != nil:
+		st, err = uc.warehouseRepo.GetStockByIngredientAndWarehouse(ctx, *item.IngredientID, warehouseID)
+	case item.ProductID != nil:
+		st, err = uc.warehouseRepo.GetStockByProductAndWarehouse(ctx, *item.ProductID, warehouseID)
+	case item.SemiFinishedID != nil:
+		st, err = uc.warehouseRepo.GetStockBySemiFinishedAndWarehouse(ctx, *item.SemiFinishedID, warehouseID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock: %w", err)
+	}
+	return st, nil
+}
+
+// checkWriteOffReason проверяет, что причина списания принадлежит заведению
+func (uc *InventoryUseCase) checkWriteOffReason(ctx context.Context, reasonID *uuid.UUID, establishmentID uuid.UUID) error {
+	if reasonID == nil {
+		return nil
+	}
+	reason, err := uc.warehouseRepo.GetWriteOffReasonByID(ctx, *reasonID, establishmentID)
+	if err != nil || reason == nil {
+		return errors.New("write-off reason not found")
+	}
+	return nil
 }
 
 // isValidStatusTransition проверяет валидность перехода статусов
@@ -345,7 +595,8 @@ func (uc *InventoryUseCase) DeleteItem(ctx context.Context, inventoryID, itemID
 	return uc.repo.DeleteItem(ctx, itemID)
 }
 
-// GetStockSnapshot получает снапшот остатков на определенную дату
+// GetStockSnapshot получает снапшот остатков на определенную дату.
+// Остатки на прошедшую дату восстанавливаются по журналу движения остатков.
 func (uc *InventoryUseCase) GetStockSnapshot(ctx context.Context, warehouseID uuid.UUID, date *time.Time, establishmentID uuid.UUID) ([]*models.Stock, error) {
 	// Проверяем существование склада
 	_, err := uc.warehouseRepo.GetWarehouseByID(ctx, warehouseID, &establishmentID)
@@ -353,7 +604,10 @@ func (uc *InventoryUseCase) GetStockSnapshot(ctx context.Context, warehouseID uu
 		return nil, errors.New("warehouse not found")
 	}
 
-	return uc.repo.GetStockSnapshot(ctx, warehouseID, date)
+	if date == nil {
+		return uc.repo.GetStockSnapshot(ctx, warehouseID, nil)
+	}
+	return stockAsOf(ctx, uc.warehouseRepo, establishmentID, &warehouseID, *date)
 }
 
 // Update обновляет инвентаризацию
@@ -373,11 +627,27 @@ func (uc *InventoryUseCase) Update(ctx context.Context, id uuid.UUID, req *Updat
 	if req.ScheduledDate != nil {
 		inventory.ScheduledDate = req.ScheduledDate
 	}
+	if req.WriteOffReasonID != nil {
+		if err := uc.checkWriteOffReason(ctx, req.WriteOffReasonID, establishmentID); err != nil {
+			return nil, err
+		}
+		inventory.WriteOffReasonID = req.WriteOffReasonID
+	}
+	if req.CountMode != "" {
+		if req.CountMode != models.InventoryCountModeSingle && req.CountMode != models.InventoryCountModeMulti {
+			return nil, errors.New("invalid count mode")
+		}
+		inventory.CountMode = req.CountMode
+	}
+	if req.Blind != nil {
+		inventory.Blind = *req.Blind
+	}
 	inventory.Comment = req.Comment
 
 	if err := uc.repo.Update(ctx, inventory); err != nil {
 		return nil, err
 	}
 
+	inventory.HideExpected()
 	return inventory, nil
 }
@@ -0,0 +1,135 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// fakeInventoryRepository хранит одну инвентаризацию и применяет проведение к fakeWarehouseRepository
+type fakeInventoryRepository struct {
+	repositories.InventoryRepository
+	inventory  *models.Inventory
+	counts     []*models.InventoryCount
+	warehouse  *fakeWarehouseRepository
+	completion *repositories.InventoryCompletion
+}
+
+func (r *fakeInventoryRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Inventory, error) {
+	if r.inventory == nil || r.inventory.ID != id {
+		return nil, errors.New("record not found")
+	}
+	return r.inventory, nil
+}
+
+func (r *fakeInventoryRepository) Complete(ctx context.Context, inventory *models.Inventory, completion *repositories.InventoryCompletion) error {
+	r.completion = completion
+	for id, delta := range completion.StockDeltas {
+		r.warehouse.stocks[id].Quantity += delta
+	}
+	r.warehouse.ledger = append(r.warehouse.ledger, completion.LedgerEntries...)
+	inventory.Status = models.InventoryStatusCompleted
+	return nil
+}
+
+// newInventoryFixture заводит 10 кг по 100 с приходом в журнале 10 дней назад
+// и расходом 3 кг вчера (текущий остаток 7 кг)
+func newInventoryFixture(t *testing.T, scheduled *time.Time, actual float64) (*InventoryUseCase, *fakeInventoryRepository, *models.Stock) {
+	t.Helper()
+	warehouse := newFakeWarehouseRepository()
+	warehouseID := warehouse.addWarehouse()
+	flourID := warehouse.addIngredient(models.UnitKilogram)
+	st := warehouse.addStock(warehouseID, flourID, 7, 100)
+	warehouse.lots[0].RemainingQuantity = 7
+	now := time.Now()
+	warehouse.ledger = []*models.StockLedgerEntry{
+		{WarehouseID: warehouseID, StockID: st.ID, IngredientID: &flourID, MovementType: models.StockLedgerSupply, Quantity: 10, Amount: 1000, Unit: models.UnitKilogram, BalanceAfter: 10, OccurredAt: now.AddDate(0, 0, -10)},
+		{WarehouseID: warehouseID, StockID: st.ID, IngredientID: &flourID, MovementType: models.StockLedgerSale, Quantity: -3, Amount: -300, Unit: models.UnitKilogram, BalanceAfter: 7, OccurredAt: now.AddDate(0, 0, -1)},
+	}
+
+	inventory := &models.Inventory{
+		ID:            uuid.New(),
+		WarehouseID:   warehouseID,
+		Status:        models.InventoryStatusInProgress,
+		ScheduledDate: scheduled,
+		Items: []models.InventoryItem{
+			{ID: uuid.New(), Type: models.InventoryItemTypeIngredient, IngredientID: &flourID, ActualQuantity: actual, Unit: models.UnitKilogram},
+		},
+	}
+	repo := &fakeInventoryRepository{inventory: inventory, warehouse: warehouse}
+	return NewInventoryUseCase(repo, warehouse, nil), repo, st
+}
+
+func TestInventoryUseCase_Complete(t *testing.T) {
+	ctx := context.Background()
+	uc, repo, st := newInventoryFixture(t, nil, 9)
+
+	require.NoError(t, uc.UpdateStatus(ctx, repo.inventory.ID, uuid.New(), models.InventoryStatusCompleted, nil, nil))
+
+	item := repo.inventory.Items[0]
+	assert.InDelta(t, 7, item.ExpectedQuantity, 1e-9)
+	assert.InDelta(t, 2, item.Difference, 1e-9)
+	assert.InDelta(t, 9, repo.warehouse.stocks[st.ID].Quantity, 1e-9)
+	assert.InDelta(t, 200, repo.inventory.SurplusAmount, 1e-9)
+	require.Len(t, repo.completion.NewLots, 1)
+	assert.InDelta(t, 2, repo.completion.NewLots[0].RemainingQuantity, 1e-9)
+}
+
+func TestInventoryUseCase_Complete_Backdated(t *testing.T) {
+	ctx := context.Background()
+	countAt := time.Now().AddDate(0, 0, -5)
+	// На дату подсчета по журналу было 10 кг, насчитали 8; расход 3 кг после подсчета сохраняется
+	uc, repo, st := newInventoryFixture(t, &countAt, 8)
+
+	require.NoError(t, uc.UpdateStatus(ctx, repo.inventory.ID, uuid.New(), models.InventoryStatusCompleted, nil, nil))
+
+	item := repo.inventory.Items[0]
+	assert.InDelta(t, 10, item.ExpectedQuantity, 1e-9)
+	assert.InDelta(t, -2, item.Difference, 1e-9)
+	assert.InDelta(t, 5, repo.warehouse.stocks[st.ID].Quantity, 1e-9)
+	assert.InDelta(t, 200, repo.inventory.ShortageAmount, 1e-9)
+	require.NotNil(t, repo.inventory.CountDate)
+	assert.Equal(t, countAt, *repo.inventory.CountDate)
+
+	require.Len(t, repo.completion.LedgerEntries, 1)
+	entry := repo.completion.LedgerEntries[0]
+	assert.Equal(t, models.StockLedgerInventory, entry.MovementType)
+	assert.Equal(t, countAt, entry.OccurredAt)
+	assert.InDelta(t, 8, entry.BalanceAfter, 1e-9)
+	// Списание недостачи — по FIFO из партии
+	require.Len(t, repo.completion.Lots, 1)
+	assert.InDelta(t, 5, repo.completion.Lots[0].RemainingQuantity, 1e-9)
+
+	// В журнале корректировка встает между поставкой и продажей, остаток после продажи уменьшается на недостачу
+	warehouseUC := &WarehouseUseCase{repo: repo.warehouse}
+	movements, err := warehouseUC.GetMovements(ctx, uuid.New(), &repositories.StockLedgerFilter{})
+	require.NoError(t, err)
+	require.Len(t, movements, 3)
+	assert.Equal(t, []string{models.StockLedgerSupply, models.StockLedgerInventory, models.StockLedgerSale},
+		[]string{movements[0].MovementType, movements[1].MovementType, movements[2].MovementType})
+	assert.InDelta(t, 10, movements[0].BalanceAfter, 1e-9)
+	assert.InDelta(t, 8, movements[1].BalanceAfter, 1e-9)
+	assert.InDelta(t, 5, movements[2].BalanceAfter, 1e-9)
+
+	// Отбор только продаж не меняет остаток после записи
+	sale := models.StockLedgerSale
+	movements, err = warehouseUC.GetMovements(ctx, uuid.New(), &repositories.StockLedgerFilter{MovementType: &sale})
+	require.NoError(t, err)
+	require.Len(t, movements, 1)
+	assert.InDelta(t, 5, movements[0].BalanceAfter, 1e-9)
+}
+
+func TestInventoryUseCase_Complete_RejectsInvalidTransition(t *testing.T) {
+	uc, repo, _ := newInventoryFixture(t, nil, 7)
+	repo.inventory.Status = models.InventoryStatusDraft
+	assert.Error(t, uc.UpdateStatus(context.Background(), repo.inventory.ID, uuid.New(), models.InventoryStatusCompleted, nil, nil))
+	assert.Nil(t, repo.completion)
+}
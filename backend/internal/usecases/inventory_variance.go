@@ -0,0 +1,279 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// Типы позиций в отчете о расхождениях
+const (
+	VarianceItemIngredient = "ingredient"
+	VarianceItemProduct    = "product"
+)
+
+// VarianceReportRow теоретический и фактический расход позиции за период.
+// Количества приведены к единице позиции, расход — положительные числа.
+type VarianceReportRow struct {
+	ItemID                 uuid.UUID  `json:"item_id"`
+	ItemType               string     `json:"item_type"` // ingredient, product
+	Name                   string     `json:"name"`
+	Unit                   string     `json:"unit"`
+	Opening                float64    `json:"opening"` // Остаток на начало периода
+	OpeningAmount          float64    `json:"opening_amount"`
+	Supplies               float64    `json:"supplies"` // Поставки
+	SuppliesAmount         float64    `json:"supplies_amount"`
+	Transfers              float64    `json:"transfers"`         // Перемещения (сальдо)
+	Production             float64    `json:"production"`        // Расход на производство полуфабрикатов
+	TheoreticalUsage       float64    `json:"theoretical_usage"` // Списано по продажам (техкарты и товары)
+	TheoreticalUsageAmount float64    `json:"theoretical_usage_amount"`
+	WriteOffs              float64    `json:"write_offs"` // Зарегистрированные списания
+	WriteOffsAmount        float64    `json:"write_offs_amount"`
+	Other                  float64    `json:"other"`                     // Прочие движения (начальные остатки и т.п.)
+	ExpectedClosing        float64    `json:"expected_closing"`          // Ожидаемый остаток на конец периода
+	CountedClosing         *float64   `json:"counted_closing,omitempty"` // Фактический остаток (если за период была инвентаризация)
+	LastCountAt            *time.Time `json:"last_count_at,omitempty"`
+	Variance               float64    `json:"variance"`                   // Расхождение: факт − ожидание (недостача отрицательная)
+	VarianceAmount         float64    `json:"variance_amount"`            // Расхождение в деньгах
+	VariancePercent        *float64   `json:"variance_percent,omitempty"` // Расхождение в % от теоретического расхода
+}
+
+// VarianceReport отчет о расхождениях теоретического и фактического расхода
+type VarianceReport struct {
+	StartDate              time.Time            `json:"start_date"`
+	EndDate                time.Time            `json:"end_date"`
+	WarehouseID            *uuid.UUID           `json:"warehouse_id,omitempty"`
+	ItemType               string               `json:"item_type"`
+	Rows                   []*VarianceReportRow `json:"rows"`
+	TheoreticalUsageAmount float64              `json:"theoretical_usage_amount"`
+	WriteOffsAmount        float64              `json:"write_offs_amount"`
+	VarianceAmount         float64              `json:"variance_amount"`
+}
+
+// GetVarianceReport сравнивает теоретический расход позиций (продажи по техкартам, производство, списания)
+// с фактическим по результатам инвентаризаций за период. Данные берутся из журнала остатков:
+// поставки, списания, продажи и корректировки инвентаризаций уже проведены в него.
+// Расхождение есть только у позиций, которые пересчитывали в периоде: корректировка инвентаризации
+// равна разнице между подсчитанным и ожидаемым остатком.
+func (uc *InventoryUseCase) GetVarianceReport(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID, itemType string, start, end time.Time) (*VarianceReport, error) {
+	if itemType == "" {
+		itemType = VarianceItemIngredient
+	}
+	if itemType != VarianceItemIngredient && itemType != VarianceItemProduct {
+		return nil, fmt.Errorf("invalid item_type: %s", itemType)
+	}
+	if !end.After(start) {
+		return nil, errors.New("end_date must be after start_date")
+	}
+
+	itemID := func(ingredientID, productID *uuid.UUID) *uuid.UUID {
+		if itemType == VarianceItemProduct {
+			return productID
+		}
+		return ingredientID
+	}
+
+	rows := make(map[uuid.UUID]*VarianceReportRow)
+	row := func(id uuid.UUID, unit string) *VarianceReportRow {
+		r, ok := rows[id]
+		if !ok {
+			r = &VarianceReportRow{ItemID: id, ItemType: itemType, Unit: unit}
+			rows[id] = r
+		}
+		return r
+	}
+	// Остатки одной позиции на разных складах могут вестись в разных единицах
+	toRowUnit := func(r *VarianceReportRow, quantity float64, unit string) (float64, error) {
+		factor, err := stockUnitFactor(unit, r.Unit)
+		if err != nil {
+			return 0, fmt.Errorf("failed to convert %s to %s: %w", unit, r.Unit, err)
+		}
+		return quantity * factor, nil
+	}
+
+	before := start.Add(-time.Nanosecond)
+	balances, err := uc.warehouseRepo.GetStockLedgerBalances(ctx, establishmentID, &repositories.StockLedgerFilter{
+		WarehouseID: warehouseID,
+		EndDate:     &before,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get opening balances: %w", err)
+	}
+	for _, b := range balances {
+		id := itemID(b.IngredientID, b.ProductID)
+		if id == nil {
+			continue
+		}
+		r := row(*id, b.Unit)
+		q, err := toRowUnit(r, b.Quantity, b.Unit)
+		if err != nil {
+			return nil, err
+		}
+		r.Opening += q
+		r.OpeningAmount += b.Amount
+	}
+
+	entries, err := uc.warehouseRepo.GetStockLedger(ctx, establishmentID, &repositories.StockLedgerFilter{
+		WarehouseID: warehouseID,
+		StartDate:   &start,
+		EndDate:     &end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock ledger: %w", err)
+	}
+	for _, e := range entries {
+		id := itemID(e.IngredientID, e.ProductID)
+		if id == nil {
+			continue
+		}
+		r := row(*id, e.Unit)
+		if r.Name == "" {
+			r.Name = stockLedgerItemName(e)
+		}
+		q, err := toRowUnit(r, e.Quantity, e.Unit)
+		if err != nil {
+			return nil, err
+		}
+		switch e.MovementType {
+		case models.StockLedgerSupply:
+			r.Supplies += q
+			r.SuppliesAmount += e.Amount
+		case models.StockLedgerSale:
+			r.TheoreticalUsage -= q
+			r.TheoreticalUsageAmount -= e.Amount
+		case models.StockLedgerWriteOff:
+			r.WriteOffs -= q
+			r.WriteOffsAmount -= e.Amount
+		case models.StockLedgerTransferIn, models.StockLedgerTransferOut:
+			r.Transfers += q
+		case models.StockLedgerProductionOut:
+			r.Production -= q
+		case models.StockLedgerInventory:
+			r.Variance += q
+			r.VarianceAmount += e.Amount
+			if r.LastCountAt == nil || e.OccurredAt.After(*r.LastCountAt) {
+				at := e.OccurredAt
+				r.LastCountAt = &at
+			}
+		default:
+			r.Other += q
+		}
+	}
+
+	// Инвентаризация без расхождений не попадает в журнал — такие позиции тоже считаются пересчитанными
+	counted, err := uc.countedItems(ctx, establishmentID, warehouseID, itemType, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VarianceReport{
+		StartDate:   start,
+		EndDate:     end,
+		WarehouseID: warehouseID,
+		ItemType:    itemType,
+		Rows:        make([]*VarianceReportRow, 0, len(rows)),
+	}
+	for id, r := range rows {
+		if countedAt, ok := counted[id]; ok && r.LastCountAt == nil {
+			r.LastCountAt = &countedAt
+		}
+		r.ExpectedClosing = r.Opening + r.Supplies + r.Transfers + r.Other - r.Production - r.TheoreticalUsage - r.WriteOffs
+		if r.LastCountAt != nil {
+			closing := models.RoundTo2(r.ExpectedClosing + r.Variance)
+			r.CountedClosing = &closing
+		}
+		if usage := r.TheoreticalUsage + r.Production; usage > 0 && r.Variance != 0 {
+			percent := models.RoundTo2(r.Variance / usage * 100)
+			r.VariancePercent = &percent
+		}
+
+		r.Opening = models.RoundTo2(r.Opening)
+		r.OpeningAmount = models.RoundTo2(r.OpeningAmount)
+		r.Supplies = models.RoundTo2(r.Supplies)
+		r.SuppliesAmount = models.RoundTo2(r.SuppliesAmount)
+		r.Transfers = models.RoundTo2(r.Transfers)
+		r.Production = models.RoundTo2(r.Production)
+		r.TheoreticalUsage = models.RoundTo2(r.TheoreticalUsage)
+		r.TheoreticalUsageAmount = models.RoundTo2(r.TheoreticalUsageAmount)
+		r.WriteOffs = models.RoundTo2(r.WriteOffs)
+		r.WriteOffsAmount = models.RoundTo2(r.WriteOffsAmount)
+		r.Other = models.RoundTo2(r.Other)
+		r.ExpectedClosing = models.RoundTo2(r.ExpectedClosing)
+		r.Variance = models.RoundTo2(r.Variance)
+		r.VarianceAmount = models.RoundTo2(r.VarianceAmount)
+
+		if r.Name == "" {
+			r.Name = uc.varianceItemName(ctx, itemType, id)
+		}
+		report.TheoreticalUsageAmount += r.TheoreticalUsageAmount
+		report.WriteOffsAmount += r.WriteOffsAmount
+		report.VarianceAmount += r.VarianceAmount
+		report.Rows = append(report.Rows, r)
+	}
+	report.TheoreticalUsageAmount = models.RoundTo2(report.TheoreticalUsageAmount)
+	report.WriteOffsAmount = models.RoundTo2(report.WriteOffsAmount)
+	report.VarianceAmount = models.RoundTo2(report.VarianceAmount)
+
+	// Сначала самые крупные недостачи
+	sort.Slice(report.Rows, func(i, j int) bool {
+		if report.Rows[i].VarianceAmount != report.Rows[j].VarianceAmount {
+			return report.Rows[i].VarianceAmount < report.Rows[j].VarianceAmount
+		}
+		return report.Rows[i].Name < report.Rows[j].Name
+	})
+	return report, nil
+}
+
+// countedItems возвращает позиции, пересчитанные завершенными инвентаризациями периода, и дату последнего подсчета.
+// Дата подсчета — CountDate: на нее проводятся корректировки остатков.
+func (uc *InventoryUseCase) countedItems(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID, itemType string, start, end time.Time) (map[uuid.UUID]time.Time, error) {
+	status := models.InventoryStatusCompleted
+	inventories, err := uc.repo.List(ctx, &repositories.InventoryFilter{
+		EstablishmentID: &establishmentID,
+		WarehouseID:     warehouseID,
+		Status:          &status,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inventories: %w", err)
+	}
+
+	counted := make(map[uuid.UUID]time.Time)
+	for _, inv := range inventories {
+		if inv.CountDate == nil || inv.CountDate.Before(start) || inv.CountDate.After(end) {
+			continue
+		}
+		for _, item := range inv.Items {
+			id := item.IngredientID
+			if itemType == VarianceItemProduct {
+				id = item.ProductID
+			}
+			if id == nil {
+				continue
+			}
+			if at, ok := counted[*id]; !ok || inv.CountDate.After(at) {
+				counted[*id] = *inv.CountDate
+			}
+		}
+	}
+	return counted, nil
+}
+
+func (uc *InventoryUseCase) varianceItemName(ctx context.Context, itemType string, id uuid.UUID) string {
+	if itemType == VarianceItemProduct {
+		if p, err := uc.warehouseRepo.GetProductByID(ctx, id); err == nil && p != nil {
+			return p.Name
+		}
+		return ""
+	}
+	if ing, err := uc.warehouseRepo.GetIngredientByID(ctx, id); err == nil && ing != nil {
+		return ing.Name
+	}
+	return ""
+}
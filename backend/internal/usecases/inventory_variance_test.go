@@ -0,0 +1,103 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+func (r *fakeInventoryRepository) List(ctx context.Context, filter *repositories.InventoryFilter) ([]*models.Inventory, error) {
+	if r.inventory == nil {
+		return nil, nil
+	}
+	return []*models.Inventory{r.inventory}, nil
+}
+
+func TestInventoryUseCase_GetVarianceReport(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0).Add(-time.Second)
+	day := func(d int) time.Time { return start.AddDate(0, 0, d-1).Add(12 * time.Hour) }
+
+	warehouse := newFakeWarehouseRepository()
+	warehouseID := warehouse.addWarehouse()
+	flourID := warehouse.addIngredient(models.UnitKilogram)
+	sugarID := warehouse.addIngredient(models.UnitKilogram)
+	saltID := warehouse.addIngredient(models.UnitKilogram)
+	warehouse.ingredients[sugarID].Name = "Сахар"
+	warehouse.ingredients[saltID].Name = "Соль"
+	stockIDs := map[uuid.UUID]uuid.UUID{flourID: uuid.New(), sugarID: uuid.New(), saltID: uuid.New()}
+	move := func(ingredientID uuid.UUID, movement string, quantity, amount float64, at time.Time) {
+		id := ingredientID
+		warehouse.ledger = append(warehouse.ledger, &models.StockLedgerEntry{
+			WarehouseID: warehouseID, StockID: stockIDs[id], IngredientID: &id, MovementType: movement,
+			Quantity: quantity, Amount: amount, Unit: models.UnitKilogram, OccurredAt: at,
+		})
+	}
+	// Мука: 10 кг на начало, поставка 5, продажи 6, списание 1, инвентаризация нашла недостачу 0.5
+	move(flourID, models.StockLedgerSupply, 10, 1000, start.AddDate(0, 0, -3))
+	move(flourID, models.StockLedgerSupply, 5, 500, day(2))
+	move(flourID, models.StockLedgerSale, -6, -600, day(10))
+	move(flourID, models.StockLedgerWriteOff, -1, -100, day(12))
+	move(flourID, models.StockLedgerInventory, -0.5, -50, day(20))
+	// Сахар не пересчитывали; соль пересчитали без расхождений
+	move(sugarID, models.StockLedgerSupply, 2, 200, start.AddDate(0, 0, -3))
+	move(sugarID, models.StockLedgerSale, -1, -100, day(5))
+	move(saltID, models.StockLedgerSupply, 1, 30, start.AddDate(0, 0, -3))
+	// Движение после периода не учитывается
+	move(flourID, models.StockLedgerSale, -3, -300, end.Add(time.Hour))
+
+	countAt := day(20)
+	inventories := &fakeInventoryRepository{inventory: &models.Inventory{
+		ID: uuid.New(), WarehouseID: warehouseID, Status: models.InventoryStatusCompleted, CountDate: &countAt,
+		Items: []models.InventoryItem{{IngredientID: &flourID}, {IngredientID: &saltID}},
+	}}
+	uc := NewInventoryUseCase(inventories, warehouse, nil)
+
+	report, err := uc.GetVarianceReport(ctx, uuid.New(), &warehouseID, "", start, end)
+	require.NoError(t, err)
+	require.Len(t, report.Rows, 3)
+
+	flour := report.Rows[0]
+	assert.Equal(t, flourID, flour.ItemID)
+	assert.Equal(t, "Мука", flour.Name)
+	assert.InDelta(t, 10, flour.Opening, 1e-9)
+	assert.InDelta(t, 5, flour.Supplies, 1e-9)
+	assert.InDelta(t, 6, flour.TheoreticalUsage, 1e-9)
+	assert.InDelta(t, 1, flour.WriteOffs, 1e-9)
+	assert.InDelta(t, 8, flour.ExpectedClosing, 1e-9)
+	require.NotNil(t, flour.CountedClosing)
+	assert.InDelta(t, 7.5, *flour.CountedClosing, 1e-9)
+	assert.InDelta(t, -0.5, flour.Variance, 1e-9)
+	assert.InDelta(t, -50, flour.VarianceAmount, 1e-9)
+	require.NotNil(t, flour.VariancePercent)
+	assert.InDelta(t, -8.33, *flour.VariancePercent, 1e-9)
+
+	rows := map[uuid.UUID]*VarianceReportRow{}
+	for _, r := range report.Rows {
+		rows[r.ItemID] = r
+	}
+	assert.Nil(t, rows[sugarID].CountedClosing)
+	assert.Nil(t, rows[sugarID].VariancePercent)
+	require.NotNil(t, rows[saltID].CountedClosing)
+	assert.InDelta(t, 1, *rows[saltID].CountedClosing, 1e-9)
+	assert.Zero(t, rows[saltID].Variance)
+
+	assert.InDelta(t, 700, report.TheoreticalUsageAmount, 1e-9)
+	assert.InDelta(t, 100, report.WriteOffsAmount, 1e-9)
+	assert.InDelta(t, -50, report.VarianceAmount, 1e-9)
+
+	t.Run("validates parameters", func(t *testing.T) {
+		_, err := uc.GetVarianceReport(ctx, uuid.New(), nil, "tech_card", start, end)
+		assert.Error(t, err)
+		_, err = uc.GetVarianceReport(ctx, uuid.New(), nil, "", end, start)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,201 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// menuEngineeringKey позиция меню в отчете: тех-карта или товар
+type menuEngineeringKey struct {
+	itemType string
+	id       uuid.UUID
+}
+
+// GetMenuEngineering строит отчет меню-инжиниринга за период: классифицирует проданные тех-карты и товары
+// каждой категории по доле продаж и маржинальному доходу с единицы (средняя цена продажи минус себестоимость).
+// Себестоимость берется из истории на конец периода, для позиций без истории — текущая.
+func (uc *StatisticsUseCase) GetMenuEngineering(ctx context.Context, establishmentID uuid.UUID, startDate, endDate time.Time, categoryID *uuid.UUID) (*models.MenuEngineeringReport, error) {
+	orders, err := uc.orderRepo.ListByEstablishmentIDAndDateRange(ctx, establishmentID, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders: %w", err)
+	}
+
+	categories, err := uc.categoryRepo.List(ctx, &repositories.CategoryFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	categoryNames := make(map[uuid.UUID]string, len(categories))
+	for _, cat := range categories {
+		categoryNames[cat.ID] = cat.Name
+	}
+
+	historyCosts := make(map[menuEngineeringKey]float64)
+	if uc.costHistoryRepo != nil {
+		entries, err := uc.costHistoryRepo.GetLatestEntries(ctx, establishmentID, endDate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cost history: %w", err)
+		}
+		for _, e := range entries {
+			switch {
+			case e.TechCardID != nil:
+				historyCosts[menuEngineeringKey{MenuItemTypeTechCard, *e.TechCardID}] = e.CostPrice
+			case e.ProductID != nil:
+				historyCosts[menuEngineeringKey{MenuItemTypeProduct, *e.ProductID}] = e.CostPrice
+			}
+		}
+	}
+
+	var keys []menuEngineeringKey
+	items := make(map[menuEngineeringKey]*models.MenuEngineeringItem)
+	for _, order := range orders {
+		if order.Status != "paid" && order.Status != "completed" {
+			continue
+		}
+		for _, orderItem := range order.Items {
+			var key menuEngineeringKey
+			var name string
+			var itemCategoryID uuid.UUID
+			var costPrice float64
+			switch {
+			case orderItem.TechCardID != nil && orderItem.TechCard != nil:
+				key = menuEngineeringKey{MenuItemTypeTechCard, *orderItem.TechCardID}
+				name, itemCategoryID, costPrice = orderItem.TechCard.Name, orderItem.TechCard.CategoryID, orderItem.TechCard.CostPrice
+			case orderItem.ProductID != nil && orderItem.Product != nil:
+				key = menuEngineeringKey{MenuItemTypeProduct, *orderItem.ProductID}
+				name, itemCategoryID, costPrice = orderItem.Product.Name, orderItem.Product.CategoryID, orderItem.Product.CostPrice
+			default:
+				continue
+			}
+			if categoryID != nil && itemCategoryID != *categoryID {
+				continue
+			}
+
+			item, ok := items[key]
+			if !ok {
+				if cost, found := historyCosts[key]; found {
+					costPrice = cost
+				}
+				item = &models.MenuEngineeringItem{
+					ItemID:       key.id,
+					ItemType:     key.itemType,
+					Name:         name,
+					CategoryID:   itemCategoryID,
+					CategoryName: categoryNames[itemCategoryID],
+					CostPrice:    models.RoundTo2(costPrice),
+				}
+				items[key] = item
+				keys = append(keys, key)
+			}
+			item.QuantitySold += orderItem.Quantity
+			item.Revenue += orderItem.TotalPrice
+		}
+	}
+
+	report := &models.MenuEngineeringReport{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Items:     make([]models.MenuEngineeringItem, 0, len(keys)),
+	}
+	for _, key := range keys {
+		item := items[key]
+		if item.QuantitySold <= 0 {
+			continue
+		}
+		item.AveragePrice = models.RoundTo2(item.Revenue / float64(item.QuantitySold))
+		item.UnitMargin = models.RoundTo2(item.AveragePrice - item.CostPrice)
+		item.TotalMargin = models.RoundTo2(item.UnitMargin * float64(item.QuantitySold))
+		item.FoodCostPercent = models.FoodCostPercent(item.CostPrice, item.AveragePrice)
+		item.Revenue = models.RoundTo2(item.Revenue)
+		report.Items = append(report.Items, *item)
+	}
+
+	// Категории по алфавиту, внутри категории — по маржинальному доходу за период
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		a, b := report.Items[i], report.Items[j]
+		if a.CategoryName != b.CategoryName {
+			return a.CategoryName < b.CategoryName
+		}
+		return a.TotalMargin > b.TotalMargin
+	})
+	report.Categories = models.ClassifyMenuEngineering(report.Items)
+
+	return report, nil
+}
+
+// CompareMenuEngineering строит отчеты за два периода и показывает, как изменились класс,
+// продажи и маржинальность каждой позиции
+func (uc *StatisticsUseCase) CompareMenuEngineering(ctx context.Context, establishmentID uuid.UUID, startDate, endDate, prevStartDate, prevEndDate time.Time, categoryID *uuid.UUID) (*models.MenuEngineeringComparison, error) {
+	current, err := uc.GetMenuEngineering(ctx, establishmentID, startDate, endDate, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := uc.GetMenuEngineering(ctx, establishmentID, prevStartDate, prevEndDate, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	prevItems := make(map[menuEngineeringKey]models.MenuEngineeringItem, len(previous.Items))
+	for _, item := range previous.Items {
+		prevItems[menuEngineeringKey{item.ItemType, item.ItemID}] = item
+	}
+
+	comparison := &models.MenuEngineeringComparison{
+		Current:  current,
+		Previous: previous,
+		Changes:  make([]models.MenuEngineeringChange, 0, len(current.Items)),
+	}
+	seen := make(map[menuEngineeringKey]bool, len(current.Items))
+	for _, cur := range current.Items {
+		key := menuEngineeringKey{cur.ItemType, cur.ItemID}
+		seen[key] = true
+		change := models.MenuEngineeringChange{
+			ItemID:           cur.ItemID,
+			ItemType:         cur.ItemType,
+			Name:             cur.Name,
+			CategoryName:     cur.CategoryName,
+			CurrentClass:     cur.Class,
+			QuantityDelta:    cur.QuantitySold,
+			UnitMarginDelta:  cur.UnitMargin,
+			SalesMixDelta:    cur.SalesMixPercent,
+			TotalMarginDelta: cur.TotalMargin,
+			Recommendation:   cur.Recommendation,
+		}
+		if prev, ok := prevItems[key]; ok {
+			change.PreviousClass = prev.Class
+			change.QuantityDelta -= prev.QuantitySold
+			change.UnitMarginDelta = models.RoundTo2(cur.UnitMargin - prev.UnitMargin)
+			change.SalesMixDelta = models.RoundTo2(cur.SalesMixPercent - prev.SalesMixPercent)
+			change.TotalMarginDelta = models.RoundTo2(cur.TotalMargin - prev.TotalMargin)
+		}
+		change.ClassChanged = change.PreviousClass != change.CurrentClass
+		comparison.Changes = append(comparison.Changes, change)
+	}
+
+	// Позиции, которые продавались только в прошлом периоде
+	for _, prev := range previous.Items {
+		if seen[menuEngineeringKey{prev.ItemType, prev.ItemID}] {
+			continue
+		}
+		comparison.Changes = append(comparison.Changes, models.MenuEngineeringChange{
+			ItemID:           prev.ItemID,
+			ItemType:         prev.ItemType,
+			Name:             prev.Name,
+			CategoryName:     prev.CategoryName,
+			PreviousClass:    prev.Class,
+			ClassChanged:     true,
+			QuantityDelta:    -prev.QuantitySold,
+			UnitMarginDelta:  -prev.UnitMargin,
+			SalesMixDelta:    -prev.SalesMixPercent,
+			TotalMarginDelta: -prev.TotalMargin,
+		})
+	}
+
+	return comparison, nil
+}
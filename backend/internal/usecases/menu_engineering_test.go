@@ -0,0 +1,164 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// fakeStatisticsOrderRepository отдает заказы за период по дате создания
+type fakeStatisticsOrderRepository struct {
+	repositories.OrderRepository
+	orders []*models.Order
+}
+
+func (r *fakeStatisticsOrderRepository) ListByEstablishmentIDAndDateRange(ctx context.Context, establishmentID uuid.UUID, startDate, endDate time.Time) ([]*models.Order, error) {
+	var orders []*models.Order
+	for _, o := range r.orders {
+		if o.EstablishmentID == establishmentID && !o.CreatedAt.Before(startDate) && !o.CreatedAt.After(endDate) {
+			orders = append(orders, o)
+		}
+	}
+	return orders, nil
+}
+
+func TestStatisticsUseCase_MenuEngineering(t *testing.T) {
+	ctx := context.Background()
+	establishmentID := uuid.New()
+	hot := &models.Category{ID: uuid.New(), EstablishmentID: establishmentID, Name: "Горячее"}
+	drinks := &models.Category{ID: uuid.New(), EstablishmentID: establishmentID, Name: "Напитки"}
+
+	borscht := &models.TechCard{ID: uuid.New(), Name: "Борщ", CategoryID: hot.ID, CostPrice: 100, Price: 300}
+	dumplings := &models.TechCard{ID: uuid.New(), Name: "Пельмени", CategoryID: hot.ID, CostPrice: 150, Price: 250}
+	soup := &models.TechCard{ID: uuid.New(), Name: "Суп дня", CategoryID: hot.ID, CostPrice: 100, Price: 400}
+	salad := &models.TechCard{ID: uuid.New(), Name: "Салат", CategoryID: hot.ID, CostPrice: 150, Price: 200}
+	cola := &models.Product{ID: uuid.New(), Name: "Кола", CategoryID: drinks.ID, CostPrice: 40, Price: 100}
+	compote := &models.Product{ID: uuid.New(), Name: "Компот", CategoryID: drinks.ID, CostPrice: 20, Price: 80}
+
+	techCardItem := func(tc *models.TechCard, qty int, total float64) models.OrderItem {
+		return models.OrderItem{TechCardID: &tc.ID, TechCard: tc, Quantity: qty, TotalPrice: total}
+	}
+	productItem := func(p *models.Product, qty int, total float64) models.OrderItem {
+		return models.OrderItem{ProductID: &p.ID, Product: p, Quantity: qty, TotalPrice: total}
+	}
+
+	endDate := time.Date(2026, 10, 8, 0, 0, 0, 0, time.UTC)
+	startDate := endDate.AddDate(0, 0, -7)
+	prevEndDate := startDate.Add(-time.Second)
+	prevStartDate := startDate.AddDate(0, 0, -7)
+	order := func(at time.Time, status string, items ...models.OrderItem) *models.Order {
+		return &models.Order{ID: uuid.New(), EstablishmentID: establishmentID, Status: status, CreatedAt: at, Items: items}
+	}
+
+	orders := &fakeStatisticsOrderRepository{orders: []*models.Order{
+		// Текущий период
+		order(startDate.AddDate(0, 0, 1), "paid",
+			techCardItem(borscht, 6, 1800), techCardItem(dumplings, 10, 2500), techCardItem(soup, 1, 400)),
+		order(startDate.AddDate(0, 0, 2), "completed",
+			techCardItem(borscht, 4, 1200), techCardItem(dumplings, 20, 5000), techCardItem(soup, 1, 350),
+			techCardItem(salad, 1, 200), productItem(cola, 20, 2000)),
+		// Неоплаченные и отмененные заказы в отчет не попадают
+		order(startDate.AddDate(0, 0, 3), "confirmed", techCardItem(borscht, 5, 1500)),
+		order(startDate.AddDate(0, 0, 3), "cancelled", techCardItem(salad, 50, 10000)),
+		// Прошлый период
+		order(prevStartDate.AddDate(0, 0, 1), "paid",
+			techCardItem(borscht, 10, 3000), techCardItem(salad, 10, 2000), productItem(compote, 5, 400)),
+	}}
+	history := &fakeCostHistoryRepository{entries: []*models.MenuItemCost{
+		// Себестоимость борща выросла в текущем периоде, запись после его конца не учитывается
+		{EstablishmentID: establishmentID, TechCardID: &borscht.ID, CostPrice: 120, RecordedAt: startDate.AddDate(0, 0, 1)},
+		{EstablishmentID: establishmentID, TechCardID: &borscht.ID, CostPrice: 200, RecordedAt: endDate.AddDate(0, 0, 1)},
+	}}
+	categories := &fakeCategoryRepository{items: []*models.Category{drinks, hot}}
+	uc := NewStatisticsUseCase(orders, nil, categories, nil, nil, nil, nil, nil, history, nil)
+
+	report, err := uc.GetMenuEngineering(ctx, establishmentID, startDate, endDate, nil)
+	require.NoError(t, err)
+
+	// Категории по алфавиту, внутри — по маржинальному доходу
+	var names []string
+	items := make(map[string]models.MenuEngineeringItem)
+	for _, item := range report.Items {
+		names = append(names, item.Name)
+		items[item.Name] = item
+	}
+	assert.Equal(t, []string{"Пельмени", "Борщ", "Суп дня", "Салат", "Кола"}, names)
+
+	b := items["Борщ"]
+	assert.Equal(t, 10, b.QuantitySold)
+	assert.InDelta(t, 3000, b.Revenue, 1e-9)
+	assert.InDelta(t, 120, b.CostPrice, 1e-9)
+	assert.InDelta(t, 180, b.UnitMargin, 1e-9)
+	assert.InDelta(t, 1800, b.TotalMargin, 1e-9)
+	assert.InDelta(t, 40, b.FoodCostPercent, 1e-9)
+	assert.Equal(t, "Горячее", b.CategoryName)
+
+	// Средняя цена учитывает скидки: (400 + 350) / 2
+	s := items["Суп дня"]
+	assert.Equal(t, 2, s.QuantitySold)
+	assert.InDelta(t, 375, s.AveragePrice, 1e-9)
+	assert.InDelta(t, 275, s.UnitMargin, 1e-9)
+
+	// Порог популярности: 100% / 4 * 0.7 = 17.5%, порог маржи: 5400 / 43
+	assert.Equal(t, models.MenuEngineeringStar, b.Class)
+	assert.Equal(t, models.MenuEngineeringPlowhorse, items["Пельмени"].Class)
+	assert.Equal(t, models.MenuEngineeringPuzzle, s.Class)
+	assert.Equal(t, models.MenuEngineeringDog, items["Салат"].Class)
+	assert.Equal(t, models.MenuEngineeringStar, items["Кола"].Class)
+	assert.Equal(t, models.MenuEngineeringDog.Recommendation(), items["Салат"].Recommendation)
+
+	require.Len(t, report.Categories, 2)
+	hotStats := report.Categories[0]
+	assert.Equal(t, hot.ID, hotStats.CategoryID)
+	assert.Equal(t, 43, hotStats.QuantitySold)
+	assert.InDelta(t, 11450, hotStats.Revenue, 1e-9)
+	assert.InDelta(t, 5400, hotStats.TotalMargin, 1e-9)
+	assert.InDelta(t, 17.5, hotStats.PopularityThreshold, 1e-9)
+	assert.Equal(t, 1, hotStats.Stars)
+	assert.Equal(t, 1, hotStats.Plowhorses)
+	assert.Equal(t, 1, hotStats.Puzzles)
+	assert.Equal(t, 1, hotStats.Dogs)
+
+	// Фильтр по категории
+	report, err = uc.GetMenuEngineering(ctx, establishmentID, startDate, endDate, &drinks.ID)
+	require.NoError(t, err)
+	require.Len(t, report.Items, 1)
+	assert.Equal(t, cola.ID, report.Items[0].ItemID)
+	assert.Equal(t, MenuItemTypeProduct, report.Items[0].ItemType)
+
+	// Сравнение с прошлым периодом: там борщ шел по текущей себестоимости 100
+	comparison, err := uc.CompareMenuEngineering(ctx, establishmentID, startDate, endDate, prevStartDate, prevEndDate, nil)
+	require.NoError(t, err)
+	changes := make(map[string]models.MenuEngineeringChange)
+	for _, ch := range comparison.Changes {
+		changes[ch.Name] = ch
+	}
+	require.Len(t, changes, 6)
+
+	assert.Equal(t, models.MenuEngineeringStar, changes["Борщ"].PreviousClass)
+	assert.False(t, changes["Борщ"].ClassChanged)
+	assert.Equal(t, 0, changes["Борщ"].QuantityDelta)
+	assert.InDelta(t, -20, changes["Борщ"].UnitMarginDelta, 1e-9)
+	assert.InDelta(t, -200, changes["Борщ"].TotalMarginDelta, 1e-9)
+
+	assert.Equal(t, models.MenuEngineeringPlowhorse, changes["Салат"].PreviousClass)
+	assert.Equal(t, models.MenuEngineeringDog, changes["Салат"].CurrentClass)
+	assert.True(t, changes["Салат"].ClassChanged)
+	assert.Equal(t, -9, changes["Салат"].QuantityDelta)
+
+	// Новая позиция и позиция, которая продавалась только в прошлом периоде
+	assert.True(t, changes["Пельмени"].ClassChanged)
+	assert.Empty(t, changes["Пельмени"].PreviousClass)
+	assert.Equal(t, 30, changes["Пельмени"].QuantityDelta)
+	assert.Empty(t, changes["Компот"].CurrentClass)
+	assert.Equal(t, models.MenuEngineeringStar, changes["Компот"].PreviousClass)
+	assert.Equal(t, -5, changes["Компот"].QuantityDelta)
+	assert.InDelta(t, -300, changes["Компот"].TotalMarginDelta, 1e-9)
+}
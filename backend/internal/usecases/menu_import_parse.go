@@ -0,0 +1,483 @@
+package usecases
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/arc/backend/pkg/xlsx"
+)
+
+// Форматы файла импорта и выгрузки меню
+const (
+	MenuExchangeFormatCSV  = "csv"
+	MenuExchangeFormatXLSX = "xlsx"
+	MenuExchangeFormatJSON = "json"
+)
+
+// Типы записей файла меню (колонка type в CSV и XLSX)
+const (
+	MenuExchangeTypeCategory     = "category"
+	MenuExchangeTypeIngredient   = "ingredient"
+	MenuExchangeTypeSemiFinished = "semi_finished"
+	MenuExchangeTypeProduct      = "product"
+	MenuExchangeTypeTechCard     = "tech_card"
+	MenuExchangeTypeLine         = "line" // Позиция рецептуры тех-карты или полуфабриката
+)
+
+// MenuExchangeDocument меню заведения в формате импорта и выгрузки. В JSON разделы хранятся как есть,
+// в CSV и XLSX — одной таблицей, где тип записи задается колонкой type.
+// Пустые (nil) поля при импорте не меняют существующую позицию.
+type MenuExchangeDocument struct {
+	Categories   []MenuExchangeItem `json:"categories"`
+	Ingredients  []MenuExchangeItem `json:"ingredients"`
+	SemiFinished []MenuExchangeItem `json:"semi_finished"`
+	Products     []MenuExchangeItem `json:"products"`
+	TechCards    []MenuExchangeItem `json:"tech_cards"`
+}
+
+// MenuExchangeItem категория, ингредиент, полуфабрикат, товар или тех-карта
+type MenuExchangeItem struct {
+	ExternalCode string  `json:"external_code,omitempty"` // Код во внешней системе; сопоставляется раньше названия
+	Name         string  `json:"name"`
+	CategoryType string  `json:"category_type,omitempty"` // Только для категорий: product, tech_card, semi_finished, ingredient
+	Category     string  `json:"category,omitempty"`      // Название категории позиции
+	Parent       string  `json:"parent,omitempty"`        // Код или название родительского товара (для модификаций)
+	Workshop     *string `json:"workshop,omitempty"`      // Название цеха
+	Unit         string  `json:"unit,omitempty"`
+	Description  *string `json:"description,omitempty"`
+	Barcode      *string `json:"barcode,omitempty"`
+
+	Price      *float64 `json:"price,omitempty"`
+	CostPrice  *float64 `json:"cost_price,omitempty"`
+	Markup     *float64 `json:"markup,omitempty"`
+	IsWeighted *bool    `json:"is_weighted,omitempty"`
+	Active     *bool    `json:"active,omitempty"`
+
+	// Ингредиенты: срок годности, потери и пищевая ценность на 100 г
+	ShelfLifeDays *int     `json:"shelf_life_days,omitempty"`
+	LossCleaning  *float64 `json:"loss_cleaning,omitempty"`
+	LossBoiling   *float64 `json:"loss_boiling,omitempty"`
+	LossFrying    *float64 `json:"loss_frying,omitempty"`
+	LossStewing   *float64 `json:"loss_stewing,omitempty"`
+	LossBaking    *float64 `json:"loss_baking,omitempty"`
+	Allergens     *string  `json:"allergens,omitempty"` // Через запятую
+	Calories      *float64 `json:"calories,omitempty"`
+	Proteins      *float64 `json:"proteins,omitempty"`
+	Fats          *float64 `json:"fats,omitempty"`
+	Carbohydrates *float64 `json:"carbohydrates,omitempty"`
+
+	// Полуфабрикаты: выход и технология
+	Yield          *float64 `json:"yield,omitempty"`
+	CookingProcess *string  `json:"cooking_process,omitempty"`
+
+	// Начальный остаток на выбранном складе (ингредиенты и товары) и цена за единицу учета
+	OpeningStock *float64 `json:"opening_stock,omitempty"`
+	OpeningPrice *float64 `json:"opening_price,omitempty"`
+
+	// Рецептура тех-карты или полуфабриката; если не задана, рецептура существующей позиции не меняется
+	Lines []MenuExchangeLine `json:"lines,omitempty"`
+
+	line   int      // Номер строки файла (для CSV и XLSX) или позиция в разделе JSON
+	errors []string // Ошибки разбора значений
+}
+
+// MenuExchangeLine позиция рецептуры: ингредиент или полуфабрикат (нетто)
+type MenuExchangeLine struct {
+	ItemType          string  `json:"item_type,omitempty"` // ingredient (по умолчанию) или semi_finished
+	Item              string  `json:"item"`                // Код или название
+	Quantity          float64 `json:"quantity"`
+	Unit              string  `json:"unit"`
+	PreparationMethod string  `json:"preparation_method,omitempty"` // cleaning, boiling, frying, stewing, baking через запятую
+
+	line   int
+	errors []string
+}
+
+// menuExchangeColumns колонки таблицы меню; выгрузка пишет их в этом порядке, импорт ищет по названию
+var menuExchangeColumns = []string{
+	"type", "external_code", "name", "category_type", "category", "parent", "workshop", "unit",
+	"description", "barcode", "price", "cost_price", "markup", "is_weighted", "active",
+	"shelf_life_days", "loss_cleaning", "loss_boiling", "loss_frying", "loss_stewing", "loss_baking",
+	"allergens", "calories", "proteins", "fats", "carbohydrates", "yield", "cooking_process",
+	"opening_stock", "opening_price", "item_type", "item", "quantity", "preparation_method",
+}
+
+// DetectMenuExchangeFormat определяет формат файла меню по расширению, а если его нет — по содержимому
+func DetectMenuExchangeFormat(filename string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xlsx":
+		return MenuExchangeFormatXLSX
+	case ".json":
+		return MenuExchangeFormatJSON
+	case ".csv", ".txt":
+		return MenuExchangeFormatCSV
+	}
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("PK")):
+		return MenuExchangeFormatXLSX
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return MenuExchangeFormatJSON
+	}
+	return MenuExchangeFormatCSV
+}
+
+func parseMenuExchange(format string, data []byte) (*MenuExchangeDocument, error) {
+	switch format {
+	case MenuExchangeFormatCSV:
+		rows, err := readImportCSV(data)
+		if err != nil {
+			return nil, err
+		}
+		return parseMenuExchangeTable(rows)
+	case MenuExchangeFormatXLSX:
+		rows, err := xlsx.ReadRows(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		return parseMenuExchangeTable(rows)
+	case MenuExchangeFormatJSON:
+		var doc MenuExchangeDocument
+		if err := json.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &doc); err != nil {
+			return nil, fmt.Errorf("invalid json: %w", err)
+		}
+		for _, section := range [][]MenuExchangeItem{doc.Categories, doc.Ingredients, doc.SemiFinished, doc.Products, doc.TechCards} {
+			for i := range section {
+				section[i].line = i + 1
+				for j := range section[i].Lines {
+					section[i].Lines[j].line = i + 1
+				}
+			}
+		}
+		return &doc, nil
+	}
+	return nil, fmt.Errorf("unsupported menu format %q, must be csv, xlsx or json", format)
+}
+
+// parseMenuExchangeTable разбирает таблицу меню: первая непустая строка — заголовки колонок.
+// Строки type=line относятся к тех-карте или полуфабрикату из колонки parent,
+// а если она пуста — к ближайшей тех-карте или полуфабрикату выше
+func parseMenuExchangeTable(rows [][]string) (*MenuExchangeDocument, error) {
+	headerRow := -1
+	for i, row := range rows {
+		if strings.TrimSpace(strings.Join(row, "")) != "" {
+			headerRow = i
+			break
+		}
+	}
+	if headerRow < 0 {
+		return nil, errors.New("file is empty")
+	}
+	col := make(map[string]int)
+	for i, h := range rows[headerRow] {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if _, ok := col[h]; !ok {
+			col[h] = i
+		}
+	}
+	for _, required := range []string{"type", "name"} {
+		if _, ok := col[required]; !ok {
+			return nil, fmt.Errorf("file must have %s column", required)
+		}
+	}
+
+	doc := &MenuExchangeDocument{}
+	// Рецептуры ссылаются на записи по индексу: срезы разделов еще растут
+	type recipeRef struct {
+		techCard bool
+		index    int
+	}
+	var last *recipeRef
+	recipes := make(map[string]recipeRef)
+	for i := headerRow + 1; i < len(rows); i++ {
+		row := rows[i]
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+		c := menuExchangeCells{row: row, col: col}
+		kind := strings.ToLower(c.text("type"))
+
+		if kind == MenuExchangeTypeLine {
+			line := MenuExchangeLine{
+				line:              i + 1,
+				ItemType:          strings.ToLower(c.text("item_type")),
+				Item:              c.text("item"),
+				Unit:              c.text("unit"),
+				PreparationMethod: c.text("preparation_method"),
+			}
+			if q := c.number("quantity", &line.errors); q != nil {
+				line.Quantity = *q
+			}
+			ref := last
+			if parent := c.text("parent"); parent != "" {
+				r, ok := recipes[menuImportKey(parent)]
+				if !ok {
+					return nil, fmt.Errorf("line %d: tech card or semi-finished product %q must be listed above its lines", i+1, parent)
+				}
+				ref = &r
+			}
+			if ref == nil {
+				return nil, fmt.Errorf("line %d: recipe line must follow a tech card or semi-finished product", i+1)
+			}
+			if ref.techCard {
+				doc.TechCards[ref.index].Lines = append(doc.TechCards[ref.index].Lines, line)
+			} else {
+				doc.SemiFinished[ref.index].Lines = append(doc.SemiFinished[ref.index].Lines, line)
+			}
+			continue
+		}
+
+		item := MenuExchangeItem{
+			line:           i + 1,
+			ExternalCode:   c.text("external_code"),
+			Name:           c.text("name"),
+			CategoryType:   strings.ToLower(c.text("category_type")),
+			Category:       c.text("category"),
+			Parent:         c.text("parent"),
+			Unit:           c.text("unit"),
+			Workshop:       c.optionalText("workshop"),
+			Description:    c.optionalText("description"),
+			Barcode:        c.optionalText("barcode"),
+			Allergens:      c.optionalText("allergens"),
+			CookingProcess: c.optionalText("cooking_process"),
+		}
+		item.Price = c.number("price", &item.errors)
+		item.CostPrice = c.number("cost_price", &item.errors)
+		item.Markup = c.number("markup", &item.errors)
+		item.IsWeighted = c.boolean("is_weighted", &item.errors)
+		item.Active = c.boolean("active", &item.errors)
+		if days := c.number("shelf_life_days", &item.errors); days != nil {
+			n := int(*days)
+			item.ShelfLifeDays = &n
+		}
+		item.LossCleaning = c.number("loss_cleaning", &item.errors)
+		item.LossBoiling = c.number("loss_boiling", &item.errors)
+		item.LossFrying = c.number("loss_frying", &item.errors)
+		item.LossStewing = c.number("loss_stewing", &item.errors)
+		item.LossBaking = c.number("loss_baking", &item.errors)
+		item.Calories = c.number("calories", &item.errors)
+		item.Proteins = c.number("proteins", &item.errors)
+		item.Fats = c.number("fats", &item.errors)
+		item.Carbohydrates = c.number("carbohydrates", &item.errors)
+		item.Yield = c.number("yield", &item.errors)
+		item.OpeningStock = c.number("opening_stock", &item.errors)
+		item.OpeningPrice = c.number("opening_price", &item.errors)
+
+		last = nil
+		switch kind {
+		case MenuExchangeTypeCategory:
+			doc.Categories = append(doc.Categories, item)
+		case MenuExchangeTypeIngredient:
+			doc.Ingredients = append(doc.Ingredients, item)
+		case MenuExchangeTypeProduct:
+			doc.Products = append(doc.Products, item)
+		case MenuExchangeTypeSemiFinished, MenuExchangeTypeTechCard:
+			ref := recipeRef{techCard: kind == MenuExchangeTypeTechCard}
+			if ref.techCard {
+				ref.index = len(doc.TechCards)
+				doc.TechCards = append(doc.TechCards, item)
+			} else {
+				ref.index = len(doc.SemiFinished)
+				doc.SemiFinished = append(doc.SemiFinished, item)
+			}
+			last = &ref
+			for _, key := range []string{item.ExternalCode, item.Name} {
+				if key != "" {
+					recipes[menuImportKey(key)] = ref
+				}
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unknown type %q, must be one of: category, ingredient, semi_finished, product, tech_card, line", i+1, kind)
+		}
+	}
+	return doc, nil
+}
+
+// menuExchangeCells значения строки таблицы меню по названиям колонок
+type menuExchangeCells struct {
+	row []string
+	col map[string]int
+}
+
+func (c menuExchangeCells) text(field string) string {
+	i, ok := c.col[field]
+	if !ok || i >= len(c.row) {
+		return ""
+	}
+	return strings.TrimSpace(c.row[i])
+}
+
+// optionalText возвращает nil для пустой ячейки, чтобы не менять поле существующей позиции
+func (c menuExchangeCells) optionalText(field string) *string {
+	if s := c.text(field); s != "" {
+		return &s
+	}
+	return nil
+}
+
+func (c menuExchangeCells) number(field string, errs *[]string) *float64 {
+	s := c.text(field)
+	if s == "" {
+		return nil
+	}
+	v, err := parseImportNumber(s)
+	if err != nil {
+		*errs = append(*errs, fmt.Sprintf("invalid %s: %v", field, err))
+		return nil
+	}
+	return &v
+}
+
+func (c menuExchangeCells) boolean(field string, errs *[]string) *bool {
+	s := strings.ToLower(c.text(field))
+	var v bool
+	switch s {
+	case "":
+		return nil
+	case "true", "1", "yes", "да":
+		v = true
+	case "false", "0", "no", "нет":
+		v = false
+	default:
+		*errs = append(*errs, fmt.Sprintf("invalid %s %q, must be true or false", field, s))
+		return nil
+	}
+	return &v
+}
+
+// ——— Выгрузка ———
+
+// menuExchangeRows раскладывает документ в таблицу: заголовок, затем разделы в порядке зависимостей,
+// позиции рецептуры — сразу после своей тех-карты или полуфабриката
+func menuExchangeRows(doc *MenuExchangeDocument) [][]string {
+	rows := [][]string{menuExchangeColumns}
+	col := make(map[string]int, len(menuExchangeColumns))
+	for i, name := range menuExchangeColumns {
+		col[name] = i
+	}
+	sections := []struct {
+		kind  string
+		items []MenuExchangeItem
+	}{
+		{MenuExchangeTypeCategory, doc.Categories},
+		{MenuExchangeTypeIngredient, doc.Ingredients},
+		{MenuExchangeTypeSemiFinished, doc.SemiFinished},
+		{MenuExchangeTypeProduct, doc.Products},
+		{MenuExchangeTypeTechCard, doc.TechCards},
+	}
+	for _, section := range sections {
+		for i := range section.items {
+			item := &section.items[i]
+			row := make([]string, len(menuExchangeColumns))
+			set := func(field, value string) { row[col[field]] = value }
+			setText := func(field string, value *string) {
+				if value != nil {
+					set(field, *value)
+				}
+			}
+			setNumber := func(field string, value *float64) {
+				if value != nil {
+					set(field, formatImportNumber(*value))
+				}
+			}
+			setBool := func(field string, value *bool) {
+				if value != nil {
+					set(field, strconv.FormatBool(*value))
+				}
+			}
+			set("type", section.kind)
+			set("external_code", item.ExternalCode)
+			set("name", item.Name)
+			set("category_type", item.CategoryType)
+			set("category", item.Category)
+			set("parent", item.Parent)
+			set("unit", item.Unit)
+			setText("workshop", item.Workshop)
+			setText("description", item.Description)
+			setText("barcode", item.Barcode)
+			setNumber("price", item.Price)
+			setNumber("cost_price", item.CostPrice)
+			setNumber("markup", item.Markup)
+			setBool("is_weighted", item.IsWeighted)
+			setBool("active", item.Active)
+			if item.ShelfLifeDays != nil {
+				set("shelf_life_days", strconv.Itoa(*item.ShelfLifeDays))
+			}
+			setNumber("loss_cleaning", item.LossCleaning)
+			setNumber("loss_boiling", item.LossBoiling)
+			setNumber("loss_frying", item.LossFrying)
+			setNumber("loss_stewing", item.LossStewing)
+			setNumber("loss_baking", item.LossBaking)
+			setText("allergens", item.Allergens)
+			setNumber("calories", item.Calories)
+			setNumber("proteins", item.Proteins)
+			setNumber("fats", item.Fats)
+			setNumber("carbohydrates", item.Carbohydrates)
+			setNumber("yield", item.Yield)
+			setText("cooking_process", item.CookingProcess)
+			setNumber("opening_stock", item.OpeningStock)
+			setNumber("opening_price", item.OpeningPrice)
+			rows = append(rows, row)
+
+			parent := item.ExternalCode
+			if parent == "" {
+				parent = item.Name
+			}
+			for _, line := range item.Lines {
+				lineRow := make([]string, len(menuExchangeColumns))
+				lineRow[col["type"]] = MenuExchangeTypeLine
+				lineRow[col["parent"]] = parent
+				lineRow[col["item_type"]] = line.ItemType
+				lineRow[col["item"]] = line.Item
+				lineRow[col["quantity"]] = formatImportNumber(line.Quantity)
+				lineRow[col["unit"]] = line.Unit
+				lineRow[col["preparation_method"]] = line.PreparationMethod
+				rows = append(rows, lineRow)
+			}
+		}
+	}
+	return rows
+}
+
+// writeMenuExchange сериализует документ в формате format. Возвращает содержимое и MIME-тип
+func writeMenuExchange(format string, doc *MenuExchangeDocument) ([]byte, string, error) {
+	var buf bytes.Buffer
+	switch format {
+	case MenuExchangeFormatCSV, "":
+		// BOM, чтобы Excel открыл UTF-8 без мастера импорта
+		buf.WriteString("\xef\xbb\xbf")
+		if err := csv.NewWriter(&buf).WriteAll(menuExchangeRows(doc)); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "text/csv; charset=utf-8", nil
+	case MenuExchangeFormatXLSX:
+		if err := xlsx.WriteRows(&buf, "Меню", menuExchangeRows(doc)); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", nil
+	case MenuExchangeFormatJSON:
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(doc); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "application/json", nil
+	}
+	return nil, "", fmt.Errorf("unsupported menu format %q, must be csv, xlsx or json", format)
+}
+
+// formatImportNumber записывает число без лишних нулей (12.5, 0.005), чтобы оно читалось обратно без потерь
+func formatImportNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// menuImportKey ключ сопоставления по коду или названию: без учета регистра и крайних пробелов
+func menuImportKey(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
@@ -0,0 +1,153 @@
+package usecases
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMenuExchangeTable(t *testing.T) {
+	rows := [][]string{
+		{},
+		{"Type", "Name", "External_Code", "Unit", "Price", "Active", "Parent", "Item_Type", "Item", "Quantity", "Opening_Stock"},
+		{"category", "Горячее", "", "", "", "", "", "", "", "", ""},
+		{"ingredient", "Мука", "ING-1", "кг", "", "да", "", "", "", "", "12,5"},
+		{"tech_card", "Блины", "TC-1", "", "300", "false", "", "", "", "", ""},
+		{"line", "", "", "г", "", "", "", "", "ING-1", "150", ""},
+		{"", "", "", "", "", "", "", "", "", "", ""},
+		{"semi_finished", "Тесто", "", "кг", "", "", "", "", "", "", ""},
+		{"line", "", "", "кг", "", "", "", "", "Мука", "1", ""},
+		{"line", "", "", "шт", "", "", "TC-1", "ingredient", "Яйцо", "2", ""},
+		{"product", "Лимонад", "", "", "12р", "может быть", "", "", "", "", ""},
+	}
+
+	doc, err := parseMenuExchangeTable(rows)
+	require.NoError(t, err)
+
+	require.Len(t, doc.Categories, 1)
+	assert.Equal(t, "Горячее", doc.Categories[0].Name)
+
+	require.Len(t, doc.Ingredients, 1)
+	flour := doc.Ingredients[0]
+	assert.Equal(t, "ING-1", flour.ExternalCode)
+	assert.Equal(t, 4, flour.line)
+	require.NotNil(t, flour.OpeningStock)
+	assert.Equal(t, 12.5, *flour.OpeningStock)
+	require.NotNil(t, flour.Active)
+	assert.True(t, *flour.Active)
+	assert.Nil(t, flour.Price)
+	assert.Nil(t, flour.Description)
+
+	// Строка с parent относится к указанной тех-карте, даже если выше идет полуфабрикат
+	require.Len(t, doc.TechCards, 1)
+	assert.Equal(t, []MenuExchangeLine{
+		{Item: "ING-1", Quantity: 150, Unit: "г", line: 6},
+		{ItemType: "ingredient", Item: "Яйцо", Quantity: 2, Unit: "шт", line: 10},
+	}, doc.TechCards[0].Lines)
+	require.NotNil(t, doc.TechCards[0].Active)
+	assert.False(t, *doc.TechCards[0].Active)
+
+	require.Len(t, doc.SemiFinished, 1)
+	assert.Equal(t, []MenuExchangeLine{{Item: "Мука", Quantity: 1, Unit: "кг", line: 9}}, doc.SemiFinished[0].Lines)
+
+	// Ошибки значений не прерывают разбор, а попадают в отчет по строке
+	require.Len(t, doc.Products, 1)
+	assert.Nil(t, doc.Products[0].Price)
+	assert.Len(t, doc.Products[0].errors, 2)
+}
+
+func TestParseMenuExchangeTable_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		rows [][]string
+		err  string
+	}{
+		{name: "empty file", rows: [][]string{{"", ""}}, err: "file is empty"},
+		{name: "no type column", rows: [][]string{{"name"}, {"Мука"}}, err: "file must have type column"},
+		{name: "unknown type", rows: [][]string{{"type", "name"}, {"dish", "Борщ"}}, err: `line 2: unknown type "dish"`},
+		{name: "line without recipe", rows: [][]string{{"type", "name", "item"}, {"ingredient", "Мука", ""}, {"line", "", "Мука"}},
+			err: "line 3: recipe line must follow"},
+		{name: "parent below its lines", rows: [][]string{{"type", "name", "parent"}, {"line", "", "Блины"}, {"tech_card", "Блины", ""}},
+			err: `line 2: tech card or semi-finished product "Блины" must be listed above`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseMenuExchangeTable(tt.rows)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestParseMenuExchange_JSON(t *testing.T) {
+	data := []byte("\xef\xbb\xbf" + `{"ingredients":[{"name":"Мука","unit":"кг"},{"name":"Сахар","unit":"кг","price":null}],
+		"tech_cards":[{"name":"Блины","lines":[{"item":"Мука","quantity":0.15,"unit":"кг"}]}]}`)
+
+	doc, err := parseMenuExchange(MenuExchangeFormatJSON, data)
+	require.NoError(t, err)
+	require.Len(t, doc.Ingredients, 2)
+	assert.Equal(t, 2, doc.Ingredients[1].line)
+	require.Len(t, doc.TechCards, 1)
+	assert.Equal(t, 1, doc.TechCards[0].Lines[0].line)
+
+	_, err = parseMenuExchange(MenuExchangeFormatJSON, []byte(`{"ingredients":`))
+	assert.ErrorContains(t, err, "invalid json")
+	_, err = parseMenuExchange("xml", data)
+	assert.ErrorContains(t, err, "unsupported menu format")
+}
+
+func TestWriteMenuExchange_RoundTrip(t *testing.T) {
+	price, stock, yield := 150.0, 0.005, 1.5
+	active, description := false, "Сладкие, с «кавычками», запятыми\nи переносом"
+	doc := &MenuExchangeDocument{
+		Categories:  []MenuExchangeItem{{Name: "Бакалея", CategoryType: "ingredient"}},
+		Ingredients: []MenuExchangeItem{{ExternalCode: "ING-1", Name: "Мука", Unit: "кг", Category: "Бакалея", OpeningStock: &stock}},
+		SemiFinished: []MenuExchangeItem{{Name: "Тесто", Unit: "кг", Yield: &yield, Lines: []MenuExchangeLine{
+			{ItemType: MenuExchangeTypeIngredient, Item: "ING-1", Quantity: 0.8, Unit: "кг", PreparationMethod: "cleaning,baking"},
+		}}},
+		Products: []MenuExchangeItem{{Name: "Лимонад", Price: &price, Active: &active}},
+		TechCards: []MenuExchangeItem{{ExternalCode: "TC-1", Name: "Блины", Description: &description, Lines: []MenuExchangeLine{
+			{ItemType: MenuExchangeTypeSemiFinished, Item: "Тесто", Quantity: 0.2, Unit: "кг"},
+		}}},
+	}
+
+	for _, format := range []string{MenuExchangeFormatCSV, MenuExchangeFormatXLSX, MenuExchangeFormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			data, _, err := writeMenuExchange(format, doc)
+			require.NoError(t, err)
+			assert.Equal(t, format, DetectMenuExchangeFormat("", data))
+
+			parsed, err := parseMenuExchange(format, data)
+			require.NoError(t, err)
+			assert.Equal(t, menuExchangeContent(doc), menuExchangeContent(parsed))
+		})
+	}
+}
+
+// menuExchangeContent документ без служебных полей разбора (номеров строк), для сравнения после чтения файла
+func menuExchangeContent(doc *MenuExchangeDocument) MenuExchangeDocument {
+	clean := func(items []MenuExchangeItem) []MenuExchangeItem {
+		out := make([]MenuExchangeItem, len(items))
+		for i, item := range items {
+			item.line, item.errors = 0, nil
+			lines := make([]MenuExchangeLine, len(item.Lines))
+			for j, line := range item.Lines {
+				line.line, line.errors = 0, nil
+				lines[j] = line
+			}
+			if len(lines) == 0 {
+				lines = nil
+			}
+			item.Lines = lines
+			out[i] = item
+		}
+		return out
+	}
+	return MenuExchangeDocument{
+		Categories:   clean(doc.Categories),
+		Ingredients:  clean(doc.Ingredients),
+		SemiFinished: clean(doc.SemiFinished),
+		Products:     clean(doc.Products),
+		TechCards:    clean(doc.TechCards),
+	}
+}
@@ -0,0 +1,1319 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// ErrMenuImportInvalid возвращается, если в файле меню есть ошибки или сохранение завершилось ошибкой
+var ErrMenuImportInvalid = errors.New("menu import has invalid rows")
+
+// Действия импорта над записью файла меню
+const (
+	MenuImportActionCreate = "create" // Позиция будет создана
+	MenuImportActionUpdate = "update" // Существующая позиция будет обновлена
+	MenuImportActionExists = "exists" // Категория уже есть, изменений нет
+)
+
+// MenuImportRow результат проверки записи файла меню
+type MenuImportRow struct {
+	Line     int        `json:"line"` // Номер строки CSV/XLSX или позиция в разделе JSON
+	Type     string     `json:"type"` // category, ingredient, semi_finished, product, tech_card
+	Name     string     `json:"name"`
+	Action   string     `json:"action,omitempty"`
+	ID       *uuid.UUID `json:"id,omitempty"` // ID существующей или создаваемой позиции
+	Errors   []string   `json:"errors,omitempty"`
+	Warnings []string   `json:"warnings,omitempty"`
+}
+
+// MenuImportReport отчет импорта меню. При пробном запуске (dry_run) и при ошибках изменения не сохраняются
+type MenuImportReport struct {
+	Format      string          `json:"format"`
+	DryRun      bool            `json:"dry_run"`
+	WarehouseID uuid.UUID       `json:"warehouse_id"`
+	Valid       bool            `json:"valid"`   // Ошибок нет
+	Applied     bool            `json:"applied"` // Изменения сохранены
+	Created     int             `json:"created"`
+	Updated     int             `json:"updated"`
+	Rows        []MenuImportRow `json:"rows"`
+}
+
+// MenuImportUseCase массовый импорт и выгрузка меню: категории, ингредиенты, полуфабрикаты,
+// товары и тех-карты с рецептурами в CSV, XLSX или JSON
+type MenuImportUseCase struct {
+	menu                   *MenuUseCase
+	categoryRepo           repositories.CategoryRepository
+	ingredientCategoryRepo repositories.IngredientCategoryRepository
+	ingredientRepo         repositories.IngredientRepository
+	semiFinishedRepo       repositories.SemiFinishedRepository
+	productRepo            repositories.ProductRepository
+	techCardRepo           repositories.TechCardRepository
+	workshopRepo           repositories.WorkshopRepository
+	warehouseRepo          repositories.WarehouseRepository
+	transactor             repositories.Transactor
+}
+
+func NewMenuImportUseCase(
+	menu *MenuUseCase,
+	categoryRepo repositories.CategoryRepository,
+	ingredientCategoryRepo repositories.IngredientCategoryRepository,
+	ingredientRepo repositories.IngredientRepository,
+	semiFinishedRepo repositories.SemiFinishedRepository,
+	productRepo repositories.ProductRepository,
+	techCardRepo repositories.TechCardRepository,
+	workshopRepo repositories.WorkshopRepository,
+	warehouseRepo repositories.WarehouseRepository,
+	transactor repositories.Transactor,
+) *MenuImportUseCase {
+	return &MenuImportUseCase{
+		menu:                   menu,
+		categoryRepo:           categoryRepo,
+		ingredientCategoryRepo: ingredientCategoryRepo,
+		ingredientRepo:         ingredientRepo,
+		semiFinishedRepo:       semiFinishedRepo,
+		productRepo:            productRepo,
+		techCardRepo:           techCardRepo,
+		workshopRepo:           workshopRepo,
+		warehouseRepo:          warehouseRepo,
+		transactor:             transactor,
+	}
+}
+
+// ——— Import ———
+
+// Import разбирает файл меню, сопоставляет записи с позициями заведения (по external_code, затем по названию)
+// и проверяет их. Без dryRun и при отсутствии ошибок создает и обновляет позиции в порядке зависимостей
+// и заводит начальные остатки на склад warehouseID. Все изменения сохраняются одной транзакцией.
+// Если в файле есть ошибки или сохранение записи не удалось, возвращается ErrMenuImportInvalid
+// вместе с отчетом, изменения не сохраняются. Пустой format определяется по файлу.
+func (uc *MenuImportUseCase) Import(ctx context.Context, establishmentID, warehouseID uuid.UUID, format, filename string, data []byte, dryRun bool) (*MenuImportReport, error) {
+	if format == "" {
+		format = DetectMenuExchangeFormat(filename, data)
+	}
+	doc, err := parseMenuExchange(format, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Categories)+len(doc.Ingredients)+len(doc.SemiFinished)+len(doc.Products)+len(doc.TechCards) == 0 {
+		return nil, errors.New("file has no menu items")
+	}
+	if _, err := uc.warehouseRepo.GetWarehouseByID(ctx, warehouseID, &establishmentID); err != nil {
+		return nil, errors.New("warehouse not found")
+	}
+
+	plan, err := uc.newMenuImportPlan(ctx, establishmentID, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	plan.report.Format = format
+	plan.report.DryRun = dryRun
+	plan.planCategories(doc.Categories)
+	plan.planIngredients(doc.Ingredients)
+	plan.planSemiFinished(doc.SemiFinished)
+	plan.planProducts(doc.Products)
+	plan.planTechCards(doc.TechCards)
+
+	report := plan.report
+	report.Valid = true
+	for _, row := range report.Rows {
+		if len(row.Errors) > 0 {
+			report.Valid = false
+		}
+		switch row.Action {
+		case MenuImportActionCreate:
+			report.Created++
+		case MenuImportActionUpdate:
+			report.Updated++
+		}
+	}
+	if !report.Valid {
+		return report, ErrMenuImportInvalid
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		for _, op := range plan.ops {
+			if err := op.apply(ctx); err != nil {
+				row := &report.Rows[op.row]
+				row.Errors = append(row.Errors, "not saved: "+err.Error()+"; no changes from the file were saved")
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		report.Valid = false
+		return report, ErrMenuImportInvalid
+	}
+	report.Applied = true
+	return report, nil
+}
+
+// menuImportOp отложенное сохранение записи: выполняется только после проверки всего файла
+type menuImportOp struct {
+	row   int
+	apply func(ctx context.Context) error
+}
+
+// menuImportIndex поиск позиции по внешнему коду и названию (без учета регистра)
+type menuImportIndex struct {
+	byCode map[string]uuid.UUID
+	byName map[string]uuid.UUID
+}
+
+func newMenuImportIndex() menuImportIndex {
+	return menuImportIndex{byCode: make(map[string]uuid.UUID), byName: make(map[string]uuid.UUID)}
+}
+
+func (ix menuImportIndex) add(code, name string, id uuid.UUID) {
+	if code != "" {
+		ix.byCode[menuImportKey(code)] = id
+	}
+	ix.byName[menuImportKey(name)] = id
+}
+
+// find ищет сначала по коду, затем по названию
+func (ix menuImportIndex) find(code, name string) (uuid.UUID, bool) {
+	if code != "" {
+		if id, ok := ix.byCode[menuImportKey(code)]; ok {
+			return id, true
+		}
+	}
+	if name == "" {
+		return uuid.Nil, false
+	}
+	id, ok := ix.byName[menuImportKey(name)]
+	return id, ok
+}
+
+// menuImportPlan позиции заведения и запланированные изменения. Новые позиции получают ID заранее,
+// чтобы на них можно было сослаться из следующих записей файла и в пробном запуске
+type menuImportPlan struct {
+	uc              *MenuImportUseCase
+	establishmentID uuid.UUID
+	warehouseID     uuid.UUID
+	report          *MenuImportReport
+	ops             []menuImportOp
+
+	categories           map[string]*models.Category // Ключ: тип + название
+	ingredientCategories map[string]*models.IngredientCategory
+	workshops            map[string]uuid.UUID
+	ingredients          map[uuid.UUID]*models.Ingredient
+	ingredientIndex      menuImportIndex
+	semiFinished         map[uuid.UUID]*models.SemiFinishedProduct
+	semiFinishedIndex    menuImportIndex
+	products             map[uuid.UUID]*models.Product
+	productIndex         menuImportIndex
+	variantParents       map[uuid.UUID]bool // Товары, которые получают модификации из файла
+	techCards            map[uuid.UUID]*models.TechCard
+	techCardIndex        menuImportIndex
+	stocks               map[uuid.UUID]*models.Stock // Остатки склада по ID ингредиента или товара
+}
+
+func (uc *MenuImportUseCase) newMenuImportPlan(ctx context.Context, establishmentID, warehouseID uuid.UUID) (*menuImportPlan, error) {
+	p := &menuImportPlan{
+		uc:                   uc,
+		establishmentID:      establishmentID,
+		warehouseID:          warehouseID,
+		report:               &MenuImportReport{WarehouseID: warehouseID, Rows: []MenuImportRow{}},
+		categories:           make(map[string]*models.Category),
+		ingredientCategories: make(map[string]*models.IngredientCategory),
+		workshops:            make(map[string]uuid.UUID),
+		ingredients:          make(map[uuid.UUID]*models.Ingredient),
+		ingredientIndex:      newMenuImportIndex(),
+		semiFinished:         make(map[uuid.UUID]*models.SemiFinishedProduct),
+		semiFinishedIndex:    newMenuImportIndex(),
+		products:             make(map[uuid.UUID]*models.Product),
+		productIndex:         newMenuImportIndex(),
+		variantParents:       make(map[uuid.UUID]bool),
+		techCards:            make(map[uuid.UUID]*models.TechCard),
+		techCardIndex:        newMenuImportIndex(),
+		stocks:               make(map[uuid.UUID]*models.Stock),
+	}
+
+	categories, err := uc.categoryRepo.List(ctx, &repositories.CategoryFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range categories {
+		p.categories[c.Type+"\x00"+menuImportKey(c.Name)] = c
+	}
+	ingredientCategories, err := uc.ingredientCategoryRepo.List(ctx, &repositories.IngredientCategoryFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range ingredientCategories {
+		p.ingredientCategories[menuImportKey(c.Name)] = c
+	}
+	workshops, err := uc.workshopRepo.ListWorkshops(ctx, establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range workshops {
+		p.workshops[menuImportKey(w.Name)] = w.ID
+	}
+
+	ingredients, err := uc.ingredientRepo.List(ctx, &repositories.IngredientFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, err
+	}
+	for _, ing := range ingredients {
+		p.ingredients[ing.ID] = ing
+		p.ingredientIndex.add(ing.ExternalCode, ing.Name, ing.ID)
+	}
+	semiFinished, err := uc.semiFinishedRepo.List(ctx, &repositories.SemiFinishedFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, err
+	}
+	for _, sf := range semiFinished {
+		p.semiFinished[sf.ID] = sf
+		p.semiFinishedIndex.add(sf.ExternalCode, sf.Name, sf.ID)
+	}
+	products, err := uc.productRepo.List(ctx, &repositories.ProductFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, err
+	}
+	for _, prod := range products {
+		p.products[prod.ID] = prod
+		p.productIndex.add(prod.ExternalCode, prod.Name, prod.ID)
+	}
+	techCards, err := uc.techCardRepo.List(ctx, &repositories.TechCardFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, err
+	}
+	for _, tc := range techCards {
+		p.techCards[tc.ID] = tc
+		p.techCardIndex.add(tc.ExternalCode, tc.Name, tc.ID)
+	}
+
+	stocks, err := uc.warehouseRepo.GetStockByWarehouseID(ctx, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range stocks {
+		switch {
+		case st.IngredientID != nil:
+			p.stocks[*st.IngredientID] = st
+		case st.ProductID != nil:
+			p.stocks[*st.ProductID] = st
+		}
+	}
+	return p, nil
+}
+
+// addRow добавляет запись в отчет вместе с ошибками разбора значений
+func (p *menuImportPlan) addRow(kind string, item *MenuExchangeItem) int {
+	row := MenuImportRow{Line: item.line, Type: kind, Name: item.Name}
+	row.Errors = append(row.Errors, item.errors...)
+	for _, line := range item.Lines {
+		for _, e := range line.errors {
+			row.Errors = append(row.Errors, fmt.Sprintf("line %d: %s", line.line, e))
+		}
+	}
+	if item.Name == "" {
+		row.Errors = append(row.Errors, "name is required")
+	}
+	p.report.Rows = append(p.report.Rows, row)
+	return len(p.report.Rows) - 1
+}
+
+func (p *menuImportPlan) fail(row int, format string, args ...interface{}) {
+	p.report.Rows[row].Errors = append(p.report.Rows[row].Errors, fmt.Sprintf(format, args...))
+}
+
+func (p *menuImportPlan) warn(row int, format string, args ...interface{}) {
+	p.report.Rows[row].Warnings = append(p.report.Rows[row].Warnings, fmt.Sprintf(format, args...))
+}
+
+func (p *menuImportPlan) hasErrors(row int) bool {
+	return len(p.report.Rows[row].Errors) > 0
+}
+
+func (p *menuImportPlan) setAction(row int, action string, id uuid.UUID) {
+	p.report.Rows[row].Action = action
+	p.report.Rows[row].ID = &id
+}
+
+func (p *menuImportPlan) addOp(row int, apply func(ctx context.Context) error) {
+	p.ops = append(p.ops, menuImportOp{row: row, apply: apply})
+}
+
+// duplicate отмечает повтор позиции в разделе файла: одна позиция не может обновляться дважды
+func (p *menuImportPlan) duplicate(row int, seen map[uuid.UUID]bool, id uuid.UUID) bool {
+	if seen[id] {
+		p.fail(row, "duplicate item in file")
+		return true
+	}
+	seen[id] = true
+	return false
+}
+
+// category возвращает категорию позиции меню; если ее нет, планирует создание
+func (p *menuImportPlan) category(row int, categoryType, name string) (*models.Category, bool) {
+	key := categoryType + "\x00" + menuImportKey(name)
+	if c, ok := p.categories[key]; ok {
+		return c, false
+	}
+	c := &models.Category{ID: uuid.New(), EstablishmentID: p.establishmentID, Name: name, Type: categoryType}
+	p.categories[key] = c
+	p.addOp(row, func(ctx context.Context) error {
+		return p.uc.menu.CreateCategory(ctx, c, p.establishmentID)
+	})
+	return c, true
+}
+
+// itemCategory категория, указанная у позиции: создается автоматически с предупреждением
+func (p *menuImportPlan) itemCategory(row int, categoryType, name string) uuid.UUID {
+	c, created := p.category(row, categoryType, name)
+	if created {
+		p.warn(row, "category %q will be created", name)
+	}
+	return c.ID
+}
+
+func (p *menuImportPlan) ingredientCategory(row int, name string) (*models.IngredientCategory, bool) {
+	key := menuImportKey(name)
+	if c, ok := p.ingredientCategories[key]; ok {
+		return c, false
+	}
+	c := &models.IngredientCategory{ID: uuid.New(), EstablishmentID: p.establishmentID, Name: name}
+	p.ingredientCategories[key] = c
+	p.addOp(row, func(ctx context.Context) error {
+		return p.uc.menu.CreateIngredientCategory(ctx, c, p.establishmentID)
+	})
+	return c, true
+}
+
+// workshop находит цех по названию; пустое название снимает цех
+func (p *menuImportPlan) workshop(row int, name string) *uuid.UUID {
+	if name == "" {
+		return nil
+	}
+	id, ok := p.workshops[menuImportKey(name)]
+	if !ok {
+		p.fail(row, "workshop %q not found", name)
+		return nil
+	}
+	return &id
+}
+
+// unit приводит единицу к каноническому виду и проверяет ее
+func (p *menuImportPlan) unit(row int, unit string) string {
+	normalized := models.NormalizeUnit(unit)
+	if !models.IsValidUnit(normalized) {
+		p.fail(row, "invalid unit %q", unit)
+	}
+	return normalized
+}
+
+// nonNegative проверяет числовые поля записи
+func (p *menuImportPlan) nonNegative(row int, fields map[string]*float64) {
+	for name, v := range fields {
+		if v != nil && *v < 0 {
+			p.fail(row, "%s cannot be negative", name)
+		}
+	}
+}
+
+// openingStock планирует начальный остаток ингредиента или товара на складе импорта.
+// Если на складе уже есть остаток, начальный остаток не заводится, чтобы повторный импорт его не удвоил
+func (p *menuImportPlan) openingStock(row int, item *MenuExchangeItem, ingredientID, productID *uuid.UUID, unit string, isNew bool) {
+	if item.OpeningStock == nil {
+		if item.OpeningPrice != nil {
+			p.fail(row, "opening_price requires opening_stock")
+		}
+		return
+	}
+	quantity := *item.OpeningStock
+	price := 0.0
+	if item.OpeningPrice != nil {
+		price = *item.OpeningPrice
+	}
+	if quantity < 0 || price < 0 {
+		p.fail(row, "opening stock and price cannot be negative")
+		return
+	}
+	if quantity == 0 {
+		return
+	}
+	if !isNew {
+		id := ingredientID
+		if id == nil {
+			id = productID
+		}
+		if st := p.stocks[*id]; st != nil && st.Quantity != 0 {
+			p.warn(row, "warehouse already has %s %s in stock, opening stock skipped", formatImportNumber(st.Quantity), st.Unit)
+			return
+		}
+	}
+	p.addOp(row, func(ctx context.Context) error {
+		return p.uc.applyOpeningStock(ctx, p.warehouseID, ingredientID, productID, unit, quantity, price)
+	})
+}
+
+// applyOpeningStock заводит начальный остаток (пустая запись остатка дополняется) и отражает его в журнале движения
+func (uc *MenuImportUseCase) applyOpeningStock(ctx context.Context, warehouseID uuid.UUID, ingredientID, productID *uuid.UUID, unit string, quantity, price float64) error {
+	var st *models.Stock
+	var err error
+	if ingredientID != nil {
+		st, err = uc.warehouseRepo.GetStockByIngredientAndWarehouse(ctx, *ingredientID, warehouseID)
+	} else {
+		st, err = uc.warehouseRepo.GetStockByProductAndWarehouse(ctx, *productID, warehouseID)
+	}
+	if err != nil {
+		return err
+	}
+	if st == nil {
+		st = &models.Stock{
+			WarehouseID:  warehouseID,
+			IngredientID: ingredientID,
+			ProductID:    productID,
+			Quantity:     quantity,
+			Unit:         unit,
+			PricePerUnit: price,
+		}
+		if err := uc.warehouseRepo.CreateStock(ctx, st); err != nil {
+			return err
+		}
+	} else {
+		st.Quantity += quantity
+		if price > 0 {
+			st.PricePerUnit = price
+		}
+		if err := uc.warehouseRepo.UpdateStock(ctx, st); err != nil {
+			return err
+		}
+	}
+	return postStockLedger(ctx, uc.warehouseRepo, st, quantity, 0, stockLedgerSource{Type: models.StockLedgerOpening})
+}
+
+func (p *menuImportPlan) planCategories(items []MenuExchangeItem) {
+	for i := range items {
+		item := &items[i]
+		row := p.addRow(MenuExchangeTypeCategory, item)
+		if item.Name == "" {
+			continue
+		}
+		var id uuid.UUID
+		var created bool
+		switch item.CategoryType {
+		case "ingredient":
+			var c *models.IngredientCategory
+			c, created = p.ingredientCategory(row, item.Name)
+			id = c.ID
+		case MenuItemTypeProduct, MenuItemTypeTechCard, "semi_finished":
+			var c *models.Category
+			c, created = p.category(row, item.CategoryType, item.Name)
+			id = c.ID
+		default:
+			p.fail(row, "category_type must be one of: product, tech_card, semi_finished, ingredient")
+			continue
+		}
+		if created {
+			p.setAction(row, MenuImportActionCreate, id)
+		} else {
+			p.setAction(row, MenuImportActionExists, id)
+		}
+	}
+}
+
+func (p *menuImportPlan) planIngredients(items []MenuExchangeItem) {
+	seen := make(map[uuid.UUID]bool)
+	for i := range items {
+		item := &items[i]
+		row := p.addRow(MenuExchangeTypeIngredient, item)
+		if item.Name == "" {
+			continue
+		}
+		id, found := p.ingredientIndex.find(item.ExternalCode, item.Name)
+		var ing *models.Ingredient
+		if found {
+			ing = p.ingredients[id]
+			p.setAction(row, MenuImportActionUpdate, id)
+		} else {
+			ing = &models.Ingredient{ID: uuid.New(), EstablishmentID: p.establishmentID, Active: true}
+			p.setAction(row, MenuImportActionCreate, ing.ID)
+			if item.Unit == "" {
+				p.fail(row, "unit is required")
+			}
+			if item.Category == "" {
+				p.fail(row, "category is required")
+			}
+		}
+		if p.duplicate(row, seen, ing.ID) {
+			continue
+		}
+
+		ing.Name = item.Name
+		if item.ExternalCode != "" {
+			ing.ExternalCode = item.ExternalCode
+		}
+		if item.Unit != "" {
+			ing.Unit = p.unit(row, item.Unit)
+		}
+		if item.Category != "" {
+			c, created := p.ingredientCategory(row, item.Category)
+			if created {
+				p.warn(row, "ingredient category %q will be created", item.Category)
+			}
+			ing.CategoryID = c.ID
+		}
+		if item.Barcode != nil {
+			ing.Barcode = *item.Barcode
+		}
+		if item.ShelfLifeDays != nil {
+			if *item.ShelfLifeDays < 0 {
+				p.fail(row, "shelf_life_days cannot be negative")
+			}
+			ing.ShelfLifeDays = *item.ShelfLifeDays
+		}
+		for _, loss := range []struct {
+			name  string
+			value *float64
+			field *float64
+		}{
+			{"loss_cleaning", item.LossCleaning, &ing.LossCleaning},
+			{"loss_boiling", item.LossBoiling, &ing.LossBoiling},
+			{"loss_frying", item.LossFrying, &ing.LossFrying},
+			{"loss_stewing", item.LossStewing, &ing.LossStewing},
+			{"loss_baking", item.LossBaking, &ing.LossBaking},
+		} {
+			if loss.value == nil {
+				continue
+			}
+			if *loss.value < 0 || *loss.value >= 100 {
+				p.fail(row, "%s must be between 0 and 100", loss.name)
+			}
+			*loss.field = *loss.value
+		}
+		if item.Allergens != nil {
+			allergens, err := models.ParseAllergens([]string{*item.Allergens})
+			if err != nil {
+				p.fail(row, "%s", err.Error())
+			}
+			ing.Allergens = allergens
+		}
+		p.nonNegative(row, map[string]*float64{
+			"calories": item.Calories, "proteins": item.Proteins, "fats": item.Fats, "carbohydrates": item.Carbohydrates,
+		})
+		setMenuImportFloat(&ing.Calories, item.Calories)
+		setMenuImportFloat(&ing.Proteins, item.Proteins)
+		setMenuImportFloat(&ing.Fats, item.Fats)
+		setMenuImportFloat(&ing.Carbohydrates, item.Carbohydrates)
+		if item.Active != nil {
+			ing.Active = *item.Active
+		}
+		p.ingredientIndex.add(ing.ExternalCode, ing.Name, ing.ID)
+		p.ingredients[ing.ID] = ing
+		if p.hasErrors(row) {
+			continue
+		}
+
+		if found {
+			p.addOp(row, func(ctx context.Context) error {
+				return p.uc.menu.UpdateIngredient(ctx, ing)
+			})
+		} else {
+			active := ing.Active
+			p.addOp(row, func(ctx context.Context) error {
+				if err := p.uc.menu.CreateIngredient(ctx, ing, uuid.Nil, 0, 0, uuid.Nil, p.establishmentID); err != nil {
+					return err
+				}
+				if active {
+					return nil
+				}
+				// Active со значением по умолчанию в БД не сохраняется как false при создании
+				ing.Active = false
+				return p.uc.menu.UpdateIngredient(ctx, ing)
+			})
+		}
+		p.openingStock(row, item, &ing.ID, nil, ing.Unit, !found)
+	}
+}
+
+func (p *menuImportPlan) planSemiFinished(items []MenuExchangeItem) {
+	seen := make(map[uuid.UUID]bool)
+	for i := range items {
+		item := &items[i]
+		row := p.addRow(MenuExchangeTypeSemiFinished, item)
+		if item.Name == "" {
+			continue
+		}
+		id, found := p.semiFinishedIndex.find(item.ExternalCode, item.Name)
+		var sf *models.SemiFinishedProduct
+		if found {
+			sf = p.semiFinished[id]
+			p.setAction(row, MenuImportActionUpdate, id)
+		} else {
+			sf = &models.SemiFinishedProduct{ID: uuid.New(), EstablishmentID: p.establishmentID, Active: true}
+			p.setAction(row, MenuImportActionCreate, sf.ID)
+			if item.Unit == "" {
+				p.fail(row, "unit is required")
+			}
+		}
+		if p.duplicate(row, seen, sf.ID) {
+			continue
+		}
+
+		sf.Name = item.Name
+		if item.ExternalCode != "" {
+			sf.ExternalCode = item.ExternalCode
+		}
+		if item.Unit != "" {
+			sf.Unit = p.unit(row, item.Unit)
+		}
+		if item.Category != "" {
+			categoryID := p.itemCategory(row, "semi_finished", item.Category)
+			sf.CategoryID = &categoryID
+		}
+		if item.Workshop != nil {
+			sf.WorkshopID = p.workshop(row, *item.Workshop)
+		}
+		if item.Description != nil {
+			sf.Description = *item.Description
+		}
+		if item.CookingProcess != nil {
+			sf.CookingProcess = *item.CookingProcess
+		}
+		p.nonNegative(row, map[string]*float64{"yield": item.Yield})
+		setMenuImportFloat(&sf.Quantity, item.Yield)
+		if item.Active != nil {
+			sf.Active = *item.Active
+		}
+		if item.OpeningStock != nil {
+			p.fail(row, "opening stock can be set only for ingredients and products")
+		}
+
+		if len(item.Lines) > 0 {
+			sf.Ingredients = make([]models.SemiFinishedIngredient, 0, len(item.Lines))
+			for _, line := range item.Lines {
+				ingredientID, ok := p.recipeLine(row, &line, false)
+				if !ok {
+					continue
+				}
+				sf.Ingredients = append(sf.Ingredients, models.SemiFinishedIngredient{
+					IngredientID:      ingredientID,
+					Net:               line.Quantity,
+					Unit:              models.NormalizeUnit(line.Unit),
+					PreparationMethod: menuImportPreparation(line.PreparationMethod),
+				})
+			}
+		} else {
+			// Рецептура не задана: сохраняем текущую (репозиторий пересоздает позиции при обновлении)
+			for j := range sf.Ingredients {
+				sf.Ingredients[j].ID = uuid.Nil
+				sf.Ingredients[j].Ingredient = nil
+				sf.Ingredients[j].SemiFinished = nil
+			}
+		}
+		p.semiFinishedIndex.add(sf.ExternalCode, sf.Name, sf.ID)
+		p.semiFinished[sf.ID] = sf
+		if p.hasErrors(row) {
+			continue
+		}
+
+		if found {
+			p.addOp(row, func(ctx context.Context) error {
+				return p.uc.menu.UpdateSemiFinished(ctx, sf, p.warehouseID, p.establishmentID)
+			})
+		} else {
+			active := sf.Active
+			p.addOp(row, func(ctx context.Context) error {
+				if err := p.uc.menu.CreateSemiFinished(ctx, sf, p.warehouseID, p.establishmentID); err != nil {
+					return err
+				}
+				if active {
+					return nil
+				}
+				sf.Active = false
+				return p.uc.menu.UpdateSemiFinished(ctx, sf, p.warehouseID, p.establishmentID)
+			})
+		}
+	}
+}
+
+// recipeLine проверяет позицию рецептуры и возвращает ID ингредиента или полуфабриката (semiFinished).
+// Полуфабрикат допускается только в тех-картах
+func (p *menuImportPlan) recipeLine(row int, line *MenuExchangeLine, allowSemiFinished bool) (uuid.UUID, bool) {
+	prefix := fmt.Sprintf("line %d", line.line)
+	if line.Item == "" {
+		p.fail(row, "%s: item is required", prefix)
+		return uuid.Nil, false
+	}
+	if line.Quantity <= 0 {
+		p.fail(row, "%s: quantity must be positive", prefix)
+	}
+	if !models.IsValidUnit(models.NormalizeUnit(line.Unit)) {
+		p.fail(row, "%s: invalid unit %q", prefix, line.Unit)
+	}
+	if _, err := models.ParsePreparationMethods(menuImportPreparation(line.PreparationMethod)); err != nil {
+		p.fail(row, "%s: %s", prefix, err.Error())
+	}
+
+	var id uuid.UUID
+	var ok bool
+	switch line.ItemType {
+	case "", MenuExchangeTypeIngredient:
+		id, ok = p.ingredientIndex.find(line.Item, line.Item)
+		if !ok {
+			p.fail(row, "%s: ingredient %q not found", prefix, line.Item)
+		}
+	case MenuExchangeTypeSemiFinished:
+		if !allowSemiFinished {
+			p.fail(row, "%s: semi-finished product can contain only ingredients", prefix)
+			return uuid.Nil, false
+		}
+		id, ok = p.semiFinishedIndex.find(line.Item, line.Item)
+		if !ok {
+			p.fail(row, "%s: semi-finished product %q not found", prefix, line.Item)
+		}
+	default:
+		p.fail(row, "%s: item_type must be ingredient or semi_finished", prefix)
+	}
+	return id, ok
+}
+
+func (p *menuImportPlan) planProducts(items []MenuExchangeItem) {
+	// Сначала товары верхнего уровня, затем модификации: родитель может быть создан этим же файлом
+	order := make([]int, 0, len(items))
+	for i := range items {
+		if items[i].Parent == "" {
+			order = append(order, i)
+		}
+	}
+	for i := range items {
+		if items[i].Parent != "" {
+			order = append(order, i)
+		}
+	}
+
+	type opening struct {
+		row     int
+		item    *MenuExchangeItem
+		product *models.Product
+		isNew   bool
+	}
+	var openings []opening
+	seen := make(map[uuid.UUID]bool)
+	for _, i := range order {
+		item := &items[i]
+		row := p.addRow(MenuExchangeTypeProduct, item)
+		if item.Name == "" {
+			continue
+		}
+		id, found := p.productIndex.find(item.ExternalCode, item.Name)
+		var prod *models.Product
+		if found {
+			prod = p.products[id]
+			p.setAction(row, MenuImportActionUpdate, id)
+		} else {
+			warehouseID := p.warehouseID
+			prod = &models.Product{ID: uuid.New(), EstablishmentID: p.establishmentID, WarehouseID: &warehouseID, Active: true}
+			p.setAction(row, MenuImportActionCreate, prod.ID)
+		}
+		if p.duplicate(row, seen, prod.ID) {
+			continue
+		}
+
+		var parentID *uuid.UUID
+		if item.Parent != "" {
+			pid, ok := p.productIndex.find(item.Parent, item.Parent)
+			switch {
+			case !ok:
+				p.fail(row, "parent product %q not found", item.Parent)
+			case p.products[pid].IsVariant():
+				p.fail(row, "a variant cannot have its own variants")
+			case found && (!prod.IsVariant() || *prod.ParentID != pid):
+				p.fail(row, "parent of an existing product cannot be changed")
+			default:
+				parentID = &pid
+				p.variantParents[pid] = true
+			}
+		} else if !found && item.Category == "" {
+			p.fail(row, "category is required")
+		}
+
+		prod.Name = item.Name
+		if item.ExternalCode != "" {
+			prod.ExternalCode = item.ExternalCode
+		}
+		// Категория и цех модификации наследуются от родителя
+		if item.Parent == "" && !prod.IsVariant() {
+			if item.Category != "" {
+				prod.CategoryID = p.itemCategory(row, MenuItemTypeProduct, item.Category)
+			}
+			if item.Workshop != nil {
+				prod.WorkshopID = p.workshop(row, *item.Workshop)
+			}
+		}
+		if item.Description != nil {
+			prod.Description = *item.Description
+		}
+		if item.Barcode != nil {
+			prod.Barcode = *item.Barcode
+		}
+		p.nonNegative(row, map[string]*float64{"price": item.Price, "cost_price": item.CostPrice, "markup": item.Markup})
+		setMenuImportFloat(&prod.Price, item.Price)
+		setMenuImportFloat(&prod.CostPrice, item.CostPrice)
+		setMenuImportFloat(&prod.Markup, item.Markup)
+		if item.IsWeighted != nil {
+			prod.IsWeighted = *item.IsWeighted
+		}
+		if item.Active != nil {
+			prod.Active = *item.Active
+		}
+		p.productIndex.add(prod.ExternalCode, prod.Name, prod.ID)
+		p.products[prod.ID] = prod
+		openings = append(openings, opening{row: row, item: item, product: prod, isNew: !found})
+		if p.hasErrors(row) {
+			continue
+		}
+
+		active := prod.Active
+		switch {
+		case found:
+			p.addOp(row, func(ctx context.Context) error {
+				return p.uc.menu.UpdateProduct(ctx, prod)
+			})
+		case parentID != nil:
+			p.addOp(row, func(ctx context.Context) error {
+				if err := p.uc.menu.CreateProductVariant(ctx, *parentID, prod, p.warehouseID, p.establishmentID); err != nil {
+					return err
+				}
+				return p.deactivateProduct(ctx, prod, active)
+			})
+		default:
+			p.addOp(row, func(ctx context.Context) error {
+				if err := p.uc.menu.CreateProduct(ctx, prod, p.warehouseID, p.establishmentID); err != nil {
+					return err
+				}
+				return p.deactivateProduct(ctx, prod, active)
+			})
+		}
+	}
+
+	// Остаток товара с модификациями учитывается только по модификациям
+	for _, o := range openings {
+		if o.item.OpeningStock != nil && *o.item.OpeningStock != 0 && (p.variantParents[o.product.ID] || o.product.HasVariants()) {
+			p.fail(o.row, "%s", ErrProductHasVariants.Error()+": set opening stock on its variants")
+			continue
+		}
+		unit := "шт"
+		if o.product.IsWeighted {
+			unit = "кг"
+		}
+		p.openingStock(o.row, o.item, nil, &o.product.ID, unit, o.isNew)
+	}
+}
+
+// deactivateProduct сохраняет неактивность созданного товара: Active по умолчанию в БД не сохраняется как false
+func (p *menuImportPlan) deactivateProduct(ctx context.Context, prod *models.Product, active bool) error {
+	if active {
+		return nil
+	}
+	prod.Active = false
+	return p.uc.menu.UpdateProduct(ctx, prod)
+}
+
+func (p *menuImportPlan) planTechCards(items []MenuExchangeItem) {
+	seen := make(map[uuid.UUID]bool)
+	for i := range items {
+		item := &items[i]
+		row := p.addRow(MenuExchangeTypeTechCard, item)
+		if item.Name == "" {
+			continue
+		}
+		id, found := p.techCardIndex.find(item.ExternalCode, item.Name)
+		var tc *models.TechCard
+		if found {
+			tc = p.techCards[id]
+			p.setAction(row, MenuImportActionUpdate, id)
+		} else {
+			tc = &models.TechCard{ID: uuid.New(), EstablishmentID: p.establishmentID, Active: true}
+			p.setAction(row, MenuImportActionCreate, tc.ID)
+			if item.Category == "" {
+				p.fail(row, "category is required")
+			}
+		}
+		if p.duplicate(row, seen, tc.ID) {
+			continue
+		}
+
+		tc.Name = item.Name
+		if item.ExternalCode != "" {
+			tc.ExternalCode = item.ExternalCode
+		}
+		if item.Category != "" {
+			tc.CategoryID = p.itemCategory(row, MenuItemTypeTechCard, item.Category)
+		}
+		if item.Workshop != nil {
+			tc.WorkshopID = p.workshop(row, *item.Workshop)
+		}
+		if item.Description != nil {
+			tc.Description = *item.Description
+		}
+		p.nonNegative(row, map[string]*float64{"price": item.Price, "cost_price": item.CostPrice, "markup": item.Markup})
+		setMenuImportFloat(&tc.Price, item.Price)
+		setMenuImportFloat(&tc.CostPrice, item.CostPrice)
+		setMenuImportFloat(&tc.Markup, item.Markup)
+		if item.IsWeighted != nil {
+			tc.IsWeighted = *item.IsWeighted
+		}
+		if item.Active != nil {
+			tc.Active = *item.Active
+		}
+		if item.OpeningStock != nil {
+			p.fail(row, "opening stock can be set only for ingredients and products")
+		}
+
+		if len(item.Lines) > 0 {
+			tc.Ingredients = make([]models.TechCardIngredient, 0, len(item.Lines))
+			for _, line := range item.Lines {
+				itemID, ok := p.recipeLine(row, &line, true)
+				if !ok {
+					continue
+				}
+				recipeLine := models.TechCardIngredient{
+					Quantity:          line.Quantity,
+					Unit:              models.NormalizeUnit(line.Unit),
+					PreparationMethod: menuImportPreparation(line.PreparationMethod),
+				}
+				if line.ItemType == MenuExchangeTypeSemiFinished {
+					recipeLine.SemiFinishedID = &itemID
+				} else {
+					recipeLine.IngredientID = &itemID
+				}
+				tc.Ingredients = append(tc.Ingredients, recipeLine)
+			}
+		} else {
+			// Рецептура не задана: сохраняем текущую (репозиторий пересоздает позиции при обновлении)
+			for j := range tc.Ingredients {
+				tc.Ingredients[j].ID = uuid.Nil
+				tc.Ingredients[j].Ingredient = nil
+				tc.Ingredients[j].SemiFinished = nil
+			}
+		}
+		p.techCardIndex.add(tc.ExternalCode, tc.Name, tc.ID)
+		p.techCards[tc.ID] = tc
+		if p.hasErrors(row) {
+			continue
+		}
+
+		// Без себестоимости в файле она рассчитывается по рецептуре и остаткам склада импорта
+		recalculate := item.CostPrice == nil && len(item.Lines) > 0
+		if found {
+			p.addOp(row, func(ctx context.Context) error {
+				return p.uc.menu.UpdateTechCard(ctx, tc, p.warehouseID, p.establishmentID, recalculate)
+			})
+		} else {
+			active := tc.Active
+			p.addOp(row, func(ctx context.Context) error {
+				if err := p.uc.menu.CreateTechCard(ctx, tc, p.warehouseID, p.establishmentID, recalculate); err != nil {
+					return err
+				}
+				if active {
+					return nil
+				}
+				tc.Active = false
+				return p.uc.menu.UpdateTechCard(ctx, tc, p.warehouseID, p.establishmentID, false)
+			})
+		}
+	}
+}
+
+func setMenuImportFloat(field *float64, value *float64) {
+	if value != nil {
+		*field = *value
+	}
+}
+
+func menuImportPreparation(method string) *string {
+	if method == "" {
+		return nil
+	}
+	return &method
+}
+
+// ——— Export ———
+
+// Export выгружает меню заведения в CSV, XLSX или JSON в формате импорта: повторный импорт файла
+// обновляет те же позиции. С warehouseID в файл попадают остатки склада как начальные остатки.
+// Возвращает содержимое файла, MIME-тип и имя файла.
+func (uc *MenuImportUseCase) Export(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID, format string) ([]byte, string, string, error) {
+	if format == "" {
+		format = MenuExchangeFormatCSV
+	}
+	doc, err := uc.exportDocument(ctx, establishmentID, warehouseID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	data, contentType, err := writeMenuExchange(format, doc)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return data, contentType, "menu-" + time.Now().Format("2006-01-02") + "." + format, nil
+}
+
+func (uc *MenuImportUseCase) exportDocument(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID) (*MenuExchangeDocument, error) {
+	doc := &MenuExchangeDocument{}
+
+	stocks := make(map[uuid.UUID]*models.Stock)
+	if warehouseID != nil {
+		if _, err := uc.warehouseRepo.GetWarehouseByID(ctx, *warehouseID, &establishmentID); err != nil {
+			return nil, errors.New("warehouse not found")
+		}
+		list, err := uc.warehouseRepo.GetStockByWarehouseID(ctx, *warehouseID)
+		if err != nil {
+			return nil, err
+		}
+		for _, st := range list {
+			if st.Quantity == 0 {
+				continue
+			}
+			switch {
+			case st.IngredientID != nil:
+				stocks[*st.IngredientID] = st
+			case st.ProductID != nil:
+				stocks[*st.ProductID] = st
+			}
+		}
+	}
+	withStock := func(item *MenuExchangeItem, id uuid.UUID) {
+		if st, ok := stocks[id]; ok {
+			quantity, price := st.Quantity, st.PricePerUnit
+			item.OpeningStock = &quantity
+			item.OpeningPrice = &price
+		}
+	}
+
+	categories, err := uc.categoryRepo.List(ctx, &repositories.CategoryFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].Type != categories[j].Type {
+			return categories[i].Type < categories[j].Type
+		}
+		return categories[i].Name < categories[j].Name
+	})
+	for _, c := range categories {
+		doc.Categories = append(doc.Categories, MenuExchangeItem{Name: c.Name, CategoryType: c.Type})
+	}
+	ingredientCategories, err := uc.ingredientCategoryRepo.List(ctx, &repositories.IngredientCategoryFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ingredientCategories, func(i, j int) bool { return ingredientCategories[i].Name < ingredientCategories[j].Name })
+	for _, c := range ingredientCategories {
+		doc.Categories = append(doc.Categories, MenuExchangeItem{Name: c.Name, CategoryType: "ingredient"})
+	}
+
+	ingredients, err := uc.ingredientRepo.List(ctx, &repositories.IngredientFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ingredients, func(i, j int) bool { return ingredients[i].Name < ingredients[j].Name })
+	for _, ing := range ingredients {
+		item := MenuExchangeItem{
+			ExternalCode:  ing.ExternalCode,
+			Name:          ing.Name,
+			Unit:          ing.Unit,
+			Barcode:       exportText(ing.Barcode),
+			Active:        exportBool(ing.Active),
+			LossCleaning:  exportAmount(ing.LossCleaning),
+			LossBoiling:   exportAmount(ing.LossBoiling),
+			LossFrying:    exportAmount(ing.LossFrying),
+			LossStewing:   exportAmount(ing.LossStewing),
+			LossBaking:    exportAmount(ing.LossBaking),
+			Calories:      exportAmount(ing.Calories),
+			Proteins:      exportAmount(ing.Proteins),
+			Fats:          exportAmount(ing.Fats),
+			Carbohydrates: exportAmount(ing.Carbohydrates),
+		}
+		if ing.Category != nil {
+			item.Category = ing.Category.Name
+		}
+		if ing.ShelfLifeDays > 0 {
+			days := ing.ShelfLifeDays
+			item.ShelfLifeDays = &days
+		}
+		if len(ing.Allergens) > 0 {
+			allergens := strings.Join(ing.Allergens, ",")
+			item.Allergens = &allergens
+		}
+		withStock(&item, ing.ID)
+		doc.Ingredients = append(doc.Ingredients, item)
+	}
+
+	semiFinished, err := uc.semiFinishedRepo.List(ctx, &repositories.SemiFinishedFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(semiFinished, func(i, j int) bool { return semiFinished[i].Name < semiFinished[j].Name })
+	for _, sf := range semiFinished {
+		item := MenuExchangeItem{
+			ExternalCode:   sf.ExternalCode,
+			Name:           sf.Name,
+			Unit:           sf.Unit,
+			Description:    exportText(sf.Description),
+			CookingProcess: exportText(sf.CookingProcess),
+			Yield:          exportAmount(sf.Quantity),
+			Active:         exportBool(sf.Active),
+		}
+		if sf.Category != nil {
+			item.Category = sf.Category.Name
+		}
+		if sf.Workshop != nil {
+			item.Workshop = exportText(sf.Workshop.Name)
+		}
+		for _, line := range sf.Ingredients {
+			if line.Ingredient == nil {
+				continue
+			}
+			item.Lines = append(item.Lines, MenuExchangeLine{
+				ItemType:          MenuExchangeTypeIngredient,
+				Item:              menuExchangeRef(line.Ingredient.ExternalCode, line.Ingredient.Name),
+				Quantity:          line.Net,
+				Unit:              line.Unit,
+				PreparationMethod: menuExportPreparation(line.PreparationMethod),
+			})
+		}
+		doc.SemiFinished = append(doc.SemiFinished, item)
+	}
+
+	products, err := uc.productRepo.List(ctx, &repositories.ProductFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*models.Product, len(products))
+	for _, prod := range products {
+		byID[prod.ID] = prod
+	}
+	// Родительские товары раньше модификаций, иначе повторный импорт не найдет родителя
+	sort.SliceStable(products, func(i, j int) bool {
+		if products[i].IsVariant() != products[j].IsVariant() {
+			return !products[i].IsVariant()
+		}
+		return products[i].Name < products[j].Name
+	})
+	for _, prod := range products {
+		item := MenuExchangeItem{
+			ExternalCode: prod.ExternalCode,
+			Name:         prod.Name,
+			Description:  exportText(prod.Description),
+			Barcode:      exportText(prod.Barcode),
+			Price:        exportFloat(prod.Price),
+			CostPrice:    exportFloat(prod.CostPrice),
+			Markup:       exportFloat(prod.Markup),
+			IsWeighted:   exportBool(prod.IsWeighted),
+			Active:       exportBool(prod.Active),
+		}
+		if prod.IsVariant() {
+			if parent, ok := byID[*prod.ParentID]; ok {
+				item.Parent = menuExchangeRef(parent.ExternalCode, parent.Name)
+			}
+		} else {
+			if prod.Category != nil {
+				item.Category = prod.Category.Name
+			}
+			if prod.Workshop != nil {
+				item.Workshop = exportText(prod.Workshop.Name)
+			}
+		}
+		withStock(&item, prod.ID)
+		doc.Products = append(doc.Products, item)
+	}
+
+	techCards, err := uc.techCardRepo.List(ctx, &repositories.TechCardFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(techCards, func(i, j int) bool { return techCards[i].Name < techCards[j].Name })
+	for _, tc := range techCards {
+		item := MenuExchangeItem{
+			ExternalCode: tc.ExternalCode,
+			Name:         tc.Name,
+			Description:  exportText(tc.Description),
+			Price:        exportFloat(tc.Price),
+			CostPrice:    exportFloat(tc.CostPrice),
+			Markup:       exportFloat(tc.Markup),
+			IsWeighted:   exportBool(tc.IsWeighted),
+			Active:       exportBool(tc.Active),
+		}
+		if tc.Category != nil {
+			item.Category = tc.Category.Name
+		}
+		if tc.Workshop != nil {
+			item.Workshop = exportText(tc.Workshop.Name)
+		}
+		for _, line := range tc.Ingredients {
+			exported := MenuExchangeLine{
+				Quantity:          line.Quantity,
+				Unit:              line.Unit,
+				PreparationMethod: menuExportPreparation(line.PreparationMethod),
+			}
+			switch {
+			case line.Ingredient != nil:
+				exported.ItemType = MenuExchangeTypeIngredient
+				exported.Item = menuExchangeRef(line.Ingredient.ExternalCode, line.Ingredient.Name)
+			case line.SemiFinished != nil:
+				exported.ItemType = MenuExchangeTypeSemiFinished
+				exported.Item = menuExchangeRef(line.SemiFinished.ExternalCode, line.SemiFinished.Name)
+			default:
+				continue
+			}
+			item.Lines = append(item.Lines, exported)
+		}
+		doc.TechCards = append(doc.TechCards, item)
+	}
+	return doc, nil
+}
+
+// menuExchangeRef ссылка на позицию в файле: внешний код, а если его нет — название
+func menuExchangeRef(code, name string) string {
+	if code != "" {
+		return code
+	}
+	return name
+}
+
+func exportText(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func exportFloat(v float64) *float64 {
+	return &v
+}
+
+// exportAmount пропускает нулевые значения необязательных числовых полей
+func exportAmount(v float64) *float64 {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
+func exportBool(v bool) *bool {
+	return &v
+}
+
+func menuExportPreparation(method *string) string {
+	if method == nil {
+		return ""
+	}
+	return *method
+}
@@ -0,0 +1,278 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// Репозитории меню для импорта и выгрузки: списки позиций заведения в памяти
+type fakeCategoryRepository struct {
+	repositories.CategoryRepository
+	items     []*models.Category
+	createErr map[string]error // Ошибка создания по названию категории
+}
+
+func (r *fakeCategoryRepository) List(ctx context.Context, filter *repositories.CategoryFilter) ([]*models.Category, error) {
+	return r.items, nil
+}
+
+func (r *fakeCategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	if err := r.createErr[category.Name]; err != nil {
+		return err
+	}
+	r.items = append(r.items, category)
+	return nil
+}
+
+type fakeIngredientCategoryRepository struct {
+	repositories.IngredientCategoryRepository
+	items []*models.IngredientCategory
+}
+
+func (r *fakeIngredientCategoryRepository) List(ctx context.Context, filter *repositories.IngredientCategoryFilter) ([]*models.IngredientCategory, error) {
+	return r.items, nil
+}
+
+type fakeIngredientRepository struct {
+	repositories.IngredientRepository
+	items []*models.Ingredient
+}
+
+func (r *fakeIngredientRepository) List(ctx context.Context, filter *repositories.IngredientFilter) ([]*models.Ingredient, error) {
+	return r.items, nil
+}
+
+type fakeSemiFinishedRepository struct {
+	repositories.SemiFinishedRepository
+	items []*models.SemiFinishedProduct
+}
+
+func (r *fakeSemiFinishedRepository) List(ctx context.Context, filter *repositories.SemiFinishedFilter) ([]*models.SemiFinishedProduct, error) {
+	return r.items, nil
+}
+
+type fakeProductRepository struct {
+	repositories.ProductRepository
+	items []*models.Product
+}
+
+func (r *fakeProductRepository) List(ctx context.Context, filter *repositories.ProductFilter) ([]*models.Product, error) {
+	return r.items, nil
+}
+
+func (r *fakeProductRepository) Update(ctx context.Context, product *models.Product) error {
+	for i, p := range r.items {
+		if p.ID == product.ID {
+			r.items[i] = product
+		}
+	}
+	return nil
+}
+
+func (r *fakeProductRepository) CountVariants(ctx context.Context, parentID uuid.UUID) (int64, error) {
+	var count int64
+	for _, p := range r.items {
+		if p.ParentID != nil && *p.ParentID == parentID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeProductRepository) SyncVariants(ctx context.Context, parent *models.Product) error {
+	return nil
+}
+
+type fakeTechCardRepository struct {
+	repositories.TechCardRepository
+	items []*models.TechCard
+}
+
+func (r *fakeTechCardRepository) List(ctx context.Context, filter *repositories.TechCardFilter) ([]*models.TechCard, error) {
+	return r.items, nil
+}
+
+func (r *fakeTechCardRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.TechCard, error) {
+	for _, tc := range r.items {
+		if tc.ID == id {
+			return tc, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+type fakeWorkshopRepository struct {
+	repositories.WorkshopRepository
+	items []*models.Workshop
+}
+
+func (r *fakeWorkshopRepository) ListWorkshops(ctx context.Context, establishmentID uuid.UUID) ([]*models.Workshop, error) {
+	return r.items, nil
+}
+
+type menuImportWarehouseRepository struct {
+	*fakeWarehouseRepository
+}
+
+func (r *menuImportWarehouseRepository) GetStockByWarehouseID(ctx context.Context, warehouseID uuid.UUID) ([]*models.Stock, error) {
+	var stocks []*models.Stock
+	for _, st := range r.stocks {
+		if st.WarehouseID == warehouseID {
+			cp := *st
+			stocks = append(stocks, &cp)
+		}
+	}
+	return stocks, nil
+}
+
+type menuImportFixture struct {
+	uc              *MenuImportUseCase
+	categories      *fakeCategoryRepository
+	transactor      *fakeTransactor
+	establishmentID uuid.UUID
+	warehouseID     uuid.UUID
+}
+
+// newMenuImportFixture меню заведения со всеми видами позиций: категории, цех, ингредиенты,
+// полуфабрикат, товар с модификацией и тех-карта, и остатки на складе
+func newMenuImportFixture(t *testing.T) *menuImportFixture {
+	warehouses := &menuImportWarehouseRepository{newFakeWarehouseRepository()}
+	warehouseID := warehouses.addWarehouse()
+
+	drinks := &models.Category{ID: uuid.New(), Name: "Напитки", Type: MenuItemTypeProduct}
+	hot := &models.Category{ID: uuid.New(), Name: "Горячее", Type: MenuItemTypeTechCard}
+	prep := &models.Category{ID: uuid.New(), Name: "Заготовки", Type: "semi_finished"}
+	grocery := &models.IngredientCategory{ID: uuid.New(), Name: "Бакалея"}
+	kitchen := &models.Workshop{ID: uuid.New(), Name: "Кухня"}
+
+	flour := &models.Ingredient{
+		ID: uuid.New(), ExternalCode: "ING-1", Name: "Мука", Unit: models.UnitKilogram, CategoryID: grocery.ID, Category: grocery,
+		ShelfLifeDays: 180, LossCleaning: 5, Allergens: models.AllergenList{models.AllergenGluten}, Active: true,
+	}
+	egg := &models.Ingredient{ID: uuid.New(), Name: "Яйцо", Unit: models.UnitPiece, CategoryID: grocery.ID, Category: grocery, Active: true}
+	cleaning := "cleaning"
+	dough := &models.SemiFinishedProduct{
+		ID: uuid.New(), ExternalCode: "SF-1", Name: "Тесто", Unit: models.UnitKilogram, Quantity: 1.5,
+		CategoryID: &prep.ID, Category: prep, WorkshopID: &kitchen.ID, Workshop: kitchen, Active: true,
+		Ingredients: []models.SemiFinishedIngredient{
+			{IngredientID: flour.ID, Ingredient: flour, Net: 1, Unit: models.UnitKilogram, PreparationMethod: &cleaning},
+		},
+	}
+	lemonade := &models.Product{
+		ID: uuid.New(), ExternalCode: "P-1", Name: "Лимонад", CategoryID: drinks.ID, Category: drinks,
+		Price: 150, CostPrice: 50, HasModifications: true, Active: true,
+	}
+	largeLemonade := &models.Product{
+		ID: uuid.New(), Name: "Лимонад 0,5", CategoryID: drinks.ID, Category: drinks, ParentID: &lemonade.ID, Price: 200, Active: true,
+	}
+	pancakes := &models.TechCard{
+		ID: uuid.New(), Name: "Блины", CategoryID: hot.ID, Category: hot, WorkshopID: &kitchen.ID, Workshop: kitchen, Price: 300, Active: true,
+		Ingredients: []models.TechCardIngredient{
+			{SemiFinishedID: &dough.ID, SemiFinished: dough, Quantity: 0.2, Unit: models.UnitKilogram},
+			{IngredientID: &egg.ID, Ingredient: egg, Quantity: 1, Unit: models.UnitPiece},
+		},
+	}
+
+	warehouses.addStock(warehouseID, flour.ID, 10, 40)
+	require.NoError(t, warehouses.CreateStock(context.Background(), &models.Stock{
+		WarehouseID: warehouseID, ProductID: &largeLemonade.ID, Quantity: 5, Unit: models.UnitPiece, PricePerUnit: 60,
+	}))
+
+	categories := &fakeCategoryRepository{items: []*models.Category{drinks, hot, prep}}
+	transactor := &fakeTransactor{}
+	uc := NewMenuImportUseCase(
+		&MenuUseCase{categoryRepo: categories},
+		categories,
+		&fakeIngredientCategoryRepository{items: []*models.IngredientCategory{grocery}},
+		&fakeIngredientRepository{items: []*models.Ingredient{flour, egg}},
+		&fakeSemiFinishedRepository{items: []*models.SemiFinishedProduct{dough}},
+		&fakeProductRepository{items: []*models.Product{largeLemonade, lemonade}},
+		&fakeTechCardRepository{items: []*models.TechCard{pancakes}},
+		&fakeWorkshopRepository{items: []*models.Workshop{kitchen}},
+		warehouses,
+		transactor,
+	)
+	return &menuImportFixture{
+		uc:              uc,
+		categories:      categories,
+		transactor:      transactor,
+		establishmentID: uuid.New(),
+		warehouseID:     warehouseID,
+	}
+}
+
+func TestMenuImportUseCase_ExportImportRoundTrip(t *testing.T) {
+	for _, format := range []string{MenuExchangeFormatCSV, MenuExchangeFormatXLSX, MenuExchangeFormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			ctx := context.Background()
+			f := newMenuImportFixture(t)
+
+			data, _, filename, err := f.uc.Export(ctx, f.establishmentID, &f.warehouseID, format)
+			require.NoError(t, err)
+			report, err := f.uc.Import(ctx, f.establishmentID, f.warehouseID, "", filename, data, true)
+			require.NoError(t, err)
+
+			// Повторный импорт выгрузки находит все позиции и ничего не создает
+			assert.Equal(t, format, report.Format)
+			assert.True(t, report.Valid)
+			assert.False(t, report.Applied)
+			assert.Zero(t, report.Created)
+			assert.Equal(t, 6, report.Updated)
+			require.Len(t, report.Rows, 10)
+			for _, row := range report.Rows {
+				assert.Empty(t, row.Errors, "%s %s", row.Type, row.Name)
+				if row.Type == MenuExchangeTypeCategory {
+					assert.Equal(t, MenuImportActionExists, row.Action, row.Name)
+				} else {
+					assert.Equal(t, MenuImportActionUpdate, row.Action, row.Name)
+				}
+			}
+			// Остатки уже есть на складе: начальный остаток не удваивается
+			var skipped int
+			for _, row := range report.Rows {
+				for _, w := range row.Warnings {
+					assert.Contains(t, w, "opening stock skipped")
+					skipped++
+				}
+			}
+			assert.Equal(t, 2, skipped)
+			assert.Zero(t, f.transactor.calls)
+		})
+	}
+}
+
+func TestMenuImportUseCase_Import_SavesInTransaction(t *testing.T) {
+	ctx := context.Background()
+	csv := []byte("type,name,category_type\ncategory,Десерты,tech_card\ncategory,Супы,tech_card\n")
+
+	t.Run("applies all rows", func(t *testing.T) {
+		f := newMenuImportFixture(t)
+		report, err := f.uc.Import(ctx, f.establishmentID, f.warehouseID, "", "menu.csv", csv, false)
+		require.NoError(t, err)
+		assert.True(t, report.Applied)
+		assert.Equal(t, 2, report.Created)
+		assert.Equal(t, 1, f.transactor.calls)
+		assert.Len(t, f.categories.items, 5)
+	})
+
+	t.Run("failed row fails the import", func(t *testing.T) {
+		f := newMenuImportFixture(t)
+		f.categories.createErr = map[string]error{"Супы": errors.New("connection reset")}
+		report, err := f.uc.Import(ctx, f.establishmentID, f.warehouseID, "", "menu.csv", csv, false)
+		assert.ErrorIs(t, err, ErrMenuImportInvalid)
+		assert.Equal(t, 1, f.transactor.calls)
+		assert.False(t, report.Valid)
+		assert.False(t, report.Applied)
+		assert.Empty(t, report.Rows[0].Errors)
+		require.Len(t, report.Rows[1].Errors, 1)
+		assert.Contains(t, report.Rows[1].Errors[0], "not saved: connection reset")
+	})
+}
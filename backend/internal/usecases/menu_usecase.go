@@ -3,6 +3,8 @@ package usecases
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,6 +12,22 @@ import (
 	"github.com/yourusername/arc/backend/internal/repositories"
 )
 
+// ErrProductHasVariants товар с модификациями продается, поступает на склад и инвентаризуется только через модификации
+var ErrProductHasVariants = errors.New("product has variants, select a variant")
+
+// soldByVariants проверяет, продается ли товар только через модификации. Флаг has_modifications
+// сверяется с числом модификаций: товар с флагом, но без модификаций продается и учитывается сам
+func soldByVariants(product *models.Product, countVariants func() (int64, error)) (bool, error) {
+	if !product.HasVariants() {
+		return false, nil
+	}
+	count, err := countVariants()
+	if err != nil {
+		return false, fmt.Errorf("failed to count variants of %q: %w", product.Name, err)
+	}
+	return count > 0, nil
+}
+
 type MenuUseCase struct {
 	productRepo          repositories.ProductRepository
 	techCardRepo         repositories.TechCardRepository
@@ -18,6 +36,8 @@ type MenuUseCase struct {
 	categoryRepo         repositories.CategoryRepository
 	ingredientCategoryRepo repositories.IngredientCategoryRepository
 	warehouseRepo        repositories.WarehouseRepository
+	availability         *AvailabilityUseCase
+	costHistory          *CostHistoryUseCase // Назначается после создания: CostHistoryUseCase сам зависит от MenuUseCase
 }
 
 func NewMenuUseCase(
@@ -28,6 +48,7 @@ func NewMenuUseCase(
 	categoryRepo repositories.CategoryRepository,
 	ingredientCategoryRepo repositories.IngredientCategoryRepository,
 	warehouseRepo repositories.WarehouseRepository,
+	availability *AvailabilityUseCase,
 ) *MenuUseCase {
 	return &MenuUseCase{
 		productRepo:           productRepo,
@@ -37,12 +58,15 @@ func NewMenuUseCase(
 		categoryRepo:          categoryRepo,
 		ingredientCategoryRepo: ingredientCategoryRepo,
 		warehouseRepo:         warehouseRepo,
+		availability:          availability,
 	}
 }
 
 // CreateProduct создает товар и автоматически добавляет его в остатки с количеством 0
 func (uc *MenuUseCase) CreateProduct(ctx context.Context, product *models.Product, warehouseID, establishmentID uuid.UUID) error {
 	product.EstablishmentID = establishmentID
+	// Модификации добавляются к уже созданному товару, новый товар продается сам
+	product.HasModifications = false
 	product.CalculatePrice()
 
 	if err := uc.productRepo.Create(ctx, product); err != nil {
@@ -66,32 +90,164 @@ func (uc *MenuUseCase) CreateProduct(ctx context.Context, product *models.Produc
 		return err
 	}
 
+	uc.costHistory.TrackProduct(ctx, product, models.CostChangeReasonManual)
 	return nil
 }
 
-// UpdateProduct обновляет товар
+// UpdateProduct обновляет товар. Модификация наследует категорию, цех и склад родителя,
+// а изменения этих полей у родителя переносятся на его модификации
 func (uc *MenuUseCase) UpdateProduct(ctx context.Context, product *models.Product) error {
+	if product.IsVariant() {
+		parent, err := uc.productRepo.GetByID(ctx, *product.ParentID, &product.EstablishmentID)
+		if err != nil {
+			return fmt.Errorf("parent product not found: %w", err)
+		}
+		product.CategoryID = parent.CategoryID
+		product.WorkshopID = parent.WorkshopID
+		product.WarehouseID = parent.WarehouseID
+		product.HasModifications = false
+	} else {
+		// Флаг следует за фактическими модификациями, а не за телом запроса
+		count, err := uc.productRepo.CountVariants(ctx, product.ID)
+		if err != nil {
+			return err
+		}
+		product.HasModifications = count > 0
+	}
+
 	// Пересчитываем цену при обновлении
 	product.CalculatePrice()
-	return uc.productRepo.Update(ctx, product)
+	if err := uc.productRepo.Update(ctx, product); err != nil {
+		return err
+	}
+	if product.HasVariants() {
+		if err := uc.productRepo.SyncVariants(ctx, product); err != nil {
+			return err
+		}
+	}
+	uc.costHistory.TrackProduct(ctx, product, models.CostChangeReasonManual)
+	return nil
 }
 
-// DeleteProduct удаляет товар (soft delete), только если он принадлежит заведению
+// DeleteProduct удаляет товар (soft delete) вместе с модификациями, только если он принадлежит заведению.
+// После удаления последней модификации родительский товар снова продается сам
 func (uc *MenuUseCase) DeleteProduct(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) error {
-	if _, err := uc.productRepo.GetByID(ctx, id, &establishmentID); err != nil {
+	product, err := uc.productRepo.GetByID(ctx, id, &establishmentID)
+	if err != nil {
 		return err
 	}
-	return uc.productRepo.Delete(ctx, id)
+	if err := uc.productRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if !product.IsVariant() {
+		return nil
+	}
+	count, err := uc.productRepo.CountVariants(ctx, *product.ParentID)
+	if err != nil || count > 0 {
+		return err
+	}
+	parent, err := uc.productRepo.GetByID(ctx, *product.ParentID, &establishmentID)
+	if err != nil {
+		return err
+	}
+	parent.HasModifications = false
+	return uc.productRepo.Update(ctx, parent)
 }
 
 // GetProducts возвращает список товаров с фильтрацией
 func (uc *MenuUseCase) GetProducts(ctx context.Context, filter *repositories.ProductFilter) ([]*models.Product, error) {
-	return uc.productRepo.List(ctx, filter)
+	products, err := uc.productRepo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if filter == nil || !filter.AvailableNow || filter.EstablishmentID == nil {
+		return products, nil
+	}
+	availability, err := uc.availability.Snapshot(ctx, *filter.EstablishmentID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*models.Product, 0, len(products))
+	for _, p := range products {
+		if availability.IsAvailable(p.AvailabilityScheduleID, p.CategoryID) {
+			result = append(result, p)
+		}
+	}
+	return result, nil
 }
 
-// GetProductByID возвращает товар по ID (с проверкой заведения)
+// GetProductByID возвращает товар по ID (с проверкой заведения) вместе с модификациями
 func (uc *MenuUseCase) GetProductByID(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) (*models.Product, error) {
-	return uc.productRepo.GetByID(ctx, id, &establishmentID)
+	product, err := uc.productRepo.GetByID(ctx, id, &establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	if product.HasVariants() {
+		variants, err := uc.productRepo.List(ctx, &repositories.ProductFilter{EstablishmentID: &establishmentID, ParentID: &product.ID})
+		if err != nil {
+			return nil, err
+		}
+		product.Variants = make([]models.Product, 0, len(variants))
+		for _, v := range variants {
+			product.Variants = append(product.Variants, *v)
+		}
+	}
+	return product, nil
+}
+
+// ——— Product variants (модификации товара) ———
+
+// GetProductVariants возвращает модификации товара
+func (uc *MenuUseCase) GetProductVariants(ctx context.Context, parentID uuid.UUID, establishmentID uuid.UUID) ([]*models.Product, error) {
+	if _, err := uc.productRepo.GetByID(ctx, parentID, &establishmentID); err != nil {
+		return nil, err
+	}
+	return uc.productRepo.List(ctx, &repositories.ProductFilter{EstablishmentID: &establishmentID, ParentID: &parentID})
+}
+
+// CreateProductVariant создает модификацию товара (например, 0,33 л или 0,5 л) со своими названием, ценой,
+// себестоимостью, штрихкодом и остатком на складе. Категория, цех, склад и опции наследуются от родителя,
+// родитель помечается как товар с модификациями и дальше продается только через них
+func (uc *MenuUseCase) CreateProductVariant(ctx context.Context, parentID uuid.UUID, variant *models.Product, warehouseID, establishmentID uuid.UUID) error {
+	parent, err := uc.productRepo.GetByID(ctx, parentID, &establishmentID)
+	if err != nil {
+		return err
+	}
+	if parent.IsVariant() {
+		return errors.New("a variant cannot have its own variants")
+	}
+	if !parent.HasModifications {
+		// Остаток родительского товара нельзя автоматически распределить по модификациям
+		stocks, err := uc.warehouseRepo.GetStockByProductID(ctx, parent.ID)
+		if err != nil {
+			return err
+		}
+		for _, st := range stocks {
+			if st.Quantity != 0 {
+				return fmt.Errorf("product %q has stock on hand; write it off or adjust it before adding variants", parent.Name)
+			}
+		}
+	}
+
+	variant.ParentID = &parent.ID
+	variant.CategoryID = parent.CategoryID
+	variant.WorkshopID = parent.WorkshopID
+	variant.WarehouseID = parent.WarehouseID
+	variant.IsWeighted = parent.IsWeighted
+	variant.ExcludeFromDiscounts = parent.ExcludeFromDiscounts
+	variant.HasModifications = false
+	if variant.CoverImage == "" {
+		variant.CoverImage = parent.CoverImage
+	}
+	if err := uc.CreateProduct(ctx, variant, warehouseID, establishmentID); err != nil {
+		return err
+	}
+
+	if !parent.HasModifications {
+		parent.HasModifications = true
+		return uc.productRepo.Update(ctx, parent)
+	}
+	return nil
 }
 
 // CreateTechCard создает тех-карту
@@ -99,6 +255,10 @@ func (uc *MenuUseCase) GetProductByID(ctx context.Context, id uuid.UUID, establi
 func (uc *MenuUseCase) CreateTechCard(ctx context.Context, techCard *models.TechCard, warehouseID, establishmentID uuid.UUID, recalculateCost bool) error {
 	techCard.EstablishmentID = establishmentID
 
+	if err := uc.validateTechCardUnits(ctx, techCard, establishmentID); err != nil {
+		return err
+	}
+
 	// Пересчитываем себестоимость если:
 	// 1. Запрошен принудительный пересчет (recalculateCost = true)
 	// 2. ИЛИ если cost_price не задан вручную (равен 0) и есть ингредиенты
@@ -129,12 +289,20 @@ func (uc *MenuUseCase) CreateTechCard(ctx context.Context, techCard *models.Tech
 	}
 
 	techCard.CalculatePrice()
-	return uc.techCardRepo.Create(ctx, techCard)
+	if err := uc.techCardRepo.Create(ctx, techCard); err != nil {
+		return err
+	}
+	uc.costHistory.TrackTechCard(ctx, techCard, models.CostChangeReasonManual)
+	return nil
 }
 
 // UpdateTechCard обновляет тех-карту (проверка заведения через techCard.EstablishmentID при GetByID перед вызовом)
 // recalculateCost - если true, принудительно пересчитать себестоимость из ингредиентов
 func (uc *MenuUseCase) UpdateTechCard(ctx context.Context, techCard *models.TechCard, warehouseID, establishmentID uuid.UUID, recalculateCost bool) error {
+	if err := uc.validateTechCardUnits(ctx, techCard, establishmentID); err != nil {
+		return err
+	}
+
 	// Пересчитываем себестоимость если:
 	// 1. Запрошен принудительный пересчет (recalculateCost = true)
 	// 2. ИЛИ если cost_price не задан вручную (равен 0) и есть ингредиенты
@@ -161,7 +329,11 @@ func (uc *MenuUseCase) UpdateTechCard(ctx context.Context, techCard *models.Tech
 	}
 
 	techCard.CalculatePrice()
-	return uc.techCardRepo.Update(ctx, techCard)
+	if err := uc.techCardRepo.Update(ctx, techCard); err != nil {
+		return err
+	}
+	uc.costHistory.TrackTechCard(ctx, techCard, models.CostChangeReasonManual)
+	return nil
 }
 
 // DeleteTechCard удаляет тех-карту (soft delete)
@@ -174,19 +346,128 @@ func (uc *MenuUseCase) DeleteTechCard(ctx context.Context, id uuid.UUID, establi
 
 // GetTechCards возвращает список тех-карт с фильтрацией
 func (uc *MenuUseCase) GetTechCards(ctx context.Context, filter *repositories.TechCardFilter) ([]*models.TechCard, error) {
-	return uc.techCardRepo.List(ctx, filter)
+	techCards, err := uc.techCardRepo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var availability *MenuAvailability
+	if filter != nil && filter.AvailableNow && filter.EstablishmentID != nil {
+		if availability, err = uc.availability.Snapshot(ctx, *filter.EstablishmentID); err != nil {
+			return nil, err
+		}
+	}
+	result := make([]*models.TechCard, 0, len(techCards))
+	for _, techCard := range techCards {
+		fillTechCardGrossNet(techCard)
+		if filter != nil && len(filter.ExcludeAllergens) > 0 && techCard.Allergens.ContainsAny(filter.ExcludeAllergens) {
+			continue
+		}
+		if !availability.IsAvailable(techCard.AvailabilityScheduleID, techCard.CategoryID) {
+			continue
+		}
+		result = append(result, techCard)
+	}
+	return result, nil
 }
 
 // GetTechCardByID возвращает тех-карту по ID
 func (uc *MenuUseCase) GetTechCardByID(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) (*models.TechCard, error) {
-	return uc.techCardRepo.GetByID(ctx, id, &establishmentID)
+	techCard, err := uc.techCardRepo.GetByID(ctx, id, &establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	fillTechCardGrossNet(techCard)
+	return techCard, nil
+}
+
+// GetPublicMenu возвращает меню заведения для гостей: активные тех-карты и товары по категориям.
+// Тех-карты, содержащие любой из excludeAllergens, не попадают в меню; у товаров состав не ведется, они не фильтруются.
+// При availableNow в меню остаются только позиции, доступные сейчас по расписанию.
+func (uc *MenuUseCase) GetPublicMenu(ctx context.Context, establishmentID uuid.UUID, excludeAllergens []string, availableNow bool) (*models.PublicMenu, error) {
+	active := true
+	techCards, err := uc.GetTechCards(ctx, &repositories.TechCardFilter{
+		EstablishmentID:  &establishmentID,
+		Active:           &active,
+		ExcludeAllergens: excludeAllergens,
+		AvailableNow:     availableNow,
+	})
+	if err != nil {
+		return nil, err
+	}
+	products, err := uc.GetProducts(ctx, &repositories.ProductFilter{EstablishmentID: &establishmentID, Active: &active, OnlyParents: true, AvailableNow: availableNow})
+	if err != nil {
+		return nil, err
+	}
+	categories, err := uc.categoryRepo.List(ctx, &repositories.CategoryFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[uuid.UUID][]models.PublicMenuItem)
+	for _, tc := range techCards {
+		items[tc.CategoryID] = append(items[tc.CategoryID], models.PublicMenuItem{
+			ID:          tc.ID,
+			Type:        MenuItemTypeTechCard,
+			Name:        tc.Name,
+			Description: tc.Description,
+			CoverImage:  tc.CoverImage,
+			Price:       tc.Price,
+			IsWeighted:  tc.IsWeighted,
+			Nutrition:   tc.Nutrition,
+			Allergens:   tc.Allergens,
+		})
+	}
+	for _, p := range products {
+		item := models.PublicMenuItem{
+			ID:          p.ID,
+			Type:        MenuItemTypeProduct,
+			Name:        p.Name,
+			Description: p.Description,
+			CoverImage:  p.CoverImage,
+			Price:       p.Price,
+			IsWeighted:  p.IsWeighted,
+		}
+		if p.HasVariants() {
+			if len(p.Variants) == 0 {
+				continue
+			}
+			for _, v := range p.Variants {
+				item.Variants = append(item.Variants, models.PublicMenuVariant{ID: v.ID, Name: v.Name, Price: v.Price})
+			}
+		}
+		items[p.CategoryID] = append(items[p.CategoryID], item)
+	}
+
+	menu := &models.PublicMenu{EstablishmentID: establishmentID, Categories: make([]models.PublicMenuCategory, 0)}
+	for _, cat := range categories {
+		if len(items[cat.ID]) == 0 {
+			continue
+		}
+		menu.Categories = append(menu.Categories, models.PublicMenuCategory{
+			ID:    cat.ID,
+			Name:  cat.Name,
+			Items: items[cat.ID],
+		})
+	}
+	return menu, nil
+}
+
+// fillTechCardGrossNet заполняет разбивку брутто/нетто по позициям тех-карты, пищевую ценность порции и аллергены
+func fillTechCardGrossNet(techCard *models.TechCard) {
+	if techCard == nil {
+		return
+	}
+	for i := range techCard.Ingredients {
+		techCard.Ingredients[i].FillGrossNet()
+	}
+	techCard.CalculateComposition()
 }
 
 // CreateIngredient создает ингредиент и автоматически создает остатки на складе
 func (uc *MenuUseCase) CreateIngredient(ctx context.Context, ingredient *models.Ingredient, warehouseID uuid.UUID, quantity float64, pricePerUnit float64, supplierID uuid.UUID, establishmentID uuid.UUID) error {
 	ingredient.EstablishmentID = establishmentID
-	if !ingredient.ValidateUnit() {
-		return errors.New("invalid unit, must be one of: шт, л, кг")
+	if err := validateIngredientUnits(ingredient); err != nil {
+		return err
 	}
 
 	if err := uc.ingredientRepo.Create(ctx, ingredient); err != nil {
@@ -206,10 +487,19 @@ func (uc *MenuUseCase) CreateIngredient(ctx context.Context, ingredient *models.
 		if err := uc.warehouseRepo.CreateStock(ctx, stock); err != nil {
 			return err
 		}
+		// При указанном поставщике начальный остаток оформляется поставкой
+		ledger := stockLedgerSource{Type: models.StockLedgerOpening}
+		if supplierID != uuid.Nil {
+			ledger = stockLedgerSource{Type: models.StockLedgerSupply, ID: uuid.New()}
+		}
+		if err := postStockLedger(ctx, uc.warehouseRepo, stock, stock.Quantity, 0, ledger); err != nil {
+			return err
+		}
 
 		// Если указан поставщик, создаем автоматическую поставку
 		if supplierID != uuid.Nil {
 			supply := &models.Supply{
+				ID:              ledger.ID,
 				WarehouseID:     warehouseID,
 				SupplierID:      supplierID,
 				DeliveryDateTime: time.Now(),
@@ -238,13 +528,142 @@ func (uc *MenuUseCase) CreateIngredient(ctx context.Context, ingredient *models.
 
 // UpdateIngredient обновляет ингредиент
 func (uc *MenuUseCase) UpdateIngredient(ctx context.Context, ingredient *models.Ingredient) error {
-	// Валидация единицы измерения
-	if !ingredient.ValidateUnit() {
-		return errors.New("invalid unit, must be one of: шт, л, кг")
+	// Валидация единицы измерения и пересчетов
+	if err := validateIngredientUnits(ingredient); err != nil {
+		return err
 	}
 	return uc.ingredientRepo.Update(ctx, ingredient)
 }
 
+// validateIngredientUnits приводит единицу ингредиента к каноническому виду и проверяет пересчеты
+func validateIngredientUnits(ingredient *models.Ingredient) error {
+	ingredient.Unit = models.NormalizeUnit(ingredient.Unit)
+	if !ingredient.ValidateUnit() {
+		return fmt.Errorf("invalid unit, must be one of: %s", strings.Join(models.ValidIngredientUnits(), ", "))
+	}
+	for i := range ingredient.UnitConversions {
+		c := &ingredient.UnitConversions[i]
+		c.Unit = models.NormalizeUnit(c.Unit)
+		c.TargetUnit = models.NormalizeUnit(c.TargetUnit)
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("invalid unit conversion %s → %s: %w", c.Unit, c.TargetUnit, err)
+		}
+	}
+	return nil
+}
+
+// convertRecipeQuantity переводит количество из единицы рецептуры в единицу unit
+// (обычно единицу учета остатка) с учетом пересчетов ингредиента
+func (uc *MenuUseCase) convertRecipeQuantity(ctx context.Context, ingredientID uuid.UUID, quantity float64, from, to string, establishmentID uuid.UUID) (float64, error) {
+	ingredient, err := uc.ingredientRepo.GetByID(ctx, ingredientID, &establishmentID)
+	if err != nil {
+		return 0, errors.New("ingredient not found or access denied")
+	}
+	if from == "" {
+		from = ingredient.Unit
+	}
+	if to == "" {
+		to = ingredient.Unit
+	}
+	converted, err := ingredient.ConvertQuantity(quantity, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("ingredient %q: %w", ingredient.Name, err)
+	}
+	return converted, nil
+}
+
+// validateTechCardUnits проверяет позиции тех-карты: каждая ссылается ровно на ингредиент или полуфабрикат,
+// а ее единица приводится к единице ингредиента (полуфабриката)
+func (uc *MenuUseCase) validateTechCardUnits(ctx context.Context, techCard *models.TechCard, establishmentID uuid.UUID) error {
+	for i := range techCard.Ingredients {
+		ing := &techCard.Ingredients[i]
+		if (ing.IngredientID == nil) == (ing.SemiFinishedID == nil) {
+			return errors.New("each tech card ingredient must reference exactly one of ingredient_id or semi_finished_id")
+		}
+		if ing.Unit != "" {
+			ing.Unit = models.NormalizeUnit(ing.Unit)
+		}
+		methods, err := models.ParsePreparationMethods(ing.PreparationMethod)
+		if err != nil {
+			return err
+		}
+		if ing.SemiFinishedID != nil {
+			if len(methods) > 0 {
+				return errors.New("preparation methods can only be set for ingredients, not semi-finished products")
+			}
+			semiFinished, err := uc.semiFinishedRepo.GetByID(ctx, *ing.SemiFinishedID, &establishmentID)
+			if err != nil {
+				return errors.New("semi-finished product not found or access denied")
+			}
+			if _, err := stockUnitFactor(ing.Unit, semiFinished.Unit); err != nil {
+				return fmt.Errorf("semi-finished product %q: %w", semiFinished.Name, err)
+			}
+			continue
+		}
+		if _, err := uc.convertRecipeQuantity(ctx, *ing.IngredientID, 1, ing.Unit, "", establishmentID); err != nil {
+			return err
+		}
+		if len(methods) > 0 {
+			normalized := strings.Join(methods, ",")
+			ing.PreparationMethod = &normalized
+		}
+	}
+	return nil
+}
+
+// validateSemiFinishedUnits проверяет, что единицы ингредиентов полуфабриката приводятся к единицам ингредиентов,
+// и пересчитывает брутто по нетто с учетом потерь способов приготовления
+func (uc *MenuUseCase) validateSemiFinishedUnits(ctx context.Context, semiFinished *models.SemiFinishedProduct, establishmentID uuid.UUID) error {
+	for i := range semiFinished.Ingredients {
+		ing := &semiFinished.Ingredients[i]
+		if ing.Unit != "" {
+			ing.Unit = models.NormalizeUnit(ing.Unit)
+		}
+		if _, err := uc.convertRecipeQuantity(ctx, ing.IngredientID, 1, ing.Unit, "", establishmentID); err != nil {
+			return err
+		}
+		methods, err := models.ParsePreparationMethods(ing.PreparationMethod)
+		if err != nil {
+			return err
+		}
+		if len(methods) > 0 {
+			normalized := strings.Join(methods, ",")
+			ing.PreparationMethod = &normalized
+		}
+
+		ingredient, err := uc.ingredientRepo.GetByID(ctx, ing.IngredientID, &establishmentID)
+		if err != nil {
+			return errors.New("ingredient not found or access denied")
+		}
+		// Считаем на копии, чтобы не сохранять ингредиент как связанную запись
+		calc := *ing
+		calc.Ingredient = ingredient
+		if err := calc.ApplyLosses(); err != nil {
+			return err
+		}
+		ing.Gross = calc.Gross
+		ing.Net = calc.Net
+		ing.LossPercent = calc.LossPercent
+	}
+	return nil
+}
+
+// recipeGrossQuantity возвращает брутто для нетто позиции рецептуры с учетом потерь способов приготовления
+func (uc *MenuUseCase) recipeGrossQuantity(ctx context.Context, ingredientID uuid.UUID, net float64, preparationMethod *string, establishmentID uuid.UUID) (float64, error) {
+	methods, err := models.ParsePreparationMethods(preparationMethod)
+	if err != nil {
+		return 0, err
+	}
+	if len(methods) == 0 {
+		return net, nil
+	}
+	ingredient, err := uc.ingredientRepo.GetByID(ctx, ingredientID, &establishmentID)
+	if err != nil {
+		return 0, errors.New("ingredient not found or access denied")
+	}
+	return ingredient.GrossFromNet(net, methods), nil
+}
+
 // DeleteIngredient удаляет ингредиент (soft delete)
 func (uc *MenuUseCase) DeleteIngredient(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) error {
 	if _, err := uc.ingredientRepo.GetByID(ctx, id, &establishmentID); err != nil {
@@ -268,7 +687,20 @@ func (uc *MenuUseCase) CalculateTechCardCost(ctx context.Context, techCard *mode
 	var totalCost float64
 
 	for _, ingredient := range techCard.Ingredients {
-		stock, err := uc.warehouseRepo.GetStockByIngredientAndWarehouse(ctx, ingredient.IngredientID, warehouseID)
+		// Полуфабрикат оценивается по себестоимости произведенного остатка или по рецептуре
+		if ingredient.SemiFinishedID != nil {
+			cost, err := uc.semiFinishedLineCost(ctx, *ingredient.SemiFinishedID, ingredient.Quantity, ingredient.Unit, warehouseID, establishmentID)
+			if err != nil {
+				return 0, err
+			}
+			totalCost += cost
+			continue
+		}
+		if ingredient.IngredientID == nil {
+			continue
+		}
+
+		stock, err := uc.warehouseRepo.GetStockByIngredientAndWarehouse(ctx, *ingredient.IngredientID, warehouseID)
 		if err != nil {
 			// Если остаток не найден, пропускаем ингредиент (ошибка не критичная)
 			continue
@@ -277,30 +709,21 @@ func (uc *MenuUseCase) CalculateTechCardCost(ctx context.Context, techCard *mode
 			continue
 		}
 
-		// Проверяем существование ингредиента
-		_, err = uc.ingredientRepo.GetByID(ctx, ingredient.IngredientID, &establishmentID)
+		// Себестоимость считается по брутто: нетто в блюде плюс потери при приготовлении
+		gross, err := uc.recipeGrossQuantity(ctx, *ingredient.IngredientID, ingredient.Quantity, ingredient.PreparationMethod, establishmentID)
 		if err != nil {
-			// Если ингредиент не найден, пропускаем его
-			continue
+			return 0, err
 		}
 
-		// Конвертируем количество ингредиента в нужную единицу измерения
-		ingredientQuantity := ingredient.Quantity
-		ingredientUnit := ingredient.Unit
-		stockUnit := stock.Unit
-
-		// Если единицы измерения разные, нужно конвертировать
-		// Упрощенная конвертация (в реальности нужна более сложная логика)
-		if ingredientUnit != stockUnit {
-			// Конвертация между кг, л, шт
-			// Для упрощения считаем, что конвертация уже выполнена
+		// Переводим количество из единицы рецептуры в единицу учета остатка (г → кг, шт → кг и т.д.)
+		ingredientQuantity, err := uc.convertRecipeQuantity(ctx, *ingredient.IngredientID, gross, ingredient.Unit, stock.Unit, establishmentID)
+		if err != nil {
+			return 0, err
 		}
 
 		// Получаем цену за единицу из остатков (Stock)
 		pricePerUnit := stock.PricePerUnit
 
-		// Рассчитываем стоимость с учетом потерь при приготовлении
-		// Пока не учитываем потери, но можно добавить позже
 		ingredientCost := ingredientQuantity * pricePerUnit
 		totalCost += ingredientCost
 	}
@@ -308,6 +731,37 @@ func (uc *MenuUseCase) CalculateTechCardCost(ctx context.Context, techCard *mode
 	return totalCost, nil
 }
 
+// semiFinishedLineCost рассчитывает стоимость количества полуфабриката в позиции тех-карты.
+// Если полуфабрикат уже произведен на склад, берется себестоимость остатка (по фактическим партиям),
+// иначе — себестоимость по рецептуре в расчете на единицу выхода.
+func (uc *MenuUseCase) semiFinishedLineCost(ctx context.Context, semiFinishedID uuid.UUID, quantity float64, unit string, warehouseID, establishmentID uuid.UUID) (float64, error) {
+	semiFinished, err := uc.semiFinishedRepo.GetByID(ctx, semiFinishedID, &establishmentID)
+	if err != nil {
+		return 0, errors.New("semi-finished product not found or access denied")
+	}
+
+	pricePerUnit := 0.0
+	priceUnit := semiFinished.Unit
+	if stock, err := uc.warehouseRepo.GetStockBySemiFinishedAndWarehouse(ctx, semiFinishedID, warehouseID); err == nil && stock != nil && stock.PricePerUnit > 0 {
+		pricePerUnit = stock.PricePerUnit
+		priceUnit = stock.Unit
+	} else if semiFinished.Quantity > 0 {
+		costPrice := semiFinished.CostPrice
+		if costPrice == 0 {
+			if costPrice, err = uc.CalculateSemiFinishedCost(ctx, semiFinished, warehouseID, establishmentID); err != nil {
+				return 0, err
+			}
+		}
+		pricePerUnit = costPrice / semiFinished.Quantity
+	}
+
+	factor, err := stockUnitFactor(unit, priceUnit)
+	if err != nil {
+		return 0, fmt.Errorf("semi-finished product %q: %w", semiFinished.Name, err)
+	}
+	return quantity * factor * pricePerUnit, nil
+}
+
 // ——— Categories (для товаров и тех-карт) ———
 
 func (uc *MenuUseCase) GetCategories(ctx context.Context, filter *repositories.CategoryFilter) ([]*models.Category, error) {
@@ -383,11 +837,24 @@ func (uc *MenuUseCase) GetSemiFinishedProducts(ctx context.Context, filter *repo
 }
 
 func (uc *MenuUseCase) GetSemiFinishedByID(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) (*models.SemiFinishedProduct, error) {
-	return uc.semiFinishedRepo.GetByID(ctx, id, &establishmentID)
+	semiFinished, err := uc.semiFinishedRepo.GetByID(ctx, id, &establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range semiFinished.Ingredients {
+		// Пересчитываем брутто/нетто по текущим процентам потерь ингредиента
+		if err := semiFinished.Ingredients[i].ApplyLosses(); err != nil {
+			return nil, err
+		}
+	}
+	return semiFinished, nil
 }
 
 func (uc *MenuUseCase) CreateSemiFinished(ctx context.Context, semiFinished *models.SemiFinishedProduct, warehouseID, establishmentID uuid.UUID) error {
 	semiFinished.EstablishmentID = establishmentID
+	if err := uc.validateSemiFinishedUnits(ctx, semiFinished, establishmentID); err != nil {
+		return err
+	}
 	if len(semiFinished.Ingredients) > 0 {
 		cost, err := uc.CalculateSemiFinishedCost(ctx, semiFinished, warehouseID, establishmentID)
 		if err == nil {
@@ -398,6 +865,9 @@ func (uc *MenuUseCase) CreateSemiFinished(ctx context.Context, semiFinished *mod
 }
 
 func (uc *MenuUseCase) UpdateSemiFinished(ctx context.Context, semiFinished *models.SemiFinishedProduct, warehouseID, establishmentID uuid.UUID) error {
+	if err := uc.validateSemiFinishedUnits(ctx, semiFinished, establishmentID); err != nil {
+		return err
+	}
 	if len(semiFinished.Ingredients) > 0 {
 		cost, err := uc.CalculateSemiFinishedCost(ctx, semiFinished, warehouseID, establishmentID)
 		if err == nil {
@@ -426,28 +896,14 @@ func (uc *MenuUseCase) CalculateSemiFinishedCost(ctx context.Context, semiFinish
 			continue
 		}
 
-		// Проверяем существование ингредиента
-		_, err = uc.ingredientRepo.GetByID(ctx, ingredient.IngredientID, &establishmentID)
+		// Себестоимость считается по брутто, переведенному в единицу учета остатка (г → кг, мл → л, шт → кг по пересчету)
+		grossForCost, err := uc.convertRecipeQuantity(ctx, ingredient.IngredientID, ingredient.GrossQuantity(), ingredient.Unit, stock.Unit, establishmentID)
 		if err != nil {
 			return 0, err
 		}
-
-		// Конвертируем количество ингредиента в нужную единицу измерения
-		ingredientQuantity := ingredient.Net
-		ingredientUnit := ingredient.Unit
-
-		// Если единицы измерения разные, конвертируем
 		pricePerUnit := stock.PricePerUnit
 
-		// Конвертируем в базовые единицы (кг, л, шт) для расчета стоимости
-		netForCost := ingredientQuantity
-		if ingredientUnit == "г" {
-			netForCost = ingredientQuantity / 1000 // конвертируем г в кг
-		} else if ingredientUnit == "мл" {
-			netForCost = ingredientQuantity / 1000 // конвертируем мл в л
-		}
-
-		ingredientCost := netForCost * pricePerUnit
+		ingredientCost := grossForCost * pricePerUnit
 		totalCost += ingredientCost
 	}
 
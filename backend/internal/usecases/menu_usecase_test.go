@@ -0,0 +1,93 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+func TestMenuUseCase_TechCardComposition(t *testing.T) {
+	ctx := context.Background()
+	establishmentID := uuid.New()
+	hot := &models.Category{ID: uuid.New(), Name: "Горячее"}
+	drinks := &models.Category{ID: uuid.New(), Name: "Напитки"}
+
+	milk := &models.Ingredient{Name: "Молоко", Unit: models.UnitLiter, Allergens: models.AllergenList{models.AllergenMilk}, Calories: 60, Proteins: 3, Fats: 3, Carbohydrates: 5}
+	butter := &models.Ingredient{Name: "Масло", Unit: models.UnitKilogram, Allergens: models.AllergenList{models.AllergenMilk}, Calories: 740, Proteins: 0.5, Fats: 82, Carbohydrates: 0.8}
+	flour := &models.Ingredient{Name: "Мука", Unit: models.UnitKilogram, Allergens: models.AllergenList{models.AllergenGluten}, Calories: 340, Proteins: 10, Fats: 1, Carbohydrates: 70}
+	pasta := &models.Ingredient{Name: "Листы для лазаньи", Unit: models.UnitKilogram, Allergens: models.AllergenList{models.AllergenGluten, models.AllergenEggs}, Calories: 350, Proteins: 12, Fats: 2, Carbohydrates: 70}
+	tomato := &models.Ingredient{Name: "Томаты", Unit: models.UnitKilogram, Calories: 20, Proteins: 1, Carbohydrates: 4}
+
+	// Бешамель: литр молока с маслом и мукой уваривается до 800 г выхода
+	bechamel := &models.SemiFinishedProduct{
+		Name:     "Бешамель",
+		Unit:     models.UnitGram,
+		Quantity: 800,
+		Ingredients: []models.SemiFinishedIngredient{
+			{Ingredient: milk, Net: 1, Unit: models.UnitLiter},
+			{Ingredient: butter, Net: 50, Unit: models.UnitGram},
+			{Ingredient: flour, Net: 50, Unit: models.UnitGram},
+		},
+	}
+	lasagna := &models.TechCard{
+		ID:         uuid.New(),
+		Name:       "Лазанья",
+		CategoryID: hot.ID,
+		Ingredients: []models.TechCardIngredient{
+			{Ingredient: pasta, Quantity: 100, Unit: models.UnitGram},
+			{SemiFinished: bechamel, Quantity: 0.2, Unit: models.UnitKilogram}, // четверть выхода соуса
+		},
+	}
+	salad := &models.TechCard{
+		ID:          uuid.New(),
+		Name:        "Салат из томатов",
+		CategoryID:  hot.ID,
+		Ingredients: []models.TechCardIngredient{{Ingredient: tomato, Quantity: 150, Unit: models.UnitGram}},
+	}
+	lemonade := &models.Product{ID: uuid.New(), Name: "Лимонад", CategoryID: drinks.ID, Price: 150}
+
+	uc := NewMenuUseCase(
+		&fakeProductRepository{items: []*models.Product{lemonade}},
+		&fakeTechCardRepository{items: []*models.TechCard{lasagna, salad}},
+		nil, nil,
+		&fakeCategoryRepository{items: []*models.Category{hot, drinks}},
+		nil, nil, nil,
+	)
+
+	// Бешамель целиком: 600 + 370 + 170 ккал на 800 г, в порции — четверть
+	tc, err := uc.GetTechCardByID(ctx, lasagna.ID, establishmentID)
+	require.NoError(t, err)
+	require.NotNil(t, tc.Nutrition)
+	assert.InDelta(t, 300, tc.Nutrition.Weight, 0.01)
+	assert.InDelta(t, 350+285, tc.Nutrition.Calories, 0.01)
+	assert.InDelta(t, 12+8.81, tc.Nutrition.Proteins, 0.01)
+	assert.InDelta(t, 2+17.88, tc.Nutrition.Fats, 0.01)
+	assert.InDelta(t, 70+21.35, tc.Nutrition.Carbohydrates, 0.01)
+	// Аллергены полуфабриката переходят в тех-карту
+	assert.Equal(t, models.AllergenList{models.AllergenEggs, models.AllergenGluten, models.AllergenMilk}, tc.Allergens)
+
+	// Публичное меню без молока: лазанья скрыта, у товаров состав не ведется
+	menu, err := uc.GetPublicMenu(ctx, establishmentID, []string{models.AllergenMilk}, false)
+	require.NoError(t, err)
+	require.Len(t, menu.Categories, 2)
+	require.Len(t, menu.Categories[0].Items, 1)
+	item := menu.Categories[0].Items[0]
+	assert.Equal(t, salad.ID, item.ID)
+	require.NotNil(t, item.Nutrition)
+	assert.InDelta(t, 150, item.Nutrition.Weight, 0.01)
+	assert.InDelta(t, 30, item.Nutrition.Calories, 0.01)
+	assert.Empty(t, item.Allergens)
+	require.Len(t, menu.Categories[1].Items, 1)
+	assert.Equal(t, lemonade.ID, menu.Categories[1].Items[0].ID)
+	assert.Nil(t, menu.Categories[1].Items[0].Nutrition)
+
+	// Без исключений в меню обе тех-карты
+	menu, err = uc.GetPublicMenu(ctx, establishmentID, nil, false)
+	require.NoError(t, err)
+	assert.Len(t, menu.Categories[0].Items, 2)
+}
@@ -17,6 +17,10 @@ type OrderUseCase struct {
 	warehouseRepo   repositories.WarehouseRepository
 	transactionRepo repositories.TransactionRepository
 	accountUseCase  *AccountUseCase // Добавлен AccountUseCase
+	stockAlerts     *StockAlertUseCase
+	establishmentRepo repositories.EstablishmentRepository
+	availability    *AvailabilityUseCase
+	combos          *ComboUseCase
 }
 
 func NewOrderUseCase(
@@ -24,12 +28,20 @@ func NewOrderUseCase(
 	warehouseRepo repositories.WarehouseRepository,
 	transactionRepo repositories.TransactionRepository,
 	accountUseCase *AccountUseCase, // Добавлен AccountUseCase
+	stockAlerts *StockAlertUseCase,
+	establishmentRepo repositories.EstablishmentRepository,
+	availability *AvailabilityUseCase,
+	combos *ComboUseCase,
 ) *OrderUseCase {
 	return &OrderUseCase{
 		orderRepo:       orderRepo,
 		warehouseRepo:   warehouseRepo,
 		transactionRepo: transactionRepo,
 		accountUseCase:  accountUseCase, // Присвоение AccountUseCase
+		stockAlerts:     stockAlerts,
+		establishmentRepo: establishmentRepo,
+		availability:    availability,
+		combos:          combos,
 	}
 }
 
@@ -43,27 +55,22 @@ func (uc *OrderUseCase) CreateOrder(ctx context.Context, establishmentID uuid.UU
 
 	// Calculate total amount and ensure item prices are set
 	var totalAmount float64
+	refs := make([]MenuItemRef, 0, len(order.Items))
 	for i := range order.Items {
 		item := &order.Items[i]
-		if item.ProductID != nil {
-			product, err := uc.warehouseRepo.GetProductByID(ctx, *item.ProductID)
-			if err != nil {
-				return nil, fmt.Errorf("product not found: %w", err)
-			}
-			item.Price = product.Price
-		} else if item.TechCardID != nil {
-			techCard, err := uc.warehouseRepo.GetTechCardByID(ctx, *item.TechCardID)
-			if err != nil {
-				return nil, fmt.Errorf("tech card not found: %w", err)
-			}
-			item.Price = techCard.Price
-		} else {
-			return nil, errors.New("order item must have a product or tech card")
+		ref, err := uc.priceOrderItem(ctx, item)
+		if err != nil {
+			return nil, err
 		}
-		item.TotalPrice = item.Price * float64(item.Quantity)
+		refs = append(refs, ref)
 		totalAmount += item.TotalPrice
 	}
 
+	// Позиции с расписанием доступности продаются только в разрешенное время
+	if err := uc.availability.CheckItems(ctx, establishmentID, refs); err != nil {
+		return nil, err
+	}
+
 	order.TotalAmount = totalAmount
 	if len(totalAmountOverride) > 0 {
 		overrideAmount := totalAmountOverride[0]
@@ -80,13 +87,68 @@ func (uc *OrderUseCase) CreateOrder(ctx context.Context, establishmentID uuid.UU
 		order.TotalAmount = overrideAmount
 	}
 
+	// Проверяем остатки по политике заведения (allow, warn, block)
+	warnings, err := uc.checkStockAvailability(ctx, establishmentID, order.Items)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := uc.orderRepo.Create(ctx, order); err != nil {
 		return nil, fmt.Errorf("failed to create order: %w", err)
 	}
+	order.StockWarnings = warnings
 
 	return order, nil
 }
 
+// priceOrderItem проставляет цену позиции заказа по товару, тех-карте или комбо и возвращает позицию меню для проверки доступности
+func (uc *OrderUseCase) priceOrderItem(ctx context.Context, item *models.OrderItem) (MenuItemRef, error) {
+	var ref MenuItemRef
+	if item.ComboID != nil {
+		if item.ProductID != nil || item.TechCardID != nil {
+			return ref, errors.New("order item must have only one of product, tech card or combo")
+		}
+		return uc.combos.PriceOrderItem(ctx, item)
+	}
+	if item.ProductID != nil {
+		product, err := uc.warehouseRepo.GetProductByID(ctx, *item.ProductID)
+		if err != nil {
+			return ref, fmt.Errorf("product not found: %w", err)
+		}
+		if product == nil {
+			return ref, errors.New("product not found")
+		}
+		byVariants, err := soldByVariants(product, func() (int64, error) {
+			return uc.warehouseRepo.CountProductVariants(ctx, product.ID)
+		})
+		if err != nil {
+			return ref, err
+		}
+		if byVariants {
+			return ref, fmt.Errorf("%w: %s", ErrProductHasVariants, product.Name)
+		}
+		item.Price = product.Price
+		ref = MenuItemRef{Type: MenuItemTypeProduct, ID: product.ID, Name: product.Name, CategoryID: product.CategoryID, ScheduleID: product.AvailabilityScheduleID}
+		// Модификация без своего расписания продается по расписанию родительского товара
+		if product.IsVariant() && ref.ScheduleID == nil {
+			if parent, err := uc.warehouseRepo.GetProductByID(ctx, *product.ParentID); err == nil && parent != nil {
+				ref.ScheduleID = parent.AvailabilityScheduleID
+			}
+		}
+	} else if item.TechCardID != nil {
+		techCard, err := uc.warehouseRepo.GetTechCardByID(ctx, *item.TechCardID)
+		if err != nil {
+			return ref, fmt.Errorf("tech card not found: %w", err)
+		}
+		item.Price = techCard.Price
+		ref = MenuItemRef{Type: MenuItemTypeTechCard, ID: techCard.ID, Name: techCard.Name, CategoryID: techCard.CategoryID, ScheduleID: techCard.AvailabilityScheduleID}
+	} else {
+		return ref, errors.New("order item must have a product, tech card or combo")
+	}
+	item.TotalPrice = item.Price * float64(item.Quantity)
+	return ref, nil
+}
+
 func (uc *OrderUseCase) GetActiveOrdersByEstablishment(ctx context.Context, establishmentID uuid.UUID) ([]*models.Order, error) {
 	orders, err := uc.orderRepo.ListActiveByEstablishmentID(ctx, establishmentID)
 	if err != nil {
@@ -102,30 +164,28 @@ func (uc *OrderUseCase) AddOrderItem(ctx context.Context, orderID uuid.UUID, ite
 	}
 
 	// Ensure item price is set
-	if item.ProductID != nil {
-		product, err := uc.warehouseRepo.GetProductByID(ctx, *item.ProductID)
-		if err != nil {
-			return nil, fmt.Errorf("product not found: %w", err)
-		}
-		item.Price = product.Price
-	} else if item.TechCardID != nil {
-		techCard, err := uc.warehouseRepo.GetTechCardByID(ctx, *item.TechCardID)
-		if err != nil {
-			return nil, fmt.Errorf("tech card not found: %w", err)
-		}
-		item.Price = techCard.Price
-	} else {
-		return nil, errors.New("order item must have a product or tech card")
+	ref, err := uc.priceOrderItem(ctx, &item)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.availability.CheckItems(ctx, order.EstablishmentID, []MenuItemRef{ref}); err != nil {
+		return nil, err
 	}
-	item.TotalPrice = item.Price * float64(item.Quantity)
 
 	// Add item to order
 	order.Items = append(order.Items, item)
 	order.TotalAmount += item.TotalPrice
 
+	// Остатки списываются при оплате, поэтому проверяем весь заказ, а не только новую позицию
+	warnings, err := uc.checkStockAvailability(ctx, order.EstablishmentID, order.Items)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := uc.orderRepo.Update(ctx, order); err != nil {
 		return nil, fmt.Errorf("failed to add order item: %w", err)
 	}
+	order.StockWarnings = warnings
 
 	return order, nil
 }
@@ -137,9 +197,11 @@ func (uc *OrderUseCase) UpdateOrderItemQuantity(ctx context.Context, orderID uui
 	}
 
 	found := false
+	increased := false
 	for i := range order.Items {
 		item := &order.Items[i]
 		if item.ID == itemID {
+			increased = quantity > item.Quantity
 			// Update total amount
 			order.TotalAmount -= item.TotalPrice
 			item.Quantity = quantity
@@ -154,9 +216,19 @@ func (uc *OrderUseCase) UpdateOrderItemQuantity(ctx context.Context, orderID uui
 		return nil, errors.New("order item not found")
 	}
 
+	// Уменьшение количества не требует проверки остатков
+	var warnings []models.StockShortage
+	if increased {
+		warnings, err = uc.checkStockAvailability(ctx, order.EstablishmentID, order.Items)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if err := uc.orderRepo.Update(ctx, order); err != nil {
 		return nil, fmt.Errorf("failed to update order item quantity: %w", err)
 	}
+	order.StockWarnings = warnings
 
 	return order, nil
 }
@@ -312,6 +384,7 @@ func (uc *OrderUseCase) ProcessOrderPayment(ctx context.Context, orderID uuid.UU
 	if err := uc.deductTechCardIngredientsFromStock(ctx, order); err != nil {
 		return nil, fmt.Errorf("failed to deduct stock for order: %w", err)
 	}
+	uc.stockAlerts.Notify(order.EstablishmentID)
 
 	return order, nil
 }
@@ -389,78 +462,36 @@ func (uc *OrderUseCase) GetOrder(ctx context.Context, orderID uuid.UUID, establi
 }
 
 func (uc *OrderUseCase) deductTechCardIngredientsFromStock(ctx context.Context, order *models.Order) error {
-	type itemUsage struct {
-		quantity float64
-		unit     string
+	// Полуфабрикаты списываются с произведенного остатка; недостающее количество
+	// раскладывается на ингредиенты по рецептуре полуфабриката
+	usage, err := uc.collectStockUsage(ctx, order.Items, func(semiFinishedID uuid.UUID, unit string) (float64, error) {
+		return uc.availableSemiFinished(ctx, order.EstablishmentID, semiFinishedID, unit)
+	})
+	if err != nil {
+		return err
 	}
 
-	// Для ингредиентов из тех-карт
-	usageByIngredient := make(map[uuid.UUID]itemUsage)
-	// Для товаров (Products)
-	usageByProduct := make(map[uuid.UUID]itemUsage)
-
-	// Собираем данные для списания
-	for _, item := range order.Items {
-		// Обработка тех-карт - списываем ингредиенты
-		if item.TechCardID != nil {
-			techCard, err := uc.warehouseRepo.GetTechCardByID(ctx, *item.TechCardID)
-			if err != nil {
-				return fmt.Errorf("tech card not found: %w", err)
-			}
-			if techCard == nil {
-				return errors.New("tech card not found")
-			}
-
-			for _, ing := range techCard.Ingredients {
-				qty := ing.Quantity * float64(item.Quantity)
-				if qty == 0 {
-					continue
-				}
-				current := usageByIngredient[ing.IngredientID]
-				unit := current.unit
-				if unit == "" {
-					unit = ing.Unit
-				}
-				usageByIngredient[ing.IngredientID] = itemUsage{
-					quantity: current.quantity + qty,
-					unit:     unit,
-				}
-			}
-		}
-
-		// Обработка товаров - списываем сами товары
-		if item.ProductID != nil {
-			qty := float64(item.Quantity)
-			if qty == 0 {
-				continue
-			}
-			current := usageByProduct[*item.ProductID]
-			unit := current.unit
-			if unit == "" {
-				unit = "шт" // По умолчанию для товаров
-			}
-			usageByProduct[*item.ProductID] = itemUsage{
-				quantity: current.quantity + qty,
-				unit:     unit,
-			}
+	for semiFinishedID, u := range usage.semiFinished {
+		if err := uc.deductItemFromStock(ctx, order, semiFinishedID, "semi_finished", u); err != nil {
+			return err
 		}
 	}
 
 	// Если нечего списывать - выходим
-	if len(usageByIngredient) == 0 && len(usageByProduct) == 0 {
+	if len(usage.ingredients) == 0 && len(usage.products) == 0 {
 		return nil
 	}
 
 	// Списываем ингредиенты из тех-карт
-	for ingredientID, usage := range usageByIngredient {
-		if err := uc.deductItemFromStock(ctx, order, ingredientID, "ingredient", usage.quantity, usage.unit); err != nil {
+	for ingredientID, u := range usage.ingredients {
+		if err := uc.deductItemFromStock(ctx, order, ingredientID, "ingredient", u); err != nil {
 			return err
 		}
 	}
 
 	// Списываем товары
-	for productID, usage := range usageByProduct {
-		if err := uc.deductItemFromStock(ctx, order, productID, "product", usage.quantity, usage.unit); err != nil {
+	for productID, u := range usage.products {
+		if err := uc.deductItemFromStock(ctx, order, productID, "product", u); err != nil {
 			return err
 		}
 	}
@@ -469,8 +500,9 @@ func (uc *OrderUseCase) deductTechCardIngredientsFromStock(ctx context.Context,
 }
 
 // deductItemFromStock списывает ингредиент или товар со складов
-func (uc *OrderUseCase) deductItemFromStock(ctx context.Context, order *models.Order, itemID uuid.UUID, itemType string, quantity float64, unit string) error {
-	remainingQty := quantity
+func (uc *OrderUseCase) deductItemFromStock(ctx context.Context, order *models.Order, itemID uuid.UUID, itemType string, usage itemUsage) error {
+	remainingQty := usage.quantity
+	unit := usage.unit
 
 	var stocks []*models.Stock
 	var err error
@@ -480,6 +512,8 @@ func (uc *OrderUseCase) deductItemFromStock(ctx context.Context, order *models.O
 		stocks, err = uc.warehouseRepo.GetStockByIngredientID(ctx, itemID)
 	} else if itemType == "product" {
 		stocks, err = uc.warehouseRepo.GetStockByProductID(ctx, itemID)
+	} else if itemType == "semi_finished" {
+		stocks, err = uc.warehouseRepo.GetStockBySemiFinishedID(ctx, itemID)
 	} else {
 		return fmt.Errorf("unsupported item type: %s", itemType)
 	}
@@ -501,24 +535,42 @@ func (uc *OrderUseCase) deductItemFromStock(ctx context.Context, order *models.O
 	// Сортируем по количеству по убыванию (сначала списываем с того, где больше)
 	sortStocksByQuantityDesc(establishmentStocks)
 
+	// Партии расходуются по FIFO, фактическая себестоимость фиксируется за заказом
+	doc := stockDocument{Type: models.StockConsumptionSale, ID: order.ID, At: time.Now()}
+	ledger := stockLedgerSource{Type: models.StockLedgerSale, ID: order.ID, At: doc.At}
+
 	// Списываем с нескольких складов если нужно
 	for _, stock := range establishmentStocks {
 		if remainingQty <= 0 {
 			break
 		}
 
-		// Определяем сколько списать с этого склада
+		// Остаток может вестись в другой единице (г и кг, шт и кг по пересчету ингредиента)
+		factor, err := ingredientStockFactor(unit, stock.Unit, usage.conversions)
+		if err != nil {
+			return fmt.Errorf("failed to deduct %s %s: %w", itemType, itemID, err)
+		}
+
+		// Определяем сколько списать с этого склада (в единицах остатка)
 		toDeduct := stock.Quantity
-		if toDeduct > remainingQty {
-			toDeduct = remainingQty
+		if toDeduct > remainingQty*factor {
+			toDeduct = remainingQty * factor
+		}
+
+		cost, err := consumeStockLots(ctx, uc.warehouseRepo, stock, toDeduct, doc)
+		if err != nil {
+			return fmt.Errorf("failed to consume stock lots for %s %s: %w", itemType, itemID, err)
 		}
 
 		stock.Quantity -= toDeduct
-		remainingQty -= toDeduct
+		remainingQty -= toDeduct / factor
 
 		if err := uc.warehouseRepo.UpdateStock(ctx, stock); err != nil {
 			return fmt.Errorf("failed to update stock for %s %s: %w", itemType, itemID, err)
 		}
+		if err := postStockLedger(ctx, uc.warehouseRepo, stock, -toDeduct, cost, ledger); err != nil {
+			return err
+		}
 	}
 
 	// Если не хватило на всех складах - создаем запись на складе по умолчанию с отрицательным остатком
@@ -533,6 +585,8 @@ func (uc *OrderUseCase) deductItemFromStock(ctx context.Context, order *models.O
 			stock, err = uc.warehouseRepo.GetStockByIngredientAndWarehouse(ctx, itemID, warehouseID)
 		} else if itemType == "product" {
 			stock, err = uc.warehouseRepo.GetStockByProductAndWarehouse(ctx, itemID, warehouseID)
+		} else if itemType == "semi_finished" {
+			stock, err = uc.warehouseRepo.GetStockBySemiFinishedAndWarehouse(ctx, itemID, warehouseID)
 		}
 
 		if err != nil {
@@ -550,22 +604,95 @@ func (uc *OrderUseCase) deductItemFromStock(ctx context.Context, order *models.O
 				stock.IngredientID = &itemID
 			} else if itemType == "product" {
 				stock.ProductID = &itemID
+			} else if itemType == "semi_finished" {
+				stock.SemiFinishedID = &itemID
 			}
 			if err := uc.warehouseRepo.CreateStock(ctx, stock); err != nil {
 				return fmt.Errorf("failed to create stock for %s %s: %w", itemType, itemID, err)
 			}
+			if err := postStockLedger(ctx, uc.warehouseRepo, stock, -remainingQty, 0, ledger); err != nil {
+				return err
+			}
 		} else {
+			factor, err := ingredientStockFactor(unit, stock.Unit, usage.conversions)
+			if err != nil {
+				return fmt.Errorf("failed to deduct %s %s: %w", itemType, itemID, err)
+			}
+			remainingQty *= factor
+			// Количество сверх остатка оценивается по цене остатка
+			cost, err := consumeStockLots(ctx, uc.warehouseRepo, stock, remainingQty, doc)
+			if err != nil {
+				return fmt.Errorf("failed to consume stock lots for %s %s: %w", itemType, itemID, err)
+			}
 			// Обновляем существующую запись
 			stock.Quantity -= remainingQty
 			if err := uc.warehouseRepo.UpdateStock(ctx, stock); err != nil {
 				return fmt.Errorf("failed to update stock for %s %s: %w", itemType, itemID, err)
 			}
+			if err := postStockLedger(ctx, uc.warehouseRepo, stock, -remainingQty, cost, ledger); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
+// itemUsage суммарный расход позиции склада в единице unit
+type itemUsage struct {
+	quantity    float64
+	unit        string
+	conversions []models.IngredientUnitConversion // Пересчеты ингредиента в единицу остатка другого измерения
+}
+
+// addIngredientUsage добавляет расход ингредиента, приводя количество к единице ингредиента,
+// чтобы суммировать расход из разных тех-карт и полуфабрикатов
+func addIngredientUsage(usage map[uuid.UUID]itemUsage, ingredientID uuid.UUID, ingredient *models.Ingredient, qty float64, unit string) error {
+	current := usage[ingredientID]
+	if ingredient != nil {
+		converted, err := ingredient.ConvertQuantity(qty, unit, ingredient.Unit)
+		if err != nil {
+			return fmt.Errorf("ingredient %q: %w", ingredient.Name, err)
+		}
+		qty = converted
+		unit = ingredient.Unit
+		current.conversions = ingredient.UnitConversions
+	}
+	if current.unit != "" {
+		unit = current.unit
+	}
+	usage[ingredientID] = itemUsage{
+		quantity:    current.quantity + qty,
+		unit:        unit,
+		conversions: current.conversions,
+	}
+	return nil
+}
+
+// availableSemiFinished возвращает произведенный остаток полуфабриката на складах заведения в единице unit
+func (uc *OrderUseCase) availableSemiFinished(ctx context.Context, establishmentID, semiFinishedID uuid.UUID, unit string) (float64, error) {
+	stocks, err := uc.warehouseRepo.GetStockBySemiFinishedID(ctx, semiFinishedID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stocks for semi_finished %s: %w", semiFinishedID, err)
+	}
+	total := 0.0
+	for _, stock := range stocks {
+		if stock.Quantity <= 0 {
+			continue
+		}
+		warehouse, err := uc.warehouseRepo.GetWarehouseByID(ctx, stock.WarehouseID, &establishmentID)
+		if err != nil || warehouse == nil {
+			continue
+		}
+		factor, err := stockUnitFactor(unit, stock.Unit)
+		if err != nil {
+			return 0, fmt.Errorf("semi_finished %s: %w", semiFinishedID, err)
+		}
+		total += stock.Quantity / factor
+	}
+	return total, nil
+}
+
 // sortStocksByQuantityDesc сортирует стоки по количеству по убыванию
 func sortStocksByQuantityDesc(stocks []*models.Stock) {
 	for i := 0; i < len(stocks)-1; i++ {
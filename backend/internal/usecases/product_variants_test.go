@@ -0,0 +1,60 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+func TestOrderUseCase_CreateOrder_ProductVariants(t *testing.T) {
+	ctx := context.Background()
+	establishmentID := uuid.New()
+
+	// Товар с флагом модификаций из старых данных, у которого модификаций нет, продается сам
+	legacy := &models.Product{ID: uuid.New(), Name: "Морс", Price: 120, HasModifications: true}
+	lemonade := &models.Product{ID: uuid.New(), Name: "Лимонад", Price: 150, HasModifications: true}
+	lemonadeLarge := &models.Product{ID: uuid.New(), Name: "Лимонад 0.5", ParentID: &lemonade.ID, Price: 200}
+
+	repo := &stockAvailabilityRepository{
+		fakeWarehouseRepository: newFakeWarehouseRepository(),
+		products:                []*models.Product{legacy, lemonade, lemonadeLarge},
+	}
+	orders := &fakeOrderRepository{orders: make(map[uuid.UUID]*models.Order)}
+	uc := NewOrderUseCase(orders, repo, nil, nil, nil, &fakeEstablishmentRepository{}, nil, nil)
+
+	order, err := uc.CreateOrder(ctx, establishmentID, nil, []models.OrderItem{{ProductID: &legacy.ID, Quantity: 2}})
+	require.NoError(t, err)
+	assert.InDelta(t, 240, order.TotalAmount, 1e-9)
+
+	// Товар с модификациями продается только через них
+	_, err = uc.CreateOrder(ctx, establishmentID, nil, []models.OrderItem{{ProductID: &lemonade.ID, Quantity: 1}})
+	assert.True(t, errors.Is(err, ErrProductHasVariants))
+
+	order, err = uc.CreateOrder(ctx, establishmentID, nil, []models.OrderItem{{ProductID: &lemonadeLarge.ID, Quantity: 1}})
+	require.NoError(t, err)
+	assert.InDelta(t, 200, order.TotalAmount, 1e-9)
+}
+
+func TestMenuUseCase_UpdateProduct_VariantsFlag(t *testing.T) {
+	ctx := context.Background()
+	legacy := &models.Product{ID: uuid.New(), Name: "Морс", Price: 120, HasModifications: true}
+	lemonade := &models.Product{ID: uuid.New(), Name: "Лимонад", Price: 150}
+	lemonadeLarge := &models.Product{ID: uuid.New(), Name: "Лимонад 0.5", ParentID: &lemonade.ID, Price: 200}
+	products := &fakeProductRepository{items: []*models.Product{legacy, lemonade, lemonadeLarge}}
+	uc := NewMenuUseCase(products, nil, nil, nil, nil, nil, nil, nil)
+
+	// Флаг пересчитывается по фактическим модификациям, а не берется из запроса
+	update := *legacy
+	require.NoError(t, uc.UpdateProduct(ctx, &update))
+	assert.False(t, update.HasModifications)
+
+	update = *lemonade
+	require.NoError(t, uc.UpdateProduct(ctx, &update))
+	assert.True(t, update.HasModifications)
+}
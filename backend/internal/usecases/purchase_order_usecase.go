@@ -0,0 +1,559 @@
+package usecases
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+	"github.com/yourusername/arc/backend/pkg/pdf"
+)
+
+// PurchaseOrderUseCase управляет заказами поставщикам и их приемкой
+type PurchaseOrderUseCase struct {
+	repo          repositories.PurchaseOrderRepository
+	warehouseRepo repositories.WarehouseRepository
+	supplierRepo  repositories.SupplierRepository
+	transactor    repositories.Transactor
+	warehouseUC   *WarehouseUseCase
+}
+
+func NewPurchaseOrderUseCase(
+	repo repositories.PurchaseOrderRepository,
+	warehouseRepo repositories.WarehouseRepository,
+	supplierRepo repositories.SupplierRepository,
+	transactor repositories.Transactor,
+	warehouseUC *WarehouseUseCase,
+) *PurchaseOrderUseCase {
+	return &PurchaseOrderUseCase{
+		repo:          repo,
+		warehouseRepo: warehouseRepo,
+		supplierRepo:  supplierRepo,
+		transactor:    transactor,
+		warehouseUC:   warehouseUC,
+	}
+}
+
+// ——— Purchase orders ———
+
+// validatePurchaseOrder проверяет поставщика, склад и позиции заказа
+func (uc *PurchaseOrderUseCase) validatePurchaseOrder(ctx context.Context, order *models.PurchaseOrder, establishmentID uuid.UUID) error {
+	if _, err := uc.supplierRepo.GetByID(ctx, order.SupplierID, &establishmentID); err != nil {
+		return errors.New("supplier not found or access denied")
+	}
+	warehouse, err := uc.warehouseRepo.GetWarehouseByID(ctx, order.WarehouseID, &establishmentID)
+	if err != nil || warehouse == nil {
+		return errors.New("warehouse not found or access denied")
+	}
+	if len(order.Items) == 0 {
+		return errors.New("purchase order must contain at least one item")
+	}
+	for i := range order.Items {
+		item := &order.Items[i]
+		if (item.IngredientID == nil) == (item.ProductID == nil) {
+			return errors.New("each purchase order item must reference exactly one of ingredient_id or product_id")
+		}
+		if item.Quantity <= 0 {
+			return errors.New("purchase order item quantity must be positive")
+		}
+		if item.PricePerUnit < 0 {
+			return errors.New("purchase order item price cannot be negative")
+		}
+		item.Unit = models.NormalizeUnit(item.Unit)
+		if !models.IsValidUnit(item.Unit) {
+			return fmt.Errorf("invalid unit %q", item.Unit)
+		}
+	}
+	return nil
+}
+
+// CreatePurchaseOrder создает заказ поставщику в статусе draft
+func (uc *PurchaseOrderUseCase) CreatePurchaseOrder(ctx context.Context, order *models.PurchaseOrder, establishmentID uuid.UUID) error {
+	order.EstablishmentID = establishmentID
+	order.Status = models.PurchaseOrderStatusDraft
+	if err := uc.validatePurchaseOrder(ctx, order, establishmentID); err != nil {
+		return err
+	}
+	for i := range order.Items {
+		order.Items[i].ReceivedQuantity = 0
+		order.Items[i].ReceivedAmount = 0
+	}
+	order.RecalculateTotals()
+	return uc.repo.Create(ctx, order)
+}
+
+// UpdatePurchaseOrder обновляет черновик заказа (позиции заменяются целиком)
+func (uc *PurchaseOrderUseCase) UpdatePurchaseOrder(ctx context.Context, order *models.PurchaseOrder, establishmentID uuid.UUID) error {
+	existing, err := uc.repo.GetByID(ctx, order.ID, &establishmentID)
+	if err != nil || existing == nil {
+		return errors.New("purchase order not found or access denied")
+	}
+	if existing.Status != models.PurchaseOrderStatusDraft {
+		return errors.New("only draft purchase orders can be edited")
+	}
+	order.EstablishmentID = establishmentID
+	order.Status = existing.Status
+	if err := uc.validatePurchaseOrder(ctx, order, establishmentID); err != nil {
+		return err
+	}
+	order.RecalculateTotals()
+	// Позиции и итоги заказа сохраняются вместе: при ошибке не остается новых позиций со старыми суммами
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.repo.ReplaceItems(ctx, order); err != nil {
+			return err
+		}
+		return uc.repo.Update(ctx, order)
+	})
+}
+
+// DeletePurchaseOrder удаляет черновик заказа
+func (uc *PurchaseOrderUseCase) DeletePurchaseOrder(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) error {
+	order, err := uc.repo.GetByID(ctx, id, &establishmentID)
+	if err != nil || order == nil {
+		return errors.New("purchase order not found or access denied")
+	}
+	if order.Status != models.PurchaseOrderStatusDraft {
+		return errors.New("only draft purchase orders can be deleted")
+	}
+	return uc.repo.Delete(ctx, id)
+}
+
+func (uc *PurchaseOrderUseCase) GetPurchaseOrder(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) (*models.PurchaseOrder, error) {
+	order, err := uc.repo.GetByID(ctx, id, &establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New("purchase order not found or access denied")
+	}
+	return order, nil
+}
+
+func (uc *PurchaseOrderUseCase) ListPurchaseOrders(ctx context.Context, establishmentID uuid.UUID, filter *repositories.PurchaseOrderFilter) ([]*models.PurchaseOrder, error) {
+	if filter == nil {
+		filter = &repositories.PurchaseOrderFilter{}
+	}
+	filter.EstablishmentID = &establishmentID
+	return uc.repo.List(ctx, filter)
+}
+
+// UpdatePurchaseOrderStatus меняет статус заказа.
+// draft → sent — заказ отправлен поставщику; draft, sent, partially_received, received → closed — заказ закрыт.
+// Статусы partially_received и received выставляются только приемкой.
+func (uc *PurchaseOrderUseCase) UpdatePurchaseOrderStatus(ctx context.Context, id uuid.UUID, status models.PurchaseOrderStatus, establishmentID uuid.UUID) (*models.PurchaseOrder, error) {
+	order, err := uc.repo.GetByID(ctx, id, &establishmentID)
+	if err != nil || order == nil {
+		return nil, errors.New("purchase order not found or access denied")
+	}
+
+	now := time.Now()
+	switch {
+	case order.Status == models.PurchaseOrderStatusDraft && status == models.PurchaseOrderStatusSent:
+		order.SentAt = &now
+	case order.Status != models.PurchaseOrderStatusClosed && status == models.PurchaseOrderStatusClosed:
+		order.ClosedAt = &now
+	default:
+		return nil, fmt.Errorf("invalid status transition from %s to %s", order.Status, status)
+	}
+	order.Status = status
+
+	if err := uc.repo.Update(ctx, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// ——— Receiving ———
+
+// ReceivePurchaseOrderItem фактически поступившее количество и цена по позиции заказа
+type ReceivePurchaseOrderItem struct {
+	PurchaseOrderItemID uuid.UUID
+	Quantity            float64  // В единице позиции заказа
+	PricePerUnit        *float64 // Если не указана — ожидаемая цена из заказа
+}
+
+// ReceivePurchaseOrderRequest данные приемки по заказу
+type ReceivePurchaseOrderRequest struct {
+	DeliveryDateTime time.Time
+	InvoiceNumber    string
+	InvoiceDate      *time.Time
+	Comment          string
+	Items            []ReceivePurchaseOrderItem
+}
+
+// PurchaseOrderReceiptLine сравнение заказанного и полученного по позиции
+type PurchaseOrderReceiptLine struct {
+	PurchaseOrderItemID uuid.UUID  `json:"purchase_order_item_id"`
+	IngredientID        *uuid.UUID `json:"ingredient_id,omitempty"`
+	ProductID           *uuid.UUID `json:"product_id,omitempty"`
+	Name                string     `json:"name"`
+	Unit                string     `json:"unit"`
+	OrderedQuantity     float64    `json:"ordered_quantity"`
+	ReceivedNow         float64    `json:"received_now"`
+	ReceivedTotal       float64    `json:"received_total"`
+	QuantityDifference  float64    `json:"quantity_difference"` // Получено всего − заказано (меньше нуля — недопоставка)
+	ExpectedPrice       float64    `json:"expected_price"`
+	ActualPrice         float64    `json:"actual_price"`
+	PriceDifference     float64    `json:"price_difference"`         // Фактическая − ожидаемая цена
+	PriceDifferencePct  float64    `json:"price_difference_percent"` // Отклонение цены в процентах
+	HasDifference       bool       `json:"has_difference"`
+}
+
+// PurchaseOrderReceipt результат приемки: созданная поставка и расхождения с заказом
+type PurchaseOrderReceipt struct {
+	PurchaseOrder  *models.PurchaseOrder      `json:"purchase_order"`
+	Supply         *models.Supply             `json:"supply"`
+	Lines          []PurchaseOrderReceiptLine `json:"lines"`
+	HasDifferences bool                       `json:"has_differences"`
+}
+
+// ReceivePurchaseOrder оформляет поставку (Supply) по заказу и возвращает расхождения по количеству и цене.
+// Позиции, не указанные в приемке, считаются не поступившими в этой поставке.
+func (uc *PurchaseOrderUseCase) ReceivePurchaseOrder(ctx context.Context, id uuid.UUID, req *ReceivePurchaseOrderRequest, establishmentID uuid.UUID) (*PurchaseOrderReceipt, error) {
+	order, err := uc.repo.GetByID(ctx, id, &establishmentID)
+	if err != nil || order == nil {
+		return nil, errors.New("purchase order not found or access denied")
+	}
+	if !order.Status.IsOpen() {
+		return nil, fmt.Errorf("cannot receive purchase order in status %s", order.Status)
+	}
+	if len(req.Items) == 0 {
+		return nil, errors.New("at least one received item is required")
+	}
+
+	itemsByID := make(map[uuid.UUID]*models.PurchaseOrderItem, len(order.Items))
+	for i := range order.Items {
+		itemsByID[order.Items[i].ID] = &order.Items[i]
+	}
+
+	deliveryDateTime := req.DeliveryDateTime
+	if deliveryDateTime.IsZero() {
+		deliveryDateTime = time.Now()
+	}
+	supplyID := uuid.New()
+	orderID := order.ID
+	supply := &models.Supply{
+		ID:               supplyID,
+		WarehouseID:      order.WarehouseID,
+		SupplierID:       order.SupplierID,
+		DeliveryDateTime: deliveryDateTime,
+		Status:           "completed",
+		Comment:          req.Comment,
+		InvoiceNumber:    req.InvoiceNumber,
+		InvoiceDate:      req.InvoiceDate,
+		PaymentStatus:    "none",
+		PurchaseOrderID:  &orderID,
+	}
+	if supply.Comment == "" {
+		supply.Comment = fmt.Sprintf("Поставка по заказу %s", order.Number)
+	}
+
+	receivedNow := make(map[uuid.UUID]float64)
+	actualPrices := make(map[uuid.UUID]float64)
+	for _, line := range req.Items {
+		item, ok := itemsByID[line.PurchaseOrderItemID]
+		if !ok {
+			return nil, fmt.Errorf("purchase order item %s not found", line.PurchaseOrderItemID)
+		}
+		if line.Quantity <= 0 {
+			return nil, errors.New("received quantity must be positive")
+		}
+		if _, dup := receivedNow[item.ID]; dup {
+			return nil, fmt.Errorf("purchase order item %s is listed more than once", item.ID)
+		}
+		price := item.PricePerUnit
+		if line.PricePerUnit != nil {
+			if *line.PricePerUnit < 0 {
+				return nil, errors.New("received price cannot be negative")
+			}
+			price = *line.PricePerUnit
+		}
+		receivedNow[item.ID] = line.Quantity
+		actualPrices[item.ID] = price
+
+		itemID := item.ID
+		total := models.RoundTo2(line.Quantity * price)
+		supply.Items = append(supply.Items, models.SupplyItem{
+			SupplyID:            supplyID,
+			IngredientID:        item.IngredientID,
+			ProductID:           item.ProductID,
+			Quantity:            line.Quantity,
+			Unit:                item.Unit,
+			PricePerUnit:        price,
+			TotalAmount:         total,
+			PurchaseOrderItemID: &itemID,
+		})
+		supply.TotalAmount += total
+	}
+	supply.TotalAmount = models.RoundTo2(supply.TotalAmount)
+
+	receipt := &PurchaseOrderReceipt{Supply: supply}
+	for i := range order.Items {
+		item := &order.Items[i]
+		qty, received := receivedNow[item.ID]
+		if received {
+			item.ReceivedQuantity = models.RoundTo2(item.ReceivedQuantity + qty)
+			item.ReceivedAmount = models.RoundTo2(item.ReceivedAmount + qty*actualPrices[item.ID])
+		}
+
+		line := PurchaseOrderReceiptLine{
+			PurchaseOrderItemID: item.ID,
+			IngredientID:        item.IngredientID,
+			ProductID:           item.ProductID,
+			Name:                purchaseOrderItemName(item),
+			Unit:                item.Unit,
+			OrderedQuantity:     item.Quantity,
+			ReceivedNow:         qty,
+			ReceivedTotal:       item.ReceivedQuantity,
+			QuantityDifference:  models.RoundTo2(item.ReceivedQuantity - item.Quantity),
+			ExpectedPrice:       item.PricePerUnit,
+		}
+		if received {
+			line.ActualPrice = actualPrices[item.ID]
+			line.PriceDifference = models.RoundTo2(line.ActualPrice - line.ExpectedPrice)
+			if line.ExpectedPrice > 0 {
+				line.PriceDifferencePct = models.RoundTo2(line.PriceDifference / line.ExpectedPrice * 100)
+			}
+		}
+		line.HasDifference = line.QuantityDifference != 0 || line.PriceDifference != 0
+		if line.HasDifference {
+			receipt.HasDifferences = true
+		}
+		receipt.Lines = append(receipt.Lines, line)
+	}
+
+	order.RecalculateTotals()
+	if order.FullyReceived() {
+		order.Status = models.PurchaseOrderStatusReceived
+	} else {
+		order.Status = models.PurchaseOrderStatusPartiallyReceived
+	}
+	// Поставка и полученные количества заказа сохраняются вместе: при ошибке не остается
+	// ни оприходованной поставки без отметки в заказе, ни отметки без поставки
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.warehouseUC.CreateSupply(ctx, supply, establishmentID); err != nil {
+			return fmt.Errorf("failed to create supply: %w", err)
+		}
+		return uc.repo.Update(ctx, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	receipt.PurchaseOrder, err = uc.repo.GetByID(ctx, order.ID, &establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// ——— Reports ———
+
+// OpenPurchaseOrderSummary открытый заказ в отчете
+type OpenPurchaseOrderSummary struct {
+	ID                uuid.UUID                  `json:"id"`
+	Number            string                     `json:"number"`
+	Status            models.PurchaseOrderStatus `json:"status"`
+	WarehouseID       uuid.UUID                  `json:"warehouse_id"`
+	ExpectedDate      *time.Time                 `json:"expected_date,omitempty"`
+	Overdue           bool                       `json:"overdue"` // Ожидаемая дата прошла, а заказ не получен
+	TotalAmount       float64                    `json:"total_amount"`
+	ReceivedAmount    float64                    `json:"received_amount"`
+	OutstandingAmount float64                    `json:"outstanding_amount"` // Недопоставлено по ожидаемым ценам
+	OutstandingItems  int                        `json:"outstanding_items"`
+}
+
+// OpenPurchaseOrdersBySupplier открытые заказы одного поставщика
+type OpenPurchaseOrdersBySupplier struct {
+	SupplierID        uuid.UUID                  `json:"supplier_id"`
+	SupplierName      string                     `json:"supplier_name"`
+	OrdersCount       int                        `json:"orders_count"`
+	OverdueCount      int                        `json:"overdue_count"`
+	TotalAmount       float64                    `json:"total_amount"`
+	ReceivedAmount    float64                    `json:"received_amount"`
+	OutstandingAmount float64                    `json:"outstanding_amount"`
+	Orders            []OpenPurchaseOrderSummary `json:"orders"`
+}
+
+// GetOpenPurchaseOrdersReport возвращает отчет по открытым заказам (sent, partially_received) в разрезе поставщиков
+func (uc *PurchaseOrderUseCase) GetOpenPurchaseOrdersReport(ctx context.Context, establishmentID uuid.UUID, supplierID *uuid.UUID) ([]*OpenPurchaseOrdersBySupplier, error) {
+	orders, err := uc.repo.List(ctx, &repositories.PurchaseOrderFilter{
+		EstablishmentID: &establishmentID,
+		SupplierID:      supplierID,
+		Statuses:        []models.PurchaseOrderStatus{models.PurchaseOrderStatusSent, models.PurchaseOrderStatusPartiallyReceived},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	bySupplier := make(map[uuid.UUID]*OpenPurchaseOrdersBySupplier)
+	for _, order := range orders {
+		group, ok := bySupplier[order.SupplierID]
+		if !ok {
+			group = &OpenPurchaseOrdersBySupplier{SupplierID: order.SupplierID}
+			if order.Supplier != nil {
+				group.SupplierName = order.Supplier.Name
+			}
+			bySupplier[order.SupplierID] = group
+		}
+
+		summary := OpenPurchaseOrderSummary{
+			ID:             order.ID,
+			Number:         order.Number,
+			Status:         order.Status,
+			WarehouseID:    order.WarehouseID,
+			ExpectedDate:   order.ExpectedDate,
+			Overdue:        order.ExpectedDate != nil && order.ExpectedDate.Before(now),
+			TotalAmount:    order.TotalAmount,
+			ReceivedAmount: order.ReceivedAmount,
+		}
+		for i := range order.Items {
+			outstanding := order.Items[i].OutstandingQuantity()
+			if outstanding > 0 {
+				summary.OutstandingItems++
+				summary.OutstandingAmount += outstanding * order.Items[i].PricePerUnit
+			}
+		}
+		summary.OutstandingAmount = models.RoundTo2(summary.OutstandingAmount)
+
+		group.Orders = append(group.Orders, summary)
+		group.OrdersCount++
+		if summary.Overdue {
+			group.OverdueCount++
+		}
+		group.TotalAmount = models.RoundTo2(group.TotalAmount + summary.TotalAmount)
+		group.ReceivedAmount = models.RoundTo2(group.ReceivedAmount + summary.ReceivedAmount)
+		group.OutstandingAmount = models.RoundTo2(group.OutstandingAmount + summary.OutstandingAmount)
+	}
+
+	result := make([]*OpenPurchaseOrdersBySupplier, 0, len(bySupplier))
+	for _, group := range bySupplier {
+		result = append(result, group)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].OutstandingAmount > result[j].OutstandingAmount })
+	return result, nil
+}
+
+// ——— Export ———
+
+// Форматы выгрузки заказа поставщику
+const (
+	ExportFormatCSV = "csv"
+	ExportFormatPDF = "pdf"
+)
+
+// ExportPurchaseOrder выгружает заказ для отправки поставщику в CSV или PDF.
+// Возвращает содержимое файла, MIME-тип и имя файла.
+func (uc *PurchaseOrderUseCase) ExportPurchaseOrder(ctx context.Context, id uuid.UUID, format string, establishmentID uuid.UUID) ([]byte, string, string, error) {
+	order, err := uc.GetPurchaseOrder(ctx, id, establishmentID)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	switch format {
+	case ExportFormatCSV, "":
+		data, err := purchaseOrderCSV(order)
+		if err != nil {
+			return nil, "", "", err
+		}
+		return data, "text/csv; charset=utf-8", order.Number + ".csv", nil
+	case ExportFormatPDF:
+		return purchaseOrderPDF(order), "application/pdf", order.Number + ".pdf", nil
+	default:
+		return nil, "", "", fmt.Errorf("unsupported export format %q, must be csv or pdf", format)
+	}
+}
+
+func purchaseOrderCSV(order *models.PurchaseOrder) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	rows := [][]string{
+		{"Заказ", order.Number},
+		{"Поставщик", purchaseOrderSupplierName(order)},
+		{"Дата", order.CreatedAt.Format("2006-01-02")},
+	}
+	if order.ExpectedDate != nil {
+		rows = append(rows, []string{"Ожидаемая дата поставки", order.ExpectedDate.Format("2006-01-02")})
+	}
+	rows = append(rows, []string{}, []string{"№", "Наименование", "Количество", "Ед.", "Цена", "Сумма"})
+	for i := range order.Items {
+		item := &order.Items[i]
+		rows = append(rows, []string{
+			strconv.Itoa(i + 1),
+			purchaseOrderItemName(item),
+			formatAmount(item.Quantity),
+			item.Unit,
+			formatAmount(item.PricePerUnit),
+			formatAmount(item.TotalAmount),
+		})
+	}
+	rows = append(rows, []string{"", "Итого", "", "", "", formatAmount(order.TotalAmount)})
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func purchaseOrderPDF(order *models.PurchaseOrder) []byte {
+	doc := pdf.New()
+	doc.Title("Заказ поставщику " + order.Number)
+	doc.Text("Поставщик: " + purchaseOrderSupplierName(order))
+	doc.Text("Дата: " + order.CreatedAt.Format("02.01.2006"))
+	if order.ExpectedDate != nil {
+		doc.Text("Ожидаемая дата поставки: " + order.ExpectedDate.Format("02.01.2006"))
+	}
+	if order.Comment != "" {
+		doc.Text("Комментарий: " + order.Comment)
+	}
+	doc.Space()
+
+	rows := make([][]string, 0, len(order.Items)+1)
+	for i := range order.Items {
+		item := &order.Items[i]
+		rows = append(rows, []string{
+			strconv.Itoa(i + 1),
+			purchaseOrderItemName(item),
+			formatAmount(item.Quantity),
+			item.Unit,
+			formatAmount(item.PricePerUnit),
+			formatAmount(item.TotalAmount),
+		})
+	}
+	rows = append(rows, []string{"", "Итого", "", "", "", formatAmount(order.TotalAmount)})
+	doc.Table([]string{"№", "Наименование", "Кол-во", "Ед.", "Цена", "Сумма"}, rows, []float64{0.5, 5, 1.2, 0.8, 1.2, 1.4})
+	return doc.Bytes()
+}
+
+func purchaseOrderSupplierName(order *models.PurchaseOrder) string {
+	if order.Supplier != nil {
+		return order.Supplier.Name
+	}
+	return order.SupplierID.String()
+}
+
+func purchaseOrderItemName(item *models.PurchaseOrderItem) string {
+	switch {
+	case item.Ingredient != nil:
+		return item.Ingredient.Name
+	case item.Product != nil:
+		return item.Product.Name
+	case item.IngredientID != nil:
+		return item.IngredientID.String()
+	case item.ProductID != nil:
+		return item.ProductID.String()
+	}
+	return ""
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(models.RoundTo2(v), 'f', 2, 64)
+}
@@ -0,0 +1,201 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// fakePurchaseOrderRepository хранит копии заказов и отмечает записи, сделанные вне транзакции
+type fakePurchaseOrderRepository struct {
+	repositories.PurchaseOrderRepository
+	orders     map[uuid.UUID]*models.PurchaseOrder
+	transactor *fakeTransactor
+	updateErr  error
+	outsideTx  []string
+}
+
+func (r *fakePurchaseOrderRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.PurchaseOrder, error) {
+	order, ok := r.orders[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	cp := *order
+	cp.Items = append([]models.PurchaseOrderItem(nil), order.Items...)
+	return &cp, nil
+}
+
+func (r *fakePurchaseOrderRepository) save(method string, order *models.PurchaseOrder) {
+	if r.transactor.depth == 0 {
+		r.outsideTx = append(r.outsideTx, method)
+	}
+	cp := *order
+	cp.Items = append([]models.PurchaseOrderItem(nil), order.Items...)
+	r.orders[cp.ID] = &cp
+}
+
+func (r *fakePurchaseOrderRepository) Update(ctx context.Context, order *models.PurchaseOrder) error {
+	if r.updateErr != nil {
+		return r.updateErr
+	}
+	r.save("Update", order)
+	return nil
+}
+
+func (r *fakePurchaseOrderRepository) ReplaceItems(ctx context.Context, order *models.PurchaseOrder) error {
+	r.save("ReplaceItems", order)
+	return nil
+}
+
+type purchaseOrderFixture struct {
+	uc          *PurchaseOrderUseCase
+	orders      *fakePurchaseOrderRepository
+	warehouse   *fakeWarehouseRepository
+	stockAlerts *StockAlertUseCase
+	costHistory *CostHistoryUseCase
+	warehouseID uuid.UUID
+	flourID     uuid.UUID
+	sugarID     uuid.UUID
+}
+
+func newPurchaseOrderFixture() *purchaseOrderFixture {
+	warehouse := newFakeWarehouseRepository()
+	f := &purchaseOrderFixture{
+		warehouse:   warehouse,
+		stockAlerts: NewStockAlertUseCase(nil, nil, nil, nil, nil),
+		costHistory: NewCostHistoryUseCase(nil, nil, nil, nil, nil),
+		warehouseID: warehouse.addWarehouse(),
+		flourID:     warehouse.addIngredient(models.UnitKilogram),
+		sugarID:     warehouse.addIngredient(models.UnitKilogram),
+	}
+	transactor := &fakeTransactor{repo: warehouse}
+	suppliers := &fakeSupplierRepository{}
+	warehouseUC := &WarehouseUseCase{
+		repo: warehouse, supplierRepo: suppliers, transactor: transactor,
+		stockAlerts: f.stockAlerts, costHistory: f.costHistory,
+	}
+	f.orders = &fakePurchaseOrderRepository{orders: make(map[uuid.UUID]*models.PurchaseOrder), transactor: transactor}
+	f.uc = NewPurchaseOrderUseCase(f.orders, warehouse, suppliers, transactor, warehouseUC)
+	return f
+}
+
+// addOrder заводит отправленный заказ: 10 кг муки по 50 и 5 кг сахара по 80
+func (f *purchaseOrderFixture) addOrder() *models.PurchaseOrder {
+	order := &models.PurchaseOrder{
+		ID:          uuid.New(),
+		Number:      "PO-1",
+		SupplierID:  uuid.New(),
+		WarehouseID: f.warehouseID,
+		Status:      models.PurchaseOrderStatusSent,
+		Items: []models.PurchaseOrderItem{
+			{ID: uuid.New(), IngredientID: &f.flourID, Quantity: 10, Unit: models.UnitKilogram, PricePerUnit: 50},
+			{ID: uuid.New(), IngredientID: &f.sugarID, Quantity: 5, Unit: models.UnitKilogram, PricePerUnit: 80},
+		},
+	}
+	order.RecalculateTotals()
+	f.orders.orders[order.ID] = order
+	return order
+}
+
+func TestPurchaseOrderUseCase_ReceivePurchaseOrder(t *testing.T) {
+	ctx := context.Background()
+	f := newPurchaseOrderFixture()
+	order := f.addOrder()
+	flourItem, sugarItem := order.Items[0].ID, order.Items[1].ID
+
+	// Частичная приемка: пришло 6 кг муки по 55, сахара нет
+	price := 55.0
+	receipt, err := f.uc.ReceivePurchaseOrder(ctx, order.ID, &ReceivePurchaseOrderRequest{
+		Items: []ReceivePurchaseOrderItem{{PurchaseOrderItemID: flourItem, Quantity: 6, PricePerUnit: &price}},
+	}, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, models.PurchaseOrderStatusPartiallyReceived, receipt.PurchaseOrder.Status)
+	assert.InDelta(t, 330, receipt.PurchaseOrder.ReceivedAmount, 1e-9)
+	assert.True(t, receipt.HasDifferences)
+	require.Len(t, receipt.Lines, 2)
+	flour := receipt.Lines[0]
+	assert.InDelta(t, 6, flour.ReceivedTotal, 1e-9)
+	assert.InDelta(t, -4, flour.QuantityDifference, 1e-9)
+	assert.InDelta(t, 5, flour.PriceDifference, 1e-9)
+	assert.InDelta(t, 10, flour.PriceDifferencePct, 1e-9)
+	sugar := receipt.Lines[1]
+	assert.Zero(t, sugar.ReceivedNow)
+	assert.InDelta(t, -5, sugar.QuantityDifference, 1e-9)
+	assert.Zero(t, sugar.ActualPrice)
+
+	// Поставка проведена сразу и ссылается на позиции заказа
+	require.Len(t, receipt.Supply.Items, 1)
+	assert.Equal(t, flourItem, *receipt.Supply.Items[0].PurchaseOrderItemID)
+	assert.Equal(t, order.ID, *f.warehouse.supplies[receipt.Supply.ID].PurchaseOrderID)
+	assert.InDelta(t, 6, f.warehouse.stock(f.warehouseID, f.flourID).Quantity, 1e-9)
+	assert.Nil(t, f.warehouse.stock(f.warehouseID, f.sugarID))
+	assert.Len(t, f.stockAlerts.trigger, 1)
+	assert.Len(t, f.costHistory.trigger, 1)
+
+	// Остаток заказа: заказ получен полностью
+	receipt, err = f.uc.ReceivePurchaseOrder(ctx, order.ID, &ReceivePurchaseOrderRequest{
+		Items: []ReceivePurchaseOrderItem{
+			{PurchaseOrderItemID: flourItem, Quantity: 4},
+			{PurchaseOrderItemID: sugarItem, Quantity: 5},
+		},
+	}, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, models.PurchaseOrderStatusReceived, receipt.PurchaseOrder.Status)
+	assert.False(t, receipt.HasDifferences)
+	assert.InDelta(t, 10, receipt.PurchaseOrder.Items[0].ReceivedQuantity, 1e-9)
+	assert.InDelta(t, 530, receipt.PurchaseOrder.Items[0].ReceivedAmount, 1e-9)
+	assert.InDelta(t, 930, receipt.PurchaseOrder.ReceivedAmount, 1e-9)
+	assert.InDelta(t, 10, f.warehouse.stock(f.warehouseID, f.flourID).Quantity, 1e-9)
+	assert.InDelta(t, 5, f.warehouse.stock(f.warehouseID, f.sugarID).Quantity, 1e-9)
+	assert.Len(t, f.warehouse.supplies, 2)
+
+	_, err = f.uc.ReceivePurchaseOrder(ctx, order.ID, &ReceivePurchaseOrderRequest{
+		Items: []ReceivePurchaseOrderItem{{PurchaseOrderItemID: flourItem, Quantity: 1}},
+	}, uuid.New())
+	assert.ErrorContains(t, err, "cannot receive purchase order in status received")
+}
+
+func TestPurchaseOrderUseCase_ReceivePurchaseOrder_RollsBack(t *testing.T) {
+	ctx := context.Background()
+	f := newPurchaseOrderFixture()
+	order := f.addOrder()
+
+	// Отметка в заказе не сохранилась: поставка откатывается, фоновые обработчики не уведомляются
+	f.orders.updateErr = errors.New("connection reset")
+	_, err := f.uc.ReceivePurchaseOrder(ctx, order.ID, &ReceivePurchaseOrderRequest{
+		Items: []ReceivePurchaseOrderItem{{PurchaseOrderItemID: order.Items[0].ID, Quantity: 10}},
+	}, uuid.New())
+	require.Error(t, err)
+	assert.Empty(t, f.warehouse.supplies)
+	assert.Nil(t, f.warehouse.stock(f.warehouseID, f.flourID))
+	assert.Empty(t, f.warehouse.ledger)
+	assert.Empty(t, f.stockAlerts.trigger)
+	assert.Empty(t, f.costHistory.trigger)
+	assert.Equal(t, models.PurchaseOrderStatusSent, f.orders.orders[order.ID].Status)
+}
+
+func TestPurchaseOrderUseCase_UpdatePurchaseOrder_InTransaction(t *testing.T) {
+	ctx := context.Background()
+	f := newPurchaseOrderFixture()
+	order := f.addOrder()
+	order.Status = models.PurchaseOrderStatusDraft
+
+	update := &models.PurchaseOrder{
+		ID:          order.ID,
+		SupplierID:  order.SupplierID,
+		WarehouseID: f.warehouseID,
+		Items:       []models.PurchaseOrderItem{{ID: uuid.New(), IngredientID: &f.flourID, Quantity: 20, Unit: "кг", PricePerUnit: 45}},
+	}
+	require.NoError(t, f.uc.UpdatePurchaseOrder(ctx, update, uuid.New()))
+	assert.Empty(t, f.orders.outsideTx)
+	stored := f.orders.orders[order.ID]
+	require.Len(t, stored.Items, 1)
+	assert.InDelta(t, 900, stored.TotalAmount, 1e-9)
+}
@@ -0,0 +1,468 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// RepricingUseCase рассчитывает цены по правилам переоценки и применяет одобренные владельцем изменения
+type RepricingUseCase struct {
+	repo          repositories.RepricingRepository
+	techCardRepo  repositories.TechCardRepository
+	productRepo   repositories.ProductRepository
+	categoryRepo  repositories.CategoryRepository
+	warehouseRepo repositories.WarehouseRepository
+	costHistory   *CostHistoryUseCase
+	logger        *zap.Logger
+	trigger       chan uuid.UUID
+}
+
+func NewRepricingUseCase(
+	repo repositories.RepricingRepository,
+	techCardRepo repositories.TechCardRepository,
+	productRepo repositories.ProductRepository,
+	categoryRepo repositories.CategoryRepository,
+	warehouseRepo repositories.WarehouseRepository,
+	costHistory *CostHistoryUseCase,
+	logger *zap.Logger,
+) *RepricingUseCase {
+	return &RepricingUseCase{
+		repo:          repo,
+		techCardRepo:  techCardRepo,
+		productRepo:   productRepo,
+		categoryRepo:  categoryRepo,
+		warehouseRepo: warehouseRepo,
+		costHistory:   costHistory,
+		logger:        logger,
+		trigger:       make(chan uuid.UUID, 100),
+	}
+}
+
+// ——— Rules ———
+
+// ListRules возвращает правила переоценки заведения
+func (uc *RepricingUseCase) ListRules(ctx context.Context, establishmentID uuid.UUID) ([]*models.RepricingRule, error) {
+	return uc.repo.ListRules(ctx, establishmentID, false)
+}
+
+// CreateRule создает правило переоценки
+func (uc *RepricingUseCase) CreateRule(ctx context.Context, rule *models.RepricingRule, establishmentID uuid.UUID) error {
+	rule.EstablishmentID = establishmentID
+	if err := uc.validateRule(ctx, rule); err != nil {
+		return err
+	}
+	return uc.repo.CreateRule(ctx, rule)
+}
+
+// UpdateRule обновляет правило переоценки
+func (uc *RepricingUseCase) UpdateRule(ctx context.Context, rule *models.RepricingRule, establishmentID uuid.UUID) error {
+	existing, err := uc.repo.GetRuleByID(ctx, rule.ID, &establishmentID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.New("repricing rule not found or access denied")
+	}
+	rule.EstablishmentID = establishmentID
+	if err := uc.validateRule(ctx, rule); err != nil {
+		return err
+	}
+	return uc.repo.UpdateRule(ctx, rule)
+}
+
+// DeleteRule удаляет правило переоценки
+func (uc *RepricingUseCase) DeleteRule(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) error {
+	existing, err := uc.repo.GetRuleByID(ctx, id, &establishmentID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.New("repricing rule not found or access denied")
+	}
+	return uc.repo.DeleteRule(ctx, id)
+}
+
+func (uc *RepricingUseCase) validateRule(ctx context.Context, rule *models.RepricingRule) error {
+	if !models.IsValidRepricingStrategy(rule.Strategy) {
+		return errors.New("invalid strategy, expected markup or food_cost")
+	}
+	if rule.TargetValue <= 0 {
+		return errors.New("target_value must be positive")
+	}
+	if rule.Strategy == models.RepricingStrategyFoodCost && rule.TargetValue >= 100 {
+		return errors.New("target food cost must be less than 100%")
+	}
+	if rule.Rounding == "" {
+		rule.Rounding = models.RepricingRoundingNone
+	}
+	if !models.IsValidRepricingRounding(rule.Rounding) {
+		return errors.New("invalid rounding, expected none, 5, 10 or 90")
+	}
+	if (rule.MinPrice != nil && *rule.MinPrice < 0) || (rule.MaxPrice != nil && *rule.MaxPrice < 0) {
+		return errors.New("price bounds must not be negative")
+	}
+	if rule.MinPrice != nil && rule.MaxPrice != nil && *rule.MinPrice > *rule.MaxPrice {
+		return errors.New("min_price must not exceed max_price")
+	}
+
+	scopes := 0
+	if rule.CategoryID != nil {
+		scopes++
+		if _, err := uc.categoryRepo.GetByID(ctx, *rule.CategoryID, &rule.EstablishmentID); err != nil {
+			return errors.New("category not found or access denied")
+		}
+	}
+	if rule.TechCardID != nil {
+		scopes++
+		if _, err := uc.techCardRepo.GetByID(ctx, *rule.TechCardID, &rule.EstablishmentID); err != nil {
+			return errors.New("tech card not found or access denied")
+		}
+	}
+	if rule.ProductID != nil {
+		scopes++
+		if _, err := uc.productRepo.GetByID(ctx, *rule.ProductID, &rule.EstablishmentID); err != nil {
+			return errors.New("product not found or access denied")
+		}
+	}
+	if scopes > 1 {
+		return errors.New("rule must target only one of category_id, tech_card_id or product_id")
+	}
+	return nil
+}
+
+// matchRule выбирает самое точное правило для позиции: для самой позиции, затем для ее категории, затем для всего меню
+func matchRule(rules []*models.RepricingRule, techCardID, productID *uuid.UUID, categoryID uuid.UUID) *models.RepricingRule {
+	var byCategory, global *models.RepricingRule
+	for _, r := range rules {
+		switch {
+		case r.TechCardID != nil || r.ProductID != nil:
+			if sameItemID(r.TechCardID, techCardID) && sameItemID(r.ProductID, productID) {
+				return r
+			}
+		case r.CategoryID != nil:
+			if byCategory == nil && *r.CategoryID == categoryID {
+				byCategory = r
+			}
+		default:
+			if global == nil {
+				global = r
+			}
+		}
+	}
+	if byCategory != nil {
+		return byCategory
+	}
+	return global
+}
+
+// ——— Preview ———
+
+// RepricingPreviewItem позиция, цена которой изменится по правилу переоценки
+type RepricingPreviewItem struct {
+	ItemType           string     `json:"item_type"` // tech_card или product
+	TechCardID         *uuid.UUID `json:"tech_card_id,omitempty"`
+	ProductID          *uuid.UUID `json:"product_id,omitempty"`
+	Name               string     `json:"name"`
+	CategoryID         uuid.UUID  `json:"category_id"`
+	RuleID             uuid.UUID  `json:"rule_id"`
+	RuleName           string     `json:"rule_name"`
+	CostPrice          float64    `json:"cost_price"`
+	OldPrice           float64    `json:"old_price"`
+	NewPrice           float64    `json:"new_price"`
+	Change             float64    `json:"change"`
+	OldFoodCostPercent float64    `json:"old_food_cost_percent"`
+	NewFoodCostPercent float64    `json:"new_food_cost_percent"`
+}
+
+// Preview рассчитывает новые цены активного меню по правилам переоценки без их применения.
+// Возвращает только позиции, цена которых изменится.
+func (uc *RepricingUseCase) Preview(ctx context.Context, establishmentID uuid.UUID) ([]*RepricingPreviewItem, error) {
+	rules, err := uc.repo.ListRules(ctx, establishmentID, true)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*RepricingPreviewItem, 0)
+	if len(rules) == 0 {
+		return items, nil
+	}
+
+	add := func(itemType string, techCardID, productID *uuid.UUID, name string, categoryID uuid.UUID, costPrice, price float64) {
+		rule := matchRule(rules, techCardID, productID, categoryID)
+		if rule == nil {
+			return
+		}
+		newPrice := rule.TargetPrice(costPrice)
+		if newPrice <= 0 || newPrice == models.RoundTo2(price) {
+			return
+		}
+		items = append(items, &RepricingPreviewItem{
+			ItemType:           itemType,
+			TechCardID:         techCardID,
+			ProductID:          productID,
+			Name:               name,
+			CategoryID:         categoryID,
+			RuleID:             rule.ID,
+			RuleName:           rule.Name,
+			CostPrice:          models.RoundTo2(costPrice),
+			OldPrice:           models.RoundTo2(price),
+			NewPrice:           newPrice,
+			Change:             models.RoundTo2(newPrice - price),
+			OldFoodCostPercent: models.FoodCostPercent(costPrice, price),
+			NewFoodCostPercent: models.FoodCostPercent(costPrice, newPrice),
+		})
+	}
+
+	techCards, err := uc.warehouseRepo.GetActiveTechCards(ctx, establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, tc := range techCards {
+		id := tc.ID
+		add(MenuItemTypeTechCard, &id, nil, tc.Name, tc.CategoryID, tc.CostPrice, tc.Price)
+	}
+	products, err := uc.warehouseRepo.GetActiveProducts(ctx, establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range products {
+		id := p.ID
+		add(MenuItemTypeProduct, nil, &id, p.Name, p.CategoryID, p.CostPrice, p.Price)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items, nil
+}
+
+// ——— Price changes ———
+
+// Notify просит фоновый обработчик пересобрать предложения заведения (после изменения себестоимости).
+// Вызов не блокирует.
+func (uc *RepricingUseCase) Notify(establishmentID uuid.UUID) {
+	if uc == nil {
+		return
+	}
+	select {
+	case uc.trigger <- establishmentID:
+	default:
+	}
+}
+
+// Run применяет одобренные изменения цен по расписанию (раз в interval)
+// и пересобирает предложения по сигналам Notify
+func (uc *RepricingUseCase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := uc.ApplyDue(ctx); err != nil && uc.logger != nil {
+				uc.logger.Error("Failed to apply scheduled price changes", zap.Error(err))
+			}
+		case establishmentID := <-uc.trigger:
+			if _, err := uc.GenerateProposals(ctx, establishmentID); err != nil && uc.logger != nil {
+				uc.logger.Error("Failed to generate price change proposals",
+					zap.String("establishment_id", establishmentID.String()), zap.Error(err))
+			}
+		}
+	}
+}
+
+// GenerateProposals создает предложения об изменении цены по результатам предпросмотра.
+// Ожидающие предложения с другой ценой или для позиций, цена которых больше не меняется, помечаются как superseded.
+func (uc *RepricingUseCase) GenerateProposals(ctx context.Context, establishmentID uuid.UUID) ([]*models.PriceChange, error) {
+	items, err := uc.Preview(ctx, establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	status := models.PriceChangeStatusPending
+	pending, err := uc.repo.ListPriceChanges(ctx, &repositories.PriceChangeFilter{EstablishmentID: &establishmentID, Status: &status})
+	if err != nil {
+		return nil, err
+	}
+	pendingByItem := make(map[uuid.UUID]*models.PriceChange, len(pending))
+	for _, ch := range pending {
+		pendingByItem[priceChangeItemID(ch.TechCardID, ch.ProductID)] = ch
+	}
+
+	created := make([]*models.PriceChange, 0)
+	for _, it := range items {
+		key := priceChangeItemID(it.TechCardID, it.ProductID)
+		if ch, ok := pendingByItem[key]; ok {
+			delete(pendingByItem, key)
+			if ch.OldPrice == it.OldPrice && ch.NewPrice == it.NewPrice {
+				continue
+			}
+			ch.Status = models.PriceChangeStatusSuperseded
+			if err := uc.repo.UpdatePriceChange(ctx, ch); err != nil {
+				return nil, err
+			}
+		}
+		ruleID := it.RuleID
+		change := &models.PriceChange{
+			EstablishmentID: establishmentID,
+			RuleID:          &ruleID,
+			TechCardID:      it.TechCardID,
+			ProductID:       it.ProductID,
+			CostPrice:       it.CostPrice,
+			OldPrice:        it.OldPrice,
+			NewPrice:        it.NewPrice,
+			Status:          models.PriceChangeStatusPending,
+		}
+		if err := uc.repo.CreatePriceChange(ctx, change); err != nil {
+			return nil, err
+		}
+		created = append(created, change)
+	}
+
+	for _, ch := range pendingByItem {
+		ch.Status = models.PriceChangeStatusSuperseded
+		if err := uc.repo.UpdatePriceChange(ctx, ch); err != nil {
+			return nil, err
+		}
+	}
+	return created, nil
+}
+
+// ListPriceChanges возвращает предложения об изменении цены
+func (uc *RepricingUseCase) ListPriceChanges(ctx context.Context, establishmentID uuid.UUID, status *string) ([]*models.PriceChange, error) {
+	return uc.repo.ListPriceChanges(ctx, &repositories.PriceChangeFilter{EstablishmentID: &establishmentID, Status: status})
+}
+
+// ApprovePriceChanges одобряет ожидающие предложения (все, если ids пуст).
+// Без applyAt или с наступившим сроком цены меняются сразу, иначе — фоновым обработчиком в applyAt.
+func (uc *RepricingUseCase) ApprovePriceChanges(ctx context.Context, establishmentID uuid.UUID, ids []uuid.UUID, applyAt *time.Time, userID *uuid.UUID) ([]*models.PriceChange, error) {
+	changes, err := uc.pendingChanges(ctx, establishmentID, ids)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, ch := range changes {
+		ch.Status = models.PriceChangeStatusApproved
+		ch.DecidedAt = &now
+		ch.DecidedBy = userID
+		ch.ApplyAt = applyAt
+		if err := uc.repo.UpdatePriceChange(ctx, ch); err != nil {
+			return nil, err
+		}
+		if applyAt == nil || !applyAt.After(now) {
+			if err := uc.apply(ctx, ch); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+// RejectPriceChanges отклоняет ожидающие предложения (все, если ids пуст)
+func (uc *RepricingUseCase) RejectPriceChanges(ctx context.Context, establishmentID uuid.UUID, ids []uuid.UUID, userID *uuid.UUID) ([]*models.PriceChange, error) {
+	changes, err := uc.pendingChanges(ctx, establishmentID, ids)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, ch := range changes {
+		ch.Status = models.PriceChangeStatusRejected
+		ch.DecidedAt = &now
+		ch.DecidedBy = userID
+		if err := uc.repo.UpdatePriceChange(ctx, ch); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+func (uc *RepricingUseCase) pendingChanges(ctx context.Context, establishmentID uuid.UUID, ids []uuid.UUID) ([]*models.PriceChange, error) {
+	status := models.PriceChangeStatusPending
+	changes, err := uc.repo.ListPriceChanges(ctx, &repositories.PriceChangeFilter{EstablishmentID: &establishmentID, Status: &status, IDs: ids})
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 && len(changes) != len(ids) {
+		return nil, errors.New("some price changes not found, already decided or access denied")
+	}
+	return changes, nil
+}
+
+// ApplyDue применяет одобренные изменения цен, срок которых наступил
+func (uc *RepricingUseCase) ApplyDue(ctx context.Context) error {
+	changes, err := uc.repo.ListDuePriceChanges(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, ch := range changes {
+		if err := uc.apply(ctx, ch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply меняет цену позиции. Если цена успела измениться с момента расчета, предложение помечается stale.
+// При заданной наценке она пересчитывается под новую цену, чтобы CalculatePrice при следующем редактировании ее сохранил.
+func (uc *RepricingUseCase) apply(ctx context.Context, ch *models.PriceChange) error {
+	now := time.Now()
+	ch.Status = models.PriceChangeStatusApplied
+	ch.AppliedAt = &now
+
+	switch {
+	case ch.TechCardID != nil:
+		tc, err := uc.techCardRepo.GetByID(ctx, *ch.TechCardID, &ch.EstablishmentID)
+		if err != nil || models.RoundTo2(tc.Price) != ch.OldPrice {
+			ch.Status = models.PriceChangeStatusStale
+			ch.AppliedAt = nil
+			break
+		}
+		tc.Markup = repricedMarkup(tc.Markup, tc.CostPrice, ch.NewPrice)
+		tc.Price = ch.NewPrice
+		if err := uc.repo.UpdateTechCardPrice(ctx, tc.ID, tc.Price, tc.Markup); err != nil {
+			return err
+		}
+		uc.costHistory.TrackTechCard(ctx, tc, models.CostChangeReasonRepricing)
+	case ch.ProductID != nil:
+		p, err := uc.productRepo.GetByID(ctx, *ch.ProductID, &ch.EstablishmentID)
+		if err != nil || models.RoundTo2(p.Price) != ch.OldPrice {
+			ch.Status = models.PriceChangeStatusStale
+			ch.AppliedAt = nil
+			break
+		}
+		p.Markup = repricedMarkup(p.Markup, p.CostPrice, ch.NewPrice)
+		p.Price = ch.NewPrice
+		if err := uc.repo.UpdateProductPrice(ctx, p.ID, p.Price, p.Markup); err != nil {
+			return err
+		}
+		uc.costHistory.TrackProduct(ctx, p, models.CostChangeReasonRepricing)
+	default:
+		return fmt.Errorf("price change %s has no item", ch.ID)
+	}
+	return uc.repo.UpdatePriceChange(ctx, ch)
+}
+
+// repricedMarkup возвращает наценку, соответствующую новой цене (если позиция оценивается по наценке)
+func repricedMarkup(markup, costPrice, price float64) float64 {
+	if markup <= 0 || costPrice <= 0 {
+		return markup
+	}
+	return models.RoundTo2((price/costPrice - 1) * 100)
+}
+
+func priceChangeItemID(techCardID, productID *uuid.UUID) uuid.UUID {
+	if techCardID != nil {
+		return *techCardID
+	}
+	if productID != nil {
+		return *productID
+	}
+	return uuid.Nil
+}
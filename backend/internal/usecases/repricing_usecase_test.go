@@ -0,0 +1,216 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// fakeRepricingRepository хранит копии правил, предложений и позиций меню: фоновый обработчик
+// читает и меняет их из своей горутины
+type fakeRepricingRepository struct {
+	repositories.RepricingRepository
+	mu        sync.Mutex
+	rules     []*models.RepricingRule
+	changes   map[uuid.UUID]models.PriceChange
+	techCards map[uuid.UUID]models.TechCard
+}
+
+func (r *fakeRepricingRepository) ListRules(ctx context.Context, establishmentID uuid.UUID, onlyActive bool) ([]*models.RepricingRule, error) {
+	var rules []*models.RepricingRule
+	for _, rule := range r.rules {
+		if !onlyActive || rule.Active {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (r *fakeRepricingRepository) CreatePriceChange(ctx context.Context, change *models.PriceChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	change.ID = uuid.New()
+	change.CreatedAt = time.Now()
+	r.changes[change.ID] = *change
+	return nil
+}
+
+func (r *fakeRepricingRepository) UpdatePriceChange(ctx context.Context, change *models.PriceChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes[change.ID] = *change
+	return nil
+}
+
+func (r *fakeRepricingRepository) ListPriceChanges(ctx context.Context, filter *repositories.PriceChangeFilter) ([]*models.PriceChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var changes []*models.PriceChange
+	for _, ch := range r.changes {
+		if filter.Status != nil && ch.Status != *filter.Status {
+			continue
+		}
+		if len(filter.IDs) > 0 && !slices.Contains(filter.IDs, ch.ID) {
+			continue
+		}
+		cp := ch
+		changes = append(changes, &cp)
+	}
+	return changes, nil
+}
+
+func (r *fakeRepricingRepository) ListDuePriceChanges(ctx context.Context, now time.Time) ([]*models.PriceChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var changes []*models.PriceChange
+	for _, ch := range r.changes {
+		if ch.Status == models.PriceChangeStatusApproved && ch.ApplyAt != nil && !ch.ApplyAt.After(now) {
+			cp := ch
+			changes = append(changes, &cp)
+		}
+	}
+	return changes, nil
+}
+
+func (r *fakeRepricingRepository) UpdateTechCardPrice(ctx context.Context, id uuid.UUID, price, markup float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tc := r.techCards[id]
+	tc.Price, tc.Markup = price, markup
+	r.techCards[id] = tc
+	return nil
+}
+
+// GetByID реализует TechCardRepository: apply сверяет цену с текущей ценой тех-карты
+func (r *fakeRepricingRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.TechCard, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tc, ok := r.techCards[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &tc, nil
+}
+
+func (r *fakeRepricingRepository) setTechCardPrice(id uuid.UUID, price float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tc := r.techCards[id]
+	tc.Price = price
+	r.techCards[id] = tc
+}
+
+func (r *fakeRepricingRepository) byStatus(status string) []models.PriceChange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var changes []models.PriceChange
+	for _, ch := range r.changes {
+		if ch.Status == status {
+			changes = append(changes, ch)
+		}
+	}
+	return changes
+}
+
+// repricingTechCardRepository отдает тех-карты из fakeRepricingRepository
+type repricingTechCardRepository struct {
+	repositories.TechCardRepository
+	repo *fakeRepricingRepository
+}
+
+func (r *repricingTechCardRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.TechCard, error) {
+	return r.repo.GetByID(ctx, id, establishmentID)
+}
+
+func TestRepricingUseCase_Run(t *testing.T) {
+	establishmentID, bakeryID := uuid.New(), uuid.New()
+	// Выпечка — по фудкосту 25%, остальное меню — с наценкой 100%
+	rules := []*models.RepricingRule{
+		{ID: uuid.New(), Name: "Меню", Strategy: models.RepricingStrategyMarkup, TargetValue: 100, Rounding: models.RepricingRoundingNone, Active: true},
+		{ID: uuid.New(), Name: "Выпечка", CategoryID: &bakeryID, Strategy: models.RepricingStrategyFoodCost, TargetValue: 25, Rounding: models.RepricingRoundingNone, Active: true},
+	}
+	pancake := &models.TechCard{ID: uuid.New(), EstablishmentID: establishmentID, Name: "Блин", CategoryID: bakeryID, CostPrice: 12, Price: 20, Markup: 66.67}
+	dumplings := &models.TechCard{ID: uuid.New(), EstablishmentID: establishmentID, Name: "Вареники", CategoryID: bakeryID, CostPrice: 3, Price: 10}
+	cola := &models.Product{ID: uuid.New(), EstablishmentID: establishmentID, Name: "Кола", CostPrice: 50, Price: 100}
+
+	repo := &fakeRepricingRepository{
+		rules:     rules,
+		changes:   make(map[uuid.UUID]models.PriceChange),
+		techCards: map[uuid.UUID]models.TechCard{pancake.ID: *pancake, dumplings.ID: *dumplings},
+	}
+	// Старое предложение по коле: цена по правилу уже совпадает, предложение устарело
+	stale := models.PriceChange{ID: uuid.New(), EstablishmentID: establishmentID, ProductID: &cola.ID, OldPrice: 100, NewPrice: 120, Status: models.PriceChangeStatusPending}
+	repo.changes[stale.ID] = stale
+
+	menu := &stockAvailabilityRepository{
+		fakeWarehouseRepository: newFakeWarehouseRepository(),
+		techCards:               map[uuid.UUID]*models.TechCard{pancake.ID: pancake, dumplings.ID: dumplings},
+		products:                []*models.Product{cola},
+	}
+	uc := NewRepricingUseCase(repo, &repricingTechCardRepository{repo: repo}, nil, nil, menu, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		uc.Run(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Себестоимость изменилась — обработчик пересобирает предложения
+	uc.Notify(establishmentID)
+	require.Eventually(t, func() bool { return len(repo.byStatus(models.PriceChangeStatusPending)) == 2 }, time.Second, 5*time.Millisecond)
+	superseded := repo.byStatus(models.PriceChangeStatusSuperseded)
+	require.Len(t, superseded, 1)
+	assert.Equal(t, stale.ID, superseded[0].ID)
+
+	status := models.PriceChangeStatusPending
+	pending, err := uc.ListPriceChanges(context.Background(), establishmentID, &status)
+	require.NoError(t, err)
+	newPrices := make(map[uuid.UUID]float64)
+	for _, ch := range pending {
+		require.NotNil(t, ch.TechCardID)
+		newPrices[*ch.TechCardID] = ch.NewPrice
+	}
+	assert.Equal(t, map[uuid.UUID]float64{pancake.ID: 48, dumplings.ID: 12}, newPrices)
+
+	// Одобрено с применением позже: цены меняет обработчик по расписанию
+	applyAt := time.Now().Add(50 * time.Millisecond)
+	approved, err := uc.ApprovePriceChanges(context.Background(), establishmentID, nil, &applyAt, nil)
+	require.NoError(t, err)
+	require.Len(t, approved, 2)
+	tc, _ := repo.GetByID(context.Background(), pancake.ID, nil)
+	assert.InDelta(t, 20, tc.Price, 1e-9)
+
+	// Цену вареников до срока поменяли вручную — предложение не применяется
+	repo.setTechCardPrice(dumplings.ID, 11)
+
+	require.Eventually(t, func() bool { return len(repo.byStatus(models.PriceChangeStatusApproved)) == 0 }, time.Second, 5*time.Millisecond)
+	applied := repo.byStatus(models.PriceChangeStatusApplied)
+	require.Len(t, applied, 1)
+	assert.Equal(t, pancake.ID, *applied[0].TechCardID)
+	assert.NotNil(t, applied[0].AppliedAt)
+	staleChanges := repo.byStatus(models.PriceChangeStatusStale)
+	require.Len(t, staleChanges, 1)
+	assert.Equal(t, dumplings.ID, *staleChanges[0].TechCardID)
+
+	// Наценка пересчитана под новую цену: 48 / 12 - 1 = 300%
+	tc, _ = repo.GetByID(context.Background(), pancake.ID, nil)
+	assert.InDelta(t, 48, tc.Price, 1e-9)
+	assert.InDelta(t, 300, tc.Markup, 1e-9)
+	tc, _ = repo.GetByID(context.Background(), dumplings.ID, nil)
+	assert.InDelta(t, 11, tc.Price, 1e-9)
+}
@@ -21,6 +21,7 @@ type StatisticsUseCase struct {
 	workshopRepo    repositories.WorkshopRepository
 	tableRepo       repositories.TableRepository
 	shiftRepo       repositories.ShiftRepository
+	costHistoryRepo repositories.CostHistoryRepository
 	logger          *zap.Logger
 }
 
@@ -33,6 +34,7 @@ func NewStatisticsUseCase(
 	workshopRepo repositories.WorkshopRepository,
 	tableRepo repositories.TableRepository,
 	shiftRepo repositories.ShiftRepository,
+	costHistoryRepo repositories.CostHistoryRepository,
 	logger *zap.Logger,
 ) *StatisticsUseCase {
 	return &StatisticsUseCase{
@@ -44,6 +46,7 @@ func NewStatisticsUseCase(
 		workshopRepo:   workshopRepo,
 		tableRepo:      tableRepo,
 		shiftRepo:      shiftRepo,
+		costHistoryRepo: costHistoryRepo,
 		logger:         logger,
 	}
 }
@@ -645,14 +648,27 @@ func (uc *StatisticsUseCase) GetCategoryStatistics(ctx context.Context, establis
 	maxRevenue := 0.0
 	var topCategoryUUID uuid.UUID
 
+	addRevenue := func(categoryID uuid.UUID, revenue float64) {
+		categoryRevenue[categoryID] += revenue
+		if categoryRevenue[categoryID] > maxRevenue {
+			maxRevenue = categoryRevenue[categoryID]
+			topCategoryUUID = categoryID
+		}
+	}
+
 	for _, order := range orders {
 		for _, item := range order.Items {
-			if item.Product != nil {
-				categoryRevenue[item.Product.CategoryID] += item.TotalPrice
-				if categoryRevenue[item.Product.CategoryID] > maxRevenue {
-					maxRevenue = categoryRevenue[item.Product.CategoryID]
-					topCategoryUUID = item.Product.CategoryID
+			// Выручка комбо распределяется по категориям выбранных компонентов
+			if item.ComboID != nil {
+				for _, component := range item.Components {
+					if categoryID, ok := component.CategoryID(); ok {
+						addRevenue(categoryID, component.AllocatedPrice*float64(item.Quantity))
+					}
 				}
+				continue
+			}
+			if item.Product != nil {
+				addRevenue(item.Product.CategoryID, item.TotalPrice)
 			}
 		}
 	}
@@ -674,14 +690,38 @@ func (uc *StatisticsUseCase) GetCategoryStatistics(ctx context.Context, establis
 	return stats, nil
 }
 
-// GetProductStatistics возвращает статистику товаров
-func (uc *StatisticsUseCase) GetProductStatistics(ctx context.Context, establishmentID uuid.UUID, startDate, endDate time.Time) (*models.ProductStatistics, error) {
-	products, err := uc.productRepo.List(ctx, &repositories.ProductFilter{
+// productReportIDs сопоставляет ID проданного товара с ID строки отчета.
+// При rollupVariants продажи модификаций сворачиваются в родительский товар, а в products остаются только товары верхнего уровня
+func productReportIDs(products []*models.Product, rollupVariants bool) (map[uuid.UUID]uuid.UUID, []*models.Product) {
+	reportIDs := make(map[uuid.UUID]uuid.UUID, len(products))
+	reported := make([]*models.Product, 0, len(products))
+	for _, p := range products {
+		reportIDs[p.ID] = p.ReportID(rollupVariants)
+		if rollupVariants && p.IsVariant() {
+			continue
+		}
+		reported = append(reported, p)
+	}
+	return reportIDs, reported
+}
+
+// productReportID возвращает ID строки отчета для проданного товара
+func productReportID(reportIDs map[uuid.UUID]uuid.UUID, productID uuid.UUID) uuid.UUID {
+	if id, ok := reportIDs[productID]; ok {
+		return id
+	}
+	return productID
+}
+
+// GetProductStatistics возвращает статистику товаров; при rollupVariants модификации сворачиваются в родительский товар
+func (uc *StatisticsUseCase) GetProductStatistics(ctx context.Context, establishmentID uuid.UUID, startDate, endDate time.Time, rollupVariants bool) (*models.ProductStatistics, error) {
+	allProducts, err := uc.productRepo.List(ctx, &repositories.ProductFilter{
 		EstablishmentID: &establishmentID,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get products: %w", err)
 	}
+	reportIDs, products := productReportIDs(allProducts, rollupVariants)
 
 	stats := &models.ProductStatistics{
 		TotalProducts: len(products),
@@ -701,18 +741,19 @@ func (uc *StatisticsUseCase) GetProductStatistics(ctx context.Context, establish
 	for _, order := range orders {
 		for _, item := range order.Items {
 			if item.ProductID != nil {
-				if productStats[*item.ProductID] == nil {
-					productStats[*item.ProductID] = &models.ProductData{
-						ProductID: item.ProductID.String(),
+				productID := productReportID(reportIDs, *item.ProductID)
+				if productStats[productID] == nil {
+					productStats[productID] = &models.ProductData{
+						ProductID: productID.String(),
 					}
 				}
-				productStats[*item.ProductID].QuantitySold += item.Quantity
-				productStats[*item.ProductID].Revenue += item.TotalPrice
-				productStats[*item.ProductID].OrdersCount++
+				productStats[productID].QuantitySold += item.Quantity
+				productStats[productID].Revenue += item.TotalPrice
+				productStats[productID].OrdersCount++
 
-				if productStats[*item.ProductID].Revenue > maxRevenue {
-					maxRevenue = productStats[*item.ProductID].Revenue
-					topProductUUID = *item.ProductID
+				if productStats[productID].Revenue > maxRevenue {
+					maxRevenue = productStats[productID].Revenue
+					topProductUUID = productID
 				}
 			}
 		}
@@ -745,15 +786,16 @@ func (uc *StatisticsUseCase) GetProductStatistics(ctx context.Context, establish
 	return stats, nil
 }
 
-// GetABCAnalysis выполняет ABC анализ товаров
-func (uc *StatisticsUseCase) GetABCAnalysis(ctx context.Context, establishmentID uuid.UUID, startDate, endDate time.Time) (*models.ABCAnalysisData, error) {
+// GetABCAnalysis выполняет ABC анализ товаров; при rollupVariants модификации сворачиваются в родительский товар
+func (uc *StatisticsUseCase) GetABCAnalysis(ctx context.Context, establishmentID uuid.UUID, startDate, endDate time.Time, rollupVariants bool) (*models.ABCAnalysisData, error) {
 	// Получаем товары
-	products, err := uc.productRepo.List(ctx, &repositories.ProductFilter{
+	allProducts, err := uc.productRepo.List(ctx, &repositories.ProductFilter{
 		EstablishmentID: &establishmentID,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get products: %w", err)
 	}
+	reportIDs, products := productReportIDs(allProducts, rollupVariants)
 
 	// Получаем заказы за период
 	orders, err := uc.orderRepo.ListByEstablishmentIDAndDateRange(ctx, establishmentID, startDate, endDate)
@@ -774,10 +816,11 @@ func (uc *StatisticsUseCase) GetABCAnalysis(ctx context.Context, establishmentID
 	for _, order := range orders {
 		for _, item := range order.Items {
 			if item.ProductID != nil {
-				stats := productStats[*item.ProductID]
+				productID := productReportID(reportIDs, *item.ProductID)
+				stats := productStats[productID]
 				stats.Revenue += item.TotalPrice
 				stats.QuantitySold += int(item.Quantity)
-				productStats[*item.ProductID] = stats
+				productStats[productID] = stats
 			}
 		}
 	}
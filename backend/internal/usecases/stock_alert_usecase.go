@@ -0,0 +1,432 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+const (
+	// Период, по которому считается средний дневной расход
+	defaultConsumptionWindowDays = 14
+	// На сколько дней после поставки должно хватить дозаказанного количества
+	defaultReorderCoverDays = 7
+)
+
+// Документы, расход по которым считается потреблением (перемещения не учитываются)
+var reorderConsumptionDocuments = []string{
+	models.StockConsumptionSale,
+	models.StockConsumptionWriteOff,
+	models.StockConsumptionProduction,
+}
+
+// StockAlertUseCase следит за остатками ниже лимита и формирует рекомендации по дозаказу
+type StockAlertUseCase struct {
+	alertRepo         repositories.StockAlertRepository
+	warehouseRepo     repositories.WarehouseRepository
+	supplierRepo      repositories.SupplierRepository
+	purchaseOrderRepo repositories.PurchaseOrderRepository
+	logger            *zap.Logger
+	trigger           chan uuid.UUID
+}
+
+func NewStockAlertUseCase(
+	alertRepo repositories.StockAlertRepository,
+	warehouseRepo repositories.WarehouseRepository,
+	supplierRepo repositories.SupplierRepository,
+	purchaseOrderRepo repositories.PurchaseOrderRepository,
+	logger *zap.Logger,
+) *StockAlertUseCase {
+	return &StockAlertUseCase{
+		alertRepo:         alertRepo,
+		warehouseRepo:     warehouseRepo,
+		supplierRepo:      supplierRepo,
+		purchaseOrderRepo: purchaseOrderRepo,
+		logger:            logger,
+		trigger:           make(chan uuid.UUID, 100),
+	}
+}
+
+// ——— Evaluator ———
+
+// Notify сообщает фоновому обработчику, что остатки заведения изменились (продажа, списание, инвентаризация).
+// Вызов не блокирует: если очередь переполнена, остатки будут проверены при следующем плановом проходе.
+func (uc *StockAlertUseCase) Notify(establishmentID uuid.UUID) {
+	if uc == nil {
+		return
+	}
+	select {
+	case uc.trigger <- establishmentID:
+	default:
+	}
+}
+
+// Run запускает фоновую проверку остатков: по сигналам Notify и периодически раз в interval
+func (uc *StockAlertUseCase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	uc.evaluateAndLog(ctx, nil)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			uc.evaluateAndLog(ctx, nil)
+		case establishmentID := <-uc.trigger:
+			uc.evaluateAndLog(ctx, &establishmentID)
+		}
+	}
+}
+
+func (uc *StockAlertUseCase) evaluateAndLog(ctx context.Context, establishmentID *uuid.UUID) {
+	if err := uc.EvaluateStockAlerts(ctx, establishmentID); err != nil && uc.logger != nil {
+		uc.logger.Error("Failed to evaluate stock alerts", zap.Error(err))
+	}
+}
+
+// EvaluateStockAlerts сравнивает остатки с лимитами: создает уведомления для остатков ниже лимита
+// и закрывает активные уведомления, если остаток восстановлен. Если establishmentID не указан — по всем заведениям.
+func (uc *StockAlertUseCase) EvaluateStockAlerts(ctx context.Context, establishmentID *uuid.UUID) error {
+	stocks, err := uc.warehouseRepo.GetStocksWithLimit(ctx, establishmentID)
+	if err != nil {
+		return fmt.Errorf("failed to get stocks with limit: %w", err)
+	}
+
+	for _, stock := range stocks {
+		if stock.Warehouse == nil {
+			continue
+		}
+		active, err := uc.alertRepo.GetActiveByStockID(ctx, stock.ID)
+		if err != nil {
+			return err
+		}
+
+		below := stock.Quantity < stock.Limit
+		switch {
+		case below && active == nil:
+			alert := &models.StockAlert{
+				EstablishmentID: stock.Warehouse.EstablishmentID,
+				WarehouseID:     stock.WarehouseID,
+				StockID:         stock.ID,
+				IngredientID:    stock.IngredientID,
+				ProductID:       stock.ProductID,
+				SemiFinishedID:  stock.SemiFinishedID,
+				Quantity:        stock.Quantity,
+				Limit:           stock.Limit,
+				Unit:            stock.Unit,
+				Status:          models.StockAlertStatusOpen,
+			}
+			if err := uc.alertRepo.Create(ctx, alert); err != nil {
+				return err
+			}
+		case below && active != nil:
+			// Обновляем текущий остаток в активном уведомлении
+			if models.RoundTo2(stock.Quantity) != active.Quantity || models.RoundTo2(stock.Limit) != active.Limit {
+				active.Quantity = models.RoundTo2(stock.Quantity)
+				active.Limit = models.RoundTo2(stock.Limit)
+				if err := uc.alertRepo.Update(ctx, active); err != nil {
+					return err
+				}
+			}
+		case !below && active != nil:
+			now := time.Now()
+			active.Quantity = models.RoundTo2(stock.Quantity)
+			active.Status = models.StockAlertStatusResolved
+			active.ResolvedAt = &now
+			if err := uc.alertRepo.Update(ctx, active); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ——— Alerts ———
+
+// ListAlerts возвращает уведомления о низком остатке
+func (uc *StockAlertUseCase) ListAlerts(ctx context.Context, establishmentID uuid.UUID, filter *repositories.StockAlertFilter) ([]*models.StockAlert, error) {
+	if filter == nil {
+		filter = &repositories.StockAlertFilter{}
+	}
+	filter.EstablishmentID = &establishmentID
+	return uc.alertRepo.List(ctx, filter)
+}
+
+// AcknowledgeAlert отмечает уведомление как просмотренное
+func (uc *StockAlertUseCase) AcknowledgeAlert(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID, userID *uuid.UUID) (*models.StockAlert, error) {
+	alert, err := uc.alertRepo.GetByID(ctx, id, &establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	if alert == nil {
+		return nil, errors.New("stock alert not found or access denied")
+	}
+	if alert.Status == models.StockAlertStatusResolved {
+		return nil, errors.New("stock alert is already resolved")
+	}
+	if alert.Status == models.StockAlertStatusAcknowledged {
+		return alert, nil
+	}
+
+	now := time.Now()
+	alert.Status = models.StockAlertStatusAcknowledged
+	alert.AcknowledgedAt = &now
+	alert.AcknowledgedBy = userID
+	if err := uc.alertRepo.Update(ctx, alert); err != nil {
+		return nil, err
+	}
+	return alert, nil
+}
+
+// ——— Reorder suggestions ———
+
+// ReorderOptions параметры расчета рекомендаций по дозаказу
+type ReorderOptions struct {
+	WarehouseID *uuid.UUID
+	WindowDays  int // Период расчета среднего расхода (по умолчанию 14 дней)
+	CoverDays   int // На сколько дней после поставки должно хватить запаса (по умолчанию 7 дней)
+}
+
+// ReorderSuggestionItem рекомендуемая к дозаказу позиция
+type ReorderSuggestionItem struct {
+	StockID           uuid.UUID  `json:"stock_id"`
+	IngredientID      *uuid.UUID `json:"ingredient_id,omitempty"`
+	ProductID         *uuid.UUID `json:"product_id,omitempty"`
+	Name              string     `json:"name"`
+	Unit              string     `json:"unit"`
+	CurrentQuantity   float64    `json:"current_quantity"`
+	Limit             float64    `json:"limit"`
+	DailyConsumption  float64    `json:"daily_consumption"` // Средний расход в день за период
+	SuggestedQuantity float64    `json:"suggested_quantity"`
+	PricePerUnit      float64    `json:"price_per_unit"` // Цена последней поставки (в единице остатка)
+	TotalAmount       float64    `json:"total_amount"`
+	LastSupplyDate    *time.Time `json:"last_supply_date,omitempty"`
+}
+
+// ReorderSuggestion рекомендация по дозаказу у одного поставщика на один склад.
+// Позиции без истории поставок группируются без поставщика.
+type ReorderSuggestion struct {
+	SupplierID   *uuid.UUID              `json:"supplier_id,omitempty"`
+	Supplier     *models.Supplier        `json:"supplier,omitempty"`
+	WarehouseID  uuid.UUID               `json:"warehouse_id"`
+	Warehouse    *models.Warehouse       `json:"warehouse,omitempty"`
+	LeadTimeDays int                     `json:"lead_time_days"`
+	TotalAmount  float64                 `json:"total_amount"`
+	Items        []ReorderSuggestionItem `json:"items"`
+}
+
+type reorderGroupKey struct {
+	supplierID  uuid.UUID
+	warehouseID uuid.UUID
+}
+
+// GetReorderSuggestions рассчитывает рекомендации по дозаказу.
+// Позиция попадает в рекомендацию, если с учетом среднего расхода за срок поставки остаток опустится ниже лимита.
+// Рекомендуемое количество покрывает лимит и расход на срок поставки плюс CoverDays.
+func (uc *StockAlertUseCase) GetReorderSuggestions(ctx context.Context, establishmentID uuid.UUID, opts ReorderOptions) ([]*ReorderSuggestion, error) {
+	if opts.WindowDays <= 0 {
+		opts.WindowDays = defaultConsumptionWindowDays
+	}
+	if opts.CoverDays <= 0 {
+		opts.CoverDays = defaultReorderCoverDays
+	}
+
+	stocks, err := uc.warehouseRepo.GetStocksWithLimit(ctx, &establishmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stocks with limit: %w", err)
+	}
+
+	since := time.Now().AddDate(0, 0, -opts.WindowDays)
+	consumptionByWarehouse := make(map[uuid.UUID]map[uuid.UUID]float64)
+	suppliers := make(map[uuid.UUID]*models.Supplier)
+	groups := make(map[reorderGroupKey]*ReorderSuggestion)
+
+	for _, stock := range stocks {
+		// Полуфабрикаты производятся, а не закупаются
+		if stock.IngredientID == nil && stock.ProductID == nil {
+			continue
+		}
+		if opts.WarehouseID != nil && stock.WarehouseID != *opts.WarehouseID {
+			continue
+		}
+
+		consumed, ok := consumptionByWarehouse[stock.WarehouseID]
+		if !ok {
+			rows, err := uc.warehouseRepo.GetConsumedQuantities(ctx, stock.WarehouseID, reorderConsumptionDocuments, since)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get consumption: %w", err)
+			}
+			consumed = make(map[uuid.UUID]float64, len(rows))
+			for _, row := range rows {
+				consumed[row.ItemID] = row.Quantity
+			}
+			consumptionByWarehouse[stock.WarehouseID] = consumed
+		}
+
+		itemID, name := stockItem(stock)
+		dailyConsumption := consumed[itemID] / float64(opts.WindowDays)
+
+		// Поставщик и цена — из последней поставки позиции
+		last, err := uc.warehouseRepo.GetLastSupplyPrice(ctx, establishmentID, stock.IngredientID, stock.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last supply price: %w", err)
+		}
+		var supplier *models.Supplier
+		pricePerUnit := stock.PricePerUnit
+		var lastSupplyDate *time.Time
+		if last != nil {
+			if s, ok := suppliers[last.SupplierID]; ok {
+				supplier = s
+			} else if s, err := uc.supplierRepo.GetByID(ctx, last.SupplierID, &establishmentID); err == nil {
+				supplier = s
+				suppliers[last.SupplierID] = s
+			}
+			if factor, err := stockUnitFactor(last.Unit, stock.Unit); err == nil && factor > 0 {
+				pricePerUnit = last.PricePerUnit / factor
+			}
+			deliveryDate := last.DeliveryDateTime
+			lastSupplyDate = &deliveryDate
+		}
+
+		leadTimeDays := 1
+		if supplier != nil && supplier.LeadTimeDays > 0 {
+			leadTimeDays = supplier.LeadTimeDays
+		}
+
+		// Остаток к моменту поступления заказа
+		projected := stock.Quantity - dailyConsumption*float64(leadTimeDays)
+		if projected >= stock.Limit {
+			continue
+		}
+		suggested := stock.Limit + dailyConsumption*float64(leadTimeDays+opts.CoverDays) - stock.Quantity
+		suggested = math.Ceil(suggested*100) / 100
+		if suggested <= 0 {
+			continue
+		}
+
+		key := reorderGroupKey{warehouseID: stock.WarehouseID}
+		if supplier != nil {
+			key.supplierID = supplier.ID
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = &ReorderSuggestion{
+				WarehouseID:  stock.WarehouseID,
+				Warehouse:    stock.Warehouse,
+				LeadTimeDays: leadTimeDays,
+			}
+			if supplier != nil {
+				supplierID := supplier.ID
+				group.SupplierID = &supplierID
+				group.Supplier = supplier
+			}
+			groups[key] = group
+		}
+
+		item := ReorderSuggestionItem{
+			StockID:           stock.ID,
+			IngredientID:      stock.IngredientID,
+			ProductID:         stock.ProductID,
+			Name:              name,
+			Unit:              stock.Unit,
+			CurrentQuantity:   models.RoundTo2(stock.Quantity),
+			Limit:             models.RoundTo2(stock.Limit),
+			DailyConsumption:  models.RoundTo2(dailyConsumption),
+			SuggestedQuantity: suggested,
+			PricePerUnit:      models.RoundTo2(pricePerUnit),
+			TotalAmount:       models.RoundTo2(suggested * pricePerUnit),
+			LastSupplyDate:    lastSupplyDate,
+		}
+		group.Items = append(group.Items, item)
+		group.TotalAmount = models.RoundTo2(group.TotalAmount + item.TotalAmount)
+	}
+
+	result := make([]*ReorderSuggestion, 0, len(groups))
+	for _, group := range groups {
+		sort.Slice(group.Items, func(i, j int) bool { return group.Items[i].Name < group.Items[j].Name })
+		result = append(result, group)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].TotalAmount > result[j].TotalAmount })
+	return result, nil
+}
+
+// CreatePurchaseOrderFromSuggestion создает черновик заказа поставщику из рекомендации по дозаказу
+func (uc *StockAlertUseCase) CreatePurchaseOrderFromSuggestion(ctx context.Context, establishmentID, supplierID, warehouseID uuid.UUID, opts ReorderOptions) (*models.PurchaseOrder, error) {
+	opts.WarehouseID = &warehouseID
+	suggestions, err := uc.GetReorderSuggestions(ctx, establishmentID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var suggestion *ReorderSuggestion
+	for _, s := range suggestions {
+		if s.SupplierID != nil && *s.SupplierID == supplierID {
+			suggestion = s
+			break
+		}
+	}
+	if suggestion == nil || len(suggestion.Items) == 0 {
+		return nil, errors.New("no reorder suggestion for this supplier and warehouse")
+	}
+
+	expected := time.Now().AddDate(0, 0, suggestion.LeadTimeDays)
+	order := &models.PurchaseOrder{
+		EstablishmentID: establishmentID,
+		SupplierID:      supplierID,
+		WarehouseID:     warehouseID,
+		Status:          models.PurchaseOrderStatusDraft,
+		ExpectedDate:    &expected,
+		Comment:         "Создан из рекомендации по дозаказу",
+		TotalAmount:     suggestion.TotalAmount,
+	}
+	for _, item := range suggestion.Items {
+		order.Items = append(order.Items, models.PurchaseOrderItem{
+			IngredientID: item.IngredientID,
+			ProductID:    item.ProductID,
+			Quantity:     item.SuggestedQuantity,
+			Unit:         item.Unit,
+			PricePerUnit: item.PricePerUnit,
+			TotalAmount:  item.TotalAmount,
+		})
+	}
+
+	if err := uc.purchaseOrderRepo.Create(ctx, order); err != nil {
+		return nil, fmt.Errorf("failed to create purchase order: %w", err)
+	}
+	return uc.purchaseOrderRepo.GetByID(ctx, order.ID, &establishmentID)
+}
+
+// stockItem возвращает ID и название позиции остатка
+func stockItem(stock *models.Stock) (uuid.UUID, string) {
+	switch {
+	case stock.IngredientID != nil:
+		name := ""
+		if stock.Ingredient != nil {
+			name = stock.Ingredient.Name
+		}
+		return *stock.IngredientID, name
+	case stock.ProductID != nil:
+		name := ""
+		if stock.Product != nil {
+			name = stock.Product.Name
+		}
+		return *stock.ProductID, name
+	case stock.SemiFinishedID != nil:
+		name := ""
+		if stock.SemiFinished != nil {
+			name = stock.SemiFinished.Name
+		}
+		return *stock.SemiFinishedID, name
+	}
+	return uuid.Nil, ""
+}
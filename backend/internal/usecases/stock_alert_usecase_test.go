@@ -0,0 +1,211 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// fakeStockAlertRepository хранит уведомления в памяти; Update меняет ту же запись, поэтому только считает вызовы
+type fakeStockAlertRepository struct {
+	repositories.StockAlertRepository
+	alerts  []*models.StockAlert
+	updates int
+}
+
+func (r *fakeStockAlertRepository) Create(ctx context.Context, alert *models.StockAlert) error {
+	alert.ID = uuid.New()
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func (r *fakeStockAlertRepository) Update(ctx context.Context, alert *models.StockAlert) error {
+	r.updates++
+	return nil
+}
+
+func (r *fakeStockAlertRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.StockAlert, error) {
+	for _, a := range r.alerts {
+		if a.ID == id {
+			return a, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeStockAlertRepository) GetActiveByStockID(ctx context.Context, stockID uuid.UUID) (*models.StockAlert, error) {
+	for _, a := range r.alerts {
+		if a.StockID == stockID && a.Status != models.StockAlertStatusResolved {
+			return a, nil
+		}
+	}
+	return nil, nil
+}
+
+// stockAlertWarehouseRepository остатки с лимитом, расход за период и последние поставки по позициям
+type stockAlertWarehouseRepository struct {
+	repositories.WarehouseRepository
+	stocks     []*models.Stock
+	consumed   map[uuid.UUID]float64
+	lastSupply map[uuid.UUID]*repositories.LastSupplyPrice
+	since      time.Time
+}
+
+func (r *stockAlertWarehouseRepository) GetStocksWithLimit(ctx context.Context, establishmentID *uuid.UUID) ([]*models.Stock, error) {
+	return r.stocks, nil
+}
+
+func (r *stockAlertWarehouseRepository) GetConsumedQuantities(ctx context.Context, warehouseID uuid.UUID, documentTypes []string, since time.Time) ([]repositories.ConsumedQuantity, error) {
+	r.since = since
+	var rows []repositories.ConsumedQuantity
+	for id, qty := range r.consumed {
+		rows = append(rows, repositories.ConsumedQuantity{ItemID: id, Quantity: qty})
+	}
+	return rows, nil
+}
+
+func (r *stockAlertWarehouseRepository) GetLastSupplyPrice(ctx context.Context, establishmentID uuid.UUID, ingredientID, productID *uuid.UUID) (*repositories.LastSupplyPrice, error) {
+	if ingredientID != nil {
+		return r.lastSupply[*ingredientID], nil
+	}
+	return r.lastSupply[*productID], nil
+}
+
+type stockAlertSupplierRepository struct {
+	repositories.SupplierRepository
+	suppliers map[uuid.UUID]*models.Supplier
+}
+
+func (r *stockAlertSupplierRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Supplier, error) {
+	return r.suppliers[id], nil
+}
+
+func TestStockAlertUseCase_EvaluateStockAlerts(t *testing.T) {
+	ctx := context.Background()
+	warehouse := &models.Warehouse{ID: uuid.New(), EstablishmentID: uuid.New()}
+	flourID, sugarID := uuid.New(), uuid.New()
+	flour := &models.Stock{ID: uuid.New(), WarehouseID: warehouse.ID, Warehouse: warehouse, IngredientID: &flourID, Quantity: 2, Limit: 5, Unit: models.UnitKilogram}
+	// Остаток, равный лимиту, еще не считается низким
+	sugar := &models.Stock{ID: uuid.New(), WarehouseID: warehouse.ID, Warehouse: warehouse, IngredientID: &sugarID, Quantity: 5, Limit: 5, Unit: models.UnitKilogram}
+	alerts := &fakeStockAlertRepository{}
+	uc := NewStockAlertUseCase(alerts, &stockAlertWarehouseRepository{stocks: []*models.Stock{flour, sugar}}, nil, nil, nil)
+
+	require.NoError(t, uc.EvaluateStockAlerts(ctx, nil))
+	require.Len(t, alerts.alerts, 1)
+	alert := alerts.alerts[0]
+	assert.Equal(t, flour.ID, alert.StockID)
+	assert.Equal(t, warehouse.EstablishmentID, alert.EstablishmentID)
+	assert.Equal(t, models.StockAlertStatusOpen, alert.Status)
+	assert.InDelta(t, 2, alert.Quantity, 1e-9)
+
+	// Повторная проверка без изменений не создает второе уведомление и не обновляет первое
+	require.NoError(t, uc.EvaluateStockAlerts(ctx, nil))
+	assert.Len(t, alerts.alerts, 1)
+	assert.Zero(t, alerts.updates)
+
+	// Просмотренное уведомление остается активным: остаток обновляется в нем же
+	_, err := uc.AcknowledgeAlert(ctx, alert.ID, warehouse.EstablishmentID, nil)
+	require.NoError(t, err)
+	flour.Quantity = 3.456
+	require.NoError(t, uc.EvaluateStockAlerts(ctx, nil))
+	assert.Len(t, alerts.alerts, 1)
+	assert.Equal(t, models.StockAlertStatusAcknowledged, alert.Status)
+	assert.InDelta(t, 3.46, alert.Quantity, 1e-9)
+
+	// Остаток восстановлен — уведомление закрывается
+	flour.Quantity = 8
+	require.NoError(t, uc.EvaluateStockAlerts(ctx, nil))
+	assert.Equal(t, models.StockAlertStatusResolved, alert.Status)
+	assert.NotNil(t, alert.ResolvedAt)
+	assert.InDelta(t, 8, alert.Quantity, 1e-9)
+
+	// Новое падение ниже лимита открывает новое уведомление
+	flour.Quantity = 1
+	require.NoError(t, uc.EvaluateStockAlerts(ctx, nil))
+	require.Len(t, alerts.alerts, 2)
+	assert.Equal(t, models.StockAlertStatusOpen, alerts.alerts[1].Status)
+}
+
+func TestStockAlertUseCase_GetReorderSuggestions(t *testing.T) {
+	ctx := context.Background()
+	establishmentID := uuid.New()
+	warehouse := &models.Warehouse{ID: uuid.New(), EstablishmentID: establishmentID}
+	supplier := &models.Supplier{ID: uuid.New(), Name: "Мельница", LeadTimeDays: 3}
+
+	flourID, sugarID, syrupID, doughID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	stock := func(ingredientID, productID, semiFinishedID *uuid.UUID, name string, quantity, limit float64) *models.Stock {
+		return &models.Stock{
+			ID: uuid.New(), WarehouseID: warehouse.ID, Warehouse: warehouse,
+			IngredientID: ingredientID, Ingredient: &models.Ingredient{Name: name},
+			ProductID: productID, Product: &models.Product{Name: name},
+			SemiFinishedID: semiFinishedID,
+			Quantity:       quantity, Limit: limit, Unit: models.UnitKilogram, PricePerUnit: 50,
+		}
+	}
+	repo := &stockAlertWarehouseRepository{
+		stocks: []*models.Stock{
+			stock(&flourID, nil, nil, "Мука", 10, 5),
+			stock(&sugarID, nil, nil, "Сахар", 20, 5),
+			stock(nil, &syrupID, nil, "Сироп", 1, 3),
+			stock(nil, nil, &doughID, "Тесто", 0, 5),
+		},
+		// 28 кг за 14 дней — 2 кг в день
+		consumed: map[uuid.UUID]float64{flourID: 28, sugarID: 28},
+		lastSupply: map[uuid.UUID]*repositories.LastSupplyPrice{
+			flourID: {SupplierID: supplier.ID, PricePerUnit: 0.06, Unit: models.UnitGram, DeliveryDateTime: time.Now().AddDate(0, 0, -5)},
+			sugarID: {SupplierID: supplier.ID, PricePerUnit: 90, Unit: models.UnitKilogram},
+		},
+	}
+	uc := NewStockAlertUseCase(nil, repo, &stockAlertSupplierRepository{suppliers: map[uuid.UUID]*models.Supplier{supplier.ID: supplier}}, nil, nil)
+
+	suggestions, err := uc.GetReorderSuggestions(ctx, establishmentID, ReorderOptions{})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, -defaultConsumptionWindowDays), repo.since, time.Minute)
+	require.Len(t, suggestions, 2)
+
+	// Мука: за 3 дня поставки остаток опустится до 4 кг < 5 — дозаказ покрывает лимит
+	// и расход на срок поставки плюс 7 дней: 5 + 2*(3+7) - 10 = 15 кг по цене поставки 60 за кг.
+	// Сахара хватает: 20 - 2*3 = 14 кг, полуфабрикаты не закупаются
+	bySupplier := suggestions[0]
+	require.NotNil(t, bySupplier.SupplierID)
+	assert.Equal(t, supplier.ID, *bySupplier.SupplierID)
+	assert.Equal(t, 3, bySupplier.LeadTimeDays)
+	require.Len(t, bySupplier.Items, 1)
+	item := bySupplier.Items[0]
+	assert.Equal(t, "Мука", item.Name)
+	assert.InDelta(t, 2, item.DailyConsumption, 1e-9)
+	assert.InDelta(t, 15, item.SuggestedQuantity, 1e-9)
+	assert.InDelta(t, 60, item.PricePerUnit, 1e-9)
+	assert.InDelta(t, 900, item.TotalAmount, 1e-9)
+	assert.NotNil(t, item.LastSupplyDate)
+	assert.InDelta(t, 900, bySupplier.TotalAmount, 1e-9)
+
+	// Сироп без истории поставок: без поставщика, срок поставки 1 день, цена остатка
+	noSupplier := suggestions[1]
+	assert.Nil(t, noSupplier.SupplierID)
+	assert.Equal(t, 1, noSupplier.LeadTimeDays)
+	require.Len(t, noSupplier.Items, 1)
+	assert.Equal(t, "Сироп", noSupplier.Items[0].Name)
+	assert.InDelta(t, 2, noSupplier.Items[0].SuggestedQuantity, 1e-9)
+	assert.InDelta(t, 100, noSupplier.Items[0].TotalAmount, 1e-9)
+
+	// За 28 дней расход муки — 1 кг в день: к поставке останется 7 кг, дозаказ не нужен
+	suggestions, err = uc.GetReorderSuggestions(ctx, establishmentID, ReorderOptions{WindowDays: 28, WarehouseID: &warehouse.ID})
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+	assert.Nil(t, suggestions[0].SupplierID)
+
+	// Запас на 17 дней после поставки: 5 + 2*(3+17) - 10 = 35 кг
+	suggestions, err = uc.GetReorderSuggestions(ctx, establishmentID, ReorderOptions{CoverDays: 17})
+	require.NoError(t, err)
+	require.Len(t, suggestions, 2)
+	assert.InDelta(t, 35, suggestions[0].Items[0].SuggestedQuantity, 1e-9)
+	assert.InDelta(t, 2100, suggestions[0].TotalAmount, 1e-9)
+}
@@ -0,0 +1,458 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// ErrInsufficientStock нехватка остатков при политике block
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// InsufficientStockError перечисляет позиции склада, которых не хватает для заказа
+type InsufficientStockError struct {
+	Shortages []models.StockShortage
+}
+
+func (e *InsufficientStockError) Error() string {
+	parts := make([]string, 0, len(e.Shortages))
+	for _, s := range e.Shortages {
+		parts = append(parts, fmt.Sprintf("%s (required %s %s, available %s)",
+			s.Name, formatAmount(s.Required), s.Unit, formatAmount(s.Available)))
+	}
+	return "insufficient stock: " + strings.Join(parts, "; ")
+}
+
+func (e *InsufficientStockError) Unwrap() error {
+	return ErrInsufficientStock
+}
+
+// stockUsage расход позиций склада на позиции заказа (или порции блюда)
+type stockUsage struct {
+	ingredients  map[uuid.UUID]itemUsage
+	products     map[uuid.UUID]itemUsage
+	semiFinished map[uuid.UUID]itemUsage // Списывается с произведенного остатка полуфабриката
+	names        map[uuid.UUID]string
+
+	// Полная потребность в полуфабрикатах до раскладки на ингредиенты
+	semiDemand map[uuid.UUID]itemUsage
+	semiByID   map[uuid.UUID]*models.SemiFinishedProduct
+}
+
+func newStockUsage() *stockUsage {
+	return &stockUsage{
+		ingredients:  make(map[uuid.UUID]itemUsage),
+		products:     make(map[uuid.UUID]itemUsage),
+		semiFinished: make(map[uuid.UUID]itemUsage),
+		names:        make(map[uuid.UUID]string),
+		semiDemand:   make(map[uuid.UUID]itemUsage),
+		semiByID:     make(map[uuid.UUID]*models.SemiFinishedProduct),
+	}
+}
+
+// addTechCard добавляет расход на quantity порций тех-карты. Со склада списывается брутто:
+// нетто с учетом потерь при приготовлении
+func (u *stockUsage) addTechCard(techCard *models.TechCard, quantity float64) error {
+	for _, ing := range techCard.Ingredients {
+		qty := ing.GrossQuantity() * quantity
+		if qty == 0 {
+			continue
+		}
+
+		if ing.SemiFinishedID != nil && ing.SemiFinished != nil {
+			// Приводим количество к единице выхода полуфабриката
+			sfUnit := models.NormalizeUnit(ing.SemiFinished.Unit)
+			factor, err := stockUnitFactor(ing.Unit, sfUnit)
+			if err != nil {
+				return fmt.Errorf("tech card %q, semi-finished product %q: %w", techCard.Name, ing.SemiFinished.Name, err)
+			}
+			current := u.semiDemand[*ing.SemiFinishedID]
+			u.semiDemand[*ing.SemiFinishedID] = itemUsage{
+				quantity: current.quantity + qty*factor,
+				unit:     sfUnit,
+			}
+			u.semiByID[*ing.SemiFinishedID] = ing.SemiFinished
+			continue
+		}
+		if ing.IngredientID == nil {
+			continue
+		}
+
+		if err := addIngredientUsage(u.ingredients, *ing.IngredientID, ing.Ingredient, qty, ing.Unit); err != nil {
+			return fmt.Errorf("tech card %q: %w", techCard.Name, err)
+		}
+		if ing.Ingredient != nil {
+			u.names[*ing.IngredientID] = ing.Ingredient.Name
+		}
+	}
+	return nil
+}
+
+// addProduct добавляет расход товара в штуках
+func (u *stockUsage) addProduct(productID uuid.UUID, name string, quantity float64) {
+	if quantity == 0 {
+		return
+	}
+	current := u.products[productID]
+	unit := current.unit
+	if unit == "" {
+		unit = "шт" // По умолчанию для товаров
+	}
+	u.products[productID] = itemUsage{
+		quantity: current.quantity + quantity,
+		unit:     unit,
+	}
+	if name != "" {
+		u.names[productID] = name
+	}
+}
+
+// resolveSemiFinished распределяет потребность в полуфабрикатах: произведенный остаток (available)
+// списывается в первую очередь, недостающее количество раскладывается на ингредиенты по рецептуре
+func (u *stockUsage) resolveSemiFinished(available func(semiFinishedID uuid.UUID, unit string) (float64, error)) error {
+	for semiFinishedID, demand := range u.semiDemand {
+		semiFinished := u.semiByID[semiFinishedID]
+		avail, err := available(semiFinishedID, demand.unit)
+		if err != nil {
+			return err
+		}
+		fromStock := demand.quantity
+		if fromStock > avail {
+			fromStock = avail
+		}
+		if fromStock > 0 {
+			u.semiFinished[semiFinishedID] = itemUsage{quantity: fromStock, unit: demand.unit}
+		}
+
+		shortage := demand.quantity - fromStock
+		if shortage <= 0.001 {
+			continue
+		}
+		if semiFinished.Quantity <= 0 {
+			return fmt.Errorf("semi-finished product %q has no yield quantity, cannot deduct its ingredients", semiFinished.Name)
+		}
+		batch := shortage / semiFinished.Quantity
+		for _, sfIng := range semiFinished.Ingredients {
+			qty := sfIng.GrossQuantity()
+			if qty == 0 {
+				continue
+			}
+			if err := addIngredientUsage(u.ingredients, sfIng.IngredientID, sfIng.Ingredient, qty*batch, sfIng.Unit); err != nil {
+				return fmt.Errorf("semi-finished product %q: %w", semiFinished.Name, err)
+			}
+			if sfIng.Ingredient != nil {
+				u.names[sfIng.IngredientID] = sfIng.Ingredient.Name
+			}
+		}
+	}
+	u.semiDemand = make(map[uuid.UUID]itemUsage)
+	return nil
+}
+
+// collectStockUsage собирает расход позиций склада на позиции заказа по рецептурам тех-карт.
+// Комбо расходует склад через выбранные компоненты: каждый по своей рецептуре
+func (uc *OrderUseCase) collectStockUsage(ctx context.Context, items []models.OrderItem, semiAvailable func(semiFinishedID uuid.UUID, unit string) (float64, error)) (*stockUsage, error) {
+	usage := newStockUsage()
+	for _, item := range items {
+		if err := uc.addMenuItemUsage(ctx, usage, item.ProductID, item.TechCardID, float64(item.Quantity)); err != nil {
+			return nil, err
+		}
+		for _, component := range item.Components {
+			if err := uc.addMenuItemUsage(ctx, usage, component.ProductID, component.TechCardID, float64(item.Quantity)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := usage.resolveSemiFinished(semiAvailable); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// addMenuItemUsage добавляет расход товара или тех-карты (по рецептуре) на quantity порций
+func (uc *OrderUseCase) addMenuItemUsage(ctx context.Context, usage *stockUsage, productID, techCardID *uuid.UUID, quantity float64) error {
+	if techCardID != nil {
+		techCard, err := uc.warehouseRepo.GetTechCardByID(ctx, *techCardID)
+		if err != nil {
+			return fmt.Errorf("tech card not found: %w", err)
+		}
+		if techCard == nil {
+			return errors.New("tech card not found")
+		}
+		if err := usage.addTechCard(techCard, quantity); err != nil {
+			return err
+		}
+	}
+	if productID != nil {
+		usage.addProduct(*productID, "", quantity)
+	}
+	return nil
+}
+
+// stockBalances положительные остатки позиций на складах заведения по ID ингредиента, товара или полуфабриката
+type stockBalances map[uuid.UUID][]*models.Stock
+
+func (uc *OrderUseCase) loadStockBalances(ctx context.Context, establishmentID uuid.UUID) (stockBalances, error) {
+	stocks, err := uc.warehouseRepo.GetStockForEstablishment(ctx, establishmentID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock: %w", err)
+	}
+	balances := make(stockBalances)
+	for _, st := range stocks {
+		if st.Quantity <= 0 {
+			continue
+		}
+		switch {
+		case st.IngredientID != nil:
+			balances[*st.IngredientID] = append(balances[*st.IngredientID], st)
+		case st.ProductID != nil:
+			balances[*st.ProductID] = append(balances[*st.ProductID], st)
+		case st.SemiFinishedID != nil:
+			balances[*st.SemiFinishedID] = append(balances[*st.SemiFinishedID], st)
+		}
+	}
+	return balances, nil
+}
+
+// available возвращает остаток позиции на всех складах заведения в единице unit
+func (b stockBalances) available(itemID uuid.UUID, unit string) (float64, error) {
+	return b.availableIn(itemID, unit, nil)
+}
+
+// availableIn как available, но переводит остаток другого измерения по пересчетам ингредиента
+func (b stockBalances) availableIn(itemID uuid.UUID, unit string, conversions []models.IngredientUnitConversion) (float64, error) {
+	total := 0.0
+	for _, st := range b[itemID] {
+		factor, err := ingredientStockFactor(unit, st.Unit, conversions)
+		if err != nil {
+			return 0, fmt.Errorf("item %s: %w", itemID, err)
+		}
+		total += st.Quantity / factor
+	}
+	return total, nil
+}
+
+// stockShortages сравнивает расход с остатками и возвращает нехватку, отсортированную по названию
+func (uc *OrderUseCase) stockShortages(ctx context.Context, usage *stockUsage, balances stockBalances) ([]models.StockShortage, error) {
+	var shortages []models.StockShortage
+	check := func(items map[uuid.UUID]itemUsage, itemType string) error {
+		for itemID, u := range items {
+			avail, err := balances.availableIn(itemID, u.unit, u.conversions)
+			if err != nil {
+				return err
+			}
+			if u.quantity <= avail+0.001 {
+				continue
+			}
+			shortages = append(shortages, models.StockShortage{
+				ItemID:    itemID,
+				ItemType:  itemType,
+				Name:      uc.stockItemName(ctx, usage, itemID, itemType),
+				Required:  models.RoundTo2(u.quantity),
+				Available: models.RoundTo2(avail),
+				Unit:      u.unit,
+			})
+		}
+		return nil
+	}
+	if err := check(usage.ingredients, "ingredient"); err != nil {
+		return nil, err
+	}
+	if err := check(usage.products, "product"); err != nil {
+		return nil, err
+	}
+	sort.Slice(shortages, func(i, j int) bool { return shortages[i].Name < shortages[j].Name })
+	return shortages, nil
+}
+
+func (uc *OrderUseCase) stockItemName(ctx context.Context, usage *stockUsage, itemID uuid.UUID, itemType string) string {
+	if name := usage.names[itemID]; name != "" {
+		return name
+	}
+	if itemType == "product" {
+		if product, err := uc.warehouseRepo.GetProductByID(ctx, itemID); err == nil && product != nil {
+			return product.Name
+		}
+	} else if ingredient, err := uc.warehouseRepo.GetIngredientByID(ctx, itemID); err == nil && ingredient != nil {
+		return ingredient.Name
+	}
+	return itemID.String()
+}
+
+// checkStockAvailability проверяет, хватает ли остатков на позиции items, по политике заведения:
+// allow — не проверяет; warn — возвращает нехватку; block — возвращает *InsufficientStockError.
+// Остатки списываются при оплате, поэтому items должны включать все позиции заказа, а не только новые.
+func (uc *OrderUseCase) checkStockAvailability(ctx context.Context, establishmentID uuid.UUID, items []models.OrderItem) ([]models.StockShortage, error) {
+	policy, err := uc.establishmentRepo.GetNegativeStockPolicy(ctx, establishmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get negative stock policy: %w", err)
+	}
+	if policy != models.NegativeStockPolicyWarn && policy != models.NegativeStockPolicyBlock {
+		return nil, nil
+	}
+
+	balances, err := uc.loadStockBalances(ctx, establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := uc.collectStockUsage(ctx, items, balances.available)
+	if err != nil {
+		return nil, err
+	}
+	shortages, err := uc.stockShortages(ctx, usage, balances)
+	if err != nil {
+		return nil, err
+	}
+	if len(shortages) > 0 && policy == models.NegativeStockPolicyBlock {
+		return nil, &InsufficientStockError{Shortages: shortages}
+	}
+	return shortages, nil
+}
+
+// StopListItem позиция меню, которую нельзя приготовить из текущих остатков
+type StopListItem struct {
+	Type      string                 `json:"type"` // product, tech_card, combo
+	ID        uuid.UUID              `json:"id"`
+	Name      string                 `json:"name"`
+	Price     float64                `json:"price"`
+	Shortages []models.StockShortage `json:"shortages"`       // Нехватка на одну порцию
+	Error     string                 `json:"error,omitempty"` // Расход не удалось посчитать (например, единицы рецептуры и остатка несовместимы)
+}
+
+// GetStopList возвращает активные товары, тех-карты и комбо, на одну порцию которых не хватает остатков.
+// Тех-карты проверяются по рецептуре, включая полуфабрикаты и их ингредиенты; комбо — по обязательным
+// группам, в которых нельзя приготовить ни одну позицию. Позиция, расход которой не удалось посчитать,
+// попадает в стоп-лист с описанием ошибки и не прерывает проверку остальных.
+func (uc *OrderUseCase) GetStopList(ctx context.Context, establishmentID uuid.UUID) ([]StopListItem, error) {
+	balances, err := uc.loadStockBalances(ctx, establishmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	stopList := []StopListItem{}
+	add := func(item StopListItem, shortages []models.StockShortage, err error) {
+		if err != nil {
+			item.Error = err.Error()
+		} else if len(shortages) == 0 {
+			return
+		}
+		item.Shortages = shortages
+		stopList = append(stopList, item)
+	}
+
+	products, err := uc.warehouseRepo.GetActiveProducts(ctx, establishmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+	for _, p := range products {
+		// Товар с модификациями продается модификациями, у него самого остатка нет
+		item := StopListItem{Type: "product", ID: p.ID, Name: p.Name, Price: p.Price}
+		byVariants, err := soldByVariants(p, func() (int64, error) {
+			return uc.warehouseRepo.CountProductVariants(ctx, p.ID)
+		})
+		if err != nil {
+			add(item, nil, err)
+			continue
+		}
+		if byVariants {
+			continue
+		}
+		usage := newStockUsage()
+		usage.addProduct(p.ID, p.Name, 1)
+		shortages, err := uc.portionShortages(ctx, usage, balances)
+		add(item, shortages, err)
+	}
+
+	techCards, err := uc.warehouseRepo.GetActiveTechCards(ctx, establishmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tech cards: %w", err)
+	}
+	for _, tc := range techCards {
+		item := StopListItem{Type: "tech_card", ID: tc.ID, Name: tc.Name, Price: tc.Price}
+		usage := newStockUsage()
+		if err := usage.addTechCard(tc, 1); err != nil {
+			add(item, nil, err)
+			continue
+		}
+		shortages, err := uc.portionShortages(ctx, usage, balances)
+		add(item, shortages, err)
+	}
+
+	if uc.combos != nil {
+		active := true
+		combos, err := uc.combos.List(ctx, &repositories.ComboFilter{EstablishmentID: &establishmentID, Active: &active})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get combos: %w", err)
+		}
+		for _, combo := range combos {
+			shortages, err := uc.comboShortages(ctx, combo, balances)
+			add(StopListItem{Type: MenuItemTypeCombo, ID: combo.ID, Name: combo.Name, Price: combo.Price}, shortages, err)
+		}
+	}
+	return stopList, nil
+}
+
+// portionShortages раскладывает потребность в полуфабрикатах и возвращает нехватку на расход usage
+func (uc *OrderUseCase) portionShortages(ctx context.Context, usage *stockUsage, balances stockBalances) ([]models.StockShortage, error) {
+	if err := usage.resolveSemiFinished(balances.available); err != nil {
+		return nil, err
+	}
+	return uc.stockShortages(ctx, usage, balances)
+}
+
+// comboShortages возвращает нехватку комбо: для каждой обязательной группы, в которой нельзя
+// приготовить ни одну позицию, — нехватку позиции по умолчанию (или первой позиции группы).
+// Ошибка возвращается, только если по группе не удалось посчитать ни одну позицию
+func (uc *OrderUseCase) comboShortages(ctx context.Context, combo *models.Combo, balances stockBalances) ([]models.StockShortage, error) {
+	var shortages []models.StockShortage
+	for _, group := range combo.Groups {
+		if !group.Required || len(group.Options) == 0 {
+			continue
+		}
+		var (
+			groupShortages []models.StockShortage
+			firstErr       error
+			available      bool
+		)
+		for i := range group.Options {
+			option := &group.Options[i]
+			usage := newStockUsage()
+			err := uc.addMenuItemUsage(ctx, usage, option.ProductID, option.TechCardID, 1)
+			if option.ProductID != nil && option.Product != nil {
+				usage.names[*option.ProductID] = option.Product.Name
+			}
+			var optionShortages []models.StockShortage
+			if err == nil {
+				optionShortages, err = uc.portionShortages(ctx, usage, balances)
+			}
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("group %q: %w", group.Name, err)
+				}
+				continue
+			}
+			if len(optionShortages) == 0 {
+				available = true
+				break
+			}
+			if groupShortages == nil || option.IsDefault {
+				groupShortages = optionShortages
+			}
+		}
+		if available {
+			continue
+		}
+		if groupShortages == nil {
+			return nil, firstErr
+		}
+		shortages = append(shortages, groupShortages...)
+	}
+	return shortages, nil
+}
@@ -0,0 +1,324 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+type fakeEstablishmentRepository struct {
+	repositories.EstablishmentRepository
+	policy    string
+	threshold float64 // Порог фудкоста, %
+	timezone  string
+}
+
+func (r *fakeEstablishmentRepository) GetNegativeStockPolicy(ctx context.Context, id uuid.UUID) (string, error) {
+	return r.policy, nil
+}
+
+func (r *fakeEstablishmentRepository) GetFoodCostThreshold(ctx context.Context, id uuid.UUID) (float64, error) {
+	return r.threshold, nil
+}
+
+func (r *fakeEstablishmentRepository) GetTimezone(ctx context.Context, id uuid.UUID) (string, error) {
+	return r.timezone, nil
+}
+
+// stockAvailabilityRepository добавляет к складу тех-карты, товары и остатки заведения
+type stockAvailabilityRepository struct {
+	*fakeWarehouseRepository
+	techCards map[uuid.UUID]*models.TechCard
+	products  []*models.Product
+}
+
+func (r *stockAvailabilityRepository) GetTechCardByID(ctx context.Context, id uuid.UUID) (*models.TechCard, error) {
+	return r.techCards[id], nil
+}
+
+func (r *stockAvailabilityRepository) GetProductByID(ctx context.Context, id uuid.UUID) (*models.Product, error) {
+	for _, p := range r.products {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *stockAvailabilityRepository) CountProductVariants(ctx context.Context, parentID uuid.UUID) (int64, error) {
+	var count int64
+	for _, p := range r.products {
+		if p.ParentID != nil && *p.ParentID == parentID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *stockAvailabilityRepository) GetStockForEstablishment(ctx context.Context, establishmentID uuid.UUID, filter *repositories.StockFilter) ([]*models.Stock, error) {
+	stocks := make([]*models.Stock, 0, len(r.stocks))
+	for _, st := range r.stocks {
+		cp := *st
+		stocks = append(stocks, &cp)
+	}
+	return stocks, nil
+}
+
+func (r *stockAvailabilityRepository) GetStockByIngredientID(ctx context.Context, ingredientID uuid.UUID) ([]*models.Stock, error) {
+	var stocks []*models.Stock
+	for _, st := range r.stocks {
+		if st.IngredientID != nil && *st.IngredientID == ingredientID {
+			stocks = append(stocks, st)
+		}
+	}
+	return stocks, nil
+}
+
+func (r *stockAvailabilityRepository) GetStockByProductID(ctx context.Context, productID uuid.UUID) ([]*models.Stock, error) {
+	var stocks []*models.Stock
+	for _, st := range r.stocks {
+		if st.ProductID != nil && *st.ProductID == productID {
+			stocks = append(stocks, st)
+		}
+	}
+	return stocks, nil
+}
+
+func (r *stockAvailabilityRepository) GetActiveTechCards(ctx context.Context, establishmentID uuid.UUID) ([]*models.TechCard, error) {
+	techCards := make([]*models.TechCard, 0, len(r.techCards))
+	for _, tc := range r.techCards {
+		techCards = append(techCards, tc)
+	}
+	return techCards, nil
+}
+
+func (r *stockAvailabilityRepository) GetActiveProducts(ctx context.Context, establishmentID uuid.UUID) ([]*models.Product, error) {
+	return r.products, nil
+}
+
+type fakeComboRepository struct {
+	repositories.ComboRepository
+	combos []*models.Combo
+}
+
+func (r *fakeComboRepository) List(ctx context.Context, filter *repositories.ComboFilter) ([]*models.Combo, error) {
+	return r.combos, nil
+}
+
+func (r *fakeComboRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Combo, error) {
+	for _, c := range r.combos {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func TestOrderUseCase_CheckStockAvailability(t *testing.T) {
+	ctx := context.Background()
+	warehouse := newFakeWarehouseRepository()
+	warehouseID := warehouse.addWarehouse()
+	flourID := warehouse.addIngredient(models.UnitKilogram)
+	warehouse.addStock(warehouseID, flourID, 0.5, 60)
+	// Блин: 200 г муки на порцию
+	pancake := &models.TechCard{ID: uuid.New(), Name: "Блин", Ingredients: []models.TechCardIngredient{
+		{IngredientID: &flourID, Ingredient: warehouse.ingredients[flourID], Quantity: 200, Unit: models.UnitGram},
+	}}
+	repo := &stockAvailabilityRepository{fakeWarehouseRepository: warehouse, techCards: map[uuid.UUID]*models.TechCard{pancake.ID: pancake}}
+	order := func(portions int) []models.OrderItem {
+		return []models.OrderItem{{TechCardID: &pancake.ID, Quantity: portions}}
+	}
+
+	tests := []struct {
+		name      string
+		policy    string
+		portions  int
+		shortages int
+		blocked   bool
+	}{
+		{name: "allow skips the check", policy: models.NegativeStockPolicyAllow, portions: 5},
+		{name: "unknown policy behaves as allow", policy: "", portions: 5},
+		{name: "warn with enough stock", policy: models.NegativeStockPolicyWarn, portions: 2},
+		{name: "warn reports shortage", policy: models.NegativeStockPolicyWarn, portions: 3, shortages: 1},
+		{name: "block with enough stock", policy: models.NegativeStockPolicyBlock, portions: 2},
+		{name: "block rejects shortage", policy: models.NegativeStockPolicyBlock, portions: 3, blocked: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &OrderUseCase{warehouseRepo: repo, establishmentRepo: &fakeEstablishmentRepository{policy: tt.policy}}
+			shortages, err := uc.checkStockAvailability(ctx, uuid.New(), order(tt.portions))
+			if tt.blocked {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, ErrInsufficientStock))
+				var stockErr *InsufficientStockError
+				require.ErrorAs(t, err, &stockErr)
+				require.Len(t, stockErr.Shortages, 1)
+				assert.InDelta(t, 0.6, stockErr.Shortages[0].Required, 1e-9)
+				assert.InDelta(t, 0.5, stockErr.Shortages[0].Available, 1e-9)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, shortages, tt.shortages)
+		})
+	}
+}
+
+func TestOrderUseCase_GetStopList(t *testing.T) {
+	warehouse := newFakeWarehouseRepository()
+	warehouseID := warehouse.addWarehouse()
+	flourID := warehouse.addIngredient(models.UnitKilogram)
+	warehouse.addStock(warehouseID, flourID, 0.15, 60)
+	ingredient := warehouse.ingredients[flourID]
+	pancake := &models.TechCard{ID: uuid.New(), Name: "Блин", Price: 120, Ingredients: []models.TechCardIngredient{
+		{IngredientID: &flourID, Ingredient: ingredient, Quantity: 200, Unit: models.UnitGram},
+	}}
+	crepe := &models.TechCard{ID: uuid.New(), Name: "Креп", Ingredients: []models.TechCardIngredient{
+		{IngredientID: &flourID, Ingredient: ingredient, Quantity: 100, Unit: models.UnitGram},
+	}}
+	repo := &stockAvailabilityRepository{fakeWarehouseRepository: warehouse, techCards: map[uuid.UUID]*models.TechCard{pancake.ID: pancake, crepe.ID: crepe}}
+	uc := &OrderUseCase{warehouseRepo: repo}
+
+	stopList, err := uc.GetStopList(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.Len(t, stopList, 1)
+	assert.Equal(t, pancake.ID, stopList[0].ID)
+	assert.Equal(t, "tech_card", stopList[0].Type)
+	require.Len(t, stopList[0].Shortages, 1)
+	assert.InDelta(t, 0.2, stopList[0].Shortages[0].Required, 1e-9)
+}
+
+func TestOrderUseCase_GetStopList_ProductsAndCombos(t *testing.T) {
+	warehouse := newFakeWarehouseRepository()
+	warehouseID := warehouse.addWarehouse()
+	flourID := warehouse.addIngredient(models.UnitKilogram)
+	warehouse.addStock(warehouseID, flourID, 0.15, 60)
+	ingredient := warehouse.ingredients[flourID]
+	pancake := &models.TechCard{ID: uuid.New(), Name: "Блин", Ingredients: []models.TechCardIngredient{
+		{IngredientID: &flourID, Ingredient: ingredient, Quantity: 200, Unit: models.UnitGram},
+	}}
+	crepe := &models.TechCard{ID: uuid.New(), Name: "Креп", Ingredients: []models.TechCardIngredient{
+		{IngredientID: &flourID, Ingredient: ingredient, Quantity: 100, Unit: models.UnitGram},
+	}}
+	// Мука в рецептуре в штуках без пересчета в килограммы: расход не посчитать
+	broken := &models.TechCard{ID: uuid.New(), Name: "Оладья", Ingredients: []models.TechCardIngredient{
+		{IngredientID: &flourID, Ingredient: ingredient, Quantity: 2, Unit: models.UnitPiece},
+	}}
+
+	// Морс есть на складе, колы нет; у лимонада модификации — сам он не продается и в стоп-лист не попадает
+	juice := &models.Product{ID: uuid.New(), Name: "Морс", Price: 90}
+	cola := &models.Product{ID: uuid.New(), Name: "Кола", Price: 100}
+	lemonade := &models.Product{ID: uuid.New(), Name: "Лимонад", HasModifications: true}
+	lemonadeLarge := &models.Product{ID: uuid.New(), Name: "Лимонад 0.5", ParentID: &lemonade.ID, Price: 150}
+	for _, st := range []*models.Stock{
+		{ID: uuid.New(), WarehouseID: warehouseID, ProductID: &juice.ID, Quantity: 5, Unit: models.UnitPiece},
+		{ID: uuid.New(), WarehouseID: warehouseID, ProductID: &lemonadeLarge.ID, Quantity: 3, Unit: models.UnitPiece},
+	} {
+		warehouse.stocks[st.ID] = st
+	}
+
+	option := func(product *models.Product, techCardID *uuid.UUID) models.ComboOption {
+		if product != nil {
+			return models.ComboOption{ID: uuid.New(), ProductID: &product.ID, Product: product}
+		}
+		return models.ComboOption{ID: uuid.New(), TechCardID: techCardID}
+	}
+	// Блин не приготовить, но в группе есть креп; колы нет, а другой позиции в обязательной группе нет
+	lunch := &models.Combo{ID: uuid.New(), Name: "Обед", Price: 250, Groups: []models.ComboGroup{
+		{Name: "Блюдо", Required: true, Options: []models.ComboOption{option(nil, &pancake.ID), option(nil, &crepe.ID)}},
+		{Name: "Напиток", Required: true, Options: []models.ComboOption{option(cola, nil)}},
+	}}
+	// Необязательную группу можно пропустить, обязательная собирается из того, что есть
+	snack := &models.Combo{ID: uuid.New(), Name: "Перекус", Price: 150, Groups: []models.ComboGroup{
+		{Name: "Блюдо", Required: true, Options: []models.ComboOption{option(nil, &crepe.ID)}},
+		{Name: "Напиток", Required: true, Options: []models.ComboOption{option(cola, nil), option(juice, nil)}},
+		{Name: "Десерт", Options: []models.ComboOption{option(nil, &pancake.ID)}},
+	}}
+	brokenCombo := &models.Combo{ID: uuid.New(), Name: "Завтрак", Price: 200, Groups: []models.ComboGroup{
+		{Name: "Блюдо", Required: true, Options: []models.ComboOption{option(nil, &broken.ID)}},
+	}}
+
+	repo := &stockAvailabilityRepository{
+		fakeWarehouseRepository: warehouse,
+		techCards:               map[uuid.UUID]*models.TechCard{pancake.ID: pancake, crepe.ID: crepe, broken.ID: broken},
+		products:                []*models.Product{juice, cola, lemonade, lemonadeLarge},
+	}
+	combos := NewComboUseCase(&fakeComboRepository{combos: []*models.Combo{lunch, snack, brokenCombo}}, nil, nil, nil)
+	uc := &OrderUseCase{warehouseRepo: repo, combos: combos}
+
+	stopList, err := uc.GetStopList(context.Background(), uuid.New())
+	require.NoError(t, err)
+	byID := make(map[uuid.UUID]StopListItem)
+	for _, item := range stopList {
+		byID[item.ID] = item
+	}
+	assert.Len(t, stopList, 5)
+	assert.NotContains(t, byID, juice.ID)
+	assert.NotContains(t, byID, lemonade.ID)
+	assert.NotContains(t, byID, lemonadeLarge.ID)
+	assert.NotContains(t, byID, crepe.ID)
+	assert.NotContains(t, byID, snack.ID)
+
+	require.Contains(t, byID, cola.ID)
+	assert.Equal(t, "product", byID[cola.ID].Type)
+	require.Len(t, byID[cola.ID].Shortages, 1)
+	assert.Equal(t, "Кола", byID[cola.ID].Shortages[0].Name)
+
+	require.Contains(t, byID, pancake.ID)
+	require.Contains(t, byID, broken.ID)
+	assert.NotEmpty(t, byID[broken.ID].Error)
+	assert.Empty(t, byID[broken.ID].Shortages)
+
+	require.Contains(t, byID, lunch.ID)
+	assert.Equal(t, MenuItemTypeCombo, byID[lunch.ID].Type)
+	assert.InDelta(t, 250, byID[lunch.ID].Price, 1e-9)
+	require.Len(t, byID[lunch.ID].Shortages, 1)
+	assert.Equal(t, cola.ID, byID[lunch.ID].Shortages[0].ItemID)
+
+	require.Contains(t, byID, brokenCombo.ID)
+	assert.Contains(t, byID[brokenCombo.ID].Error, `group "Блюдо"`)
+}
+
+func TestOrderUseCase_Stock_IngredientUnitConversion(t *testing.T) {
+	ctx := context.Background()
+	warehouse := newFakeWarehouseRepository()
+	warehouseID := warehouse.addWarehouse()
+	// Яйца учитываются в штуках, а остаток ведется в килограммах: 1 шт = 0.05 кг
+	eggID := warehouse.addIngredient(models.UnitPiece)
+	egg := warehouse.ingredients[eggID]
+	egg.UnitConversions = []models.IngredientUnitConversion{{Unit: models.UnitPiece, Factor: 0.05, TargetUnit: models.UnitKilogram}}
+	warehouse.addStock(warehouseID, eggID, 0.25, 200)
+	omelette := &models.TechCard{ID: uuid.New(), Name: "Омлет", Ingredients: []models.TechCardIngredient{
+		{IngredientID: &eggID, Ingredient: egg, Quantity: 2, Unit: models.UnitPiece},
+	}}
+	repo := &stockAvailabilityRepository{fakeWarehouseRepository: warehouse, techCards: map[uuid.UUID]*models.TechCard{omelette.ID: omelette}}
+	uc := &OrderUseCase{warehouseRepo: repo, establishmentRepo: &fakeEstablishmentRepository{policy: models.NegativeStockPolicyBlock}}
+
+	// 0.25 кг — это 5 яиц: на три омлета не хватает
+	_, err := uc.checkStockAvailability(ctx, uuid.New(), []models.OrderItem{{TechCardID: &omelette.ID, Quantity: 3}})
+	var stockErr *InsufficientStockError
+	require.ErrorAs(t, err, &stockErr)
+	require.Len(t, stockErr.Shortages, 1)
+	assert.InDelta(t, 6, stockErr.Shortages[0].Required, 1e-9)
+	assert.InDelta(t, 5, stockErr.Shortages[0].Available, 1e-9)
+	assert.Equal(t, models.UnitPiece, stockErr.Shortages[0].Unit)
+
+	items := []models.OrderItem{{TechCardID: &omelette.ID, Quantity: 2}}
+	shortages, err := uc.checkStockAvailability(ctx, uuid.New(), items)
+	require.NoError(t, err)
+	assert.Empty(t, shortages)
+
+	// Четыре яйца списываются с остатка как 0.2 кг
+	order := &models.Order{ID: uuid.New(), EstablishmentID: uuid.New(), Items: items}
+	require.NoError(t, uc.deductTechCardIngredientsFromStock(ctx, order))
+	assert.InDelta(t, 0.05, warehouse.stock(warehouseID, eggID).Quantity, 1e-9)
+	require.Len(t, warehouse.ledger, 1)
+	assert.InDelta(t, -0.2, warehouse.ledger[0].Quantity, 1e-9)
+	assert.InDelta(t, -40, warehouse.ledger[0].Amount, 1e-9)
+}
@@ -0,0 +1,257 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// Статусы партий в отчете по срокам годности
+const (
+	ExpiryStatusExpired  = "expired"  // Срок годности истек
+	ExpiryStatusExpiring = "expiring" // Истекает в пределах горизонта отчета
+)
+
+// ExpiredWriteOffReason причина списания просроченных остатков
+const ExpiredWriteOffReason = "Истек срок годности"
+
+// ExpiryReportRow партия с истекающим или истекшим сроком годности
+type ExpiryReportRow struct {
+	LotID             uuid.UUID  `json:"lot_id"`
+	WarehouseID       uuid.UUID  `json:"warehouse_id"`
+	WarehouseName     string     `json:"warehouse_name"`
+	IngredientID      *uuid.UUID `json:"ingredient_id,omitempty"`
+	ProductID         *uuid.UUID `json:"product_id,omitempty"`
+	SemiFinishedID    *uuid.UUID `json:"semi_finished_id,omitempty"`
+	ItemName          string     `json:"item_name"`
+	Unit              string     `json:"unit"`
+	RemainingQuantity float64    `json:"remaining_quantity"`
+	UnitCost          float64    `json:"unit_cost"`
+	Value             float64    `json:"value"` // Себестоимость остатка партии
+	ReceivedAt        time.Time  `json:"received_at"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	DaysLeft          int        `json:"days_left"` // Отрицательное — сколько дней назад истек срок
+	Status            string     `json:"status"`    // expired, expiring
+}
+
+// ExpiryReport отчет по срокам годности остатков
+type ExpiryReport struct {
+	AsOf          time.Time         `json:"as_of"`
+	Days          int               `json:"days"` // Горизонт отчета, дней
+	Rows          []ExpiryReportRow `json:"rows"`
+	ExpiredValue  float64           `json:"expired_value"`  // Себестоимость просроченных остатков
+	ExpiringValue float64           `json:"expiring_value"` // Себестоимость остатков, истекающих в горизонте
+}
+
+// GetExpiryReport возвращает открытые партии, срок годности которых истек или истекает в ближайшие days дней
+func (uc *WarehouseUseCase) GetExpiryReport(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID, days int, now time.Time) (*ExpiryReport, error) {
+	if days < 0 {
+		return nil, errors.New("days must not be negative")
+	}
+	horizon := now.AddDate(0, 0, days)
+	lots, err := uc.repo.GetStockLots(ctx, establishmentID, &repositories.StockLotFilter{
+		WarehouseID:   warehouseID,
+		OnlyOpen:      true,
+		ExpiresBefore: &horizon,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock lots: %w", err)
+	}
+
+	report := &ExpiryReport{AsOf: now, Days: days, Rows: make([]ExpiryReportRow, 0, len(lots))}
+	for _, lot := range lots {
+		row := ExpiryReportRow{
+			LotID:             lot.ID,
+			WarehouseID:       lot.WarehouseID,
+			IngredientID:      lot.IngredientID,
+			ProductID:         lot.ProductID,
+			SemiFinishedID:    lot.SemiFinishedID,
+			ItemName:          stockLotItemName(lot),
+			Unit:              lot.Unit,
+			RemainingQuantity: lot.RemainingQuantity,
+			UnitCost:          lot.UnitCost,
+			Value:             models.RoundTo2(lot.RemainingQuantity * lot.UnitCost),
+			ReceivedAt:        lot.ReceivedAt,
+			ExpiresAt:         *lot.ExpiresAt,
+			DaysLeft:          int(math.Floor(lot.ExpiresAt.Sub(now).Hours() / 24)),
+			Status:            ExpiryStatusExpiring,
+		}
+		if lot.Warehouse != nil {
+			row.WarehouseName = lot.Warehouse.Name
+		}
+		if lot.IsExpired(now) {
+			row.Status = ExpiryStatusExpired
+			report.ExpiredValue += row.Value
+		} else {
+			report.ExpiringValue += row.Value
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	report.ExpiredValue = models.RoundTo2(report.ExpiredValue)
+	report.ExpiringValue = models.RoundTo2(report.ExpiringValue)
+	return report, nil
+}
+
+// SuggestExpiredWriteOffs формирует (без проведения) списания просроченных остатков — по одному на склад.
+// Количество позиции — сумма остатков просроченных партий, но не больше остатка на складе.
+// Полуфабрикаты в списания не попадают: документ списания их не поддерживает.
+func (uc *WarehouseUseCase) SuggestExpiredWriteOffs(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID, now time.Time) ([]*models.WriteOff, error) {
+	lots, err := uc.repo.GetStockLots(ctx, establishmentID, &repositories.StockLotFilter{
+		WarehouseID:   warehouseID,
+		OnlyOpen:      true,
+		ExpiresBefore: &now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock lots: %w", err)
+	}
+
+	type itemKey struct {
+		warehouseID uuid.UUID
+		itemID      uuid.UUID
+	}
+	type expiredItem struct {
+		lots     []*models.StockLot
+		quantity float64
+		cost     float64
+	}
+	items := make(map[itemKey]*expiredItem)
+	var order []itemKey
+	for _, lot := range lots {
+		var itemID uuid.UUID
+		switch {
+		case lot.IngredientID != nil:
+			itemID = *lot.IngredientID
+		case lot.ProductID != nil:
+			itemID = *lot.ProductID
+		default:
+			continue
+		}
+		key := itemKey{warehouseID: lot.WarehouseID, itemID: itemID}
+		it, ok := items[key]
+		if !ok {
+			it = &expiredItem{}
+			items[key] = it
+			order = append(order, key)
+		}
+		it.lots = append(it.lots, lot)
+		it.quantity += lot.RemainingQuantity
+		it.cost += lot.RemainingQuantity * lot.UnitCost
+	}
+
+	writeOffs := make(map[uuid.UUID]*models.WriteOff)
+	var result []*models.WriteOff
+	for _, key := range order {
+		it := items[key]
+		first := it.lots[0]
+		st, _, _, err := uc.resolveStock(ctx, key.warehouseID, first.IngredientID, first.ProductID, first.Unit)
+		if err != nil {
+			return nil, err
+		}
+		quantity := it.quantity
+		if st == nil || st.Quantity <= 0 {
+			continue
+		}
+		if quantity > st.Quantity {
+			quantity = st.Quantity
+		}
+		quantity = models.RoundTo2(quantity)
+		if quantity <= 0 {
+			continue
+		}
+
+		wo, ok := writeOffs[key.warehouseID]
+		if !ok {
+			wo = &models.WriteOff{
+				WarehouseID:      key.warehouseID,
+				Warehouse:        first.Warehouse,
+				WriteOffDateTime: now,
+				Reason:           ExpiredWriteOffReason,
+			}
+			writeOffs[key.warehouseID] = wo
+			result = append(result, wo)
+		}
+
+		unitCost := models.RoundTo2(it.cost / it.quantity)
+		wo.Items = append(wo.Items, models.WriteOffItem{
+			IngredientID: first.IngredientID,
+			Ingredient:   first.Ingredient,
+			ProductID:    first.ProductID,
+			Product:      first.Product,
+			Quantity:     quantity,
+			Unit:         st.Unit,
+			Details:      expiredLotsDetails(it.lots),
+			PricePerUnit: unitCost,
+			TotalAmount:  models.RoundTo2(quantity * unitCost),
+		})
+		wo.TotalAmount = models.RoundTo2(wo.TotalAmount + quantity*unitCost)
+	}
+
+	for _, wo := range result {
+		sort.SliceStable(wo.Items, func(i, j int) bool {
+			return writeOffItemName(&wo.Items[i]) < writeOffItemName(&wo.Items[j])
+		})
+	}
+	return result, nil
+}
+
+// WriteOffExpired проводит предложенные списания просроченных остатков.
+// Партии расходуются по FEFO, поэтому списание забирает именно просроченные партии.
+func (uc *WarehouseUseCase) WriteOffExpired(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID, comment string, now time.Time) ([]*models.WriteOff, error) {
+	writeOffs, err := uc.SuggestExpiredWriteOffs(ctx, establishmentID, warehouseID, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, wo := range writeOffs {
+		wo.Comment = comment
+		wo.Warehouse = nil
+		for i := range wo.Items {
+			wo.Items[i].Ingredient = nil
+			wo.Items[i].Product = nil
+		}
+		if err := uc.CreateWriteOff(ctx, wo, establishmentID); err != nil {
+			return nil, err
+		}
+	}
+	return writeOffs, nil
+}
+
+// expiredLotsDetails описывает просроченные партии позиции для поля Details списания
+func expiredLotsDetails(lots []*models.StockLot) string {
+	parts := make([]string, 0, len(lots))
+	for _, lot := range lots {
+		parts = append(parts, fmt.Sprintf("годен до %s — %s %s",
+			lot.ExpiresAt.Format("02.01.2006"), formatAmount(lot.RemainingQuantity), lot.Unit))
+	}
+	return "Партии: " + strings.Join(parts, "; ")
+}
+
+func stockLotItemName(lot *models.StockLot) string {
+	switch {
+	case lot.Ingredient != nil:
+		return lot.Ingredient.Name
+	case lot.Product != nil:
+		return lot.Product.Name
+	case lot.SemiFinished != nil:
+		return lot.SemiFinished.Name
+	}
+	return ""
+}
+
+func writeOffItemName(item *models.WriteOffItem) string {
+	if item.Ingredient != nil {
+		return item.Ingredient.Name
+	}
+	if item.Product != nil {
+		return item.Product.Name
+	}
+	return ""
+}
@@ -0,0 +1,257 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// stockLedgerSource документ, по которому изменяется остаток
+type stockLedgerSource struct {
+	Type string    // models.StockLedger*
+	ID   uuid.UUID // ID документа (uuid.Nil — без документа)
+	At   time.Time // Момент движения
+}
+
+// postStockLedger записывает изменение остатка st в журнал.
+// st — остаток уже после изменения, quantity — изменение со знаком в единице остатка,
+// cost — себестоимость движения без знака (если 0, движение оценивается по цене остатка).
+func postStockLedger(ctx context.Context, repo repositories.WarehouseRepository, st *models.Stock, quantity, cost float64, src stockLedgerSource) error {
+	entry := newStockLedgerEntry(st, quantity, cost, src)
+	if entry == nil {
+		return nil
+	}
+	if err := repo.CreateStockLedgerEntry(ctx, entry); err != nil {
+		return fmt.Errorf("failed to record stock ledger entry: %w", err)
+	}
+	return nil
+}
+
+// newStockLedgerEntry собирает запись журнала без сохранения (nil при нулевом изменении)
+func newStockLedgerEntry(st *models.Stock, quantity, cost float64, src stockLedgerSource) *models.StockLedgerEntry {
+	if quantity == 0 {
+		return nil
+	}
+	if cost == 0 {
+		cost = math.Abs(quantity) * st.PricePerUnit
+	}
+	amount := cost
+	if quantity < 0 {
+		amount = -cost
+	}
+	at := src.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	entry := &models.StockLedgerEntry{
+		WarehouseID:    st.WarehouseID,
+		StockID:        st.ID,
+		IngredientID:   st.IngredientID,
+		ProductID:      st.ProductID,
+		SemiFinishedID: st.SemiFinishedID,
+		MovementType:   src.Type,
+		Quantity:       quantity,
+		Unit:           st.Unit,
+		UnitCost:       cost / math.Abs(quantity),
+		Amount:         amount,
+		BalanceAfter:   st.Quantity,
+		OccurredAt:     at,
+	}
+	if src.ID != uuid.Nil {
+		sourceID := src.ID
+		entry.SourceID = &sourceID
+	}
+	return entry
+}
+
+// ——— Ledger reports ———
+
+// GetStockAsOf восстанавливает остатки складов на момент asOf по журналу
+func (uc *WarehouseUseCase) GetStockAsOf(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID, asOf time.Time) ([]*models.Stock, error) {
+	return stockAsOf(ctx, uc.repo, establishmentID, warehouseID, asOf)
+}
+
+// stockAsOf собирает остатки на момент asOf из журнала.
+// Цена за единицу — средняя себестоимость остатка на этот момент.
+func stockAsOf(ctx context.Context, repo repositories.WarehouseRepository, establishmentID uuid.UUID, warehouseID *uuid.UUID, asOf time.Time) ([]*models.Stock, error) {
+	stocks, err := repo.GetStockForEstablishment(ctx, establishmentID, &repositories.StockFilter{
+		EstablishmentID: &establishmentID,
+		WarehouseID:     warehouseID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	balances, err := repo.GetStockLedgerBalances(ctx, establishmentID, &repositories.StockLedgerFilter{
+		WarehouseID: warehouseID,
+		EndDate:     &asOf,
+	})
+	if err != nil {
+		return nil, err
+	}
+	byStock := make(map[uuid.UUID]repositories.StockLedgerBalance, len(balances))
+	for _, b := range balances {
+		byStock[b.StockID] = b
+	}
+
+	result := make([]*models.Stock, 0, len(stocks))
+	for _, st := range stocks {
+		snapshot := *st
+		snapshot.Quantity = 0
+		snapshot.PricePerUnit = 0
+		if b, ok := byStock[st.ID]; ok {
+			snapshot.Quantity = models.RoundTo2(b.Quantity)
+			if b.Quantity > 0 {
+				snapshot.PricePerUnit = models.RoundTo2(b.Amount / b.Quantity)
+			}
+		}
+		result = append(result, &snapshot)
+	}
+	return result, nil
+}
+
+// StockMovementReportRow движение позиции склада за период
+type StockMovementReportRow struct {
+	StockID        uuid.UUID          `json:"stock_id"`
+	WarehouseID    uuid.UUID          `json:"warehouse_id"`
+	WarehouseName  string             `json:"warehouse_name"`
+	IngredientID   *uuid.UUID         `json:"ingredient_id,omitempty"`
+	ProductID      *uuid.UUID         `json:"product_id,omitempty"`
+	SemiFinishedID *uuid.UUID         `json:"semi_finished_id,omitempty"`
+	Name           string             `json:"name"`
+	Unit           string             `json:"unit"`
+	Opening        float64            `json:"opening"`        // Остаток на начало периода
+	OpeningAmount  float64            `json:"opening_amount"` // Себестоимость остатка на начало
+	Incoming       float64            `json:"incoming"`       // Приход за период
+	Outgoing       float64            `json:"outgoing"`       // Расход за период (положительное число)
+	Closing        float64            `json:"closing"`        // Остаток на конец периода
+	ClosingAmount  float64            `json:"closing_amount"`
+	ByType         map[string]float64 `json:"by_type"` // Изменение по типам движений
+}
+
+// GetStockMovementReport возвращает по каждой позиции склада остаток на начало, приход, расход
+// по типам движений и остаток на конец периода. Все значения берутся из журнала остатков.
+func (uc *WarehouseUseCase) GetStockMovementReport(ctx context.Context, establishmentID uuid.UUID, filter repositories.StockLedgerFilter) ([]*StockMovementReportRow, error) {
+	if filter.EndDate == nil {
+		now := time.Now()
+		filter.EndDate = &now
+	}
+
+	rows := make(map[uuid.UUID]*StockMovementReportRow)
+	row := func(stockID, warehouseID uuid.UUID, ingredientID, productID, semiFinishedID *uuid.UUID, unit string) *StockMovementReportRow {
+		r, ok := rows[stockID]
+		if !ok {
+			r = &StockMovementReportRow{
+				StockID:        stockID,
+				WarehouseID:    warehouseID,
+				IngredientID:   ingredientID,
+				ProductID:      productID,
+				SemiFinishedID: semiFinishedID,
+				Unit:           unit,
+				ByType:         map[string]float64{},
+			}
+			rows[stockID] = r
+		}
+		return r
+	}
+
+	// Остаток на начало — сумма записей до начала периода
+	if filter.StartDate != nil {
+		before := filter.StartDate.Add(-time.Nanosecond)
+		openingFilter := filter
+		openingFilter.StartDate = nil
+		openingFilter.EndDate = &before
+		openingFilter.MovementType = nil
+		balances, err := uc.repo.GetStockLedgerBalances(ctx, establishmentID, &openingFilter)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range balances {
+			r := row(b.StockID, b.WarehouseID, b.IngredientID, b.ProductID, b.SemiFinishedID, b.Unit)
+			r.Opening = b.Quantity
+			r.OpeningAmount = b.Amount
+		}
+	}
+
+	entries, err := uc.repo.GetStockLedger(ctx, establishmentID, &filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		r := row(e.StockID, e.WarehouseID, e.IngredientID, e.ProductID, e.SemiFinishedID, e.Unit)
+		if r.Name == "" {
+			r.Name = stockLedgerItemName(e)
+		}
+		if r.WarehouseName == "" && e.Warehouse != nil {
+			r.WarehouseName = e.Warehouse.Name
+		}
+		if e.Quantity > 0 {
+			r.Incoming += e.Quantity
+		} else {
+			r.Outgoing -= e.Quantity
+		}
+		r.ByType[e.MovementType] += e.Quantity
+		r.ClosingAmount += e.Amount
+	}
+
+	// Названия позиций без движений за период берем из текущих остатков
+	stocks, err := uc.repo.GetStockForEstablishment(ctx, establishmentID, &repositories.StockFilter{
+		EstablishmentID: &establishmentID,
+		WarehouseID:     filter.WarehouseID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range stocks {
+		r, ok := rows[st.ID]
+		if !ok {
+			continue
+		}
+		if r.Name == "" {
+			_, r.Name = stockItem(st)
+		}
+		if r.WarehouseName == "" && st.Warehouse != nil {
+			r.WarehouseName = st.Warehouse.Name
+		}
+	}
+
+	result := make([]*StockMovementReportRow, 0, len(rows))
+	for _, r := range rows {
+		r.Opening = models.RoundTo2(r.Opening)
+		r.OpeningAmount = models.RoundTo2(r.OpeningAmount)
+		r.Incoming = models.RoundTo2(r.Incoming)
+		r.Outgoing = models.RoundTo2(r.Outgoing)
+		r.Closing = models.RoundTo2(r.Opening + r.Incoming - r.Outgoing)
+		r.ClosingAmount = models.RoundTo2(r.OpeningAmount + r.ClosingAmount)
+		for t, q := range r.ByType {
+			r.ByType[t] = models.RoundTo2(q)
+		}
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].WarehouseName != result[j].WarehouseName {
+			return result[i].WarehouseName < result[j].WarehouseName
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+func stockLedgerItemName(e *models.StockLedgerEntry) string {
+	switch {
+	case e.Ingredient != nil:
+		return e.Ingredient.Name
+	case e.Product != nil:
+		return e.Product.Name
+	case e.SemiFinished != nil:
+		return e.SemiFinished.Name
+	}
+	return ""
+}
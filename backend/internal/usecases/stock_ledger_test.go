@@ -0,0 +1,50 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+func TestPostStockLedger(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeWarehouseRepository()
+	warehouseID := repo.addWarehouse()
+	st := repo.addStock(warehouseID, repo.addIngredient(models.UnitKilogram), 7, 100)
+
+	// Нулевое изменение в журнал не попадает
+	require.NoError(t, postStockLedger(ctx, repo, st, 0, 0, stockLedgerSource{Type: models.StockLedgerSale}))
+	assert.Empty(t, repo.ledger)
+
+	// Расход без себестоимости оценивается по цене остатка, сумма — со знаком движения
+	orderID := uuid.New()
+	require.NoError(t, postStockLedger(ctx, repo, st, -3, 0, stockLedgerSource{Type: models.StockLedgerSale, ID: orderID}))
+	// Приход по себестоимости партии на дату документа
+	at := time.Now().AddDate(0, 0, -2)
+	require.NoError(t, postStockLedger(ctx, repo, st, 2, 180, stockLedgerSource{Type: models.StockLedgerSupply, At: at}))
+
+	require.Len(t, repo.ledger, 2)
+	sale := repo.ledger[0]
+	assert.Equal(t, st.ID, sale.StockID)
+	assert.Equal(t, warehouseID, sale.WarehouseID)
+	assert.Equal(t, st.IngredientID, sale.IngredientID)
+	assert.InDelta(t, -3, sale.Quantity, 1e-9)
+	assert.InDelta(t, 100, sale.UnitCost, 1e-9)
+	assert.InDelta(t, -300, sale.Amount, 1e-9)
+	assert.InDelta(t, 7, sale.BalanceAfter, 1e-9)
+	require.NotNil(t, sale.SourceID)
+	assert.Equal(t, orderID, *sale.SourceID)
+	assert.WithinDuration(t, time.Now(), sale.OccurredAt, time.Minute)
+
+	supply := repo.ledger[1]
+	assert.InDelta(t, 90, supply.UnitCost, 1e-9)
+	assert.InDelta(t, 180, supply.Amount, 1e-9)
+	assert.Nil(t, supply.SourceID)
+	assert.Equal(t, at, supply.OccurredAt)
+}
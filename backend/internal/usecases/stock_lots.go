@@ -0,0 +1,150 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// stockDocument описывает документ, который расходует партии
+type stockDocument struct {
+	Type string    // models.StockConsumption*
+	ID   uuid.UUID // ID документа (заказ, списание, перемещение, производство)
+	At   time.Time // Момент расхода
+}
+
+// consumeStockLots расходует quantity позиции остатка stock с партий склада по FEFO (см. GetOpenStockLots)
+// и возвращает фактическую себестоимость израсходованного количества.
+// Если партий не хватает (остаток заведен до учета партий или уходит в минус),
+// недостающее количество оценивается по текущей цене остатка stock.PricePerUnit.
+func consumeStockLots(ctx context.Context, repo repositories.WarehouseRepository, stock *models.Stock, quantity float64, doc stockDocument) (float64, error) {
+	if quantity <= 0 {
+		return 0, nil
+	}
+
+	lots, err := repo.GetOpenStockLots(ctx, stock.WarehouseID, stock.IngredientID, stock.ProductID, stock.SemiFinishedID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stock lots: %w", err)
+	}
+
+	plan := planStockLotConsumption(lots, stock, quantity, doc)
+	for _, lot := range plan.Lots {
+		if err := repo.UpdateStockLot(ctx, lot); err != nil {
+			return 0, fmt.Errorf("failed to update stock lot: %w", err)
+		}
+	}
+	for _, c := range plan.Consumptions {
+		if err := repo.CreateStockLotConsumption(ctx, c); err != nil {
+			return 0, fmt.Errorf("failed to record stock lot consumption: %w", err)
+		}
+	}
+
+	return plan.Cost, nil
+}
+
+// nearestStockLotExpiry возвращает ближайший срок годности открытых партий остатка stock (nil — срок не отслеживается)
+func nearestStockLotExpiry(ctx context.Context, repo repositories.WarehouseRepository, stock *models.Stock) (*time.Time, error) {
+	lots, err := repo.GetOpenStockLots(ctx, stock.WarehouseID, stock.IngredientID, stock.ProductID, stock.SemiFinishedID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock lots: %w", err)
+	}
+	// Партии упорядочены по FEFO — первая партия со сроком и есть ближайшая
+	for _, lot := range lots {
+		if lot.ExpiresAt != nil {
+			return lot.ExpiresAt, nil
+		}
+	}
+	return nil, nil
+}
+
+// stockLotPlan расход партий, рассчитанный без записи в базу
+type stockLotPlan struct {
+	Lots         []*models.StockLot            // Партии с уменьшенным остатком
+	Consumptions []*models.StockLotConsumption // Записи расхода
+	Cost         float64                       // Себестоимость израсходованного количества
+}
+
+// planStockLotConsumption распределяет quantity по открытым партиям lots в переданном порядке (FEFO).
+// Партии изменяются на месте; непокрытое партиями количество оценивается по цене остатка.
+func planStockLotConsumption(lots []*models.StockLot, stock *models.Stock, quantity float64, doc stockDocument) *stockLotPlan {
+	plan := &stockLotPlan{}
+	if quantity <= 0 {
+		return plan
+	}
+
+	remaining := quantity
+	totalCost := 0.0
+	for _, lot := range lots {
+		if remaining <= 0 {
+			break
+		}
+		take := lot.RemainingQuantity
+		if take > remaining {
+			take = remaining
+		}
+		lot.RemainingQuantity -= take
+		remaining -= take
+		plan.Lots = append(plan.Lots, lot)
+
+		lotID := lot.ID
+		cost := models.RoundTo2(take * lot.UnitCost)
+		totalCost += cost
+		plan.Consumptions = append(plan.Consumptions, &models.StockLotConsumption{
+			LotID:          &lotID,
+			WarehouseID:    stock.WarehouseID,
+			IngredientID:   stock.IngredientID,
+			ProductID:      stock.ProductID,
+			SemiFinishedID: stock.SemiFinishedID,
+			DocumentType:   doc.Type,
+			DocumentID:     doc.ID,
+			Quantity:       take,
+			UnitCost:       lot.UnitCost,
+			TotalCost:      cost,
+			ConsumedAt:     doc.At,
+		})
+	}
+
+	// Количество, не покрытое партиями, оцениваем по цене остатка
+	if remaining > 0.001 {
+		cost := models.RoundTo2(remaining * stock.PricePerUnit)
+		totalCost += cost
+		plan.Consumptions = append(plan.Consumptions, &models.StockLotConsumption{
+			WarehouseID:    stock.WarehouseID,
+			IngredientID:   stock.IngredientID,
+			ProductID:      stock.ProductID,
+			SemiFinishedID: stock.SemiFinishedID,
+			DocumentType:   doc.Type,
+			DocumentID:     doc.ID,
+			Quantity:       remaining,
+			UnitCost:       stock.PricePerUnit,
+			TotalCost:      cost,
+			ConsumedAt:     doc.At,
+		})
+	}
+
+	plan.Cost = models.RoundTo2(totalCost)
+	return plan
+}
+
+// stockUnitFactor возвращает множитель для перевода количества из unit в единицу остатка stockUnit.
+// Пустые и совпадающие единицы считаются одинаковыми.
+func stockUnitFactor(unit, stockUnit string) (float64, error) {
+	if unit == "" || stockUnit == "" || models.NormalizeUnit(unit) == models.NormalizeUnit(stockUnit) {
+		return 1, nil
+	}
+	return models.ConvertUnit(1, unit, stockUnit)
+}
+
+// ingredientStockFactor как stockUnitFactor, но учитывает пересчеты ингредиента между измерениями:
+// расход в штуках списывается с остатка в килограммах (1 шт яйца = 0.05 кг)
+func ingredientStockFactor(unit, stockUnit string, conversions []models.IngredientUnitConversion) (float64, error) {
+	if unit == "" || stockUnit == "" || models.NormalizeUnit(unit) == models.NormalizeUnit(stockUnit) {
+		return 1, nil
+	}
+	return models.ConvertIngredientUnit(1, unit, stockUnit, conversions)
+}
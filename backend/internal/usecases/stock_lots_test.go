@@ -0,0 +1,58 @@
+package usecases
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+func TestPlanStockLotConsumption(t *testing.T) {
+	newLots := func() []*models.StockLot {
+		return []*models.StockLot{
+			{ID: uuid.New(), RemainingQuantity: 2, UnitCost: 100},
+			{ID: uuid.New(), RemainingQuantity: 3, UnitCost: 120},
+		}
+	}
+	stock := &models.Stock{WarehouseID: uuid.New(), PricePerUnit: 150}
+	doc := stockDocument{Type: models.StockConsumptionWriteOff, ID: uuid.New(), At: time.Now()}
+
+	tests := []struct {
+		name         string
+		quantity     float64
+		cost         float64
+		remaining    []float64 // Остатки партий после расхода
+		consumptions int
+	}{
+		{name: "first lot only", quantity: 1.5, cost: 150, remaining: []float64{0.5, 3}, consumptions: 1},
+		{name: "spans lots in order", quantity: 4, cost: 200 + 240, remaining: []float64{0, 1}, consumptions: 2},
+		{name: "shortage valued at stock price", quantity: 6, cost: 200 + 360 + 150, remaining: []float64{0, 0}, consumptions: 3},
+		{name: "nothing to consume", quantity: 0, cost: 0, remaining: []float64{2, 3}, consumptions: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lots := newLots()
+			plan := planStockLotConsumption(lots, stock, tt.quantity, doc)
+			assert.InDelta(t, tt.cost, plan.Cost, 1e-9)
+			require.Len(t, plan.Consumptions, tt.consumptions)
+			for i, lot := range lots {
+				assert.InDelta(t, tt.remaining[i], lot.RemainingQuantity, 1e-9)
+			}
+			for _, c := range plan.Consumptions {
+				assert.Equal(t, doc.ID, c.DocumentID)
+			}
+		})
+	}
+
+	t.Run("shortage line has no lot", func(t *testing.T) {
+		plan := planStockLotConsumption(newLots(), stock, 6, doc)
+		last := plan.Consumptions[len(plan.Consumptions)-1]
+		assert.Nil(t, last.LotID)
+		assert.InDelta(t, 1, last.Quantity, 1e-9)
+		assert.InDelta(t, 150, last.UnitCost, 1e-9)
+	})
+}
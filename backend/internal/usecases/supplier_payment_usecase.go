@@ -0,0 +1,460 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// SupplierPaymentUseCase расчеты с поставщиками: платежи, баланс и старение задолженности
+type SupplierPaymentUseCase struct {
+	repo         repositories.SupplierPaymentRepository
+	supplierRepo repositories.SupplierRepository
+	transactor   repositories.Transactor // Платеж, оплаты поставок и транзакция по счету сохраняются вместе
+	financeUC    *FinanceUseCase
+}
+
+func NewSupplierPaymentUseCase(
+	repo repositories.SupplierPaymentRepository,
+	supplierRepo repositories.SupplierRepository,
+	transactor repositories.Transactor,
+	financeUC *FinanceUseCase,
+) *SupplierPaymentUseCase {
+	return &SupplierPaymentUseCase{
+		repo:         repo,
+		supplierRepo: supplierRepo,
+		transactor:   transactor,
+		financeUC:    financeUC,
+	}
+}
+
+// supplyDueDate срок оплаты поставки: сохраненный при создании или вычисленный по отсрочке поставщика
+func supplyDueDate(supply *models.Supply, supplier *models.Supplier) time.Time {
+	if supply.DueDate != nil {
+		return *supply.DueDate
+	}
+	terms := 0
+	if supplier != nil {
+		terms = supplier.PaymentTermDays
+	}
+	return supply.DueDateFor(terms)
+}
+
+// ——— Payments ———
+
+// CreatePayment проводит платеж поставщику: распределяет сумму по поставкам и создает расходную транзакцию.
+// Если распределение не указано, сумма закрывает неоплаченные поставки по сроку оплаты (сначала самые ранние);
+// нераспределенный остаток остается авансом поставщику.
+func (uc *SupplierPaymentUseCase) CreatePayment(ctx context.Context, payment *models.SupplierPayment, establishmentID uuid.UUID) error {
+	supplier, err := uc.supplierRepo.GetByID(ctx, payment.SupplierID, &establishmentID)
+	if err != nil || supplier == nil {
+		return errors.New("supplier not found or access denied")
+	}
+	payment.Amount = models.RoundTo2(payment.Amount)
+	if payment.Amount <= 0 {
+		return errors.New("payment amount must be greater than 0")
+	}
+	payment.EstablishmentID = establishmentID
+	if payment.PaymentDate.IsZero() {
+		payment.PaymentDate = time.Now()
+	}
+
+	supplies, err := uc.repo.ListPayableSupplies(ctx, establishmentID, &payment.SupplierID)
+	if err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]*models.Supply, len(supplies))
+	for _, s := range supplies {
+		byID[s.ID] = s
+	}
+
+	if len(payment.Allocations) == 0 {
+		payment.Allocations = autoAllocate(payment.Amount, supplies, supplier)
+	}
+
+	allocated := 0.0
+	touched := make([]*models.Supply, 0, len(payment.Allocations))
+	seen := make(map[uuid.UUID]bool)
+	for i := range payment.Allocations {
+		a := &payment.Allocations[i]
+		a.Amount = models.RoundTo2(a.Amount)
+		if a.Amount <= 0 {
+			return errors.New("allocation amount must be greater than 0")
+		}
+		supply, ok := byID[a.SupplyID]
+		if !ok {
+			return fmt.Errorf("supply %s not found, not completed or belongs to another supplier", a.SupplyID)
+		}
+		if a.Amount > supply.OutstandingAmount() {
+			return fmt.Errorf("allocation %.2f exceeds outstanding amount %.2f of supply %s", a.Amount, supply.OutstandingAmount(), a.SupplyID)
+		}
+		allocated += a.Amount
+
+		paymentDate := payment.PaymentDate
+		supply.PaymentAmount = models.RoundTo2(supply.PaymentAmount + a.Amount)
+		supply.PaymentStatus = supply.PaymentStatusFor(supply.PaymentAmount)
+		supply.PaymentDate = &paymentDate
+		if !seen[supply.ID] {
+			seen[supply.ID] = true
+			touched = append(touched, supply)
+		}
+	}
+	if models.RoundTo2(allocated) > payment.Amount {
+		return fmt.Errorf("allocated amount %.2f exceeds payment amount %.2f", allocated, payment.Amount)
+	}
+
+	// Расход по счету; при недостатке средств платеж не проводится
+	description := fmt.Sprintf("Оплата поставщику %s", supplier.Name)
+	if payment.Comment != "" {
+		description += ": " + payment.Comment
+	}
+	transaction := &models.Transaction{
+		AccountID:       payment.AccountID,
+		Type:            "expense",
+		Category:        "supply_payment",
+		Amount:          payment.Amount,
+		Description:     description,
+		TransactionDate: payment.PaymentDate,
+	}
+	// Баланс счета не должен расходиться с платежами: расход и платеж сохраняются одной транзакцией
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.financeUC.CreateTransaction(ctx, transaction, establishmentID); err != nil {
+			return fmt.Errorf("failed to create payment transaction: %w", err)
+		}
+		payment.TransactionID = &transaction.ID
+		return uc.repo.Create(ctx, payment, touched)
+	})
+}
+
+// autoAllocate распределяет сумму по неоплаченным поставкам в порядке срока оплаты
+func autoAllocate(amount float64, supplies []*models.Supply, supplier *models.Supplier) []models.SupplierPaymentAllocation {
+	open := make([]*models.Supply, 0, len(supplies))
+	for _, s := range supplies {
+		if s.OutstandingAmount() > 0 {
+			open = append(open, s)
+		}
+	}
+	sort.SliceStable(open, func(i, j int) bool {
+		return supplyDueDate(open[i], supplier).Before(supplyDueDate(open[j], supplier))
+	})
+
+	var allocations []models.SupplierPaymentAllocation
+	remaining := amount
+	for _, s := range open {
+		if remaining <= 0 {
+			break
+		}
+		part := s.OutstandingAmount()
+		if part > remaining {
+			part = remaining
+		}
+		allocations = append(allocations, models.SupplierPaymentAllocation{SupplyID: s.ID, Amount: models.RoundTo2(part)})
+		remaining = models.RoundTo2(remaining - part)
+	}
+	return allocations
+}
+
+// DeletePayment отменяет платеж: возвращает деньги на счет и уменьшает оплаченные суммы поставок
+func (uc *SupplierPaymentUseCase) DeletePayment(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) error {
+	payment, err := uc.repo.GetByID(ctx, id, &establishmentID)
+	if err != nil {
+		return err
+	}
+	if payment == nil {
+		return errors.New("payment not found or access denied")
+	}
+
+	supplies, err := uc.repo.ListPayableSupplies(ctx, establishmentID, &payment.SupplierID)
+	if err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]*models.Supply, len(supplies))
+	for _, s := range supplies {
+		byID[s.ID] = s
+	}
+	var touched []*models.Supply
+	for _, a := range payment.Allocations {
+		supply, ok := byID[a.SupplyID]
+		if !ok {
+			continue
+		}
+		supply.PaymentAmount = models.RoundTo2(supply.PaymentAmount - a.Amount)
+		if supply.PaymentAmount < 0 {
+			supply.PaymentAmount = 0
+		}
+		supply.PaymentStatus = supply.PaymentStatusFor(supply.PaymentAmount)
+		if supply.PaymentAmount == 0 {
+			supply.PaymentDate = nil
+		}
+		touched = append(touched, supply)
+	}
+
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if payment.TransactionID != nil {
+			if err := uc.financeUC.DeleteTransaction(ctx, *payment.TransactionID, establishmentID); err != nil {
+				return fmt.Errorf("failed to revert payment transaction: %w", err)
+			}
+		}
+		return uc.repo.Delete(ctx, id, touched)
+	})
+}
+
+func (uc *SupplierPaymentUseCase) GetPayment(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) (*models.SupplierPayment, error) {
+	payment, err := uc.repo.GetByID(ctx, id, &establishmentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, errors.New("payment not found")
+	}
+	return payment, nil
+}
+
+func (uc *SupplierPaymentUseCase) ListPayments(ctx context.Context, establishmentID uuid.UUID, filter *repositories.SupplierPaymentFilter) ([]*models.SupplierPayment, error) {
+	if filter == nil {
+		filter = &repositories.SupplierPaymentFilter{}
+	}
+	filter.EstablishmentID = &establishmentID
+	return uc.repo.List(ctx, filter)
+}
+
+// ——— Balance & aging ———
+
+// PayableSupply неоплаченная (или частично оплаченная) поставка в расчетах с поставщиком
+type PayableSupply struct {
+	SupplyID         uuid.UUID `json:"supply_id"`
+	InvoiceNumber    string    `json:"invoice_number"`
+	DeliveryDateTime time.Time `json:"delivery_date_time"`
+	DueDate          time.Time `json:"due_date"`
+	InvoiceAmount    float64   `json:"invoice_amount"`
+	PaidAmount       float64   `json:"paid_amount"`
+	Outstanding      float64   `json:"outstanding"`
+	DaysOverdue      int       `json:"days_overdue"` // 0 — срок оплаты еще не наступил
+	PaymentStatus    string    `json:"payment_status"`
+}
+
+// SupplierBalance задолженность заведения перед поставщиком
+type SupplierBalance struct {
+	SupplierID      uuid.UUID       `json:"supplier_id"`
+	SupplierName    string          `json:"supplier_name"`
+	PaymentTermDays int             `json:"payment_term_days"`
+	Invoiced        float64         `json:"invoiced"`    // Сумма проведенных поставок
+	Paid            float64         `json:"paid"`        // Оплачено по поставкам
+	Outstanding     float64         `json:"outstanding"` // Неоплаченный остаток по поставкам
+	Overdue         float64         `json:"overdue"`     // Из них просрочено
+	Advance         float64         `json:"advance"`     // Нераспределенные платежи (аванс)
+	Balance         float64         `json:"balance"`     // Итоговый долг: outstanding − advance (отрицательный — переплата)
+	Supplies        []PayableSupply `json:"supplies"`
+}
+
+// AgingBuckets задолженность по срокам просрочки
+type AgingBuckets struct {
+	Current    float64 `json:"current"`      // Срок оплаты не наступил
+	Days0To30  float64 `json:"days_0_30"`    // Просрочено 0–30 дней
+	Days31To60 float64 `json:"days_31_60"`   // Просрочено 31–60 дней
+	Days60Plus float64 `json:"days_60_plus"` // Просрочено более 60 дней
+	Total      float64 `json:"total"`
+}
+
+func (b *AgingBuckets) add(amount float64, daysOverdue int, overdue bool) {
+	switch {
+	case !overdue:
+		b.Current += amount
+	case daysOverdue <= 30:
+		b.Days0To30 += amount
+	case daysOverdue <= 60:
+		b.Days31To60 += amount
+	default:
+		b.Days60Plus += amount
+	}
+	b.Total += amount
+}
+
+func (b *AgingBuckets) round() {
+	b.Current = models.RoundTo2(b.Current)
+	b.Days0To30 = models.RoundTo2(b.Days0To30)
+	b.Days31To60 = models.RoundTo2(b.Days31To60)
+	b.Days60Plus = models.RoundTo2(b.Days60Plus)
+	b.Total = models.RoundTo2(b.Total)
+}
+
+// SupplierAging строка отчета по старению задолженности
+type SupplierAging struct {
+	SupplierID      uuid.UUID `json:"supplier_id"`
+	SupplierName    string    `json:"supplier_name"`
+	PaymentTermDays int       `json:"payment_term_days"`
+	AgingBuckets
+	Advance float64 `json:"advance"`
+	Balance float64 `json:"balance"`
+}
+
+// AgingReport отчет по старению задолженности перед поставщиками
+type AgingReport struct {
+	AsOf      time.Time        `json:"as_of"`
+	Suppliers []*SupplierAging `json:"suppliers"`
+	Totals    AgingBuckets     `json:"totals"`
+	Advance   float64          `json:"advance"`
+	Balance   float64          `json:"balance"`
+}
+
+// overdueDays возвращает число полных дней просрочки и признак просрочки на дату asOf
+func overdueDays(due, asOf time.Time) (int, bool) {
+	if !asOf.After(due) {
+		return 0, false
+	}
+	return int(asOf.Sub(due).Hours() / 24), true
+}
+
+// unallocatedBySupplier суммирует нераспределенные остатки платежей (авансы) по поставщикам
+func (uc *SupplierPaymentUseCase) unallocatedBySupplier(ctx context.Context, establishmentID uuid.UUID, supplierID *uuid.UUID, asOf time.Time) (map[uuid.UUID]float64, error) {
+	payments, err := uc.repo.List(ctx, &repositories.SupplierPaymentFilter{
+		EstablishmentID: &establishmentID,
+		SupplierID:      supplierID,
+		EndDate:         &asOf,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[uuid.UUID]float64)
+	for _, p := range payments {
+		if rest := p.UnallocatedAmount(); rest > 0 {
+			result[p.SupplierID] += rest
+		}
+	}
+	return result, nil
+}
+
+// GetSupplierBalance возвращает задолженность перед поставщиком с детализацией по неоплаченным поставкам
+func (uc *SupplierPaymentUseCase) GetSupplierBalance(ctx context.Context, supplierID uuid.UUID, establishmentID uuid.UUID) (*SupplierBalance, error) {
+	supplier, err := uc.supplierRepo.GetByID(ctx, supplierID, &establishmentID)
+	if err != nil || supplier == nil {
+		return nil, errors.New("supplier not found or access denied")
+	}
+	supplies, err := uc.repo.ListPayableSupplies(ctx, establishmentID, &supplierID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	advances, err := uc.unallocatedBySupplier(ctx, establishmentID, &supplierID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := &SupplierBalance{
+		SupplierID:      supplier.ID,
+		SupplierName:    supplier.Name,
+		PaymentTermDays: supplier.PaymentTermDays,
+		Supplies:        []PayableSupply{},
+	}
+	for _, s := range supplies {
+		invoice := s.InvoiceAmount()
+		outstanding := s.OutstandingAmount()
+		balance.Invoiced += invoice
+		balance.Paid += s.PaymentAmount
+		if outstanding <= 0 {
+			continue
+		}
+		due := supplyDueDate(s, supplier)
+		days, overdue := overdueDays(due, now)
+		balance.Outstanding += outstanding
+		if overdue {
+			balance.Overdue += outstanding
+		}
+		balance.Supplies = append(balance.Supplies, PayableSupply{
+			SupplyID:         s.ID,
+			InvoiceNumber:    s.InvoiceNumber,
+			DeliveryDateTime: s.DeliveryDateTime,
+			DueDate:          due,
+			InvoiceAmount:    invoice,
+			PaidAmount:       models.RoundTo2(s.PaymentAmount),
+			Outstanding:      outstanding,
+			DaysOverdue:      days,
+			PaymentStatus:    s.PaymentStatus,
+		})
+	}
+	balance.Invoiced = models.RoundTo2(balance.Invoiced)
+	balance.Paid = models.RoundTo2(balance.Paid)
+	balance.Outstanding = models.RoundTo2(balance.Outstanding)
+	balance.Overdue = models.RoundTo2(balance.Overdue)
+	balance.Advance = models.RoundTo2(advances[supplierID])
+	balance.Balance = models.RoundTo2(balance.Outstanding - balance.Advance)
+	return balance, nil
+}
+
+// GetAgingReport возвращает задолженность перед поставщиками по срокам просрочки на дату asOf
+func (uc *SupplierPaymentUseCase) GetAgingReport(ctx context.Context, establishmentID uuid.UUID, asOf time.Time) (*AgingReport, error) {
+	if asOf.IsZero() {
+		asOf = time.Now()
+	}
+	supplies, err := uc.repo.ListPayableSupplies(ctx, establishmentID, nil)
+	if err != nil {
+		return nil, err
+	}
+	advances, err := uc.unallocatedBySupplier(ctx, establishmentID, nil, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make(map[uuid.UUID]*SupplierAging)
+	row := func(supplier *models.Supplier, supplierID uuid.UUID) *SupplierAging {
+		r, ok := rows[supplierID]
+		if !ok {
+			r = &SupplierAging{SupplierID: supplierID}
+			if supplier != nil {
+				r.SupplierName = supplier.Name
+				r.PaymentTermDays = supplier.PaymentTermDays
+			}
+			rows[supplierID] = r
+		}
+		return r
+	}
+
+	for _, s := range supplies {
+		// Поставки после даты отчета не учитываются
+		if s.DeliveryDateTime.After(asOf) {
+			continue
+		}
+		outstanding := s.OutstandingAmount()
+		if outstanding <= 0 {
+			continue
+		}
+		days, overdue := overdueDays(supplyDueDate(s, s.Supplier), asOf)
+		row(s.Supplier, s.SupplierID).add(outstanding, days, overdue)
+	}
+	for supplierID, advance := range advances {
+		r, ok := rows[supplierID]
+		if !ok {
+			supplier, _ := uc.supplierRepo.GetByID(ctx, supplierID, &establishmentID)
+			r = row(supplier, supplierID)
+		}
+		r.Advance += advance
+	}
+
+	report := &AgingReport{AsOf: asOf, Suppliers: make([]*SupplierAging, 0, len(rows))}
+	for _, r := range rows {
+		r.round()
+		r.Advance = models.RoundTo2(r.Advance)
+		r.Balance = models.RoundTo2(r.Total - r.Advance)
+		report.Totals.Current += r.Current
+		report.Totals.Days0To30 += r.Days0To30
+		report.Totals.Days31To60 += r.Days31To60
+		report.Totals.Days60Plus += r.Days60Plus
+		report.Totals.Total += r.Total
+		report.Advance += r.Advance
+		report.Suppliers = append(report.Suppliers, r)
+	}
+	report.Totals.round()
+	report.Advance = models.RoundTo2(report.Advance)
+	report.Balance = models.RoundTo2(report.Totals.Total - report.Advance)
+	sort.Slice(report.Suppliers, func(i, j int) bool {
+		return report.Suppliers[i].Balance > report.Suppliers[j].Balance
+	})
+	return report, nil
+}
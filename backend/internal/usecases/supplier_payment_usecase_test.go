@@ -0,0 +1,293 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// fakeSupplierPaymentRepository хранит проведенные поставки и платежи в памяти.
+// С transactor считает записи, сделанные вне транзакции
+type fakeSupplierPaymentRepository struct {
+	repositories.SupplierPaymentRepository
+	supplies   []*models.Supply
+	payments   []*models.SupplierPayment
+	transactor *fakeTransactor
+	createErr  error
+	outsideTx  int
+}
+
+func (r *fakeSupplierPaymentRepository) write() {
+	if r.transactor != nil && r.transactor.depth == 0 {
+		r.outsideTx++
+	}
+}
+
+func (r *fakeSupplierPaymentRepository) ListPayableSupplies(ctx context.Context, establishmentID uuid.UUID, supplierID *uuid.UUID) ([]*models.Supply, error) {
+	var result []*models.Supply
+	for _, s := range r.supplies {
+		if supplierID == nil || s.SupplierID == *supplierID {
+			cp := *s
+			result = append(result, &cp)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeSupplierPaymentRepository) List(ctx context.Context, filter *repositories.SupplierPaymentFilter) ([]*models.SupplierPayment, error) {
+	return r.payments, nil
+}
+
+func (r *fakeSupplierPaymentRepository) Create(ctx context.Context, payment *models.SupplierPayment, supplies []*models.Supply) error {
+	r.write()
+	if r.createErr != nil {
+		return r.createErr
+	}
+	r.payments = append(r.payments, payment)
+	for _, s := range supplies {
+		for i, stored := range r.supplies {
+			if stored.ID == s.ID {
+				cp := *s
+				r.supplies[i] = &cp
+			}
+		}
+	}
+	return nil
+}
+
+func (r *fakeSupplierPaymentRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.SupplierPayment, error) {
+	for _, p := range r.payments {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeSupplierPaymentRepository) Delete(ctx context.Context, id uuid.UUID, supplies []*models.Supply) error {
+	r.write()
+	r.payments = slices.DeleteFunc(r.payments, func(p *models.SupplierPayment) bool { return p.ID == id })
+	return nil
+}
+
+type fakeAccountRepository struct {
+	repositories.AccountRepository
+	balance float64
+}
+
+func (r *fakeAccountRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Account, error) {
+	return &models.Account{ID: id, Balance: r.balance}, nil
+}
+
+func (r *fakeAccountRepository) UpdateBalance(ctx context.Context, id uuid.UUID, balance float64) error {
+	r.balance = balance
+	return nil
+}
+
+type fakeTransactionRepository struct {
+	repositories.TransactionRepository
+	created []*models.Transaction
+}
+
+func (r *fakeTransactionRepository) Create(ctx context.Context, transaction *models.Transaction) error {
+	transaction.ID = uuid.New()
+	r.created = append(r.created, transaction)
+	return nil
+}
+
+func (r *fakeTransactionRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Transaction, error) {
+	for _, t := range r.created {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeTransactionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.created = slices.DeleteFunc(r.created, func(t *models.Transaction) bool { return t.ID == id })
+	return nil
+}
+
+func payableSupply(supplierID uuid.UUID, delivered time.Time, termDays int, amount, paid float64) *models.Supply {
+	s := &models.Supply{
+		ID:               uuid.New(),
+		SupplierID:       supplierID,
+		DeliveryDateTime: delivered,
+		Status:           "completed",
+		TotalAmount:      amount,
+		PaymentAmount:    paid,
+	}
+	due := s.DueDateFor(termDays)
+	s.DueDate = &due
+	return s
+}
+
+func TestAutoAllocate(t *testing.T) {
+	supplierID := uuid.New()
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	late := payableSupply(supplierID, day(10), 0, 300, 0)
+	early := payableSupply(supplierID, day(1), 0, 100, 40)
+	paid := payableSupply(supplierID, day(2), 0, 50, 50)
+	supplies := []*models.Supply{late, early, paid}
+
+	tests := []struct {
+		name   string
+		amount float64
+		want   []models.SupplierPaymentAllocation
+	}{
+		{name: "earliest due first", amount: 30, want: []models.SupplierPaymentAllocation{{SupplyID: early.ID, Amount: 30}}},
+		{name: "spans supplies by due date", amount: 100, want: []models.SupplierPaymentAllocation{
+			{SupplyID: early.ID, Amount: 60},
+			{SupplyID: late.ID, Amount: 40},
+		}},
+		{name: "excess stays unallocated", amount: 500, want: []models.SupplierPaymentAllocation{
+			{SupplyID: early.ID, Amount: 60},
+			{SupplyID: late.ID, Amount: 300},
+		}},
+		{name: "nothing to allocate", amount: 0, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, autoAllocate(tt.amount, supplies, nil))
+		})
+	}
+}
+
+func TestOverdueDays(t *testing.T) {
+	due := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		asOf    time.Time
+		days    int
+		overdue bool
+	}{
+		{name: "before due date", asOf: due.AddDate(0, 0, -3), days: 0, overdue: false},
+		{name: "exactly on due date", asOf: due, days: 0, overdue: false},
+		{name: "same day after due time", asOf: due.Add(5 * time.Hour), days: 0, overdue: true},
+		{name: "full days overdue", asOf: due.AddDate(0, 0, 31).Add(time.Hour), days: 31, overdue: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days, overdue := overdueDays(due, tt.asOf)
+			assert.Equal(t, tt.days, days)
+			assert.Equal(t, tt.overdue, overdue)
+		})
+	}
+}
+
+func TestSupplierPaymentUseCase_CreatePayment_AutoAllocates(t *testing.T) {
+	ctx := context.Background()
+	supplierID := uuid.New()
+	first := payableSupply(supplierID, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), 7, 100, 0)
+	second := payableSupply(supplierID, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), 7, 200, 0)
+	repo := &fakeSupplierPaymentRepository{supplies: []*models.Supply{second, first}}
+	accounts := &fakeAccountRepository{balance: 1000}
+	transactions := &fakeTransactionRepository{}
+	uc := NewSupplierPaymentUseCase(repo, &fakeSupplierRepository{termDays: 7}, &fakeTransactor{},
+		&FinanceUseCase{accountRepo: accounts, transactionRepo: transactions})
+
+	payment := &models.SupplierPayment{SupplierID: supplierID, AccountID: uuid.New(), Amount: 350}
+	require.NoError(t, uc.CreatePayment(ctx, payment, uuid.New()))
+
+	assert.Equal(t, []models.SupplierPaymentAllocation{
+		{SupplyID: first.ID, Amount: 100},
+		{SupplyID: second.ID, Amount: 200},
+	}, payment.Allocations)
+	assert.InDelta(t, 50, payment.UnallocatedAmount(), 1e-9)
+	assert.InDelta(t, 650, accounts.balance, 1e-9)
+	require.Len(t, transactions.created, 1)
+	assert.Equal(t, transactions.created[0].ID, *payment.TransactionID)
+	for _, s := range repo.supplies {
+		assert.Equal(t, models.SupplyPaymentStatusPaid, s.PaymentStatus)
+	}
+
+	t.Run("rejects allocation above outstanding", func(t *testing.T) {
+		extra := payableSupply(supplierID, time.Now(), 0, 80, 50)
+		repo.supplies = append(repo.supplies, extra)
+		err := uc.CreatePayment(ctx, &models.SupplierPayment{
+			SupplierID:  supplierID,
+			AccountID:   uuid.New(),
+			Amount:      40,
+			Allocations: []models.SupplierPaymentAllocation{{SupplyID: extra.ID, Amount: 40}},
+		}, uuid.New())
+		assert.ErrorContains(t, err, "exceeds outstanding amount")
+	})
+}
+
+func TestSupplierPaymentUseCase_PaymentInTransaction(t *testing.T) {
+	ctx := context.Background()
+	establishmentID, supplierID := uuid.New(), uuid.New()
+	supply := payableSupply(supplierID, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), 0, 100, 0)
+	transactor := &fakeTransactor{}
+	repo := &fakeSupplierPaymentRepository{supplies: []*models.Supply{supply}, transactor: transactor}
+	accounts := &fakeAccountRepository{balance: 1000}
+	transactions := &fakeTransactionRepository{}
+	uc := NewSupplierPaymentUseCase(repo, &fakeSupplierRepository{}, transactor,
+		&FinanceUseCase{accountRepo: accounts, transactionRepo: transactions})
+
+	// Платеж не сохранился: расход по счету откатывается транзакцией, а не удалением вслед
+	repo.createErr = errors.New("connection reset")
+	payment := &models.SupplierPayment{ID: uuid.New(), SupplierID: supplierID, AccountID: uuid.New(), Amount: 100}
+	assert.Error(t, uc.CreatePayment(ctx, payment, establishmentID))
+	assert.Equal(t, 1, transactor.calls)
+	assert.Len(t, transactions.created, 1)
+	assert.Zero(t, repo.outsideTx)
+
+	repo.createErr = nil
+	transactions.created = nil
+	accounts.balance = 1000
+	require.NoError(t, uc.CreatePayment(ctx, payment, establishmentID))
+	assert.InDelta(t, 900, accounts.balance, 1e-9)
+
+	// Отмена платежа возвращает деньги на счет и снимает оплату с поставки в одной транзакции
+	require.NoError(t, uc.DeletePayment(ctx, payment.ID, establishmentID))
+	assert.Equal(t, 3, transactor.calls)
+	assert.Zero(t, repo.outsideTx)
+	assert.Empty(t, repo.payments)
+	assert.Empty(t, transactions.created)
+	assert.InDelta(t, 1000, accounts.balance, 1e-9)
+}
+
+func TestSupplierPaymentUseCase_GetAgingReport(t *testing.T) {
+	ctx := context.Background()
+	asOf := time.Date(2026, 6, 30, 12, 0, 0, 0, time.UTC)
+	supplierA, supplierB := uuid.New(), uuid.New()
+	withSupplier := func(s *models.Supply, name string) *models.Supply {
+		s.Supplier = &models.Supplier{ID: s.SupplierID, Name: name}
+		return s
+	}
+	repo := &fakeSupplierPaymentRepository{
+		supplies: []*models.Supply{
+			withSupplier(payableSupply(supplierA, asOf.AddDate(0, 0, -5), 14, 100, 0), "А"),  // срок не наступил
+			withSupplier(payableSupply(supplierA, asOf.AddDate(0, 0, -20), 0, 200, 50), "А"), // 20 дней
+			withSupplier(payableSupply(supplierA, asOf.AddDate(0, 0, -45), 0, 300, 0), "А"),  // 45 дней
+			withSupplier(payableSupply(supplierB, asOf.AddDate(0, 0, -90), 0, 400, 0), "Б"),  // 90 дней
+			withSupplier(payableSupply(supplierB, asOf.AddDate(0, 0, -100), 0, 70, 70), "Б"), // оплачена
+			withSupplier(payableSupply(supplierB, asOf.AddDate(0, 0, 3), 0, 999, 0), "Б"),    // после даты отчета
+		},
+		payments: []*models.SupplierPayment{{SupplierID: supplierB, Amount: 100}},
+	}
+	uc := NewSupplierPaymentUseCase(repo, &fakeSupplierRepository{}, &fakeTransactor{}, nil)
+
+	report, err := uc.GetAgingReport(ctx, uuid.New(), asOf)
+	require.NoError(t, err)
+
+	assert.Equal(t, AgingBuckets{Current: 100, Days0To30: 150, Days31To60: 300, Days60Plus: 400, Total: 950}, report.Totals)
+	assert.InDelta(t, 100, report.Advance, 1e-9)
+	assert.InDelta(t, 850, report.Balance, 1e-9)
+	require.Len(t, report.Suppliers, 2)
+	assert.Equal(t, supplierA, report.Suppliers[0].SupplierID)
+	assert.InDelta(t, 550, report.Suppliers[0].Balance, 1e-9)
+	assert.Equal(t, "Б", report.Suppliers[1].SupplierName)
+	assert.InDelta(t, 300, report.Suppliers[1].Balance, 1e-9)
+}
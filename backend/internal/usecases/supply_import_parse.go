@@ -0,0 +1,341 @@
+package usecases
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/pkg/xlsx"
+)
+
+// Форматы документов поставщика для импорта поставки
+const (
+	SupplyImportFormatCSV  = "csv"
+	SupplyImportFormatXLSX = "xlsx"
+	SupplyImportFormatUBL  = "ubl" // Электронный счет UBL 2.x (Invoice)
+)
+
+// supplyImportDocument разобранный документ поставщика
+type supplyImportDocument struct {
+	InvoiceNumber     string
+	InvoiceDate       *time.Time
+	SupplierName      string
+	SupplierTaxNumber string
+	Lines             []SupplyImportLine
+}
+
+// DetectSupplyImportFormat определяет формат документа по расширению файла, а если его нет — по содержимому
+func DetectSupplyImportFormat(filename string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xlsx":
+		return SupplyImportFormatXLSX
+	case ".xml":
+		return SupplyImportFormatUBL
+	case ".csv", ".txt":
+		return SupplyImportFormatCSV
+	}
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("PK")):
+		return SupplyImportFormatXLSX
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return SupplyImportFormatUBL
+	}
+	return SupplyImportFormatCSV
+}
+
+func parseSupplyImport(format string, data []byte) (*supplyImportDocument, error) {
+	switch format {
+	case SupplyImportFormatCSV:
+		rows, err := readImportCSV(data)
+		if err != nil {
+			return nil, err
+		}
+		return parseSupplyImportTable(rows)
+	case SupplyImportFormatXLSX:
+		rows, err := xlsx.ReadRows(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		return parseSupplyImportTable(rows)
+	case SupplyImportFormatUBL:
+		return parseUBLInvoice(data)
+	}
+	return nil, fmt.Errorf("unsupported import format %q, must be csv, xlsx or ubl", format)
+}
+
+// readImportCSV читает CSV с разделителем «;», «,» или табуляцией (определяется по первой строке)
+func readImportCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	delimiter := ','
+	best := bytes.Count(firstLine, []byte(","))
+	for _, d := range []rune{';', '\t'} {
+		if n := bytes.Count(firstLine, []byte(string(d))); n > best {
+			delimiter, best = d, n
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	return rows, nil
+}
+
+// Названия колонок таблицы поставщика (сравниваются без учета регистра)
+var supplyImportColumns = map[string][]string{
+	"code":            {"code", "sku", "article", "item_code", "supplier_code", "артикул", "код"},
+	"name":            {"name", "item", "description", "item_name", "supplier_name", "наименование", "название", "товар"},
+	"barcode":         {"barcode", "ean", "gtin", "штрихкод", "штрих-код"},
+	"quantity":        {"quantity", "qty", "количество", "кол-во", "кол."},
+	"unit":            {"unit", "uom", "ед", "ед.", "ед. изм.", "ед.изм.", "единица"},
+	"price":           {"price", "price_per_unit", "unit_price", "цена"},
+	"total":           {"total", "amount", "total_amount", "sum", "сумма", "стоимость"},
+	"expiry_date":     {"expiry_date", "best_before", "годен до", "срок годности"},
+	"production_date": {"production_date", "дата производства", "дата изготовления"},
+}
+
+// parseSupplyImportTable разбирает таблицу: первая непустая строка — заголовки колонок
+func parseSupplyImportTable(rows [][]string) (*supplyImportDocument, error) {
+	headerRow := -1
+	for i, row := range rows {
+		if strings.TrimSpace(strings.Join(row, "")) != "" {
+			headerRow = i
+			break
+		}
+	}
+	if headerRow < 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	col := make(map[string]int)
+	for i, h := range rows[headerRow] {
+		h = strings.Join(strings.Fields(strings.ToLower(h)), " ")
+		for field, aliases := range supplyImportColumns {
+			if _, ok := col[field]; ok {
+				continue
+			}
+			for _, alias := range aliases {
+				if h == alias {
+					col[field] = i
+					break
+				}
+			}
+		}
+	}
+	if _, ok := col["quantity"]; !ok {
+		return nil, errors.New("file must have quantity column")
+	}
+	_, hasCode := col["code"]
+	_, hasName := col["name"]
+	_, hasBarcode := col["barcode"]
+	if !hasCode && !hasName && !hasBarcode {
+		return nil, errors.New("file must have code, name or barcode column")
+	}
+
+	cell := func(row []string, field string) string {
+		i, ok := col[field]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	doc := &supplyImportDocument{}
+	for i := headerRow + 1; i < len(rows); i++ {
+		row := rows[i]
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+		line := SupplyImportLine{
+			Line:         i + 1,
+			SupplierCode: cell(row, "code"),
+			SupplierName: cell(row, "name"),
+			Barcode:      cell(row, "barcode"),
+			Unit:         cell(row, "unit"),
+		}
+		var err error
+		if line.Quantity, err = parseImportNumber(cell(row, "quantity")); err != nil {
+			line.Errors = append(line.Errors, "invalid quantity: "+err.Error())
+		}
+		if line.PricePerUnit, err = parseImportNumber(cell(row, "price")); err != nil {
+			line.Errors = append(line.Errors, "invalid price: "+err.Error())
+		}
+		if line.TotalAmount, err = parseImportNumber(cell(row, "total")); err != nil {
+			line.Errors = append(line.Errors, "invalid total: "+err.Error())
+		}
+		if line.ExpiryDate, err = parseImportDate(cell(row, "expiry_date")); err != nil {
+			line.Errors = append(line.Errors, "invalid expiry_date: "+err.Error())
+		}
+		if line.ProductionDate, err = parseImportDate(cell(row, "production_date")); err != nil {
+			line.Errors = append(line.Errors, "invalid production_date: "+err.Error())
+		}
+		doc.Lines = append(doc.Lines, line)
+	}
+	return doc, nil
+}
+
+// parseImportNumber разбирает число в записи «1 234,50», «1.234,50», «1,234.50» или «1234.50»; пустая строка — 0.
+// Если есть и точка, и запятая, десятичный разделитель — тот, что стоит последним.
+func parseImportNumber(s string) (float64, error) {
+	s = strings.NewReplacer(" ", "", "\u00a0", "", "\u202f", "").Replace(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+	if comma := strings.LastIndex(s, ","); comma >= 0 {
+		if strings.LastIndex(s, ".") > comma {
+			s = strings.ReplaceAll(s, ",", "") // 1,234.50
+		} else {
+			s = strings.ReplaceAll(strings.ReplaceAll(s, ".", ""), ",", ".") // 1.234,50 и 1234,50
+		}
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// parseImportDate разбирает дату в форматах 2006-01-02, 02.01.2006, RFC3339 или серийный номер даты Excel
+func parseImportDate(s string) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{"2006-01-02", "02.01.2006", time.RFC3339, "02/01/2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t, nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(s, 64); err == nil && serial > 0 {
+		t := xlsx.SerialToTime(serial)
+		return &t, nil
+	}
+	return nil, fmt.Errorf("unsupported date %q", s)
+}
+
+// ——— UBL ———
+
+type ublQuantity struct {
+	Value    string `xml:",chardata"`
+	UnitCode string `xml:"unitCode,attr"`
+}
+
+type ublParty struct {
+	Name      string `xml:"PartyName>Name"`
+	LegalName string `xml:"PartyLegalEntity>RegistrationName"`
+	TaxID     string `xml:"PartyTaxScheme>CompanyID"`
+}
+
+type ublInvoiceLine struct {
+	Quantity   ublQuantity `xml:"InvoicedQuantity"`
+	LineAmount string      `xml:"LineExtensionAmount"`
+	Item       struct {
+		Name        string `xml:"Name"`
+		Description string `xml:"Description"`
+		SellersID   string `xml:"SellersItemIdentification>ID"`
+		StandardID  string `xml:"StandardItemIdentification>ID"`
+	} `xml:"Item"`
+	Price struct {
+		Amount       string      `xml:"PriceAmount"`
+		BaseQuantity ublQuantity `xml:"BaseQuantity"`
+	} `xml:"Price"`
+}
+
+type ublInvoice struct {
+	XMLName   xml.Name
+	ID        string           `xml:"ID"`
+	IssueDate string           `xml:"IssueDate"`
+	Supplier  ublParty         `xml:"AccountingSupplierParty>Party"`
+	Lines     []ublInvoiceLine `xml:"InvoiceLine"`
+}
+
+// Коды единиц UN/ECE Recommendation 20, которые используются в UBL
+var ublUnitCodes = map[string]string{
+	"KGM": models.UnitKilogram,
+	"GRM": models.UnitGram,
+	"LTR": models.UnitLiter,
+	"MLT": models.UnitMilliliter,
+	"H87": models.UnitPiece,
+	"C62": models.UnitPiece,
+	"EA":  models.UnitPiece,
+	"PCE": models.UnitPiece,
+	"NMP": models.UnitPiece,
+}
+
+// parseUBLInvoice разбирает электронный счет UBL (корневой элемент Invoice)
+func parseUBLInvoice(data []byte) (*supplyImportDocument, error) {
+	var inv ublInvoice
+	if err := xml.Unmarshal(data, &inv); err != nil {
+		return nil, fmt.Errorf("invalid xml: %w", err)
+	}
+	if inv.XMLName.Local != "Invoice" {
+		return nil, fmt.Errorf("unsupported UBL document %q, expected Invoice", inv.XMLName.Local)
+	}
+
+	doc := &supplyImportDocument{
+		InvoiceNumber:     strings.TrimSpace(inv.ID),
+		SupplierName:      strings.TrimSpace(inv.Supplier.Name),
+		SupplierTaxNumber: strings.TrimSpace(inv.Supplier.TaxID),
+	}
+	if doc.SupplierName == "" {
+		doc.SupplierName = strings.TrimSpace(inv.Supplier.LegalName)
+	}
+	if inv.IssueDate != "" {
+		t, err := time.Parse("2006-01-02", strings.TrimSpace(inv.IssueDate))
+		if err != nil {
+			return nil, fmt.Errorf("invalid IssueDate: %w", err)
+		}
+		doc.InvoiceDate = &t
+	}
+
+	for i, l := range inv.Lines {
+		line := SupplyImportLine{
+			Line:         i + 1,
+			SupplierCode: strings.TrimSpace(l.Item.SellersID),
+			SupplierName: strings.TrimSpace(l.Item.Name),
+			Barcode:      strings.TrimSpace(l.Item.StandardID),
+			Unit:         ublUnit(l.Quantity.UnitCode),
+		}
+		if line.SupplierName == "" {
+			line.SupplierName = strings.TrimSpace(l.Item.Description)
+		}
+		var err error
+		if line.Quantity, err = parseImportNumber(l.Quantity.Value); err != nil {
+			line.Errors = append(line.Errors, "invalid InvoicedQuantity: "+err.Error())
+		}
+		if line.TotalAmount, err = parseImportNumber(l.LineAmount); err != nil {
+			line.Errors = append(line.Errors, "invalid LineExtensionAmount: "+err.Error())
+		}
+		price, err := parseImportNumber(l.Price.Amount)
+		if err != nil {
+			line.Errors = append(line.Errors, "invalid PriceAmount: "+err.Error())
+		}
+		// Цена в UBL может быть указана за BaseQuantity единиц
+		if base, err := parseImportNumber(l.Price.BaseQuantity.Value); err == nil && base > 0 {
+			price /= base
+		}
+		line.PricePerUnit = price
+		doc.Lines = append(doc.Lines, line)
+	}
+	return doc, nil
+}
+
+func ublUnit(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if unit, ok := ublUnitCodes[code]; ok {
+		return unit
+	}
+	return code
+}
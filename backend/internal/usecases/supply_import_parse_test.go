@@ -0,0 +1,219 @@
+package usecases
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+func TestParseSupplyImportTable(t *testing.T) {
+	expiry := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		rows    [][]string
+		wantErr string
+		lines   []SupplyImportLine
+	}{
+		{
+			name: "russian headers after blank rows",
+			rows: [][]string{
+				{"", ""},
+				{"Артикул", "Наименование", "Кол-во", "Ед. изм.", "Цена", "Сумма", "Годен до"},
+				{"A-1", "Мука пшеничная", "12,5", "кг", "1 200,50", "15006,25", "31.12.2026"},
+				{"", "", "", "", "", "", ""},
+				{"A-2", "Сахар", "3", "кг", "80", "240", ""},
+			},
+			lines: []SupplyImportLine{
+				{Line: 3, SupplierCode: "A-1", SupplierName: "Мука пшеничная", Quantity: 12.5, Unit: "кг", PricePerUnit: 1200.5, TotalAmount: 15006.25,
+					ExpiryDate: &expiry},
+				{Line: 5, SupplierCode: "A-2", SupplierName: "Сахар", Quantity: 3, Unit: "кг", PricePerUnit: 80, TotalAmount: 240},
+			},
+		},
+		{
+			name: "english headers, barcode only, short rows",
+			rows: [][]string{
+				{"EAN", "QTY", "Unit_Price"},
+				{"4601234567890", "2"},
+			},
+			lines: []SupplyImportLine{{Line: 2, Barcode: "4601234567890", Quantity: 2}},
+		},
+		{
+			name: "bad values are reported per line",
+			rows: [][]string{
+				{"code", "quantity", "price", "production_date"},
+				{"X", "много", "12р", "вчера"},
+			},
+			lines: []SupplyImportLine{{Line: 2, SupplierCode: "X", Errors: []string{
+				`invalid quantity: strconv.ParseFloat: parsing "много": invalid syntax`,
+				`invalid price: strconv.ParseFloat: parsing "12р": invalid syntax`,
+				`invalid production_date: unsupported date "вчера"`,
+			}}},
+		},
+		{name: "empty file", rows: [][]string{{""}}, wantErr: "file is empty"},
+		{name: "no quantity column", rows: [][]string{{"code", "price"}}, wantErr: "quantity column"},
+		{name: "no item column", rows: [][]string{{"quantity", "price"}}, wantErr: "code, name or barcode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseSupplyImportTable(tt.rows)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.lines, doc.Lines)
+		})
+	}
+}
+
+func TestReadImportCSV_DetectsDelimiter(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "semicolon with BOM", data: "\xef\xbb\xbfкод;количество\nA-1;1,5\n"},
+		{name: "comma", data: "код,количество\nA-1,\"1,5\"\n"},
+		{name: "tab", data: "код\tколичество\nA-1\t1,5\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readImportCSV([]byte(tt.data))
+			require.NoError(t, err)
+			assert.Equal(t, [][]string{{"код", "количество"}, {"A-1", "1,5"}}, rows)
+		})
+	}
+}
+
+func TestParseImportNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		err  bool
+	}{
+		{in: "", want: 0},
+		{in: "1234.50", want: 1234.5},
+		{in: "1 234,50", want: 1234.5},
+		{in: "1 234,5", want: 1234.5},
+		{in: "1,234.50", want: 1234.5},
+		{in: "1.234,50", want: 1234.5},
+		{in: "1.234.567,5", want: 1234567.5},
+		{in: "abc", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseImportNumber(tt.in)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+func TestParseImportDate(t *testing.T) {
+	want := time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)
+	for _, in := range []string{"2026-03-15", "15.03.2026", "15/03/2026", "2026-03-15T00:00:00Z", "46096"} {
+		t.Run(in, func(t *testing.T) {
+			got, err := parseImportDate(in)
+			require.NoError(t, err)
+			require.NotNil(t, got)
+			assert.True(t, want.Equal(*got), "got %s", got)
+		})
+	}
+	got, err := parseImportDate(" ")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+const ublInvoiceSample = `<?xml version="1.0" encoding="UTF-8"?>
+<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+         xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+         xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2">
+  <cbc:ID>INV-2026-017</cbc:ID>
+  <cbc:IssueDate>2026-04-02</cbc:IssueDate>
+  <cac:AccountingSupplierParty>
+    <cac:Party>
+      <cac:PartyTaxScheme><cbc:CompanyID>7701234567</cbc:CompanyID></cac:PartyTaxScheme>
+      <cac:PartyLegalEntity><cbc:RegistrationName>ООО Мельница</cbc:RegistrationName></cac:PartyLegalEntity>
+    </cac:Party>
+  </cac:AccountingSupplierParty>
+  <cac:InvoiceLine>
+    <cbc:InvoicedQuantity unitCode="KGM">25</cbc:InvoicedQuantity>
+    <cbc:LineExtensionAmount currencyID="RUB">1500.00</cbc:LineExtensionAmount>
+    <cac:Item>
+      <cbc:Name>Мука пшеничная</cbc:Name>
+      <cac:SellersItemIdentification><cbc:ID>M-25</cbc:ID></cac:SellersItemIdentification>
+      <cac:StandardItemIdentification><cbc:ID schemeID="0160">4601234567890</cbc:ID></cac:StandardItemIdentification>
+    </cac:Item>
+    <cac:Price><cbc:PriceAmount currencyID="RUB">60</cbc:PriceAmount></cac:Price>
+  </cac:InvoiceLine>
+  <cac:InvoiceLine>
+    <cbc:InvoicedQuantity unitCode="H87">12</cbc:InvoicedQuantity>
+    <cbc:LineExtensionAmount currencyID="RUB">540</cbc:LineExtensionAmount>
+    <cac:Item><cbc:Description>Яйцо С1</cbc:Description></cac:Item>
+    <cac:Price>
+      <cbc:PriceAmount currencyID="RUB">450</cbc:PriceAmount>
+      <cbc:BaseQuantity unitCode="H87">10</cbc:BaseQuantity>
+    </cac:Price>
+  </cac:InvoiceLine>
+</Invoice>`
+
+func TestParseUBLInvoice(t *testing.T) {
+	doc, err := parseUBLInvoice([]byte(ublInvoiceSample))
+	require.NoError(t, err)
+
+	assert.Equal(t, "INV-2026-017", doc.InvoiceNumber)
+	require.NotNil(t, doc.InvoiceDate)
+	assert.Equal(t, time.Date(2026, 4, 2, 0, 0, 0, 0, time.UTC), *doc.InvoiceDate)
+	assert.Equal(t, "ООО Мельница", doc.SupplierName, "falls back to the legal name")
+	assert.Equal(t, "7701234567", doc.SupplierTaxNumber)
+	assert.Equal(t, []SupplyImportLine{
+		{Line: 1, SupplierCode: "M-25", SupplierName: "Мука пшеничная", Barcode: "4601234567890", Quantity: 25, Unit: models.UnitKilogram, PricePerUnit: 60, TotalAmount: 1500},
+		// Цена 450 за 10 штук
+		{Line: 2, SupplierName: "Яйцо С1", Quantity: 12, Unit: models.UnitPiece, PricePerUnit: 45, TotalAmount: 540},
+	}, doc.Lines)
+}
+
+func TestParseUBLInvoice_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "not xml", data: "qty;price", wantErr: "invalid xml"},
+		{name: "credit note", data: `<CreditNote xmlns="urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2"></CreditNote>`, wantErr: "expected Invoice"},
+		{name: "bad issue date", data: `<Invoice><IssueDate>02.04.2026</IssueDate></Invoice>`, wantErr: "invalid IssueDate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseUBLInvoice([]byte(tt.data))
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestDetectSupplyImportFormat(t *testing.T) {
+	tests := []struct {
+		filename string
+		data     string
+		want     string
+	}{
+		{filename: "invoice.XLSX", want: SupplyImportFormatXLSX},
+		{filename: "invoice.xml", want: SupplyImportFormatUBL},
+		{filename: "invoice.txt", data: "<Invoice/>", want: SupplyImportFormatCSV},
+		{filename: "upload", data: "PK\x03\x04", want: SupplyImportFormatXLSX},
+		{filename: "upload", data: "\xef\xbb\xbf  <?xml version=\"1.0\"?>", want: SupplyImportFormatUBL},
+		{filename: "upload", data: "code;qty", want: SupplyImportFormatCSV},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename+"/"+tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, DetectSupplyImportFormat(tt.filename, []byte(tt.data)))
+		})
+	}
+}
@@ -0,0 +1,530 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// ErrSupplyImportInvalid возвращается, если в импортируемых строках остались ошибки
+var ErrSupplyImportInvalid = errors.New("supply import has invalid lines")
+
+// Способы сопоставления строки документа поставщика с позицией заведения
+const (
+	SupplyImportMatchManual  = "manual"  // Позиция выбрана пользователем
+	SupplyImportMatchMapping = "mapping" // Запомненное сопоставление поставщика
+	SupplyImportMatchBarcode = "barcode" // Штрихкод позиции
+	SupplyImportMatchName    = "name"    // Совпадение названия
+)
+
+// supplyImportPriceDeviation отклонение цены от последней закупки, после которого строка получает предупреждение
+const supplyImportPriceDeviation = 0.2
+
+// SupplyImportLine строка документа поставщика. Используется и в предпросмотре, и при подтверждении импорта:
+// пользователь может выбрать позицию (ingredient_id/product_id) и коэффициент единицы (unit_factor) вручную.
+type SupplyImportLine struct {
+	Line           int        `json:"line"` // Номер строки в документе
+	SupplierCode   string     `json:"supplier_code"`
+	SupplierName   string     `json:"supplier_name"`
+	Barcode        string     `json:"barcode,omitempty"`
+	Quantity       float64    `json:"quantity"` // В единице поставщика
+	Unit           string     `json:"unit"`     // Единица поставщика
+	PricePerUnit   float64    `json:"price_per_unit"`
+	TotalAmount    float64    `json:"total_amount"`
+	ProductionDate *time.Time `json:"production_date,omitempty"`
+	ExpiryDate     *time.Time `json:"expiry_date,omitempty"`
+	IngredientID   *uuid.UUID `json:"ingredient_id,omitempty"`
+	ProductID      *uuid.UUID `json:"product_id,omitempty"`
+	UnitFactor     float64    `json:"unit_factor,omitempty"` // Количество FactorUnit в одной единице поставщика
+	FactorUnit     string     `json:"factor_unit,omitempty"` // Единица UnitFactor (пусто — единица учета позиции)
+
+	// Результат сопоставления и пересчета
+	ItemName          string     `json:"item_name,omitempty"`
+	MatchedBy         string     `json:"matched_by,omitempty"` // manual, mapping, barcode, name
+	MappingID         *uuid.UUID `json:"mapping_id,omitempty"`
+	StockUnit         string     `json:"stock_unit,omitempty"`
+	StockQuantity     float64    `json:"stock_quantity"`       // Количество в единице учета остатка
+	StockPricePerUnit float64    `json:"stock_price_per_unit"` // Цена за единицу учета остатка
+	Errors            []string   `json:"errors,omitempty"`
+	Warnings          []string   `json:"warnings,omitempty"`
+}
+
+// SupplyImportPreview результат разбора документа поставщика до создания поставки
+type SupplyImportPreview struct {
+	SupplierID        uuid.UUID          `json:"supplier_id"`
+	WarehouseID       uuid.UUID          `json:"warehouse_id"`
+	Format            string             `json:"format,omitempty"`
+	InvoiceNumber     string             `json:"invoice_number,omitempty"`
+	InvoiceDate       *time.Time         `json:"invoice_date,omitempty"`
+	DocumentSupplier  string             `json:"document_supplier,omitempty"` // Поставщик, указанный в документе (UBL)
+	DocumentTaxNumber string             `json:"document_tax_number,omitempty"`
+	Lines             []SupplyImportLine `json:"lines"`
+	TotalAmount       float64            `json:"total_amount"`
+	Unmatched         int                `json:"unmatched"` // Строк без сопоставленной позиции
+	Valid             bool               `json:"valid"`     // Ошибок нет, поставку можно создать
+	Warnings          []string           `json:"warnings,omitempty"`
+}
+
+// SupplyImportRequest подтверждение импорта: строки предпросмотра (возможно, исправленные пользователем)
+type SupplyImportRequest struct {
+	SupplierID       uuid.UUID          `json:"supplier_id" binding:"required"`
+	WarehouseID      uuid.UUID          `json:"warehouse_id" binding:"required"`
+	DeliveryDateTime *time.Time         `json:"delivery_date_time"` // По умолчанию — текущий момент
+	Status           string             `json:"status"`             // pending, completed (по умолчанию)
+	InvoiceNumber    string             `json:"invoice_number"`
+	InvoiceDate      *time.Time         `json:"invoice_date"`
+	TotalAmount      float64            `json:"total_amount"`
+	Comment          string             `json:"comment"`
+	RememberMappings bool               `json:"remember_mappings"` // Сохранить сопоставления строк для следующих документов поставщика
+	Lines            []SupplyImportLine `json:"lines" binding:"required,min=1"`
+}
+
+// SupplyImportUseCase импорт поставок из документов поставщиков (CSV, XLSX, UBL)
+// и сопоставления позиций поставщиков с ингредиентами и товарами заведения
+type SupplyImportUseCase struct {
+	supplierRepo   repositories.SupplierRepository
+	warehouseRepo  repositories.WarehouseRepository
+	ingredientRepo repositories.IngredientRepository
+	productRepo    repositories.ProductRepository
+	barcodes       *BarcodeUseCase
+	warehouse      *WarehouseUseCase
+}
+
+func NewSupplyImportUseCase(
+	supplierRepo repositories.SupplierRepository,
+	warehouseRepo repositories.WarehouseRepository,
+	ingredientRepo repositories.IngredientRepository,
+	productRepo repositories.ProductRepository,
+	barcodes *BarcodeUseCase,
+	warehouse *WarehouseUseCase,
+) *SupplyImportUseCase {
+	return &SupplyImportUseCase{
+		supplierRepo:   supplierRepo,
+		warehouseRepo:  warehouseRepo,
+		ingredientRepo: ingredientRepo,
+		productRepo:    productRepo,
+		barcodes:       barcodes,
+		warehouse:      warehouse,
+	}
+}
+
+// ——— Import ———
+
+// Preview разбирает документ поставщика, сопоставляет строки с позициями заведения,
+// пересчитывает количества в единицы учета и проверяет строки. Поставка не создается.
+// Пустой format определяется по имени файла и содержимому.
+func (uc *SupplyImportUseCase) Preview(ctx context.Context, establishmentID, supplierID, warehouseID uuid.UUID, format, filename string, data []byte) (*SupplyImportPreview, error) {
+	if format == "" {
+		format = DetectSupplyImportFormat(filename, data)
+	}
+	doc, err := parseSupplyImport(format, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Lines) == 0 {
+		return nil, errors.New("document has no lines")
+	}
+
+	preview := &SupplyImportPreview{
+		SupplierID:        supplierID,
+		WarehouseID:       warehouseID,
+		Format:            format,
+		InvoiceNumber:     doc.InvoiceNumber,
+		InvoiceDate:       doc.InvoiceDate,
+		DocumentSupplier:  doc.SupplierName,
+		DocumentTaxNumber: doc.SupplierTaxNumber,
+		Lines:             doc.Lines,
+	}
+	supplier, err := uc.resolve(ctx, establishmentID, preview)
+	if err != nil {
+		return nil, err
+	}
+	if doc.SupplierTaxNumber != "" && supplier.TaxpayerNumber != "" &&
+		!strings.EqualFold(strings.TrimSpace(supplier.TaxpayerNumber), doc.SupplierTaxNumber) {
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf(
+			"document supplier tax number %s does not match supplier %q", doc.SupplierTaxNumber, supplier.Name))
+	}
+	return preview, nil
+}
+
+// Import создает поставку из подтвержденных строк. Строки проверяются заново; если остались ошибки,
+// возвращается ErrSupplyImportInvalid вместе с предпросмотром. Количества и цены передаются
+// в поставку уже в единицах учета остатков.
+func (uc *SupplyImportUseCase) Import(ctx context.Context, establishmentID uuid.UUID, req *SupplyImportRequest) (*models.Supply, *SupplyImportPreview, error) {
+	// Строки пришли от клиента: результат предыдущей проверки пересчитывается заново
+	for i := range req.Lines {
+		req.Lines[i].Errors = nil
+	}
+	preview := &SupplyImportPreview{
+		SupplierID:    req.SupplierID,
+		WarehouseID:   req.WarehouseID,
+		InvoiceNumber: req.InvoiceNumber,
+		InvoiceDate:   req.InvoiceDate,
+		Lines:         req.Lines,
+	}
+	if _, err := uc.resolve(ctx, establishmentID, preview); err != nil {
+		return nil, nil, err
+	}
+	if !preview.Valid {
+		return nil, preview, ErrSupplyImportInvalid
+	}
+
+	if req.RememberMappings {
+		if err := uc.rememberMappings(ctx, establishmentID, req.SupplierID, preview.Lines); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	deliveryAt := time.Now()
+	if req.DeliveryDateTime != nil {
+		deliveryAt = *req.DeliveryDateTime
+	}
+	status := req.Status
+	if status == "" {
+		status = "completed"
+	}
+	supply := &models.Supply{
+		WarehouseID:      req.WarehouseID,
+		SupplierID:       req.SupplierID,
+		DeliveryDateTime: deliveryAt,
+		Status:           status,
+		Comment:          req.Comment,
+		InvoiceNumber:    req.InvoiceNumber,
+		InvoiceDate:      req.InvoiceDate,
+		TotalAmount:      req.TotalAmount,
+		PaymentStatus:    "none",
+		Items:            make([]models.SupplyItem, 0, len(preview.Lines)),
+	}
+	for _, line := range preview.Lines {
+		supply.Items = append(supply.Items, models.SupplyItem{
+			ID:             uuid.New(),
+			IngredientID:   line.IngredientID,
+			ProductID:      line.ProductID,
+			Quantity:       line.StockQuantity,
+			Unit:           line.StockUnit,
+			PricePerUnit:   line.StockPricePerUnit,
+			TotalAmount:    line.TotalAmount,
+			ProductionDate: line.ProductionDate,
+			ExpiryDate:     line.ExpiryDate,
+		})
+	}
+	if err := uc.warehouse.CreateSupply(ctx, supply, establishmentID); err != nil {
+		return nil, nil, err
+	}
+	return supply, preview, nil
+}
+
+// supplyImportCatalog позиции заведения и сопоставления поставщика для разбора строк
+type supplyImportCatalog struct {
+	mappings          map[string]*models.SupplierItemMapping
+	ingredients       map[uuid.UUID]*models.Ingredient
+	products          map[uuid.UUID]*models.Product
+	ingredientsByName map[string]*models.Ingredient
+	productsByName    map[string]*models.Product
+}
+
+func (uc *SupplyImportUseCase) loadCatalog(ctx context.Context, establishmentID, supplierID uuid.UUID) (*supplyImportCatalog, error) {
+	mappings, err := uc.supplierRepo.ListItemMappings(ctx, supplierID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplier item mappings: %w", err)
+	}
+	ingredients, err := uc.ingredientRepo.List(ctx, &repositories.IngredientFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingredients: %w", err)
+	}
+	products, err := uc.productRepo.List(ctx, &repositories.ProductFilter{EstablishmentID: &establishmentID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+
+	catalog := &supplyImportCatalog{
+		mappings:          make(map[string]*models.SupplierItemMapping, len(mappings)),
+		ingredients:       make(map[uuid.UUID]*models.Ingredient, len(ingredients)),
+		products:          make(map[uuid.UUID]*models.Product, len(products)),
+		ingredientsByName: make(map[string]*models.Ingredient, len(ingredients)),
+		productsByName:    make(map[string]*models.Product, len(products)),
+	}
+	for _, m := range mappings {
+		catalog.mappings[m.MatchKey] = m
+	}
+	for _, ing := range ingredients {
+		catalog.ingredients[ing.ID] = ing
+		catalog.ingredientsByName[supplyImportName(ing.Name)] = ing
+	}
+	for _, p := range products {
+		catalog.products[p.ID] = p
+		catalog.productsByName[supplyImportName(p.Name)] = p
+	}
+	return catalog, nil
+}
+
+// resolve сопоставляет и проверяет строки предпросмотра, заполняя итоги
+func (uc *SupplyImportUseCase) resolve(ctx context.Context, establishmentID uuid.UUID, preview *SupplyImportPreview) (*models.Supplier, error) {
+	w, err := uc.warehouseRepo.GetWarehouseByID(ctx, preview.WarehouseID, &establishmentID)
+	if err != nil || w == nil {
+		return nil, errors.New("warehouse not found or access denied")
+	}
+	supplier, err := uc.supplierRepo.GetByID(ctx, preview.SupplierID, &establishmentID)
+	if err != nil || supplier == nil {
+		return nil, errors.New("supplier not found or access denied")
+	}
+	catalog, err := uc.loadCatalog(ctx, establishmentID, preview.SupplierID)
+	if err != nil {
+		return nil, err
+	}
+
+	preview.Valid = true
+	preview.TotalAmount = 0
+	preview.Unmatched = 0
+	for i := range preview.Lines {
+		line := &preview.Lines[i]
+		if line.Line == 0 {
+			line.Line = i + 1
+		}
+		uc.resolveLine(ctx, establishmentID, preview.WarehouseID, catalog, line)
+		if line.IngredientID == nil && line.ProductID == nil {
+			preview.Unmatched++
+		}
+		if len(line.Errors) > 0 {
+			preview.Valid = false
+		}
+		preview.TotalAmount += line.TotalAmount
+	}
+	preview.TotalAmount = models.RoundTo2(preview.TotalAmount)
+	return supplier, nil
+}
+
+// resolveLine находит позицию строки (выбранная пользователем, запомненное сопоставление, штрихкод, название)
+// и переводит количество и цену в единицу учета остатка
+func (uc *SupplyImportUseCase) resolveLine(ctx context.Context, establishmentID, warehouseID uuid.UUID, catalog *supplyImportCatalog, line *SupplyImportLine) {
+	// Ошибки разбора файла (Errors до сопоставления) сохраняются
+	line.Warnings = nil
+	line.ItemName, line.MatchedBy, line.MappingID = "", "", nil
+	line.StockUnit, line.StockQuantity, line.StockPricePerUnit = "", 0, 0
+
+	if line.Quantity <= 0 {
+		line.Errors = append(line.Errors, "quantity must be positive")
+	}
+	if line.PricePerUnit < 0 || line.TotalAmount < 0 {
+		line.Errors = append(line.Errors, "price must not be negative")
+	}
+	if line.PricePerUnit == 0 && line.TotalAmount > 0 && line.Quantity > 0 {
+		line.PricePerUnit = models.RoundTo2(line.TotalAmount / line.Quantity)
+	} else if line.TotalAmount == 0 && line.PricePerUnit > 0 {
+		line.TotalAmount = models.RoundTo2(line.PricePerUnit * line.Quantity)
+	} else if line.PricePerUnit > 0 && line.Quantity > 0 &&
+		math.Abs(line.PricePerUnit*line.Quantity-line.TotalAmount) > math.Max(0.01, line.TotalAmount*0.01) {
+		line.Warnings = append(line.Warnings, fmt.Sprintf("total %s differs from quantity × price %s",
+			formatAmount(line.TotalAmount), formatAmount(line.PricePerUnit*line.Quantity)))
+	}
+
+	if !uc.matchLine(ctx, establishmentID, catalog, line) {
+		line.Errors = append(line.Errors, "item is not matched, choose an ingredient or product")
+		return
+	}
+
+	// Единица поставщика → единица позиции: коэффициент сопоставления, иначе стандартный пересчет
+	quantity := line.Quantity
+	unit := models.NormalizeUnit(line.Unit)
+	if line.UnitFactor > 0 {
+		quantity *= line.UnitFactor
+		unit = models.NormalizeUnit(line.FactorUnit)
+	} else if line.UnitFactor < 0 {
+		line.Errors = append(line.Errors, "unit_factor must not be negative")
+		return
+	}
+	if unit != "" && !models.IsValidUnit(unit) {
+		line.Errors = append(line.Errors, fmt.Sprintf("unknown unit %q, set unit_factor for the supplier unit", line.Unit))
+		return
+	}
+	_, stockUnit, factor, err := uc.warehouse.resolveStock(ctx, warehouseID, line.IngredientID, line.ProductID, unit)
+	if err != nil {
+		line.Errors = append(line.Errors, err.Error())
+		return
+	}
+	line.StockUnit = stockUnit
+	line.StockQuantity = models.RoundTo2(quantity * factor)
+	if line.StockQuantity > 0 {
+		line.StockPricePerUnit = models.RoundTo2(line.TotalAmount / line.StockQuantity)
+	}
+
+	// Цена заметно отличается от последней закупки — вероятна ошибка в единице или коэффициенте
+	last, err := uc.warehouseRepo.GetLastSupplyPrice(ctx, establishmentID, line.IngredientID, line.ProductID)
+	if err == nil && last != nil && last.PricePerUnit > 0 && line.StockPricePerUnit > 0 {
+		lastFactor, err := stockUnitFactor(last.Unit, stockUnit)
+		if err == nil && lastFactor > 0 {
+			lastPrice := last.PricePerUnit / lastFactor
+			if math.Abs(line.StockPricePerUnit-lastPrice) > lastPrice*supplyImportPriceDeviation {
+				line.Warnings = append(line.Warnings, fmt.Sprintf("price %s per %s differs from last supply price %s by more than %d%%",
+					formatAmount(line.StockPricePerUnit), stockUnit, formatAmount(lastPrice), int(supplyImportPriceDeviation*100)))
+			}
+		}
+	}
+}
+
+// matchLine заполняет позицию строки; false — позиция не найдена
+func (uc *SupplyImportUseCase) matchLine(ctx context.Context, establishmentID uuid.UUID, catalog *supplyImportCatalog, line *SupplyImportLine) bool {
+	mapping := catalog.mappings[models.SupplierItemKey(line.SupplierCode, line.SupplierName)]
+	if mapping == nil && line.SupplierCode != "" {
+		mapping = catalog.mappings[models.SupplierItemKey("", line.SupplierName)]
+	}
+
+	switch {
+	case line.IngredientID != nil || line.ProductID != nil:
+		line.MatchedBy = SupplyImportMatchManual
+		if mapping != nil && sameItemID(mapping.IngredientID, line.IngredientID) && sameItemID(mapping.ProductID, line.ProductID) {
+			line.MatchedBy = SupplyImportMatchMapping
+			line.MappingID = &mapping.ID
+		}
+	case mapping != nil:
+		line.MatchedBy = SupplyImportMatchMapping
+		line.MappingID = &mapping.ID
+		line.IngredientID, line.ProductID = mapping.IngredientID, mapping.ProductID
+		if line.UnitFactor == 0 && mapping.UnitFactor > 0 &&
+			(mapping.SupplierUnit == "" || strings.EqualFold(mapping.SupplierUnit, line.Unit)) {
+			line.UnitFactor, line.FactorUnit = mapping.UnitFactor, mapping.Unit
+		}
+	case line.Barcode != "" && uc.barcodes != nil:
+		match, err := uc.barcodes.Lookup(ctx, establishmentID, line.Barcode)
+		if err != nil || match == nil || (match.IngredientID == nil && match.ProductID == nil) {
+			break
+		}
+		line.MatchedBy = SupplyImportMatchBarcode
+		line.IngredientID, line.ProductID = match.IngredientID, match.ProductID
+		// Штрихкод упаковки: одна единица поставщика — Quantity единиц позиции
+		if line.UnitFactor == 0 && match.Quantity > 0 && match.Quantity != 1 {
+			line.UnitFactor, line.FactorUnit = match.Quantity, match.Unit
+		}
+	}
+	if line.MatchedBy == "" {
+		name := supplyImportName(line.SupplierName)
+		if ing, ok := catalog.ingredientsByName[name]; ok && name != "" {
+			line.MatchedBy = SupplyImportMatchName
+			line.IngredientID = &ing.ID
+		} else if p, ok := catalog.productsByName[name]; ok && name != "" {
+			line.MatchedBy = SupplyImportMatchName
+			line.ProductID = &p.ID
+		}
+	}
+	if line.MatchedBy == "" {
+		return false
+	}
+
+	// Позиция должна принадлежать заведению
+	if line.IngredientID != nil {
+		line.ProductID = nil
+		ing, ok := catalog.ingredients[*line.IngredientID]
+		if !ok {
+			line.IngredientID = nil
+			return false
+		}
+		line.ItemName = ing.Name
+		return true
+	}
+	p, ok := catalog.products[*line.ProductID]
+	if !ok {
+		line.ProductID = nil
+		return false
+	}
+	line.ItemName = p.Name
+	return true
+}
+
+// rememberMappings сохраняет сопоставления строк поставщика. Строки, найденные по совпадению
+// названия без коэффициента единицы, не запоминаются — они и так найдутся в следующий раз.
+func (uc *SupplyImportUseCase) rememberMappings(ctx context.Context, establishmentID, supplierID uuid.UUID, lines []SupplyImportLine) error {
+	for _, line := range lines {
+		key := models.SupplierItemKey(line.SupplierCode, line.SupplierName)
+		if key == "" || (line.MatchedBy == SupplyImportMatchName && line.UnitFactor == 0) {
+			continue
+		}
+		m := &models.SupplierItemMapping{
+			EstablishmentID: establishmentID,
+			SupplierID:      supplierID,
+			MatchKey:        key,
+			SupplierCode:    line.SupplierCode,
+			SupplierName:    line.SupplierName,
+			IngredientID:    line.IngredientID,
+			ProductID:       line.ProductID,
+			SupplierUnit:    line.Unit,
+			UnitFactor:      line.UnitFactor,
+			Unit:            models.NormalizeUnit(line.FactorUnit),
+		}
+		if err := uc.supplierRepo.SaveItemMapping(ctx, m); err != nil {
+			return fmt.Errorf("failed to save supplier item mapping: %w", err)
+		}
+	}
+	return nil
+}
+
+// supplyImportName нормализует название для сравнения: регистр и лишние пробелы не учитываются
+func supplyImportName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// ——— Supplier item mappings ———
+
+// ListItemMappings возвращает запомненные сопоставления позиций поставщика
+func (uc *SupplyImportUseCase) ListItemMappings(ctx context.Context, establishmentID, supplierID uuid.UUID) ([]*models.SupplierItemMapping, error) {
+	if _, err := uc.supplierRepo.GetByID(ctx, supplierID, &establishmentID); err != nil {
+		return nil, errors.New("supplier not found or access denied")
+	}
+	return uc.supplierRepo.ListItemMappings(ctx, supplierID)
+}
+
+// SaveItemMapping создает или обновляет сопоставление позиции поставщика (ключ — артикул или название)
+func (uc *SupplyImportUseCase) SaveItemMapping(ctx context.Context, establishmentID uuid.UUID, m *models.SupplierItemMapping) error {
+	if _, err := uc.supplierRepo.GetByID(ctx, m.SupplierID, &establishmentID); err != nil {
+		return errors.New("supplier not found or access denied")
+	}
+	m.EstablishmentID = establishmentID
+	m.MatchKey = models.SupplierItemKey(m.SupplierCode, m.SupplierName)
+	if m.MatchKey == "" {
+		return errors.New("supplier_code or supplier_name is required")
+	}
+	if (m.IngredientID == nil) == (m.ProductID == nil) {
+		return errors.New("exactly one of ingredient_id or product_id is required")
+	}
+	if m.UnitFactor < 0 {
+		return errors.New("unit_factor must not be negative")
+	}
+	m.Unit = models.NormalizeUnit(m.Unit)
+	if m.Unit != "" && !models.IsValidUnit(m.Unit) {
+		return fmt.Errorf("unknown unit %q", m.Unit)
+	}
+	if m.IngredientID != nil {
+		ing, err := uc.ingredientRepo.GetByID(ctx, *m.IngredientID, &establishmentID)
+		if err != nil || ing == nil {
+			return errors.New("ingredient not found")
+		}
+	} else {
+		p, err := uc.productRepo.GetByID(ctx, *m.ProductID, &establishmentID)
+		if err != nil || p == nil {
+			return errors.New("product not found")
+		}
+	}
+	return uc.supplierRepo.SaveItemMapping(ctx, m)
+}
+
+// DeleteItemMapping удаляет сопоставление позиции поставщика
+func (uc *SupplyImportUseCase) DeleteItemMapping(ctx context.Context, establishmentID, supplierID, id uuid.UUID) error {
+	if _, err := uc.supplierRepo.GetByID(ctx, supplierID, &establishmentID); err != nil {
+		return errors.New("supplier not found or access denied")
+	}
+	m, err := uc.supplierRepo.GetItemMapping(ctx, id, supplierID)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return errors.New("item mapping not found")
+	}
+	return uc.supplierRepo.DeleteItemMapping(ctx, id)
+}
@@ -0,0 +1,203 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+	"github.com/yourusername/arc/backend/pkg/imaging"
+	"github.com/yourusername/arc/backend/pkg/storage"
+)
+
+// ErrInvalidImage файл не является изображением поддерживаемого формата или поврежден
+var ErrInvalidImage = errors.New("invalid image")
+
+const (
+	// uploadOrphanGracePeriod сколько хранится изображение без ссылок: его могли загрузить до сохранения формы позиции
+	uploadOrphanGracePeriod = 24 * time.Hour
+	// uploadCleanupBatchSize сколько загрузок проверяется одним запросом
+	uploadCleanupBatchSize = 500
+)
+
+// UploadOwner позиция, для которой загружается изображение
+type UploadOwner struct {
+	EntityType string
+	EntityID   *uuid.UUID
+}
+
+// UploadUseCase обработка и учет загруженных изображений: проверка по содержимому, удаление метаданных,
+// уменьшенные варианты и очистка изображений, на которые больше не ссылается ни один CoverImage
+type UploadUseCase struct {
+	repo    repositories.UploadRepository
+	storage *storage.MinIOClient
+	options imaging.Options
+	logger  *zap.Logger
+}
+
+// NewUploadUseCase создает use case загрузок. cwebp и dwebp обязательны: без них не создать WebP-вариант
+// и не уменьшить загруженный WebP, поэтому при их отсутствии возвращается ошибка
+func NewUploadUseCase(repo repositories.UploadRepository, storageClient *storage.MinIOClient, cwebpPath, dwebpPath string, logger *zap.Logger) (*UploadUseCase, error) {
+	options := imaging.DefaultOptions()
+	encoder, err := imaging.NewCWebPEncoder(cwebpPath)
+	if err != nil {
+		return nil, fmt.Errorf("WebP encoder is required for image variants: %w", err)
+	}
+	options.WebP = encoder
+	decoder, err := imaging.NewDWebPDecoder(dwebpPath)
+	if err != nil {
+		return nil, fmt.Errorf("WebP decoder is required for image variants: %w", err)
+	}
+	options.WebPDecoder = decoder
+	return &UploadUseCase{
+		repo:    repo,
+		storage: storageClient,
+		options: options,
+		logger:  logger,
+	}, nil
+}
+
+// UploadImage проверяет изображение по сигнатуре, удаляет метаданные, готовит варианты (thumb, medium, WebP)
+// и сохраняет их в хранилище под общим префиксом. Ошибки содержимого файла оборачивают ErrInvalidImage.
+func (uc *UploadUseCase) UploadImage(ctx context.Context, data []byte, owner UploadOwner, uploadedBy *uuid.UUID) (*models.Upload, error) {
+	if owner.EntityType != "" && !models.IsValidUploadEntity(owner.EntityType) {
+		return nil, fmt.Errorf("%w: entity_type must be one of: product, tech_card, semi_finished, combo", ErrInvalidImage)
+	}
+	if owner.EntityType == "" && owner.EntityID != nil {
+		return nil, fmt.Errorf("%w: entity_id requires entity_type", ErrInvalidImage)
+	}
+	result, err := imaging.Process(data, uc.options)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	upload := &models.Upload{
+		ID:         uuid.New(),
+		EntityType: owner.EntityType,
+		EntityID:   owner.EntityID,
+		UploadedBy: uploadedBy,
+	}
+	upload.ObjectPrefix = fmt.Sprintf("images/%s/", upload.ID)
+	for _, v := range result.Variants {
+		url, err := uc.storage.PutObject(ctx, upload.ObjectPrefix+v.Name+v.Format.Extension(), v.Data, v.Format.ContentType())
+		if err != nil {
+			uc.removeObjects(ctx, upload.ObjectPrefix)
+			return nil, err
+		}
+		switch v.Name {
+		case imaging.VariantOriginal:
+			upload.URL = url
+			upload.ContentType = v.Format.ContentType()
+			upload.Size = int64(len(v.Data))
+			upload.Width = v.Width
+			upload.Height = v.Height
+		case imaging.VariantMedium:
+			upload.MediumURL = url
+		case imaging.VariantThumb:
+			upload.ThumbURL = url
+		case imaging.VariantWebP:
+			upload.WebPURL = url
+		}
+	}
+	if err := uc.repo.Create(ctx, upload); err != nil {
+		uc.removeObjects(ctx, upload.ObjectPrefix)
+		return nil, err
+	}
+	return upload, nil
+}
+
+// removeObjects удаляет объекты загрузки, которую не удалось сохранить
+func (uc *UploadUseCase) removeObjects(ctx context.Context, prefix string) {
+	if err := uc.storage.DeletePrefix(ctx, prefix); err != nil && uc.logger != nil {
+		uc.logger.Error("Failed to remove image objects", zap.String("prefix", prefix), zap.Error(err))
+	}
+}
+
+// ——— Cleanup ———
+
+// Run периодически (раз в interval) удаляет изображения без ссылок
+func (uc *UploadUseCase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, err := uc.CleanupOrphans(ctx, time.Now().Add(-uploadOrphanGracePeriod))
+			if err != nil && uc.logger != nil {
+				uc.logger.Error("Failed to clean up orphaned images", zap.Error(err))
+			} else if removed > 0 && uc.logger != nil {
+				uc.logger.Info("Removed orphaned images", zap.Int("count", removed))
+			}
+		}
+	}
+}
+
+// CleanupOrphans проверяет загрузки, созданные до createdBefore. Если ни один из URL загрузки не указан
+// в CoverImage товара, тех-карты, полуфабриката или комбо (удаленные позиции не учитываются),
+// объекты и запись удаляются; иначе у загрузки обновляется владелец. Возвращает число удаленных загрузок.
+func (uc *UploadUseCase) CleanupOrphans(ctx context.Context, createdBefore time.Time) (int, error) {
+	removed := 0
+	var cursor time.Time
+	for {
+		uploads, err := uc.repo.ListCreatedBetween(ctx, cursor, createdBefore, uploadCleanupBatchSize)
+		if err != nil {
+			return removed, err
+		}
+		if len(uploads) == 0 {
+			return removed, nil
+		}
+		cursor = uploads[len(uploads)-1].CreatedAt
+
+		urls := make([]string, 0, len(uploads)*4)
+		for _, u := range uploads {
+			urls = append(urls, u.URLs()...)
+		}
+		refs, err := uc.repo.FindReferences(ctx, urls)
+		if err != nil {
+			return removed, err
+		}
+		byURL := make(map[string]models.UploadReference, len(refs))
+		for _, ref := range refs {
+			byURL[ref.URL] = ref
+		}
+
+		now := time.Now()
+		for _, u := range uploads {
+			ref, referenced := uploadReference(u, byURL)
+			if referenced {
+				if err := uc.repo.UpdateOwner(ctx, u.ID, ref.EntityType, ref.EntityID, now); err != nil {
+					return removed, err
+				}
+				continue
+			}
+			if err := uc.storage.DeletePrefix(ctx, u.ObjectPrefix); err != nil {
+				return removed, err
+			}
+			if err := uc.repo.Delete(ctx, u.ID); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+		if len(uploads) < uploadCleanupBatchSize {
+			return removed, nil
+		}
+	}
+}
+
+// uploadReference ищет ссылку на любой из URL загрузки
+func uploadReference(u *models.Upload, byURL map[string]models.UploadReference) (models.UploadReference, bool) {
+	for _, url := range u.URLs() {
+		if ref, ok := byURL[url]; ok {
+			return ref, true
+		}
+	}
+	return models.UploadReference{}, false
+}
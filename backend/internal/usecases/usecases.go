@@ -28,9 +28,20 @@ type UseCases struct {
 	User                  *UserUseCase
 	Role                  *RoleUseCase // Добавлен новый UseCase для управления ролями
 	Inventory             *InventoryUseCase
+	StockAlert            *StockAlertUseCase
+	PurchaseOrder         *PurchaseOrderUseCase
+	SupplierPayment       *SupplierPaymentUseCase
+	Barcode               *BarcodeUseCase
+	SupplyImport          *SupplyImportUseCase
+	CostHistory           *CostHistoryUseCase
+	Repricing             *RepricingUseCase
+	Availability          *AvailabilityUseCase
+	Combo                 *ComboUseCase
+	MenuImport            *MenuImportUseCase
 	Salary                *SalaryUseCase
 	EmployeeStatistics     *EmployeeStatisticsUseCase
 	Storage               *storage.MinIOClient
+	Upload                *UploadUseCase
 	Marketing              *MarketingUseCase
 }
 
@@ -41,29 +52,44 @@ func NewUseCases(repos *repositories.Repositories, cfg *config.Config, logger *z
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize storage: %w", err)
 	}
+	uploadUseCase, err := NewUploadUseCase(repos.Upload, storageClient, cfg.Storage.CWebPPath, cfg.Storage.DWebPPath, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize uploads: %w", err)
+	}
 
 	accountUseCase := NewAccountUseCase(repos.Account, repos.AccountType)
 	userUseCase := NewUserUseCase(repos.User, repos.Role)
 	roleUseCase := NewRoleUseCase(repos.Role)
 	marketingUseCase := NewMarketingUseCase(repos.Client, repos.ClientGroup, repos.LoyaltyProgram, repos.Promotion, repos.Exclusion, logger)
 	shiftUseCase := NewShiftUseCase(repos.Shift, repos.ShiftSession, repos.User, repos.Transaction, repos.Account, repos.AccountType, repos.Order)
-	inventoryUseCase := NewInventoryUseCase(repos.Inventory, repos.Warehouse)
+	stockAlertUseCase := NewStockAlertUseCase(repos.StockAlert, repos.Warehouse, repos.Supplier, repos.PurchaseOrder, logger)
+	inventoryUseCase := NewInventoryUseCase(repos.Inventory, repos.Warehouse, stockAlertUseCase)
+
+	availabilityUseCase := NewAvailabilityUseCase(repos.Availability, repos.Category, repos.Establishment)
+	menuUseCase := NewMenuUseCase(repos.Product, repos.TechCard, repos.SemiFinished, repos.Ingredient, repos.Category, repos.IngredientCategory, repos.Warehouse, availabilityUseCase)
+	costHistoryUseCase := NewCostHistoryUseCase(repos.CostHistory, menuUseCase, repos.Warehouse, repos.Establishment, logger)
+	menuUseCase.costHistory = costHistoryUseCase
+	repricingUseCase := NewRepricingUseCase(repos.Repricing, repos.TechCard, repos.Product, repos.Category, repos.Warehouse, costHistoryUseCase, logger)
+	costHistoryUseCase.repricing = repricingUseCase
 
-	financeUseCase := NewFinanceUseCase(repos.Transaction, repos.Account, repos.Shift, repos.Order)
-	warehouseUseCase := NewWarehouseUseCase(repos.Warehouse, repos.Supplier, financeUseCase)
+	financeUseCase := NewFinanceUseCase(repos.Transaction, repos.Account, repos.Shift, repos.Order, repos.Warehouse, repos.Inventory)
+	warehouseUseCase := NewWarehouseUseCase(repos.Warehouse, repos.Supplier, repos.Transactor, financeUseCase, stockAlertUseCase, costHistoryUseCase)
+	comboUseCase := NewComboUseCase(repos.Combo, repos.Product, repos.TechCard, repos.Category)
+	orderUseCase := NewOrderUseCase(repos.Order, repos.Warehouse, repos.Transaction, accountUseCase, stockAlertUseCase, repos.Establishment, availabilityUseCase, comboUseCase)
 	salaryUseCase := NewSalaryUseCase(repos.User, repos.Role, repos.Shift, repos.Order)
 	employeeStatisticsUseCase := NewEmployeeStatisticsUseCase(repos.User, repos.Shift)
+	barcodeUseCase := NewBarcodeUseCase(repos.Barcode, repos.Warehouse, warehouseUseCase, inventoryUseCase, orderUseCase)
 
 	return &UseCases{
 		Auth:                NewAuthUseCase(repos.User, repos.Role, repos.Subscription, repos.Token, repos.Establishment, shiftUseCase, cfg),
 		Establishment:        NewEstablishmentUseCase(repos.Establishment, repos.Table, repos.Room),
 		Room:                NewRoomUseCase(repos.Room),
-		Menu:                NewMenuUseCase(repos.Product, repos.TechCard, repos.SemiFinished, repos.Ingredient, repos.Category, repos.IngredientCategory, repos.Warehouse),
+		Menu:                menuUseCase,
 		Warehouse:           warehouseUseCase,
 		Workshop:            NewWorkshopUseCase(repos.Workshop),
 		Finance:             financeUseCase,
-		Statistics:          NewStatisticsUseCase(repos.Order, repos.Product, repos.Category, repos.Client, repos.User, repos.Workshop, repos.Table, repos.Shift, logger),
-		Order:               NewOrderUseCase(repos.Order, repos.Warehouse, repos.Transaction, accountUseCase),
+		Statistics:          NewStatisticsUseCase(repos.Order, repos.Product, repos.Category, repos.Client, repos.User, repos.Workshop, repos.Table, repos.Shift, repos.CostHistory, logger),
+		Order:               orderUseCase,
 		Shift:               shiftUseCase,
 		Onboarding:          NewOnboardingUseCase(repos.Onboarding, repos.Establishment, repos.Table, repos.Room, repos.User, accountUseCase),
 		Account:             accountUseCase,
@@ -71,9 +97,20 @@ func NewUseCases(repos *repositories.Repositories, cfg *config.Config, logger *z
 		User:                userUseCase,
 		Role:                roleUseCase,
 		Inventory:           inventoryUseCase,
+		StockAlert:          stockAlertUseCase,
+		PurchaseOrder:       NewPurchaseOrderUseCase(repos.PurchaseOrder, repos.Warehouse, repos.Supplier, repos.Transactor, warehouseUseCase),
+		SupplierPayment:     NewSupplierPaymentUseCase(repos.SupplierPayment, repos.Supplier, repos.Transactor, financeUseCase),
+		Barcode:             barcodeUseCase,
+		CostHistory:         costHistoryUseCase,
+		Repricing:           repricingUseCase,
+		Availability:        availabilityUseCase,
+		Combo:               comboUseCase,
+		MenuImport:          NewMenuImportUseCase(menuUseCase, repos.Category, repos.IngredientCategory, repos.Ingredient, repos.SemiFinished, repos.Product, repos.TechCard, repos.Workshop, repos.Warehouse, repos.Transactor),
+		SupplyImport:        NewSupplyImportUseCase(repos.Supplier, repos.Warehouse, repos.Ingredient, repos.Product, barcodeUseCase, warehouseUseCase),
 		Salary:              salaryUseCase,
 		EmployeeStatistics:   employeeStatisticsUseCase,
 		Storage:             storageClient,
+		Upload:              uploadUseCase,
 		Marketing:            marketingUseCase,
 	}, nil
 }
\ No newline at end of file
@@ -14,14 +14,20 @@ import (
 type WarehouseUseCase struct {
 	repo         repositories.WarehouseRepository
 	supplierRepo repositories.SupplierRepository
+	transactor   repositories.Transactor // Документ, остатки, партии и журнал проводятся одной транзакцией
 	financeUC    *FinanceUseCase
+	stockAlerts  *StockAlertUseCase
+	costHistory  *CostHistoryUseCase
 }
 
-func NewWarehouseUseCase(repo repositories.WarehouseRepository, supplierRepo repositories.SupplierRepository, financeUC *FinanceUseCase) *WarehouseUseCase {
+func NewWarehouseUseCase(repo repositories.WarehouseRepository, supplierRepo repositories.SupplierRepository, transactor repositories.Transactor, financeUC *FinanceUseCase, stockAlerts *StockAlertUseCase, costHistory *CostHistoryUseCase) *WarehouseUseCase {
 	return &WarehouseUseCase{
 		repo:         repo,
 		supplierRepo: supplierRepo,
+		transactor:   transactor,
 		financeUC:    financeUC,
+		stockAlerts:  stockAlerts,
+		costHistory:  costHistory,
 	}
 }
 
@@ -103,7 +109,98 @@ func (uc *WarehouseUseCase) UpdateStockLimit(ctx context.Context, id uuid.UUID,
 	if err != nil || warehouse == nil {
 		return errors.New("stock not found or access denied")
 	}
-	return uc.repo.UpdateStockLimit(ctx, id, limit)
+	if err := uc.repo.UpdateStockLimit(ctx, id, limit); err != nil {
+		return err
+	}
+	uc.stockAlerts.Notify(establishmentID)
+	return nil
+}
+
+// GetStockLots возвращает партии FIFO с их себестоимостью
+func (uc *WarehouseUseCase) GetStockLots(ctx context.Context, establishmentID uuid.UUID, filter *repositories.StockLotFilter) ([]*models.StockLot, error) {
+	return uc.repo.GetStockLots(ctx, establishmentID, filter)
+}
+
+// resolveStock находит остаток позиции на складе и множитель для перевода количества позиции документа
+// из unit в единицу учета остатка. Если остатка еще нет, единицей учета становится единица ингредиента.
+// Для ингредиентов учитываются их пользовательские пересчеты (например, 1 шт = 0.05 кг).
+func (uc *WarehouseUseCase) resolveStock(ctx context.Context, warehouseID uuid.UUID, ingredientID, productID *uuid.UUID, unit string) (*models.Stock, string, float64, error) {
+	var st *models.Stock
+	var ingredient *models.Ingredient
+	if ingredientID != nil {
+		st, _ = uc.repo.GetStockByIngredientAndWarehouse(ctx, *ingredientID, warehouseID)
+		ing, err := uc.repo.GetIngredientByID(ctx, *ingredientID)
+		if err != nil || ing == nil {
+			return nil, "", 0, errors.New("ingredient not found")
+		}
+		ingredient = ing
+	} else if productID != nil {
+		product, err := uc.repo.GetProductByID(ctx, *productID)
+		if err != nil || product == nil {
+			return nil, "", 0, errors.New("product not found")
+		}
+		// Остатки товара с модификациями ведутся по модификациям
+		byVariants, err := soldByVariants(product, func() (int64, error) {
+			return uc.repo.CountProductVariants(ctx, product.ID)
+		})
+		if err != nil {
+			return nil, "", 0, err
+		}
+		if byVariants {
+			return nil, "", 0, fmt.Errorf("%w: %s", ErrProductHasVariants, product.Name)
+		}
+		st, _ = uc.repo.GetStockByProductAndWarehouse(ctx, *productID, warehouseID)
+	}
+
+	stockUnit := unit
+	if st != nil && st.Unit != "" {
+		stockUnit = st.Unit
+	} else if ingredient != nil {
+		stockUnit = ingredient.Unit
+	}
+	if stockUnit == "" {
+		stockUnit = models.UnitPiece
+	}
+	if unit == "" {
+		return st, stockUnit, 1, nil
+	}
+
+	if ingredient != nil {
+		factor, err := ingredient.ConvertQuantity(1, unit, stockUnit)
+		if err != nil {
+			return nil, "", 0, fmt.Errorf("ingredient %q: %w", ingredient.Name, err)
+		}
+		return st, stockUnit, factor, nil
+	}
+	factor, err := stockUnitFactor(unit, stockUnit)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	return st, stockUnit, factor, nil
+}
+
+// stockDemand суммирует расход документа по остаткам. Строки с одной и той же позицией получают общий
+// *models.Stock: достаточность проверяется по суммарному количеству, и списание одной строки
+// не перезаписывается сохранением другой
+type stockDemand struct {
+	stocks map[uuid.UUID]*models.Stock
+	needed map[uuid.UUID]float64
+}
+
+func newStockDemand() *stockDemand {
+	return &stockDemand{stocks: make(map[uuid.UUID]*models.Stock), needed: make(map[uuid.UUID]float64)}
+}
+
+// add учитывает расход quantity по остатку st и возвращает общий для документа остаток позиции.
+// false — суммарный расход по позиции превышает остаток
+func (d *stockDemand) add(st *models.Stock, quantity float64) (*models.Stock, bool) {
+	if shared, ok := d.stocks[st.ID]; ok {
+		st = shared
+	} else {
+		d.stocks[st.ID] = st
+	}
+	d.needed[st.ID] += quantity
+	return st, st.Quantity >= d.needed[st.ID]
 }
 
 // ——— Supply (поставка: создаём документ и увеличиваем остатки) ———
@@ -117,67 +214,115 @@ func (uc *WarehouseUseCase) CreateSupply(ctx context.Context, supply *models.Sup
 	if err != nil || sup == nil {
 		return errors.New("supplier not found or access denied")
 	}
-	_ = sup
+	// Срок оплаты фиксируется при создании, чтобы изменение отсрочки поставщика не сдвигало старые счета
+	if supply.DueDate == nil {
+		due := supply.DueDateFor(sup.PaymentTermDays)
+		supply.DueDate = &due
+	}
 
-	if err := uc.repo.CreateSupply(ctx, supply); err != nil {
-		return err
+	// Проверяем единицы позиций до создания документа
+	for _, it := range supply.Items {
+		if _, _, _, err := uc.resolveStock(ctx, supply.WarehouseID, it.IngredientID, it.ProductID, it.Unit); err != nil {
+			return err
+		}
 	}
 
-	// Создаем транзакцию оплаты, если указан счет и сумма оплаты
-	if supply.PaymentStatus == "paid" || supply.PaymentStatus == "partial" {
-		if supply.AccountID != nil && supply.PaymentAmount > 0 {
-			paymentDate := time.Now()
-			if supply.PaymentDate != nil {
-				paymentDate = *supply.PaymentDate
-			}
+	// Документ, оплата, остатки, партии и журнал сохраняются одной транзакцией
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.repo.CreateSupply(ctx, supply); err != nil {
+			return err
+		}
+		if err := uc.createSupplyPayment(ctx, supply, establishmentID); err != nil {
+			return err
+		}
+		if supply.Status == "completed" {
+			return uc.postSupply(ctx, supply)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if supply.Status == "completed" {
+		uc.notifySupplyPosted(ctx, establishmentID, supply.ID)
+	}
+	return nil
+}
 
-			description := fmt.Sprintf("Оплата поставки #%s от поставщика", supply.ID.String())
-			if supply.InvoiceNumber != "" {
-				description = fmt.Sprintf("Оплата счета %s от поставщика", supply.InvoiceNumber)
-			}
+// createSupplyPayment создает транзакцию оплаты, если указан счет и сумма оплаты
+func (uc *WarehouseUseCase) createSupplyPayment(ctx context.Context, supply *models.Supply, establishmentID uuid.UUID) error {
+	if supply.PaymentStatus != "paid" && supply.PaymentStatus != "partial" {
+		return nil
+	}
+	if supply.AccountID == nil || supply.PaymentAmount <= 0 {
+		return nil
+	}
+	paymentDate := time.Now()
+	if supply.PaymentDate != nil {
+		paymentDate = *supply.PaymentDate
+	}
 
-			transaction := &models.Transaction{
-				AccountID:       *supply.AccountID,
-				Type:            "expense",
-				Category:        "supply_payment",
-				Amount:          supply.PaymentAmount,
-				Description:     description,
-				TransactionDate: paymentDate,
-			}
+	description := fmt.Sprintf("Оплата поставки #%s от поставщика", supply.ID.String())
+	if supply.InvoiceNumber != "" {
+		description = fmt.Sprintf("Оплата счета %s от поставщика", supply.InvoiceNumber)
+	}
 
-			if err := uc.financeUC.CreateTransaction(ctx, transaction, establishmentID); err != nil {
-				// Если не удалось создать транзакцию, откатываем создание поставки
-				_ = uc.repo.DeleteSupply(ctx, supply.ID)
-				return fmt.Errorf("failed to create payment transaction: %w", err)
-			}
-		}
+	transaction := &models.Transaction{
+		AccountID:       *supply.AccountID,
+		Type:            "expense",
+		Category:        "supply_payment",
+		Amount:          supply.PaymentAmount,
+		Description:     description,
+		TransactionDate: paymentDate,
+	}
+	if err := uc.financeUC.CreateTransaction(ctx, transaction, establishmentID); err != nil {
+		return fmt.Errorf("failed to create payment transaction: %w", err)
 	}
+	return nil
+}
 
+// notifySupplyPosted запускает фоновые проверки после проведения поставки.
+// Поставка может проводиться во внешней транзакции (приемка заказа поставщику),
+// поэтому обработчики получают сигнал только после ее фиксации
+func (uc *WarehouseUseCase) notifySupplyPosted(ctx context.Context, establishmentID, supplyID uuid.UUID) {
+	uc.transactor.AfterCommit(ctx, func() {
+		uc.stockAlerts.Notify(establishmentID)
+		// Закупочные цены обновлены — пересчитываем себестоимость меню в фоне
+		uc.costHistory.NotifySupply(establishmentID, supplyID)
+	})
+}
+
+// postSupply проводит поставку: увеличивает остатки, записывает движения в журнал и создает партии FIFO.
+// Вызывается один раз, когда поставка становится проведенной (status = completed)
+func (uc *WarehouseUseCase) postSupply(ctx context.Context, supply *models.Supply) error {
 	for _, it := range supply.Items {
-		var st *models.Stock
-		if it.IngredientID != nil {
-			st, _ = uc.repo.GetStockByIngredientAndWarehouse(ctx, *it.IngredientID, supply.WarehouseID)
-		} else if it.ProductID != nil {
-			st, _ = uc.repo.GetStockByProductAndWarehouse(ctx, *it.ProductID, supply.WarehouseID)
+		// Количество и цена позиции переводятся в единицу учета остатка (например, 500 г → 0.5 кг)
+		st, stockUnit, factor, err := uc.resolveStock(ctx, supply.WarehouseID, it.IngredientID, it.ProductID, it.Unit)
+		if err != nil {
+			return err
 		}
+		quantity := it.Quantity * factor
+		itemPrice := it.PricePerUnit / factor
+		ledger := stockLedgerSource{Type: models.StockLedgerSupply, ID: supply.ID, At: supply.DeliveryDateTime}
 		if st != nil {
-			st.Quantity += it.Quantity
+			st.Quantity += quantity
 			// Обновляем цену за единицу из поставки, если она указана
-			if it.PricePerUnit > 0 {
-				st.PricePerUnit = it.PricePerUnit
+			if itemPrice > 0 {
+				st.PricePerUnit = itemPrice
 			} else if it.ProductID != nil && st.PricePerUnit == 0 {
 				// Если цена в поставке не указана и текущая цена 0, берем цену из товара
 				if product, err := uc.repo.GetProductByID(ctx, *it.ProductID); err == nil && product != nil {
 					st.PricePerUnit = product.Price
 				}
 			}
-			_ = uc.repo.UpdateStock(ctx, st)
-		} else {
-			unit := it.Unit
-			if unit == "" {
-				unit = "шт"
+			if err := uc.repo.UpdateStock(ctx, st); err != nil {
+				return err
+			}
+			if err := postStockLedger(ctx, uc.repo, st, quantity, quantity*itemPrice, ledger); err != nil {
+				return err
 			}
-			pricePerUnit := it.PricePerUnit
+		} else {
+			pricePerUnit := itemPrice
 			// Если цена в поставке не указана и это товар, берем цену из товара
 			if pricePerUnit == 0 && it.ProductID != nil {
 				if product, err := uc.repo.GetProductByID(ctx, *it.ProductID); err == nil && product != nil {
@@ -188,11 +333,63 @@ func (uc *WarehouseUseCase) CreateSupply(ctx context.Context, supply *models.Sup
 				WarehouseID:  supply.WarehouseID,
 				IngredientID: it.IngredientID,
 				ProductID:    it.ProductID,
-				Quantity:     it.Quantity,
-				Unit:         unit,
+				Quantity:     quantity,
+				Unit:         stockUnit,
 				PricePerUnit: pricePerUnit,
 			}
-			_ = uc.repo.CreateStock(ctx, newSt)
+			if err := uc.repo.CreateStock(ctx, newSt); err != nil {
+				return err
+			}
+			if err := postStockLedger(ctx, uc.repo, newSt, quantity, quantity*itemPrice, ledger); err != nil {
+				return err
+			}
+		}
+	}
+
+	return uc.createSupplyLots(ctx, supply)
+}
+
+// createSupplyLots создает партии FIFO по позициям проведенной поставки
+func (uc *WarehouseUseCase) createSupplyLots(ctx context.Context, supply *models.Supply) error {
+	for _, it := range supply.Items {
+		// Партия ведется в единице учета остатка
+		st, unit, factor, err := uc.resolveStock(ctx, supply.WarehouseID, it.IngredientID, it.ProductID, it.Unit)
+		if err != nil {
+			return err
+		}
+		unitCost := it.PricePerUnit / factor
+		if unitCost == 0 && st != nil {
+			// Цена не указана в поставке — берем себестоимость из остатка
+			unitCost = st.PricePerUnit
+		}
+		quantity := it.Quantity * factor
+
+		// Срок годности: из позиции поставки или по сроку годности ингредиента
+		shelfLifeDays := 0
+		if it.ExpiryDate == nil && it.IngredientID != nil {
+			if ing, err := uc.repo.GetIngredientByID(ctx, *it.IngredientID); err == nil && ing != nil {
+				shelfLifeDays = ing.ShelfLifeDays
+			}
+		}
+
+		supplyID := supply.ID
+		supplyItemID := it.ID
+		lot := &models.StockLot{
+			WarehouseID:       supply.WarehouseID,
+			IngredientID:      it.IngredientID,
+			ProductID:         it.ProductID,
+			SourceType:        models.StockLotSourceSupply,
+			SourceID:          &supplyID,
+			SupplyItemID:      &supplyItemID,
+			Quantity:          quantity,
+			RemainingQuantity: quantity,
+			Unit:              unit,
+			UnitCost:          unitCost,
+			ReceivedAt:        supply.DeliveryDateTime,
+			ExpiresAt:         models.StockLotExpiry(it.ExpiryDate, it.ProductionDate, supply.DeliveryDateTime, shelfLifeDays),
+		}
+		if err := uc.repo.CreateStockLot(ctx, lot); err != nil {
+			return fmt.Errorf("failed to create stock lot: %w", err)
 		}
 	}
 	return nil
@@ -217,10 +414,77 @@ func (uc *WarehouseUseCase) UpdateSupply(ctx context.Context, supply *models.Sup
 	if err != nil || sup == nil {
 		return errors.New("supplier not found or access denied")
 	}
-	_ = sup
 
-	// Обновляем поставку
-	return uc.repo.UpdateSupply(ctx, supply)
+	// Оплата ведется платежами поставщику: сумма, статус и дата оплаты при обновлении не меняются
+	if existingSupply.PaymentAmount > 0 && supply.SupplierID != existingSupply.SupplierID {
+		return errors.New("supply with payments cannot change supplier")
+	}
+	supply.PaymentAmount = existingSupply.PaymentAmount
+	supply.PaymentDate = existingSupply.PaymentDate
+	supply.PaymentStatus = existingSupply.PaymentStatus
+	if supply.PaymentAmount > 0 {
+		// Сумма по счету могла измениться — статус пересчитывается от уже оплаченной суммы
+		supply.PaymentStatus = supply.PaymentStatusFor(supply.PaymentAmount)
+	}
+
+	// Срок оплаты считается от даты счета (или поставки) и отсрочки поставщика — пересчитываем при их изменении
+	if supply.DueDate == nil || supply.SupplierID != existingSupply.SupplierID ||
+		!supply.DueDateFor(0).Equal(existingSupply.DueDateFor(0)) {
+		due := supply.DueDateFor(sup.PaymentTermDays)
+		supply.DueDate = &due
+	}
+
+	// Проведенная поставка уже изменила остатки и партии: ее нельзя вернуть в другой статус,
+	// перенести на другой склад или изменить позиции
+	if existingSupply.Status == "completed" {
+		if supply.Status != "completed" {
+			return errors.New("completed supply cannot change status")
+		}
+		if supply.WarehouseID != existingSupply.WarehouseID || !supplyItemsEqual(existingSupply.Items, supply.Items) {
+			return errors.New("completed supply items and warehouse cannot be changed")
+		}
+	}
+
+	// Проверяем единицы позиций до обновления документа
+	for _, it := range supply.Items {
+		if _, _, _, err := uc.resolveStock(ctx, supply.WarehouseID, it.IngredientID, it.ProductID, it.Unit); err != nil {
+			return err
+		}
+	}
+
+	// Поставка проведена — приходуем остатки и создаем партии по ее позициям
+	// в одной транзакции с обновлением документа
+	posting := existingSupply.Status != "completed" && supply.Status == "completed"
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.repo.UpdateSupply(ctx, supply); err != nil {
+			return err
+		}
+		if posting {
+			return uc.postSupply(ctx, supply)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if posting {
+		uc.notifySupplyPosted(ctx, establishmentID, supply.ID)
+	}
+	return nil
+}
+
+// supplyItemsEqual сравнивает позиции поставки по товару, количеству, единице и цене
+func supplyItemsEqual(a, b []models.SupplyItem) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !sameItemID(a[i].IngredientID, b[i].IngredientID) || !sameItemID(a[i].ProductID, b[i].ProductID) ||
+			a[i].Quantity != b[i].Quantity || a[i].Unit != b[i].Unit || a[i].PricePerUnit != b[i].PricePerUnit {
+			return false
+		}
+	}
+	return true
 }
 
 // ——— WriteOff (списание: создаём документ и уменьшаем остатки) ———
@@ -229,32 +493,478 @@ func (uc *WarehouseUseCase) CreateWriteOff(ctx context.Context, writeOff *models
 	if _, err := uc.repo.GetWarehouseByID(ctx, writeOff.WarehouseID, &establishmentID); err != nil {
 		return errors.New("warehouse not found or access denied")
 	}
-
-	if err := uc.repo.CreateWriteOff(ctx, writeOff); err != nil {
+	// Остатки, партии, журнал и документ сохраняются вместе: ошибка не оставляет списание проведенным частично
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		return uc.postWriteOff(ctx, writeOff)
+	})
+	if err != nil {
 		return err
 	}
+	uc.stockAlerts.Notify(establishmentID)
+	return nil
+}
 
-	for _, it := range writeOff.Items {
-		var st *models.Stock
-		if it.IngredientID != nil {
-			st, _ = uc.repo.GetStockByIngredientAndWarehouse(ctx, *it.IngredientID, writeOff.WarehouseID)
-		} else if it.ProductID != nil {
-			st, _ = uc.repo.GetStockByProductAndWarehouse(ctx, *it.ProductID, writeOff.WarehouseID)
+// postWriteOff уменьшает остатки по позициям списания, расходует партии и сохраняет документ
+func (uc *WarehouseUseCase) postWriteOff(ctx context.Context, writeOff *models.WriteOff) error {
+	// Сначала проверяем все позиции, чтобы не списать документ частично
+	stocks := make([]*models.Stock, len(writeOff.Items))
+	quantities := make([]float64, len(writeOff.Items)) // количество в единицах остатка
+	demand := newStockDemand()
+	for i, it := range writeOff.Items {
+		st, _, factor, err := uc.resolveStock(ctx, writeOff.WarehouseID, it.IngredientID, it.ProductID, it.Unit)
+		if err != nil {
+			return err
 		}
 		if st == nil {
 			return errors.New("stock entry not found for write-off item")
 		}
-		if st.Quantity < it.Quantity {
+		quantities[i] = it.Quantity * factor
+		shared, ok := demand.add(st, quantities[i])
+		if !ok {
 			return errors.New("insufficient stock for write-off")
 		}
-		st.Quantity -= it.Quantity
+		stocks[i] = shared
+	}
+
+	if writeOff.ID == uuid.Nil {
+		writeOff.ID = uuid.New()
+	}
+	doc := stockDocument{Type: models.StockConsumptionWriteOff, ID: writeOff.ID, At: writeOff.WriteOffDateTime}
+
+	// Уменьшаем остатки и расходуем партии по FIFO, фиксируя фактическую себестоимость
+	total := 0.0
+	for i := range writeOff.Items {
+		st := stocks[i]
+		cost, err := consumeStockLots(ctx, uc.repo, st, quantities[i], doc)
+		if err != nil {
+			return err
+		}
+		st.Quantity -= quantities[i]
 		if err := uc.repo.UpdateStock(ctx, st); err != nil {
 			return err
 		}
+		if err := postStockLedger(ctx, uc.repo, st, -quantities[i], cost, stockLedgerSource{Type: models.StockLedgerWriteOff, ID: writeOff.ID, At: writeOff.WriteOffDateTime}); err != nil {
+			return err
+		}
+		writeOff.Items[i].TotalAmount = cost
+		if writeOff.Items[i].Quantity > 0 {
+			writeOff.Items[i].PricePerUnit = models.RoundTo2(cost / writeOff.Items[i].Quantity)
+		}
+		total += cost
+	}
+	writeOff.TotalAmount = total
+	return uc.repo.CreateWriteOff(ctx, writeOff)
+}
+
+// ——— Transfer (перемещение: списываем со склада-отправителя и приходуем на склад-получатель) ———
+
+// CreateTransfer создает перемещение между складами. Если статус не указан, документ создается черновиком.
+func (uc *WarehouseUseCase) CreateTransfer(ctx context.Context, transfer *models.Transfer, establishmentID uuid.UUID) error {
+	if transfer.SourceWarehouseID == transfer.TargetWarehouseID {
+		return errors.New("source and target warehouses must be different")
+	}
+	if w, err := uc.repo.GetWarehouseByID(ctx, transfer.SourceWarehouseID, &establishmentID); err != nil || w == nil {
+		return errors.New("source warehouse not found or access denied")
+	}
+	if w, err := uc.repo.GetWarehouseByID(ctx, transfer.TargetWarehouseID, &establishmentID); err != nil || w == nil {
+		return errors.New("target warehouse not found or access denied")
+	}
+
+	targetStatus := transfer.Status
+	if targetStatus == "" {
+		targetStatus = models.TransferStatusDraft
+	}
+	if targetStatus != models.TransferStatusDraft && targetStatus != models.TransferStatusSent && targetStatus != models.TransferStatusReceived {
+		return fmt.Errorf("invalid transfer status: %s", targetStatus)
+	}
+
+	// Документ всегда создается черновиком, движение остатков выполняется через смену статуса
+	// в той же транзакции: при ошибке не остается ни документа, ни частичного движения
+	transfer.Status = models.TransferStatusDraft
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.repo.CreateTransfer(ctx, transfer); err != nil {
+			return err
+		}
+		if targetStatus == models.TransferStatusDraft {
+			return nil
+		}
+		return uc.updateTransferStatus(ctx, transfer.ID, targetStatus, establishmentID)
+	})
+	if err != nil {
+		return err
+	}
+	if targetStatus != models.TransferStatusDraft {
+		uc.stockAlerts.Notify(establishmentID)
 	}
 	return nil
 }
 
+// UpdateTransferStatus переводит перемещение в новый статус.
+// draft → sent: остатки списываются со склада-отправителя, себестоимость фиксируется по его партиям (FIFO).
+// sent → received: остатки приходуются на склад-получатель по зафиксированной себестоимости.
+// draft → received выполняет оба шага сразу. Списание и приход проводятся одной транзакцией,
+// поэтому остаток не может уйти со склада-отправителя, не поступив на склад-получатель.
+func (uc *WarehouseUseCase) UpdateTransferStatus(ctx context.Context, id uuid.UUID, status models.TransferStatus, establishmentID uuid.UUID) error {
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		return uc.updateTransferStatus(ctx, id, status, establishmentID)
+	})
+	if err != nil {
+		return err
+	}
+	uc.stockAlerts.Notify(establishmentID)
+	return nil
+}
+
+func (uc *WarehouseUseCase) updateTransferStatus(ctx context.Context, id uuid.UUID, status models.TransferStatus, establishmentID uuid.UUID) error {
+	transfer, err := uc.repo.GetTransferByID(ctx, id, &establishmentID)
+	if err != nil || transfer == nil {
+		return errors.New("transfer not found or access denied")
+	}
+
+	switch {
+	case transfer.Status == models.TransferStatusDraft && status == models.TransferStatusSent:
+		if err := uc.sendTransfer(ctx, transfer); err != nil {
+			return err
+		}
+	case transfer.Status == models.TransferStatusSent && status == models.TransferStatusReceived:
+		if err := uc.receiveTransfer(ctx, transfer); err != nil {
+			return err
+		}
+	case transfer.Status == models.TransferStatusDraft && status == models.TransferStatusReceived:
+		if err := uc.sendTransfer(ctx, transfer); err != nil {
+			return err
+		}
+		if err := uc.receiveTransfer(ctx, transfer); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid status transition from %s to %s", transfer.Status, status)
+	}
+	return uc.repo.UpdateTransfer(ctx, transfer)
+}
+
+// sendTransfer списывает позиции со склада-отправителя и фиксирует их себестоимость
+func (uc *WarehouseUseCase) sendTransfer(ctx context.Context, transfer *models.Transfer) error {
+	// Сначала проверяем все позиции, чтобы не списать документ частично
+	stocks := make([]*models.Stock, len(transfer.Items))
+	quantities := make([]float64, len(transfer.Items)) // количество в единицах остатка
+	demand := newStockDemand()
+	for i, it := range transfer.Items {
+		st, _, factor, err := uc.resolveStock(ctx, transfer.SourceWarehouseID, it.IngredientID, it.ProductID, it.Unit)
+		if err != nil {
+			return err
+		}
+		if st == nil {
+			return errors.New("stock entry not found on source warehouse for transfer item")
+		}
+		quantities[i] = it.Quantity * factor
+		shared, ok := demand.add(st, quantities[i])
+		if !ok {
+			return errors.New("insufficient stock on source warehouse for transfer")
+		}
+		stocks[i] = shared
+	}
+
+	now := time.Now()
+	doc := stockDocument{Type: models.StockConsumptionTransfer, ID: transfer.ID, At: now}
+
+	// Себестоимость перемещения — фактическая себестоимость партий склада-отправителя (FEFO/FIFO)
+	total := 0.0
+	for i := range transfer.Items {
+		st := stocks[i]
+		// Партия на складе-получателе наследует ближайший срок годности отправленных партий
+		expiresAt, err := nearestStockLotExpiry(ctx, uc.repo, st)
+		if err != nil {
+			return err
+		}
+		transfer.Items[i].ExpiresAt = expiresAt
+		cost, err := consumeStockLots(ctx, uc.repo, st, quantities[i], doc)
+		if err != nil {
+			return err
+		}
+		st.Quantity -= quantities[i]
+		if err := uc.repo.UpdateStock(ctx, st); err != nil {
+			return err
+		}
+		if err := postStockLedger(ctx, uc.repo, st, -quantities[i], cost, stockLedgerSource{Type: models.StockLedgerTransferOut, ID: transfer.ID, At: now}); err != nil {
+			return err
+		}
+		transfer.Items[i].TotalAmount = cost
+		if transfer.Items[i].Quantity > 0 {
+			transfer.Items[i].PricePerUnit = models.RoundTo2(cost / transfer.Items[i].Quantity)
+		}
+		total += cost
+	}
+
+	transfer.TotalAmount = total
+	transfer.SentAt = &now
+	transfer.Status = models.TransferStatusSent
+	return nil
+}
+
+// receiveTransfer приходует позиции на склад-получатель по себестоимости склада-отправителя
+func (uc *WarehouseUseCase) receiveTransfer(ctx context.Context, transfer *models.Transfer) error {
+	// Сначала проверяем, что единицы позиций приводятся к единицам остатков склада-получателя
+	for _, it := range transfer.Items {
+		if _, _, _, err := uc.resolveStock(ctx, transfer.TargetWarehouseID, it.IngredientID, it.ProductID, it.Unit); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for _, it := range transfer.Items {
+		// Остаток читается заново для каждой позиции: строки с одной позицией приходуются на один остаток
+		st, unit, factor, err := uc.resolveStock(ctx, transfer.TargetWarehouseID, it.IngredientID, it.ProductID, it.Unit)
+		if err != nil {
+			return err
+		}
+		quantity := it.Quantity * factor
+		unitCost := it.PricePerUnit / factor
+		// Партия на складе-получателе несет себестоимость склада-отправителя
+		transferID := transfer.ID
+		if err := uc.repo.CreateStockLot(ctx, &models.StockLot{
+			WarehouseID:       transfer.TargetWarehouseID,
+			IngredientID:      it.IngredientID,
+			ProductID:         it.ProductID,
+			SourceType:        models.StockLotSourceTransfer,
+			SourceID:          &transferID,
+			Quantity:          quantity,
+			RemainingQuantity: quantity,
+			Unit:              unit,
+			UnitCost:          unitCost,
+			ReceivedAt:        now,
+			ExpiresAt:         it.ExpiresAt,
+		}); err != nil {
+			return fmt.Errorf("failed to create stock lot: %w", err)
+		}
+
+		ledger := stockLedgerSource{Type: models.StockLedgerTransferIn, ID: transfer.ID, At: now}
+		if st != nil {
+			st.Quantity += quantity
+			if unitCost > 0 {
+				st.PricePerUnit = unitCost
+			}
+			if err := uc.repo.UpdateStock(ctx, st); err != nil {
+				return err
+			}
+			if err := postStockLedger(ctx, uc.repo, st, quantity, quantity*unitCost, ledger); err != nil {
+				return err
+			}
+			continue
+		}
+		newSt := &models.Stock{
+			WarehouseID:  transfer.TargetWarehouseID,
+			IngredientID: it.IngredientID,
+			ProductID:    it.ProductID,
+			Quantity:     quantity,
+			Unit:         unit,
+			PricePerUnit: unitCost,
+		}
+		if err := uc.repo.CreateStock(ctx, newSt); err != nil {
+			return err
+		}
+		if err := postStockLedger(ctx, uc.repo, newSt, quantity, quantity*unitCost, ledger); err != nil {
+			return err
+		}
+	}
+
+	transfer.ReceivedAt = &now
+	transfer.Status = models.TransferStatusReceived
+	return nil
+}
+
+// GetTransfers возвращает список перемещений (склад может быть отправителем или получателем)
+func (uc *WarehouseUseCase) GetTransfers(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID) ([]*models.Transfer, error) {
+	return uc.repo.GetTransfersByWarehouse(ctx, establishmentID, warehouseID)
+}
+
+// GetTransfer возвращает перемещение по ID
+func (uc *WarehouseUseCase) GetTransfer(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) (*models.Transfer, error) {
+	return uc.repo.GetTransferByID(ctx, id, &establishmentID)
+}
+
+// ——— Production (производство полуфабрикатов: списываем ингредиенты и приходуем выход) ———
+
+// CreateProduction проводит производство полуфабриката на складе.
+// Ингредиенты списываются по рецептуре полуфабриката пропорционально выходу (брутто, если указано, иначе нетто),
+// их фактическая себестоимость (FIFO) делится на выход и становится себестоимостью партии полуфабриката.
+func (uc *WarehouseUseCase) CreateProduction(ctx context.Context, production *models.Production, establishmentID uuid.UUID) error {
+	if w, err := uc.repo.GetWarehouseByID(ctx, production.WarehouseID, &establishmentID); err != nil || w == nil {
+		return errors.New("warehouse not found or access denied")
+	}
+	semiFinished, err := uc.repo.GetSemiFinishedByID(ctx, production.SemiFinishedID, &establishmentID)
+	if err != nil || semiFinished == nil {
+		return errors.New("semi-finished product not found or access denied")
+	}
+	if production.Quantity <= 0 {
+		return errors.New("production quantity must be positive")
+	}
+	if semiFinished.Quantity <= 0 {
+		return fmt.Errorf("semi-finished product %q has no yield quantity", semiFinished.Name)
+	}
+	if len(semiFinished.Ingredients) == 0 {
+		return fmt.Errorf("semi-finished product %q has no ingredients", semiFinished.Name)
+	}
+
+	// Выход ведется в единице полуфабриката
+	outputUnit := models.NormalizeUnit(semiFinished.Unit)
+	if production.Unit != "" {
+		factor, err := stockUnitFactor(production.Unit, outputUnit)
+		if err != nil {
+			return fmt.Errorf("semi-finished product %q: %w", semiFinished.Name, err)
+		}
+		production.Quantity *= factor
+	}
+	production.Unit = outputUnit
+	batch := production.Quantity / semiFinished.Quantity
+
+	// Сначала проверяем все ингредиенты, чтобы не провести документ частично
+	stocks := make([]*models.Stock, len(semiFinished.Ingredients))
+	quantities := make([]float64, len(semiFinished.Ingredients)) // количество в единицах остатка
+	demand := newStockDemand()
+	for i, ing := range semiFinished.Ingredients {
+		qty := ing.GrossQuantity()
+		ingredientID := ing.IngredientID
+		st, _, factor, err := uc.resolveStock(ctx, production.WarehouseID, &ingredientID, nil, ing.Unit)
+		if err != nil {
+			return err
+		}
+		name := ingredientID.String()
+		if ing.Ingredient != nil {
+			name = ing.Ingredient.Name
+		}
+		if st == nil {
+			return fmt.Errorf("stock entry not found for ingredient %q", name)
+		}
+		quantities[i] = qty * batch * factor
+		shared, ok := demand.add(st, quantities[i])
+		if !ok {
+			return fmt.Errorf("insufficient stock of ingredient %q for production", name)
+		}
+		stocks[i] = shared
+	}
+
+	if production.ID == uuid.Nil {
+		production.ID = uuid.New()
+	}
+	if production.ProductionDateTime.IsZero() {
+		production.ProductionDateTime = time.Now()
+	}
+
+	// Списание ингредиентов, приход выхода, партия и документ проводятся одной транзакцией
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		return uc.postProduction(ctx, production, semiFinished, stocks, quantities)
+	})
+	if err != nil {
+		return err
+	}
+	uc.stockAlerts.Notify(establishmentID)
+	return nil
+}
+
+// postProduction списывает ингредиенты (quantities — в единицах остатков stocks) и приходует выход производства
+func (uc *WarehouseUseCase) postProduction(ctx context.Context, production *models.Production, semiFinished *models.SemiFinishedProduct, stocks []*models.Stock, quantities []float64) error {
+	doc := stockDocument{Type: models.StockConsumptionProduction, ID: production.ID, At: production.ProductionDateTime}
+
+	// Списываем ингредиенты по FIFO, фиксируя фактическую себестоимость
+	total := 0.0
+	items := make([]models.ProductionItem, 0, len(semiFinished.Ingredients))
+	for i, ing := range semiFinished.Ingredients {
+		st := stocks[i]
+		cost, err := consumeStockLots(ctx, uc.repo, st, quantities[i], doc)
+		if err != nil {
+			return err
+		}
+		st.Quantity -= quantities[i]
+		if err := uc.repo.UpdateStock(ctx, st); err != nil {
+			return err
+		}
+		if err := postStockLedger(ctx, uc.repo, st, -quantities[i], cost, stockLedgerSource{Type: models.StockLedgerProductionOut, ID: production.ID, At: production.ProductionDateTime}); err != nil {
+			return err
+		}
+		item := models.ProductionItem{
+			IngredientID: ing.IngredientID,
+			Quantity:     quantities[i],
+			Unit:         st.Unit,
+			TotalAmount:  cost,
+		}
+		if quantities[i] > 0 {
+			item.PricePerUnit = cost / quantities[i]
+		}
+		items = append(items, item)
+		total += cost
+	}
+	production.Items = items
+	production.TotalCost = total
+	production.UnitCost = total / production.Quantity
+	production.Status = models.ProductionStatusCompleted
+
+	// Выход приходуется в единице существующего остатка полуфабриката (остаток в г, выход в кг),
+	// партия заводится в той же единице, чтобы FIFO списывал ее вместе с остатком
+	semiFinishedID := semiFinished.ID
+	st, err := uc.repo.GetStockBySemiFinishedAndWarehouse(ctx, semiFinishedID, production.WarehouseID)
+	if err != nil {
+		return err
+	}
+	isNew := st == nil
+	if isNew {
+		st = &models.Stock{
+			WarehouseID:    production.WarehouseID,
+			SemiFinishedID: &semiFinishedID,
+			Unit:           production.Unit,
+		}
+	}
+	factor, err := stockUnitFactor(production.Unit, st.Unit)
+	if err != nil {
+		return err
+	}
+	quantity := production.Quantity * factor
+	unitCost := production.UnitCost / factor
+
+	// Приходуем выход партией с себестоимостью производства
+	productionID := production.ID
+	if err := uc.repo.CreateStockLot(ctx, &models.StockLot{
+		WarehouseID:       production.WarehouseID,
+		SemiFinishedID:    &semiFinishedID,
+		SourceType:        models.StockLotSourceProduction,
+		SourceID:          &productionID,
+		Quantity:          quantity,
+		RemainingQuantity: quantity,
+		Unit:              st.Unit,
+		UnitCost:          unitCost,
+		ReceivedAt:        production.ProductionDateTime,
+	}); err != nil {
+		return fmt.Errorf("failed to create stock lot: %w", err)
+	}
+
+	st.Quantity += quantity
+	st.PricePerUnit = unitCost
+	if isNew {
+		err = uc.repo.CreateStock(ctx, st)
+	} else {
+		err = uc.repo.UpdateStock(ctx, st)
+	}
+	if err != nil {
+		return err
+	}
+	outputLedger := stockLedgerSource{Type: models.StockLedgerProductionIn, ID: production.ID, At: production.ProductionDateTime}
+	if err := postStockLedger(ctx, uc.repo, st, quantity, total, outputLedger); err != nil {
+		return err
+	}
+
+	return uc.repo.CreateProduction(ctx, production)
+}
+
+// GetProductions возвращает список производств полуфабрикатов
+func (uc *WarehouseUseCase) GetProductions(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID) ([]*models.Production, error) {
+	return uc.repo.GetProductionsByWarehouse(ctx, establishmentID, warehouseID)
+}
+
+// GetProduction возвращает производство по ID
+func (uc *WarehouseUseCase) GetProduction(ctx context.Context, id uuid.UUID, establishmentID uuid.UUID) (*models.Production, error) {
+	return uc.repo.GetProductionByID(ctx, id, &establishmentID)
+}
+
 // GetSuppliesByIngredientOrProduct возвращает поставки по ингредиенту или товару
 func (uc *WarehouseUseCase) GetSuppliesByIngredientOrProduct(ctx context.Context, establishmentID uuid.UUID, ingredientID *uuid.UUID, productID *uuid.UUID) ([]*models.Supply, error) {
 	if ingredientID == nil && productID == nil {
@@ -283,67 +993,10 @@ func (uc *WarehouseUseCase) GetWriteOff(ctx context.Context, id uuid.UUID, estab
 	return uc.repo.GetWriteOffByID(ctx, id, &establishmentID)
 }
 
-// GetMovements возвращает все движения по складу (Supply и WriteOff)
-func (uc *WarehouseUseCase) GetMovements(ctx context.Context, establishmentID uuid.UUID, warehouseID *uuid.UUID) ([]interface{}, error) {
-	movements := make([]interface{}, 0)
-	
-	// Получаем поставки
-	supplies, err := uc.repo.GetSuppliesByWarehouse(ctx, establishmentID, warehouseID)
-	if err != nil {
-		return nil, err
-	}
-	
-	// Преобразуем Supply в движения
-	for _, supply := range supplies {
-		warehouseName := ""
-		if supply.Warehouse != nil {
-			warehouseName = supply.Warehouse.Name
-		}
-		supplierName := ""
-		if supply.Supplier != nil {
-			supplierName = supply.Supplier.Name
-		}
-		movements = append(movements, map[string]interface{}{
-			"type":            "supply",
-			"id":              supply.ID,
-			"warehouse_id":    supply.WarehouseID,
-			"warehouse_name":  warehouseName,
-			"supplier_id":     supply.SupplierID,
-			"supplier_name":   supplierName,
-			"date_time":       supply.DeliveryDateTime,
-			"status":          supply.Status,
-			"comment":         supply.Comment,
-			"items":           supply.Items,
-			"created_at":      supply.CreatedAt,
-		})
-	}
-	
-	// Получаем списания
-	writeOffs, err := uc.repo.GetWriteOffsByWarehouse(ctx, establishmentID, warehouseID)
-	if err != nil {
-		return nil, err
-	}
-	
-	// Преобразуем WriteOff в движения
-	for _, writeOff := range writeOffs {
-		warehouseName := ""
-		if writeOff.Warehouse != nil {
-			warehouseName = writeOff.Warehouse.Name
-		}
-		movements = append(movements, map[string]interface{}{
-			"type":         "write_off",
-			"id":           writeOff.ID,
-			"warehouse_id": writeOff.WarehouseID,
-			"warehouse_name": warehouseName,
-			"date_time":    writeOff.WriteOffDateTime,
-			"reason":       writeOff.Reason,
-			"comment":      writeOff.Comment,
-			"items":        writeOff.Items,
-			"created_at":   writeOff.CreatedAt,
-		})
-	}
-
-	return movements, nil
+// GetMovements возвращает движения остатков из журнала: поставки, продажи, списания,
+// перемещения, производство и корректировки инвентаризации
+func (uc *WarehouseUseCase) GetMovements(ctx context.Context, establishmentID uuid.UUID, filter *repositories.StockLedgerFilter) ([]*models.StockLedgerEntry, error) {
+	return uc.repo.GetStockLedger(ctx, establishmentID, filter)
 }
 
 // ——— WriteOffReason CRUD ———
@@ -0,0 +1,766 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourusername/arc/backend/internal/models"
+	"github.com/yourusername/arc/backend/internal/repositories"
+)
+
+// fakeTransactor выполняет fn без БД и считает открытые транзакции.
+// С repo ошибка fn откатывает изменения фейкового склада, как откат транзакции.
+// Вложенный вызов выполняется во внешней транзакции, AfterCommit — после ее успешного завершения
+type fakeTransactor struct {
+	calls       int
+	repo        *fakeWarehouseRepository
+	depth       int
+	afterCommit []func()
+}
+
+func (t *fakeTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	t.calls++
+	if t.depth > 0 {
+		return fn(ctx)
+	}
+	restore := func() {}
+	if t.repo != nil {
+		restore = t.repo.snapshot()
+	}
+	t.depth++
+	err := fn(ctx)
+	t.depth--
+	hooks := t.afterCommit
+	t.afterCommit = nil
+	if err != nil {
+		restore()
+		return err
+	}
+	for _, hook := range hooks {
+		hook()
+	}
+	return nil
+}
+
+func (t *fakeTransactor) AfterCommit(ctx context.Context, fn func()) {
+	if t.depth > 0 {
+		t.afterCommit = append(t.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+// fakeWarehouseRepository хранит остатки, партии и журнал в памяти.
+// Как и база, отдает копии остатков, поэтому повторное чтение не видит несохраненных изменений.
+// Методы, которые не нужны тестам, не реализованы (вызов паникует через nil-интерфейс).
+type fakeWarehouseRepository struct {
+	repositories.WarehouseRepository
+
+	warehouses  map[uuid.UUID]*models.Warehouse
+	ingredients map[uuid.UUID]*models.Ingredient
+	stocks      map[uuid.UUID]*models.Stock
+	lots        []*models.StockLot
+	ledger      []*models.StockLedgerEntry
+	supplies    map[uuid.UUID]*models.Supply
+	transfers   map[uuid.UUID]*models.Transfer
+	writeOffs   []*models.WriteOff
+
+	semiFinished map[uuid.UUID]*models.SemiFinishedProduct
+	productions  []*models.Production
+
+	createLotErr error // Ошибка CreateStockLot: обрыв проведения документа на середине
+}
+
+func newFakeWarehouseRepository() *fakeWarehouseRepository {
+	return &fakeWarehouseRepository{
+		warehouses:  make(map[uuid.UUID]*models.Warehouse),
+		ingredients: make(map[uuid.UUID]*models.Ingredient),
+		stocks:      make(map[uuid.UUID]*models.Stock),
+		supplies:    make(map[uuid.UUID]*models.Supply),
+		transfers:   make(map[uuid.UUID]*models.Transfer),
+
+		semiFinished: make(map[uuid.UUID]*models.SemiFinishedProduct),
+	}
+}
+
+// snapshot запоминает состояние склада и возвращает функцию, которая его восстанавливает.
+// Записи заменяются копиями при каждом сохранении, поэтому достаточно копий карт и срезов
+func (r *fakeWarehouseRepository) snapshot() func() {
+	stocks := maps.Clone(r.stocks)
+	supplies := maps.Clone(r.supplies)
+	lots := slices.Clone(r.lots)
+	ledger := slices.Clone(r.ledger)
+	writeOffs := slices.Clone(r.writeOffs)
+	productions := slices.Clone(r.productions)
+	// Перемещение use case меняет на месте, поэтому копируются сами документы
+	transfers := make(map[uuid.UUID]models.Transfer, len(r.transfers))
+	for id, t := range r.transfers {
+		transfers[id] = *t
+	}
+	return func() {
+		r.stocks, r.supplies, r.lots, r.ledger = stocks, supplies, lots, ledger
+		r.writeOffs, r.productions = writeOffs, productions
+		for id, t := range transfers {
+			*r.transfers[id] = t
+		}
+	}
+}
+
+func (r *fakeWarehouseRepository) addWarehouse() uuid.UUID {
+	w := &models.Warehouse{ID: uuid.New(), Name: "Склад"}
+	r.warehouses[w.ID] = w
+	return w.ID
+}
+
+func (r *fakeWarehouseRepository) addIngredient(unit string) uuid.UUID {
+	ing := &models.Ingredient{ID: uuid.New(), Name: "Мука", Unit: unit}
+	r.ingredients[ing.ID] = ing
+	return ing.ID
+}
+
+// addStock заводит остаток ингредиента и одну партию на все количество
+func (r *fakeWarehouseRepository) addStock(warehouseID, ingredientID uuid.UUID, quantity, price float64) *models.Stock {
+	id := ingredientID
+	st := &models.Stock{ID: uuid.New(), WarehouseID: warehouseID, IngredientID: &id, Quantity: quantity, Unit: models.UnitKilogram, PricePerUnit: price}
+	r.stocks[st.ID] = st
+	r.lots = append(r.lots, &models.StockLot{
+		ID: uuid.New(), WarehouseID: warehouseID, IngredientID: &id, SourceType: models.StockLotSourceSupply,
+		Quantity: quantity, RemainingQuantity: quantity, Unit: st.Unit, UnitCost: price, ReceivedAt: time.Now().Add(-time.Hour),
+	})
+	return st
+}
+
+func (r *fakeWarehouseRepository) stock(warehouseID, ingredientID uuid.UUID) *models.Stock {
+	for _, st := range r.stocks {
+		if st.WarehouseID == warehouseID && st.IngredientID != nil && *st.IngredientID == ingredientID {
+			return st
+		}
+	}
+	return nil
+}
+
+func (r *fakeWarehouseRepository) lotsOf(warehouseID, ingredientID uuid.UUID) []*models.StockLot {
+	var lots []*models.StockLot
+	for _, lot := range r.lots {
+		if lot.WarehouseID == warehouseID && lot.IngredientID != nil && *lot.IngredientID == ingredientID {
+			lots = append(lots, lot)
+		}
+	}
+	return lots
+}
+
+func (r *fakeWarehouseRepository) GetWarehouseByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Warehouse, error) {
+	if w, ok := r.warehouses[id]; ok {
+		return w, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeWarehouseRepository) GetIngredientByID(ctx context.Context, id uuid.UUID) (*models.Ingredient, error) {
+	if ing, ok := r.ingredients[id]; ok {
+		return ing, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeWarehouseRepository) GetStockByIngredientAndWarehouse(ctx context.Context, ingredientID, warehouseID uuid.UUID) (*models.Stock, error) {
+	if st := r.stock(warehouseID, ingredientID); st != nil {
+		cp := *st
+		return &cp, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeWarehouseRepository) CreateStock(ctx context.Context, stock *models.Stock) error {
+	if stock.ID == uuid.Nil {
+		stock.ID = uuid.New()
+	}
+	cp := *stock
+	r.stocks[cp.ID] = &cp
+	return nil
+}
+
+func (r *fakeWarehouseRepository) UpdateStock(ctx context.Context, stock *models.Stock) error {
+	cp := *stock
+	r.stocks[cp.ID] = &cp
+	return nil
+}
+
+func (r *fakeWarehouseRepository) CreateStockLot(ctx context.Context, lot *models.StockLot) error {
+	if r.createLotErr != nil {
+		return r.createLotErr
+	}
+	if lot.ID == uuid.Nil {
+		lot.ID = uuid.New()
+	}
+	cp := *lot
+	r.lots = append(r.lots, &cp)
+	return nil
+}
+
+func (r *fakeWarehouseRepository) UpdateStockLot(ctx context.Context, lot *models.StockLot) error {
+	for i, l := range r.lots {
+		if l.ID == lot.ID {
+			cp := *lot
+			r.lots[i] = &cp
+			return nil
+		}
+	}
+	return errors.New("record not found")
+}
+
+func (r *fakeWarehouseRepository) GetOpenStockLots(ctx context.Context, warehouseID uuid.UUID, ingredientID, productID, semiFinishedID *uuid.UUID) ([]*models.StockLot, error) {
+	var lots []*models.StockLot
+	for _, lot := range r.lots {
+		if lot.WarehouseID != warehouseID || lot.RemainingQuantity <= 0 || ingredientID == nil || lot.IngredientID == nil || *lot.IngredientID != *ingredientID {
+			continue
+		}
+		cp := *lot
+		lots = append(lots, &cp)
+	}
+	sort.SliceStable(lots, func(i, j int) bool { return lots[i].ReceivedAt.Before(lots[j].ReceivedAt) })
+	return lots, nil
+}
+
+func (r *fakeWarehouseRepository) CreateStockLotConsumption(ctx context.Context, consumption *models.StockLotConsumption) error {
+	return nil
+}
+
+func (r *fakeWarehouseRepository) CreateStockLedgerEntry(ctx context.Context, entry *models.StockLedgerEntry) error {
+	r.ledger = append(r.ledger, entry)
+	return nil
+}
+
+// GetStockLedger отдает записи журнала склада за период filter по времени движения;
+// как и репозиторий, пересчитывает BalanceAfter по всем записям позиции до записи включительно
+func (r *fakeWarehouseRepository) GetStockLedger(ctx context.Context, establishmentID uuid.UUID, filter *repositories.StockLedgerFilter) ([]*models.StockLedgerEntry, error) {
+	var entries []*models.StockLedgerEntry
+	for i, e := range r.ledger {
+		if filter.WarehouseID != nil && e.WarehouseID != *filter.WarehouseID {
+			continue
+		}
+		if filter.MovementType != nil && e.MovementType != *filter.MovementType {
+			continue
+		}
+		if (filter.StartDate != nil && e.OccurredAt.Before(*filter.StartDate)) || (filter.EndDate != nil && e.OccurredAt.After(*filter.EndDate)) {
+			continue
+		}
+		cp := *e
+		cp.BalanceAfter = 0
+		for j, prev := range r.ledger {
+			if prev.StockID == e.StockID && (prev.OccurredAt.Before(e.OccurredAt) || prev.OccurredAt.Equal(e.OccurredAt) && j <= i) {
+				cp.BalanceAfter += prev.Quantity
+			}
+		}
+		cp.BalanceAfter = models.RoundTo2(cp.BalanceAfter)
+		entries = append(entries, &cp)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].OccurredAt.Before(entries[j].OccurredAt) })
+	return entries, nil
+}
+
+// GetStockLedgerBalances суммирует журнал по остаткам склада на filter.EndDate
+func (r *fakeWarehouseRepository) GetStockLedgerBalances(ctx context.Context, establishmentID uuid.UUID, filter *repositories.StockLedgerFilter) ([]repositories.StockLedgerBalance, error) {
+	byStock := make(map[uuid.UUID]*repositories.StockLedgerBalance)
+	var result []repositories.StockLedgerBalance
+	for _, e := range r.ledger {
+		if filter.WarehouseID != nil && e.WarehouseID != *filter.WarehouseID {
+			continue
+		}
+		if filter.EndDate != nil && e.OccurredAt.After(*filter.EndDate) {
+			continue
+		}
+		b, ok := byStock[e.StockID]
+		if !ok {
+			b = &repositories.StockLedgerBalance{StockID: e.StockID, WarehouseID: e.WarehouseID, IngredientID: e.IngredientID, Unit: e.Unit}
+			byStock[e.StockID] = b
+		}
+		b.Quantity += e.Quantity
+		b.Amount += e.Amount
+	}
+	for _, b := range byStock {
+		result = append(result, *b)
+	}
+	return result, nil
+}
+
+func (r *fakeWarehouseRepository) CreateWriteOff(ctx context.Context, writeOff *models.WriteOff) error {
+	r.writeOffs = append(r.writeOffs, writeOff)
+	return nil
+}
+
+func (r *fakeWarehouseRepository) GetTransferByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Transfer, error) {
+	if t, ok := r.transfers[id]; ok {
+		return t, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeWarehouseRepository) UpdateTransfer(ctx context.Context, transfer *models.Transfer) error {
+	r.transfers[transfer.ID] = transfer
+	return nil
+}
+
+func (r *fakeWarehouseRepository) CreateSupply(ctx context.Context, supply *models.Supply) error {
+	if supply.ID == uuid.Nil {
+		supply.ID = uuid.New()
+	}
+	for i := range supply.Items {
+		supply.Items[i].SupplyID = supply.ID
+	}
+	cp := *supply
+	cp.Items = append([]models.SupplyItem(nil), supply.Items...)
+	r.supplies[cp.ID] = &cp
+	return nil
+}
+
+func (r *fakeWarehouseRepository) UpdateSupply(ctx context.Context, supply *models.Supply) error {
+	return r.CreateSupply(ctx, supply)
+}
+
+func (r *fakeWarehouseRepository) GetSupplyByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Supply, error) {
+	if s, ok := r.supplies[id]; ok {
+		cp := *s
+		cp.Items = append([]models.SupplyItem(nil), s.Items...)
+		return &cp, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+// fakeSupplierRepository возвращает любого поставщика с отсрочкой termDays
+type fakeSupplierRepository struct {
+	repositories.SupplierRepository
+	termDays int
+}
+
+func (r *fakeSupplierRepository) GetByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.Supplier, error) {
+	return &models.Supplier{ID: id, Name: "Поставщик", PaymentTermDays: r.termDays}, nil
+}
+
+func (r *fakeWarehouseRepository) GetSemiFinishedByID(ctx context.Context, id uuid.UUID, establishmentID *uuid.UUID) (*models.SemiFinishedProduct, error) {
+	if sf, ok := r.semiFinished[id]; ok {
+		return sf, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeWarehouseRepository) GetStockBySemiFinishedAndWarehouse(ctx context.Context, semiFinishedID, warehouseID uuid.UUID) (*models.Stock, error) {
+	for _, st := range r.stocks {
+		if st.WarehouseID == warehouseID && st.SemiFinishedID != nil && *st.SemiFinishedID == semiFinishedID {
+			cp := *st
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeWarehouseRepository) CreateProduction(ctx context.Context, production *models.Production) error {
+	r.productions = append(r.productions, production)
+	return nil
+}
+
+func TestWarehouseUseCase_CreateWriteOff_DuplicateLines(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeWarehouseRepository()
+	warehouseID := repo.addWarehouse()
+	flourID := repo.addIngredient(models.UnitKilogram)
+	repo.addStock(warehouseID, flourID, 10, 50)
+	uc := &WarehouseUseCase{repo: repo, transactor: &fakeTransactor{}}
+
+	t.Run("both lines are deducted", func(t *testing.T) {
+		writeOff := &models.WriteOff{
+			WarehouseID:      warehouseID,
+			WriteOffDateTime: time.Now(),
+			Items: []models.WriteOffItem{
+				{IngredientID: &flourID, Quantity: 3, Unit: models.UnitKilogram},
+				{IngredientID: &flourID, Quantity: 2000, Unit: models.UnitGram},
+			},
+		}
+		require.NoError(t, uc.CreateWriteOff(ctx, writeOff, uuid.New()))
+
+		assert.InDelta(t, 5, repo.stock(warehouseID, flourID).Quantity, 1e-9)
+		assert.InDelta(t, 5, repo.lotsOf(warehouseID, flourID)[0].RemainingQuantity, 1e-9)
+		require.Len(t, repo.ledger, 2)
+		assert.InDelta(t, 7, repo.ledger[0].BalanceAfter, 1e-9)
+		assert.InDelta(t, 5, repo.ledger[1].BalanceAfter, 1e-9)
+		assert.InDelta(t, 250, writeOff.TotalAmount, 1e-9)
+	})
+
+	t.Run("lines are checked against stock together", func(t *testing.T) {
+		writeOff := &models.WriteOff{
+			WarehouseID: warehouseID,
+			Items: []models.WriteOffItem{
+				{IngredientID: &flourID, Quantity: 3, Unit: models.UnitKilogram},
+				{IngredientID: &flourID, Quantity: 3, Unit: models.UnitKilogram},
+			},
+		}
+		assert.Error(t, uc.CreateWriteOff(ctx, writeOff, uuid.New()))
+		assert.InDelta(t, 5, repo.stock(warehouseID, flourID).Quantity, 1e-9)
+		assert.Len(t, repo.ledger, 2)
+	})
+}
+
+func TestWarehouseUseCase_Transfer_SendAndReceive(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeWarehouseRepository()
+	sourceID := repo.addWarehouse()
+	targetID := repo.addWarehouse()
+	flourID := repo.addIngredient(models.UnitKilogram)
+	repo.addStock(sourceID, flourID, 10, 40)
+	uc := &WarehouseUseCase{repo: repo, transactor: &fakeTransactor{}}
+
+	transfer := &models.Transfer{
+		ID:                uuid.New(),
+		SourceWarehouseID: sourceID,
+		TargetWarehouseID: targetID,
+		Status:            models.TransferStatusDraft,
+		Items: []models.TransferItem{
+			{IngredientID: &flourID, Quantity: 4, Unit: models.UnitKilogram},
+			{IngredientID: &flourID, Quantity: 4, Unit: models.UnitKilogram},
+		},
+	}
+	repo.transfers[transfer.ID] = transfer
+
+	require.NoError(t, uc.UpdateTransferStatus(ctx, transfer.ID, models.TransferStatusSent, uuid.New()))
+	assert.Equal(t, models.TransferStatusSent, transfer.Status)
+	assert.InDelta(t, 2, repo.stock(sourceID, flourID).Quantity, 1e-9)
+	assert.InDelta(t, 320, transfer.TotalAmount, 1e-9)
+	assert.Nil(t, repo.stock(targetID, flourID))
+
+	require.NoError(t, uc.UpdateTransferStatus(ctx, transfer.ID, models.TransferStatusReceived, uuid.New()))
+	assert.Equal(t, models.TransferStatusReceived, transfer.Status)
+	target := repo.stock(targetID, flourID)
+	require.NotNil(t, target)
+	assert.InDelta(t, 8, target.Quantity, 1e-9)
+	assert.InDelta(t, 40, target.PricePerUnit, 1e-9)
+	lots := repo.lotsOf(targetID, flourID)
+	require.Len(t, lots, 2)
+	assert.Equal(t, models.StockLotSourceTransfer, lots[0].SourceType)
+
+	// Повторно отправить полученное перемещение нельзя
+	assert.Error(t, uc.UpdateTransferStatus(ctx, transfer.ID, models.TransferStatusSent, uuid.New()))
+
+	// Второе отправление тех же позиций не проходит: на складе-отправителе осталось 2 кг
+	again := &models.Transfer{
+		ID:                uuid.New(),
+		SourceWarehouseID: sourceID,
+		TargetWarehouseID: targetID,
+		Status:            models.TransferStatusDraft,
+		Items: []models.TransferItem{
+			{IngredientID: &flourID, Quantity: 1.5, Unit: models.UnitKilogram},
+			{IngredientID: &flourID, Quantity: 1.5, Unit: models.UnitKilogram},
+		},
+	}
+	repo.transfers[again.ID] = again
+	assert.Error(t, uc.UpdateTransferStatus(ctx, again.ID, models.TransferStatusSent, uuid.New()))
+	assert.InDelta(t, 2, repo.stock(sourceID, flourID).Quantity, 1e-9)
+}
+
+func TestWarehouseUseCase_Transfer_RollsBackOnError(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeWarehouseRepository()
+	sourceID := repo.addWarehouse()
+	targetID := repo.addWarehouse()
+	flourID := repo.addIngredient(models.UnitKilogram)
+	repo.addStock(sourceID, flourID, 10, 40)
+	transactor := &fakeTransactor{repo: repo}
+	uc := &WarehouseUseCase{repo: repo, transactor: transactor}
+
+	transfer := &models.Transfer{
+		ID:                uuid.New(),
+		SourceWarehouseID: sourceID,
+		TargetWarehouseID: targetID,
+		Status:            models.TransferStatusDraft,
+		Items:             []models.TransferItem{{IngredientID: &flourID, Quantity: 4, Unit: models.UnitKilogram}},
+	}
+	repo.transfers[transfer.ID] = transfer
+
+	// Приход на склад-получатель обрывается после списания со склада-отправителя
+	repo.createLotErr = errors.New("connection reset")
+	assert.Error(t, uc.UpdateTransferStatus(ctx, transfer.ID, models.TransferStatusReceived, uuid.New()))
+	assert.Equal(t, 1, transactor.calls)
+	assert.Equal(t, models.TransferStatusDraft, transfer.Status)
+	assert.InDelta(t, 10, repo.stock(sourceID, flourID).Quantity, 1e-9)
+	assert.InDelta(t, 10, repo.lotsOf(sourceID, flourID)[0].RemainingQuantity, 1e-9)
+	assert.Empty(t, repo.ledger)
+
+	repo.createLotErr = nil
+	require.NoError(t, uc.UpdateTransferStatus(ctx, transfer.ID, models.TransferStatusReceived, uuid.New()))
+	assert.InDelta(t, 6, repo.stock(sourceID, flourID).Quantity, 1e-9)
+	assert.InDelta(t, 4, repo.stock(targetID, flourID).Quantity, 1e-9)
+	assert.Len(t, repo.ledger, 2)
+}
+
+func TestWarehouseUseCase_Supply_PostsOnCompletion(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeWarehouseRepository()
+	warehouseID := repo.addWarehouse()
+	flourID := repo.addIngredient(models.UnitKilogram)
+	uc := &WarehouseUseCase{repo: repo, supplierRepo: &fakeSupplierRepository{}, transactor: &fakeTransactor{}}
+
+	supply := &models.Supply{
+		WarehouseID:      warehouseID,
+		SupplierID:       uuid.New(),
+		DeliveryDateTime: time.Now(),
+		Status:           "pending",
+		Items: []models.SupplyItem{
+			{ID: uuid.New(), IngredientID: &flourID, Quantity: 5, Unit: models.UnitKilogram, PricePerUnit: 60},
+			{ID: uuid.New(), IngredientID: &flourID, Quantity: 500, Unit: models.UnitGram, PricePerUnit: 0.07},
+		},
+	}
+	require.NoError(t, uc.CreateSupply(ctx, supply, uuid.New()))
+
+	// Непроведенная поставка не меняет ни остатки, ни журнал, ни партии
+	assert.Nil(t, repo.stock(warehouseID, flourID))
+	assert.Empty(t, repo.ledger)
+	assert.Empty(t, repo.lotsOf(warehouseID, flourID))
+
+	supply.Status = "completed"
+	require.NoError(t, uc.UpdateSupply(ctx, supply, uuid.New()))
+	st := repo.stock(warehouseID, flourID)
+	require.NotNil(t, st)
+	assert.InDelta(t, 5.5, st.Quantity, 1e-9)
+	assert.Len(t, repo.ledger, 2)
+	lots := repo.lotsOf(warehouseID, flourID)
+	require.Len(t, lots, 2)
+	assert.InDelta(t, 0.5, lots[1].Quantity, 1e-9)
+	assert.InDelta(t, 70, lots[1].UnitCost, 1e-9)
+
+	// Повторное сохранение проведенной поставки не приходует ее второй раз
+	supply.Comment = "накладная получена"
+	require.NoError(t, uc.UpdateSupply(ctx, supply, uuid.New()))
+	assert.InDelta(t, 5.5, repo.stock(warehouseID, flourID).Quantity, 1e-9)
+	assert.Len(t, repo.ledger, 2)
+
+	supply.Status = "pending"
+	assert.Error(t, uc.UpdateSupply(ctx, supply, uuid.New()))
+	supply.Status = "completed"
+	supply.Items[0].Quantity = 50
+	assert.Error(t, uc.UpdateSupply(ctx, supply, uuid.New()))
+}
+
+func TestWarehouseUseCase_CreateSupply_Completed(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeWarehouseRepository()
+	warehouseID := repo.addWarehouse()
+	flourID := repo.addIngredient(models.UnitKilogram)
+	uc := &WarehouseUseCase{repo: repo, supplierRepo: &fakeSupplierRepository{termDays: 14}, transactor: &fakeTransactor{}}
+
+	delivered := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	supply := &models.Supply{
+		WarehouseID:      warehouseID,
+		SupplierID:       uuid.New(),
+		DeliveryDateTime: delivered,
+		Status:           "completed",
+		Items:            []models.SupplyItem{{ID: uuid.New(), IngredientID: &flourID, Quantity: 2, Unit: models.UnitKilogram, PricePerUnit: 80}},
+	}
+	require.NoError(t, uc.CreateSupply(ctx, supply, uuid.New()))
+
+	assert.InDelta(t, 2, repo.stock(warehouseID, flourID).Quantity, 1e-9)
+	require.Len(t, repo.ledger, 1)
+	assert.Equal(t, models.StockLedgerSupply, repo.ledger[0].MovementType)
+	require.Len(t, repo.lotsOf(warehouseID, flourID), 1)
+	require.NotNil(t, supply.DueDate)
+	assert.Equal(t, delivered.AddDate(0, 0, 14), *supply.DueDate)
+}
+
+func TestWarehouseUseCase_Supply_RollsBackOnError(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeWarehouseRepository()
+	warehouseID := repo.addWarehouse()
+	flourID := repo.addIngredient(models.UnitKilogram)
+	transactor := &fakeTransactor{repo: repo}
+	uc := &WarehouseUseCase{repo: repo, supplierRepo: &fakeSupplierRepository{}, transactor: transactor}
+	repo.createLotErr = errors.New("connection reset")
+
+	newSupply := func(status string) *models.Supply {
+		return &models.Supply{
+			WarehouseID:      warehouseID,
+			SupplierID:       uuid.New(),
+			DeliveryDateTime: time.Now(),
+			Status:           status,
+			Items:            []models.SupplyItem{{ID: uuid.New(), IngredientID: &flourID, Quantity: 2, Unit: models.UnitKilogram, PricePerUnit: 80}},
+		}
+	}
+
+	// Проведение обрывается на создании партии: не остается ни документа, ни остатка, ни журнала
+	assert.Error(t, uc.CreateSupply(ctx, newSupply("completed"), uuid.New()))
+	assert.Empty(t, repo.supplies)
+	assert.Nil(t, repo.stock(warehouseID, flourID))
+	assert.Empty(t, repo.ledger)
+
+	// Проведение при обновлении откатывает и сам документ
+	supply := newSupply("pending")
+	require.NoError(t, uc.CreateSupply(ctx, supply, uuid.New()))
+	supply.Status = "completed"
+	assert.Error(t, uc.UpdateSupply(ctx, supply, uuid.New()))
+	assert.Equal(t, "pending", repo.supplies[supply.ID].Status)
+	assert.Nil(t, repo.stock(warehouseID, flourID))
+	assert.Empty(t, repo.ledger)
+	assert.Equal(t, 3, transactor.calls)
+
+	repo.createLotErr = nil
+	require.NoError(t, uc.UpdateSupply(ctx, supply, uuid.New()))
+	assert.InDelta(t, 2, repo.stock(warehouseID, flourID).Quantity, 1e-9)
+	assert.Len(t, repo.ledger, 1)
+}
+
+func TestWarehouseUseCase_UpdateSupply_PaymentFieldsAndDueDate(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeWarehouseRepository()
+	warehouseID := repo.addWarehouse()
+	flourID := repo.addIngredient(models.UnitKilogram)
+	uc := &WarehouseUseCase{repo: repo, supplierRepo: &fakeSupplierRepository{termDays: 10}, transactor: &fakeTransactor{}}
+
+	delivered := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	supply := &models.Supply{
+		WarehouseID:      warehouseID,
+		SupplierID:       uuid.New(),
+		DeliveryDateTime: delivered,
+		Status:           "completed",
+		TotalAmount:      300,
+		Items:            []models.SupplyItem{{ID: uuid.New(), IngredientID: &flourID, Quantity: 3, Unit: models.UnitKilogram, PricePerUnit: 100}},
+	}
+	require.NoError(t, uc.CreateSupply(ctx, supply, uuid.New()))
+	// Частичная оплата внесена платежом поставщику
+	paidAt := delivered.AddDate(0, 0, 2)
+	repo.supplies[supply.ID].PaymentAmount = 100
+	repo.supplies[supply.ID].PaymentStatus = models.SupplyPaymentStatusPartial
+	repo.supplies[supply.ID].PaymentDate = &paidAt
+
+	update, err := repo.GetSupplyByID(ctx, supply.ID, nil)
+	require.NoError(t, err)
+	invoiceDate := delivered.AddDate(0, 0, 5)
+	update.InvoiceDate = &invoiceDate
+	update.TotalAmount = 100
+	update.PaymentAmount = 300
+	update.PaymentStatus = models.SupplyPaymentStatusPaid
+	update.PaymentDate = nil
+	require.NoError(t, uc.UpdateSupply(ctx, update, uuid.New()))
+
+	stored := repo.supplies[supply.ID]
+	assert.InDelta(t, 100, stored.PaymentAmount, 1e-9)
+	require.NotNil(t, stored.PaymentDate)
+	assert.Equal(t, paidAt, *stored.PaymentDate)
+	// Сумма счета уменьшилась до оплаченной — статус пересчитан от платежей
+	assert.Equal(t, models.SupplyPaymentStatusPaid, stored.PaymentStatus)
+	require.NotNil(t, stored.DueDate)
+	assert.Equal(t, invoiceDate.AddDate(0, 0, 10), *stored.DueDate)
+
+	update.SupplierID = uuid.New()
+	assert.ErrorContains(t, uc.UpdateSupply(ctx, update, uuid.New()), "cannot change supplier")
+}
+
+func TestWarehouseUseCase_CreateProduction(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeWarehouseRepository()
+	warehouseID := repo.addWarehouse()
+	flourID := repo.addIngredient(models.UnitKilogram)
+	repo.addStock(warehouseID, flourID, 10, 50)
+	uc := &WarehouseUseCase{repo: repo, transactor: &fakeTransactor{}}
+
+	// Тесто: выход 1 кг из 600 г и еще 200 г муки (например, на подпыл) — две строки одного ингредиента
+	dough := &models.SemiFinishedProduct{
+		ID:       uuid.New(),
+		Name:     "Тесто",
+		Unit:     models.UnitKilogram,
+		Quantity: 1,
+		Ingredients: []models.SemiFinishedIngredient{
+			{IngredientID: flourID, Gross: 600, Net: 600, Unit: models.UnitGram},
+			{IngredientID: flourID, Gross: 200, Net: 200, Unit: models.UnitGram},
+		},
+	}
+	repo.semiFinished[dough.ID] = dough
+
+	production := &models.Production{WarehouseID: warehouseID, SemiFinishedID: dough.ID, Quantity: 2}
+	require.NoError(t, uc.CreateProduction(ctx, production, uuid.New()))
+
+	assert.Equal(t, models.ProductionStatusCompleted, production.Status)
+	assert.InDelta(t, 8.4, repo.stock(warehouseID, flourID).Quantity, 1e-9)
+	assert.InDelta(t, 80, production.TotalCost, 1e-9)
+	assert.InDelta(t, 40, production.UnitCost, 1e-9)
+
+	out, err := repo.GetStockBySemiFinishedAndWarehouse(ctx, dough.ID, warehouseID)
+	require.NoError(t, err)
+	require.NotNil(t, out)
+	assert.InDelta(t, 2, out.Quantity, 1e-9)
+	assert.InDelta(t, 40, out.PricePerUnit, 1e-9)
+	require.Len(t, repo.ledger, 3)
+	assert.Equal(t, models.StockLedgerProductionIn, repo.ledger[2].MovementType)
+
+	// Не хватает муки на суммарный расход обеих строк
+	production = &models.Production{WarehouseID: warehouseID, SemiFinishedID: dough.ID, Quantity: 11}
+	assert.Error(t, uc.CreateProduction(ctx, production, uuid.New()))
+	assert.InDelta(t, 8.4, repo.stock(warehouseID, flourID).Quantity, 1e-9)
+}
+
+func TestWarehouseUseCase_CreateProduction_StockInOtherUnit(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeWarehouseRepository()
+	warehouseID := repo.addWarehouse()
+	flourID := repo.addIngredient(models.UnitKilogram)
+	repo.addStock(warehouseID, flourID, 10, 50)
+	transactor := &fakeTransactor{repo: repo}
+	uc := &WarehouseUseCase{repo: repo, transactor: transactor}
+
+	dough := &models.SemiFinishedProduct{
+		ID:          uuid.New(),
+		Name:        "Тесто",
+		Unit:        models.UnitKilogram,
+		Quantity:    1,
+		Ingredients: []models.SemiFinishedIngredient{{IngredientID: flourID, Gross: 800, Net: 800, Unit: models.UnitGram}},
+	}
+	repo.semiFinished[dough.ID] = dough
+	// Остаток теста уже ведется в граммах
+	doughID := dough.ID
+	require.NoError(t, repo.CreateStock(ctx, &models.Stock{
+		WarehouseID: warehouseID, SemiFinishedID: &doughID, Quantity: 500, Unit: models.UnitGram, PricePerUnit: 0.03,
+	}))
+	doughLots := func() []*models.StockLot {
+		var lots []*models.StockLot
+		for _, lot := range repo.lots {
+			if lot.SemiFinishedID != nil && *lot.SemiFinishedID == doughID {
+				lots = append(lots, lot)
+			}
+		}
+		return lots
+	}
+
+	// Партия создания обрывается: мука не списана, документа нет
+	repo.createLotErr = errors.New("connection reset")
+	assert.Error(t, uc.CreateProduction(ctx, &models.Production{WarehouseID: warehouseID, SemiFinishedID: dough.ID, Quantity: 2}, uuid.New()))
+	assert.InDelta(t, 10, repo.stock(warehouseID, flourID).Quantity, 1e-9)
+	assert.Empty(t, repo.ledger)
+	assert.Empty(t, repo.productions)
+	repo.createLotErr = nil
+
+	// 2 кг выхода приходуются как 2000 г: остаток и партия в одной единице
+	production := &models.Production{WarehouseID: warehouseID, SemiFinishedID: dough.ID, Quantity: 2}
+	require.NoError(t, uc.CreateProduction(ctx, production, uuid.New()))
+	assert.Equal(t, 2, transactor.calls)
+	assert.InDelta(t, 40, production.UnitCost, 1e-9)
+
+	out, err := repo.GetStockBySemiFinishedAndWarehouse(ctx, dough.ID, warehouseID)
+	require.NoError(t, err)
+	assert.Equal(t, models.UnitGram, out.Unit)
+	assert.InDelta(t, 2500, out.Quantity, 1e-9)
+	assert.InDelta(t, 0.04, out.PricePerUnit, 1e-9)
+	lots := doughLots()
+	require.Len(t, lots, 1)
+	assert.Equal(t, models.UnitGram, lots[0].Unit)
+	assert.InDelta(t, 2000, lots[0].Quantity, 1e-9)
+	assert.InDelta(t, 2000, lots[0].RemainingQuantity, 1e-9)
+	assert.InDelta(t, 0.04, lots[0].UnitCost, 1e-9)
+	require.Len(t, repo.ledger, 2)
+	assert.InDelta(t, 2000, repo.ledger[1].Quantity, 1e-9)
+	assert.InDelta(t, 80, repo.ledger[1].Amount, 1e-9)
+}
@@ -46,6 +46,42 @@ func migrateAccountBalances(db *gorm.DB, logger *zap.Logger) error {
 	return nil
 }
 
+// backfillStockLedgerOpenings заводит в журнал остатков начальные записи для остатков,
+// по которым еще нет ни одной записи (остатки, накопленные до ведения журнала)
+func backfillStockLedgerOpenings(db *gorm.DB, logger *zap.Logger) error {
+	result := db.Exec(`
+		INSERT INTO stock_ledger_entries (id, warehouse_id, stock_id, ingredient_id, product_id, semi_finished_id,
+			movement_type, quantity, unit, unit_cost, amount, balance_after, occurred_at, created_at)
+		SELECT gen_random_uuid(), s.warehouse_id, s.id, s.ingredient_id, s.product_id, s.semi_finished_id,
+			?, s.quantity, s.unit, s.price_per_unit, ROUND((s.quantity * s.price_per_unit)::numeric, 2), s.quantity, s.updated_at, NOW()
+		FROM stocks s
+		WHERE s.quantity <> 0
+			AND NOT EXISTS (SELECT 1 FROM stock_ledger_entries e WHERE e.stock_id = s.id)`, models.StockLedgerOpening)
+	if result.Error != nil {
+		return result.Error
+	}
+	if logger != nil && result.RowsAffected > 0 {
+		logger.Info("Stock ledger opening balances created", zap.Int64("count", result.RowsAffected))
+	}
+	return nil
+}
+
+// clearStaleProductModificationFlags снимает флаг has_modifications с товаров без модификаций:
+// такой товар (данные до появления модификаций) продается и учитывается на складе сам
+func clearStaleProductModificationFlags(db *gorm.DB, logger *zap.Logger) error {
+	result := db.Exec(`
+		UPDATE products SET has_modifications = false
+		WHERE has_modifications AND parent_id IS NULL
+			AND NOT EXISTS (SELECT 1 FROM products v WHERE v.parent_id = products.id AND v.deleted_at IS NULL)`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if logger != nil && result.RowsAffected > 0 {
+		logger.Info("Cleared has_modifications on products without variants", zap.Int64("count", result.RowsAffected))
+	}
+	return nil
+}
+
 // RunMigrations выполняет автоматические миграции для всех моделей
 func RunMigrations(db *gorm.DB, logger *zap.Logger) error {
 	if logger != nil {
@@ -127,6 +163,13 @@ func RunMigrations(db *gorm.DB, logger *zap.Logger) error {
 	}
 
 	// 6. Модели для меню и склада
+	// Расписания доступности раньше категорий, товаров и тех-карт, которые на них ссылаются
+	if err := migrateDB.AutoMigrate(&models.AvailabilitySchedule{}); err != nil {
+		return fmt.Errorf("failed to migrate AvailabilitySchedule: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.AvailabilityRule{}); err != nil {
+		return fmt.Errorf("failed to migrate AvailabilityRule: %w", err)
+	}
 	if err := migrateDB.AutoMigrate(&models.Category{}); err != nil {
 		return fmt.Errorf("failed to migrate Category: %w", err)
 	}
@@ -139,9 +182,15 @@ func RunMigrations(db *gorm.DB, logger *zap.Logger) error {
 	if err := migrateDB.AutoMigrate(&models.Ingredient{}); err != nil {
 		return fmt.Errorf("failed to migrate Ingredient: %w", err)
 	}
+	if err := migrateDB.AutoMigrate(&models.IngredientUnitConversion{}); err != nil {
+		return fmt.Errorf("failed to migrate IngredientUnitConversion: %w", err)
+	}
 	if err := migrateDB.AutoMigrate(&models.Product{}); err != nil {
 		return fmt.Errorf("failed to migrate Product: %w", err)
 	}
+	if err := clearStaleProductModificationFlags(migrateDB, logger); err != nil {
+		return fmt.Errorf("failed to clear product modification flags: %w", err)
+	}
 	if err := migrateDB.AutoMigrate(&models.SemiFinishedProduct{}); err != nil {
 		return fmt.Errorf("failed to migrate SemiFinishedProduct: %w", err)
 	}
@@ -160,6 +209,18 @@ func RunMigrations(db *gorm.DB, logger *zap.Logger) error {
 	if err := migrateDB.AutoMigrate(&models.ModifierOption{}); err != nil {
 		return fmt.Errorf("failed to migrate ModifierOption: %w", err)
 	}
+	if err := migrateDB.AutoMigrate(&models.Combo{}); err != nil {
+		return fmt.Errorf("failed to migrate Combo: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.ComboGroup{}); err != nil {
+		return fmt.Errorf("failed to migrate ComboGroup: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.ComboOption{}); err != nil {
+		return fmt.Errorf("failed to migrate ComboOption: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.Upload{}); err != nil {
+		return fmt.Errorf("failed to migrate Upload: %w", err)
+	}
 
 	// 7. Модели для склада
 	if err := migrateDB.AutoMigrate(&models.Warehouse{}); err != nil {
@@ -171,6 +232,9 @@ func RunMigrations(db *gorm.DB, logger *zap.Logger) error {
 	if err := migrateDB.AutoMigrate(&models.Supplier{}); err != nil {
 		return fmt.Errorf("failed to migrate Supplier: %w", err)
 	}
+	if err := migrateDB.AutoMigrate(&models.SupplierItemMapping{}); err != nil {
+		return fmt.Errorf("failed to migrate SupplierItemMapping: %w", err)
+	}
 	if err := migrateDB.AutoMigrate(&models.Supply{}); err != nil {
 		return fmt.Errorf("failed to migrate Supply: %w", err)
 	}
@@ -186,12 +250,69 @@ func RunMigrations(db *gorm.DB, logger *zap.Logger) error {
 	if err := migrateDB.AutoMigrate(&models.WriteOffReason{}); err != nil {
 		return fmt.Errorf("failed to migrate WriteOffReason: %w", err)
 	}
+	if err := migrateDB.AutoMigrate(&models.Transfer{}); err != nil {
+		return fmt.Errorf("failed to migrate Transfer: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.TransferItem{}); err != nil {
+		return fmt.Errorf("failed to migrate TransferItem: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.Production{}); err != nil {
+		return fmt.Errorf("failed to migrate Production: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.ProductionItem{}); err != nil {
+		return fmt.Errorf("failed to migrate ProductionItem: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.StockLot{}); err != nil {
+		return fmt.Errorf("failed to migrate StockLot: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.StockLotConsumption{}); err != nil {
+		return fmt.Errorf("failed to migrate StockLotConsumption: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.StockLedgerEntry{}); err != nil {
+		return fmt.Errorf("failed to migrate StockLedgerEntry: %w", err)
+	}
+	if err := backfillStockLedgerOpenings(migrateDB, logger); err != nil {
+		return fmt.Errorf("failed to backfill stock ledger: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.StockAlert{}); err != nil {
+		return fmt.Errorf("failed to migrate StockAlert: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.PurchaseOrder{}); err != nil {
+		return fmt.Errorf("failed to migrate PurchaseOrder: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.PurchaseOrderItem{}); err != nil {
+		return fmt.Errorf("failed to migrate PurchaseOrderItem: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.SupplierPayment{}); err != nil {
+		return fmt.Errorf("failed to migrate SupplierPayment: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.SupplierPaymentAllocation{}); err != nil {
+		return fmt.Errorf("failed to migrate SupplierPaymentAllocation: %w", err)
+	}
 	if err := migrateDB.AutoMigrate(&models.Inventory{}); err != nil {
 		return fmt.Errorf("failed to migrate Inventory: %w", err)
 	}
 	if err := migrateDB.AutoMigrate(&models.InventoryItem{}); err != nil {
 		return fmt.Errorf("failed to migrate InventoryItem: %w", err)
 	}
+	if err := migrateDB.AutoMigrate(&models.InventoryCount{}); err != nil {
+		return fmt.Errorf("failed to migrate InventoryCount: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.ItemBarcode{}); err != nil {
+		return fmt.Errorf("failed to migrate ItemBarcode: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.MenuItemCost{}); err != nil {
+		return fmt.Errorf("failed to migrate MenuItemCost: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.FoodCostAlert{}); err != nil {
+		return fmt.Errorf("failed to migrate FoodCostAlert: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.RepricingRule{}); err != nil {
+		return fmt.Errorf("failed to migrate RepricingRule: %w", err)
+	}
+	if err := migrateDB.AutoMigrate(&models.PriceChange{}); err != nil {
+		return fmt.Errorf("failed to migrate PriceChange: %w", err)
+	}
 
 	// 8. Модели для заказов
 	if err := migrateDB.AutoMigrate(&models.Order{}); err != nil {
@@ -200,6 +321,9 @@ func RunMigrations(db *gorm.DB, logger *zap.Logger) error {
 	if err := migrateDB.AutoMigrate(&models.OrderItem{}); err != nil {
 		return fmt.Errorf("failed to migrate OrderItem: %w", err)
 	}
+	if err := migrateDB.AutoMigrate(&models.OrderItemComponent{}); err != nil {
+		return fmt.Errorf("failed to migrate OrderItemComponent: %w", err)
+	}
 
 	// 9. Модели для клиентов
 	if err := migrateDB.AutoMigrate(&models.Client{}); err != nil {
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/yourusername/arc/backend/internal/models"
+)
+
+func TestBackfillStockLedgerOpenings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening gorm database", err)
+	}
+
+	// Начальные записи заводятся одним запросом только для ненулевых остатков без записей в журнале
+	query := `(?s)INSERT INTO stock_ledger_entries .* SELECT .* FROM stocks s WHERE s\.quantity <> 0 .*NOT EXISTS \(SELECT 1 FROM stock_ledger_entries e WHERE e\.stock_id = s\.id\)`
+
+	t.Run("Opening entries created", func(t *testing.T) {
+		mock.ExpectExec(query).WithArgs(models.StockLedgerOpening).WillReturnResult(sqlmock.NewResult(0, 3))
+
+		assert.NoError(t, backfillStockLedgerOpenings(gormDB, nil))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Repeated run is a no-op", func(t *testing.T) {
+		mock.ExpectExec(query).WithArgs(models.StockLedgerOpening).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.NoError(t, backfillStockLedgerOpenings(gormDB, nil))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Database error", func(t *testing.T) {
+		mock.ExpectExec(query).WithArgs(models.StockLedgerOpening).WillReturnError(errors.New("relation \"stock_ledger_entries\" does not exist"))
+
+		assert.Error(t, backfillStockLedgerOpenings(gormDB, nil))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestClearStaleProductModificationFlags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening gorm database", err)
+	}
+
+	// Флаг снимается только с товаров верхнего уровня, у которых не осталось неудаленных модификаций
+	query := `(?s)UPDATE products SET has_modifications = false WHERE has_modifications AND parent_id IS NULL .*NOT EXISTS \(SELECT 1 FROM products v WHERE v\.parent_id = products\.id AND v\.deleted_at IS NULL\)`
+
+	t.Run("Stale flags cleared", func(t *testing.T) {
+		mock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 2))
+
+		assert.NoError(t, clearStaleProductModificationFlags(gormDB, nil))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Database error", func(t *testing.T) {
+		mock.ExpectExec(query).WillReturnError(errors.New("column \"has_modifications\" does not exist"))
+
+		assert.Error(t, clearStaleProductModificationFlags(gormDB, nil))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
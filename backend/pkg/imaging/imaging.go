@@ -0,0 +1,230 @@
+// Package imaging проверяет загружаемые изображения по содержимому, удаляет метаданные (EXIF, XMP)
+// и готовит уменьшенные варианты для меню и витрины.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// Format формат изображения, определенный по сигнатуре файла
+type Format string
+
+const (
+	FormatJPEG Format = "jpeg"
+	FormatPNG  Format = "png"
+	FormatGIF  Format = "gif"
+	FormatWebP Format = "webp"
+)
+
+// ContentType MIME-тип формата
+func (f Format) ContentType() string {
+	return "image/" + string(f)
+}
+
+// Extension расширение файла формата с точкой
+func (f Format) Extension() string {
+	if f == FormatJPEG {
+		return ".jpg"
+	}
+	return "." + string(f)
+}
+
+// Имена вариантов изображения
+const (
+	VariantOriginal = "original" // Исходное изображение без метаданных, не больше Options.MaxSize
+	VariantMedium   = "medium"   // Для карточки позиции
+	VariantThumb    = "thumb"    // Для списков и плиток на планшете
+	VariantWebP     = "webp"     // Средний вариант в WebP
+)
+
+var (
+	// ErrUnsupportedFormat содержимое файла не является JPEG, PNG, GIF или WebP
+	ErrUnsupportedFormat = errors.New("unsupported image format: expected JPEG, PNG, GIF or WebP")
+	// ErrTooLarge изображение превышает допустимое число пикселей
+	ErrTooLarge = errors.New("image dimensions are too large")
+)
+
+// maxPixels предел размера изображения: защита от файлов, которые при декодировании занимают гигабайты памяти
+const maxPixels = 50_000_000
+
+// Options параметры обработки
+type Options struct {
+	MaxSize     int         // Наибольшая сторона сохраняемого оригинала
+	MediumSize  int         // Наибольшая сторона среднего варианта
+	ThumbSize   int         // Наибольшая сторона миниатюры
+	JPEGQuality int         // Качество JPEG (1–100)
+	WebPQuality int         // Качество WebP (1–100)
+	WebP        WebPEncoder // Кодировщик WebP; без него вариант WebP не создается
+	WebPDecoder WebPDecoder // Декодер WebP; без него WebP не уменьшается, а только очищается от метаданных
+}
+
+// DefaultOptions параметры по умолчанию
+func DefaultOptions() Options {
+	return Options{
+		MaxSize:     2048,
+		MediumSize:  1024,
+		ThumbSize:   320,
+		JPEGQuality: 85,
+		WebPQuality: 80,
+	}
+}
+
+// Variant обработанный вариант изображения
+type Variant struct {
+	Name   string
+	Format Format
+	Data   []byte
+	Width  int
+	Height int
+}
+
+// Result результат обработки: формат исходного файла и варианты, начиная с оригинала
+type Result struct {
+	SourceFormat Format
+	Variants     []Variant
+}
+
+// Variant возвращает вариант по имени или nil
+func (r *Result) Variant(name string) *Variant {
+	for i := range r.Variants {
+		if r.Variants[i].Name == name {
+			return &r.Variants[i]
+		}
+	}
+	return nil
+}
+
+// DetectFormat определяет формат по сигнатуре (magic bytes), а не по расширению или заявленному типу
+func DetectFormat(data []byte) (Format, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return FormatJPEG, nil
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG, nil
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return FormatGIF, nil
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return FormatWebP, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Process проверяет и обрабатывает изображение. JPEG, PNG и GIF декодируются и кодируются заново:
+// метаданные не переносятся, поворот из EXIF применяется к пикселям. Непрозрачные изображения
+// сохраняются в JPEG, с прозрачностью — в PNG; у GIF берется первый кадр.
+// WebP декодируется через Options.WebPDecoder и обрабатывается так же. Без декодера из WebP
+// удаляются только блоки EXIF и XMP, а уменьшенные варианты не создаются.
+func Process(data []byte, opts Options) (*Result, error) {
+	format, err := DetectFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var src image.Image
+	if format == FormatWebP {
+		// Структура и размеры проверяются до запуска декодера
+		stripped, err := processWebP(data)
+		if err != nil || opts.WebPDecoder == nil {
+			return stripped, err
+		}
+		if src, err = opts.WebPDecoder.DecodeWebP(stripped.Variants[0].Data); err != nil {
+			return nil, fmt.Errorf("invalid %s image: %w", format, err)
+		}
+	} else {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s image: %w", format, err)
+		}
+		if cfg.Width <= 0 || cfg.Height <= 0 {
+			return nil, fmt.Errorf("invalid %s image: empty dimensions", format)
+		}
+		if cfg.Width*cfg.Height > maxPixels {
+			return nil, ErrTooLarge
+		}
+		if src, err = decode(format, data); err != nil {
+			return nil, fmt.Errorf("invalid %s image: %w", format, err)
+		}
+	}
+	img := toRGBA(src)
+	if format == FormatJPEG {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	result := &Result{SourceFormat: format}
+	original := Resize(img, opts.MaxSize)
+	medium := Resize(original, opts.MediumSize)
+	for _, v := range []struct {
+		name string
+		img  *image.RGBA
+	}{
+		{VariantOriginal, original},
+		{VariantMedium, medium},
+		{VariantThumb, Resize(medium, opts.ThumbSize)},
+	} {
+		variant, err := encode(v.name, v.img, opts.JPEGQuality)
+		if err != nil {
+			return nil, err
+		}
+		result.Variants = append(result.Variants, variant)
+	}
+	if opts.WebP != nil {
+		webp, err := opts.WebP.EncodeWebP(medium, opts.WebPQuality)
+		if err != nil {
+			return nil, fmt.Errorf("encode webp: %w", err)
+		}
+		b := medium.Bounds()
+		result.Variants = append(result.Variants, Variant{
+			Name: VariantWebP, Format: FormatWebP, Data: webp, Width: b.Dx(), Height: b.Dy(),
+		})
+	}
+	return result, nil
+}
+
+func decode(format Format, data []byte) (image.Image, error) {
+	r := bytes.NewReader(data)
+	switch format {
+	case FormatJPEG:
+		return jpeg.Decode(r)
+	case FormatPNG:
+		return png.Decode(r)
+	case FormatGIF:
+		return gif.Decode(r)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// encode сохраняет непрозрачное изображение в JPEG, прозрачное — в PNG
+func encode(name string, img *image.RGBA, quality int) (Variant, error) {
+	b := img.Bounds()
+	variant := Variant{Name: name, Width: b.Dx(), Height: b.Dy()}
+	var buf bytes.Buffer
+	if img.Opaque() {
+		variant.Format = FormatJPEG
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return Variant{}, fmt.Errorf("encode jpeg: %w", err)
+		}
+	} else {
+		variant.Format = FormatPNG
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := encoder.Encode(&buf, img); err != nil {
+			return Variant{}, fmt.Errorf("encode png: %w", err)
+		}
+	}
+	variant.Data = buf.Bytes()
+	return variant, nil
+}
+
+// toRGBA приводит изображение к RGBA с началом координат в (0, 0)
+func toRGBA(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	return dst
+}
@@ -0,0 +1,196 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFormat(t *testing.T) {
+	cases := map[string]Format{
+		"\xFF\xD8\xFF\xE0rest":         FormatJPEG,
+		"\x89PNG\r\n\x1a\nrest":        FormatPNG,
+		"GIF89a-rest":                  FormatGIF,
+		"RIFF\x10\x00\x00\x00WEBPVP8 ": FormatWebP,
+	}
+	for data, want := range cases {
+		got, err := DetectFormat([]byte(data))
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	_, err := DetectFormat([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"/>"))
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+// jpegWithOrientation кодирует JPEG w×h и вставляет после SOI блок EXIF с тегом Orientation
+func jpegWithOrientation(t *testing.T, w, h int, orientation uint16) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	encoded := buf.Bytes()
+
+	tiff := []byte("MM\x00\x2A\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00")
+	binary.BigEndian.PutUint16(tiff[18:20], orientation)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:4], uint16(len(payload)+2))
+	app1 = append(app1, payload...)
+
+	out := append([]byte{}, encoded[:2]...)
+	out = append(out, app1...)
+	return append(out, encoded[2:]...)
+}
+
+func TestProcess_JPEGAppliesOrientationAndStripsExif(t *testing.T) {
+	data := jpegWithOrientation(t, 400, 100, 6)
+	require.Equal(t, 6, jpegOrientation(data))
+
+	opts := DefaultOptions()
+	opts.MediumSize = 200
+	opts.ThumbSize = 50
+	result, err := Process(data, opts)
+	require.NoError(t, err)
+	assert.Equal(t, FormatJPEG, result.SourceFormat)
+	require.Len(t, result.Variants, 3)
+	assert.Nil(t, result.Variant(VariantWebP))
+
+	original := result.Variant(VariantOriginal)
+	assert.Equal(t, FormatJPEG, original.Format)
+	assert.Equal(t, 100, original.Width) // Повернуто на 90°: стороны меняются местами
+	assert.Equal(t, 400, original.Height)
+	assert.False(t, bytes.Contains(original.Data, []byte("Exif")))
+
+	medium := result.Variant(VariantMedium)
+	assert.Equal(t, []int{50, 200}, []int{medium.Width, medium.Height})
+	thumb := result.Variant(VariantThumb)
+	assert.Equal(t, []int{13, 50}, []int{thumb.Width, thumb.Height})
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(thumb.Data))
+	require.NoError(t, err)
+	assert.Equal(t, []int{13, 50}, []int{cfg.Width, cfg.Height})
+}
+
+func TestProcess_TransparentPNGStaysPNG(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	img.Set(10, 10, color.NRGBA{R: 255, A: 128})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+
+	result, err := Process(buf.Bytes(), DefaultOptions())
+	require.NoError(t, err)
+	for _, v := range result.Variants {
+		assert.Equal(t, FormatPNG, v.Format, v.Name)
+	}
+}
+
+func TestProcess_RejectsDisguisedFile(t *testing.T) {
+	_, err := Process([]byte("#!/bin/sh\necho not an image"), DefaultOptions())
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	_, err = Process([]byte("\x89PNG\r\n\x1a\ntruncated"), DefaultOptions())
+	assert.Error(t, err)
+}
+
+// webpWithMetadata собирает WebP 100×50 с альфа-каналом и блоками EXIF и XMP.
+// Данные VP8L не настоящие: структуру проверяет Process, а пиксели — декодер
+func webpWithMetadata() []byte {
+	chunk := func(fourCC string, payload []byte) []byte {
+		c := append([]byte(fourCC), 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(c[4:8], uint32(len(payload)))
+		c = append(c, payload...)
+		if len(payload)%2 == 1 {
+			c = append(c, 0)
+		}
+		return c
+	}
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x08 | 0x04 | 0x10 // EXIF, XMP, альфа-канал
+	vp8x[4], vp8x[7] = 99, 49    // 100×50
+	body := []byte("WEBP")
+	body = append(body, chunk("VP8X", vp8x)...)
+	body = append(body, chunk("VP8L", []byte{0x2F, 0, 0, 0, 0})...)
+	body = append(body, chunk("EXIF", []byte("gps"))...)
+	body = append(body, chunk("XMP ", []byte("<x/>"))...)
+	data := append([]byte("RIFF\x00\x00\x00\x00"), body...)
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(body)))
+	return data
+}
+
+func TestProcess_WebPWithoutDecoderStripsMetadata(t *testing.T) {
+	result, err := Process(webpWithMetadata(), DefaultOptions())
+	require.NoError(t, err)
+	require.Len(t, result.Variants, 1)
+	v := result.Variants[0]
+	assert.Equal(t, FormatWebP, v.Format)
+	assert.Equal(t, []int{100, 50}, []int{v.Width, v.Height})
+	assert.False(t, bytes.Contains(v.Data, []byte("EXIF")))
+	assert.False(t, bytes.Contains(v.Data, []byte("XMP ")))
+	assert.Equal(t, byte(0x10), v.Data[20]) // Флаги VP8X: осталась только альфа
+	assert.Equal(t, uint32(len(v.Data)-8), binary.LittleEndian.Uint32(v.Data[4:8]))
+}
+
+// fakeWebPCodec вместо dwebp и cwebp: возвращает заданное изображение и запоминает входные данные
+type fakeWebPCodec struct {
+	img     image.Image
+	err     error
+	decoded []byte
+	encoded image.Image
+}
+
+func (c *fakeWebPCodec) DecodeWebP(data []byte) (image.Image, error) {
+	c.decoded = data
+	return c.img, c.err
+}
+
+func (c *fakeWebPCodec) EncodeWebP(img image.Image, quality int) ([]byte, error) {
+	c.encoded = img
+	return []byte("RIFF-webp"), nil
+}
+
+func TestProcess_WebPDecodesAndResizes(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	codec := &fakeWebPCodec{img: img}
+	opts := DefaultOptions()
+	opts.MediumSize = 40
+	opts.ThumbSize = 10
+	opts.WebP = codec
+	opts.WebPDecoder = codec
+
+	result, err := Process(webpWithMetadata(), opts)
+	require.NoError(t, err)
+	// Декодеру передается файл уже без метаданных
+	assert.False(t, bytes.Contains(codec.decoded, []byte("EXIF")))
+
+	assert.Equal(t, FormatWebP, result.SourceFormat)
+	require.Len(t, result.Variants, 4)
+	original := result.Variant(VariantOriginal)
+	assert.Equal(t, FormatJPEG, original.Format)
+	assert.Equal(t, []int{100, 50}, []int{original.Width, original.Height})
+	medium := result.Variant(VariantMedium)
+	assert.Equal(t, []int{40, 20}, []int{medium.Width, medium.Height})
+	thumb := result.Variant(VariantThumb)
+	assert.Equal(t, []int{10, 5}, []int{thumb.Width, thumb.Height})
+	webp := result.Variant(VariantWebP)
+	assert.Equal(t, []int{40, 20}, []int{webp.Width, webp.Height})
+	assert.Equal(t, image.Rect(0, 0, 40, 20), codec.encoded.Bounds())
+
+	codec.err = errors.New("corrupt VP8L bitstream")
+	_, err = Process(webpWithMetadata(), opts)
+	assert.ErrorContains(t, err, "invalid webp image: corrupt VP8L bitstream")
+}
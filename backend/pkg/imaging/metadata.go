@@ -0,0 +1,177 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+)
+
+// jpegOrientation читает тег Orientation (0x0112) из EXIF JPEG. Возвращает 1, если тега нет или EXIF поврежден
+func jpegOrientation(data []byte) int {
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		if marker == 0xD8 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0x01 {
+			pos += 2
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 { // Начало данных изображения: EXIF идет раньше
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos = end
+	}
+	return 1
+}
+
+// exifOrientation ищет Orientation в IFD0 блока TIFF
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) != 0x0112 {
+			continue
+		}
+		value := int(order.Uint16(tiff[entry+8 : entry+10]))
+		if value < 1 || value > 8 {
+			return 1
+		}
+		return value
+	}
+	return 1
+}
+
+// orient поворачивает и отражает изображение согласно EXIF Orientation (2–8),
+// чтобы после удаления метаданных фото с телефона не отображалось повернутым
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // Отражение по горизонтали
+				sx, sy = w-1-x, y
+			case 3: // Поворот на 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // Отражение по вертикали
+				sx, sy = x, h-1-y
+			case 5: // Транспонирование
+				sx, sy = y, x
+			case 6: // Поворот на 90° по часовой
+				sx, sy = y, h-1-x
+			case 7: // Транспонирование по побочной диагонали
+				sx, sy = w-1-y, h-1-x
+			case 8: // Поворот на 90° против часовой
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], src.Pix[sy*src.Stride+sx*4:sy*src.Stride+sx*4+4])
+		}
+	}
+	return dst
+}
+
+// processWebP удаляет из WebP блоки EXIF и XMP, проверяя структуру RIFF, и возвращает файл как единственный вариант
+func processWebP(data []byte) (*Result, error) {
+	size := int(binary.LittleEndian.Uint32(data[4:8])) + 8
+	if size > len(data) || size < 12 {
+		return nil, fmt.Errorf("invalid webp image: truncated file")
+	}
+	var out bytes.Buffer
+	out.Write(data[0:12])
+	width, height := 0, 0
+	vp8xFlags := -1
+	for pos := 12; pos < size; {
+		if pos+8 > size {
+			return nil, fmt.Errorf("invalid webp image: truncated chunk")
+		}
+		fourCC := string(data[pos : pos+4])
+		chunkSize := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		end := pos + 8 + chunkSize + chunkSize%2
+		if pos+8+chunkSize > size {
+			return nil, fmt.Errorf("invalid webp image: truncated chunk")
+		}
+		payload := data[pos+8 : pos+8+chunkSize]
+		switch fourCC {
+		case "EXIF", "XMP ":
+			pos = end
+			continue
+		case "VP8X":
+			if len(payload) >= 10 {
+				width = (int(payload[4]) | int(payload[5])<<8 | int(payload[6])<<16) + 1
+				height = (int(payload[7]) | int(payload[8])<<8 | int(payload[9])<<16) + 1
+				vp8xFlags = out.Len() + 8
+			}
+		case "VP8 ":
+			if width == 0 && len(payload) >= 10 {
+				width = int(binary.LittleEndian.Uint16(payload[6:8]) & 0x3FFF)
+				height = int(binary.LittleEndian.Uint16(payload[8:10]) & 0x3FFF)
+			}
+		case "VP8L":
+			if width == 0 && len(payload) >= 5 && payload[0] == 0x2F {
+				bits := binary.LittleEndian.Uint32(payload[1:5])
+				width = int(bits&0x3FFF) + 1
+				height = int(bits>>14&0x3FFF) + 1
+			}
+		}
+		if end > size {
+			end = size
+		}
+		out.Write(data[pos:end])
+		pos = end
+	}
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid webp image: no image data")
+	}
+	if width*height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	result := out.Bytes()
+	if vp8xFlags >= 0 {
+		result[vp8xFlags] &^= 0x08 | 0x04 // Флаги наличия EXIF и XMP
+	}
+	binary.LittleEndian.PutUint32(result[4:8], uint32(len(result)-8))
+	return &Result{
+		SourceFormat: FormatWebP,
+		Variants:     []Variant{{Name: VariantOriginal, Format: FormatWebP, Data: result, Width: width, Height: height}},
+	}, nil
+}
@@ -0,0 +1,74 @@
+package imaging
+
+import (
+	"image"
+)
+
+// Resize уменьшает изображение так, чтобы большая сторона не превышала maxSize, сохраняя пропорции.
+// Каждый пиксель результата — среднее по покрываемой области исходного изображения (box-фильтр),
+// что для уменьшения дает результат без муара. Изображение меньше maxSize (или maxSize <= 0) возвращается как есть.
+func Resize(src *image.RGBA, maxSize int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if maxSize <= 0 || (w <= maxSize && h <= maxSize) {
+		return src
+	}
+	dw, dh := maxSize, maxSize
+	if w >= h {
+		dh = max(1, (h*maxSize+w/2)/w)
+	} else {
+		dw = max(1, (w*maxSize+h/2)/h)
+	}
+	return scaleVertical(scaleHorizontal(src, dw), dh)
+}
+
+// scaleHorizontal сжимает ширину до dw, усредняя пиксели в каждой строке
+func scaleHorizontal(src *image.RGBA, dw int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, dw, h))
+	for y := 0; y < h; y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+w*4]
+		out := dst.Pix[y*dst.Stride : y*dst.Stride+dw*4]
+		for x := 0; x < dw; x++ {
+			from, to := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+			var sum [4]uint32
+			for sx := from; sx < to; sx++ {
+				for c := 0; c < 4; c++ {
+					sum[c] += uint32(row[sx*4+c])
+				}
+			}
+			n := uint32(to - from)
+			for c := 0; c < 4; c++ {
+				out[x*4+c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
+
+// scaleVertical сжимает высоту до dh, усредняя пиксели в каждом столбце
+func scaleVertical(src *image.RGBA, dh int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, dh))
+	sum := make([]uint32, w*4)
+	for y := 0; y < dh; y++ {
+		from, to := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+		for i := range sum {
+			sum[i] = 0
+		}
+		for sy := from; sy < to; sy++ {
+			row := src.Pix[sy*src.Stride : sy*src.Stride+w*4]
+			for i, v := range row {
+				sum[i] += uint32(v)
+			}
+		}
+		n := uint32(to - from)
+		out := dst.Pix[y*dst.Stride : y*dst.Stride+w*4]
+		for i := range out {
+			out[i] = uint8((sum[i] + n/2) / n)
+		}
+	}
+	return dst
+}
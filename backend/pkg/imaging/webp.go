@@ -0,0 +1,115 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// WebPEncoder кодирует изображение в WebP
+type WebPEncoder interface {
+	EncodeWebP(img image.Image, quality int) ([]byte, error)
+}
+
+// WebPDecoder декодирует WebP
+type WebPDecoder interface {
+	DecodeWebP(data []byte) (image.Image, error)
+}
+
+// cwebpTimeout предельное время кодирования или декодирования одного изображения
+const cwebpTimeout = 30 * time.Second
+
+// CWebPEncoder кодирует WebP утилитой cwebp из libwebp: в стандартной библиотеке Go кодировщика WebP нет
+type CWebPEncoder struct {
+	path string
+}
+
+// NewCWebPEncoder находит cwebp по пути или в PATH. Возвращает ошибку, если утилита не установлена
+func NewCWebPEncoder(path string) (*CWebPEncoder, error) {
+	if path == "" {
+		path = "cwebp"
+	}
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("cwebp not found: %w", err)
+	}
+	return &CWebPEncoder{path: resolved}, nil
+}
+
+// EncodeWebP передает изображение в cwebp через временные файлы
+func (e *CWebPEncoder) EncodeWebP(img image.Image, quality int) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "cwebp-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input.png")
+	output := filepath.Join(dir, "output.webp")
+	var buf bytes.Buffer
+	if err := (&png.Encoder{CompressionLevel: png.BestSpeed}).Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(input, buf.Bytes(), 0o600); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cwebpTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, e.path, "-quiet", "-metadata", "none", "-q", strconv.Itoa(quality), input, "-o", output)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("cwebp: %w: %s", err, bytes.TrimSpace(out))
+	}
+	return os.ReadFile(output)
+}
+
+// DWebPDecoder декодирует WebP утилитой dwebp из libwebp: в стандартной библиотеке Go декодера WebP нет
+type DWebPDecoder struct {
+	path string
+}
+
+// NewDWebPDecoder находит dwebp по пути или в PATH. Возвращает ошибку, если утилита не установлена
+func NewDWebPDecoder(path string) (*DWebPDecoder, error) {
+	if path == "" {
+		path = "dwebp"
+	}
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("dwebp not found: %w", err)
+	}
+	return &DWebPDecoder{path: resolved}, nil
+}
+
+// DecodeWebP передает файл в dwebp через временные файлы и читает результат в PNG
+func (d *DWebPDecoder) DecodeWebP(data []byte) (image.Image, error) {
+	dir, err := os.MkdirTemp("", "dwebp-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input.webp")
+	output := filepath.Join(dir, "output.png")
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cwebpTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, d.path, "-quiet", input, "-png", "-o", output)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("dwebp: %w: %s", err, bytes.TrimSpace(out))
+	}
+	decoded, err := os.ReadFile(output)
+	if err != nil {
+		return nil, err
+	}
+	return png.Decode(bytes.NewReader(decoded))
+}
@@ -0,0 +1,300 @@
+// Package pdf формирует простые табличные PDF-документы (заказы, отчеты, бланки)
+// без внешних зависимостей. Текст выводится встроенным шрифтом DejaVu Sans,
+// поэтому кириллица сохраняется как есть; жирное начертание имитируется обводкой.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	pageWidth  = 595.28 // A4, пункты
+	pageHeight = 841.89
+	margin     = 40.0
+
+	titleSize = 14.0
+	textSize  = 9.0
+)
+
+// Document многостраничный документ из строк текста и таблиц
+type Document struct {
+	pages   []*bytes.Buffer
+	current *bytes.Buffer
+	y       float64
+	font    *trueTypeFont
+	used    map[uint16]rune // Использованные глифы и их символы (для подмножества шрифта и ToUnicode)
+}
+
+// New создает пустой документ формата A4
+func New() *Document {
+	d := &Document{font: regularFont(), used: make(map[uint16]rune)}
+	d.addPage()
+	return d
+}
+
+func (d *Document) addPage() {
+	d.current = &bytes.Buffer{}
+	d.pages = append(d.pages, d.current)
+	d.y = pageHeight - margin
+}
+
+// ensureSpace переносит вывод на новую страницу, если строка высотой height не помещается
+func (d *Document) ensureSpace(height float64) {
+	if d.y-height < margin {
+		d.addPage()
+	}
+}
+
+func (d *Document) write(bold bool, size, x float64, text string) {
+	// Жирный текст выводится заливкой с обводкой (режим 2)
+	mode := "0 Tr"
+	if bold {
+		mode = fmt.Sprintf("2 Tr %.2f w", size*0.03)
+	}
+	fmt.Fprintf(d.current, "BT /F1 %.1f Tf %s %.2f %.2f Td <%s> Tj ET\n", size, mode, x, d.y, d.encode(text))
+}
+
+// glyphs переводит текст в глифы шрифта; отсутствующие в шрифте символы заменяются на «?»
+func (d *Document) glyphs(text string) []uint16 {
+	glyphs := make([]uint16, 0, len(text))
+	for _, r := range text {
+		if r == '\n' || r == '\r' || r == '\t' {
+			r = ' '
+		} else if r < 0x20 {
+			continue
+		}
+		g := d.font.glyph(r)
+		if g == 0 {
+			r = '?'
+			g = d.font.glyph(r)
+		}
+		glyphs = append(glyphs, g)
+		if _, ok := d.used[g]; !ok {
+			d.used[g] = r
+		}
+	}
+	return glyphs
+}
+
+// encode возвращает текст в виде шестнадцатеричной строки номеров глифов (кодировка Identity-H)
+func (d *Document) encode(text string) string {
+	var b strings.Builder
+	for _, g := range d.glyphs(text) {
+		fmt.Fprintf(&b, "%04X", g)
+	}
+	return b.String()
+}
+
+// textWidth ширина текста в пунктах при кегле size
+func (d *Document) textWidth(text string, size float64) float64 {
+	total := 0
+	for _, r := range text {
+		total += d.font.width(d.font.glyph(r))
+	}
+	return float64(total) * size / 1000
+}
+
+// fit обрезает текст до ширины width, заканчивая его многоточием
+func (d *Document) fit(text string, size, width float64) string {
+	if d.textWidth(text, size) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && d.textWidth(string(runes)+"…", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
+
+// Title выводит заголовок
+func (d *Document) Title(text string) {
+	d.ensureSpace(titleSize * 1.6)
+	d.y -= titleSize
+	d.write(true, titleSize, margin, text)
+	d.y -= titleSize * 0.6
+}
+
+// Text выводит строку текста
+func (d *Document) Text(text string) {
+	d.ensureSpace(textSize * 1.5)
+	d.y -= textSize
+	d.write(false, textSize, margin, text)
+	d.y -= textSize * 0.5
+}
+
+// Space добавляет вертикальный отступ
+func (d *Document) Space() {
+	d.y -= textSize
+}
+
+// Table выводит таблицу; widths задают доли ширины страницы для колонок.
+// Заголовок таблицы повторяется на каждой новой странице.
+func (d *Document) Table(headers []string, rows [][]string, widths []float64) {
+	columns := columnPositions(widths, len(headers))
+	d.row(true, headers, columns)
+	for _, r := range rows {
+		if d.y-textSize*1.5 < margin {
+			d.addPage()
+			d.row(true, headers, columns)
+		}
+		d.row(false, r, columns)
+	}
+}
+
+type column struct {
+	x     float64
+	width float64
+}
+
+func columnPositions(widths []float64, count int) []column {
+	available := pageWidth - 2*margin
+	total := 0.0
+	for i := 0; i < count; i++ {
+		if i < len(widths) {
+			total += widths[i]
+		} else {
+			total += 1
+		}
+	}
+	columns := make([]column, count)
+	x := margin
+	for i := 0; i < count; i++ {
+		w := 1.0
+		if i < len(widths) {
+			w = widths[i]
+		}
+		columns[i] = column{x: x, width: available * w / total}
+		x += columns[i].width
+	}
+	return columns
+}
+
+func (d *Document) row(bold bool, cells []string, columns []column) {
+	d.ensureSpace(textSize * 1.5)
+	d.y -= textSize
+	for i, cell := range cells {
+		if i >= len(columns) {
+			break
+		}
+		// Небольшой зазор между колонками
+		text := d.fit(cell, textSize, columns[i].width-textSize*0.5)
+		d.write(bold, textSize, columns[i].x, text)
+	}
+	d.y -= textSize * 0.5
+}
+
+// Bytes возвращает содержимое документа в формате PDF
+func (d *Document) Bytes() []byte {
+	var out bytes.Buffer
+	offsets := []int{}
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	stream := func(dict string, data []byte) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n<< %s /Length %d >>\nstream\n", len(offsets), dict, len(data))
+		out.Write(data)
+		out.WriteString("\nendstream\nendobj\n")
+	}
+
+	out.WriteString("%PDF-1.4\n")
+
+	// 1 — каталог, 2 — дерево страниц, 3–7 — шрифт, далее пары (страница, содержимое)
+	const firstPage = 8
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+i*2)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+
+	f := d.font
+	fontFile := f.subset(d.used)
+	name := "ARCDOC+DejaVuSans"
+	object(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [4 0 R] /ToUnicode 7 0 R >>", name))
+	object(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor 5 0 R /CIDToGIDMap /Identity /DW %d /W [%s] >>",
+		name, f.width(0), d.widths()))
+	object(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 6 0 R >>",
+		name, f.scale(f.bbox[0]), f.scale(f.bbox[1]), f.scale(f.bbox[2]), f.scale(f.bbox[3]), f.scale(f.ascent), f.scale(f.descent), f.scale(f.capHeight)))
+	stream(fmt.Sprintf("/Filter /FlateDecode /Length1 %d", len(fontFile)), deflate(fontFile))
+	stream("", d.toUnicode())
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, firstPage+1+i*2))
+		stream("/Filter /FlateDecode", deflate(page.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// usedGlyphs возвращает использованные глифы по возрастанию
+func (d *Document) usedGlyphs() []uint16 {
+	glyphs := make([]uint16, 0, len(d.used))
+	for g := range d.used {
+		glyphs = append(glyphs, g)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+	return glyphs
+}
+
+// widths массив ширин /W для использованных глифов
+func (d *Document) widths() string {
+	var b strings.Builder
+	for _, g := range d.usedGlyphs() {
+		fmt.Fprintf(&b, "%d [%d] ", g, d.font.width(g))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// toUnicode таблица соответствия глифов символам, чтобы текст из документа можно было копировать и искать
+func (d *Document) toUnicode() []byte {
+	var b bytes.Buffer
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	b.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	b.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	b.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	glyphs := d.usedGlyphs()
+	// В одном блоке bfchar допускается не более 100 записей
+	for start := 0; start < len(glyphs); start += 100 {
+		end := start + 100
+		if end > len(glyphs) {
+			end = len(glyphs)
+		}
+		fmt.Fprintf(&b, "%d beginbfchar\n", end-start)
+		for _, g := range glyphs[start:end] {
+			fmt.Fprintf(&b, "<%04X> <%s>\n", g, utf16Hex(d.used[g]))
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return b.Bytes()
+}
+
+// utf16Hex символ в кодировке UTF-16BE в шестнадцатеричном виде
+func utf16Hex(r rune) string {
+	if r < 0x10000 {
+		return fmt.Sprintf("%04X", r)
+	}
+	r -= 0x10000
+	return fmt.Sprintf("%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+}
+
+func deflate(data []byte) []byte {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	_, _ = w.Write(data)
+	_ = w.Close()
+	return b.Bytes()
+}
@@ -0,0 +1,64 @@
+package pdf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocument_KeepsCyrillic(t *testing.T) {
+	doc := New()
+	doc.Title("Заказ поставщику № ЗП-0001")
+	doc.Table([]string{"Позиция", "Кол-во"}, [][]string{{"Мука пшеничная", "12,5 кг"}}, []float64{3, 1})
+	out := doc.Bytes()
+
+	assert.True(t, bytes.HasPrefix(out, []byte("%PDF-1.4")))
+	assert.True(t, bytes.HasSuffix(out, []byte("%%EOF\n")))
+	assert.Contains(t, string(out), "/FontFile2")
+	// Символы восстанавливаются через ToUnicode: «З» (U+0417) и «№» (U+2116)
+	assert.Contains(t, string(out), "<0417>")
+	assert.Contains(t, string(out), "<2116>")
+	assert.NotContains(t, string(out), "Zakaz")
+	// Встраивается подмножество, а не весь шрифт
+	assert.Less(t, len(out), 60<<10)
+}
+
+func TestTrueTypeFont_Subset(t *testing.T) {
+	font := regularFont()
+	ze, zhe := font.glyph('З'), font.glyph('Ж')
+	require.NotZero(t, ze)
+	require.NotZero(t, zhe)
+	require.NotEmpty(t, font.glyphData(zhe))
+
+	sub, err := parseTrueType(font.subset(map[uint16]rune{ze: 'З'}))
+	require.NoError(t, err)
+	assert.Equal(t, font.numGlyphs, sub.numGlyphs)
+	assert.Equal(t, font.glyphData(ze), sub.glyphData(ze))
+	assert.Empty(t, sub.glyphData(zhe))
+	assert.Equal(t, font.width(zhe), sub.width(zhe))
+}
+
+func TestTrueTypeFont_SubsetKeepsComponents(t *testing.T) {
+	font := regularFont()
+	// «Й» в DejaVu Sans собрана из «И» и бреве
+	short := font.glyph('Й')
+	parts := components(font.glyphData(short))
+	require.NotEmpty(t, parts)
+
+	sub, err := parseTrueType(font.subset(map[uint16]rune{short: 'Й'}))
+	require.NoError(t, err)
+	for _, g := range parts {
+		assert.NotEmpty(t, sub.glyphData(g))
+	}
+}
+
+func TestDocument_FitTruncatesByWidth(t *testing.T) {
+	doc := New()
+	text := "Очень длинное наименование позиции заказа"
+	fitted := doc.fit(text, textSize, 60)
+	assert.NotEqual(t, text, fitted)
+	assert.LessOrEqual(t, doc.textWidth(fitted, textSize), 60.0)
+	assert.Equal(t, "Мука", doc.fit("Мука", textSize, 60))
+}
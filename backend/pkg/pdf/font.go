@@ -0,0 +1,390 @@
+package pdf
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Шрифт DejaVu Sans (лицензия Bitstream Vera, см. fonts/LICENSE) покрывает латиницу и кириллицу.
+// В документ встраивается подмножество шрифта только с использованными глифами.
+//
+//go:embed fonts/DejaVuSans.ttf
+var dejaVuSans []byte
+
+var (
+	regularOnce sync.Once
+	regular     *trueTypeFont
+)
+
+// regularFont возвращает разобранный встроенный шрифт
+func regularFont() *trueTypeFont {
+	regularOnce.Do(func() {
+		f, err := parseTrueType(dejaVuSans)
+		if err != nil {
+			panic(fmt.Sprintf("pdf: embedded font: %v", err))
+		}
+		regular = f
+	})
+	return regular
+}
+
+// trueTypeFont метрики и таблицы TrueType-шрифта, нужные для встраивания в PDF
+type trueTypeFont struct {
+	tables     map[string][]byte
+	unitsPerEm int
+	numGlyphs  int
+	advances   []int // Ширина глифа в единицах шрифта
+	cmap       map[rune]uint16
+	bbox       [4]int
+	ascent     int
+	descent    int
+	capHeight  int
+	longLoca   bool
+}
+
+var errBadFont = errors.New("malformed TrueType font")
+
+func parseTrueType(data []byte) (*trueTypeFont, error) {
+	if len(data) < 12 {
+		return nil, errBadFont
+	}
+	f := &trueTypeFont{tables: make(map[string][]byte)}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		rec := 12 + 16*i
+		if rec+16 > len(data) {
+			return nil, errBadFont
+		}
+		tag := string(data[rec : rec+4])
+		offset := int(binary.BigEndian.Uint32(data[rec+8:]))
+		length := int(binary.BigEndian.Uint32(data[rec+12:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil, errBadFont
+		}
+		f.tables[tag] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "loca", "glyf"} {
+		if _, ok := f.tables[tag]; !ok {
+			return nil, fmt.Errorf("%w: no %s table", errBadFont, tag)
+		}
+	}
+
+	head := f.tables["head"]
+	if len(head) < 54 {
+		return nil, errBadFont
+	}
+	f.unitsPerEm = int(binary.BigEndian.Uint16(head[18:]))
+	for i := range f.bbox {
+		f.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+2*i:])))
+	}
+	f.longLoca = binary.BigEndian.Uint16(head[50:]) == 1
+	if f.unitsPerEm == 0 {
+		return nil, errBadFont
+	}
+
+	maxp := f.tables["maxp"]
+	if len(maxp) < 6 {
+		return nil, errBadFont
+	}
+	f.numGlyphs = int(binary.BigEndian.Uint16(maxp[4:]))
+
+	hhea := f.tables["hhea"]
+	if len(hhea) < 36 {
+		return nil, errBadFont
+	}
+	f.ascent = int(int16(binary.BigEndian.Uint16(hhea[4:])))
+	f.descent = int(int16(binary.BigEndian.Uint16(hhea[6:])))
+	f.capHeight = f.ascent
+	if os2 := f.tables["OS/2"]; len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+		f.capHeight = int(int16(binary.BigEndian.Uint16(os2[88:])))
+	}
+
+	numMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	hmtx := f.tables["hmtx"]
+	if numMetrics == 0 || len(hmtx) < numMetrics*4 {
+		return nil, errBadFont
+	}
+	f.advances = make([]int, f.numGlyphs)
+	for g := range f.advances {
+		m := g
+		if m >= numMetrics {
+			m = numMetrics - 1
+		}
+		f.advances[g] = int(binary.BigEndian.Uint16(hmtx[m*4:]))
+	}
+
+	// В подмножестве для PDF cmap не нужен: текст выводится номерами глифов
+	if t, ok := f.tables["cmap"]; ok {
+		cmap, err := parseCmap(t)
+		if err != nil {
+			return nil, err
+		}
+		f.cmap = cmap
+	}
+	return f, nil
+}
+
+// parseCmap читает юникодную таблицу символов: формат 12 (все плоскости) или формат 4 (BMP)
+func parseCmap(t []byte) (map[rune]uint16, error) {
+	if len(t) < 4 {
+		return nil, errBadFont
+	}
+	var format4, format12 []byte
+	numTables := int(binary.BigEndian.Uint16(t[2:]))
+	for i := 0; i < numTables; i++ {
+		rec := 4 + 8*i
+		if rec+8 > len(t) {
+			return nil, errBadFont
+		}
+		platform := binary.BigEndian.Uint16(t[rec:])
+		encoding := binary.BigEndian.Uint16(t[rec+2:])
+		offset := int(binary.BigEndian.Uint32(t[rec+4:]))
+		if offset+4 > len(t) || (platform != 0 && !(platform == 3 && (encoding == 1 || encoding == 10))) {
+			continue
+		}
+		switch binary.BigEndian.Uint16(t[offset:]) {
+		case 4:
+			format4 = t[offset:]
+		case 12:
+			format12 = t[offset:]
+		}
+	}
+
+	cmap := make(map[rune]uint16)
+	switch {
+	case len(format12) >= 16:
+		groups := int(binary.BigEndian.Uint32(format12[12:]))
+		if 16+groups*12 > len(format12) {
+			return nil, errBadFont
+		}
+		for i := 0; i < groups; i++ {
+			g := format12[16+12*i:]
+			start, end, glyph := binary.BigEndian.Uint32(g), binary.BigEndian.Uint32(g[4:]), binary.BigEndian.Uint32(g[8:])
+			for c := start; c <= end && c <= 0x10FFFF; c++ {
+				cmap[rune(c)] = uint16(glyph + c - start)
+			}
+		}
+	case len(format4) >= 14:
+		segX2 := int(binary.BigEndian.Uint16(format4[6:]))
+		rangeOffsets := 16 + 3*segX2
+		if rangeOffsets+segX2 > len(format4) {
+			return nil, errBadFont
+		}
+		for i := 0; i < segX2/2; i++ {
+			end := int(binary.BigEndian.Uint16(format4[14+2*i:]))
+			start := int(binary.BigEndian.Uint16(format4[16+segX2+2*i:]))
+			delta := int(binary.BigEndian.Uint16(format4[16+2*segX2+2*i:]))
+			rangeOffset := int(binary.BigEndian.Uint16(format4[rangeOffsets+2*i:]))
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				glyph := (c + delta) & 0xFFFF
+				if rangeOffset != 0 {
+					addr := rangeOffsets + 2*i + rangeOffset + 2*(c-start)
+					if addr+2 > len(format4) {
+						return nil, errBadFont
+					}
+					glyph = int(binary.BigEndian.Uint16(format4[addr:]))
+					if glyph != 0 {
+						glyph = (glyph + delta) & 0xFFFF
+					}
+				}
+				if glyph != 0 {
+					cmap[rune(c)] = uint16(glyph)
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: no unicode cmap", errBadFont)
+	}
+	return cmap, nil
+}
+
+// glyph возвращает глиф символа; 0 — символа нет в шрифте
+func (f *trueTypeFont) glyph(r rune) uint16 {
+	return f.cmap[r]
+}
+
+// width ширина глифа в тысячных долях кегля
+func (f *trueTypeFont) width(glyph uint16) int {
+	if int(glyph) >= len(f.advances) {
+		return 0
+	}
+	return f.advances[glyph] * 1000 / f.unitsPerEm
+}
+
+// scale переводит единицы шрифта в тысячные доли кегля
+func (f *trueTypeFont) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
+
+// glyphData возвращает описание глифа из таблицы glyf
+func (f *trueTypeFont) glyphData(glyph uint16) []byte {
+	loca, glyf := f.tables["loca"], f.tables["glyf"]
+	g := int(glyph)
+	if g >= f.numGlyphs {
+		return nil
+	}
+	var start, end int
+	if f.longLoca {
+		if (g+2)*4 > len(loca) {
+			return nil
+		}
+		start, end = int(binary.BigEndian.Uint32(loca[g*4:])), int(binary.BigEndian.Uint32(loca[g*4+4:]))
+	} else {
+		if (g+2)*2 > len(loca) {
+			return nil
+		}
+		start, end = int(binary.BigEndian.Uint16(loca[g*2:]))*2, int(binary.BigEndian.Uint16(loca[g*2+2:]))*2
+	}
+	if start > end || end > len(glyf) {
+		return nil
+	}
+	return glyf[start:end]
+}
+
+// Флаги составного глифа
+const (
+	compositeArgWords    = 0x0001
+	compositeScale       = 0x0008
+	compositeMore        = 0x0020
+	compositeXYScale     = 0x0040
+	compositeTwoByTwo    = 0x0080
+	compositeHeaderBytes = 10
+)
+
+// components возвращает глифы, из которых собран составной глиф
+func components(data []byte) []uint16 {
+	if len(data) < compositeHeaderBytes || int16(binary.BigEndian.Uint16(data)) >= 0 {
+		return nil
+	}
+	var glyphs []uint16
+	p := compositeHeaderBytes
+	for p+4 <= len(data) {
+		flags := binary.BigEndian.Uint16(data[p:])
+		glyphs = append(glyphs, binary.BigEndian.Uint16(data[p+2:]))
+		p += 4
+		if flags&compositeArgWords != 0 {
+			p += 4
+		} else {
+			p += 2
+		}
+		switch {
+		case flags&compositeScale != 0:
+			p += 2
+		case flags&compositeXYScale != 0:
+			p += 4
+		case flags&compositeTwoByTwo != 0:
+			p += 8
+		}
+		if flags&compositeMore == 0 {
+			break
+		}
+	}
+	return glyphs
+}
+
+// subset собирает шрифт, в котором описания оставлены только у used (и их составляющих).
+// Номера глифов не меняются, поэтому текст можно выводить исходными номерами глифов.
+func (f *trueTypeFont) subset(used map[uint16]rune) []byte {
+	keep := map[uint16]bool{0: true}
+	queue := []uint16{0}
+	for g := range used {
+		if !keep[g] {
+			keep[g] = true
+			queue = append(queue, g)
+		}
+	}
+	for len(queue) > 0 {
+		g := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		for _, c := range components(f.glyphData(g)) {
+			if !keep[c] {
+				keep[c] = true
+				queue = append(queue, c)
+			}
+		}
+	}
+
+	var glyf []byte
+	loca := make([]byte, 4*(f.numGlyphs+1))
+	for g := 0; g < f.numGlyphs; g++ {
+		binary.BigEndian.PutUint32(loca[4*g:], uint32(len(glyf)))
+		if keep[uint16(g)] {
+			glyf = append(glyf, f.glyphData(uint16(g))...)
+			for len(glyf)%4 != 0 {
+				glyf = append(glyf, 0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[4*f.numGlyphs:], uint32(len(glyf)))
+
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)  // checkSumAdjustment
+	binary.BigEndian.PutUint16(head[50:], 1) // indexToLocFormat: длинные смещения
+
+	tables := map[string][]byte{
+		"head": head,
+		"hhea": f.tables["hhea"],
+		"maxp": f.tables["maxp"],
+		"hmtx": f.tables["hmtx"],
+		"loca": loca,
+		"glyf": glyf,
+	}
+	// Хинтинг нужен для корректной растеризации мелкого кегля
+	for _, tag := range []string{"cvt ", "fpgm", "prep"} {
+		if t, ok := f.tables[tag]; ok {
+			tables[tag] = t
+		}
+	}
+	return writeTrueType(tables)
+}
+
+// writeTrueType собирает файл шрифта из таблиц
+func writeTrueType(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	n := len(tags)
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= n {
+		searchRange *= 2
+		entrySelector++
+	}
+	searchRange *= 16
+
+	out := make([]byte, 12+16*n)
+	binary.BigEndian.PutUint32(out, 0x00010000)
+	binary.BigEndian.PutUint16(out[4:], uint16(n))
+	binary.BigEndian.PutUint16(out[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(out[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(out[10:], uint16(n*16-searchRange))
+	for i, tag := range tags {
+		t := tables[tag]
+		rec := out[12+16*i:]
+		copy(rec, tag)
+		binary.BigEndian.PutUint32(rec[4:], tableChecksum(t))
+		binary.BigEndian.PutUint32(rec[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(rec[12:], uint32(len(t)))
+		out = append(out, t...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	return out
+}
+
+func tableChecksum(t []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(t); i += 4 {
+		var word [4]byte
+		copy(word[:], t[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
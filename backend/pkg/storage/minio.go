@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -131,6 +132,34 @@ func (m *MinIOClient) UploadImage(ctx context.Context, file io.Reader, filename
 	return url, nil
 }
 
+// PutObject загружает данные под именем objectName и возвращает публичный URL
+func (m *MinIOClient) PutObject(ctx context.Context, objectName string, data []byte, contentType string) (string, error) {
+	_, err := m.client.PutObject(ctx, m.bucket, objectName, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType:  contentType,
+		CacheControl: "public, max-age=31536000, immutable", // Имена объектов уникальны, содержимое не меняется
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
+	}
+	return fmt.Sprintf("%s/%s/%s", m.publicURL, m.bucket, objectName), nil
+}
+
+// DeletePrefix удаляет все объекты, имена которых начинаются с prefix
+func (m *MinIOClient) DeletePrefix(ctx context.Context, prefix string) error {
+	// Отмена контекста останавливает листинг, если выходим из цикла по ошибке
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for object := range m.client.ListObjects(ctx, m.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return fmt.Errorf("failed to list files: %w", object.Err)
+		}
+		if err := m.client.RemoveObject(ctx, m.bucket, object.Key, minio.RemoveObjectOptions{}); err != nil {
+			return fmt.Errorf("failed to delete file: %w", err)
+		}
+	}
+	return nil
+}
+
 // DeleteImage удаляет изображение из MinIO
 func (m *MinIOClient) DeleteImage(ctx context.Context, objectName string) error {
 	err := m.client.RemoveObject(ctx, m.bucket, objectName, minio.RemoveObjectOptions{})
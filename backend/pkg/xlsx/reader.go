@@ -0,0 +1,236 @@
+// Package xlsx читает и записывает таблицы Office Open XML (.xlsx) без внешних зависимостей.
+// Поддерживается только то, что нужно для импорта и выгрузки: значения ячеек первого листа
+// (общие и встроенные строки, числа, логические значения); стили и формулы игнорируются.
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoSheets возвращается, если в книге нет ни одного листа
+var ErrNoSheets = errors.New("xlsx: workbook has no sheets")
+
+type workbookXML struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type relationshipsXML struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type richTextXML struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t richTextXML) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.Text)
+	}
+	return b.String()
+}
+
+type sharedStringsXML struct {
+	Items []richTextXML `xml:"si"`
+}
+
+type worksheetXML struct {
+	Rows []struct {
+		Index int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string      `xml:"r,attr"`
+			Type   string      `xml:"t,attr"`
+			Value  string      `xml:"v"`
+			Inline richTextXML `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ReadRows возвращает строки первого листа книги. Пустые ячейки внутри строки
+// заполняются пустыми строками, пустые строки листа сохраняются, чтобы номера строк совпадали с Excel.
+func ReadRows(r io.ReaderAt, size int64) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("xlsx: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared sharedStringsXML
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeFile(f, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	var sheet worksheetXML
+	f, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("xlsx: sheet %s not found", sheetPath)
+	}
+	if err := decodeFile(f, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for i, row := range sheet.Rows {
+		index := row.Index
+		if index == 0 {
+			index = i + 1
+		}
+		for len(rows) < index-1 {
+			rows = append(rows, nil)
+		}
+		var cells []string
+		for j, c := range row.Cells {
+			col := j
+			if c.Ref != "" {
+				if n, ok := columnIndex(c.Ref); ok {
+					col = n
+				}
+			}
+			for len(cells) < col {
+				cells = append(cells, "")
+			}
+			value := c.Value
+			switch c.Type {
+			case "s":
+				n, err := strconv.Atoi(strings.TrimSpace(c.Value))
+				if err != nil || n < 0 || n >= len(shared.Items) {
+					return nil, fmt.Errorf("xlsx: invalid shared string index %q in %s", c.Value, c.Ref)
+				}
+				value = shared.Items[n].String()
+			case "inlineStr":
+				value = c.Inline.String()
+			case "b":
+				if value == "1" {
+					value = "TRUE"
+				} else {
+					value = "FALSE"
+				}
+			}
+			if col < len(cells) {
+				cells[col] = value
+			} else {
+				cells = append(cells, value)
+			}
+		}
+		rows = append(rows, cells)
+	}
+	return rows, nil
+}
+
+// ReadAll читает книгу целиком из r (для multipart-файлов и тел запросов)
+func ReadAll(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ReadRows(bytes.NewReader(data), int64(len(data)))
+}
+
+// SerialToTime переводит дату Excel (число дней от 30.12.1899) во время UTC
+func SerialToTime(serial float64) time.Time {
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 86400)
+	return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).
+		AddDate(0, 0, int(days)).
+		Add(time.Duration(seconds) * time.Second)
+}
+
+// firstSheetPath находит файл первого листа по workbook.xml и его связям
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+	wbFile, ok := files["xl/workbook.xml"]
+	if !ok {
+		if _, ok := files[fallback]; ok {
+			return fallback, nil
+		}
+		return "", errors.New("xlsx: workbook.xml not found")
+	}
+	var wb workbookXML
+	if err := decodeFile(wbFile, &wb); err != nil {
+		return "", err
+	}
+	if len(wb.Sheets) == 0 {
+		return "", ErrNoSheets
+	}
+
+	relsFile, ok := files["xl/_rels/workbook.xml.rels"]
+	if !ok {
+		return fallback, nil
+	}
+	var rels relationshipsXML
+	if err := decodeFile(relsFile, &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != wb.Sheets[0].RID {
+			continue
+		}
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			return strings.TrimPrefix(target, "/"), nil
+		}
+		return path.Join("xl", target), nil
+	}
+	return fallback, nil
+}
+
+func decodeFile(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("xlsx: %w", err)
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("xlsx: invalid %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// columnIndex возвращает номер колонки (с нуля) из ссылки на ячейку вида "AB12"
+func columnIndex(ref string) (int, bool) {
+	n := 0
+	i := 0
+	for ; i < len(ref); i++ {
+		ch := ref[i]
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		n = n*26 + int(ch-'A'+1)
+	}
+	if i == 0 {
+		return 0, false
+	}
+	return n - 1, true
+}
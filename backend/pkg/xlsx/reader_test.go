@@ -0,0 +1,57 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildWorkbook(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestReadRows(t *testing.T) {
+	data := buildWorkbook(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Накладная" sheetId="1" r:id="rId3"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId3" Target="worksheets/data.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>Наименование</t></si><si><t>Кол-во</t></si><si><r><t>Мука </t></r><r><t>в/с</t></r></si></sst>`,
+		"xl/worksheets/data.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>
+<row r="3"><c r="A3" t="s"><v>2</v></c><c r="B3" t="inlineStr"><is><t>кг</t></is></c><c r="C3"><v>12.5</v></c><c r="D3" t="b"><v>1</v></c></row>
+</sheetData></worksheet>`,
+	})
+
+	rows, err := ReadRows(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"Наименование", "", "Кол-во"},
+		nil,
+		{"Мука в/с", "кг", "12.5", "TRUE"},
+	}, rows)
+}
+
+func TestReadRows_NotZip(t *testing.T) {
+	_, err := ReadAll(bytes.NewReader([]byte("name;qty")))
+	assert.Error(t, err)
+}
+
+func TestSerialToTime(t *testing.T) {
+	assert.Equal(t, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), SerialToTime(46314.5))
+}
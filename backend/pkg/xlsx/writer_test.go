@@ -0,0 +1,34 @@
+package xlsx
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteRows_RoundTrip(t *testing.T) {
+	rows := [][]string{
+		{"type", "name", "price", "barcode", "unit"},
+		{"product", "Сок <яблочный> & \"свежий\"", "12.5", "4601234567890", "шт"},
+		{"line", "", "0.05", "007", ""},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, WriteRows(&buf, "Меню", rows))
+
+	got, err := ReadRows(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"type", "name", "price", "barcode", "unit"},
+		{"product", "Сок <яблочный> & \"свежий\"", "12.5", "4601234567890", "шт"},
+		{"line", "", "0.05", "007"},
+	}, got)
+}
+
+func TestColumnName(t *testing.T) {
+	assert.Equal(t, "A", columnName(0))
+	assert.Equal(t, "Z", columnName(25))
+	assert.Equal(t, "AA", columnName(26))
+	assert.Equal(t, "AB", columnName(27))
+}